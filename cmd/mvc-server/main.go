@@ -16,6 +16,8 @@ import (
 	"drone-control-system/internal/mvc/models"
 	"drone-control-system/internal/mvc/routes"
 	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/blobstore"
+	"drone-control-system/pkg/database"
 	"drone-control-system/pkg/kafka"
 	"drone-control-system/pkg/logger"
 
@@ -36,12 +38,12 @@ func main() {
 		Output: config.GetString("logging.output"),
 	})
 
-	// 初始化数据库（示例，需要根据实际情况实现）
-	// db, err := initDatabase(config)
-	// if err != nil {
-	//     appLogger.WithFields(map[string]interface{}{"error": err}).Fatal("Failed to initialize database")
-	//     return
-	// }
+	// 初始化数据库（MySQL + Redis）
+	dbManager, err := initDatabase(config)
+	if err != nil {
+		appLogger.WithFields(map[string]interface{}{"error": err}).Fatal("Failed to initialize database")
+		return
+	}
 
 	// 初始化服务层（示例，需要根据实际情况实现）
 	// userService := services.NewUserService(db, appLogger)
@@ -50,6 +52,12 @@ func main() {
 	// 为了演示，创建mock服务
 	userService := &MockUserService{}
 	droneService := &MockDroneService{}
+	alertService := &MockAlertService{}
+
+	// 任务服务装饰：分配和启动时校验无人机能力，创建和更新时校验航线
+	var taskService services.TaskService = &MockTaskService{}
+	taskService = services.NewCapabilityTaskService(taskService, droneService)
+	taskService = services.NewMissionTaskService(taskService, droneService)
 
 	// 🚀 初始化Kafka服务
 	kafkaConfig := &kafka.Config{
@@ -67,28 +75,244 @@ func main() {
 		log.Fatalf("Failed to create kafka service: %v", err)
 	}
 
+	// 🛩️ 初始化机队管理服务（机队角色在全局角色之上限制无人机访问）
+	fleetService := services.NewFleetService(dbManager.GetDB(), appLogger)
+
+	// 🔑 初始化设备接入服务（设备凭证和指令通道）
+	deviceConfig := loadDeviceConfig(config)
+	deviceService := services.NewDeviceService(deviceConfig, dbManager.GetDB(), kafkaService, appLogger)
+
 	// 🌐 初始化WebSocket服务
-	websocketService := services.NewWebSocketService(appLogger)
+	websocketService := services.NewWebSocketService(appLogger, fleetService)
+
+	// 💓 初始化心跳监控（多实例部署时仅主节点执行超时扫描）
+	heartbeatMonitor := services.NewHeartbeatMonitor(
+		loadHeartbeatConfig(config),
+		dbManager.GetRedis(),
+		dbManager.GetLock(),
+		droneService,
+		alertService,
+		kafkaService,
+		appLogger,
+	)
+
+	// 🔧 初始化维护管理服务（使用量统计和逾期检查仅在主节点执行）
+	// 维护结束的无人机重新纳入心跳监控
+	maintenanceService := services.NewMaintenanceService(
+		loadMaintenanceConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetLock(),
+		heartbeatMonitor,
+		alertService,
+		kafkaService,
+		appLogger,
+	)
+
+	// 🔋 初始化电池组管理服务
+	batteryService := services.NewBatteryService(
+		loadBatteryConfig(config),
+		dbManager.GetDB(),
+		alertService,
+		kafkaService,
+		appLogger,
+	)
+
+	// 🚧 初始化地理围栏服务（位置更新检查禁飞区，任务航线不得穿越限制区域）
+	geofenceService := services.NewGeofenceService(
+		loadGeofenceConfig(config),
+		dbManager.GetDB(),
+		alertService,
+		kafkaService,
+		appLogger,
+	)
+	taskService = services.NewGeofenceTaskService(taskService, geofenceService)
+
+	// 🧭 初始化航线规划服务（绕开禁飞区和障碍物，校验LLM给出的航线）
+	routeService := services.NewRouteService(loadRouteConfig(config), geofenceService, appLogger)
+	taskService = services.NewRouteTaskService(taskService, routeService)
 
 	// 🧠 初始化智能告警服务
-	smartAlertService := services.NewSmartAlertService(appLogger, kafkaService)
+	smartAlertService := services.NewSmartAlertService(appLogger, kafkaService, maintenanceService, batteryService)
+
+	// 📈 初始化遥测时序存储（降采样和过期清理仅在主节点执行）
+	telemetryService := services.NewTelemetryService(
+		loadTelemetryConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetRedis(),
+		dbManager.GetLock(),
+		appLogger,
+	)
+
+	// 📍 初始化任务进度推算（按位置匹配航点，推算进度和ETA，停滞巡检仅在主节点执行）
+	progressService := services.NewProgressService(
+		loadProgressConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetLock(),
+		alertService,
+		kafkaService,
+		appLogger,
+	)
+
+	// 🗺️ 初始化在线无人机实时位置索引
+	geoIndex := services.NewDroneGeoIndex(loadGeoIndexConfig(config), dbManager.GetRedis(), droneService, appLogger)
+
+	// 📦 初始化固件管理服务（发布调度仅在主节点执行）
+	firmwareService := services.NewFirmwareService(
+		loadFirmwareConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetLock(),
+		kafkaService,
+		appLogger,
+	)
+
+	// 🔋 初始化能耗估算（任务耗电预测保存到任务，完成后按实际耗电校准机型）
+	// 未接入气象数据源时按无风估算
+	energyService := services.NewEnergyService(
+		loadEnergyConfig(config),
+		dbManager.GetDB(),
+		taskService,
+		nil,
+		appLogger,
+	)
+
+	// ✅ 初始化起飞前检查（启动任务前执行，警告需管理员放行）
+	// 未接入气象数据源时跳过风速和能见度检查
+	preflightService := services.NewPreflightService(
+		loadPreflightConfig(config),
+		dbManager.GetDB(),
+		taskService,
+		maintenanceService,
+		firmwareService,
+		geofenceService,
+		energyService,
+		nil,
+		appLogger,
+	)
+	taskService = services.NewPreflightTaskService(taskService, preflightService)
+
+	// 📝 初始化任务审批（命中审批规则的任务需管理员审批后才能启动）
+	// 位于调度器和流程使用的任务服务之内，所有创建和启动路径都经过审批检查
+	approvalService := services.NewApprovalService(
+		loadApprovalConfig(config),
+		dbManager.GetDB(),
+		geofenceService,
+		kafkaService,
+		appLogger,
+	)
+	taskService = services.NewApprovalTaskService(taskService, approvalService, appLogger)
+	taskService = services.NewEnergyTaskService(taskService, energyService, droneService, appLogger)
+
+	// 🎯 初始化任务自动分配（未指定无人机的任务自动选择，无人机不可用时改派）
+	assignmentService := services.NewAssignmentService(
+		loadAssignmentConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetLock(),
+		taskService,
+		energyService,
+		kafkaService,
+		appLogger,
+	)
+	taskService = services.NewAssignmentTaskService(taskService, assignmentService)
+
+	// 📦 初始化配送任务（校验取送信息和载荷，记录取货、出发、交付节点和交付证明）
+	deliveryService := services.NewDeliveryService(loadDeliveryConfig(config), dbManager.GetDB(), kafkaService, appLogger)
+	taskService = services.NewDeliveryTaskService(taskService, deliveryService)
+
+	// ⏰ 初始化任务调度器（定时启动和周期任务仅在主节点执行）
+	// 位于配送装饰器之外，周期生成的配送任务同样校验并保存取送信息
+	taskScheduler := services.NewTaskScheduler(
+		loadSchedulerConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetLock(),
+		taskService,
+		kafkaService,
+		appLogger,
+	)
+
+	// 🔁 初始化失败重试（按任务类型策略在原无人机重试或改派，记录每次执行历史，巡检仅在主节点执行）
+	retryService := services.NewRetryService(
+		loadRetryConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetLock(),
+		assignmentService,
+		telemetryService,
+		kafkaService,
+		appLogger,
+	)
+	taskService = services.NewRetryTaskService(taskService, retryService, appLogger)
+
+	// 🔀 初始化多步骤流程执行器（按依赖启动步骤任务，步骤任务结束后立即推进，巡检仅在主节点执行）
+	workflowService := services.NewWorkflowService(
+		loadWorkflowConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetLock(),
+		taskService,
+		kafkaService,
+		appLogger,
+	)
+	taskService = services.NewWorkflowTaskService(taskService, workflowService, appLogger)
+
+	// 🗂️ 初始化任务文件存储（上传、校验和、照片视频元数据提取、签名下载链接，过期清理仅在主节点执行）
+	artifactStore, err := blobstore.NewLocalStore(config.GetString("artifact.storage_dir"))
+	if err != nil {
+		log.Fatalf("Failed to initialize artifact store: %v", err)
+	}
+	artifactService := services.NewArtifactService(
+		loadArtifactConfig(config),
+		dbManager.GetDB(),
+		dbManager.GetLock(),
+		artifactStore,
+		appLogger,
+	)
+	taskService = services.NewArtifactTaskService(taskService, artifactService, appLogger)
+
+	// 🗺️ 初始化测绘覆盖规划（按测区生成往返航线和拍照点，多机并行时按飞行时间均衡切分）
+	coverageService := services.NewCoverageService(loadCoverageConfig(config), taskService, assignmentService, appLogger)
+	templateService := services.NewTaskTemplateService(dbManager.GetDB(), taskService, appLogger)
 
 	// 🔗 初始化事件处理器
-	eventHandler := handlers.NewEventHandler(appLogger, websocketService, smartAlertService)
+	eventHandler := handlers.NewEventHandler(appLogger, websocketService, smartAlertService, heartbeatMonitor, telemetryService, geoIndex, batteryService, assignmentService, geofenceService, retryService, progressService)
 
 	// 初始化控制器
 	userController := controllers.NewUserController(appLogger, userService)
-	droneController := controllers.NewDroneController(appLogger, droneService, kafkaService)
+	droneController := controllers.NewDroneController(appLogger, droneService, kafkaService, fleetService, deviceService)
+	taskController := controllers.NewTaskController(appLogger, taskService, fleetService, assignmentService, preflightService, routeService, energyService, retryService, coverageService, deliveryService, templateService, approvalService)
+	trackExportService := services.NewTrackExportService(dbManager.GetDB(), telemetryService)
+	telemetryController := controllers.NewTelemetryController(appLogger, telemetryService, trackExportService, taskService, fleetService)
+	geoController := controllers.NewGeoController(appLogger, geoIndex, fleetService)
+	firmwareController := controllers.NewFirmwareController(appLogger, firmwareService)
+	maintenanceController := controllers.NewMaintenanceController(appLogger, maintenanceService, fleetService)
+	batteryController := controllers.NewBatteryController(appLogger, batteryService, fleetService)
+	fleetController := controllers.NewFleetController(appLogger, fleetService, droneService, kafkaService, deviceService)
+	deviceController := controllers.NewDeviceController(appLogger, deviceService, fleetService)
+	scheduleController := controllers.NewScheduleController(appLogger, taskScheduler, taskService, fleetService)
+	geofenceController := controllers.NewGeofenceController(appLogger, geofenceService)
+	workflowController := controllers.NewWorkflowController(appLogger, workflowService, fleetService)
+	artifactController := controllers.NewArtifactController(appLogger, taskService, artifactService, fleetService)
 
 	// 初始化中间件
 	authMiddleware := middleware.NewAuthMiddleware(userService, appLogger)
+	deviceAuthMiddleware := middleware.NewDeviceAuthMiddleware(deviceService, deviceConfig.FingerprintHeader, appLogger)
 
 	// 初始化路由
 	router := routes.NewRouter(
 		appLogger,
 		authMiddleware,
+		deviceAuthMiddleware,
 		userController,
 		droneController,
+		taskController,
+		telemetryController,
+		geoController,
+		firmwareController,
+		maintenanceController,
+		batteryController,
+		fleetController,
+		deviceController,
+		scheduleController,
+		geofenceController,
+		workflowController,
+		artifactController,
 		websocketService,
 	)
 
@@ -101,21 +325,77 @@ func main() {
 		log.Fatalf("Failed to start WebSocket service: %v", err)
 	}
 
-	// 🚀 启动Kafka服务并注册事件处理器
+	// 🚀 注册Kafka事件处理器并启动Kafka服务
+	eventHandler.Register(kafkaService)
 	if err := kafkaService.Start(context.Background()); err != nil {
 		appLogger.Error("Failed to start Kafka service", map[string]interface{}{"error": err.Error()})
 		log.Fatalf("Failed to start Kafka service: %v", err)
 	}
 
-	// 注册Kafka事件处理器（如果Kafka管理器支持）
-	// 这里需要根据实际的Kafka管理器API来注册
 	appLogger.Info("Event handler initialized", map[string]interface{}{
 		"handler":             "event_handler",
 		"smart_alert_enabled": true,
 	})
 
-	// 使用事件处理器（示例用法）
-	_ = eventHandler
+	// 🚀 启动心跳监控
+	if err := heartbeatMonitor.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start heartbeat monitor", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start heartbeat monitor: %v", err)
+	}
+
+	// 🚀 启动遥测存储后台任务
+	if err := telemetryService.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start telemetry service", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start telemetry service: %v", err)
+	}
+
+	// 🚀 启动任务停滞巡检
+	if err := progressService.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start progress service", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start progress service: %v", err)
+	}
+
+	// 🚀 启动固件发布调度
+	if err := firmwareService.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start firmware service", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start firmware service: %v", err)
+	}
+
+	// 🚀 启动维护管理后台任务
+	if err := maintenanceService.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start maintenance service", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start maintenance service: %v", err)
+	}
+
+	// 🚀 启动任务自动分配巡检
+	if err := assignmentService.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start assignment service", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start assignment service: %v", err)
+	}
+
+	// 🚀 启动任务调度器
+	if err := taskScheduler.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start task scheduler", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start task scheduler: %v", err)
+	}
+
+	// 🚀 启动失败重试巡检
+	if err := retryService.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start retry service", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start retry service: %v", err)
+	}
+
+	// 🚀 启动流程执行器
+	if err := workflowService.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start workflow service", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start workflow service: %v", err)
+	}
+
+	// 🚀 启动任务文件过期清理
+	if err := artifactService.Start(context.Background()); err != nil {
+		appLogger.Error("Failed to start artifact service", map[string]interface{}{"error": err.Error()})
+		log.Fatalf("Failed to start artifact service: %v", err)
+	}
 
 	// 创建HTTP服务器
 	server := &http.Server{
@@ -147,6 +427,56 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// 🛑 停止心跳监控
+	if err := heartbeatMonitor.Stop(); err != nil {
+		appLogger.Error("Error stopping heartbeat monitor", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止遥测存储后台任务
+	if err := telemetryService.Stop(); err != nil {
+		appLogger.Error("Error stopping telemetry service", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止任务停滞巡检
+	if err := progressService.Stop(); err != nil {
+		appLogger.Error("Error stopping progress service", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止固件发布调度
+	if err := firmwareService.Stop(); err != nil {
+		appLogger.Error("Error stopping firmware service", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止任务调度器
+	if err := taskScheduler.Stop(); err != nil {
+		appLogger.Error("Error stopping task scheduler", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止任务文件过期清理
+	if err := artifactService.Stop(); err != nil {
+		appLogger.Error("Error stopping artifact service", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止流程执行器
+	if err := workflowService.Stop(); err != nil {
+		appLogger.Error("Error stopping workflow service", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止失败重试巡检
+	if err := retryService.Stop(); err != nil {
+		appLogger.Error("Error stopping retry service", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止任务自动分配巡检
+	if err := assignmentService.Stop(); err != nil {
+		appLogger.Error("Error stopping assignment service", map[string]interface{}{"error": err.Error()})
+	}
+
+	// 🛑 停止维护管理后台任务
+	if err := maintenanceService.Stop(); err != nil {
+		appLogger.Error("Error stopping maintenance service", map[string]interface{}{"error": err.Error()})
+	}
+
 	// 🛑 停止Kafka服务
 	if err := kafkaService.Stop(); err != nil {
 		appLogger.Error("Error stopping Kafka service", map[string]interface{}{"error": err.Error()})
@@ -169,6 +499,11 @@ func main() {
 	} else {
 		appLogger.Info("Server shutdown completed")
 	}
+
+	// 🛑 关闭数据库连接
+	if err := dbManager.Shutdown(); err != nil {
+		appLogger.Error("Error closing database connections", map[string]interface{}{"error": err.Error()})
+	}
 }
 
 // loadConfig 加载配置
@@ -180,6 +515,108 @@ func loadConfig() (*viper.Viper, error) {
 	config.SetDefault("logging.level", "info")
 	config.SetDefault("logging.format", "json")
 	config.SetDefault("logging.output", "stdout")
+	config.SetDefault("monitor.heartbeat.timeout", "30s")
+	config.SetDefault("monitor.heartbeat.scan_interval", "5s")
+	config.SetDefault("monitor.heartbeat.lock_ttl", "15s")
+	config.SetDefault("monitor.geo.stale_after", "2m")
+	config.SetDefault("telemetry.raw_retention", "168h")
+	config.SetDefault("telemetry.minute_retention", "2160h")
+	config.SetDefault("telemetry.hour_retention", "17520h")
+	config.SetDefault("telemetry.downsample_interval", "1m")
+	config.SetDefault("telemetry.downsample_lag", "2m")
+	config.SetDefault("telemetry.partition_ahead", "72h")
+	config.SetDefault("telemetry.lock_ttl", "2m")
+	config.SetDefault("progress.arrival_radius", 10.0)
+	config.SetDefault("progress.min_progress_distance", 10.0)
+	config.SetDefault("progress.default_speed", 10.0)
+	config.SetDefault("progress.stall_timeout", "3m")
+	config.SetDefault("progress.check_interval", "30s")
+	config.SetDefault("progress.lock_ttl", "1m")
+	config.SetDefault("firmware.scan_interval", "15s")
+	config.SetDefault("firmware.dispatch_timeout", "30m")
+	config.SetDefault("firmware.wait_timeout", "2h")
+	config.SetDefault("firmware.lock_ttl", "1m")
+	config.SetDefault("maintenance.check_interval", "5m")
+	config.SetDefault("maintenance.usage_lag", "10m")
+	config.SetDefault("maintenance.airborne_altitude", 2.0)
+	config.SetDefault("maintenance.lock_ttl", "2m")
+	config.SetDefault("battery.low_health_threshold", 80.0)
+	config.SetDefault("battery.critical_health_threshold", 70.0)
+	config.SetDefault("device.rotation_grace_period", "24h")
+	config.SetDefault("device.command_ttl", "10m")
+	config.SetDefault("device.fingerprint_header", "")
+	config.SetDefault("scheduler.check_interval", "30s")
+	config.SetDefault("scheduler.missed_run_tolerance", "5m")
+	config.SetDefault("scheduler.start_retry_window", "15m")
+	config.SetDefault("scheduler.max_catch_up_runs", 10)
+	config.SetDefault("scheduler.default_policy", "run_once")
+	config.SetDefault("scheduler.lock_ttl", "1m")
+	config.SetDefault("workflow.check_interval", "15s")
+	config.SetDefault("workflow.start_retry_window", "15m")
+	config.SetDefault("workflow.lock_ttl", "1m")
+	config.SetDefault("retry.check_interval", "30s")
+	config.SetDefault("retry.look_back", "24h")
+	config.SetDefault("retry.waypoint_radius", 15.0)
+	config.SetDefault("retry.lock_ttl", "1m")
+	config.SetDefault("assignment.distance_weight", 0.4)
+	config.SetDefault("assignment.battery_weight", 0.4)
+	config.SetDefault("assignment.workload_weight", 0.2)
+	config.SetDefault("assignment.max_distance", 20000.0)
+	config.SetDefault("assignment.check_interval", "1m")
+	config.SetDefault("assignment.lock_ttl", "2m")
+	config.SetDefault("geofence.approach_distance", 200.0)
+	config.SetDefault("geofence.cache_ttl", "30s")
+	config.SetDefault("energy.cruise_speed", 10.0)
+	config.SetDefault("energy.climb_speed", 3.0)
+	config.SetDefault("energy.descent_speed", 2.0)
+	config.SetDefault("energy.default_flight_time", 25)
+	config.SetDefault("energy.battery_reserve", 20)
+	config.SetDefault("energy.climb_power_factor", 1.5)
+	config.SetDefault("energy.descent_power_factor", 0.8)
+	config.SetDefault("energy.hover_power_factor", 1.1)
+	config.SetDefault("energy.wind_power_factor", 0.3)
+	config.SetDefault("energy.max_wind_speed", 12.0)
+	config.SetDefault("energy.payload_power_factor", 0.3)
+	config.SetDefault("energy.default_payload_capacity", 2.0)
+	config.SetDefault("energy.default_margin", 0.15)
+	config.SetDefault("energy.min_margin", 3.0)
+	config.SetDefault("energy.min_samples", 5)
+	config.SetDefault("energy.calibration_weight", 0.2)
+	config.SetDefault("energy.min_calibration_usage", 5.0)
+	config.SetDefault("energy.cache_ttl", "5m")
+	config.SetDefault("preflight.battery_warn_margin", 10)
+	config.SetDefault("preflight.max_wind_speed", 12.0)
+	config.SetDefault("preflight.wind_warn_ratio", 0.8)
+	config.SetDefault("preflight.min_visibility", 1.0)
+	config.SetDefault("preflight.warn_visibility", 3.0)
+	config.SetDefault("preflight.require_weather", false)
+	config.SetDefault("preflight.maintenance_warn", 0.9)
+	config.SetDefault("preflight.override_ttl", "30m")
+	config.SetDefault("route.safety_buffer", 30.0)
+	config.SetDefault("route.vertical_buffer", 10.0)
+	config.SetDefault("route.max_altitude", 120.0)
+	config.SetDefault("route.circle_segments", 32)
+	config.SetDefault("route.max_nodes", 2000)
+	config.SetDefault("coverage.default_speed", 8.0)
+	config.SetDefault("coverage.turn_margin", 15.0)
+	config.SetDefault("coverage.turn_time", 8.0)
+	config.SetDefault("coverage.max_drones", 10)
+	config.SetDefault("delivery.cruise_altitude", 60.0)
+	config.SetDefault("delivery.handoff_altitude", 10.0)
+	config.SetDefault("delivery.handoff_hold_time", 30.0)
+	config.SetDefault("delivery.proof_radius", 50.0)
+	config.SetDefault("delivery.proof_max_age", "10m")
+	config.SetDefault("artifact.storage_dir", "./data/artifacts")
+	config.SetDefault("artifact.max_size", "2GB")
+	config.SetDefault("artifact.retention", "2160h")
+	config.SetDefault("artifact.upload_ttl", "24h")
+	config.SetDefault("artifact.signing_secret", "")
+	config.SetDefault("artifact.url_ttl", "15m")
+	config.SetDefault("artifact.public_base_url", "")
+	config.SetDefault("artifact.cleanup_interval", "10m")
+	config.SetDefault("artifact.lock_ttl", "2m")
+	config.SetDefault("approval.allow_self_approval", false)
+	config.SetDefault("approval.window_step", "10m")
 
 	// 设置配置文件
 	config.SetConfigName("config")
@@ -201,6 +638,281 @@ func loadConfig() (*viper.Viper, error) {
 	return config, nil
 }
 
+// initDatabase 初始化数据库连接并执行迁移
+func initDatabase(config *viper.Viper) (*database.DatabaseManager, error) {
+	mysqlConfig := database.DefaultConfig()
+	if config.IsSet("database.mysql.host") {
+		mysqlConfig = database.Config{
+			Host:            config.GetString("database.mysql.host"),
+			Port:            config.GetInt("database.mysql.port"),
+			User:            config.GetString("database.mysql.user"),
+			Password:        config.GetString("database.mysql.password"),
+			DBName:          config.GetString("database.mysql.dbname"),
+			Charset:         config.GetString("database.mysql.charset"),
+			ParseTime:       config.GetBool("database.mysql.parse_time"),
+			Loc:             config.GetString("database.mysql.loc"),
+			MaxOpenConns:    config.GetInt("database.mysql.max_open_conns"),
+			MaxIdleConns:    config.GetInt("database.mysql.max_idle_conns"),
+			ConnMaxLifetime: config.GetDuration("database.mysql.conn_max_lifetime"),
+			ConnMaxIdleTime: config.GetDuration("database.mysql.conn_max_idle_time"),
+			LogLevel:        config.GetString("database.mysql.log_level"),
+		}
+	}
+
+	redisConfig := database.DefaultRedisConfig()
+	if config.IsSet("database.redis.addr") {
+		redisConfig = database.RedisConfig{
+			Addr:         config.GetString("database.redis.addr"),
+			Password:     config.GetString("database.redis.password"),
+			DB:           config.GetInt("database.redis.db"),
+			PoolSize:     config.GetInt("database.redis.pool_size"),
+			MinIdleConns: config.GetInt("database.redis.min_idle_conns"),
+			DialTimeout:  config.GetDuration("database.redis.dial_timeout"),
+			ReadTimeout:  config.GetDuration("database.redis.read_timeout"),
+			WriteTimeout: config.GetDuration("database.redis.write_timeout"),
+			PoolTimeout:  config.GetDuration("database.redis.pool_timeout"),
+			IdleTimeout:  config.GetDuration("database.redis.idle_timeout"),
+		}
+	}
+
+	dbManager, err := database.NewDatabaseManager(mysqlConfig, redisConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dbManager.Initialize(); err != nil {
+		dbManager.Shutdown()
+		return nil, err
+	}
+
+	return dbManager, nil
+}
+
+// loadHeartbeatConfig 加载心跳监控配置
+func loadHeartbeatConfig(config *viper.Viper) *services.HeartbeatConfig {
+	return &services.HeartbeatConfig{
+		Timeout:      config.GetDuration("monitor.heartbeat.timeout"),
+		ScanInterval: config.GetDuration("monitor.heartbeat.scan_interval"),
+		LockTTL:      config.GetDuration("monitor.heartbeat.lock_ttl"),
+	}
+}
+
+// loadGeoIndexConfig 加载实时位置索引配置
+func loadGeoIndexConfig(config *viper.Viper) *services.GeoIndexConfig {
+	return &services.GeoIndexConfig{
+		StaleAfter: config.GetDuration("monitor.geo.stale_after"),
+	}
+}
+
+// loadTelemetryConfig 加载遥测存储配置
+func loadTelemetryConfig(config *viper.Viper) *services.TelemetryConfig {
+	return &services.TelemetryConfig{
+		RawRetention:       config.GetDuration("telemetry.raw_retention"),
+		MinuteRetention:    config.GetDuration("telemetry.minute_retention"),
+		HourRetention:      config.GetDuration("telemetry.hour_retention"),
+		DownsampleInterval: config.GetDuration("telemetry.downsample_interval"),
+		DownsampleLag:      config.GetDuration("telemetry.downsample_lag"),
+		PartitionAhead:     config.GetDuration("telemetry.partition_ahead"),
+		LockTTL:            config.GetDuration("telemetry.lock_ttl"),
+	}
+}
+
+// loadProgressConfig 加载任务进度推算配置
+func loadProgressConfig(config *viper.Viper) *services.ProgressConfig {
+	return &services.ProgressConfig{
+		ArrivalRadius:       config.GetFloat64("progress.arrival_radius"),
+		MinProgressDistance: config.GetFloat64("progress.min_progress_distance"),
+		DefaultSpeed:        config.GetFloat64("progress.default_speed"),
+		StallTimeout:        config.GetDuration("progress.stall_timeout"),
+		CheckInterval:       config.GetDuration("progress.check_interval"),
+		LockTTL:             config.GetDuration("progress.lock_ttl"),
+	}
+}
+
+// loadFirmwareConfig 加载固件发布配置
+func loadFirmwareConfig(config *viper.Viper) *services.FirmwareConfig {
+	return &services.FirmwareConfig{
+		ScanInterval:    config.GetDuration("firmware.scan_interval"),
+		DispatchTimeout: config.GetDuration("firmware.dispatch_timeout"),
+		WaitTimeout:     config.GetDuration("firmware.wait_timeout"),
+		LockTTL:         config.GetDuration("firmware.lock_ttl"),
+	}
+}
+
+// loadMaintenanceConfig 加载维护管理配置
+func loadMaintenanceConfig(config *viper.Viper) *services.MaintenanceConfig {
+	return &services.MaintenanceConfig{
+		CheckInterval:    config.GetDuration("maintenance.check_interval"),
+		UsageLag:         config.GetDuration("maintenance.usage_lag"),
+		AirborneAltitude: config.GetFloat64("maintenance.airborne_altitude"),
+		LockTTL:          config.GetDuration("maintenance.lock_ttl"),
+	}
+}
+
+// loadBatteryConfig 加载电池组管理配置
+func loadBatteryConfig(config *viper.Viper) *services.BatteryConfig {
+	return &services.BatteryConfig{
+		LowHealthThreshold:      config.GetFloat64("battery.low_health_threshold"),
+		CriticalHealthThreshold: config.GetFloat64("battery.critical_health_threshold"),
+	}
+}
+
+// loadDeviceConfig 加载设备接入配置
+func loadDeviceConfig(config *viper.Viper) *services.DeviceConfig {
+	return &services.DeviceConfig{
+		RotationGracePeriod: config.GetDuration("device.rotation_grace_period"),
+		CommandTTL:          config.GetDuration("device.command_ttl"),
+		FingerprintHeader:   config.GetString("device.fingerprint_header"),
+	}
+}
+
+// loadSchedulerConfig 加载任务调度配置
+func loadSchedulerConfig(config *viper.Viper) *services.SchedulerConfig {
+	return &services.SchedulerConfig{
+		CheckInterval:      config.GetDuration("scheduler.check_interval"),
+		MissedRunTolerance: config.GetDuration("scheduler.missed_run_tolerance"),
+		StartRetryWindow:   config.GetDuration("scheduler.start_retry_window"),
+		MaxCatchUpRuns:     config.GetInt("scheduler.max_catch_up_runs"),
+		DefaultPolicy:      models.MissedRunPolicy(config.GetString("scheduler.default_policy")),
+		LockTTL:            config.GetDuration("scheduler.lock_ttl"),
+	}
+}
+
+// loadWorkflowConfig 加载流程执行配置
+func loadWorkflowConfig(config *viper.Viper) *services.WorkflowConfig {
+	return &services.WorkflowConfig{
+		CheckInterval:    config.GetDuration("workflow.check_interval"),
+		StartRetryWindow: config.GetDuration("workflow.start_retry_window"),
+		LockTTL:          config.GetDuration("workflow.lock_ttl"),
+	}
+}
+
+// loadRetryConfig 加载任务失败重试配置
+func loadRetryConfig(config *viper.Viper) *services.RetryConfig {
+	return &services.RetryConfig{
+		CheckInterval:  config.GetDuration("retry.check_interval"),
+		LookBack:       config.GetDuration("retry.look_back"),
+		WaypointRadius: config.GetFloat64("retry.waypoint_radius"),
+		LockTTL:        config.GetDuration("retry.lock_ttl"),
+	}
+}
+
+// loadAssignmentConfig 加载任务自动分配配置
+func loadAssignmentConfig(config *viper.Viper) *services.AssignmentConfig {
+	return &services.AssignmentConfig{
+		DistanceWeight: config.GetFloat64("assignment.distance_weight"),
+		BatteryWeight:  config.GetFloat64("assignment.battery_weight"),
+		WorkloadWeight: config.GetFloat64("assignment.workload_weight"),
+		MaxDistance:    config.GetFloat64("assignment.max_distance"),
+		CheckInterval:  config.GetDuration("assignment.check_interval"),
+		LockTTL:        config.GetDuration("assignment.lock_ttl"),
+	}
+}
+
+// loadGeofenceConfig 加载地理围栏配置
+func loadGeofenceConfig(config *viper.Viper) *services.GeofenceConfig {
+	return &services.GeofenceConfig{
+		ApproachDistance: config.GetFloat64("geofence.approach_distance"),
+		CacheTTL:         config.GetDuration("geofence.cache_ttl"),
+	}
+}
+
+// loadEnergyConfig 加载能耗估算配置
+func loadEnergyConfig(config *viper.Viper) *services.EnergyConfig {
+	return &services.EnergyConfig{
+		CruiseSpeed:            config.GetFloat64("energy.cruise_speed"),
+		ClimbSpeed:             config.GetFloat64("energy.climb_speed"),
+		DescentSpeed:           config.GetFloat64("energy.descent_speed"),
+		DefaultFlightTime:      config.GetInt("energy.default_flight_time"),
+		BatteryReserve:         config.GetInt("energy.battery_reserve"),
+		ClimbPowerFactor:       config.GetFloat64("energy.climb_power_factor"),
+		DescentPowerFactor:     config.GetFloat64("energy.descent_power_factor"),
+		HoverPowerFactor:       config.GetFloat64("energy.hover_power_factor"),
+		WindPowerFactor:        config.GetFloat64("energy.wind_power_factor"),
+		MaxWindSpeed:           config.GetFloat64("energy.max_wind_speed"),
+		PayloadPowerFactor:     config.GetFloat64("energy.payload_power_factor"),
+		DefaultPayloadCapacity: config.GetFloat64("energy.default_payload_capacity"),
+		DefaultMargin:          config.GetFloat64("energy.default_margin"),
+		MinMargin:              config.GetFloat64("energy.min_margin"),
+		MinSamples:             config.GetInt("energy.min_samples"),
+		CalibrationWeight:      config.GetFloat64("energy.calibration_weight"),
+		MinCalibrationUsage:    config.GetFloat64("energy.min_calibration_usage"),
+		CacheTTL:               config.GetDuration("energy.cache_ttl"),
+	}
+}
+
+// loadPreflightConfig 加载起飞前检查配置
+func loadPreflightConfig(config *viper.Viper) *services.PreflightConfig {
+	return &services.PreflightConfig{
+		BatteryWarnMargin: config.GetInt("preflight.battery_warn_margin"),
+		MaxWindSpeed:      config.GetFloat64("preflight.max_wind_speed"),
+		WindWarnRatio:     config.GetFloat64("preflight.wind_warn_ratio"),
+		MinVisibility:     config.GetFloat64("preflight.min_visibility"),
+		WarnVisibility:    config.GetFloat64("preflight.warn_visibility"),
+		RequireWeather:    config.GetBool("preflight.require_weather"),
+		MaintenanceWarn:   config.GetFloat64("preflight.maintenance_warn"),
+		OverrideTTL:       config.GetDuration("preflight.override_ttl"),
+	}
+}
+
+// loadRouteConfig 加载航线规划配置
+func loadRouteConfig(config *viper.Viper) *services.RouteConfig {
+	return &services.RouteConfig{
+		SafetyBuffer:   config.GetFloat64("route.safety_buffer"),
+		VerticalBuffer: config.GetFloat64("route.vertical_buffer"),
+		MaxAltitude:    config.GetFloat64("route.max_altitude"),
+		CircleSegments: config.GetInt("route.circle_segments"),
+		MaxNodes:       config.GetInt("route.max_nodes"),
+	}
+}
+
+// loadCoverageConfig 加载测绘覆盖规划配置
+func loadCoverageConfig(config *viper.Viper) *services.CoverageConfig {
+	return &services.CoverageConfig{
+		DefaultSpeed: config.GetFloat64("coverage.default_speed"),
+		TurnMargin:   config.GetFloat64("coverage.turn_margin"),
+		TurnTime:     config.GetFloat64("coverage.turn_time"),
+		MaxDrones:    config.GetInt("coverage.max_drones"),
+	}
+}
+
+// loadDeliveryConfig 加载配送任务配置
+func loadDeliveryConfig(config *viper.Viper) *services.DeliveryConfig {
+	return &services.DeliveryConfig{
+		CruiseAltitude:  config.GetFloat64("delivery.cruise_altitude"),
+		HandoffAltitude: config.GetFloat64("delivery.handoff_altitude"),
+		HandoffHoldTime: config.GetFloat64("delivery.handoff_hold_time"),
+		ProofRadius:     config.GetFloat64("delivery.proof_radius"),
+		ProofMaxAge:     config.GetDuration("delivery.proof_max_age"),
+	}
+}
+
+// loadArtifactConfig 加载任务文件配置，未配置签名密钥时使用JWT密钥
+func loadArtifactConfig(config *viper.Viper) *services.ArtifactConfig {
+	secret := config.GetString("artifact.signing_secret")
+	if secret == "" {
+		secret = config.GetString("jwt.secret")
+	}
+	return &services.ArtifactConfig{
+		MaxSize:         int64(config.GetSizeInBytes("artifact.max_size")),
+		Retention:       config.GetDuration("artifact.retention"),
+		UploadTTL:       config.GetDuration("artifact.upload_ttl"),
+		SigningSecret:   secret,
+		URLTTL:          config.GetDuration("artifact.url_ttl"),
+		PublicBaseURL:   config.GetString("artifact.public_base_url"),
+		CleanupInterval: config.GetDuration("artifact.cleanup_interval"),
+		LockTTL:         config.GetDuration("artifact.lock_ttl"),
+	}
+}
+
+// loadApprovalConfig 加载任务审批配置
+func loadApprovalConfig(config *viper.Viper) *services.ApprovalConfig {
+	return &services.ApprovalConfig{
+		AllowSelfApproval: config.GetBool("approval.allow_self_approval"),
+		WindowStep:        config.GetDuration("approval.window_step"),
+	}
+}
+
 // Mock服务实现（示例）
 type MockUserService struct{}
 
@@ -286,6 +998,90 @@ func (m *MockDroneService) UpdateDroneBattery(ctx context.Context, id uint, batt
 	return fmt.Errorf("not implemented")
 }
 
-func (m *MockDroneService) GetAvailableDrones(ctx context.Context) ([]*models.Drone, error) {
+func (m *MockDroneService) GetAvailableDrones(ctx context.Context, filter *models.CapabilityRequirements) ([]*models.Drone, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+type MockTaskService struct{}
+
+func (m *MockTaskService) CreateTask(ctx context.Context, params *services.CreateTaskParams) (*models.Task, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) GetTaskByID(ctx context.Context, id uint) (*models.Task, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) UpdateTask(ctx context.Context, id uint, params *services.UpdateTaskParams) (*models.Task, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) DeleteTask(ctx context.Context, id uint) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) ListTasks(ctx context.Context, params *services.ListTasksParams) ([]*models.Task, int64, error) {
+	return nil, 0, fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) StartTask(ctx context.Context, id uint) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) StopTask(ctx context.Context, id uint) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) UpdateTaskProgress(ctx context.Context, id uint, progress int) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) CompleteTask(ctx context.Context, id uint, success bool, message string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) GetTasksByUser(ctx context.Context, userID uint, params *services.ListTasksParams) ([]*models.Task, int64, error) {
+	return nil, 0, fmt.Errorf("not implemented")
+}
+
+func (m *MockTaskService) GetTasksByDrone(ctx context.Context, droneID uint, params *services.ListTasksParams) ([]*models.Task, int64, error) {
+	return nil, 0, fmt.Errorf("not implemented")
+}
+
+type MockAlertService struct{}
+
+func (m *MockAlertService) CreateAlert(ctx context.Context, params *services.CreateAlertParams) (*models.Alert, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockAlertService) GetAlertByID(ctx context.Context, id uint) (*models.Alert, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockAlertService) UpdateAlert(ctx context.Context, id uint, params *services.UpdateAlertParams) (*models.Alert, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockAlertService) DeleteAlert(ctx context.Context, id uint) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockAlertService) ListAlerts(ctx context.Context, params *services.ListAlertsParams) ([]*models.Alert, int64, error) {
+	return nil, 0, fmt.Errorf("not implemented")
+}
+
+func (m *MockAlertService) AcknowledgeAlert(ctx context.Context, id uint, userID uint) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockAlertService) ResolveAlert(ctx context.Context, id uint, userID uint) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *MockAlertService) GetActiveAlerts(ctx context.Context) ([]*models.Alert, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *MockAlertService) GetAlertsByDrone(ctx context.Context, droneID uint) ([]*models.Alert, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/logger"
+	"drone-control-system/pkg/trackexport"
+
+	"github.com/spf13/viper"
+)
+
+func main() {
+	var (
+		configPath = flag.String("config", "./configs/config.yaml", "配置文件路径")
+		droneID    = flag.Uint("drone", 0, "无人机ID（导出时间窗口内的实际轨迹）")
+		taskID     = flag.Uint("task", 0, "任务ID（导出计划航线和实际轨迹）")
+		from       = flag.String("from", "", "起始时间 RFC3339，默认结束时间前1小时")
+		to         = flag.String("to", "", "结束时间 RFC3339，默认当前时间")
+		resolution = flag.String("resolution", "", "数据精度: raw, 1m, 1h，默认自动选择")
+		format     = flag.String("format", "gpx", "导出格式: gpx, kml, geojson")
+		output     = flag.String("output", "", "输出文件路径，默认使用导出文件名，- 表示标准输出")
+	)
+	flag.Parse()
+
+	if (*droneID == 0) == (*taskID == 0) {
+		log.Fatal("必须且只能指定 -drone 或 -task 之一")
+	}
+
+	exportFormat, err := trackexport.ParseFormat(*format)
+	if err != nil {
+		log.Fatalf("导出格式错误: %v", err)
+	}
+
+	res := models.TelemetryResolution(*resolution)
+	if res != "" && !res.IsValid() {
+		log.Fatalf("数据精度错误: %s", *resolution)
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	mysqlConfig := database.Config{
+		Host:            config.GetString("database.mysql.host"),
+		Port:            config.GetInt("database.mysql.port"),
+		User:            config.GetString("database.mysql.user"),
+		Password:        config.GetString("database.mysql.password"),
+		DBName:          config.GetString("database.mysql.dbname"),
+		Charset:         config.GetString("database.mysql.charset"),
+		ParseTime:       config.GetBool("database.mysql.parse_time"),
+		Loc:             config.GetString("database.mysql.loc"),
+		MaxOpenConns:    config.GetInt("database.mysql.max_open_conns"),
+		MaxIdleConns:    config.GetInt("database.mysql.max_idle_conns"),
+		ConnMaxLifetime: config.GetDuration("database.mysql.conn_max_lifetime"),
+		ConnMaxIdleTime: config.GetDuration("database.mysql.conn_max_idle_time"),
+		LogLevel:        config.GetString("database.mysql.log_level"),
+	}
+
+	db, err := database.NewMySQLConnection(mysqlConfig)
+	if err != nil {
+		log.Fatalf("连接数据库失败: %v", err)
+	}
+
+	appLogger := logger.NewLogger(logger.Config{Level: "warn", Format: "text", Output: "stderr"})
+
+	// 只读查询，不启动降采样后台任务，因此无需Redis
+	telemetryService := services.NewTelemetryService(services.DefaultTelemetryConfig(), db, nil, nil, appLogger)
+	exportService := services.NewTrackExportService(db, telemetryService)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var export *services.TrackExport
+	if *taskID != 0 {
+		export, err = exportService.ExportTaskTrack(ctx, uint(*taskID), res, exportFormat)
+	} else {
+		params := &services.TrackQueryParams{
+			DroneID:    uint(*droneID),
+			Resolution: res,
+		}
+		if params.From, err = parseTime(*from); err != nil {
+			log.Fatalf("起始时间格式错误: %v", err)
+		}
+		if params.To, err = parseTime(*to); err != nil {
+			log.Fatalf("结束时间格式错误: %v", err)
+		}
+		export, err = exportService.ExportDroneTrack(ctx, params, exportFormat)
+	}
+	if err != nil {
+		log.Fatalf("导出轨迹失败: %v", err)
+	}
+
+	if *output == "-" {
+		if _, err := os.Stdout.Write(export.Data); err != nil {
+			log.Fatalf("写入标准输出失败: %v", err)
+		}
+		return
+	}
+
+	path := *output
+	if path == "" {
+		path = export.Filename
+	}
+	if err := os.WriteFile(path, export.Data, 0644); err != nil {
+		log.Fatalf("写入文件失败: %v", err)
+	}
+	log.Printf("轨迹已导出: %s (%d bytes)", path, len(export.Data))
+}
+
+// parseTime 解析RFC3339时间，空字符串返回零值
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func loadConfig(configPath string) (*viper.Viper, error) {
+	config := viper.New()
+	config.SetConfigFile(configPath)
+	config.SetConfigType("yaml")
+
+	// 设置默认值
+	config.SetDefault("database.mysql.host", "localhost")
+	config.SetDefault("database.mysql.port", 3306)
+	config.SetDefault("database.mysql.user", "root")
+	config.SetDefault("database.mysql.password", "password")
+	config.SetDefault("database.mysql.dbname", "drone_control")
+	config.SetDefault("database.mysql.charset", "utf8mb4")
+	config.SetDefault("database.mysql.parse_time", true)
+	config.SetDefault("database.mysql.loc", "Local")
+	config.SetDefault("database.mysql.max_open_conns", 10)
+	config.SetDefault("database.mysql.max_idle_conns", 2)
+	config.SetDefault("database.mysql.conn_max_lifetime", "1h")
+	config.SetDefault("database.mysql.conn_max_idle_time", "30m")
+	config.SetDefault("database.mysql.log_level", "warn")
+
+	if err := config.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+			log.Printf("配置文件不存在，使用默认配置: %s", configPath)
+		} else {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,515 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/blobstore"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFormFieldSize 上传表单中普通字段的最大长度
+const maxFormFieldSize = 1024
+
+// ArtifactController 任务文件控制器
+type ArtifactController struct {
+	*BaseController
+	*fleetGuard
+	taskService     services.TaskService
+	artifactService services.ArtifactService
+}
+
+// NewArtifactController 创建任务文件控制器
+func NewArtifactController(
+	logger *logger.Logger,
+	taskService services.TaskService,
+	artifactService services.ArtifactService,
+	fleetService services.FleetService,
+) *ArtifactController {
+	base := NewBaseController(logger)
+	return &ArtifactController{
+		BaseController:  base,
+		fleetGuard:      newFleetGuard(base, fleetService),
+		taskService:     taskService,
+		artifactService: artifactService,
+	}
+}
+
+// CreateArtifactUploadRequest 创建断点续传上传请求
+type CreateArtifactUploadRequest struct {
+	FileName    string              `json:"file_name" binding:"required,max=255"`
+	Size        int64               `json:"size" binding:"required,min=1"`
+	Kind        models.ArtifactKind `json:"kind" binding:"omitempty,oneof=photo video file"`
+	ContentType string              `json:"content_type" binding:"omitempty,max=100"`
+	Checksum    string              `json:"checksum" binding:"omitempty,len=64"`
+}
+
+// UploadArtifact 上传任务文件（multipart/form-data）
+// 可选字段 kind、checksum 需要位于文件之前，文件字段名为 file；内容边接收边写入存储，不在内存中缓存
+func (ac *ArtifactController) UploadArtifact(c *gin.Context) {
+	userID, err := ac.GetUserID(c)
+	if err != nil {
+		ac.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	taskID, ok := ac.authorizeTask(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		ac.BadRequest(c, "request must be multipart/form-data")
+		return
+	}
+
+	params := &services.UploadArtifactParams{TaskID: taskID, UserID: userID}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			ac.BadRequest(c, "file is required")
+			return
+		}
+		if err != nil {
+			ac.BadRequest(c, "invalid multipart body")
+			return
+		}
+
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize))
+			part.Close()
+			if err != nil {
+				ac.BadRequest(c, "invalid multipart body")
+				return
+			}
+			switch part.FormName() {
+			case "kind":
+				params.Kind = models.ArtifactKind(strings.TrimSpace(string(value)))
+			case "checksum":
+				params.Checksum = string(value)
+			}
+			continue
+		}
+
+		params.FileName = part.FileName()
+		params.ContentType = part.Header.Get("Content-Type")
+		artifact, err := ac.artifactService.Upload(c.Request.Context(), params, part)
+		part.Close()
+		if err != nil {
+			if ac.handleArtifactError(c, err) {
+				return
+			}
+			ac.LogError("UploadArtifact", err, map[string]interface{}{"task_id": taskID})
+			ac.InternalError(c, "failed to upload artifact")
+			return
+		}
+
+		ac.LogInfo("UploadArtifact", map[string]interface{}{
+			"task_id":     taskID,
+			"artifact_id": artifact.ID,
+			"size":        artifact.Size,
+		})
+		ac.Success(c, artifact)
+		return
+	}
+}
+
+// CreateArtifactUpload 创建断点续传上传
+func (ac *ArtifactController) CreateArtifactUpload(c *gin.Context) {
+	userID, err := ac.GetUserID(c)
+	if err != nil {
+		ac.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	taskID, ok := ac.authorizeTask(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+
+	var req CreateArtifactUploadRequest
+	if err := ac.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	upload, err := ac.artifactService.CreateUpload(c.Request.Context(), &services.CreateArtifactUploadParams{
+		TaskID:      taskID,
+		Kind:        req.Kind,
+		FileName:    req.FileName,
+		ContentType: req.ContentType,
+		Size:        req.Size,
+		Checksum:    req.Checksum,
+		UserID:      userID,
+	})
+	if err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("CreateArtifactUpload", err, map[string]interface{}{"task_id": taskID})
+		ac.InternalError(c, "failed to create upload")
+		return
+	}
+
+	c.Header("Upload-Offset", "0")
+	ac.Success(c, upload)
+}
+
+// GetArtifactUpload 获取上传状态，Upload-Offset 响应头为已接收的字节数
+func (ac *ArtifactController) GetArtifactUpload(c *gin.Context) {
+	taskID, ok := ac.authorizeTask(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+	uploadID, err := ac.ParseID(c, "upload_id")
+	if err != nil {
+		ac.BadRequest(c, "invalid upload ID")
+		return
+	}
+
+	upload, err := ac.artifactService.GetUpload(c.Request.Context(), taskID, uploadID)
+	if err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("GetArtifactUpload", err, map[string]interface{}{"upload_id": uploadID})
+		ac.InternalError(c, "failed to get upload")
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	ac.Success(c, upload)
+}
+
+// AppendArtifactUpload 追加上传数据
+// 请求头 Upload-Offset 为本段在文件中的起始位置，请求体为原始字节；
+// 位置与已接收字节数不一致时返回409和当前位置，客户端从该位置继续
+func (ac *ArtifactController) AppendArtifactUpload(c *gin.Context) {
+	taskID, ok := ac.authorizeTask(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+	uploadID, err := ac.ParseID(c, "upload_id")
+	if err != nil {
+		ac.BadRequest(c, "invalid upload ID")
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		ac.BadRequest(c, "Upload-Offset header is required")
+		return
+	}
+
+	upload, err := ac.artifactService.GetUpload(c.Request.Context(), taskID, uploadID)
+	if err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("GetArtifactUpload", err, map[string]interface{}{"upload_id": uploadID})
+		ac.InternalError(c, "failed to get upload")
+		return
+	}
+	if c.Request.ContentLength > 0 && offset+c.Request.ContentLength > upload.Size {
+		ac.BadRequest(c, "chunk exceeds declared upload size")
+		return
+	}
+
+	upload, artifact, err := ac.artifactService.AppendUpload(c.Request.Context(), taskID, uploadID, offset, c.Request.Body)
+	if upload != nil {
+		c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	}
+	if err != nil {
+		if errors.Is(err, services.ErrUploadOffset) && upload != nil {
+			c.JSON(http.StatusConflict, Response{
+				Code:    http.StatusConflict,
+				Message: "upload offset mismatch",
+				Data:    gin.H{"offset": upload.Offset},
+				Time:    time.Now().Unix(),
+			})
+			return
+		}
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("AppendArtifactUpload", err, map[string]interface{}{
+			"upload_id": uploadID,
+			"offset":    offset,
+		})
+		ac.InternalError(c, "failed to append upload")
+		return
+	}
+
+	if artifact != nil {
+		ac.LogInfo("UploadArtifact", map[string]interface{}{
+			"task_id":     taskID,
+			"artifact_id": artifact.ID,
+			"size":        artifact.Size,
+		})
+	}
+	ac.Success(c, gin.H{
+		"upload":   upload,
+		"complete": artifact != nil,
+		"artifact": artifact,
+	})
+}
+
+// CancelArtifactUpload 取消上传
+func (ac *ArtifactController) CancelArtifactUpload(c *gin.Context) {
+	taskID, ok := ac.authorizeTask(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+	uploadID, err := ac.ParseID(c, "upload_id")
+	if err != nil {
+		ac.BadRequest(c, "invalid upload ID")
+		return
+	}
+
+	if err := ac.artifactService.CancelUpload(c.Request.Context(), taskID, uploadID); err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("CancelArtifactUpload", err, map[string]interface{}{"upload_id": uploadID})
+		ac.InternalError(c, "failed to cancel upload")
+		return
+	}
+	ac.Success(c, gin.H{"message": "upload cancelled"})
+}
+
+// ListArtifacts 获取任务文件列表
+// 查询参数: kind (photo/video/file)
+func (ac *ArtifactController) ListArtifacts(c *gin.Context) {
+	taskID, ok := ac.authorizeTask(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	kind := models.ArtifactKind(c.Query("kind"))
+	if kind != "" && !kind.IsValid() {
+		ac.BadRequest(c, "invalid artifact kind")
+		return
+	}
+
+	artifacts, err := ac.artifactService.ListArtifacts(c.Request.Context(), taskID, kind)
+	if err != nil {
+		ac.LogError("ListArtifacts", err, map[string]interface{}{"task_id": taskID})
+		ac.InternalError(c, "failed to list artifacts")
+		return
+	}
+
+	ac.Success(c, gin.H{
+		"artifacts": artifacts,
+		"count":     len(artifacts),
+	})
+}
+
+// GetArtifact 获取任务文件信息
+func (ac *ArtifactController) GetArtifact(c *gin.Context) {
+	taskID, ok := ac.authorizeTask(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+	artifactID, err := ac.ParseID(c, "artifact_id")
+	if err != nil {
+		ac.BadRequest(c, "invalid artifact ID")
+		return
+	}
+
+	artifact, err := ac.artifactService.GetArtifact(c.Request.Context(), taskID, artifactID)
+	if err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("GetArtifact", err, map[string]interface{}{"artifact_id": artifactID})
+		ac.InternalError(c, "failed to get artifact")
+		return
+	}
+	ac.Success(c, artifact)
+}
+
+// DownloadArtifact 下载任务文件，支持 Range 请求
+func (ac *ArtifactController) DownloadArtifact(c *gin.Context) {
+	taskID, ok := ac.authorizeTask(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+	artifactID, err := ac.ParseID(c, "artifact_id")
+	if err != nil {
+		ac.BadRequest(c, "invalid artifact ID")
+		return
+	}
+
+	artifact, object, err := ac.artifactService.OpenArtifact(c.Request.Context(), taskID, artifactID)
+	if err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("DownloadArtifact", err, map[string]interface{}{"artifact_id": artifactID})
+		ac.InternalError(c, "failed to open artifact")
+		return
+	}
+	ac.serveArtifact(c, artifact, object)
+}
+
+// GetArtifactURL 生成有时效的免登录下载链接
+func (ac *ArtifactController) GetArtifactURL(c *gin.Context) {
+	taskID, ok := ac.authorizeTask(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+	artifactID, err := ac.ParseID(c, "artifact_id")
+	if err != nil {
+		ac.BadRequest(c, "invalid artifact ID")
+		return
+	}
+
+	artifact, err := ac.artifactService.GetArtifact(c.Request.Context(), taskID, artifactID)
+	if err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("GetArtifact", err, map[string]interface{}{"artifact_id": artifactID})
+		ac.InternalError(c, "failed to get artifact")
+		return
+	}
+
+	url, expiresAt, err := ac.artifactService.SignURL(artifact)
+	if err != nil {
+		ac.LogError("SignArtifactURL", err, map[string]interface{}{"artifact_id": artifactID})
+		ac.InternalError(c, "failed to sign download URL")
+		return
+	}
+	ac.Success(c, gin.H{
+		"url":        url,
+		"expires_at": expiresAt,
+	})
+}
+
+// DownloadSignedArtifact 通过签名链接下载任务文件，不需要登录
+// 查询参数: expires (Unix秒), signature
+func (ac *ArtifactController) DownloadSignedArtifact(c *gin.Context) {
+	artifactID, err := ac.ParseID(c, "id")
+	if err != nil {
+		ac.BadRequest(c, "invalid artifact ID")
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		ac.Forbidden(c, "invalid or expired download link")
+		return
+	}
+
+	artifact, object, err := ac.artifactService.OpenSigned(c.Request.Context(), artifactID, expires, c.Query("signature"))
+	if err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("DownloadSignedArtifact", err, map[string]interface{}{"artifact_id": artifactID})
+		ac.InternalError(c, "failed to open artifact")
+		return
+	}
+	ac.serveArtifact(c, artifact, object)
+}
+
+// DeleteArtifact 删除任务文件
+func (ac *ArtifactController) DeleteArtifact(c *gin.Context) {
+	taskID, ok := ac.authorizeTask(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+	artifactID, err := ac.ParseID(c, "artifact_id")
+	if err != nil {
+		ac.BadRequest(c, "invalid artifact ID")
+		return
+	}
+
+	if err := ac.artifactService.DeleteArtifact(c.Request.Context(), taskID, artifactID); err != nil {
+		if ac.handleArtifactError(c, err) {
+			return
+		}
+		ac.LogError("DeleteArtifact", err, map[string]interface{}{"artifact_id": artifactID})
+		ac.InternalError(c, "failed to delete artifact")
+		return
+	}
+
+	ac.LogInfo("DeleteArtifact", map[string]interface{}{
+		"task_id":     taskID,
+		"artifact_id": artifactID,
+	})
+	ac.Success(c, gin.H{"message": "artifact deleted successfully"})
+}
+
+// authorizeTask 解析任务ID并检查当前用户对任务所用无人机的权限，失败时已写入响应
+func (ac *ArtifactController) authorizeTask(c *gin.Context, required models.UserRole) (uint, bool) {
+	taskID, err := ac.ParseID(c, "id")
+	if err != nil {
+		ac.BadRequest(c, "invalid task ID")
+		return 0, false
+	}
+
+	task, err := ac.taskService.GetTaskByID(c.Request.Context(), taskID)
+	if err != nil {
+		if err == services.ErrTaskNotFound {
+			ac.NotFound(c, "task not found")
+			return 0, false
+		}
+		ac.LogError("GetTask", err, map[string]interface{}{"task_id": taskID})
+		ac.InternalError(c, "failed to get task")
+		return 0, false
+	}
+
+	if task.DroneID != 0 && !ac.authorizeDrone(c, task.DroneID, required) {
+		return 0, false
+	}
+	return taskID, true
+}
+
+// serveArtifact 返回文件内容，由 http.ServeContent 处理 Range 和条件请求
+func (ac *ArtifactController) serveArtifact(c *gin.Context, artifact *models.TaskArtifact, object blobstore.Object) {
+	defer object.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": artifact.FileName})
+	if disposition == "" {
+		disposition = fmt.Sprintf("attachment; filename=%q", "artifact")
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("Content-Type", artifact.ContentType)
+	c.Header("ETag", strconv.Quote(artifact.Checksum))
+	http.ServeContent(c.Writer, c.Request, "", artifact.CreatedAt, object)
+}
+
+// handleArtifactError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (ac *ArtifactController) handleArtifactError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrTaskNotFound):
+		ac.NotFound(c, "task not found")
+	case errors.Is(err, services.ErrArtifactNotFound):
+		ac.NotFound(c, "artifact not found")
+	case errors.Is(err, services.ErrUploadNotFound):
+		ac.NotFound(c, "upload not found")
+	case errors.Is(err, services.ErrInvalidArtifact),
+		errors.Is(err, services.ErrChecksumMismatch),
+		errors.Is(err, services.ErrUploadOffset):
+		ac.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrArtifactTooLarge):
+		ac.Error(c, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrUploadBusy):
+		ac.Error(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidSignature):
+		ac.Forbidden(c, "invalid or expired download link")
+	default:
+		return false
+	}
+	return true
+}
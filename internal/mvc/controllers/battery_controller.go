@@ -0,0 +1,374 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BatteryController 电池组管理控制器
+type BatteryController struct {
+	*BaseController
+	*fleetGuard
+	batteryService services.BatteryService
+}
+
+// NewBatteryController 创建电池组管理控制器
+func NewBatteryController(logger *logger.Logger, batteryService services.BatteryService, fleetService services.FleetService) *BatteryController {
+	base := NewBaseController(logger)
+	return &BatteryController{
+		BaseController: base,
+		fleetGuard:     newFleetGuard(base, fleetService),
+		batteryService: batteryService,
+	}
+}
+
+// CreateBatteryPackRequest 登记电池组请求
+type CreateBatteryPackRequest struct {
+	SerialNo      string                  `json:"serial_no" binding:"required,max=50"`
+	Chemistry     models.BatteryChemistry `json:"chemistry" binding:"required"`
+	CapacityMah   int                     `json:"capacity_mah" binding:"min=0"`
+	RatedCycles   int                     `json:"rated_cycles" binding:"min=0"`
+	CycleCount    int                     `json:"cycle_count" binding:"min=0"`
+	StateOfHealth *float64                `json:"state_of_health" binding:"omitempty,min=0,max=100"`
+	Notes         string                  `json:"notes" binding:"omitempty,max=2000"`
+}
+
+// UpdateBatteryPackRequest 更新电池组请求
+type UpdateBatteryPackRequest struct {
+	CapacityMah   *int                     `json:"capacity_mah" binding:"omitempty,min=0"`
+	RatedCycles   *int                     `json:"rated_cycles" binding:"omitempty,min=1"`
+	StateOfHealth *float64                 `json:"state_of_health" binding:"omitempty,min=0,max=100"`
+	Status        models.BatteryPackStatus `json:"status" binding:"omitempty,oneof=available retired"`
+	Notes         *string                  `json:"notes" binding:"omitempty,max=2000"`
+}
+
+// CreatePack 登记电池组
+func (bc *BatteryController) CreatePack(c *gin.Context) {
+	if !bc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	var req CreateBatteryPackRequest
+	if err := bc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	pack, err := bc.batteryService.CreatePack(c.Request.Context(), &services.CreateBatteryPackParams{
+		SerialNo:      req.SerialNo,
+		Chemistry:     req.Chemistry,
+		CapacityMah:   req.CapacityMah,
+		RatedCycles:   req.RatedCycles,
+		CycleCount:    req.CycleCount,
+		StateOfHealth: req.StateOfHealth,
+		Notes:         req.Notes,
+	})
+	if err != nil {
+		if bc.handleBatteryError(c, err) {
+			return
+		}
+		bc.LogError("CreatePack", err, map[string]interface{}{"serial_no": req.SerialNo})
+		bc.InternalError(c, "failed to create battery pack")
+		return
+	}
+
+	bc.LogInfo("CreatePack", map[string]interface{}{
+		"pack_id":   pack.ID,
+		"serial_no": pack.SerialNo,
+	})
+	bc.Success(c, pack)
+}
+
+// GetPack 获取电池组
+func (bc *BatteryController) GetPack(c *gin.Context) {
+	id, err := bc.ParseID(c, "id")
+	if err != nil {
+		bc.BadRequest(c, "invalid battery pack ID")
+		return
+	}
+
+	pack, err := bc.batteryService.GetPack(c.Request.Context(), id)
+	if err != nil {
+		if bc.handleBatteryError(c, err) {
+			return
+		}
+		bc.LogError("GetPack", err, map[string]interface{}{"pack_id": id})
+		bc.InternalError(c, "failed to get battery pack")
+		return
+	}
+	if pack.DroneID != nil && !bc.authorizeDrone(c, *pack.DroneID, models.RoleViewer) {
+		return
+	}
+
+	bc.Success(c, pack)
+}
+
+// ListPacks 获取电池组列表
+// 查询参数: status, drone_id, max_health
+func (bc *BatteryController) ListPacks(c *gin.Context) {
+	offset, limit := bc.ParsePagination(c)
+
+	scope, ok := bc.fleetScope(c, 0, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	params := &services.ListBatteryPacksParams{
+		Offset: offset,
+		Limit:  limit,
+		Status: models.BatteryPackStatus(c.Query("status")),
+		Scope:  scope,
+	}
+	if droneID := c.Query("drone_id"); droneID != "" {
+		id, err := strconv.ParseUint(droneID, 10, 32)
+		if err != nil {
+			bc.BadRequest(c, "invalid drone ID")
+			return
+		}
+		params.DroneID = uint(id)
+	}
+	if maxHealth := c.Query("max_health"); maxHealth != "" {
+		health, err := strconv.ParseFloat(maxHealth, 64)
+		if err != nil {
+			bc.BadRequest(c, "invalid max_health")
+			return
+		}
+		params.MaxHealth = health
+	}
+
+	packs, total, err := bc.batteryService.ListPacks(c.Request.Context(), params)
+	if err != nil {
+		bc.LogError("ListPacks", err, map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		})
+		bc.InternalError(c, "failed to list battery packs")
+		return
+	}
+
+	bc.Success(c, gin.H{
+		"packs":  packs,
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// UpdatePack 更新电池组
+func (bc *BatteryController) UpdatePack(c *gin.Context) {
+	if !bc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	id, err := bc.ParseID(c, "id")
+	if err != nil {
+		bc.BadRequest(c, "invalid battery pack ID")
+		return
+	}
+
+	var req UpdateBatteryPackRequest
+	if err := bc.BindJSON(c, &req); err != nil {
+		return
+	}
+	if !bc.authorizePack(c, id, models.RoleOperator) {
+		return
+	}
+
+	pack, err := bc.batteryService.UpdatePack(c.Request.Context(), id, &services.UpdateBatteryPackParams{
+		CapacityMah:   req.CapacityMah,
+		RatedCycles:   req.RatedCycles,
+		StateOfHealth: req.StateOfHealth,
+		Status:        req.Status,
+		Notes:         req.Notes,
+	})
+	if err != nil {
+		if bc.handleBatteryError(c, err) {
+			return
+		}
+		bc.LogError("UpdatePack", err, map[string]interface{}{"pack_id": id})
+		bc.InternalError(c, "failed to update battery pack")
+		return
+	}
+
+	bc.LogInfo("UpdatePack", map[string]interface{}{"pack_id": id})
+	bc.Success(c, pack)
+}
+
+// InstallPack 将电池组安装到无人机
+func (bc *BatteryController) InstallPack(c *gin.Context) {
+	if !bc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	userID, err := bc.GetUserID(c)
+	if err != nil {
+		bc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	id, err := bc.ParseID(c, "id")
+	if err != nil {
+		bc.BadRequest(c, "invalid battery pack ID")
+		return
+	}
+
+	var req struct {
+		DroneID uint `json:"drone_id" binding:"required"`
+	}
+	if err := bc.BindJSON(c, &req); err != nil {
+		return
+	}
+	if !bc.authorizeDrone(c, req.DroneID, models.RoleOperator) {
+		return
+	}
+
+	if err := bc.batteryService.InstallPack(c.Request.Context(), id, req.DroneID, userID); err != nil {
+		if bc.handleBatteryError(c, err) {
+			return
+		}
+		bc.LogError("InstallPack", err, map[string]interface{}{
+			"pack_id":  id,
+			"drone_id": req.DroneID,
+		})
+		bc.InternalError(c, "failed to install battery pack")
+		return
+	}
+
+	bc.LogInfo("InstallPack", map[string]interface{}{
+		"pack_id":  id,
+		"drone_id": req.DroneID,
+	})
+	bc.Success(c, gin.H{"message": "battery pack installed successfully"})
+}
+
+// RemovePack 从无人机拆下电池组
+func (bc *BatteryController) RemovePack(c *gin.Context) {
+	if !bc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	userID, err := bc.GetUserID(c)
+	if err != nil {
+		bc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	id, err := bc.ParseID(c, "id")
+	if err != nil {
+		bc.BadRequest(c, "invalid battery pack ID")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := bc.BindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	if !bc.authorizePack(c, id, models.RoleOperator) {
+		return
+	}
+
+	if err := bc.batteryService.RemovePack(c.Request.Context(), id, userID, req.Reason); err != nil {
+		if bc.handleBatteryError(c, err) {
+			return
+		}
+		bc.LogError("RemovePack", err, map[string]interface{}{"pack_id": id})
+		bc.InternalError(c, "failed to remove battery pack")
+		return
+	}
+
+	bc.LogInfo("RemovePack", map[string]interface{}{"pack_id": id})
+	bc.Success(c, gin.H{"message": "battery pack removed successfully"})
+}
+
+// GetPackHistory 获取电池组安装历史
+func (bc *BatteryController) GetPackHistory(c *gin.Context) {
+	id, err := bc.ParseID(c, "id")
+	if err != nil {
+		bc.BadRequest(c, "invalid battery pack ID")
+		return
+	}
+
+	history, err := bc.batteryService.GetPackHistory(c.Request.Context(), id)
+	if err != nil {
+		if bc.handleBatteryError(c, err) {
+			return
+		}
+		bc.LogError("GetPackHistory", err, map[string]interface{}{"pack_id": id})
+		bc.InternalError(c, "failed to get battery pack history")
+		return
+	}
+
+	bc.Success(c, gin.H{
+		"history": history,
+		"total":   len(history),
+	})
+}
+
+// GetDronePack 获取无人机当前安装的电池组
+func (bc *BatteryController) GetDronePack(c *gin.Context) {
+	id, err := bc.ParseID(c, "id")
+	if err != nil {
+		bc.BadRequest(c, "invalid drone ID")
+		return
+	}
+	if !bc.authorizeDrone(c, id, models.RoleViewer) {
+		return
+	}
+
+	pack, err := bc.batteryService.GetInstalledPack(c.Request.Context(), id)
+	if err != nil {
+		bc.LogError("GetDronePack", err, map[string]interface{}{"drone_id": id})
+		bc.InternalError(c, "failed to get installed battery pack")
+		return
+	}
+	if pack == nil {
+		bc.NotFound(c, "no battery pack installed")
+		return
+	}
+
+	bc.Success(c, pack)
+}
+
+// authorizePack 电池组已安装时按所在无人机校验机队权限，失败时已写入响应
+func (bc *BatteryController) authorizePack(c *gin.Context, id uint, required models.UserRole) bool {
+	pack, err := bc.batteryService.GetPack(c.Request.Context(), id)
+	if err != nil {
+		if bc.handleBatteryError(c, err) {
+			return false
+		}
+		bc.LogError("GetPack", err, map[string]interface{}{"pack_id": id})
+		bc.InternalError(c, "failed to get battery pack")
+		return false
+	}
+	return pack.DroneID == nil || bc.authorizeDrone(c, *pack.DroneID, required)
+}
+
+// handleBatteryError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (bc *BatteryController) handleBatteryError(c *gin.Context, err error) bool {
+	switch {
+	case err == services.ErrBatteryPackNotFound:
+		bc.NotFound(c, "battery pack not found")
+	case err == services.ErrDroneNotFound:
+		bc.NotFound(c, "drone not found")
+	case err == services.ErrBatteryPackExists:
+		bc.BadRequest(c, "battery pack with this serial number already exists")
+	case err == services.ErrBatteryPackInUse, err == services.ErrBatteryPackRetired,
+		err == services.ErrBatteryPackNotInstalled, err == services.ErrDroneInUse:
+		bc.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrInvalidData):
+		bc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceController 设备凭证和指令通道控制器
+type DeviceController struct {
+	*BaseController
+	*fleetGuard
+	deviceService services.DeviceService
+}
+
+// NewDeviceController 创建设备控制器
+func NewDeviceController(logger *logger.Logger, deviceService services.DeviceService, fleetService services.FleetService) *DeviceController {
+	base := NewBaseController(logger)
+	return &DeviceController{
+		BaseController: base,
+		fleetGuard:     newFleetGuard(base, fleetService),
+		deviceService:  deviceService,
+	}
+}
+
+// IssueCredentialRequest 签发或轮换设备凭证请求
+type IssueCredentialRequest struct {
+	Type        models.DeviceCredentialType `json:"type" binding:"omitempty,oneof=api_key mtls"`
+	Fingerprint string                      `json:"fingerprint" binding:"omitempty,max=95"`
+	Description string                      `json:"description" binding:"omitempty,max=255"`
+	ExpiresAt   *time.Time                  `json:"expires_at"`
+}
+
+// AcknowledgeCommandRequest 设备确认指令请求
+type AcknowledgeCommandRequest struct {
+	Success bool   `json:"success"`
+	Result  string `json:"result" binding:"omitempty,max=500"`
+}
+
+// ListCredentials 获取无人机的设备凭证
+func (dc *DeviceController) ListCredentials(c *gin.Context) {
+	droneID, ok := dc.parseDroneID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	credentials, err := dc.deviceService.ListCredentials(c.Request.Context(), droneID)
+	if err != nil {
+		dc.LogError("ListCredentials", err, map[string]interface{}{"drone_id": droneID})
+		dc.InternalError(c, "failed to list device credentials")
+		return
+	}
+
+	dc.Success(c, gin.H{
+		"credentials": credentials,
+		"count":       len(credentials),
+	})
+}
+
+// IssueCredential 为无人机签发新凭证
+func (dc *DeviceController) IssueCredential(c *gin.Context) {
+	droneID, ok := dc.parseDroneID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	params, ok := dc.bindCredentialParams(c)
+	if !ok {
+		return
+	}
+
+	issued, err := dc.deviceService.IssueCredential(c.Request.Context(), droneID, params)
+	if err != nil {
+		if dc.handleDeviceError(c, err) {
+			return
+		}
+		dc.LogError("IssueCredential", err, map[string]interface{}{"drone_id": droneID})
+		dc.InternalError(c, "failed to issue device credential")
+		return
+	}
+
+	dc.LogInfo("IssueCredential", map[string]interface{}{
+		"drone_id":      droneID,
+		"credential_id": issued.Credential.ID,
+		"type":          issued.Credential.Type,
+	})
+	dc.Success(c, issued)
+}
+
+// RotateCredential 轮换设备凭证
+func (dc *DeviceController) RotateCredential(c *gin.Context) {
+	droneID, ok := dc.parseDroneID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	credentialID, err := dc.ParseID(c, "credential_id")
+	if err != nil {
+		dc.BadRequest(c, "invalid credential ID")
+		return
+	}
+
+	params, ok := dc.bindCredentialParams(c)
+	if !ok {
+		return
+	}
+
+	issued, err := dc.deviceService.RotateCredential(c.Request.Context(), droneID, credentialID, params)
+	if err != nil {
+		if dc.handleDeviceError(c, err) {
+			return
+		}
+		dc.LogError("RotateCredential", err, map[string]interface{}{
+			"drone_id":      droneID,
+			"credential_id": credentialID,
+		})
+		dc.InternalError(c, "failed to rotate device credential")
+		return
+	}
+
+	dc.LogInfo("RotateCredential", map[string]interface{}{
+		"drone_id":      droneID,
+		"credential_id": issued.Credential.ID,
+		"rotated_from":  credentialID,
+	})
+	dc.Success(c, issued)
+}
+
+// RevokeCredential 吊销设备凭证
+func (dc *DeviceController) RevokeCredential(c *gin.Context) {
+	droneID, ok := dc.parseDroneID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	credentialID, err := dc.ParseID(c, "credential_id")
+	if err != nil {
+		dc.BadRequest(c, "invalid credential ID")
+		return
+	}
+
+	userID, _ := dc.GetUserID(c)
+	if err := dc.deviceService.RevokeCredential(c.Request.Context(), droneID, credentialID, userID); err != nil {
+		if dc.handleDeviceError(c, err) {
+			return
+		}
+		dc.LogError("RevokeCredential", err, map[string]interface{}{
+			"drone_id":      droneID,
+			"credential_id": credentialID,
+		})
+		dc.InternalError(c, "failed to revoke device credential")
+		return
+	}
+
+	dc.LogInfo("RevokeCredential", map[string]interface{}{
+		"drone_id":      droneID,
+		"credential_id": credentialID,
+	})
+	dc.Success(c, gin.H{"message": "device credential revoked successfully"})
+}
+
+// ListCommands 获取无人机的指令记录
+func (dc *DeviceController) ListCommands(c *gin.Context) {
+	droneID, ok := dc.parseDroneID(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	commands, err := dc.deviceService.ListCommands(c.Request.Context(), droneID, limit)
+	if err != nil {
+		dc.LogError("ListCommands", err, map[string]interface{}{"drone_id": droneID})
+		dc.InternalError(c, "failed to list drone commands")
+		return
+	}
+
+	dc.Success(c, gin.H{
+		"commands": commands,
+		"count":    len(commands),
+	})
+}
+
+// PollCommands 设备拉取待执行指令
+func (dc *DeviceController) PollCommands(c *gin.Context) {
+	droneID, err := dc.ParseID(c, "id")
+	if err != nil {
+		dc.BadRequest(c, "invalid drone ID")
+		return
+	}
+
+	commands, err := dc.deviceService.PollCommands(c.Request.Context(), droneID)
+	if err != nil {
+		dc.LogError("PollCommands", err, map[string]interface{}{"drone_id": droneID})
+		dc.InternalError(c, "failed to poll drone commands")
+		return
+	}
+
+	dc.Success(c, gin.H{
+		"commands": commands,
+		"count":    len(commands),
+	})
+}
+
+// AcknowledgeCommand 设备确认指令执行结果
+func (dc *DeviceController) AcknowledgeCommand(c *gin.Context) {
+	droneID, err := dc.ParseID(c, "id")
+	if err != nil {
+		dc.BadRequest(c, "invalid drone ID")
+		return
+	}
+
+	var req AcknowledgeCommandRequest
+	if err := dc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	commandID := c.Param("command_id")
+	if err := dc.deviceService.AcknowledgeCommand(c.Request.Context(), droneID, commandID, req.Success, req.Result); err != nil {
+		if dc.handleDeviceError(c, err) {
+			return
+		}
+		dc.LogError("AcknowledgeCommand", err, map[string]interface{}{
+			"drone_id":   droneID,
+			"command_id": commandID,
+		})
+		dc.InternalError(c, "failed to acknowledge drone command")
+		return
+	}
+
+	dc.Success(c, gin.H{"message": "command acknowledged successfully"})
+}
+
+// parseDroneID 解析路径中的无人机ID并校验机队角色，失败时已写入响应
+func (dc *DeviceController) parseDroneID(c *gin.Context, required models.UserRole) (uint, bool) {
+	if !dc.CheckPermission(c, required) {
+		return 0, false
+	}
+
+	droneID, err := dc.ParseID(c, "id")
+	if err != nil {
+		dc.BadRequest(c, "invalid drone ID")
+		return 0, false
+	}
+
+	if !dc.authorizeDrone(c, droneID, required) {
+		return 0, false
+	}
+	return droneID, true
+}
+
+// bindCredentialParams 解析凭证请求，请求体可为空
+func (dc *DeviceController) bindCredentialParams(c *gin.Context) (*services.IssueCredentialParams, bool) {
+	var req IssueCredentialRequest
+	if c.Request.ContentLength > 0 {
+		if err := dc.BindJSON(c, &req); err != nil {
+			return nil, false
+		}
+	}
+
+	userID, _ := dc.GetUserID(c)
+	return &services.IssueCredentialParams{
+		Type:        req.Type,
+		Fingerprint: req.Fingerprint,
+		Description: req.Description,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedBy:   userID,
+	}, true
+}
+
+// handleDeviceError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (dc *DeviceController) handleDeviceError(c *gin.Context, err error) bool {
+	switch {
+	case err == services.ErrCredentialNotFound:
+		dc.NotFound(c, "device credential not found")
+	case err == services.ErrCommandNotFound:
+		dc.NotFound(c, "drone command not found")
+	case err == services.ErrDroneNotFound:
+		dc.NotFound(c, "drone not found")
+	case err == services.ErrCredentialExists, err == services.ErrCredentialRevoked,
+		err == services.ErrCommandAlreadyAcknowledged:
+		dc.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrInvalidData):
+		dc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -1,6 +1,14 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"drone-control-system/internal/mvc/models"
 	"drone-control-system/internal/mvc/services"
 	"drone-control-system/pkg/kafka"
@@ -12,37 +20,59 @@ import (
 // DroneController 无人机控制器
 type DroneController struct {
 	*BaseController
-	droneService services.DroneService
-	kafkaService services.KafkaService // 添加Kafka服务
+	*fleetGuard
+	droneService  services.DroneService
+	kafkaService  services.KafkaService // 添加Kafka服务
+	deviceService services.DeviceService
 }
 
 // NewDroneController 创建无人机控制器
-func NewDroneController(logger *logger.Logger, droneService services.DroneService, kafkaService services.KafkaService) *DroneController {
+func NewDroneController(
+	logger *logger.Logger,
+	droneService services.DroneService,
+	kafkaService services.KafkaService,
+	fleetService services.FleetService,
+	deviceService services.DeviceService,
+) *DroneController {
+	base := NewBaseController(logger)
 	return &DroneController{
-		BaseController: NewBaseController(logger),
+		BaseController: base,
+		fleetGuard:     newFleetGuard(base, fleetService),
 		droneService:   droneService,
 		kafkaService:   kafkaService,
+		deviceService:  deviceService,
 	}
 }
 
 // CreateDroneRequest 创建无人机请求
 type CreateDroneRequest struct {
-	SerialNo     string   `json:"serial_no" binding:"required,min=3,max=50"`
-	Model        string   `json:"model" binding:"required,min=2,max=100"`
-	Capabilities []string `json:"capabilities"`
-	Firmware     string   `json:"firmware" binding:"omitempty,max=50"`
-	Version      string   `json:"version" binding:"omitempty,max=20"`
+	SerialNo     string                    `json:"serial_no" binding:"required,min=3,max=50"`
+	Model        string                    `json:"model" binding:"required,min=2,max=100"`
+	Capabilities *models.DroneCapabilities `json:"capabilities"`
+	Firmware     string                    `json:"firmware" binding:"omitempty,max=50"`
+	Version      string                    `json:"version" binding:"omitempty,max=20"`
+	FleetID      *uint                     `json:"fleet_id"`
+	GroupID      *uint                     `json:"group_id"`
+	// CertFingerprint 设备客户端证书SHA-256指纹，提供时同时签发mTLS凭证
+	CertFingerprint string `json:"cert_fingerprint" binding:"omitempty,max=95"`
+}
+
+// CreateDroneResponse 创建无人机响应，设备API密钥只在此返回一次
+type CreateDroneResponse struct {
+	*models.Drone
+	APIKey      string                     `json:"api_key,omitempty"`
+	Credentials []*models.DeviceCredential `json:"device_credentials,omitempty"`
 }
 
 // UpdateDroneRequest 更新无人机请求
 type UpdateDroneRequest struct {
-	Model        string             `json:"model" binding:"omitempty,min=2,max=100"`
-	Status       models.DroneStatus `json:"status" binding:"omitempty,oneof=offline online flying charging maintenance error"`
-	Position     *models.Position   `json:"position"`
-	Battery      *int               `json:"battery" binding:"omitempty,min=0,max=100"`
-	Capabilities []string           `json:"capabilities"`
-	Firmware     string             `json:"firmware" binding:"omitempty,max=50"`
-	Version      string             `json:"version" binding:"omitempty,max=20"`
+	Model        string                    `json:"model" binding:"omitempty,min=2,max=100"`
+	Status       models.DroneStatus        `json:"status" binding:"omitempty,oneof=offline online flying charging maintenance error"`
+	Position     *models.Position          `json:"position"`
+	Battery      *int                      `json:"battery" binding:"omitempty,min=0,max=100"`
+	Capabilities *models.DroneCapabilities `json:"capabilities"`
+	Firmware     string                    `json:"firmware" binding:"omitempty,max=50"`
+	Version      string                    `json:"version" binding:"omitempty,max=20"`
 }
 
 // UpdatePositionRequest 更新位置请求
@@ -53,6 +83,12 @@ type UpdatePositionRequest struct {
 	Heading   float64 `json:"heading" binding:"min=0,max=360"`
 }
 
+// DroneCommandRequest 控制指令请求
+type DroneCommandRequest struct {
+	Command models.DroneCommand    `json:"command" binding:"required"`
+	Params  map[string]interface{} `json:"params"`
+}
+
 // CreateDrone 创建无人机
 func (dc *DroneController) CreateDrone(c *gin.Context) {
 	// 检查权限 - 只有管理员和操作员可以创建无人机
@@ -65,6 +101,40 @@ func (dc *DroneController) CreateDrone(c *gin.Context) {
 		return
 	}
 
+	if req.Capabilities != nil {
+		if err := req.Capabilities.Validate(); err != nil {
+			dc.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	// 直接加入机队需要该机队的操作员权限
+	if req.FleetID != nil {
+		if dc.fleetService == nil {
+			dc.BadRequest(c, "fleets are not enabled")
+			return
+		}
+		if !dc.authorizeFleet(c, *req.FleetID, models.RoleOperator) {
+			return
+		}
+	} else if req.GroupID != nil {
+		dc.BadRequest(c, "group_id requires fleet_id")
+		return
+	}
+
+	// 创建前检查证书指纹，避免无人机已创建而凭证签发失败
+	if req.CertFingerprint != "" && dc.deviceService != nil {
+		if err := dc.deviceService.CheckFingerprint(c.Request.Context(), req.CertFingerprint); err != nil {
+			if errors.Is(err, services.ErrInvalidData) || err == services.ErrCredentialExists {
+				dc.BadRequest(c, err.Error())
+				return
+			}
+			dc.LogError("CreateDrone", err, map[string]interface{}{"serial_no": req.SerialNo})
+			dc.InternalError(c, "failed to check certificate fingerprint")
+			return
+		}
+	}
+
 	drone, err := dc.droneService.CreateDrone(c.Request.Context(), &services.CreateDroneParams{
 		SerialNo:     req.SerialNo,
 		Model:        req.Model,
@@ -77,6 +147,10 @@ func (dc *DroneController) CreateDrone(c *gin.Context) {
 			dc.BadRequest(c, "drone with this serial number already exists")
 			return
 		}
+		if errors.Is(err, services.ErrInvalidCapabilities) {
+			dc.BadRequest(c, err.Error())
+			return
+		}
 		dc.LogError("CreateDrone", err, map[string]interface{}{
 			"serial_no": req.SerialNo,
 		})
@@ -84,12 +158,76 @@ func (dc *DroneController) CreateDrone(c *gin.Context) {
 		return
 	}
 
+	if req.FleetID != nil {
+		if err := dc.fleetService.AssignDrones(c.Request.Context(), *req.FleetID, req.GroupID, []uint{drone.ID}); err != nil {
+			if handleFleetError(dc.BaseController, c, err) {
+				return
+			}
+			dc.LogError("CreateDrone", err, map[string]interface{}{
+				"drone_id": drone.ID,
+				"fleet_id": *req.FleetID,
+			})
+			dc.InternalError(c, "drone created but fleet assignment failed")
+			return
+		}
+		drone.FleetID = req.FleetID
+		drone.GroupID = req.GroupID
+	}
+
+	resp, err := dc.provisionDevice(c, drone, req.CertFingerprint)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidData) || err == services.ErrCredentialExists {
+			dc.BadRequest(c, "drone created but device provisioning failed: "+err.Error())
+			return
+		}
+		dc.LogError("CreateDrone", err, map[string]interface{}{
+			"drone_id": drone.ID,
+		})
+		dc.InternalError(c, "drone created but device provisioning failed")
+		return
+	}
+
 	dc.LogInfo("CreateDrone", map[string]interface{}{
 		"drone_id":  drone.ID,
 		"serial_no": drone.SerialNo,
+		"fleet_id":  req.FleetID,
 	})
 
-	dc.Success(c, drone)
+	dc.Success(c, resp)
+}
+
+// provisionDevice 为新无人机签发设备凭证
+func (dc *DroneController) provisionDevice(c *gin.Context, drone *models.Drone, fingerprint string) (*CreateDroneResponse, error) {
+	resp := &CreateDroneResponse{Drone: drone}
+	if dc.deviceService == nil {
+		return resp, nil
+	}
+
+	userID, _ := dc.GetUserID(c)
+	issued, err := dc.deviceService.IssueCredential(c.Request.Context(), drone.ID, &services.IssueCredentialParams{
+		Type:        models.DeviceCredentialAPIKey,
+		Description: "issued on drone creation",
+		CreatedBy:   userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp.APIKey = issued.APIKey
+	resp.Credentials = append(resp.Credentials, issued.Credential)
+
+	if fingerprint != "" {
+		issued, err := dc.deviceService.IssueCredential(c.Request.Context(), drone.ID, &services.IssueCredentialParams{
+			Type:        models.DeviceCredentialMTLS,
+			Fingerprint: fingerprint,
+			Description: "issued on drone creation",
+			CreatedBy:   userID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Credentials = append(resp.Credentials, issued.Credential)
+	}
+	return resp, nil
 }
 
 // GetDrone 获取无人机信息
@@ -100,6 +238,10 @@ func (dc *DroneController) GetDrone(c *gin.Context) {
 		return
 	}
 
+	if !dc.authorizeDrone(c, id, models.RoleViewer) {
+		return
+	}
+
 	drone, err := dc.droneService.GetDroneByID(c.Request.Context(), id)
 	if err != nil {
 		if err == services.ErrDroneNotFound {
@@ -127,11 +269,22 @@ func (dc *DroneController) UpdateDrone(c *gin.Context) {
 		return
 	}
 
+	if !dc.authorizeDrone(c, id, models.RoleOperator) {
+		return
+	}
+
 	var req UpdateDroneRequest
 	if err := dc.BindJSON(c, &req); err != nil {
 		return
 	}
 
+	if req.Capabilities != nil {
+		if err := req.Capabilities.Validate(); err != nil {
+			dc.BadRequest(c, err.Error())
+			return
+		}
+	}
+
 	drone, err := dc.droneService.UpdateDrone(c.Request.Context(), id, &services.UpdateDroneParams{
 		Model:        req.Model,
 		Status:       req.Status,
@@ -146,6 +299,10 @@ func (dc *DroneController) UpdateDrone(c *gin.Context) {
 			dc.NotFound(c, "drone not found")
 			return
 		}
+		if errors.Is(err, services.ErrInvalidCapabilities) {
+			dc.BadRequest(c, err.Error())
+			return
+		}
 		dc.LogError("UpdateDrone", err, map[string]interface{}{"drone_id": id})
 		dc.InternalError(c, "failed to update drone")
 		return
@@ -171,6 +328,10 @@ func (dc *DroneController) DeleteDrone(c *gin.Context) {
 		return
 	}
 
+	if !dc.authorizeDrone(c, id, models.RoleAdmin) {
+		return
+	}
+
 	err = dc.droneService.DeleteDrone(c.Request.Context(), id)
 	if err != nil {
 		if err == services.ErrDroneNotFound {
@@ -195,15 +356,36 @@ func (dc *DroneController) ListDrones(c *gin.Context) {
 	offset, limit := dc.ParsePagination(c)
 
 	// 筛选参数
-	status := c.Query("status")
-	search := c.Query("search")
-
-	drones, total, err := dc.droneService.ListDrones(c.Request.Context(), &services.ListDronesParams{
+	params := &services.ListDronesParams{
 		Offset: offset,
 		Limit:  limit,
-		Status: models.DroneStatus(status),
-		Search: search,
-	})
+		Status: models.DroneStatus(c.Query("status")),
+		Search: c.Query("search"),
+	}
+	if fleetID := c.Query("fleet_id"); fleetID != "" {
+		id, err := strconv.ParseUint(fleetID, 10, 32)
+		if err != nil {
+			dc.BadRequest(c, "invalid fleet ID")
+			return
+		}
+		params.FleetID = uint(id)
+	}
+	if groupID := c.Query("group_id"); groupID != "" {
+		id, err := strconv.ParseUint(groupID, 10, 32)
+		if err != nil {
+			dc.BadRequest(c, "invalid group ID")
+			return
+		}
+		params.GroupID = uint(id)
+	}
+
+	scope, ok := dc.fleetScope(c, params.FleetID, models.RoleViewer)
+	if !ok {
+		return
+	}
+	params.Scope = scope
+
+	drones, total, err := dc.droneService.ListDrones(c.Request.Context(), params)
 	if err != nil {
 		dc.LogError("ListDrones", err, map[string]interface{}{
 			"offset": offset,
@@ -234,6 +416,10 @@ func (dc *DroneController) UpdateDroneStatus(c *gin.Context) {
 		return
 	}
 
+	if !dc.authorizeDrone(c, id, models.RoleOperator) {
+		return
+	}
+
 	var req struct {
 		Status models.DroneStatus `json:"status" binding:"required,oneof=offline online flying charging maintenance error"`
 	}
@@ -260,6 +446,9 @@ func (dc *DroneController) UpdateDroneStatus(c *gin.Context) {
 		"status":   req.Status,
 	})
 
+	// 发布状态变化事件，供实时位置索引等订阅方更新
+	publishDroneStatusChanged(dc.BaseController, dc.kafkaService, id, req.Status, "manual")
+
 	dc.Success(c, gin.H{"message": "drone status updated successfully"})
 }
 
@@ -271,6 +460,26 @@ func (dc *DroneController) UpdateDronePosition(c *gin.Context) {
 		return
 	}
 
+	if !dc.authorizeDrone(c, id, models.RoleOperator) {
+		return
+	}
+
+	dc.updatePosition(c, id)
+}
+
+// ReportPosition 设备上报位置，设备身份由设备认证中间件校验
+func (dc *DroneController) ReportPosition(c *gin.Context) {
+	id, err := dc.ParseID(c, "id")
+	if err != nil {
+		dc.BadRequest(c, "invalid drone ID")
+		return
+	}
+
+	dc.updatePosition(c, id)
+}
+
+// updatePosition 更新位置并发布位置事件
+func (dc *DroneController) updatePosition(c *gin.Context, id uint) {
 	var req UpdatePositionRequest
 	if err := dc.BindJSON(c, &req); err != nil {
 		return
@@ -283,7 +492,7 @@ func (dc *DroneController) UpdateDronePosition(c *gin.Context) {
 		Heading:   req.Heading,
 	}
 
-	err = dc.droneService.UpdateDronePosition(c.Request.Context(), id, position)
+	err := dc.droneService.UpdateDronePosition(c.Request.Context(), id, position)
 	if err != nil {
 		if err == services.ErrDroneNotFound {
 			dc.NotFound(c, "drone not found")
@@ -327,6 +536,26 @@ func (dc *DroneController) UpdateDroneBattery(c *gin.Context) {
 		return
 	}
 
+	if !dc.authorizeDrone(c, id, models.RoleOperator) {
+		return
+	}
+
+	dc.updateBattery(c, id)
+}
+
+// ReportBattery 设备上报电量，设备身份由设备认证中间件校验
+func (dc *DroneController) ReportBattery(c *gin.Context) {
+	id, err := dc.ParseID(c, "id")
+	if err != nil {
+		dc.BadRequest(c, "invalid drone ID")
+		return
+	}
+
+	dc.updateBattery(c, id)
+}
+
+// updateBattery 更新电量并发布电量事件
+func (dc *DroneController) updateBattery(c *gin.Context, id uint) {
 	var req struct {
 		Battery int `json:"battery" binding:"required,min=0,max=100"`
 	}
@@ -334,7 +563,7 @@ func (dc *DroneController) UpdateDroneBattery(c *gin.Context) {
 		return
 	}
 
-	err = dc.droneService.UpdateDroneBattery(c.Request.Context(), id, req.Battery)
+	err := dc.droneService.UpdateDroneBattery(c.Request.Context(), id, req.Battery)
 	if err != nil {
 		if err == services.ErrDroneNotFound {
 			dc.NotFound(c, "drone not found")
@@ -348,20 +577,204 @@ func (dc *DroneController) UpdateDroneBattery(c *gin.Context) {
 		return
 	}
 
+	// 发布电量更新事件，供遥测存储和心跳监控使用
+	if dc.kafkaService != nil {
+		eventData := map[string]interface{}{
+			"drone_id":  id,
+			"battery":   req.Battery,
+			"timestamp": time.Now(),
+		}
+
+		go func() {
+			if err := dc.kafkaService.PublishDroneEvent(context.Background(), kafka.DroneBatteryUpdatedEvent, eventData); err != nil {
+				dc.Logger.Error("Failed to publish drone battery event", map[string]interface{}{
+					"drone_id": id,
+					"error":    err.Error(),
+				})
+			}
+		}()
+	}
+
 	dc.Success(c, gin.H{"message": "drone battery updated successfully"})
 }
 
 // GetAvailableDrones 获取可用无人机列表
+// 能力过滤参数: sensors (逗号分隔), min_payload, min_range, min_flight_time, min_wind, night_flight
 func (dc *DroneController) GetAvailableDrones(c *gin.Context) {
-	drones, err := dc.droneService.GetAvailableDrones(c.Request.Context())
+	filter, err := parseCapabilityFilter(c)
+	if err != nil {
+		dc.BadRequest(c, err.Error())
+		return
+	}
+
+	scope, ok := dc.fleetScope(c, 0, models.RoleOperator)
+	if !ok {
+		return
+	}
+
+	drones, err := dc.droneService.GetAvailableDrones(c.Request.Context(), filter)
 	if err != nil {
 		dc.LogError("GetAvailableDrones", err, map[string]interface{}{})
 		dc.InternalError(c, "failed to get available drones")
 		return
 	}
 
+	// 只返回当前用户有权调度的无人机
+	if scope != nil {
+		allowed := make([]*models.Drone, 0, len(drones))
+		for _, drone := range drones {
+			if scope.Contains(drone.FleetID) {
+				allowed = append(allowed, drone)
+			}
+		}
+		drones = allowed
+	}
+
 	dc.Success(c, gin.H{
 		"drones": drones,
 		"count":  len(drones),
 	})
 }
+
+// SendCommand 向无人机下发控制指令
+func (dc *DroneController) SendCommand(c *gin.Context) {
+	if !dc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	userID, err := dc.GetUserID(c)
+	if err != nil {
+		dc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	id, err := dc.ParseID(c, "id")
+	if err != nil {
+		dc.BadRequest(c, "invalid drone ID")
+		return
+	}
+
+	if !dc.authorizeDrone(c, id, models.RoleOperator) {
+		return
+	}
+
+	var req DroneCommandRequest
+	if err := dc.BindJSON(c, &req); err != nil {
+		return
+	}
+	if !req.Command.IsValid() {
+		dc.BadRequest(c, "unknown command: "+string(req.Command))
+		return
+	}
+	if dc.deviceService == nil {
+		dc.Error(c, http.StatusServiceUnavailable, "command channel is not available")
+		return
+	}
+
+	record, err := dc.deviceService.IssueCommand(c.Request.Context(), &services.IssueCommandParams{
+		DroneID:  id,
+		Command:  req.Command,
+		Params:   req.Params,
+		IssuedBy: userID,
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidData) {
+			dc.BadRequest(c, err.Error())
+			return
+		}
+		dc.LogError("SendCommand", err, map[string]interface{}{
+			"drone_id": id,
+			"command":  req.Command,
+		})
+		dc.InternalError(c, "failed to send drone command")
+		return
+	}
+
+	dc.LogInfo("SendCommand", map[string]interface{}{
+		"drone_id":   id,
+		"command":    req.Command,
+		"command_id": record.CommandID,
+	})
+	dc.Success(c, gin.H{
+		"command_id": record.CommandID,
+		"expires_at": record.ExpiresAt,
+		"message":    "command sent successfully",
+	})
+}
+
+// publishDroneStatusChanged 异步发布无人机状态变化事件
+func publishDroneStatusChanged(bc *BaseController, kafkaService services.KafkaService, droneID uint, status models.DroneStatus, reason string) {
+	if kafkaService == nil {
+		return
+	}
+
+	eventData := kafka.DroneStatusChangedEventData{
+		DroneID:   droneID,
+		NewStatus: string(status),
+		Reason:    reason,
+		Timestamp: time.Now(),
+	}
+
+	go func() {
+		if err := kafkaService.PublishDroneEvent(context.Background(), kafka.DroneStatusChangedEvent, eventData); err != nil {
+			bc.Logger.Error("Failed to publish drone status event", map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			})
+		}
+	}()
+}
+
+// parseCapabilityFilter 解析能力过滤查询参数，未提供任何参数时返回nil
+func parseCapabilityFilter(c *gin.Context) (*models.CapabilityRequirements, error) {
+	filter := &models.CapabilityRequirements{}
+
+	if sensors := c.Query("sensors"); sensors != "" {
+		for _, name := range strings.Split(sensors, ",") {
+			sensor := models.SensorType(strings.ToLower(strings.TrimSpace(name)))
+			if sensor == "" {
+				continue
+			}
+			filter.Sensors = append(filter.Sensors, sensor)
+		}
+	}
+
+	floats := map[string]*float64{
+		"min_payload": &filter.MinPayloadCapacity,
+		"min_range":   &filter.MinRange,
+		"min_wind":    &filter.MinWindSpeed,
+	}
+	for name, target := range floats {
+		if value := c.Query(name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s", name)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := c.Query("min_flight_time"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_flight_time")
+		}
+		filter.MinFlightTime = parsed
+	}
+
+	if value := c.Query("night_flight"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid night_flight")
+		}
+		filter.NightFlight = parsed
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if filter.IsEmpty() {
+		return nil, nil
+	}
+	return filter, nil
+}
@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FirmwareController 固件管理控制器
+type FirmwareController struct {
+	*BaseController
+	firmwareService services.FirmwareService
+}
+
+// NewFirmwareController 创建固件管理控制器
+func NewFirmwareController(logger *logger.Logger, firmwareService services.FirmwareService) *FirmwareController {
+	return &FirmwareController{
+		BaseController:  NewBaseController(logger),
+		firmwareService: firmwareService,
+	}
+}
+
+// CreateReleaseRequest 登记固件版本请求
+type CreateReleaseRequest struct {
+	Model        string `json:"model" binding:"required,max=100"`
+	Version      string `json:"version" binding:"required,max=50"`
+	Checksum     string `json:"checksum" binding:"required,len=64,hexadecimal"`
+	DownloadURL  string `json:"download_url" binding:"omitempty,url,max=500"`
+	Size         int64  `json:"size" binding:"min=0"`
+	ReleaseNotes string `json:"release_notes"`
+	MinVersion   string `json:"min_version" binding:"omitempty,max=50"`
+}
+
+// CreateRolloutRequest 创建发布活动请求
+type CreateRolloutRequest struct {
+	Name             string                       `json:"name" binding:"required,min=2,max=100"`
+	ReleaseID        uint                         `json:"release_id" binding:"required"`
+	DroneIDs         []uint                       `json:"drone_ids"`
+	CanaryPercent    *int                         `json:"canary_percent" binding:"omitempty,min=0,max=100"`
+	BatchSize        int                          `json:"batch_size" binding:"min=0"`
+	FailureThreshold int                          `json:"failure_threshold" binding:"min=0"`
+	FailureAction    models.FirmwareFailureAction `json:"failure_action" binding:"omitempty,oneof=pause abort"`
+	AutoPromote      bool                         `json:"auto_promote"`
+}
+
+// ReportResultRequest 单机升级结果请求
+type ReportResultRequest struct {
+	Success bool   `json:"success"`
+	Version string `json:"version" binding:"omitempty,max=50"`
+	Message string `json:"message" binding:"omitempty,max=1000"`
+}
+
+// CreateRelease 登记固件版本
+func (fc *FirmwareController) CreateRelease(c *gin.Context) {
+	if !fc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	userID, err := fc.GetUserID(c)
+	if err != nil {
+		fc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req CreateReleaseRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	release, err := fc.firmwareService.CreateRelease(c.Request.Context(), &services.CreateFirmwareReleaseParams{
+		Model:        req.Model,
+		Version:      req.Version,
+		Checksum:     req.Checksum,
+		DownloadURL:  req.DownloadURL,
+		Size:         req.Size,
+		ReleaseNotes: req.ReleaseNotes,
+		MinVersion:   req.MinVersion,
+		CreatedBy:    userID,
+	})
+	if err != nil {
+		if fc.handleFirmwareError(c, err) {
+			return
+		}
+		fc.LogError("CreateRelease", err, map[string]interface{}{
+			"model":   req.Model,
+			"version": req.Version,
+		})
+		fc.InternalError(c, "failed to create firmware release")
+		return
+	}
+
+	fc.LogInfo("CreateRelease", map[string]interface{}{
+		"release_id": release.ID,
+		"model":      release.Model,
+		"version":    release.Version,
+	})
+	fc.Success(c, release)
+}
+
+// ListReleases 获取固件版本列表
+// 查询参数: model
+func (fc *FirmwareController) ListReleases(c *gin.Context) {
+	releases, err := fc.firmwareService.ListReleases(c.Request.Context(), c.Query("model"))
+	if err != nil {
+		fc.LogError("ListReleases", err, nil)
+		fc.InternalError(c, "failed to list firmware releases")
+		return
+	}
+
+	fc.Success(c, gin.H{
+		"releases": releases,
+		"total":    len(releases),
+	})
+}
+
+// GetRelease 获取固件版本
+func (fc *FirmwareController) GetRelease(c *gin.Context) {
+	id, err := fc.ParseID(c, "id")
+	if err != nil {
+		fc.BadRequest(c, "invalid release ID")
+		return
+	}
+
+	release, err := fc.firmwareService.GetRelease(c.Request.Context(), id)
+	if err != nil {
+		if fc.handleFirmwareError(c, err) {
+			return
+		}
+		fc.LogError("GetRelease", err, map[string]interface{}{"release_id": id})
+		fc.InternalError(c, "failed to get firmware release")
+		return
+	}
+
+	fc.Success(c, release)
+}
+
+// GetComplianceReport 获取固件合规报告
+// 查询参数: model, outdated_only
+func (fc *FirmwareController) GetComplianceReport(c *gin.Context) {
+	outdatedOnly, err := strconv.ParseBool(c.DefaultQuery("outdated_only", "false"))
+	if err != nil {
+		fc.BadRequest(c, "invalid outdated_only")
+		return
+	}
+
+	report, err := fc.firmwareService.GetComplianceReport(c.Request.Context(), c.Query("model"), outdatedOnly)
+	if err != nil {
+		fc.LogError("GetComplianceReport", err, map[string]interface{}{"model": c.Query("model")})
+		fc.InternalError(c, "failed to build compliance report")
+		return
+	}
+
+	fc.Success(c, report)
+}
+
+// CreateRollout 创建发布活动
+func (fc *FirmwareController) CreateRollout(c *gin.Context) {
+	if !fc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	userID, err := fc.GetUserID(c)
+	if err != nil {
+		fc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req CreateRolloutRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	canaryPercent := 10
+	if req.CanaryPercent != nil {
+		canaryPercent = *req.CanaryPercent
+	}
+
+	rollout, err := fc.firmwareService.CreateRollout(c.Request.Context(), &services.CreateRolloutParams{
+		Name:             req.Name,
+		ReleaseID:        req.ReleaseID,
+		DroneIDs:         req.DroneIDs,
+		CanaryPercent:    canaryPercent,
+		BatchSize:        req.BatchSize,
+		FailureThreshold: req.FailureThreshold,
+		FailureAction:    req.FailureAction,
+		AutoPromote:      req.AutoPromote,
+		CreatedBy:        userID,
+	})
+	if err != nil {
+		if fc.handleFirmwareError(c, err) {
+			return
+		}
+		fc.LogError("CreateRollout", err, map[string]interface{}{"release_id": req.ReleaseID})
+		fc.InternalError(c, "failed to create firmware rollout")
+		return
+	}
+
+	fc.LogInfo("CreateRollout", map[string]interface{}{
+		"rollout_id": rollout.ID,
+		"release_id": rollout.ReleaseID,
+		"targets":    rollout.TotalTargets,
+	})
+	fc.Success(c, rollout)
+}
+
+// ListRollouts 获取发布活动列表
+// 查询参数: status
+func (fc *FirmwareController) ListRollouts(c *gin.Context) {
+	rollouts, err := fc.firmwareService.ListRollouts(c.Request.Context(), models.FirmwareRolloutStatus(c.Query("status")))
+	if err != nil {
+		fc.LogError("ListRollouts", err, nil)
+		fc.InternalError(c, "failed to list firmware rollouts")
+		return
+	}
+
+	fc.Success(c, gin.H{
+		"rollouts": rollouts,
+		"total":    len(rollouts),
+	})
+}
+
+// GetRollout 获取发布活动及其无人机列表
+func (fc *FirmwareController) GetRollout(c *gin.Context) {
+	id, err := fc.ParseID(c, "id")
+	if err != nil {
+		fc.BadRequest(c, "invalid rollout ID")
+		return
+	}
+
+	rollout, err := fc.firmwareService.GetRollout(c.Request.Context(), id)
+	if err != nil {
+		if fc.handleFirmwareError(c, err) {
+			return
+		}
+		fc.LogError("GetRollout", err, map[string]interface{}{"rollout_id": id})
+		fc.InternalError(c, "failed to get firmware rollout")
+		return
+	}
+
+	targets, err := fc.firmwareService.GetRolloutTargets(c.Request.Context(), id)
+	if err != nil {
+		fc.LogError("GetRollout", err, map[string]interface{}{"rollout_id": id})
+		fc.InternalError(c, "failed to get firmware rollout")
+		return
+	}
+
+	fc.Success(c, gin.H{
+		"rollout": rollout,
+		"targets": targets,
+	})
+}
+
+// StartRollout 启动发布活动
+func (fc *FirmwareController) StartRollout(c *gin.Context) {
+	fc.changeRolloutState(c, "StartRollout", "rollout started successfully", func(id uint, _ string) error {
+		return fc.firmwareService.StartRollout(c.Request.Context(), id)
+	})
+}
+
+// PauseRollout 暂停发布活动
+func (fc *FirmwareController) PauseRollout(c *gin.Context) {
+	fc.changeRolloutState(c, "PauseRollout", "rollout paused successfully", func(id uint, reason string) error {
+		return fc.firmwareService.PauseRollout(c.Request.Context(), id, reason)
+	})
+}
+
+// ResumeRollout 恢复发布活动（金丝雀阶段确认后也通过该接口推进）
+func (fc *FirmwareController) ResumeRollout(c *gin.Context) {
+	fc.changeRolloutState(c, "ResumeRollout", "rollout resumed successfully", func(id uint, _ string) error {
+		return fc.firmwareService.ResumeRollout(c.Request.Context(), id)
+	})
+}
+
+// AbortRollout 终止发布活动
+func (fc *FirmwareController) AbortRollout(c *gin.Context) {
+	fc.changeRolloutState(c, "AbortRollout", "rollout aborted successfully", func(id uint, reason string) error {
+		return fc.firmwareService.AbortRollout(c.Request.Context(), id, reason)
+	})
+}
+
+// ReportUpdateResult 回报单机升级结果
+func (fc *FirmwareController) ReportUpdateResult(c *gin.Context) {
+	if !fc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	rolloutID, err := fc.ParseID(c, "id")
+	if err != nil {
+		fc.BadRequest(c, "invalid rollout ID")
+		return
+	}
+	droneID, err := fc.ParseID(c, "drone_id")
+	if err != nil {
+		fc.BadRequest(c, "invalid drone ID")
+		return
+	}
+
+	var req ReportResultRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	err = fc.firmwareService.ReportUpdateResult(c.Request.Context(), &services.FirmwareUpdateResult{
+		RolloutID: rolloutID,
+		DroneID:   droneID,
+		Success:   req.Success,
+		Version:   req.Version,
+		Message:   req.Message,
+	})
+	if err != nil {
+		if fc.handleFirmwareError(c, err) {
+			return
+		}
+		fc.LogError("ReportUpdateResult", err, map[string]interface{}{
+			"rollout_id": rolloutID,
+			"drone_id":   droneID,
+		})
+		fc.InternalError(c, "failed to record update result")
+		return
+	}
+
+	fc.Success(c, gin.H{"message": "update result recorded successfully"})
+}
+
+// changeRolloutState 发布活动状态变更的公共处理，请求体可选携带 reason
+func (fc *FirmwareController) changeRolloutState(c *gin.Context, operation, message string, change func(id uint, reason string) error) {
+	if !fc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	id, err := fc.ParseID(c, "id")
+	if err != nil {
+		fc.BadRequest(c, "invalid rollout ID")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := fc.BindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	if err := change(id, req.Reason); err != nil {
+		if fc.handleFirmwareError(c, err) {
+			return
+		}
+		fc.LogError(operation, err, map[string]interface{}{"rollout_id": id})
+		fc.InternalError(c, "failed to update firmware rollout")
+		return
+	}
+
+	fc.LogInfo(operation, map[string]interface{}{
+		"rollout_id": id,
+		"reason":     req.Reason,
+	})
+	fc.Success(c, gin.H{"message": message})
+}
+
+// handleFirmwareError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (fc *FirmwareController) handleFirmwareError(c *gin.Context, err error) bool {
+	switch {
+	case err == services.ErrFirmwareNotFound:
+		fc.NotFound(c, "firmware release not found")
+	case err == services.ErrRolloutNotFound:
+		fc.NotFound(c, "firmware rollout not found")
+	case err == services.ErrFirmwareExists:
+		fc.BadRequest(c, "firmware release with this version already exists")
+	case err == services.ErrRolloutInvalidState, err == services.ErrNoRolloutTargets:
+		fc.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrInvalidData):
+		fc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
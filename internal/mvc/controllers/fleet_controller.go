@@ -0,0 +1,605 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FleetController 机队管理控制器
+type FleetController struct {
+	*BaseController
+	*fleetGuard
+	droneService  services.DroneService
+	kafkaService  services.KafkaService
+	deviceService services.DeviceService
+}
+
+// NewFleetController 创建机队管理控制器
+func NewFleetController(
+	logger *logger.Logger,
+	fleetService services.FleetService,
+	droneService services.DroneService,
+	kafkaService services.KafkaService,
+	deviceService services.DeviceService,
+) *FleetController {
+	base := NewBaseController(logger)
+	return &FleetController{
+		BaseController: base,
+		fleetGuard:     newFleetGuard(base, fleetService),
+		droneService:   droneService,
+		kafkaService:   kafkaService,
+		deviceService:  deviceService,
+	}
+}
+
+// FleetRequest 机队或分组请求
+type FleetRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"omitempty,max=1000"`
+}
+
+// FleetDronesRequest 机队无人机分配请求
+type FleetDronesRequest struct {
+	DroneIDs []uint `json:"drone_ids" binding:"required,min=1,max=500"`
+	GroupID  *uint  `json:"group_id"`
+}
+
+// BulkStatusRequest 批量修改状态请求
+type BulkStatusRequest struct {
+	Status  models.DroneStatus `json:"status" binding:"required,oneof=offline online flying charging maintenance error"`
+	GroupID *uint              `json:"group_id"` // 为空时作用于整个机队
+}
+
+// BulkCommandRequest 批量下发指令请求
+type BulkCommandRequest struct {
+	Command models.DroneCommand    `json:"command" binding:"required"`
+	Params  map[string]interface{} `json:"params"`
+	GroupID *uint                  `json:"group_id"` // 为空时作用于整个机队
+}
+
+// BulkFailure 批量操作中失败的无人机
+type BulkFailure struct {
+	DroneID uint   `json:"drone_id"`
+	Error   string `json:"error"`
+}
+
+// ListFleets 获取当前用户可见的机队
+func (fc *FleetController) ListFleets(c *gin.Context) {
+	scope, ok := fc.fleetScope(c, 0, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	fleets, err := fc.fleetService.ListFleets(c.Request.Context(), scope)
+	if err != nil {
+		fc.LogError("ListFleets", err, nil)
+		fc.InternalError(c, "failed to list fleets")
+		return
+	}
+
+	fc.Success(c, gin.H{
+		"fleets": fleets,
+		"total":  len(fleets),
+	})
+}
+
+// GetFleet 获取机队
+func (fc *FleetController) GetFleet(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	fleet, err := fc.fleetService.GetFleet(c.Request.Context(), id)
+	if err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("GetFleet", err, map[string]interface{}{"fleet_id": id})
+		fc.InternalError(c, "failed to get fleet")
+		return
+	}
+
+	fc.Success(c, fleet)
+}
+
+// CreateFleet 创建机队
+func (fc *FleetController) CreateFleet(c *gin.Context) {
+	if !fc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	var req FleetRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	fleet, err := fc.fleetService.CreateFleet(c.Request.Context(), &services.FleetParams{
+		Name:        req.Name,
+		Description: req.Description,
+	})
+	if err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("CreateFleet", err, map[string]interface{}{"name": req.Name})
+		fc.InternalError(c, "failed to create fleet")
+		return
+	}
+
+	fc.LogInfo("CreateFleet", map[string]interface{}{"fleet_id": fleet.ID})
+	fc.Success(c, fleet)
+}
+
+// UpdateFleet 更新机队
+func (fc *FleetController) UpdateFleet(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req FleetRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	fleet, err := fc.fleetService.UpdateFleet(c.Request.Context(), id, &services.FleetParams{
+		Name:        req.Name,
+		Description: req.Description,
+	})
+	if err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("UpdateFleet", err, map[string]interface{}{"fleet_id": id})
+		fc.InternalError(c, "failed to update fleet")
+		return
+	}
+
+	fc.LogInfo("UpdateFleet", map[string]interface{}{"fleet_id": id})
+	fc.Success(c, fleet)
+}
+
+// DeleteFleet 删除机队
+func (fc *FleetController) DeleteFleet(c *gin.Context) {
+	if !fc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	id, err := fc.ParseID(c, "id")
+	if err != nil {
+		fc.BadRequest(c, "invalid fleet ID")
+		return
+	}
+
+	if err := fc.fleetService.DeleteFleet(c.Request.Context(), id); err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("DeleteFleet", err, map[string]interface{}{"fleet_id": id})
+		fc.InternalError(c, "failed to delete fleet")
+		return
+	}
+
+	fc.LogInfo("DeleteFleet", map[string]interface{}{"fleet_id": id})
+	fc.Success(c, gin.H{"message": "fleet deleted successfully"})
+}
+
+// ListMembers 获取机队成员
+func (fc *FleetController) ListMembers(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	members, err := fc.fleetService.ListMembers(c.Request.Context(), id)
+	if err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("ListMembers", err, map[string]interface{}{"fleet_id": id})
+		fc.InternalError(c, "failed to list fleet members")
+		return
+	}
+
+	fc.Success(c, gin.H{
+		"members": members,
+		"total":   len(members),
+	})
+}
+
+// SetMember 添加成员或修改成员在机队内的角色
+func (fc *FleetController) SetMember(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	userID, err := fc.ParseID(c, "user_id")
+	if err != nil {
+		fc.BadRequest(c, "invalid user ID")
+		return
+	}
+
+	var req struct {
+		Role models.UserRole `json:"role" binding:"required,oneof=viewer operator admin"`
+	}
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	member, err := fc.fleetService.SetMember(c.Request.Context(), id, userID, req.Role)
+	if err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("SetMember", err, map[string]interface{}{
+			"fleet_id": id,
+			"user_id":  userID,
+		})
+		fc.InternalError(c, "failed to set fleet member")
+		return
+	}
+
+	fc.LogInfo("SetMember", map[string]interface{}{
+		"fleet_id": id,
+		"user_id":  userID,
+		"role":     req.Role,
+	})
+	fc.Success(c, member)
+}
+
+// RemoveMember 移除机队成员
+func (fc *FleetController) RemoveMember(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	userID, err := fc.ParseID(c, "user_id")
+	if err != nil {
+		fc.BadRequest(c, "invalid user ID")
+		return
+	}
+
+	if err := fc.fleetService.RemoveMember(c.Request.Context(), id, userID); err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("RemoveMember", err, map[string]interface{}{
+			"fleet_id": id,
+			"user_id":  userID,
+		})
+		fc.InternalError(c, "failed to remove fleet member")
+		return
+	}
+
+	fc.LogInfo("RemoveMember", map[string]interface{}{
+		"fleet_id": id,
+		"user_id":  userID,
+	})
+	fc.Success(c, gin.H{"message": "fleet member removed successfully"})
+}
+
+// ListGroups 获取机队分组
+func (fc *FleetController) ListGroups(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	groups, err := fc.fleetService.ListGroups(c.Request.Context(), id)
+	if err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("ListGroups", err, map[string]interface{}{"fleet_id": id})
+		fc.InternalError(c, "failed to list drone groups")
+		return
+	}
+
+	fc.Success(c, gin.H{
+		"groups": groups,
+		"total":  len(groups),
+	})
+}
+
+// CreateGroup 创建分组
+func (fc *FleetController) CreateGroup(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req FleetRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	group, err := fc.fleetService.CreateGroup(c.Request.Context(), id, &services.FleetParams{
+		Name:        req.Name,
+		Description: req.Description,
+	})
+	if err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("CreateGroup", err, map[string]interface{}{"fleet_id": id})
+		fc.InternalError(c, "failed to create drone group")
+		return
+	}
+
+	fc.LogInfo("CreateGroup", map[string]interface{}{
+		"fleet_id": id,
+		"group_id": group.ID,
+	})
+	fc.Success(c, group)
+}
+
+// DeleteGroup 删除分组
+func (fc *FleetController) DeleteGroup(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	groupID, err := fc.ParseID(c, "group_id")
+	if err != nil {
+		fc.BadRequest(c, "invalid group ID")
+		return
+	}
+
+	if err := fc.fleetService.DeleteGroup(c.Request.Context(), id, groupID); err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("DeleteGroup", err, map[string]interface{}{
+			"fleet_id": id,
+			"group_id": groupID,
+		})
+		fc.InternalError(c, "failed to delete drone group")
+		return
+	}
+
+	fc.LogInfo("DeleteGroup", map[string]interface{}{
+		"fleet_id": id,
+		"group_id": groupID,
+	})
+	fc.Success(c, gin.H{"message": "drone group deleted successfully"})
+}
+
+// AssignDrones 将无人机加入机队
+// 需要机队管理员权限，且对被移动的无人机具备操作员权限
+func (fc *FleetController) AssignDrones(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req FleetDronesRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	for _, droneID := range req.DroneIDs {
+		if !fc.authorizeDrone(c, droneID, models.RoleOperator) {
+			return
+		}
+	}
+
+	if err := fc.fleetService.AssignDrones(c.Request.Context(), id, req.GroupID, req.DroneIDs); err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("AssignDrones", err, map[string]interface{}{"fleet_id": id})
+		fc.InternalError(c, "failed to assign drones")
+		return
+	}
+
+	fc.LogInfo("AssignDrones", map[string]interface{}{
+		"fleet_id": id,
+		"group_id": req.GroupID,
+		"drones":   len(req.DroneIDs),
+	})
+	fc.Success(c, gin.H{"message": "drones assigned successfully"})
+}
+
+// UnassignDrones 将无人机移出机队
+func (fc *FleetController) UnassignDrones(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleAdmin)
+	if !ok {
+		return
+	}
+
+	var req FleetDronesRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	if err := fc.fleetService.UnassignDrones(c.Request.Context(), id, req.DroneIDs); err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return
+		}
+		fc.LogError("UnassignDrones", err, map[string]interface{}{"fleet_id": id})
+		fc.InternalError(c, "failed to unassign drones")
+		return
+	}
+
+	fc.LogInfo("UnassignDrones", map[string]interface{}{
+		"fleet_id": id,
+		"drones":   len(req.DroneIDs),
+	})
+	fc.Success(c, gin.H{"message": "drones unassigned successfully"})
+}
+
+// BulkUpdateStatus 批量修改机队（或分组）内无人机的状态
+func (fc *FleetController) BulkUpdateStatus(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+
+	var req BulkStatusRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	droneIDs, ok := fc.fleetDrones(c, id, req.GroupID)
+	if !ok {
+		return
+	}
+
+	var failures []BulkFailure
+	for _, droneID := range droneIDs {
+		if err := fc.droneService.UpdateDroneStatus(c.Request.Context(), droneID, req.Status); err != nil {
+			failures = append(failures, BulkFailure{DroneID: droneID, Error: err.Error()})
+			continue
+		}
+		publishDroneStatusChanged(fc.BaseController, fc.kafkaService, droneID, req.Status, "fleet_bulk")
+	}
+
+	fc.LogInfo("BulkUpdateStatus", map[string]interface{}{
+		"fleet_id": id,
+		"group_id": req.GroupID,
+		"status":   req.Status,
+		"total":    len(droneIDs),
+		"failed":   len(failures),
+	})
+	fc.Success(c, bulkResult(len(droneIDs), failures))
+}
+
+// BulkCommand 向机队（或分组）内所有无人机下发指令
+func (fc *FleetController) BulkCommand(c *gin.Context) {
+	id, ok := fc.parseFleetID(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+
+	userID, err := fc.GetUserID(c)
+	if err != nil {
+		fc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req BulkCommandRequest
+	if err := fc.BindJSON(c, &req); err != nil {
+		return
+	}
+	if !req.Command.IsValid() {
+		fc.BadRequest(c, "unknown command: "+string(req.Command))
+		return
+	}
+	if fc.deviceService == nil {
+		fc.Error(c, http.StatusServiceUnavailable, "command channel is not available")
+		return
+	}
+
+	droneIDs, ok := fc.fleetDrones(c, id, req.GroupID)
+	if !ok {
+		return
+	}
+
+	var failures []BulkFailure
+	for _, droneID := range droneIDs {
+		if _, err := fc.deviceService.IssueCommand(c.Request.Context(), &services.IssueCommandParams{
+			DroneID:  droneID,
+			Command:  req.Command,
+			Params:   req.Params,
+			FleetID:  &id,
+			IssuedBy: userID,
+		}); err != nil {
+			failures = append(failures, BulkFailure{DroneID: droneID, Error: err.Error()})
+		}
+	}
+
+	fc.LogInfo("BulkCommand", map[string]interface{}{
+		"fleet_id": id,
+		"group_id": req.GroupID,
+		"command":  req.Command,
+		"total":    len(droneIDs),
+		"failed":   len(failures),
+	})
+	fc.Success(c, bulkResult(len(droneIDs), failures))
+}
+
+// parseFleetID 解析路径中的机队ID并校验机队角色，失败时已写入响应
+func (fc *FleetController) parseFleetID(c *gin.Context, required models.UserRole) (uint, bool) {
+	if fc.fleetService == nil {
+		fc.Error(c, http.StatusServiceUnavailable, "fleets are not enabled")
+		return 0, false
+	}
+
+	id, err := fc.ParseID(c, "id")
+	if err != nil {
+		fc.BadRequest(c, "invalid fleet ID")
+		return 0, false
+	}
+
+	if !fc.authorizeFleet(c, id, required) {
+		return 0, false
+	}
+	return id, true
+}
+
+// fleetDrones 获取批量操作的目标无人机，失败时已写入响应
+func (fc *FleetController) fleetDrones(c *gin.Context, fleetID uint, groupID *uint) ([]uint, bool) {
+	droneIDs, err := fc.fleetService.ListFleetDroneIDs(c.Request.Context(), fleetID, groupID)
+	if err != nil {
+		if handleFleetError(fc.BaseController, c, err) {
+			return nil, false
+		}
+		fc.LogError("ListFleetDroneIDs", err, map[string]interface{}{"fleet_id": fleetID})
+		fc.InternalError(c, "failed to list fleet drones")
+		return nil, false
+	}
+	if len(droneIDs) == 0 {
+		fc.BadRequest(c, "no drones in fleet")
+		return nil, false
+	}
+	return droneIDs, true
+}
+
+// bulkResult 批量操作结果
+func bulkResult(total int, failures []BulkFailure) gin.H {
+	if failures == nil {
+		failures = []BulkFailure{}
+	}
+	return gin.H{
+		"total":     total,
+		"succeeded": total - len(failures),
+		"failed":    failures,
+	}
+}
+
+// handleFleetError 将机队相关业务错误转换为HTTP响应，返回是否已处理
+func handleFleetError(bc *BaseController, c *gin.Context, err error) bool {
+	switch {
+	case err == services.ErrFleetNotFound:
+		bc.NotFound(c, "fleet not found")
+	case err == services.ErrFleetMemberNotFound:
+		bc.NotFound(c, "fleet member not found")
+	case err == services.ErrDroneGroupNotFound:
+		bc.NotFound(c, "drone group not found")
+	case err == services.ErrDroneNotFound:
+		bc.NotFound(c, "drone not found")
+	case err == services.ErrUserNotFound:
+		bc.NotFound(c, "user not found")
+	case err == services.ErrFleetExists:
+		bc.BadRequest(c, "fleet with this name already exists")
+	case err == services.ErrDroneGroupExists:
+		bc.BadRequest(c, "drone group with this name already exists in the fleet")
+	case err == services.ErrFleetNotEmpty, err == services.ErrDroneNotInFleet:
+		bc.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrInvalidData):
+		bc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fleetGuard 在全局角色之上按机队角色校验无人机访问权限
+type fleetGuard struct {
+	base         *BaseController
+	fleetService services.FleetService // 为nil时只做全局角色校验
+}
+
+// newFleetGuard 创建机队权限校验器
+func newFleetGuard(base *BaseController, fleetService services.FleetService) *fleetGuard {
+	return &fleetGuard{base: base, fleetService: fleetService}
+}
+
+// fleetAccess 获取当前用户的机队权限，失败时已写入响应
+func (g *fleetGuard) fleetAccess(c *gin.Context) (*services.FleetAccess, bool) {
+	userID, err := g.base.GetUserID(c)
+	if err != nil {
+		g.base.Unauthorized(c, "user not authenticated")
+		return nil, false
+	}
+	role, err := g.base.GetUserRole(c)
+	if err != nil {
+		g.base.Unauthorized(c, "authentication required")
+		return nil, false
+	}
+
+	access, err := g.fleetService.GetAccess(c.Request.Context(), userID, role)
+	if err != nil {
+		g.base.LogError("GetFleetAccess", err, map[string]interface{}{"user_id": userID})
+		g.base.InternalError(c, "failed to check fleet permissions")
+		return nil, false
+	}
+	return access, true
+}
+
+// authorizeDrone 检查当前用户对无人机所属机队的权限，失败时已写入响应
+func (g *fleetGuard) authorizeDrone(c *gin.Context, droneID uint, required models.UserRole) bool {
+	if g.fleetService == nil {
+		return true
+	}
+
+	access, ok := g.fleetAccess(c)
+	if !ok {
+		return false
+	}
+	if access.IsAdmin() {
+		return true
+	}
+
+	fleetID, err := g.fleetService.GetDroneFleet(c.Request.Context(), droneID)
+	if err != nil {
+		if err == services.ErrDroneNotFound {
+			g.base.NotFound(c, "drone not found")
+			return false
+		}
+		g.base.LogError("GetDroneFleet", err, map[string]interface{}{"drone_id": droneID})
+		g.base.InternalError(c, "failed to check fleet permissions")
+		return false
+	}
+
+	if !access.Allows(fleetID, required) {
+		g.base.Forbidden(c, "insufficient fleet permissions")
+		return false
+	}
+	return true
+}
+
+// authorizeFleet 检查当前用户在机队内的角色，失败时已写入响应
+func (g *fleetGuard) authorizeFleet(c *gin.Context, fleetID uint, required models.UserRole) bool {
+	if g.fleetService == nil {
+		return g.base.CheckPermission(c, required)
+	}
+
+	access, ok := g.fleetAccess(c)
+	if !ok {
+		return false
+	}
+	if !access.Allows(&fleetID, required) {
+		g.base.Forbidden(c, "insufficient fleet permissions")
+		return false
+	}
+	return true
+}
+
+// fleetScope 当前用户具备所需角色的机队范围，为nil时不限制
+// fleetID 不为0时同时校验用户能否访问该机队
+func (g *fleetGuard) fleetScope(c *gin.Context, fleetID uint, required models.UserRole) (*services.FleetScope, bool) {
+	if g.fleetService == nil {
+		return nil, true
+	}
+
+	access, ok := g.fleetAccess(c)
+	if !ok {
+		return nil, false
+	}
+	if fleetID != 0 && !access.Allows(&fleetID, required) {
+		g.base.Forbidden(c, "insufficient fleet permissions")
+		return nil, false
+	}
+	return access.Scope(required), true
+}
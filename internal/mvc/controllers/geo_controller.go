@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"strconv"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeoController 无人机实时位置查询控制器
+type GeoController struct {
+	*BaseController
+	*fleetGuard
+	geoIndex     services.DroneGeoIndex
+	fleetService services.FleetService
+}
+
+// maxNearestCount 最近可用无人机查询的数量上限
+const maxNearestCount = 100
+
+// NewGeoController 创建实时位置查询控制器
+func NewGeoController(logger *logger.Logger, geoIndex services.DroneGeoIndex, fleetService services.FleetService) *GeoController {
+	base := NewBaseController(logger)
+	return &GeoController{
+		BaseController: base,
+		fleetGuard:     newFleetGuard(base, fleetService),
+		geoIndex:       geoIndex,
+		fleetService:   fleetService,
+	}
+}
+
+// GetDronesWithinRadius 查询半径范围内的无人机
+// 查询参数: lat, lon, radius (米), limit
+func (gc *GeoController) GetDronesWithinRadius(c *gin.Context) {
+	lat, lon, ok := gc.parseCenter(c)
+	if !ok {
+		return
+	}
+
+	radius, err := strconv.ParseFloat(c.Query("radius"), 64)
+	if err != nil || radius <= 0 {
+		gc.BadRequest(c, "invalid radius")
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		gc.BadRequest(c, "invalid limit")
+		return
+	}
+
+	scope, ok := gc.fleetScope(c, 0, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	drones, err := gc.geoIndex.WithinRadius(c.Request.Context(), lat, lon, radius, limit)
+	if err != nil {
+		gc.LogError("GetDronesWithinRadius", err, map[string]interface{}{
+			"latitude":  lat,
+			"longitude": lon,
+			"radius":    radius,
+		})
+		gc.InternalError(c, "failed to search drones")
+		return
+	}
+	if drones, ok = gc.filterScope(c, scope, drones); !ok {
+		return
+	}
+
+	gc.Success(c, gin.H{
+		"drones": drones,
+		"count":  len(drones),
+	})
+}
+
+// GetNearestAvailableDrones 查询最近的可用无人机
+// 查询参数: lat, lon, count (默认5), min_battery (默认0)
+func (gc *GeoController) GetNearestAvailableDrones(c *gin.Context) {
+	lat, lon, ok := gc.parseCenter(c)
+	if !ok {
+		return
+	}
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if err != nil || count <= 0 || count > maxNearestCount {
+		gc.BadRequest(c, "invalid count")
+		return
+	}
+
+	minBattery, err := strconv.Atoi(c.DefaultQuery("min_battery", "0"))
+	if err != nil || minBattery < 0 || minBattery > 100 {
+		gc.BadRequest(c, "invalid min_battery")
+		return
+	}
+
+	scope, ok := gc.fleetScope(c, 0, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	// 按机队范围过滤会减少结果，受限用户按上限查询后再截取
+	fetch := count
+	if scope != nil {
+		fetch = maxNearestCount
+	}
+
+	drones, err := gc.geoIndex.NearestAvailable(c.Request.Context(), lat, lon, fetch, minBattery)
+	if err != nil {
+		gc.LogError("GetNearestAvailableDrones", err, map[string]interface{}{
+			"latitude":    lat,
+			"longitude":   lon,
+			"count":       count,
+			"min_battery": minBattery,
+		})
+		gc.InternalError(c, "failed to search drones")
+		return
+	}
+	if drones, ok = gc.filterScope(c, scope, drones); !ok {
+		return
+	}
+	if len(drones) > count {
+		drones = drones[:count]
+	}
+
+	gc.Success(c, gin.H{
+		"drones": drones,
+		"count":  len(drones),
+	})
+}
+
+// GetDronesWithinBounds 查询矩形范围内的无人机
+// 查询参数: min_lat, min_lon, max_lat, max_lon
+func (gc *GeoController) GetDronesWithinBounds(c *gin.Context) {
+	var box services.BoundingBox
+	values := map[string]*float64{
+		"min_lat": &box.MinLatitude,
+		"min_lon": &box.MinLongitude,
+		"max_lat": &box.MaxLatitude,
+		"max_lon": &box.MaxLongitude,
+	}
+	for name, target := range values {
+		value, err := strconv.ParseFloat(c.Query(name), 64)
+		if err != nil {
+			gc.BadRequest(c, "invalid "+name)
+			return
+		}
+		*target = value
+	}
+
+	if box.MinLatitude < -90 || box.MaxLatitude > 90 || box.MinLongitude < -180 || box.MaxLongitude > 180 ||
+		box.MinLatitude >= box.MaxLatitude || box.MinLongitude >= box.MaxLongitude {
+		gc.BadRequest(c, "invalid bounding box")
+		return
+	}
+
+	scope, ok := gc.fleetScope(c, 0, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	drones, err := gc.geoIndex.WithinBounds(c.Request.Context(), box)
+	if err != nil {
+		gc.LogError("GetDronesWithinBounds", err, map[string]interface{}{
+			"box": box,
+		})
+		gc.InternalError(c, "failed to search drones")
+		return
+	}
+	if drones, ok = gc.filterScope(c, scope, drones); !ok {
+		return
+	}
+
+	gc.Success(c, gin.H{
+		"drones": drones,
+		"count":  len(drones),
+	})
+}
+
+// filterScope 去掉不在用户机队范围内的无人机，失败时已写入响应
+func (gc *GeoController) filterScope(c *gin.Context, scope *services.FleetScope, drones []*services.NearbyDrone) ([]*services.NearbyDrone, bool) {
+	if scope == nil {
+		return drones, true
+	}
+
+	filtered := make([]*services.NearbyDrone, 0, len(drones))
+	for _, drone := range drones {
+		fleetID, err := gc.fleetService.GetDroneFleet(c.Request.Context(), drone.DroneID)
+		if err == services.ErrDroneNotFound {
+			continue
+		}
+		if err != nil {
+			gc.LogError("GetDroneFleet", err, map[string]interface{}{"drone_id": drone.DroneID})
+			gc.InternalError(c, "failed to check fleet permissions")
+			return nil, false
+		}
+		if scope.Contains(fleetID) {
+			filtered = append(filtered, drone)
+		}
+	}
+	return filtered, true
+}
+
+// parseCenter 解析查询中心点
+func (gc *GeoController) parseCenter(c *gin.Context) (float64, float64, bool) {
+	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		gc.BadRequest(c, "invalid lat")
+		return 0, 0, false
+	}
+
+	lon, err := strconv.ParseFloat(c.Query("lon"), 64)
+	if err != nil || lon < -180 || lon > 180 {
+		gc.BadRequest(c, "invalid lon")
+		return 0, 0, false
+	}
+
+	return lat, lon, true
+}
@@ -0,0 +1,281 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GeofenceController 地理围栏控制器
+type GeofenceController struct {
+	*BaseController
+	geofenceService services.GeofenceService
+}
+
+// NewGeofenceController 创建地理围栏控制器
+func NewGeofenceController(logger *logger.Logger, geofenceService services.GeofenceService) *GeofenceController {
+	return &GeofenceController{
+		BaseController:  NewBaseController(logger),
+		geofenceService: geofenceService,
+	}
+}
+
+// CreateGeofenceRequest 创建围栏请求
+type CreateGeofenceRequest struct {
+	Name            string                  `json:"name" binding:"required,min=2,max=100"`
+	Description     string                  `json:"description" binding:"omitempty,max=2000"`
+	Type            models.GeofenceType     `json:"type" binding:"required,oneof=no_fly restricted advisory"`
+	Shape           models.GeofenceShape    `json:"shape" binding:"required,oneof=polygon circle"`
+	Enabled         *bool                   `json:"enabled"`
+	Vertices        []geo.Point             `json:"vertices"`
+	CenterLatitude  float64                 `json:"center_latitude" binding:"min=-90,max=90"`
+	CenterLongitude float64                 `json:"center_longitude" binding:"min=-180,max=180"`
+	Radius          float64                 `json:"radius" binding:"min=0"`
+	MinAltitude     float64                 `json:"min_altitude" binding:"min=0"`
+	MaxAltitude     float64                 `json:"max_altitude" binding:"min=0"`
+	ActiveFrom      *time.Time              `json:"active_from"`
+	ActiveUntil     *time.Time              `json:"active_until"`
+	Windows         []models.GeofenceWindow `json:"windows"`
+	Timezone        string                  `json:"timezone" binding:"omitempty,max=64"`
+}
+
+// UpdateGeofenceRequest 更新围栏请求，设置 shape 时替换整个几何
+type UpdateGeofenceRequest struct {
+	Name            string                  `json:"name" binding:"omitempty,min=2,max=100"`
+	Description     *string                 `json:"description" binding:"omitempty,max=2000"`
+	Type            models.GeofenceType     `json:"type" binding:"omitempty,oneof=no_fly restricted advisory"`
+	Shape           models.GeofenceShape    `json:"shape" binding:"omitempty,oneof=polygon circle"`
+	Enabled         *bool                   `json:"enabled"`
+	Vertices        []geo.Point             `json:"vertices"`
+	CenterLatitude  float64                 `json:"center_latitude" binding:"min=-90,max=90"`
+	CenterLongitude float64                 `json:"center_longitude" binding:"min=-180,max=180"`
+	Radius          float64                 `json:"radius" binding:"min=0"`
+	MinAltitude     *float64                `json:"min_altitude" binding:"omitempty,min=0"`
+	MaxAltitude     *float64                `json:"max_altitude" binding:"omitempty,min=0"`
+	ActiveFrom      *time.Time              `json:"active_from"`
+	ActiveUntil     *time.Time              `json:"active_until"`
+	Windows         []models.GeofenceWindow `json:"windows"`
+	Timezone        *string                 `json:"timezone" binding:"omitempty,max=64"`
+}
+
+// CheckPlanRequest 航线检查请求
+type CheckPlanRequest struct {
+	Plan models.TaskPlan `json:"plan"`
+	At   *time.Time      `json:"at"` // 计划执行时间，为空时使用当前时间
+}
+
+// CreateGeofence 创建围栏
+func (gc *GeofenceController) CreateGeofence(c *gin.Context) {
+	userID, err := gc.GetUserID(c)
+	if err != nil {
+		gc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req CreateGeofenceRequest
+	if err := gc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	fence, err := gc.geofenceService.CreateGeofence(c.Request.Context(), &services.GeofenceParams{
+		Name:            req.Name,
+		Description:     &req.Description,
+		Type:            req.Type,
+		Shape:           req.Shape,
+		Enabled:         req.Enabled,
+		Vertices:        req.Vertices,
+		CenterLatitude:  req.CenterLatitude,
+		CenterLongitude: req.CenterLongitude,
+		Radius:          req.Radius,
+		MinAltitude:     &req.MinAltitude,
+		MaxAltitude:     &req.MaxAltitude,
+		ActiveFrom:      req.ActiveFrom,
+		ActiveUntil:     req.ActiveUntil,
+		Windows:         req.Windows,
+		Timezone:        &req.Timezone,
+		CreatedBy:       userID,
+	})
+	if err != nil {
+		if gc.handleGeofenceError(c, err) {
+			return
+		}
+		gc.LogError("CreateGeofence", err, map[string]interface{}{"name": req.Name})
+		gc.InternalError(c, "failed to create geofence")
+		return
+	}
+
+	gc.LogInfo("CreateGeofence", map[string]interface{}{
+		"geofence_id": fence.ID,
+		"type":        fence.Type,
+	})
+	gc.Success(c, fence)
+}
+
+// GetGeofence 获取围栏
+func (gc *GeofenceController) GetGeofence(c *gin.Context) {
+	id, err := gc.ParseID(c, "id")
+	if err != nil {
+		gc.BadRequest(c, "invalid geofence ID")
+		return
+	}
+
+	fence, err := gc.geofenceService.GetGeofence(c.Request.Context(), id)
+	if err != nil {
+		if gc.handleGeofenceError(c, err) {
+			return
+		}
+		gc.LogError("GetGeofence", err, map[string]interface{}{"geofence_id": id})
+		gc.InternalError(c, "failed to get geofence")
+		return
+	}
+
+	gc.Success(c, fence)
+}
+
+// ListGeofences 获取围栏列表
+func (gc *GeofenceController) ListGeofences(c *gin.Context) {
+	offset, limit := gc.ParsePagination(c)
+
+	params := &services.ListGeofencesParams{
+		Offset: offset,
+		Limit:  limit,
+		Type:   models.GeofenceType(c.Query("type")),
+	}
+	if enabled := c.Query("enabled"); enabled != "" {
+		value, err := strconv.ParseBool(enabled)
+		if err != nil {
+			gc.BadRequest(c, "invalid enabled filter")
+			return
+		}
+		params.Enabled = &value
+	}
+
+	fences, total, err := gc.geofenceService.ListGeofences(c.Request.Context(), params)
+	if err != nil {
+		gc.LogError("ListGeofences", err, map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		})
+		gc.InternalError(c, "failed to list geofences")
+		return
+	}
+
+	gc.Success(c, gin.H{
+		"geofences": fences,
+		"total":     total,
+		"offset":    offset,
+		"limit":     limit,
+	})
+}
+
+// UpdateGeofence 更新围栏
+func (gc *GeofenceController) UpdateGeofence(c *gin.Context) {
+	id, err := gc.ParseID(c, "id")
+	if err != nil {
+		gc.BadRequest(c, "invalid geofence ID")
+		return
+	}
+
+	var req UpdateGeofenceRequest
+	if err := gc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	fence, err := gc.geofenceService.UpdateGeofence(c.Request.Context(), id, &services.GeofenceParams{
+		Name:            req.Name,
+		Description:     req.Description,
+		Type:            req.Type,
+		Shape:           req.Shape,
+		Enabled:         req.Enabled,
+		Vertices:        req.Vertices,
+		CenterLatitude:  req.CenterLatitude,
+		CenterLongitude: req.CenterLongitude,
+		Radius:          req.Radius,
+		MinAltitude:     req.MinAltitude,
+		MaxAltitude:     req.MaxAltitude,
+		ActiveFrom:      req.ActiveFrom,
+		ActiveUntil:     req.ActiveUntil,
+		Windows:         req.Windows,
+		Timezone:        req.Timezone,
+	})
+	if err != nil {
+		if gc.handleGeofenceError(c, err) {
+			return
+		}
+		gc.LogError("UpdateGeofence", err, map[string]interface{}{"geofence_id": id})
+		gc.InternalError(c, "failed to update geofence")
+		return
+	}
+
+	gc.LogInfo("UpdateGeofence", map[string]interface{}{
+		"geofence_id": fence.ID,
+		"enabled":     fence.Enabled,
+	})
+	gc.Success(c, fence)
+}
+
+// DeleteGeofence 删除围栏
+func (gc *GeofenceController) DeleteGeofence(c *gin.Context) {
+	id, err := gc.ParseID(c, "id")
+	if err != nil {
+		gc.BadRequest(c, "invalid geofence ID")
+		return
+	}
+
+	if err := gc.geofenceService.DeleteGeofence(c.Request.Context(), id); err != nil {
+		if gc.handleGeofenceError(c, err) {
+			return
+		}
+		gc.LogError("DeleteGeofence", err, map[string]interface{}{"geofence_id": id})
+		gc.InternalError(c, "failed to delete geofence")
+		return
+	}
+
+	gc.LogInfo("DeleteGeofence", map[string]interface{}{"geofence_id": id})
+	gc.Success(c, gin.H{"message": "geofence deleted successfully"})
+}
+
+// CheckPlan 检查航线是否穿越禁飞区或限飞区
+func (gc *GeofenceController) CheckPlan(c *gin.Context) {
+	var req CheckPlanRequest
+	if err := gc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	at := time.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+
+	err := gc.geofenceService.CheckPlan(c.Request.Context(), &req.Plan, at)
+	var violation *services.GeofenceViolationError
+	switch {
+	case err == nil:
+		gc.Success(c, gin.H{"allowed": true, "violations": []services.GeofenceViolation{}})
+	case errors.As(err, &violation):
+		gc.Success(c, gin.H{"allowed": false, "violations": violation.Violations})
+	default:
+		gc.LogError("CheckPlan", err, nil)
+		gc.InternalError(c, "failed to check plan against geofences")
+	}
+}
+
+// handleGeofenceError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (gc *GeofenceController) handleGeofenceError(c *gin.Context, err error) bool {
+	switch {
+	case err == services.ErrGeofenceNotFound:
+		gc.NotFound(c, "geofence not found")
+	case errors.Is(err, services.ErrInvalidData):
+		gc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,465 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceController 维护管理控制器
+type MaintenanceController struct {
+	*BaseController
+	*fleetGuard
+	maintenanceService services.MaintenanceService
+}
+
+// NewMaintenanceController 创建维护管理控制器
+func NewMaintenanceController(logger *logger.Logger, maintenanceService services.MaintenanceService, fleetService services.FleetService) *MaintenanceController {
+	base := NewBaseController(logger)
+	return &MaintenanceController{
+		BaseController:     base,
+		fleetGuard:         newFleetGuard(base, fleetService),
+		maintenanceService: maintenanceService,
+	}
+}
+
+// CreateMaintenanceRequest 创建维护工单请求
+type CreateMaintenanceRequest struct {
+	DroneID     uint                   `json:"drone_id" binding:"required"`
+	IntervalID  *uint                  `json:"interval_id"`
+	Type        models.MaintenanceType `json:"type" binding:"omitempty,oneof=scheduled inspection repair"`
+	Title       string                 `json:"title" binding:"required,max=200"`
+	Description string                 `json:"description" binding:"omitempty,max=2000"`
+	Reason      string                 `json:"reason" binding:"omitempty,max=500"`
+	Technician  string                 `json:"technician" binding:"omitempty,max=100"`
+	Ground      bool                   `json:"ground"`
+}
+
+// CompleteMaintenanceRequest 维护完成签字请求
+type CompleteMaintenanceRequest struct {
+	Parts      []models.MaintenancePart `json:"parts" binding:"omitempty,dive"`
+	Technician string                   `json:"technician" binding:"omitempty,max=100"`
+	Notes      string                   `json:"notes" binding:"omitempty,max=2000"`
+}
+
+// ServiceIntervalRequest 保养周期请求
+type ServiceIntervalRequest struct {
+	Model        string  `json:"model" binding:"required,max=100"`
+	Name         string  `json:"name" binding:"required,max=100"`
+	Description  string  `json:"description" binding:"omitempty,max=1000"`
+	FlightHours  float64 `json:"flight_hours" binding:"min=0"`
+	FlightCycles int     `json:"flight_cycles" binding:"min=0"`
+	CalendarDays int     `json:"calendar_days" binding:"min=0"`
+}
+
+// CreateRecord 创建维护工单
+func (mc *MaintenanceController) CreateRecord(c *gin.Context) {
+	if !mc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	userID, err := mc.GetUserID(c)
+	if err != nil {
+		mc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req CreateMaintenanceRequest
+	if err := mc.BindJSON(c, &req); err != nil {
+		return
+	}
+	if !mc.authorizeDrone(c, req.DroneID, models.RoleOperator) {
+		return
+	}
+
+	record, err := mc.maintenanceService.CreateRecord(c.Request.Context(), &services.CreateMaintenanceParams{
+		DroneID:     req.DroneID,
+		IntervalID:  req.IntervalID,
+		Type:        req.Type,
+		Title:       req.Title,
+		Description: req.Description,
+		Reason:      req.Reason,
+		Technician:  req.Technician,
+		Ground:      req.Ground,
+		OpenedBy:    userID,
+	})
+	if err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("CreateRecord", err, map[string]interface{}{"drone_id": req.DroneID})
+		mc.InternalError(c, "failed to create maintenance record")
+		return
+	}
+
+	mc.LogInfo("CreateRecord", map[string]interface{}{
+		"record_id": record.ID,
+		"drone_id":  record.DroneID,
+	})
+	mc.Success(c, record)
+}
+
+// GetRecord 获取维护工单
+func (mc *MaintenanceController) GetRecord(c *gin.Context) {
+	id, err := mc.ParseID(c, "id")
+	if err != nil {
+		mc.BadRequest(c, "invalid record ID")
+		return
+	}
+
+	record, err := mc.maintenanceService.GetRecord(c.Request.Context(), id)
+	if err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("GetRecord", err, map[string]interface{}{"record_id": id})
+		mc.InternalError(c, "failed to get maintenance record")
+		return
+	}
+	if !mc.authorizeDrone(c, record.DroneID, models.RoleViewer) {
+		return
+	}
+
+	mc.Success(c, record)
+}
+
+// ListRecords 获取维护工单列表
+// 查询参数: drone_id, status
+func (mc *MaintenanceController) ListRecords(c *gin.Context) {
+	offset, limit := mc.ParsePagination(c)
+
+	scope, ok := mc.fleetScope(c, 0, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	params := &services.ListMaintenanceParams{
+		Offset: offset,
+		Limit:  limit,
+		Status: models.MaintenanceStatus(c.Query("status")),
+		Scope:  scope,
+	}
+	if droneID := c.Query("drone_id"); droneID != "" {
+		id, err := strconv.ParseUint(droneID, 10, 32)
+		if err != nil {
+			mc.BadRequest(c, "invalid drone ID")
+			return
+		}
+		params.DroneID = uint(id)
+	}
+
+	records, total, err := mc.maintenanceService.ListRecords(c.Request.Context(), params)
+	if err != nil {
+		mc.LogError("ListRecords", err, map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		})
+		mc.InternalError(c, "failed to list maintenance records")
+		return
+	}
+
+	mc.Success(c, gin.H{
+		"records": records,
+		"total":   total,
+		"offset":  offset,
+		"limit":   limit,
+	})
+}
+
+// StartRecord 开始维护
+func (mc *MaintenanceController) StartRecord(c *gin.Context) {
+	if !mc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	id, err := mc.ParseID(c, "id")
+	if err != nil {
+		mc.BadRequest(c, "invalid record ID")
+		return
+	}
+
+	var req struct {
+		Technician string `json:"technician" binding:"max=100"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := mc.BindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	if !mc.authorizeRecord(c, id, models.RoleOperator) {
+		return
+	}
+
+	if err := mc.maintenanceService.StartRecord(c.Request.Context(), id, req.Technician); err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("StartRecord", err, map[string]interface{}{"record_id": id})
+		mc.InternalError(c, "failed to start maintenance")
+		return
+	}
+
+	mc.LogInfo("StartRecord", map[string]interface{}{"record_id": id})
+	mc.Success(c, gin.H{"message": "maintenance started successfully"})
+}
+
+// CompleteRecord 签字完成维护
+func (mc *MaintenanceController) CompleteRecord(c *gin.Context) {
+	if !mc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	userID, err := mc.GetUserID(c)
+	if err != nil {
+		mc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	id, err := mc.ParseID(c, "id")
+	if err != nil {
+		mc.BadRequest(c, "invalid record ID")
+		return
+	}
+
+	var req CompleteMaintenanceRequest
+	if err := mc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	record, err := mc.maintenanceService.CompleteRecord(c.Request.Context(), id, &services.CompleteMaintenanceParams{
+		Parts:       req.Parts,
+		Technician:  req.Technician,
+		Notes:       req.Notes,
+		SignedOffBy: userID,
+	})
+	if err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("CompleteRecord", err, map[string]interface{}{"record_id": id})
+		mc.InternalError(c, "failed to complete maintenance")
+		return
+	}
+
+	mc.LogInfo("CompleteRecord", map[string]interface{}{
+		"record_id":     record.ID,
+		"drone_id":      record.DroneID,
+		"signed_off_by": userID,
+	})
+	mc.Success(c, record)
+}
+
+// CancelRecord 取消维护工单
+func (mc *MaintenanceController) CancelRecord(c *gin.Context) {
+	if !mc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	id, err := mc.ParseID(c, "id")
+	if err != nil {
+		mc.BadRequest(c, "invalid record ID")
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"max=500"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := mc.BindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	if !mc.authorizeRecord(c, id, models.RoleOperator) {
+		return
+	}
+
+	if err := mc.maintenanceService.CancelRecord(c.Request.Context(), id, req.Reason); err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("CancelRecord", err, map[string]interface{}{"record_id": id})
+		mc.InternalError(c, "failed to cancel maintenance")
+		return
+	}
+
+	mc.LogInfo("CancelRecord", map[string]interface{}{"record_id": id})
+	mc.Success(c, gin.H{"message": "maintenance cancelled successfully"})
+}
+
+// ListIntervals 获取保养周期列表
+// 查询参数: model
+func (mc *MaintenanceController) ListIntervals(c *gin.Context) {
+	intervals, err := mc.maintenanceService.ListIntervals(c.Request.Context(), c.Query("model"))
+	if err != nil {
+		mc.LogError("ListIntervals", err, nil)
+		mc.InternalError(c, "failed to list service intervals")
+		return
+	}
+
+	mc.Success(c, gin.H{
+		"intervals": intervals,
+		"total":     len(intervals),
+	})
+}
+
+// CreateInterval 创建保养周期
+func (mc *MaintenanceController) CreateInterval(c *gin.Context) {
+	if !mc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	var req ServiceIntervalRequest
+	if err := mc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	interval, err := mc.maintenanceService.CreateInterval(c.Request.Context(), req.params())
+	if err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("CreateInterval", err, map[string]interface{}{
+			"model": req.Model,
+			"name":  req.Name,
+		})
+		mc.InternalError(c, "failed to create service interval")
+		return
+	}
+
+	mc.LogInfo("CreateInterval", map[string]interface{}{"interval_id": interval.ID})
+	mc.Success(c, interval)
+}
+
+// UpdateInterval 更新保养周期
+func (mc *MaintenanceController) UpdateInterval(c *gin.Context) {
+	if !mc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	id, err := mc.ParseID(c, "id")
+	if err != nil {
+		mc.BadRequest(c, "invalid interval ID")
+		return
+	}
+
+	var req ServiceIntervalRequest
+	if err := mc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	interval, err := mc.maintenanceService.UpdateInterval(c.Request.Context(), id, req.params())
+	if err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("UpdateInterval", err, map[string]interface{}{"interval_id": id})
+		mc.InternalError(c, "failed to update service interval")
+		return
+	}
+
+	mc.LogInfo("UpdateInterval", map[string]interface{}{"interval_id": id})
+	mc.Success(c, interval)
+}
+
+// DeleteInterval 删除保养周期
+func (mc *MaintenanceController) DeleteInterval(c *gin.Context) {
+	if !mc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	id, err := mc.ParseID(c, "id")
+	if err != nil {
+		mc.BadRequest(c, "invalid interval ID")
+		return
+	}
+
+	if err := mc.maintenanceService.DeleteInterval(c.Request.Context(), id); err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("DeleteInterval", err, map[string]interface{}{"interval_id": id})
+		mc.InternalError(c, "failed to delete service interval")
+		return
+	}
+
+	mc.LogInfo("DeleteInterval", map[string]interface{}{"interval_id": id})
+	mc.Success(c, gin.H{"message": "service interval deleted successfully"})
+}
+
+// GetDroneMaintenance 获取无人机累计使用量和保养到期情况
+func (mc *MaintenanceController) GetDroneMaintenance(c *gin.Context) {
+	id, err := mc.ParseID(c, "id")
+	if err != nil {
+		mc.BadRequest(c, "invalid drone ID")
+		return
+	}
+	if !mc.authorizeDrone(c, id, models.RoleViewer) {
+		return
+	}
+
+	status, err := mc.maintenanceService.GetMaintenanceStatus(c.Request.Context(), id)
+	if err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return
+		}
+		mc.LogError("GetDroneMaintenance", err, map[string]interface{}{"drone_id": id})
+		mc.InternalError(c, "failed to get maintenance status")
+		return
+	}
+
+	mc.Success(c, status)
+}
+
+// authorizeRecord 按工单所属无人机校验机队权限，失败时已写入响应
+func (mc *MaintenanceController) authorizeRecord(c *gin.Context, id uint, required models.UserRole) bool {
+	record, err := mc.maintenanceService.GetRecord(c.Request.Context(), id)
+	if err != nil {
+		if mc.handleMaintenanceError(c, err) {
+			return false
+		}
+		mc.LogError("GetRecord", err, map[string]interface{}{"record_id": id})
+		mc.InternalError(c, "failed to get maintenance record")
+		return false
+	}
+	return mc.authorizeDrone(c, record.DroneID, required)
+}
+
+// params 转换为服务层参数
+func (r *ServiceIntervalRequest) params() *services.ServiceIntervalParams {
+	return &services.ServiceIntervalParams{
+		Model:        r.Model,
+		Name:         r.Name,
+		Description:  r.Description,
+		FlightHours:  r.FlightHours,
+		FlightCycles: r.FlightCycles,
+		CalendarDays: r.CalendarDays,
+	}
+}
+
+// handleMaintenanceError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (mc *MaintenanceController) handleMaintenanceError(c *gin.Context, err error) bool {
+	switch {
+	case err == services.ErrMaintenanceNotFound:
+		mc.NotFound(c, "maintenance record not found")
+	case err == services.ErrServiceIntervalNotFound:
+		mc.NotFound(c, "service interval not found")
+	case err == services.ErrDroneNotFound:
+		mc.NotFound(c, "drone not found")
+	case err == services.ErrServiceIntervalExists:
+		mc.BadRequest(c, "service interval with this name already exists for the model")
+	case err == services.ErrMaintenanceInvalidState, err == services.ErrDroneInUse:
+		mc.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrInvalidData):
+		mc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,360 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleController 周期任务计划控制器
+type ScheduleController struct {
+	*BaseController
+	*fleetGuard
+	scheduler   services.TaskScheduler
+	taskService services.TaskService
+}
+
+// NewScheduleController 创建周期任务计划控制器
+func NewScheduleController(
+	logger *logger.Logger,
+	scheduler services.TaskScheduler,
+	taskService services.TaskService,
+	fleetService services.FleetService,
+) *ScheduleController {
+	base := NewBaseController(logger)
+	return &ScheduleController{
+		BaseController: base,
+		fleetGuard:     newFleetGuard(base, fleetService),
+		scheduler:      scheduler,
+		taskService:    taskService,
+	}
+}
+
+// CreateScheduleRequest 创建周期计划请求
+type CreateScheduleRequest struct {
+	Name     string                 `json:"name" binding:"required,min=2,max=100"`
+	CronExpr string                 `json:"cron_expr" binding:"required,max=100"`
+	Timezone string                 `json:"timezone" binding:"omitempty,max=64"`
+	Enabled  *bool                  `json:"enabled"`
+	Policy   models.MissedRunPolicy `json:"missed_run_policy" binding:"omitempty,oneof=skip run_once run_all"`
+	StartAt  *time.Time             `json:"start_at"`
+	EndAt    *time.Time             `json:"end_at"`
+
+	TaskName             string                         `json:"task_name" binding:"required,min=2,max=80"`
+	TaskDescription      string                         `json:"task_description" binding:"omitempty,max=1000"`
+	TaskType             models.TaskType                `json:"task_type" binding:"required,oneof=inspection delivery mapping patrol emergency"`
+	TaskPriority         models.TaskPriority            `json:"task_priority" binding:"omitempty,oneof=low normal high urgent"`
+	DroneID              uint                           `json:"drone_id" binding:"required"`
+	Plan                 *models.TaskPlan               `json:"plan"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+	Delivery             *models.TaskDelivery           `json:"delivery"`
+}
+
+// UpdateScheduleRequest 更新周期计划请求
+type UpdateScheduleRequest struct {
+	Name     string                 `json:"name" binding:"omitempty,min=2,max=100"`
+	CronExpr string                 `json:"cron_expr" binding:"omitempty,max=100"`
+	Timezone string                 `json:"timezone" binding:"omitempty,max=64"`
+	Enabled  *bool                  `json:"enabled"`
+	Policy   models.MissedRunPolicy `json:"missed_run_policy" binding:"omitempty,oneof=skip run_once run_all"`
+	StartAt  *time.Time             `json:"start_at"`
+	EndAt    *time.Time             `json:"end_at"`
+
+	TaskName             string                         `json:"task_name" binding:"omitempty,min=2,max=80"`
+	TaskDescription      string                         `json:"task_description" binding:"omitempty,max=1000"`
+	TaskType             models.TaskType                `json:"task_type" binding:"omitempty,oneof=inspection delivery mapping patrol emergency"`
+	TaskPriority         models.TaskPriority            `json:"task_priority" binding:"omitempty,oneof=low normal high urgent"`
+	DroneID              uint                           `json:"drone_id"`
+	Plan                 *models.TaskPlan               `json:"plan"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+	Delivery             *models.TaskDelivery           `json:"delivery"`
+}
+
+// CreateSchedule 创建周期计划
+func (sc *ScheduleController) CreateSchedule(c *gin.Context) {
+	userID, err := sc.GetUserID(c)
+	if err != nil {
+		sc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req CreateScheduleRequest
+	if err := sc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	if !sc.authorizeDrone(c, req.DroneID, models.RoleOperator) {
+		return
+	}
+
+	schedule, err := sc.scheduler.CreateSchedule(c.Request.Context(), &services.TaskScheduleParams{
+		Name:                 req.Name,
+		CronExpr:             req.CronExpr,
+		Timezone:             req.Timezone,
+		Enabled:              req.Enabled,
+		Policy:               req.Policy,
+		StartAt:              req.StartAt,
+		EndAt:                req.EndAt,
+		CreatedBy:            userID,
+		TaskName:             req.TaskName,
+		TaskDescription:      req.TaskDescription,
+		TaskType:             req.TaskType,
+		TaskPriority:         req.TaskPriority,
+		DroneID:              req.DroneID,
+		Plan:                 req.Plan,
+		RequiredCapabilities: req.RequiredCapabilities,
+		Delivery:             req.Delivery,
+	})
+	if err != nil {
+		if sc.handleScheduleError(c, err) {
+			return
+		}
+		sc.LogError("CreateSchedule", err, map[string]interface{}{"drone_id": req.DroneID})
+		sc.InternalError(c, "failed to create task schedule")
+		return
+	}
+
+	sc.LogInfo("CreateSchedule", map[string]interface{}{
+		"schedule_id": schedule.ID,
+		"cron":        schedule.CronExpr,
+		"drone_id":    schedule.DroneID,
+	})
+	sc.Success(c, schedule)
+}
+
+// GetSchedule 获取周期计划
+func (sc *ScheduleController) GetSchedule(c *gin.Context) {
+	schedule, ok := sc.loadSchedule(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+	sc.Success(c, schedule)
+}
+
+// ListSchedules 获取周期计划列表
+func (sc *ScheduleController) ListSchedules(c *gin.Context) {
+	offset, limit := sc.ParsePagination(c)
+
+	params := &services.ListTaskSchedulesParams{
+		Offset: offset,
+		Limit:  limit,
+	}
+	if droneID := c.Query("drone_id"); droneID != "" {
+		id, err := strconv.ParseUint(droneID, 10, 32)
+		if err != nil {
+			sc.BadRequest(c, "invalid drone ID")
+			return
+		}
+		params.DroneID = uint(id)
+	}
+	if enabled := c.Query("enabled"); enabled != "" {
+		value, err := strconv.ParseBool(enabled)
+		if err != nil {
+			sc.BadRequest(c, "invalid enabled filter")
+			return
+		}
+		params.Enabled = &value
+	}
+
+	scope, ok := sc.fleetScope(c, 0, models.RoleViewer)
+	if !ok {
+		return
+	}
+	params.Scope = scope
+
+	schedules, total, err := sc.scheduler.ListSchedules(c.Request.Context(), params)
+	if err != nil {
+		sc.LogError("ListSchedules", err, map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		})
+		sc.InternalError(c, "failed to list task schedules")
+		return
+	}
+
+	sc.Success(c, gin.H{
+		"schedules": schedules,
+		"total":     total,
+		"offset":    offset,
+		"limit":     limit,
+	})
+}
+
+// UpdateSchedule 更新周期计划
+func (sc *ScheduleController) UpdateSchedule(c *gin.Context) {
+	schedule, ok := sc.loadSchedule(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+
+	var req UpdateScheduleRequest
+	if err := sc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	if req.DroneID != 0 && req.DroneID != schedule.DroneID {
+		if !sc.authorizeDrone(c, req.DroneID, models.RoleOperator) {
+			return
+		}
+	}
+
+	updated, err := sc.scheduler.UpdateSchedule(c.Request.Context(), schedule.ID, &services.TaskScheduleParams{
+		Name:                 req.Name,
+		CronExpr:             req.CronExpr,
+		Timezone:             req.Timezone,
+		Enabled:              req.Enabled,
+		Policy:               req.Policy,
+		StartAt:              req.StartAt,
+		EndAt:                req.EndAt,
+		TaskName:             req.TaskName,
+		TaskDescription:      req.TaskDescription,
+		TaskType:             req.TaskType,
+		TaskPriority:         req.TaskPriority,
+		DroneID:              req.DroneID,
+		Plan:                 req.Plan,
+		RequiredCapabilities: req.RequiredCapabilities,
+		Delivery:             req.Delivery,
+	})
+	if err != nil {
+		if sc.handleScheduleError(c, err) {
+			return
+		}
+		sc.LogError("UpdateSchedule", err, map[string]interface{}{"schedule_id": schedule.ID})
+		sc.InternalError(c, "failed to update task schedule")
+		return
+	}
+
+	sc.LogInfo("UpdateSchedule", map[string]interface{}{
+		"schedule_id": updated.ID,
+		"enabled":     updated.Enabled,
+		"next_run_at": updated.NextRunAt,
+	})
+	sc.Success(c, updated)
+}
+
+// DeleteSchedule 删除周期计划
+func (sc *ScheduleController) DeleteSchedule(c *gin.Context) {
+	schedule, ok := sc.loadSchedule(c, models.RoleOperator)
+	if !ok {
+		return
+	}
+
+	if err := sc.scheduler.DeleteSchedule(c.Request.Context(), schedule.ID); err != nil {
+		if sc.handleScheduleError(c, err) {
+			return
+		}
+		sc.LogError("DeleteSchedule", err, map[string]interface{}{"schedule_id": schedule.ID})
+		sc.InternalError(c, "failed to delete task schedule")
+		return
+	}
+
+	sc.LogInfo("DeleteSchedule", map[string]interface{}{"schedule_id": schedule.ID})
+	sc.Success(c, gin.H{"message": "task schedule deleted successfully"})
+}
+
+// GetScheduleTasks 获取周期计划生成的任务实例
+func (sc *ScheduleController) GetScheduleTasks(c *gin.Context) {
+	schedule, ok := sc.loadSchedule(c, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	offset, limit := sc.ParsePagination(c)
+	tasks, total, err := sc.taskService.ListTasks(c.Request.Context(), &services.ListTasksParams{
+		Offset:     offset,
+		Limit:      limit,
+		Status:     models.TaskStatus(c.Query("status")),
+		ScheduleID: schedule.ID,
+	})
+	if err != nil {
+		sc.LogError("GetScheduleTasks", err, map[string]interface{}{"schedule_id": schedule.ID})
+		sc.InternalError(c, "failed to list scheduled tasks")
+		return
+	}
+
+	sc.Success(c, gin.H{
+		"tasks":  tasks,
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// PreviewSchedule 预览cron规则接下来的执行时间
+func (sc *ScheduleController) PreviewSchedule(c *gin.Context) {
+	cronExpr := c.Query("cron")
+	if cronExpr == "" {
+		sc.BadRequest(c, "cron is required")
+		return
+	}
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "5"))
+
+	runs, err := sc.scheduler.PreviewRuns(cronExpr, c.Query("timezone"), count)
+	if err != nil {
+		if sc.handleScheduleError(c, err) {
+			return
+		}
+		sc.InternalError(c, "failed to preview task schedule")
+		return
+	}
+
+	sc.Success(c, gin.H{
+		"cron":      cronExpr,
+		"next_runs": runs,
+	})
+}
+
+// loadSchedule 解析路径中的计划ID并校验对计划无人机的权限，失败时已写入响应
+func (sc *ScheduleController) loadSchedule(c *gin.Context, required models.UserRole) (*models.TaskSchedule, bool) {
+	id, err := sc.ParseID(c, "id")
+	if err != nil {
+		sc.BadRequest(c, "invalid schedule ID")
+		return nil, false
+	}
+
+	schedule, err := sc.scheduler.GetSchedule(c.Request.Context(), id)
+	if err != nil {
+		if sc.handleScheduleError(c, err) {
+			return nil, false
+		}
+		sc.LogError("GetSchedule", err, map[string]interface{}{"schedule_id": id})
+		sc.InternalError(c, "failed to get task schedule")
+		return nil, false
+	}
+
+	if !sc.authorizeDrone(c, schedule.DroneID, required) {
+		return nil, false
+	}
+	return schedule, true
+}
+
+// handleScheduleError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (sc *ScheduleController) handleScheduleError(c *gin.Context, err error) bool {
+	var invalidMission *services.MissionValidationError
+	switch {
+	case errors.As(err, &invalidMission):
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "invalid mission plan",
+			Data:    invalidMission,
+			Time:    time.Now().Unix(),
+		})
+	case err == services.ErrScheduleNotFound:
+		sc.NotFound(c, "task schedule not found")
+	case err == services.ErrDroneNotFound:
+		sc.NotFound(c, "drone not found")
+	case errors.Is(err, services.ErrInvalidSchedule), errors.Is(err, services.ErrInvalidCapabilities),
+		errors.Is(err, services.ErrInvalidData):
+		sc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
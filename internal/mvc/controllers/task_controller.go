@@ -0,0 +1,1685 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/coverage"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/llm"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaskController 任务控制器
+type TaskController struct {
+	*BaseController
+	*fleetGuard
+	taskService       services.TaskService
+	assignmentService services.AssignmentService
+	preflightService  services.PreflightService
+	routeService      services.RouteService
+	energyService     services.EnergyService
+	retryService      services.RetryService
+	coverageService   services.CoverageService
+	deliveryService   services.DeliveryService
+	templateService   services.TaskTemplateService
+	approvalService   services.ApprovalService
+}
+
+// NewTaskController 创建任务控制器
+func NewTaskController(
+	logger *logger.Logger,
+	taskService services.TaskService,
+	fleetService services.FleetService,
+	assignmentService services.AssignmentService,
+	preflightService services.PreflightService,
+	routeService services.RouteService,
+	energyService services.EnergyService,
+	retryService services.RetryService,
+	coverageService services.CoverageService,
+	deliveryService services.DeliveryService,
+	templateService services.TaskTemplateService,
+	approvalService services.ApprovalService,
+) *TaskController {
+	base := NewBaseController(logger)
+	return &TaskController{
+		BaseController:    base,
+		fleetGuard:        newFleetGuard(base, fleetService),
+		taskService:       taskService,
+		assignmentService: assignmentService,
+		preflightService:  preflightService,
+		routeService:      routeService,
+		energyService:     energyService,
+		retryService:      retryService,
+		coverageService:   coverageService,
+		deliveryService:   deliveryService,
+		templateService:   templateService,
+		approvalService:   approvalService,
+	}
+}
+
+// CreateTaskRequest 创建任务请求
+type CreateTaskRequest struct {
+	Name                 string                         `json:"name" binding:"required,min=2,max=100"`
+	Description          string                         `json:"description" binding:"omitempty,max=1000"`
+	Type                 models.TaskType                `json:"type" binding:"required,oneof=inspection delivery mapping patrol emergency"`
+	Priority             models.TaskPriority            `json:"priority" binding:"omitempty,oneof=low normal high urgent"`
+	DroneID              uint                           `json:"drone_id"`
+	Plan                 models.TaskPlan                `json:"plan"`
+	ScheduledAt          *time.Time                     `json:"scheduled_at"`
+	AutoRoute            bool                           `json:"auto_route"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+	Delivery             *models.TaskDelivery           `json:"delivery"` // 配送任务必填
+}
+
+// UpdateTaskRequest 更新任务请求
+type UpdateTaskRequest struct {
+	Name                 string                         `json:"name" binding:"omitempty,min=2,max=100"`
+	Description          string                         `json:"description" binding:"omitempty,max=1000"`
+	Type                 models.TaskType                `json:"type" binding:"omitempty,oneof=inspection delivery mapping patrol emergency"`
+	Priority             models.TaskPriority            `json:"priority" binding:"omitempty,oneof=low normal high urgent"`
+	DroneID              *uint                          `json:"drone_id"`
+	Plan                 *models.TaskPlan               `json:"plan"`
+	ScheduledAt          *time.Time                     `json:"scheduled_at"`
+	AutoRoute            bool                           `json:"auto_route"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+	Delivery             *models.TaskDelivery           `json:"delivery"` // 配送任务必填
+}
+
+// CreateTask 创建任务
+func (tc *TaskController) CreateTask(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req CreateTaskRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	if req.Priority == "" {
+		req.Priority = models.TaskPriorityNormal
+	}
+
+	params := &services.CreateTaskParams{
+		Name:                 req.Name,
+		Description:          req.Description,
+		Type:                 req.Type,
+		Priority:             req.Priority,
+		UserID:               userID,
+		DroneID:              req.DroneID,
+		Plan:                 req.Plan,
+		ScheduledAt:          req.ScheduledAt,
+		AutoRoute:            req.AutoRoute,
+		RequiredCapabilities: req.RequiredCapabilities,
+		Delivery:             req.Delivery,
+	}
+	if req.DroneID != 0 {
+		if !tc.authorizeDrone(c, req.DroneID, models.RoleOperator) {
+			return
+		}
+	} else {
+		// 未指定无人机时自动分配，只在当前用户可调度的机队内选择
+		scope, ok := tc.fleetScope(c, 0, models.RoleOperator)
+		if !ok {
+			return
+		}
+		params.AssignmentScope = scope
+	}
+
+	task, err := tc.taskService.CreateTask(c.Request.Context(), params)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("CreateTask", err, map[string]interface{}{
+			"name":     req.Name,
+			"drone_id": req.DroneID,
+		})
+		tc.InternalError(c, "failed to create task")
+		return
+	}
+
+	tc.LogInfo("CreateTask", map[string]interface{}{
+		"task_id":       task.ID,
+		"drone_id":      task.DroneID,
+		"auto_assigned": params.AutoAssigned,
+	})
+
+	tc.Success(c, task)
+}
+
+// GetTask 获取任务信息
+func (tc *TaskController) GetTask(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	task, ok := tc.loadTask(c, id, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	tc.Success(c, task)
+}
+
+// UpdateTask 更新任务
+func (tc *TaskController) UpdateTask(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	if _, ok := tc.loadTask(c, id, models.RoleOperator); !ok {
+		return
+	}
+
+	var req UpdateTaskRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	// 改派到其他无人机时同样需要目标机队的操作员权限
+	if req.DroneID != nil && *req.DroneID != 0 && !tc.authorizeDrone(c, *req.DroneID, models.RoleOperator) {
+		return
+	}
+
+	task, err := tc.taskService.UpdateTask(c.Request.Context(), id, &services.UpdateTaskParams{
+		Name:                 req.Name,
+		Description:          req.Description,
+		Type:                 req.Type,
+		Priority:             req.Priority,
+		DroneID:              req.DroneID,
+		Plan:                 req.Plan,
+		ScheduledAt:          req.ScheduledAt,
+		AutoRoute:            req.AutoRoute,
+		RequiredCapabilities: req.RequiredCapabilities,
+		Delivery:             req.Delivery,
+	})
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("UpdateTask", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to update task")
+		return
+	}
+
+	tc.LogInfo("UpdateTask", map[string]interface{}{"task_id": task.ID})
+	tc.Success(c, task)
+}
+
+// DeleteTask 删除任务
+func (tc *TaskController) DeleteTask(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	if _, ok := tc.loadTask(c, id, models.RoleAdmin); !ok {
+		return
+	}
+
+	if err := tc.taskService.DeleteTask(c.Request.Context(), id); err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("DeleteTask", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to delete task")
+		return
+	}
+
+	tc.LogInfo("DeleteTask", map[string]interface{}{"task_id": id})
+	tc.Success(c, gin.H{"message": "task deleted successfully"})
+}
+
+// ListTasks 获取任务列表
+func (tc *TaskController) ListTasks(c *gin.Context) {
+	offset, limit := tc.ParsePagination(c)
+
+	params := &services.ListTasksParams{
+		Offset: offset,
+		Limit:  limit,
+		Status: models.TaskStatus(c.Query("status")),
+		Type:   models.TaskType(c.Query("type")),
+		Search: c.Query("search"),
+	}
+	if droneID := c.Query("drone_id"); droneID != "" {
+		id, err := strconv.ParseUint(droneID, 10, 32)
+		if err != nil {
+			tc.BadRequest(c, "invalid drone ID")
+			return
+		}
+		params.DroneID = uint(id)
+	}
+	if fleetID := c.Query("fleet_id"); fleetID != "" {
+		id, err := strconv.ParseUint(fleetID, 10, 32)
+		if err != nil {
+			tc.BadRequest(c, "invalid fleet ID")
+			return
+		}
+		params.FleetID = uint(id)
+	}
+	if templateID := c.Query("template_id"); templateID != "" {
+		id, err := strconv.ParseUint(templateID, 10, 32)
+		if err != nil {
+			tc.BadRequest(c, "invalid template ID")
+			return
+		}
+		params.TemplateID = uint(id)
+	}
+
+	scope, ok := tc.fleetScope(c, params.FleetID, models.RoleViewer)
+	if !ok {
+		return
+	}
+	params.Scope = scope
+
+	tasks, total, err := tc.taskService.ListTasks(c.Request.Context(), params)
+	if err != nil {
+		tc.LogError("ListTasks", err, map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		})
+		tc.InternalError(c, "failed to list tasks")
+		return
+	}
+
+	tc.Success(c, gin.H{
+		"tasks":  tasks,
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// GetMyTasks 获取当前用户创建的任务
+func (tc *TaskController) GetMyTasks(c *gin.Context) {
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	offset, limit := tc.ParsePagination(c)
+	tasks, total, err := tc.taskService.GetTasksByUser(c.Request.Context(), userID, &services.ListTasksParams{
+		Offset: offset,
+		Limit:  limit,
+		Status: models.TaskStatus(c.Query("status")),
+	})
+	if err != nil {
+		tc.LogError("GetMyTasks", err, map[string]interface{}{"user_id": userID})
+		tc.InternalError(c, "failed to list tasks")
+		return
+	}
+
+	tc.Success(c, gin.H{
+		"tasks":  tasks,
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// StartTask 启动任务
+func (tc *TaskController) StartTask(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	if _, ok := tc.loadTask(c, id, models.RoleOperator); !ok {
+		return
+	}
+
+	if err := tc.taskService.StartTask(c.Request.Context(), id); err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("StartTask", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to start task")
+		return
+	}
+
+	tc.LogInfo("StartTask", map[string]interface{}{"task_id": id})
+	tc.Success(c, gin.H{"message": "task started successfully"})
+}
+
+// StopTask 停止任务
+func (tc *TaskController) StopTask(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleOperator) {
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	if _, ok := tc.loadTask(c, id, models.RoleOperator); !ok {
+		return
+	}
+
+	if err := tc.taskService.StopTask(c.Request.Context(), id); err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("StopTask", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to stop task")
+		return
+	}
+
+	tc.LogInfo("StopTask", map[string]interface{}{"task_id": id})
+	tc.Success(c, gin.H{"message": "task stopped successfully"})
+}
+
+// UpdateTaskProgress 更新任务进度
+func (tc *TaskController) UpdateTaskProgress(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	if _, ok := tc.loadTask(c, id, models.RoleOperator); !ok {
+		return
+	}
+
+	var req struct {
+		Progress int `json:"progress" binding:"min=0,max=100"`
+	}
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	if err := tc.taskService.UpdateTaskProgress(c.Request.Context(), id, req.Progress); err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("UpdateTaskProgress", err, map[string]interface{}{
+			"task_id":  id,
+			"progress": req.Progress,
+		})
+		tc.InternalError(c, "failed to update task progress")
+		return
+	}
+
+	tc.Success(c, gin.H{"message": "task progress updated successfully"})
+}
+
+// SuggestDronesRequest 候选无人机查询请求
+type SuggestDronesRequest struct {
+	Plan                 models.TaskPlan                `json:"plan"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+	Limit                int                            `json:"limit" binding:"omitempty,min=1,max=50"`
+}
+
+// SuggestDrones 按任务计划给出候选无人机及评分说明
+func (tc *TaskController) SuggestDrones(c *gin.Context) {
+	var req SuggestDronesRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = 5
+	}
+
+	tc.suggestDrones(c, &services.AssignmentRequest{
+		Plan:                 req.Plan,
+		RequiredCapabilities: req.RequiredCapabilities,
+	}, req.Limit)
+}
+
+// GetTaskCandidates 为已有任务给出候选无人机
+func (tc *TaskController) GetTaskCandidates(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	task, ok := tc.loadTask(c, id, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	capabilities, err := task.ParseRequiredCapabilities()
+	if err != nil {
+		tc.BadRequest(c, "task has invalid required capabilities")
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	tc.suggestDrones(c, &services.AssignmentRequest{
+		Plan:                 task.Plan,
+		RequiredCapabilities: capabilities,
+	}, limit)
+}
+
+// RouteRequest 航线规划或校验请求
+type RouteRequest struct {
+	Waypoints   models.WaypointList `json:"waypoints" binding:"required,min=1"`
+	ScheduledAt *time.Time          `json:"scheduled_at"` // 按该时间生效的围栏规划，为空时使用当前时间
+	Obstacles   []llm.Obstacle      `json:"obstacles"`
+	Zones       []llm.Zone          `json:"zones"`
+}
+
+// PlanRoute 规划绕开禁飞区、限飞区和障碍物的航线
+func (tc *TaskController) PlanRoute(c *gin.Context) {
+	tc.route(c, "PlanRoute", tc.routeService.PlanRoute)
+}
+
+// VerifyRoute 校验航线（如LLM给出的航线）是否与限制区域保持安全距离
+func (tc *TaskController) VerifyRoute(c *gin.Context) {
+	tc.route(c, "VerifyRoute", tc.routeService.VerifyRoute)
+}
+
+// route 解析航线请求并调用规划或校验
+func (tc *TaskController) route(
+	c *gin.Context,
+	operation string,
+	fn func(context.Context, *services.RouteParams) (*services.RouteResult, error),
+) {
+	var req RouteRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	result, err := fn(c.Request.Context(), &services.RouteParams{
+		Waypoints: req.Waypoints,
+		At:        req.ScheduledAt,
+		Obstacles: req.Obstacles,
+		Zones:     req.Zones,
+	})
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError(operation, err, map[string]interface{}{"waypoints": len(req.Waypoints)})
+		tc.InternalError(c, "failed to process route")
+		return
+	}
+
+	tc.Success(c, result)
+}
+
+// CoverageRequest 测绘覆盖规划请求
+type CoverageRequest struct {
+	Polygon      []geo.Point       `json:"polygon" binding:"required,min=3"`
+	Altitude     float64           `json:"altitude" binding:"required,gt=0"`
+	Camera       coverage.Camera   `json:"camera"`
+	Sensor       models.SensorType `json:"sensor"`
+	FrontOverlap float64           `json:"front_overlap" binding:"min=0,max=95"`
+	SideOverlap  float64           `json:"side_overlap" binding:"min=0,max=95"`
+	Angle        *float64          `json:"angle"`
+	Speed        float64           `json:"speed" binding:"omitempty,gt=0"`
+	Drones       int               `json:"drones" binding:"omitempty,min=1"`
+}
+
+// params 转换为服务层参数
+func (r *CoverageRequest) params() services.CoverageParams {
+	return services.CoverageParams{
+		Polygon:      r.Polygon,
+		Altitude:     r.Altitude,
+		Camera:       r.Camera,
+		Sensor:       r.Sensor,
+		FrontOverlap: r.FrontOverlap,
+		SideOverlap:  r.SideOverlap,
+		Angle:        r.Angle,
+		Speed:        r.Speed,
+		Drones:       r.Drones,
+	}
+}
+
+// CreateCoverageTasksRequest 按覆盖规划创建测绘任务请求
+type CreateCoverageTasksRequest struct {
+	CoverageRequest
+	Name                 string                         `json:"name" binding:"required,min=2,max=90"`
+	Description          string                         `json:"description" binding:"omitempty,max=1000"`
+	Priority             models.TaskPriority            `json:"priority" binding:"omitempty,oneof=low normal high urgent"`
+	DroneIDs             []uint                         `json:"drone_ids"` // 按部分顺序指定，为空时自动分配
+	ScheduledAt          *time.Time                     `json:"scheduled_at"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+}
+
+// PlanCoverage 预览测区的往返式测绘航线、拍照点和多机切分，不创建任务
+func (tc *TaskController) PlanCoverage(c *gin.Context) {
+	var req CoverageRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	params := req.params()
+	result, err := tc.coverageService.PlanCoverage(c.Request.Context(), &params)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("PlanCoverage", err, map[string]interface{}{"vertices": len(req.Polygon)})
+		tc.InternalError(c, "failed to plan coverage")
+		return
+	}
+
+	tc.Success(c, result)
+}
+
+// CreateCoverageTasks 按覆盖规划为每架无人机创建一个测绘任务
+func (tc *TaskController) CreateCoverageTasks(c *gin.Context) {
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req CreateCoverageTasksRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+	if req.Priority == "" {
+		req.Priority = models.TaskPriorityNormal
+	}
+
+	params := &services.CoverageTaskParams{
+		CoverageParams:       req.params(),
+		Name:                 req.Name,
+		Description:          req.Description,
+		Priority:             req.Priority,
+		UserID:               userID,
+		DroneIDs:             req.DroneIDs,
+		ScheduledAt:          req.ScheduledAt,
+		RequiredCapabilities: req.RequiredCapabilities,
+	}
+	if len(req.DroneIDs) > 0 {
+		for _, droneID := range req.DroneIDs {
+			if !tc.authorizeDrone(c, droneID, models.RoleOperator) {
+				return
+			}
+		}
+	} else {
+		// 未指定无人机时自动分配，只在当前用户可调度的机队内选择
+		scope, ok := tc.fleetScope(c, 0, models.RoleOperator)
+		if !ok {
+			return
+		}
+		params.AssignmentScope = scope
+	}
+
+	result, err := tc.coverageService.CreateCoverageTasks(c.Request.Context(), params)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("CreateCoverageTasks", err, map[string]interface{}{
+			"name":   req.Name,
+			"drones": req.Drones,
+		})
+		tc.InternalError(c, "failed to create coverage tasks")
+		return
+	}
+
+	taskIDs := make([]uint, len(result.Tasks))
+	for i, task := range result.Tasks {
+		taskIDs[i] = task.ID
+	}
+	tc.LogInfo("CreateCoverageTasks", map[string]interface{}{
+		"task_ids": taskIDs,
+		"photos":   result.Coverage.Photos,
+	})
+
+	tc.Success(c, result)
+}
+
+// EstimateEnergyRequest 能耗估算请求
+type EstimateEnergyRequest struct {
+	DroneID      uint             `json:"drone_id" binding:"required"`
+	Plan         models.TaskPlan  `json:"plan"`
+	Weather      *llm.WeatherInfo `json:"weather"`
+	StartBattery *int             `json:"start_battery" binding:"omitempty,min=0,max=100"`
+}
+
+// RecordEnergyRequest 回填实际耗电请求
+type RecordEnergyRequest struct {
+	EndBattery *int `json:"end_battery" binding:"omitempty,min=0,max=100"` // 为空时使用无人机当前电量
+}
+
+// EstimateEnergy 按无人机和计划估算航程、时长和耗电，不保存
+func (tc *TaskController) EstimateEnergy(c *gin.Context) {
+	var req EstimateEnergyRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+	if !tc.authorizeDrone(c, req.DroneID, models.RoleViewer) {
+		return
+	}
+
+	estimate, err := tc.energyService.Estimate(c.Request.Context(), &services.EnergyEstimateParams{
+		DroneID:      req.DroneID,
+		Plan:         &req.Plan,
+		Weather:      req.Weather,
+		StartBattery: req.StartBattery,
+	})
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("EstimateEnergy", err, map[string]interface{}{"drone_id": req.DroneID})
+		tc.InternalError(c, "failed to estimate energy")
+		return
+	}
+
+	tc.Success(c, estimate)
+}
+
+// GetTaskEnergy 获取任务保存的能耗估算和实际耗电
+func (tc *TaskController) GetTaskEnergy(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	task, ok := tc.loadTask(c, id, models.RoleViewer)
+	if !ok {
+		return
+	}
+	if task.Energy.IsZero() {
+		tc.NotFound(c, services.ErrEnergyNotEstimated.Error())
+		return
+	}
+
+	tc.Success(c, task.Energy)
+}
+
+// EstimateTaskEnergy 按无人机当前状态重新估算并保存任务能耗
+func (tc *TaskController) EstimateTaskEnergy(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+	if _, ok := tc.loadTask(c, id, models.RoleOperator); !ok {
+		return
+	}
+
+	estimate, err := tc.energyService.EstimateTask(c.Request.Context(), id)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("EstimateTaskEnergy", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to estimate task energy")
+		return
+	}
+
+	tc.Success(c, estimate)
+}
+
+// RecordTaskEnergy 回填已完成任务的实际耗电并校准机型
+func (tc *TaskController) RecordTaskEnergy(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	task, ok := tc.loadTask(c, id, models.RoleOperator)
+	if !ok {
+		return
+	}
+	if task.Status != models.TaskStatusCompleted {
+		tc.BadRequest(c, "only completed tasks can record actual energy usage")
+		return
+	}
+
+	var req RecordEnergyRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	estimate, err := tc.energyService.RecordActual(c.Request.Context(), id, req.EndBattery)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("RecordTaskEnergy", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to record actual energy usage")
+		return
+	}
+
+	tc.LogInfo("RecordTaskEnergy", map[string]interface{}{
+		"task_id":   id,
+		"estimated": estimate.BatteryUsed,
+		"actual":    estimate.ActualBatteryUsed,
+	})
+	tc.Success(c, estimate)
+}
+
+// ListEnergyCalibrations 获取各机型的耗电校准参数
+func (tc *TaskController) ListEnergyCalibrations(c *gin.Context) {
+	calibrations, err := tc.energyService.ListCalibrations(c.Request.Context())
+	if err != nil {
+		tc.LogError("ListEnergyCalibrations", err, nil)
+		tc.InternalError(c, "failed to list energy calibrations")
+		return
+	}
+
+	tc.Success(c, calibrations)
+}
+
+// RetryPolicyRequest 任务类型重试策略请求
+type RetryPolicyRequest struct {
+	MaxAttempts         int                  `json:"max_attempts" binding:"required,min=1,max=10"`
+	BackoffSeconds      int                  `json:"backoff_seconds" binding:"min=0"`
+	BackoffMultiplier   float64              `json:"backoff_multiplier" binding:"min=0"`
+	MaxBackoffSeconds   int                  `json:"max_backoff_seconds" binding:"min=0"`
+	RetryableErrorCodes []string             `json:"retryable_error_codes" binding:"omitempty,dive,max=50"`
+	Strategy            models.RetryStrategy `json:"strategy" binding:"omitempty,oneof=same_drone reassign auto"`
+	ReassignErrorCodes  []string             `json:"reassign_error_codes" binding:"omitempty,dive,max=50"`
+	ResumeFromWaypoint  bool                 `json:"resume_from_waypoint"`
+}
+
+// GetTaskAttempts 获取任务的执行历史（每次执行和失败后的重试决定）
+func (tc *TaskController) GetTaskAttempts(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+	if _, ok := tc.loadTask(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	attempts, err := tc.retryService.ListAttempts(c.Request.Context(), id)
+	if err != nil {
+		tc.LogError("GetTaskAttempts", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to list task attempts")
+		return
+	}
+
+	tc.Success(c, gin.H{
+		"attempts": attempts,
+		"total":    len(attempts),
+	})
+}
+
+// DeliveryMilestoneRequest 记录配送状态节点请求
+type DeliveryMilestoneRequest struct {
+	Status    models.DeliveryStatus         `json:"status" binding:"required,oneof=picked_up en_route delivered returned"`
+	Latitude  *float64                      `json:"latitude"` // 为空时使用无人机当前位置
+	Longitude *float64                      `json:"longitude"`
+	Note      string                        `json:"note" binding:"omitempty,max=500"`
+	Proof     *services.DeliveryProofParams `json:"proof"` // delivered 时必填
+}
+
+// GetTaskDelivery 获取配送信息、状态节点和交付证明
+func (tc *TaskController) GetTaskDelivery(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	task, ok := tc.loadTask(c, id, models.RoleViewer)
+	if !ok {
+		return
+	}
+	if task.Type != models.TaskTypeDelivery || task.Delivery.IsZero() {
+		tc.NotFound(c, "task has no delivery")
+		return
+	}
+
+	tc.Success(c, gin.H{
+		"delivery": task.Delivery,
+		"proof":    task.Result.Proof,
+	})
+}
+
+// RecordDeliveryMilestone 记录取货、出发、交付或带回，交付时需附交付证明
+func (tc *TaskController) RecordDeliveryMilestone(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	if _, ok := tc.loadTask(c, id, models.RoleOperator); !ok {
+		return
+	}
+
+	var req DeliveryMilestoneRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	task, err := tc.deliveryService.RecordMilestone(c.Request.Context(), id, &services.DeliveryMilestoneParams{
+		Status:    req.Status,
+		Latitude:  req.Latitude,
+		Longitude: req.Longitude,
+		Note:      req.Note,
+		UserID:    userID,
+		Proof:     req.Proof,
+	})
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("RecordDeliveryMilestone", err, map[string]interface{}{
+			"task_id": id,
+			"status":  req.Status,
+		})
+		tc.InternalError(c, "failed to record delivery milestone")
+		return
+	}
+
+	tc.LogInfo("RecordDeliveryMilestone", map[string]interface{}{
+		"task_id": id,
+		"status":  req.Status,
+	})
+
+	tc.Success(c, gin.H{
+		"delivery": task.Delivery,
+		"proof":    task.Result.Proof,
+	})
+}
+
+// ListRetryPolicies 获取各任务类型的重试策略
+func (tc *TaskController) ListRetryPolicies(c *gin.Context) {
+	policies, err := tc.retryService.ListPolicies(c.Request.Context())
+	if err != nil {
+		tc.LogError("ListRetryPolicies", err, nil)
+		tc.InternalError(c, "failed to list retry policies")
+		return
+	}
+
+	tc.Success(c, policies)
+}
+
+// SetRetryPolicy 创建或替换任务类型的重试策略
+func (tc *TaskController) SetRetryPolicy(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	taskType := models.TaskType(c.Param("type"))
+	var req RetryPolicyRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	policy, err := tc.retryService.SetPolicy(c.Request.Context(), &services.RetryPolicyParams{
+		TaskType:            taskType,
+		MaxAttempts:         req.MaxAttempts,
+		BackoffSeconds:      req.BackoffSeconds,
+		BackoffMultiplier:   req.BackoffMultiplier,
+		MaxBackoffSeconds:   req.MaxBackoffSeconds,
+		RetryableErrorCodes: req.RetryableErrorCodes,
+		Strategy:            req.Strategy,
+		ReassignErrorCodes:  req.ReassignErrorCodes,
+		ResumeFromWaypoint:  req.ResumeFromWaypoint,
+	})
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("SetRetryPolicy", err, map[string]interface{}{"task_type": taskType})
+		tc.InternalError(c, "failed to save retry policy")
+		return
+	}
+
+	tc.LogInfo("SetRetryPolicy", map[string]interface{}{
+		"task_type":    taskType,
+		"max_attempts": policy.MaxAttempts,
+		"strategy":     policy.Strategy,
+	})
+	tc.Success(c, policy)
+}
+
+// DeleteRetryPolicy 删除任务类型的重试策略
+func (tc *TaskController) DeleteRetryPolicy(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	taskType := models.TaskType(c.Param("type"))
+	if err := tc.retryService.DeletePolicy(c.Request.Context(), taskType); err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("DeleteRetryPolicy", err, map[string]interface{}{"task_type": taskType})
+		tc.InternalError(c, "failed to delete retry policy")
+		return
+	}
+
+	tc.LogInfo("DeleteRetryPolicy", map[string]interface{}{"task_type": taskType})
+	tc.Success(c, gin.H{"message": "retry policy deleted successfully"})
+}
+
+// ApprovalRuleRequest 创建或修改审批规则请求
+type ApprovalRuleRequest struct {
+	Name          string                  `json:"name" binding:"required,min=2,max=100"`
+	Description   string                  `json:"description" binding:"omitempty,max=1000"`
+	Enabled       *bool                   `json:"enabled"`
+	Priorities    []models.TaskPriority   `json:"priorities" binding:"omitempty,dive,oneof=low normal high urgent"`
+	TaskTypes     []models.TaskType       `json:"task_types" binding:"omitempty,dive,oneof=inspection delivery mapping patrol emergency"`
+	GeofenceIDs   []uint                  `json:"geofence_ids"`
+	GeofenceTypes []models.GeofenceType   `json:"geofence_types"`
+	Windows       []models.GeofenceWindow `json:"windows"`
+	Timezone      string                  `json:"timezone" binding:"omitempty,max=64"`
+}
+
+// ApprovalDecisionRequest 审批任务请求，驳回时必须填写意见
+type ApprovalDecisionRequest struct {
+	Comment string `json:"comment" binding:"omitempty,max=1000"`
+}
+
+// ListApprovalRules 获取任务审批规则
+func (tc *TaskController) ListApprovalRules(c *gin.Context) {
+	rules, err := tc.approvalService.ListRules(c.Request.Context())
+	if err != nil {
+		tc.LogError("ListApprovalRules", err, nil)
+		tc.InternalError(c, "failed to list approval rules")
+		return
+	}
+
+	tc.Success(c, rules)
+}
+
+// GetApprovalRule 获取任务审批规则详情
+func (tc *TaskController) GetApprovalRule(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid approval rule ID")
+		return
+	}
+
+	rule, err := tc.approvalService.GetRule(c.Request.Context(), id)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("GetApprovalRule", err, map[string]interface{}{"rule_id": id})
+		tc.InternalError(c, "failed to get approval rule")
+		return
+	}
+
+	tc.Success(c, rule)
+}
+
+// CreateApprovalRule 创建任务审批规则
+func (tc *TaskController) CreateApprovalRule(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req ApprovalRuleRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	params := req.params()
+	params.CreatedBy = userID
+	rule, err := tc.approvalService.CreateRule(c.Request.Context(), params)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("CreateApprovalRule", err, map[string]interface{}{"name": req.Name})
+		tc.InternalError(c, "failed to create approval rule")
+		return
+	}
+
+	tc.LogInfo("CreateApprovalRule", map[string]interface{}{
+		"rule_id": rule.ID,
+		"name":    rule.Name,
+	})
+	tc.Success(c, rule)
+}
+
+// UpdateApprovalRule 替换任务审批规则
+func (tc *TaskController) UpdateApprovalRule(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid approval rule ID")
+		return
+	}
+
+	var req ApprovalRuleRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	rule, err := tc.approvalService.UpdateRule(c.Request.Context(), id, req.params())
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("UpdateApprovalRule", err, map[string]interface{}{"rule_id": id})
+		tc.InternalError(c, "failed to update approval rule")
+		return
+	}
+
+	tc.LogInfo("UpdateApprovalRule", map[string]interface{}{
+		"rule_id": rule.ID,
+		"enabled": rule.Enabled,
+	})
+	tc.Success(c, rule)
+}
+
+// DeleteApprovalRule 删除任务审批规则
+func (tc *TaskController) DeleteApprovalRule(c *gin.Context) {
+	if !tc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid approval rule ID")
+		return
+	}
+
+	if err := tc.approvalService.DeleteRule(c.Request.Context(), id); err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("DeleteApprovalRule", err, map[string]interface{}{"rule_id": id})
+		tc.InternalError(c, "failed to delete approval rule")
+		return
+	}
+
+	tc.LogInfo("DeleteApprovalRule", map[string]interface{}{"rule_id": id})
+	tc.Success(c, gin.H{"message": "approval rule deleted successfully"})
+}
+
+// ApproveTask 管理员审批通过待审批的任务
+func (tc *TaskController) ApproveTask(c *gin.Context) {
+	tc.decideApproval(c, "ApproveTask", tc.approvalService.Approve)
+}
+
+// RejectTask 管理员驳回待审批的任务，任务被取消
+func (tc *TaskController) RejectTask(c *gin.Context) {
+	tc.decideApproval(c, "RejectTask", tc.approvalService.Reject)
+}
+
+// decideApproval 记录审批结果
+func (tc *TaskController) decideApproval(c *gin.Context, operation string, decide func(ctx context.Context, taskID, userID uint, comment string) (*models.Task, error)) {
+	if !tc.CheckPermission(c, models.RoleAdmin) {
+		return
+	}
+
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+
+	var req ApprovalDecisionRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	task, err := decide(c.Request.Context(), id, userID, req.Comment)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError(operation, err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to record approval decision")
+		return
+	}
+
+	tc.LogInfo(operation, map[string]interface{}{
+		"task_id":  id,
+		"decision": task.Approval.Status,
+		"status":   task.Status,
+	})
+	tc.Success(c, task)
+}
+
+// params 转换为服务层参数
+func (r *ApprovalRuleRequest) params() *services.ApprovalRuleParams {
+	return &services.ApprovalRuleParams{
+		Name:          r.Name,
+		Description:   r.Description,
+		Enabled:       r.Enabled,
+		Priorities:    r.Priorities,
+		TaskTypes:     r.TaskTypes,
+		GeofenceIDs:   r.GeofenceIDs,
+		GeofenceTypes: r.GeofenceTypes,
+		Windows:       r.Windows,
+		Timezone:      r.Timezone,
+	}
+}
+
+// TaskTemplateRequest 创建任务模板请求
+type TaskTemplateRequest struct {
+	Name                 string                       `json:"name" binding:"required,min=2,max=100"`
+	Description          string                       `json:"description" binding:"omitempty,max=1000"`
+	TaskName             string                       `json:"task_name" binding:"omitempty,max=100"`
+	Type                 models.TaskType              `json:"type" binding:"required,oneof=inspection delivery mapping patrol emergency"`
+	Priority             models.TaskPriority          `json:"priority" binding:"omitempty,oneof=low normal high urgent"`
+	Plan                 models.TemplateBody          `json:"plan" binding:"required"`
+	RequiredCapabilities models.TemplateBody          `json:"required_capabilities"`
+	Delivery             models.TemplateBody          `json:"delivery"` // 配送模板必填
+	Parameters           models.TemplateParameterList `json:"parameters"`
+}
+
+// CloneTaskTemplateRequest 克隆任务模板请求，未提供的字段沿用来源版本
+type CloneTaskTemplateRequest struct {
+	Name                 string                       `json:"name" binding:"omitempty,min=2,max=100"` // 为空时生成新版本
+	Description          string                       `json:"description" binding:"omitempty,max=1000"`
+	TaskName             string                       `json:"task_name" binding:"omitempty,max=100"`
+	Type                 models.TaskType              `json:"type" binding:"omitempty,oneof=inspection delivery mapping patrol emergency"`
+	Priority             models.TaskPriority          `json:"priority" binding:"omitempty,oneof=low normal high urgent"`
+	Plan                 models.TemplateBody          `json:"plan"`
+	RequiredCapabilities models.TemplateBody          `json:"required_capabilities"`
+	Delivery             models.TemplateBody          `json:"delivery"`
+	Parameters           models.TemplateParameterList `json:"parameters"`
+}
+
+// InstantiateTemplateRequest 由模板创建任务请求
+type InstantiateTemplateRequest struct {
+	Values      map[string]json.RawMessage `json:"values"`
+	Name        string                     `json:"name" binding:"omitempty,min=2,max=100"`
+	Description string                     `json:"description" binding:"omitempty,max=1000"`
+	Priority    models.TaskPriority        `json:"priority" binding:"omitempty,oneof=low normal high urgent"`
+	DroneID     uint                       `json:"drone_id"`
+	ScheduledAt *time.Time                 `json:"scheduled_at"`
+	AutoRoute   bool                       `json:"auto_route"`
+}
+
+// CreateTaskTemplate 创建任务模板
+func (tc *TaskController) CreateTaskTemplate(c *gin.Context) {
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req TaskTemplateRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	template, err := tc.templateService.CreateTemplate(c.Request.Context(), &services.TaskTemplateParams{
+		Name:                 req.Name,
+		Description:          req.Description,
+		TaskName:             req.TaskName,
+		Type:                 req.Type,
+		Priority:             req.Priority,
+		Plan:                 req.Plan,
+		RequiredCapabilities: req.RequiredCapabilities,
+		Delivery:             req.Delivery,
+		Parameters:           req.Parameters,
+		CreatedBy:            userID,
+	})
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("CreateTaskTemplate", err, map[string]interface{}{"name": req.Name})
+		tc.InternalError(c, "failed to create task template")
+		return
+	}
+
+	tc.LogInfo("CreateTaskTemplate", map[string]interface{}{
+		"template_id": template.ID,
+		"version":     template.Version,
+	})
+	tc.Success(c, template)
+}
+
+// ListTaskTemplates 获取任务模板列表，默认每个模板只返回最新版本
+func (tc *TaskController) ListTaskTemplates(c *gin.Context) {
+	offset, limit := tc.ParsePagination(c)
+
+	templates, total, err := tc.templateService.ListTemplates(c.Request.Context(), &services.ListTaskTemplatesParams{
+		Offset:      offset,
+		Limit:       limit,
+		Name:        c.Query("name"),
+		Type:        models.TaskType(c.Query("type")),
+		Search:      c.Query("search"),
+		AllVersions: c.Query("all_versions") == "true",
+	})
+	if err != nil {
+		tc.LogError("ListTaskTemplates", err, map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		})
+		tc.InternalError(c, "failed to list task templates")
+		return
+	}
+
+	tc.Success(c, gin.H{
+		"templates": templates,
+		"total":     total,
+		"offset":    offset,
+		"limit":     limit,
+	})
+}
+
+// GetTaskTemplate 获取任务模板版本
+func (tc *TaskController) GetTaskTemplate(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid template ID")
+		return
+	}
+
+	template, err := tc.templateService.GetTemplate(c.Request.Context(), id)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("GetTaskTemplate", err, map[string]interface{}{"template_id": id})
+		tc.InternalError(c, "failed to get task template")
+		return
+	}
+	tc.Success(c, template)
+}
+
+// CloneTaskTemplate 克隆任务模板，生成同名模板的新版本或新模板
+func (tc *TaskController) CloneTaskTemplate(c *gin.Context) {
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid template ID")
+		return
+	}
+
+	var req CloneTaskTemplateRequest
+	if c.Request.ContentLength > 0 {
+		if err := tc.BindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	template, err := tc.templateService.CloneTemplate(c.Request.Context(), id, &services.TaskTemplateParams{
+		Name:                 req.Name,
+		Description:          req.Description,
+		TaskName:             req.TaskName,
+		Type:                 req.Type,
+		Priority:             req.Priority,
+		Plan:                 req.Plan,
+		RequiredCapabilities: req.RequiredCapabilities,
+		Delivery:             req.Delivery,
+		Parameters:           req.Parameters,
+		CreatedBy:            userID,
+	})
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("CloneTaskTemplate", err, map[string]interface{}{"template_id": id})
+		tc.InternalError(c, "failed to clone task template")
+		return
+	}
+
+	tc.LogInfo("CloneTaskTemplate", map[string]interface{}{
+		"source_id":   id,
+		"template_id": template.ID,
+		"version":     template.Version,
+	})
+	tc.Success(c, template)
+}
+
+// DeleteTaskTemplate 删除任务模板版本
+func (tc *TaskController) DeleteTaskTemplate(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid template ID")
+		return
+	}
+
+	if err := tc.templateService.DeleteTemplate(c.Request.Context(), id); err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("DeleteTaskTemplate", err, map[string]interface{}{"template_id": id})
+		tc.InternalError(c, "failed to delete task template")
+		return
+	}
+
+	tc.LogInfo("DeleteTaskTemplate", map[string]interface{}{"template_id": id})
+	tc.Success(c, gin.H{"message": "task template deleted successfully"})
+}
+
+// InstantiateTaskTemplate 按参数值由模板创建任务
+func (tc *TaskController) InstantiateTaskTemplate(c *gin.Context) {
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid template ID")
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if c.Request.ContentLength > 0 {
+		if err := tc.BindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	params := &services.InstantiateTemplateParams{
+		TemplateID:  id,
+		Values:      req.Values,
+		Name:        req.Name,
+		Description: req.Description,
+		Priority:    req.Priority,
+		UserID:      userID,
+		DroneID:     req.DroneID,
+		ScheduledAt: req.ScheduledAt,
+		AutoRoute:   req.AutoRoute,
+	}
+	if req.DroneID != 0 {
+		if !tc.authorizeDrone(c, req.DroneID, models.RoleOperator) {
+			return
+		}
+	} else {
+		// 未指定无人机时自动分配，只在当前用户可调度的机队内选择
+		scope, ok := tc.fleetScope(c, 0, models.RoleOperator)
+		if !ok {
+			return
+		}
+		params.AssignmentScope = scope
+	}
+
+	task, err := tc.templateService.Instantiate(c.Request.Context(), params)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("InstantiateTaskTemplate", err, map[string]interface{}{
+			"template_id": id,
+			"drone_id":    req.DroneID,
+		})
+		tc.InternalError(c, "failed to create task from template")
+		return
+	}
+
+	tc.LogInfo("InstantiateTaskTemplate", map[string]interface{}{
+		"task_id":          task.ID,
+		"template_id":      id,
+		"template_version": task.TemplateVersion,
+		"drone_id":         task.DroneID,
+	})
+	tc.Success(c, task)
+}
+
+// OverridePreflightRequest 放行起飞前检查警告请求
+type OverridePreflightRequest struct {
+	Reason string `json:"reason" binding:"required,min=5,max=1000"`
+}
+
+// GetPreflight 执行起飞前检查并返回报告
+func (tc *TaskController) GetPreflight(c *gin.Context) {
+	id, ok := tc.preflightTaskID(c)
+	if !ok {
+		return
+	}
+
+	report, err := tc.preflightService.RunChecks(c.Request.Context(), id)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("GetPreflight", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to run pre-flight checks")
+		return
+	}
+
+	tc.Success(c, report)
+}
+
+// OverridePreflight 管理员放行起飞前检查警告，放行记录在有效期内用于下一次启动
+func (tc *TaskController) OverridePreflight(c *gin.Context) {
+	userID, err := tc.GetUserID(c)
+	if err != nil {
+		tc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	id, ok := tc.preflightTaskID(c)
+	if !ok {
+		return
+	}
+
+	var req OverridePreflightRequest
+	if err := tc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	override, err := tc.preflightService.OverrideWarnings(c.Request.Context(), id, userID, req.Reason)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("OverridePreflight", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to override pre-flight warnings")
+		return
+	}
+
+	tc.LogInfo("OverridePreflight", map[string]interface{}{
+		"task_id":     id,
+		"override_id": override.ID,
+		"warnings":    override.Warnings,
+	})
+	tc.Success(c, override)
+}
+
+// ListPreflightOverrides 获取任务的放行审计记录
+func (tc *TaskController) ListPreflightOverrides(c *gin.Context) {
+	id, ok := tc.preflightTaskID(c)
+	if !ok {
+		return
+	}
+
+	overrides, err := tc.preflightService.ListOverrides(c.Request.Context(), id)
+	if err != nil {
+		tc.LogError("ListPreflightOverrides", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to list pre-flight overrides")
+		return
+	}
+
+	tc.Success(c, gin.H{"overrides": overrides})
+}
+
+// preflightTaskID 解析任务ID并校验权限，失败时已写入响应
+func (tc *TaskController) preflightTaskID(c *gin.Context) (uint, bool) {
+	if tc.preflightService == nil {
+		tc.Error(c, http.StatusServiceUnavailable, "pre-flight checks are not enabled")
+		return 0, false
+	}
+
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return 0, false
+	}
+
+	if _, ok := tc.loadTask(c, id, models.RoleOperator); !ok {
+		return 0, false
+	}
+	return id, true
+}
+
+// suggestDrones 在当前用户可调度的机队内查询候选无人机
+func (tc *TaskController) suggestDrones(c *gin.Context, req *services.AssignmentRequest, limit int) {
+	if tc.assignmentService == nil {
+		tc.Error(c, http.StatusServiceUnavailable, "automatic assignment is not enabled")
+		return
+	}
+
+	scope, ok := tc.fleetScope(c, 0, models.RoleOperator)
+	if !ok {
+		return
+	}
+	req.Scope = scope
+
+	candidates, err := tc.assignmentService.SuggestDrones(c.Request.Context(), req, limit)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return
+		}
+		tc.LogError("SuggestDrones", err, map[string]interface{}{})
+		tc.InternalError(c, "failed to suggest drones")
+		return
+	}
+
+	tc.Success(c, gin.H{
+		"candidates": candidates,
+		"count":      len(candidates),
+	})
+}
+
+// loadTask 获取任务并按任务无人机所属机队校验权限，失败时已写入响应
+// 未指派无人机的任务仅受全局角色控制
+func (tc *TaskController) loadTask(c *gin.Context, id uint, required models.UserRole) (*models.Task, bool) {
+	task, err := tc.taskService.GetTaskByID(c.Request.Context(), id)
+	if err != nil {
+		if tc.handleTaskError(c, err) {
+			return nil, false
+		}
+		tc.LogError("GetTask", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to get task")
+		return nil, false
+	}
+
+	if task.DroneID != 0 && !tc.authorizeDrone(c, task.DroneID, required) {
+		return nil, false
+	}
+	return task, true
+}
+
+// handleTaskError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (tc *TaskController) handleTaskError(c *gin.Context, err error) bool {
+	var mismatch *services.CapabilityMismatchError
+	var invalidMission *services.MissionValidationError
+	var violation *services.GeofenceViolationError
+	var preflight *services.PreflightFailedError
+	var insufficient *services.EnergyInsufficientError
+	switch {
+	case errors.As(err, &mismatch):
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "drone does not satisfy required capabilities",
+			Data:    mismatch,
+			Time:    time.Now().Unix(),
+		})
+	case errors.As(err, &invalidMission):
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "invalid mission plan",
+			Data:    invalidMission,
+			Time:    time.Now().Unix(),
+		})
+	case errors.As(err, &violation):
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "task plan crosses restricted geofence",
+			Data:    violation,
+			Time:    time.Now().Unix(),
+		})
+	case errors.As(err, &preflight):
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: preflight.Error(),
+			Data:    preflight,
+			Time:    time.Now().Unix(),
+		})
+	case errors.As(err, &insufficient):
+		c.JSON(http.StatusBadRequest, Response{
+			Code:    http.StatusBadRequest,
+			Message: "not enough battery for mission",
+			Data:    insufficient,
+			Time:    time.Now().Unix(),
+		})
+	case err == services.ErrTaskNotFound:
+		tc.NotFound(c, "task not found")
+	case err == services.ErrEnergyNotEstimated, err == services.ErrRetryPolicyNotFound, err == services.ErrTemplateNotFound,
+		err == services.ErrApprovalRuleNotFound:
+		tc.NotFound(c, err.Error())
+	case err == services.ErrDroneNotFound:
+		tc.NotFound(c, "drone not found")
+	case err == services.ErrTaskNotRunning, err == services.ErrTaskAlreadyRunning,
+		err == services.ErrTaskCannotStart, err == services.ErrDroneNotAvailable, err == services.ErrDroneInUse,
+		err == services.ErrNoDroneAvailable, err == services.ErrNothingToOverride,
+		err == services.ErrApprovalRequired, err == services.ErrApprovalPending, err == services.ErrTaskNotPendingApproval:
+		tc.BadRequest(c, err.Error())
+	case err == services.ErrSelfApproval:
+		tc.Forbidden(c, err.Error())
+	case errors.Is(err, services.ErrInvalidCapabilities), errors.Is(err, services.ErrInvalidData),
+		errors.Is(err, services.ErrNoRoute), errors.Is(err, services.ErrInvalidRetryPolicy),
+		errors.Is(err, services.ErrInvalidCoverage), errors.Is(err, services.ErrInvalidDelivery),
+		errors.Is(err, services.ErrDeliveryTransition), errors.Is(err, services.ErrDeliveryNotConfirmed),
+		errors.Is(err, services.ErrInvalidTemplate), errors.Is(err, services.ErrInvalidApprovalRule),
+		errors.Is(err, services.ErrInvalidApproval):
+		tc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+	"drone-control-system/pkg/trackexport"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TelemetryController 遥测轨迹控制器
+type TelemetryController struct {
+	*BaseController
+	*fleetGuard
+	telemetryService   services.TelemetryService
+	trackExportService services.TrackExportService
+	taskService        services.TaskService
+}
+
+// NewTelemetryController 创建遥测轨迹控制器
+func NewTelemetryController(logger *logger.Logger, telemetryService services.TelemetryService, trackExportService services.TrackExportService, taskService services.TaskService, fleetService services.FleetService) *TelemetryController {
+	base := NewBaseController(logger)
+	return &TelemetryController{
+		BaseController:     base,
+		fleetGuard:         newFleetGuard(base, fleetService),
+		telemetryService:   telemetryService,
+		trackExportService: trackExportService,
+		taskService:        taskService,
+	}
+}
+
+// GetDroneTrack 查询无人机历史轨迹
+// 查询参数: from/to (RFC3339，默认最近1小时), resolution (raw/1m/1h，默认自动), limit
+// 点数超过 limit 时 track.truncated 为 true，以 track.next 作为 from 继续查询
+func (tc *TelemetryController) GetDroneTrack(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid drone ID")
+		return
+	}
+	if !tc.authorizeDrone(c, id, models.RoleViewer) {
+		return
+	}
+
+	params, ok := tc.parseTrackQuery(c, id)
+	if !ok {
+		return
+	}
+
+	track, err := tc.telemetryService.QueryTrack(c.Request.Context(), params)
+	if err != nil {
+		if err == services.ErrInvalidTimeRange {
+			tc.BadRequest(c, "from must be before to")
+			return
+		}
+		tc.LogError("GetDroneTrack", err, map[string]interface{}{
+			"drone_id": id,
+		})
+		tc.InternalError(c, "failed to query drone track")
+		return
+	}
+
+	tc.Success(c, gin.H{
+		"track": track,
+		"count": len(track.Points),
+	})
+}
+
+// GetTaskTrack 查询任务执行期间的实际轨迹
+func (tc *TelemetryController) GetTaskTrack(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+	if !tc.authorizeTask(c, id) {
+		return
+	}
+
+	resolution, ok := tc.parseResolution(c)
+	if !ok {
+		return
+	}
+
+	track, err := tc.telemetryService.GetTaskTrack(c.Request.Context(), id, resolution)
+	if err != nil {
+		switch err {
+		case services.ErrTaskNotFound:
+			tc.NotFound(c, "task not found")
+		case services.ErrTaskNotStarted:
+			tc.BadRequest(c, "task has not started")
+		default:
+			tc.LogError("GetTaskTrack", err, map[string]interface{}{
+				"task_id": id,
+			})
+			tc.InternalError(c, "failed to query task track")
+		}
+		return
+	}
+
+	tc.Success(c, gin.H{
+		"track": track,
+		"count": len(track.Points),
+	})
+}
+
+// ExportDroneTrack 导出无人机历史轨迹
+// 查询参数同 GetDroneTrack，另加 format (gpx/kml/geojson，默认gpx)
+func (tc *TelemetryController) ExportDroneTrack(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid drone ID")
+		return
+	}
+	if !tc.authorizeDrone(c, id, models.RoleViewer) {
+		return
+	}
+
+	params, ok := tc.parseTrackQuery(c, id)
+	if !ok {
+		return
+	}
+
+	format, err := trackexport.ParseFormat(c.DefaultQuery("format", string(trackexport.FormatGPX)))
+	if err != nil {
+		tc.BadRequest(c, "invalid format, expected gpx, kml or geojson")
+		return
+	}
+
+	export, err := tc.trackExportService.ExportDroneTrack(c.Request.Context(), params, format)
+	if err != nil {
+		if err == services.ErrInvalidTimeRange {
+			tc.BadRequest(c, "from must be before to")
+			return
+		}
+		tc.LogError("ExportDroneTrack", err, map[string]interface{}{
+			"drone_id": id,
+			"format":   format,
+		})
+		tc.InternalError(c, "failed to export drone track")
+		return
+	}
+
+	tc.sendExport(c, export)
+}
+
+// ExportTaskTrack 导出任务计划航线和实际轨迹
+func (tc *TelemetryController) ExportTaskTrack(c *gin.Context) {
+	id, err := tc.ParseID(c, "id")
+	if err != nil {
+		tc.BadRequest(c, "invalid task ID")
+		return
+	}
+	if !tc.authorizeTask(c, id) {
+		return
+	}
+
+	resolution, ok := tc.parseResolution(c)
+	if !ok {
+		return
+	}
+
+	format, err := trackexport.ParseFormat(c.DefaultQuery("format", string(trackexport.FormatGPX)))
+	if err != nil {
+		tc.BadRequest(c, "invalid format, expected gpx, kml or geojson")
+		return
+	}
+
+	export, err := tc.trackExportService.ExportTaskTrack(c.Request.Context(), id, resolution, format)
+	if err != nil {
+		if err == services.ErrTaskNotFound {
+			tc.NotFound(c, "task not found")
+			return
+		}
+		tc.LogError("ExportTaskTrack", err, map[string]interface{}{
+			"task_id": id,
+			"format":  format,
+		})
+		tc.InternalError(c, "failed to export task track")
+		return
+	}
+
+	tc.sendExport(c, export)
+}
+
+// authorizeTask 按任务无人机所属机队校验查看权限，失败时已写入响应
+// 未指派无人机的任务仅受全局角色控制
+func (tc *TelemetryController) authorizeTask(c *gin.Context, id uint) bool {
+	task, err := tc.taskService.GetTaskByID(c.Request.Context(), id)
+	if err != nil {
+		if err == services.ErrTaskNotFound {
+			tc.NotFound(c, "task not found")
+			return false
+		}
+		tc.LogError("GetTask", err, map[string]interface{}{"task_id": id})
+		tc.InternalError(c, "failed to get task")
+		return false
+	}
+
+	return task.DroneID == 0 || tc.authorizeDrone(c, task.DroneID, models.RoleViewer)
+}
+
+// sendExport 以附件形式返回导出文件，轨迹被截断时通过响应头提示
+func (tc *TelemetryController) sendExport(c *gin.Context, export *services.TrackExport) {
+	if export.Truncated {
+		c.Header("X-Track-Truncated", "true")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	c.Data(http.StatusOK, export.ContentType, export.Data)
+}
+
+// parseTrackQuery 解析轨迹查询参数
+func (tc *TelemetryController) parseTrackQuery(c *gin.Context, droneID uint) (*services.TrackQueryParams, bool) {
+	params := &services.TrackQueryParams{DroneID: droneID}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if params.From, err = time.Parse(time.RFC3339, from); err != nil {
+			tc.BadRequest(c, "invalid from time, expected RFC3339")
+			return nil, false
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if params.To, err = time.Parse(time.RFC3339, to); err != nil {
+			tc.BadRequest(c, "invalid to time, expected RFC3339")
+			return nil, false
+		}
+	}
+
+	resolution, ok := tc.parseResolution(c)
+	if !ok {
+		return nil, false
+	}
+	params.Resolution = resolution
+
+	if limit := c.Query("limit"); limit != "" {
+		if params.Limit, err = strconv.Atoi(limit); err != nil || params.Limit <= 0 {
+			tc.BadRequest(c, "invalid limit")
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// parseResolution 解析精度参数，为空表示自动选择
+func (tc *TelemetryController) parseResolution(c *gin.Context) (models.TelemetryResolution, bool) {
+	resolution := models.TelemetryResolution(c.Query("resolution"))
+	if resolution != "" && !resolution.IsValid() {
+		tc.BadRequest(c, "invalid resolution, expected raw, 1m or 1h")
+		return "", false
+	}
+	return resolution, true
+}
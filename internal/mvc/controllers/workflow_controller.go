@@ -0,0 +1,411 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkflowController 多步骤流程控制器
+type WorkflowController struct {
+	*BaseController
+	*fleetGuard
+	workflowService services.WorkflowService
+}
+
+// NewWorkflowController 创建多步骤流程控制器
+func NewWorkflowController(
+	logger *logger.Logger,
+	workflowService services.WorkflowService,
+	fleetService services.FleetService,
+) *WorkflowController {
+	base := NewBaseController(logger)
+	return &WorkflowController{
+		BaseController:  base,
+		fleetGuard:      newFleetGuard(base, fleetService),
+		workflowService: workflowService,
+	}
+}
+
+// CreateWorkflowRequest 创建流程定义请求
+type CreateWorkflowRequest struct {
+	Name        string                  `json:"name" binding:"required,min=2,max=100"`
+	Description string                  `json:"description" binding:"omitempty,max=1000"`
+	Steps       models.WorkflowStepList `json:"steps" binding:"required,min=1"`
+}
+
+// UpdateWorkflowRequest 更新流程定义请求
+type UpdateWorkflowRequest struct {
+	Name        string                  `json:"name" binding:"omitempty,min=2,max=100"`
+	Description string                  `json:"description" binding:"omitempty,max=1000"`
+	Steps       models.WorkflowStepList `json:"steps"`
+}
+
+// StartWorkflowRequest 启动流程请求
+type StartWorkflowRequest struct {
+	Name string `json:"name" binding:"omitempty,min=2,max=100"`
+}
+
+// CancelWorkflowRunRequest 取消流程请求
+type CancelWorkflowRunRequest struct {
+	Reason string `json:"reason" binding:"omitempty,max=500"`
+}
+
+// CreateWorkflow 创建流程定义
+func (wc *WorkflowController) CreateWorkflow(c *gin.Context) {
+	userID, err := wc.GetUserID(c)
+	if err != nil {
+		wc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	var req CreateWorkflowRequest
+	if err := wc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	if !wc.authorizeSteps(c, req.Steps) {
+		return
+	}
+
+	definition, err := wc.workflowService.CreateDefinition(c.Request.Context(), &services.WorkflowDefinitionParams{
+		Name:        req.Name,
+		Description: req.Description,
+		Steps:       req.Steps,
+		CreatedBy:   userID,
+	})
+	if err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return
+		}
+		wc.LogError("CreateWorkflow", err, map[string]interface{}{"name": req.Name})
+		wc.InternalError(c, "failed to create workflow")
+		return
+	}
+
+	wc.LogInfo("CreateWorkflow", map[string]interface{}{
+		"definition_id": definition.ID,
+		"steps":         len(definition.Steps),
+	})
+	wc.Success(c, definition)
+}
+
+// GetWorkflow 获取流程定义
+func (wc *WorkflowController) GetWorkflow(c *gin.Context) {
+	definition, ok := wc.loadDefinition(c)
+	if !ok {
+		return
+	}
+	wc.Success(c, definition)
+}
+
+// ListWorkflows 获取流程定义列表
+func (wc *WorkflowController) ListWorkflows(c *gin.Context) {
+	offset, limit := wc.ParsePagination(c)
+
+	definitions, total, err := wc.workflowService.ListDefinitions(c.Request.Context(), offset, limit)
+	if err != nil {
+		wc.LogError("ListWorkflows", err, map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		})
+		wc.InternalError(c, "failed to list workflows")
+		return
+	}
+
+	wc.Success(c, gin.H{
+		"workflows": definitions,
+		"total":     total,
+		"offset":    offset,
+		"limit":     limit,
+	})
+}
+
+// UpdateWorkflow 更新流程定义
+func (wc *WorkflowController) UpdateWorkflow(c *gin.Context) {
+	definition, ok := wc.loadDefinition(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateWorkflowRequest
+	if err := wc.BindJSON(c, &req); err != nil {
+		return
+	}
+
+	if req.Steps != nil && !wc.authorizeSteps(c, req.Steps) {
+		return
+	}
+
+	updated, err := wc.workflowService.UpdateDefinition(c.Request.Context(), definition.ID, &services.WorkflowDefinitionParams{
+		Name:        req.Name,
+		Description: req.Description,
+		Steps:       req.Steps,
+	})
+	if err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return
+		}
+		wc.LogError("UpdateWorkflow", err, map[string]interface{}{"definition_id": definition.ID})
+		wc.InternalError(c, "failed to update workflow")
+		return
+	}
+
+	wc.LogInfo("UpdateWorkflow", map[string]interface{}{"definition_id": updated.ID})
+	wc.Success(c, updated)
+}
+
+// DeleteWorkflow 删除流程定义
+func (wc *WorkflowController) DeleteWorkflow(c *gin.Context) {
+	definition, ok := wc.loadDefinition(c)
+	if !ok {
+		return
+	}
+
+	if err := wc.workflowService.DeleteDefinition(c.Request.Context(), definition.ID); err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return
+		}
+		wc.LogError("DeleteWorkflow", err, map[string]interface{}{"definition_id": definition.ID})
+		wc.InternalError(c, "failed to delete workflow")
+		return
+	}
+
+	wc.LogInfo("DeleteWorkflow", map[string]interface{}{"definition_id": definition.ID})
+	wc.Success(c, gin.H{"message": "workflow deleted successfully"})
+}
+
+// StartWorkflow 启动流程实例
+func (wc *WorkflowController) StartWorkflow(c *gin.Context) {
+	userID, err := wc.GetUserID(c)
+	if err != nil {
+		wc.Unauthorized(c, "user not authenticated")
+		return
+	}
+
+	definition, ok := wc.loadDefinition(c)
+	if !ok {
+		return
+	}
+
+	var req StartWorkflowRequest
+	if c.Request.ContentLength > 0 {
+		if err := wc.BindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	// 指定无人机的步骤需要调度权限，未指定的步骤只在当前用户可调度的机队内自动分配
+	if !wc.authorizeSteps(c, definition.Steps) {
+		return
+	}
+	scope, ok := wc.fleetScope(c, 0, models.RoleOperator)
+	if !ok {
+		return
+	}
+
+	run, err := wc.workflowService.StartWorkflow(c.Request.Context(), &services.StartWorkflowParams{
+		DefinitionID:    definition.ID,
+		Name:            req.Name,
+		CreatedBy:       userID,
+		AssignmentScope: scope,
+	})
+	if err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return
+		}
+		wc.LogError("StartWorkflow", err, map[string]interface{}{"definition_id": definition.ID})
+		wc.InternalError(c, "failed to start workflow")
+		return
+	}
+
+	wc.LogInfo("StartWorkflow", map[string]interface{}{
+		"definition_id": definition.ID,
+		"run_id":        run.ID,
+	})
+	wc.Success(c, run)
+}
+
+// ListWorkflowRuns 获取流程实例列表
+func (wc *WorkflowController) ListWorkflowRuns(c *gin.Context) {
+	offset, limit := wc.ParsePagination(c)
+
+	params := &services.ListWorkflowRunsParams{
+		Offset: offset,
+		Limit:  limit,
+		Status: models.WorkflowRunStatus(c.Query("status")),
+	}
+	if definitionID := c.Query("definition_id"); definitionID != "" {
+		id, err := strconv.ParseUint(definitionID, 10, 32)
+		if err != nil {
+			wc.BadRequest(c, "invalid definition ID")
+			return
+		}
+		params.DefinitionID = uint(id)
+	}
+
+	runs, total, err := wc.workflowService.ListRuns(c.Request.Context(), params)
+	if err != nil {
+		wc.LogError("ListWorkflowRuns", err, map[string]interface{}{
+			"offset": offset,
+			"limit":  limit,
+		})
+		wc.InternalError(c, "failed to list workflow runs")
+		return
+	}
+
+	wc.Success(c, gin.H{
+		"runs":   runs,
+		"total":  total,
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// GetWorkflowRun 获取流程实例状态和进度
+func (wc *WorkflowController) GetWorkflowRun(c *gin.Context) {
+	id, err := wc.ParseID(c, "id")
+	if err != nil {
+		wc.BadRequest(c, "invalid workflow run ID")
+		return
+	}
+
+	run, err := wc.workflowService.GetRun(c.Request.Context(), id)
+	if err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return
+		}
+		wc.LogError("GetWorkflowRun", err, map[string]interface{}{"run_id": id})
+		wc.InternalError(c, "failed to get workflow run")
+		return
+	}
+	wc.Success(c, run)
+}
+
+// GetWorkflowRunTasks 获取流程实例创建的任务
+func (wc *WorkflowController) GetWorkflowRunTasks(c *gin.Context) {
+	id, err := wc.ParseID(c, "id")
+	if err != nil {
+		wc.BadRequest(c, "invalid workflow run ID")
+		return
+	}
+
+	tasks, err := wc.workflowService.GetRunTasks(c.Request.Context(), id)
+	if err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return
+		}
+		wc.LogError("GetWorkflowRunTasks", err, map[string]interface{}{"run_id": id})
+		wc.InternalError(c, "failed to list workflow run tasks")
+		return
+	}
+
+	wc.Success(c, gin.H{
+		"tasks": tasks,
+		"total": len(tasks),
+	})
+}
+
+// CancelWorkflowRun 取消流程实例
+func (wc *WorkflowController) CancelWorkflowRun(c *gin.Context) {
+	id, err := wc.ParseID(c, "id")
+	if err != nil {
+		wc.BadRequest(c, "invalid workflow run ID")
+		return
+	}
+
+	var req CancelWorkflowRunRequest
+	if c.Request.ContentLength > 0 {
+		if err := wc.BindJSON(c, &req); err != nil {
+			return
+		}
+	}
+
+	// 取消会停止步骤任务，需要对所有已分配无人机有调度权限
+	tasks, err := wc.workflowService.GetRunTasks(c.Request.Context(), id)
+	if err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return
+		}
+		wc.LogError("CancelWorkflowRun", err, map[string]interface{}{"run_id": id})
+		wc.InternalError(c, "failed to cancel workflow run")
+		return
+	}
+	for _, task := range tasks {
+		if !task.IsCompleted() && !wc.authorizeDrone(c, task.DroneID, models.RoleOperator) {
+			return
+		}
+	}
+
+	run, err := wc.workflowService.CancelRun(c.Request.Context(), id, req.Reason)
+	if err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return
+		}
+		wc.LogError("CancelWorkflowRun", err, map[string]interface{}{"run_id": id})
+		wc.InternalError(c, "failed to cancel workflow run")
+		return
+	}
+
+	wc.LogInfo("CancelWorkflowRun", map[string]interface{}{
+		"run_id": run.ID,
+		"reason": req.Reason,
+	})
+	wc.Success(c, run)
+}
+
+// loadDefinition 解析路径中的流程定义ID并加载定义，失败时已写入响应
+func (wc *WorkflowController) loadDefinition(c *gin.Context) (*models.WorkflowDefinition, bool) {
+	id, err := wc.ParseID(c, "id")
+	if err != nil {
+		wc.BadRequest(c, "invalid workflow ID")
+		return nil, false
+	}
+
+	definition, err := wc.workflowService.GetDefinition(c.Request.Context(), id)
+	if err != nil {
+		if wc.handleWorkflowError(c, err) {
+			return nil, false
+		}
+		wc.LogError("GetWorkflow", err, map[string]interface{}{"definition_id": id})
+		wc.InternalError(c, "failed to get workflow")
+		return nil, false
+	}
+	return definition, true
+}
+
+// authorizeSteps 校验对步骤指定无人机的调度权限，失败时已写入响应
+func (wc *WorkflowController) authorizeSteps(c *gin.Context, steps models.WorkflowStepList) bool {
+	checked := make(map[uint]bool)
+	for _, step := range steps {
+		if step.DroneID == 0 || checked[step.DroneID] {
+			continue
+		}
+		if !wc.authorizeDrone(c, step.DroneID, models.RoleOperator) {
+			return false
+		}
+		checked[step.DroneID] = true
+	}
+	return true
+}
+
+// handleWorkflowError 将已知业务错误转换为HTTP响应，返回是否已处理
+func (wc *WorkflowController) handleWorkflowError(c *gin.Context, err error) bool {
+	switch {
+	case err == services.ErrWorkflowNotFound:
+		wc.NotFound(c, "workflow not found")
+	case err == services.ErrWorkflowRunNotFound:
+		wc.NotFound(c, "workflow run not found")
+	case err == services.ErrWorkflowRunFinished, errors.Is(err, services.ErrInvalidWorkflow), errors.Is(err, services.ErrInvalidCapabilities),
+		errors.Is(err, services.ErrInvalidData):
+		wc.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 
+	"drone-control-system/internal/mvc/models"
 	"drone-control-system/internal/mvc/services"
 	"drone-control-system/pkg/kafka"
 	"drone-control-system/pkg/logger"
@@ -13,21 +15,65 @@ type EventHandler struct {
 	logger            *logger.Logger
 	websocketService  services.WebSocketService
 	smartAlertService services.SmartAlertService
+	heartbeatMonitor  services.HeartbeatMonitor
+	telemetryService  services.TelemetryService
+	geoIndex          services.DroneGeoIndex
+	batteryService    services.BatteryService
+	assignmentService services.AssignmentService
+	geofenceService   services.GeofenceService
+	retryService      services.RetryService
+	progressService   services.ProgressService
 	eventBuffer       []kafka.Event
 	bufferSize        int
 }
 
 // NewEventHandler 创建事件处理器
-func NewEventHandler(logger *logger.Logger, websocketService services.WebSocketService, smartAlertService services.SmartAlertService) *EventHandler {
+func NewEventHandler(
+	logger *logger.Logger,
+	websocketService services.WebSocketService,
+	smartAlertService services.SmartAlertService,
+	heartbeatMonitor services.HeartbeatMonitor,
+	telemetryService services.TelemetryService,
+	geoIndex services.DroneGeoIndex,
+	batteryService services.BatteryService,
+	assignmentService services.AssignmentService,
+	geofenceService services.GeofenceService,
+	retryService services.RetryService,
+	progressService services.ProgressService,
+) *EventHandler {
 	return &EventHandler{
 		logger:            logger,
 		websocketService:  websocketService,
 		smartAlertService: smartAlertService,
+		heartbeatMonitor:  heartbeatMonitor,
+		telemetryService:  telemetryService,
+		geoIndex:          geoIndex,
+		batteryService:    batteryService,
+		assignmentService: assignmentService,
+		geofenceService:   geofenceService,
+		retryService:      retryService,
+		progressService:   progressService,
 		eventBuffer:       make([]kafka.Event, 0, 100),
 		bufferSize:        100,
 	}
 }
 
+// Register 将事件处理器注册到Kafka服务（需在Kafka服务启动前调用）
+func (h *EventHandler) Register(kafkaService services.KafkaService) {
+	kafkaService.RegisterHandler(kafka.DroneEventsTopic, kafka.MessageHandlerFunc(
+		func(ctx context.Context, message *kafka.Message) error {
+			return h.HandleDroneEvent(message)
+		}))
+	kafkaService.RegisterHandler(kafka.TaskEventsTopic, kafka.MessageHandlerFunc(
+		func(ctx context.Context, message *kafka.Message) error {
+			return h.HandleTaskEvent(message)
+		}))
+	kafkaService.RegisterHandler(kafka.AlertEventsTopic, kafka.MessageHandlerFunc(
+		func(ctx context.Context, message *kafka.Message) error {
+			return h.HandleAlertEvent(message)
+		}))
+}
+
 // HandleDroneEvent 处理无人机事件
 func (h *EventHandler) HandleDroneEvent(message *kafka.Message) error {
 	h.logger.Debug("Handling drone event", map[string]interface{}{
@@ -56,7 +102,9 @@ func (h *EventHandler) HandleDroneEvent(message *kafka.Message) error {
 		h.handleBatteryLowEvent(&event)
 	case kafka.DroneLocationUpdatedEvent:
 		h.handleLocationUpdateEvent(&event)
-	case kafka.DroneStatusChangedEvent:
+	case kafka.DroneBatteryUpdatedEvent:
+		h.handleBatteryUpdateEvent(&event)
+	case kafka.DroneStatusChangedEvent, kafka.DroneConnectedEvent, kafka.DroneDisconnectedEvent:
 		h.handleStatusChangeEvent(&event)
 	}
 
@@ -111,7 +159,7 @@ func (h *EventHandler) handleEventPattern(pattern *services.EventPattern) {
 
 	// 发送预测性告警
 	for _, issue := range pattern.PredictedIssues {
-		h.websocketService.BroadcastToAll(services.WebSocketMessage{
+		h.websocketService.BroadcastForDrone(issue.DroneID, services.WebSocketMessage{
 			Type: "predictive_alert",
 			Data: issue,
 		})
@@ -119,7 +167,7 @@ func (h *EventHandler) handleEventPattern(pattern *services.EventPattern) {
 
 	// 发送位置异常警告
 	for _, anomaly := range pattern.LocationAnomalies {
-		h.websocketService.BroadcastToAll(services.WebSocketMessage{
+		h.websocketService.BroadcastForDrone(anomaly.DroneID, services.WebSocketMessage{
 			Type: "location_anomaly",
 			Data: anomaly,
 		})
@@ -192,14 +240,130 @@ func (h *EventHandler) handleBatteryLowEvent(event *kafka.Event) {
 
 // handleLocationUpdateEvent 处理位置更新事件
 func (h *EventHandler) handleLocationUpdateEvent(event *kafka.Event) {
-	// 可以添加额外的处理逻辑：
-	// 1. 检查是否进入禁飞区
-	// 2. 更新轨迹缓存
-	// 3. 地理围栏检查
-
 	h.logger.Debug("Drone location updated", map[string]interface{}{
 		"event_data": event.Data,
 	})
+
+	droneID, ok := eventDroneID(event)
+	if !ok {
+		return
+	}
+
+	// 位置上报即视为一次心跳
+	if h.heartbeatMonitor != nil {
+		if err := h.heartbeatMonitor.RecordHeartbeat(context.Background(), droneID, event.Timestamp); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to record drone heartbeat")
+		}
+	}
+
+	position, ok := eventPosition(event)
+	if !ok {
+		return
+	}
+
+	h.recordTelemetry(&services.TelemetrySample{
+		DroneID:   droneID,
+		Position:  position,
+		Timestamp: event.Timestamp,
+	})
+
+	if h.geoIndex != nil {
+		if err := h.geoIndex.UpdatePosition(context.Background(), droneID, *position, event.Timestamp); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to update drone geo index")
+		}
+	}
+
+	// 地理围栏检查，进入或接近限制区域时由围栏服务产生安全告警
+	if h.geofenceService != nil {
+		if _, err := h.geofenceService.CheckPosition(context.Background(), droneID, *position, event.Timestamp); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to check drone geofences")
+		}
+	}
+
+	// 按执行中任务的航点推算进度
+	if h.progressService != nil {
+		if err := h.progressService.HandlePosition(context.Background(), droneID, *position, event.Timestamp); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to update task progress")
+		}
+	}
+}
+
+// handleBatteryUpdateEvent 处理电量更新事件
+func (h *EventHandler) handleBatteryUpdateEvent(event *kafka.Event) {
+	droneID, ok := eventDroneID(event)
+	if !ok {
+		return
+	}
+
+	if h.heartbeatMonitor != nil {
+		if err := h.heartbeatMonitor.RecordHeartbeat(context.Background(), droneID, event.Timestamp); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to record drone heartbeat")
+		}
+	}
+
+	battery, ok := event.Data["battery"].(float64)
+	if !ok {
+		return
+	}
+
+	level := int(battery)
+	h.recordTelemetry(&services.TelemetrySample{
+		DroneID:   droneID,
+		Battery:   &level,
+		Timestamp: event.Timestamp,
+	})
+
+	if h.geoIndex != nil {
+		if err := h.geoIndex.UpdateBattery(context.Background(), droneID, level); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to update drone geo index")
+		}
+	}
+
+	// 电量读数累计到当前安装的电池组，用于循环计数和健康度跟踪
+	if h.batteryService != nil {
+		var stateOfHealth *float64
+		if soh, ok := event.Data["state_of_health"].(float64); ok {
+			stateOfHealth = &soh
+		}
+		if err := h.batteryService.RecordReading(context.Background(), droneID, level, stateOfHealth, event.Timestamp); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to record battery pack reading")
+		}
+	}
+}
+
+// recordTelemetry 写入遥测时序存储
+func (h *EventHandler) recordTelemetry(sample *services.TelemetrySample) {
+	if h.telemetryService == nil {
+		return
+	}
+
+	if err := h.telemetryService.RecordTelemetry(context.Background(), sample); err != nil {
+		h.logger.WithFields(map[string]interface{}{
+			"drone_id": sample.DroneID,
+			"error":    err.Error(),
+		}).Warn("Failed to record drone telemetry")
+	}
 }
 
 // handleStatusChangeEvent 处理状态变化事件
@@ -212,6 +376,41 @@ func (h *EventHandler) handleStatusChangeEvent(event *kafka.Event) {
 	// 1. 状态变化通知
 	// 2. 统计数据更新
 	// 3. 自动化响应
+
+	droneID, ok := eventDroneID(event)
+	if !ok {
+		return
+	}
+
+	newStatus, ok := event.Data["new_status"].(string)
+	if !ok || newStatus == "" {
+		return
+	}
+
+	// 离线无人机移出实时位置索引
+	if h.geoIndex != nil {
+		if err := h.geoIndex.UpdateStatus(context.Background(), droneID, models.DroneStatus(newStatus)); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to update drone geo index")
+		}
+	}
+
+	// 离线无人机丢弃缓存的最近遥测，恢复上报时从数据库重新加载
+	if h.telemetryService != nil && models.DroneStatus(newStatus) == models.DroneStatusOffline {
+		h.telemetryService.ForgetDrone(droneID)
+	}
+
+	// 无人机离线或进入维护等状态时，改派其自动分配且未开始的任务
+	if h.assignmentService != nil && !(&models.Drone{Status: models.DroneStatus(newStatus)}).IsOnline() {
+		if err := h.assignmentService.ReassignDroneTasks(context.Background(), droneID); err != nil {
+			h.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Warn("Failed to reassign drone tasks")
+		}
+	}
 }
 
 // handleTaskFailedEvent 处理任务失败事件
@@ -220,10 +419,21 @@ func (h *EventHandler) handleTaskFailedEvent(event *kafka.Event) {
 		"event_data": event.Data,
 	})
 
+	// 按任务类型的重试策略安排重试，已由任务服务处理过的任务会被忽略
+	if h.retryService != nil {
+		if taskID, ok := eventTaskID(event); ok {
+			if err := h.retryService.HandleTaskFinished(context.Background(), taskID); err != nil {
+				h.logger.Warn("Failed to handle failed task retry", map[string]interface{}{
+					"task_id": taskID,
+					"error":   err.Error(),
+				})
+			}
+		}
+	}
+
 	// 可以添加额外的处理逻辑：
 	// 1. 发送失败通知
-	// 2. 自动重试逻辑
-	// 3. 故障分析
+	// 2. 故障分析
 }
 
 // handleTaskCompletedEvent 处理任务完成事件
@@ -237,3 +447,55 @@ func (h *EventHandler) handleTaskCompletedEvent(event *kafka.Event) {
 	// 2. 结果统计
 	// 3. 后续任务调度
 }
+
+// eventDroneID 从事件数据中解析无人机ID
+func eventDroneID(event *kafka.Event) (uint, bool) {
+	if event.Data == nil {
+		return 0, false
+	}
+
+	droneID, ok := event.Data["drone_id"].(float64)
+	if !ok || droneID <= 0 {
+		return 0, false
+	}
+
+	return uint(droneID), true
+}
+
+// eventTaskID 从事件数据中解析任务ID
+func eventTaskID(event *kafka.Event) (uint, bool) {
+	if event.Data == nil {
+		return 0, false
+	}
+
+	taskID, ok := event.Data["task_id"].(float64)
+	if !ok || taskID <= 0 {
+		return 0, false
+	}
+
+	return uint(taskID), true
+}
+
+// eventPosition 从事件数据中解析位置信息
+func eventPosition(event *kafka.Event) (*models.Position, bool) {
+	raw, ok := event.Data["position"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	latitude, latOK := raw["latitude"].(float64)
+	longitude, lonOK := raw["longitude"].(float64)
+	if !latOK || !lonOK {
+		return nil, false
+	}
+
+	altitude, _ := raw["altitude"].(float64)
+	heading, _ := raw["heading"].(float64)
+
+	return &models.Position{
+		Latitude:  latitude,
+		Longitude: longitude,
+		Altitude:  altitude,
+		Heading:   heading,
+	}, true
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"drone-control-system/internal/mvc/services"
+	"drone-control-system/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceAuthMiddleware 设备认证中间件，使用设备凭证而不是用户JWT
+type DeviceAuthMiddleware struct {
+	deviceService     services.DeviceService
+	fingerprintHeader string // TLS终止代理传递证书指纹的请求头
+	logger            *logger.Logger
+}
+
+// NewDeviceAuthMiddleware 创建设备认证中间件
+func NewDeviceAuthMiddleware(deviceService services.DeviceService, fingerprintHeader string, logger *logger.Logger) *DeviceAuthMiddleware {
+	return &DeviceAuthMiddleware{
+		deviceService:     deviceService,
+		fingerprintHeader: fingerprintHeader,
+		logger:            logger,
+	}
+}
+
+// RequireDevice 需要设备认证的中间件
+func (dm *DeviceAuthMiddleware) RequireDevice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := dm.extractKey(c)
+		fingerprint := ""
+		if apiKey == "" {
+			fingerprint = dm.extractFingerprint(c)
+		}
+		if apiKey == "" && fingerprint == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": "device authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		credential, err := dm.deviceService.Authenticate(c.Request.Context(), apiKey, fingerprint)
+		if err != nil {
+			if err != services.ErrInvalidDeviceCredential {
+				dm.logger.WithError(err).Error("Device authentication failed")
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"code":    http.StatusInternalServerError,
+					"message": "failed to authenticate device",
+				})
+				c.Abort()
+				return
+			}
+
+			dm.logger.WithFields(map[string]interface{}{
+				"client_ip": c.ClientIP(),
+				"mtls":      fingerprint != "",
+			}).Warn("Device credential rejected")
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": "invalid or expired device credential",
+			})
+			c.Abort()
+			return
+		}
+
+		// 将设备信息存储到上下文
+		c.Set("device_drone_id", credential.DroneID)
+		c.Set("device_credential_id", credential.ID)
+
+		c.Next()
+	}
+}
+
+// RequireOwnDrone 限制设备只能访问自己的无人机，param 为路径中的无人机ID参数名
+func (dm *DeviceAuthMiddleware) RequireOwnDrone(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		droneID, err := strconv.ParseUint(c.Param(param), 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    http.StatusBadRequest,
+				"message": "invalid drone ID",
+			})
+			c.Abort()
+			return
+		}
+
+		if deviceDroneID, ok := c.Get("device_drone_id"); !ok || deviceDroneID.(uint) != uint(droneID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    http.StatusForbidden,
+				"message": "device is not allowed to access this drone",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// extractKey 从请求中提取设备API密钥
+func (dm *DeviceAuthMiddleware) extractKey(c *gin.Context) string {
+	if key := c.GetHeader("X-Device-Key"); key != "" {
+		return key
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	if authHeader != "" {
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) == 2 && parts[0] == "Device" {
+			return parts[1]
+		}
+	}
+	return ""
+}
+
+// extractFingerprint 获取客户端证书指纹，直连TLS优先于代理请求头
+func (dm *DeviceAuthMiddleware) extractFingerprint(c *gin.Context) string {
+	if tls := c.Request.TLS; tls != nil && len(tls.PeerCertificates) > 0 {
+		return services.CertificateFingerprint(tls.PeerCertificates[0].Raw)
+	}
+	if dm.fingerprintHeader != "" {
+		return c.GetHeader(dm.fingerprintHeader)
+	}
+	return ""
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ApprovalRule 任务审批规则
+// 已设置的条件全部满足时命中（同一条件内的多个取值满足其一即可），命中任一启用规则的任务需要管理员审批后才能启动
+type ApprovalRule struct {
+	BaseModel
+	Name        string `json:"name" gorm:"not null;size:100"`
+	Description string `json:"description" gorm:"type:text"`
+	Enabled     bool   `json:"enabled" gorm:"index"`
+
+	Priorities TaskPriorityList `json:"priorities" gorm:"type:text"` // 任务优先级
+	TaskTypes  TaskTypeList     `json:"task_types" gorm:"type:text"` // 任务类型
+
+	// 航线穿越的区域：指定围栏或某类围栏，只考虑计划执行时生效的围栏
+	GeofenceIDs   IDList           `json:"geofence_ids" gorm:"type:text"`
+	GeofenceTypes GeofenceTypeList `json:"geofence_types" gorm:"type:text"`
+
+	// 飞行时间落在这些每周重复的时间窗口内（如夜间 19:00-06:00）
+	Windows  GeofenceWindowList `json:"windows" gorm:"type:text"`
+	Timezone string             `json:"timezone" gorm:"size:64"`
+
+	CreatedBy uint `json:"created_by"`
+}
+
+// TableName 指定表名
+func (ApprovalRule) TableName() string {
+	return "approval_rules"
+}
+
+// HasZone 是否设置了区域条件
+func (r *ApprovalRule) HasZone() bool {
+	return len(r.GeofenceIDs) > 0 || len(r.GeofenceTypes) > 0
+}
+
+// Validate 检查规则条件
+func (r *ApprovalRule) Validate() error {
+	if len(r.Priorities) == 0 && len(r.TaskTypes) == 0 && !r.HasZone() && len(r.Windows) == 0 {
+		return fmt.Errorf("at least one condition is required")
+	}
+	for _, p := range r.Priorities {
+		switch p {
+		case TaskPriorityLow, TaskPriorityNormal, TaskPriorityHigh, TaskPriorityUrgent:
+		default:
+			return fmt.Errorf("unknown priority: %s", p)
+		}
+	}
+	for _, t := range r.TaskTypes {
+		switch t {
+		case TaskTypeInspection, TaskTypeDelivery, TaskTypeMapping, TaskTypePatrol, TaskTypeEmergency:
+		default:
+			return fmt.Errorf("unknown task type: %s", t)
+		}
+	}
+	for _, t := range r.GeofenceTypes {
+		if !t.IsValid() {
+			return fmt.Errorf("unknown geofence type: %s", t)
+		}
+	}
+	if _, err := r.Location(); err != nil {
+		return fmt.Errorf("unknown timezone: %s", r.Timezone)
+	}
+	for i, w := range r.Windows {
+		if err := w.validate(); err != nil {
+			return fmt.Errorf("window %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Location 时间窗口使用的时区，未设置时为UTC
+func (r *ApprovalRule) Location() (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(r.Timezone)
+}
+
+// WindowAt 返回时间所在的时间窗口，未设置窗口或不在任何窗口内时返回 false
+func (r *ApprovalRule) WindowAt(t time.Time) (GeofenceWindow, bool) {
+	loc, err := r.Location()
+	if err != nil {
+		return GeofenceWindow{}, false
+	}
+	local := t.In(loc)
+	for _, w := range r.Windows {
+		if w.contains(local) {
+			return w, true
+		}
+	}
+	return GeofenceWindow{}, false
+}
+
+// ApprovalStatus 任务审批状态
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
+// ApprovalAction 审批记录动作
+type ApprovalAction string
+
+const (
+	ApprovalRequested ApprovalAction = "requested" // 命中规则，提交审批
+	ApprovalGranted   ApprovalAction = "approved"
+	ApprovalDenied    ApprovalAction = "rejected"
+	ApprovalWithdrawn ApprovalAction = "withdrawn" // 修改后不再命中规则，撤回审批
+)
+
+// TaskApproval 任务审批状态和审批记录
+type TaskApproval struct {
+	Status      ApprovalStatus   `json:"status"`            // 为空表示不需要审批
+	Reasons     []ApprovalReason `json:"reasons,omitempty"` // 当前审批命中的规则
+	RequestedBy uint             `json:"requested_by,omitempty"`
+	RequestedAt *time.Time       `json:"requested_at,omitempty"`
+	DecidedBy   uint             `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time       `json:"decided_at,omitempty"`
+	Comment     string           `json:"comment,omitempty"`
+	Digest      string           `json:"digest,omitempty"`  // 审批时计划、排期、类型和优先级的摘要，变更后需要重新审批
+	History     []ApprovalEvent  `json:"history,omitempty"` // 提交、审批和撤回记录，按时间顺序
+}
+
+// ApprovalReason 命中的审批规则及满足的条件
+type ApprovalReason struct {
+	RuleID     uint     `json:"rule_id"`
+	RuleName   string   `json:"rule_name"`
+	Conditions []string `json:"conditions"`
+}
+
+// SameApprovalReasons 两次评估是否命中相同的规则和条件
+func SameApprovalReasons(a, b []ApprovalReason) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].RuleID != b[i].RuleID || len(a[i].Conditions) != len(b[i].Conditions) {
+			return false
+		}
+		for j := range a[i].Conditions {
+			if a[i].Conditions[j] != b[i].Conditions[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ApprovalDigest 计算审批所针对内容（计划、排期、类型和优先级）的摘要
+func ApprovalDigest(task *Task) string {
+	subject := struct {
+		Type        TaskType     `json:"type"`
+		Priority    TaskPriority `json:"priority"`
+		ScheduledAt int64        `json:"scheduled_at"`
+		Plan        TaskPlan     `json:"plan"`
+	}{Type: task.Type, Priority: task.Priority, Plan: task.Plan}
+	if task.ScheduledAt != nil {
+		subject.ScheduledAt = task.ScheduledAt.Unix()
+	}
+
+	data, err := json.Marshal(subject)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ApprovalEvent 审批记录
+type ApprovalEvent struct {
+	Action  ApprovalAction   `json:"action"`
+	UserID  uint             `json:"user_id,omitempty"`
+	Comment string           `json:"comment,omitempty"`
+	Reasons []ApprovalReason `json:"reasons,omitempty"` // 提交审批时命中的规则
+	At      time.Time        `json:"at"`
+}
+
+// IsZero 是否没有审批信息
+func (a TaskApproval) IsZero() bool {
+	return a.Status == "" && len(a.History) == 0
+}
+
+// Value 实现 driver.Valuer
+func (a TaskApproval) Value() (driver.Value, error) {
+	return marshalTextColumn(a, a.IsZero())
+}
+
+// Scan 实现 sql.Scanner
+func (a *TaskApproval) Scan(value interface{}) error {
+	*a = TaskApproval{}
+	return unmarshalTextColumn(value, a)
+}
+
+// TaskPriorityList 任务优先级列表，以JSON存储在text列中
+type TaskPriorityList []TaskPriority
+
+// Value 实现 driver.Valuer
+func (l TaskPriorityList) Value() (driver.Value, error) {
+	return marshalTextColumn([]TaskPriority(l), len(l) == 0)
+}
+
+// Scan 实现 sql.Scanner
+func (l *TaskPriorityList) Scan(value interface{}) error {
+	*l = nil
+	return unmarshalTextColumn(value, l)
+}
+
+// TaskTypeList 任务类型列表，以JSON存储在text列中
+type TaskTypeList []TaskType
+
+// Value 实现 driver.Valuer
+func (l TaskTypeList) Value() (driver.Value, error) {
+	return marshalTextColumn([]TaskType(l), len(l) == 0)
+}
+
+// Scan 实现 sql.Scanner
+func (l *TaskTypeList) Scan(value interface{}) error {
+	*l = nil
+	return unmarshalTextColumn(value, l)
+}
+
+// GeofenceTypeList 围栏类型列表，以JSON存储在text列中
+type GeofenceTypeList []GeofenceType
+
+// Value 实现 driver.Valuer
+func (l GeofenceTypeList) Value() (driver.Value, error) {
+	return marshalTextColumn([]GeofenceType(l), len(l) == 0)
+}
+
+// Scan 实现 sql.Scanner
+func (l *GeofenceTypeList) Scan(value interface{}) error {
+	*l = nil
+	return unmarshalTextColumn(value, l)
+}
+
+// IDList ID列表，以JSON存储在text列中
+type IDList []uint
+
+// Value 实现 driver.Valuer
+func (l IDList) Value() (driver.Value, error) {
+	return marshalTextColumn([]uint(l), len(l) == 0)
+}
+
+// Scan 实现 sql.Scanner
+func (l *IDList) Scan(value interface{}) error {
+	*l = nil
+	return unmarshalTextColumn(value, l)
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql/driver"
+	"strings"
+	"time"
+)
+
+// TaskArtifact 任务产出的文件（照片、视频、日志等），内容保存在对象存储中
+type TaskArtifact struct {
+	BaseModel
+	TaskID      uint             `json:"task_id" gorm:"not null;index"`
+	Kind        ArtifactKind     `json:"kind" gorm:"size:20;index"`
+	FileName    string           `json:"file_name" gorm:"size:255"`
+	ContentType string           `json:"content_type" gorm:"size:100"`
+	Size        int64            `json:"size"`
+	Checksum    string           `json:"checksum" gorm:"size:64"` // SHA-256（十六进制）
+	StorageKey  string           `json:"-" gorm:"size:255;not null"`
+	Metadata    ArtifactMetadata `json:"metadata" gorm:"type:text"`
+	UploadedBy  uint             `json:"uploaded_by"`
+	ExpiresAt   *time.Time       `json:"expires_at" gorm:"index"` // 为空表示保留到任务删除
+}
+
+// ArtifactKind 文件类别
+type ArtifactKind string
+
+const (
+	ArtifactPhoto ArtifactKind = "photo"
+	ArtifactVideo ArtifactKind = "video"
+	ArtifactFile  ArtifactKind = "file"
+)
+
+// IsValid 检查文件类别是否合法
+func (k ArtifactKind) IsValid() bool {
+	switch k {
+	case ArtifactPhoto, ArtifactVideo, ArtifactFile:
+		return true
+	}
+	return false
+}
+
+// ArtifactKindFor 按内容类型推断文件类别
+func ArtifactKindFor(contentType string) ArtifactKind {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return ArtifactPhoto
+	case strings.HasPrefix(contentType, "video/"):
+		return ArtifactVideo
+	}
+	return ArtifactFile
+}
+
+// ArtifactMetadata 从文件中提取的拍摄时间、地理标签和设备信息
+type ArtifactMetadata struct {
+	CapturedAt *time.Time `json:"captured_at,omitempty"`
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+	Altitude   *float64   `json:"altitude,omitempty"`
+	Make       string     `json:"make,omitempty"`
+	Model      string     `json:"model,omitempty"`
+}
+
+// IsZero 是否没有任何元数据
+func (m ArtifactMetadata) IsZero() bool {
+	return m.CapturedAt == nil && m.Latitude == nil && m.Longitude == nil &&
+		m.Altitude == nil && m.Make == "" && m.Model == ""
+}
+
+// Value 实现 driver.Valuer
+func (m ArtifactMetadata) Value() (driver.Value, error) {
+	return marshalTextColumn(m, m.IsZero())
+}
+
+// Scan 实现 sql.Scanner
+func (m *ArtifactMetadata) Scan(value interface{}) error {
+	*m = ArtifactMetadata{}
+	return unmarshalTextColumn(value, m)
+}
+
+// ArtifactUpload 进行中的断点续传上传，完成后转为 TaskArtifact 并删除
+type ArtifactUpload struct {
+	BaseModel
+	TaskID      uint         `json:"task_id" gorm:"not null;index"`
+	Kind        ArtifactKind `json:"kind" gorm:"size:20"` // 为空时完成后按内容类型推断
+	FileName    string       `json:"file_name" gorm:"size:255"`
+	ContentType string       `json:"content_type" gorm:"size:100"` // 为空时完成后按内容检测
+	Size        int64        `json:"size"`                         // 文件总大小
+	Offset      int64        `json:"offset"`                       // 已接收的字节数
+	Checksum    string       `json:"checksum" gorm:"size:64"`      // 客户端声明的SHA-256，完成时校验
+	StorageKey  string       `json:"-" gorm:"size:255;not null"`
+	CreatedBy   uint         `json:"created_by"`
+	ExpiresAt   time.Time    `json:"expires_at" gorm:"index"` // 超过该时间未完成的上传会被清理
+}
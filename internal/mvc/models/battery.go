@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+)
+
+// BatteryPack 电池组
+type BatteryPack struct {
+	BaseModel
+	SerialNo         string             `json:"serial_no" gorm:"unique;not null;size:50"`
+	Chemistry        BatteryChemistry   `json:"chemistry" gorm:"not null;size:20"`
+	CapacityMah      int                `json:"capacity_mah"`                       // 标称容量（mAh）
+	RatedCycles      int                `json:"rated_cycles" gorm:"default:300"`    // 额定循环寿命，达到时健康度约为80%
+	CycleCount       int                `json:"cycle_count"`                        // 等效完整充电循环数
+	ChargeThroughput float64            `json:"charge_throughput"`                  // 累计充入电量（百分比之和）
+	StateOfHealth    float64            `json:"state_of_health" gorm:"default:100"` // 健康度（%）
+	Status           BatteryPackStatus  `json:"status" gorm:"default:available;size:20;index"`
+	DroneID          *uint              `json:"drone_id" gorm:"index"` // 当前安装的无人机
+	InstalledAt      *time.Time         `json:"installed_at"`
+	LastLevel        *int               `json:"last_level"` // 最近一次电量读数
+	LastReadingAt    *time.Time         `json:"last_reading_at"`
+	HealthAlertLevel BatteryHealthLevel `json:"health_alert_level" gorm:"size:20"` // 已告警的健康等级，避免重复告警
+	Notes            string             `json:"notes" gorm:"type:text"`
+}
+
+// BatteryInstallation 电池组安装记录
+type BatteryInstallation struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	PackID          uint       `json:"pack_id" gorm:"not null;index"`
+	DroneID         uint       `json:"drone_id" gorm:"not null;index"`
+	InstalledAt     time.Time  `json:"installed_at"`
+	InstalledBy     uint       `json:"installed_by"`
+	RemovedAt       *time.Time `json:"removed_at"`
+	RemovedBy       *uint      `json:"removed_by"`
+	RemoveReason    string     `json:"remove_reason" gorm:"size:255"`
+	CyclesAtInstall int        `json:"cycles_at_install"`
+	CyclesAtRemoval *int       `json:"cycles_at_removal"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// BatteryChemistry 电池化学体系
+type BatteryChemistry string
+
+const (
+	BatteryChemistryLiPo   BatteryChemistry = "lipo"
+	BatteryChemistryLiHV   BatteryChemistry = "lihv"
+	BatteryChemistryLiIon  BatteryChemistry = "liion"
+	BatteryChemistryLiFePO BatteryChemistry = "lifepo4"
+	BatteryChemistrySolid  BatteryChemistry = "solid_state"
+)
+
+// BatteryPackStatus 电池组状态
+type BatteryPackStatus string
+
+const (
+	BatteryPackAvailable BatteryPackStatus = "available"
+	BatteryPackInstalled BatteryPackStatus = "installed"
+	BatteryPackRetired   BatteryPackStatus = "retired"
+)
+
+// BatteryHealthLevel 电池健康等级
+type BatteryHealthLevel string
+
+const (
+	BatteryHealthGood     BatteryHealthLevel = ""
+	BatteryHealthLow      BatteryHealthLevel = "low"
+	BatteryHealthCritical BatteryHealthLevel = "critical"
+)
+
+// TableName 指定表名
+func (BatteryPack) TableName() string {
+	return "battery_packs"
+}
+
+// TableName 指定表名
+func (BatteryInstallation) TableName() string {
+	return "battery_installations"
+}
+
+// IsValid 检查化学体系是否合法
+func (c BatteryChemistry) IsValid() bool {
+	switch c {
+	case BatteryChemistryLiPo, BatteryChemistryLiHV, BatteryChemistryLiIon, BatteryChemistryLiFePO, BatteryChemistrySolid:
+		return true
+	default:
+		return false
+	}
+}
+
+// EstimatedHealth 按循环次数估算健康度，额定循环寿命时约为80%
+func (p *BatteryPack) EstimatedHealth() float64 {
+	if p.RatedCycles <= 0 {
+		return 100
+	}
+
+	health := 100 - 20*float64(p.CycleCount)/float64(p.RatedCycles)
+	if health < 0 {
+		return 0
+	}
+	return health
+}
@@ -0,0 +1,250 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SensorType 传感器类型
+type SensorType string
+
+const (
+	SensorRGB           SensorType = "rgb"
+	SensorThermal       SensorType = "thermal"
+	SensorLiDAR         SensorType = "lidar"
+	SensorMultispectral SensorType = "multispectral"
+	SensorZoom          SensorType = "zoom"
+)
+
+// IsValid 检查传感器类型是否合法
+func (s SensorType) IsValid() bool {
+	switch s {
+	case SensorRGB, SensorThermal, SensorLiDAR, SensorMultispectral, SensorZoom:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsCamera 是否为可拍照的相机类传感器（激光雷达不能拍照）
+func (s SensorType) IsCamera() bool {
+	switch s {
+	case SensorRGB, SensorThermal, SensorMultispectral, SensorZoom:
+		return true
+	default:
+		return false
+	}
+}
+
+// DroneCapabilities 无人机能力
+type DroneCapabilities struct {
+	Sensors         []SensorType `json:"sensors"`
+	PayloadCapacity float64      `json:"payload_capacity"` // 最大载重（kg），0表示未登记
+	MaxRange        float64      `json:"max_range"`        // 最大航程（m）
+	MaxFlightTime   int          `json:"max_flight_time"`  // 最大续航（分钟）
+	MaxWindSpeed    float64      `json:"max_wind_speed"`   // 最大抗风（m/s）
+	NightFlight     bool         `json:"night_flight"`     // 是否支持夜间飞行
+	MaxAltitude     float64      `json:"max_altitude"`     // 机型最大飞行高度（m），0表示未登记
+	MaxSpeed        float64      `json:"max_speed"`        // 机型最大速度（m/s），0表示未登记
+	CruiseSpeed     float64      `json:"cruise_speed"`     // 巡航速度（m/s），0表示使用默认值
+	ClimbSpeed      float64      `json:"climb_speed"`      // 爬升速度（m/s），0表示使用默认值
+	DescentSpeed    float64      `json:"descent_speed"`    // 下降速度（m/s），0表示使用默认值
+}
+
+// CapabilityRequirements 任务对无人机能力的要求，零值字段表示不限制
+type CapabilityRequirements struct {
+	Sensors            []SensorType `json:"sensors,omitempty"`
+	MinPayloadCapacity float64      `json:"min_payload_capacity,omitempty"`
+	MinRange           float64      `json:"min_range,omitempty"`
+	MinFlightTime      int          `json:"min_flight_time,omitempty"`
+	MinWindSpeed       float64      `json:"min_wind_speed,omitempty"`
+	NightFlight        bool         `json:"night_flight,omitempty"`
+}
+
+// Validate 校验能力数据
+func (c *DroneCapabilities) Validate() error {
+	seen := make(map[SensorType]bool, len(c.Sensors))
+	for _, sensor := range c.Sensors {
+		if !sensor.IsValid() {
+			return fmt.Errorf("unknown sensor type: %s", sensor)
+		}
+		if seen[sensor] {
+			return fmt.Errorf("duplicate sensor type: %s", sensor)
+		}
+		seen[sensor] = true
+	}
+
+	switch {
+	case c.PayloadCapacity < 0:
+		return fmt.Errorf("payload_capacity must not be negative")
+	case c.MaxRange < 0:
+		return fmt.Errorf("max_range must not be negative")
+	case c.MaxFlightTime < 0:
+		return fmt.Errorf("max_flight_time must not be negative")
+	case c.MaxWindSpeed < 0:
+		return fmt.Errorf("max_wind_speed must not be negative")
+	case c.MaxAltitude < 0:
+		return fmt.Errorf("max_altitude must not be negative")
+	case c.MaxSpeed < 0:
+		return fmt.Errorf("max_speed must not be negative")
+	case c.CruiseSpeed < 0 || c.ClimbSpeed < 0 || c.DescentSpeed < 0:
+		return fmt.Errorf("cruise, climb and descent speeds must not be negative")
+	}
+
+	return nil
+}
+
+// HasSensor 是否配备指定传感器
+func (c *DroneCapabilities) HasSensor(sensor SensorType) bool {
+	for _, s := range c.Sensors {
+		if s == sensor {
+			return true
+		}
+	}
+	return false
+}
+
+// HasCamera 是否配备任意相机类传感器
+func (c *DroneCapabilities) HasCamera() bool {
+	for _, s := range c.Sensors {
+		if s.IsCamera() {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing 返回不满足的能力要求，全部满足时返回空列表
+func (c *DroneCapabilities) Missing(req *CapabilityRequirements) []string {
+	if req == nil {
+		return nil
+	}
+
+	var missing []string
+	for _, sensor := range req.Sensors {
+		if !c.HasSensor(sensor) {
+			missing = append(missing, fmt.Sprintf("sensor %s", sensor))
+		}
+	}
+	if req.MinPayloadCapacity > 0 && c.PayloadCapacity < req.MinPayloadCapacity {
+		missing = append(missing, fmt.Sprintf("payload capacity %.2fkg < %.2fkg", c.PayloadCapacity, req.MinPayloadCapacity))
+	}
+	if req.MinRange > 0 && c.MaxRange < req.MinRange {
+		missing = append(missing, fmt.Sprintf("range %.0fm < %.0fm", c.MaxRange, req.MinRange))
+	}
+	if req.MinFlightTime > 0 && c.MaxFlightTime < req.MinFlightTime {
+		missing = append(missing, fmt.Sprintf("flight time %dmin < %dmin", c.MaxFlightTime, req.MinFlightTime))
+	}
+	if req.MinWindSpeed > 0 && c.MaxWindSpeed < req.MinWindSpeed {
+		missing = append(missing, fmt.Sprintf("wind tolerance %.1fm/s < %.1fm/s", c.MaxWindSpeed, req.MinWindSpeed))
+	}
+	if req.NightFlight && !c.NightFlight {
+		missing = append(missing, "night flight")
+	}
+
+	return missing
+}
+
+// Satisfies 是否满足能力要求
+func (c *DroneCapabilities) Satisfies(req *CapabilityRequirements) bool {
+	return len(c.Missing(req)) == 0
+}
+
+// Validate 校验能力要求
+func (r *CapabilityRequirements) Validate() error {
+	for _, sensor := range r.Sensors {
+		if !sensor.IsValid() {
+			return fmt.Errorf("unknown sensor type: %s", sensor)
+		}
+	}
+	if r.MinPayloadCapacity < 0 || r.MinRange < 0 || r.MinFlightTime < 0 || r.MinWindSpeed < 0 {
+		return fmt.Errorf("capability requirements must not be negative")
+	}
+	return nil
+}
+
+// IsEmpty 是否没有任何要求
+func (r *CapabilityRequirements) IsEmpty() bool {
+	return r == nil || (len(r.Sensors) == 0 && r.MinPayloadCapacity == 0 && r.MinRange == 0 &&
+		r.MinFlightTime == 0 && r.MinWindSpeed == 0 && !r.NightFlight)
+}
+
+// ParseCapabilities 解析无人机能力
+// 兼容旧格式（字符串数组），其中可识别的传感器名和 night_flight 会被转换
+func (d *Drone) ParseCapabilities() (*DroneCapabilities, error) {
+	caps := &DroneCapabilities{Sensors: []SensorType{}}
+	if d.Capabilities == "" {
+		return caps, nil
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(d.Capabilities), "[") {
+		var legacy []string
+		if err := json.Unmarshal([]byte(d.Capabilities), &legacy); err != nil {
+			return nil, err
+		}
+		for _, item := range legacy {
+			name := strings.ToLower(strings.TrimSpace(item))
+			switch {
+			case SensorType(name).IsValid():
+				caps.Sensors = append(caps.Sensors, SensorType(name))
+			case name == "camera":
+				caps.Sensors = append(caps.Sensors, SensorRGB)
+			case name == "night_flight" || name == "night":
+				caps.NightFlight = true
+			}
+		}
+		return caps, nil
+	}
+
+	if err := json.Unmarshal([]byte(d.Capabilities), caps); err != nil {
+		return nil, err
+	}
+	if caps.Sensors == nil {
+		caps.Sensors = []SensorType{}
+	}
+	return caps, nil
+}
+
+// SetCapabilities 序列化并保存无人机能力
+func (d *Drone) SetCapabilities(caps *DroneCapabilities) error {
+	if caps == nil {
+		d.Capabilities = ""
+		return nil
+	}
+
+	data, err := json.Marshal(caps)
+	if err != nil {
+		return err
+	}
+	d.Capabilities = string(data)
+	return nil
+}
+
+// ParseRequiredCapabilities 解析任务的能力要求，未设置时返回nil
+func (t *Task) ParseRequiredCapabilities() (*CapabilityRequirements, error) {
+	if t.RequiredCapabilities == "" {
+		return nil, nil
+	}
+
+	var req CapabilityRequirements
+	if err := json.Unmarshal([]byte(t.RequiredCapabilities), &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// SetRequiredCapabilities 序列化并保存任务的能力要求
+func (t *Task) SetRequiredCapabilities(req *CapabilityRequirements) error {
+	if req.IsEmpty() {
+		t.RequiredCapabilities = ""
+		return nil
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	t.RequiredCapabilities = string(data)
+	return nil
+}
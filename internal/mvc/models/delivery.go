@@ -0,0 +1,133 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// HandoffMethod 交付方式
+type HandoffMethod string
+
+const (
+	HandoffLanding HandoffMethod = "landing" // 降落后卸货
+	HandoffWinch   HandoffMethod = "winch"   // 悬停并用绞盘放下
+	HandoffDrop    HandoffMethod = "drop"    // 低空悬停投放
+)
+
+// IsValid 检查交付方式是否合法
+func (m HandoffMethod) IsValid() bool {
+	switch m {
+	case HandoffLanding, HandoffWinch, HandoffDrop:
+		return true
+	}
+	return false
+}
+
+// DeliveryStatus 配送状态
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"   // 尚未取货
+	DeliveryPickedUp  DeliveryStatus = "picked_up" // 已在取货点装载
+	DeliveryEnRoute   DeliveryStatus = "en_route"  // 飞往投递点
+	DeliveryDelivered DeliveryStatus = "delivered" // 已交付，附交付证明
+	DeliveryReturned  DeliveryStatus = "returned"  // 未能交付，载荷已带回
+)
+
+// IsFinal 是否为终止状态
+func (s DeliveryStatus) IsFinal() bool {
+	return s == DeliveryDelivered || s == DeliveryReturned
+}
+
+// CanTransitionTo 检查配送状态流转是否合法
+func (s DeliveryStatus) CanTransitionTo(next DeliveryStatus) bool {
+	switch s {
+	case DeliveryPending:
+		return next == DeliveryPickedUp
+	case DeliveryPickedUp:
+		return next == DeliveryEnRoute || next == DeliveryDelivered || next == DeliveryReturned
+	case DeliveryEnRoute:
+		return next == DeliveryDelivered || next == DeliveryReturned
+	}
+	return false
+}
+
+// DeliveryLocation 取货点或投递点
+type DeliveryLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude,omitempty"` // 相对起飞点高度（米），降落交付时为地面高度
+	Name      string  `json:"name,omitempty"`
+	Address   string  `json:"address,omitempty"`
+}
+
+// DeliveryRecipient 收件人联系方式
+type DeliveryRecipient struct {
+	Name  string `json:"name"`
+	Phone string `json:"phone,omitempty"`
+	Email string `json:"email,omitempty"`
+	Note  string `json:"note,omitempty"` // 交付说明，如放置位置
+}
+
+// DeliveryMilestone 配送状态节点
+type DeliveryMilestone struct {
+	Status    DeliveryStatus `json:"status"`
+	At        time.Time      `json:"at"`
+	Latitude  float64        `json:"latitude"`
+	Longitude float64        `json:"longitude"`
+	UserID    uint           `json:"user_id,omitempty"` // 记录人，由设备上报时为0
+	Note      string         `json:"note,omitempty"`
+}
+
+// TaskDelivery 配送任务的取送信息和状态，以JSON存储在text列中
+type TaskDelivery struct {
+	Pickup     DeliveryLocation    `json:"pickup"`
+	Dropoff    DeliveryLocation    `json:"dropoff"`
+	Recipient  DeliveryRecipient   `json:"recipient"`
+	Handoff    HandoffMethod       `json:"handoff"`
+	Status     DeliveryStatus      `json:"status"`
+	Milestones []DeliveryMilestone `json:"milestones,omitempty"`
+}
+
+// IsZero 是否未配置配送信息
+func (d TaskDelivery) IsZero() bool {
+	return d.Handoff == "" && d.Status == ""
+}
+
+// Value 实现 driver.Valuer
+func (d TaskDelivery) Value() (driver.Value, error) {
+	return marshalTextColumn(d, d.IsZero())
+}
+
+// Scan 实现 sql.Scanner
+func (d *TaskDelivery) Scan(value interface{}) error {
+	*d = TaskDelivery{}
+	return unmarshalTextColumn(value, d)
+}
+
+// DeliveryProof 交付证明：照片、交付时的定位和时间
+type DeliveryProof struct {
+	PhotoRef   string    `json:"photo_ref"` // 照片的存储引用
+	Latitude   float64   `json:"latitude"`
+	Longitude  float64   `json:"longitude"`
+	Altitude   float64   `json:"altitude"`
+	Accuracy   float64   `json:"accuracy,omitempty"` // 定位精度（米）
+	CapturedAt time.Time `json:"captured_at"`
+	Distance   float64   `json:"distance"` // 与投递点的水平距离（米）
+}
+
+// IsZero 是否没有交付证明
+func (p DeliveryProof) IsZero() bool {
+	return p.PhotoRef == "" && p.CapturedAt.IsZero()
+}
+
+// Value 实现 driver.Valuer
+func (p DeliveryProof) Value() (driver.Value, error) {
+	return marshalTextColumn(p, p.IsZero())
+}
+
+// Scan 实现 sql.Scanner
+func (p *DeliveryProof) Scan(value interface{}) error {
+	*p = DeliveryProof{}
+	return unmarshalTextColumn(value, p)
+}
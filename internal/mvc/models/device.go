@@ -0,0 +1,99 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeviceCredential 无人机设备凭证，用于设备直接上报遥测和拉取指令
+type DeviceCredential struct {
+	BaseModel
+	DroneID     uint                   `json:"drone_id" gorm:"not null;index"`
+	Type        DeviceCredentialType   `json:"type" gorm:"not null;size:20"`
+	KeyPrefix   string                 `json:"key_prefix,omitempty" gorm:"size:16;index"`  // API密钥前缀，用于查找和展示
+	KeyHash     string                 `json:"-" gorm:"size:64"`                           // API密钥SHA-256摘要
+	Fingerprint string                 `json:"fingerprint,omitempty" gorm:"size:64;index"` // 客户端证书SHA-256指纹
+	Status      DeviceCredentialStatus `json:"status" gorm:"default:active;size:20;index"`
+	Description string                 `json:"description" gorm:"size:255"`
+	ExpiresAt   *time.Time             `json:"expires_at"`
+	LastUsedAt  *time.Time             `json:"last_used_at"`
+	CreatedBy   uint                   `json:"created_by"`
+	RotatedFrom *uint                  `json:"rotated_from"` // 轮换前的凭证
+	RevokedAt   *time.Time             `json:"revoked_at"`
+	RevokedBy   *uint                  `json:"revoked_by"`
+}
+
+// DeviceCredentialType 设备凭证类型
+type DeviceCredentialType string
+
+const (
+	DeviceCredentialAPIKey DeviceCredentialType = "api_key"
+	DeviceCredentialMTLS   DeviceCredentialType = "mtls"
+)
+
+// DeviceCredentialStatus 设备凭证状态
+type DeviceCredentialStatus string
+
+const (
+	DeviceCredentialActive  DeviceCredentialStatus = "active"
+	DeviceCredentialRevoked DeviceCredentialStatus = "revoked"
+)
+
+// DroneCommandRecord 已下发的无人机指令，设备通过拉取获取
+type DroneCommandRecord struct {
+	ID             uint               `json:"id" gorm:"primaryKey"`
+	CommandID      string             `json:"command_id" gorm:"unique;not null;size:64"`
+	DroneID        uint               `json:"drone_id" gorm:"not null;index"`
+	Command        DroneCommand       `json:"command" gorm:"not null;size:30"`
+	Params         string             `json:"params" gorm:"type:text"` // JSON格式的指令参数
+	FleetID        *uint              `json:"fleet_id"`                // 机队批量下发时设置
+	IssuedBy       uint               `json:"issued_by"`
+	Status         DroneCommandStatus `json:"status" gorm:"default:pending;size:20;index"`
+	ExpiresAt      time.Time          `json:"expires_at"`
+	DeliveredAt    *time.Time         `json:"delivered_at"`
+	AcknowledgedAt *time.Time         `json:"acknowledged_at"`
+	Result         string             `json:"result" gorm:"size:500"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+// DroneCommandStatus 指令状态
+type DroneCommandStatus string
+
+const (
+	DroneCommandPending   DroneCommandStatus = "pending"   // 等待设备拉取
+	DroneCommandDelivered DroneCommandStatus = "delivered" // 设备已拉取，等待确认
+	DroneCommandSucceeded DroneCommandStatus = "succeeded"
+	DroneCommandFailed    DroneCommandStatus = "failed"
+	DroneCommandExpired   DroneCommandStatus = "expired"
+)
+
+// TableName 指定表名
+func (DeviceCredential) TableName() string {
+	return "device_credentials"
+}
+
+// TableName 指定表名
+func (DroneCommandRecord) TableName() string {
+	return "drone_commands"
+}
+
+// IsUsable 凭证是否有效
+func (c *DeviceCredential) IsUsable(now time.Time) bool {
+	if c.Status != DeviceCredentialActive {
+		return false
+	}
+	return c.ExpiresAt == nil || now.Before(*c.ExpiresAt)
+}
+
+// ParseParams 解析指令参数
+func (r *DroneCommandRecord) ParseParams() (map[string]interface{}, error) {
+	if r.Params == "" {
+		return map[string]interface{}{}, nil
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(r.Params), &params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
@@ -13,9 +13,11 @@ type Drone struct {
 	Battery      int         `json:"battery" gorm:"default:0;check:battery >= 0 AND battery <= 100"`
 	Position     Position    `json:"position" gorm:"embedded;embeddedPrefix:pos_"`
 	LastSeen     *time.Time  `json:"last_seen"`
-	Capabilities string      `json:"capabilities" gorm:"type:text"` // JSON字符串存储能力列表
+	Capabilities string      `json:"capabilities" gorm:"type:text"` // JSON字符串存储能力（DroneCapabilities）
 	Firmware     string      `json:"firmware" gorm:"size:50"`
 	Version      string      `json:"version" gorm:"size:20"`
+	FleetID      *uint       `json:"fleet_id" gorm:"index"` // 所属机队，为空时仅受全局角色控制
+	GroupID      *uint       `json:"group_id" gorm:"index"` // 机队内分组
 
 	// 关联关系 - 在需要时加载，避免循环引用
 	// Tasks []Task `json:"tasks,omitempty" gorm:"foreignKey:DroneID"`
@@ -34,6 +36,29 @@ const (
 	DroneStatusError       DroneStatus = "error"
 )
 
+// DroneCommand 无人机控制指令
+type DroneCommand string
+
+const (
+	DroneCommandReturnHome    DroneCommand = "return_home"
+	DroneCommandLand          DroneCommand = "land"
+	DroneCommandHover         DroneCommand = "hover"
+	DroneCommandResume        DroneCommand = "resume"
+	DroneCommandEmergencyStop DroneCommand = "emergency_stop"
+	DroneCommandReboot        DroneCommand = "reboot"
+)
+
+// IsValid 检查指令是否合法
+func (c DroneCommand) IsValid() bool {
+	switch c {
+	case DroneCommandReturnHome, DroneCommandLand, DroneCommandHover,
+		DroneCommandResume, DroneCommandEmergencyStop, DroneCommandReboot:
+		return true
+	default:
+		return false
+	}
+}
+
 // Position 位置信息
 type Position struct {
 	Latitude  float64 `json:"latitude" gorm:"type:decimal(10,8)"`
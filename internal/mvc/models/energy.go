@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// EnergyEstimate 任务航程、时长和耗电估算，完成后回填实际值用于校准
+type EnergyEstimate struct {
+	Model string `json:"model"` // 估算时使用的机型
+
+	Distance        float64 `json:"distance"`         // 任务航程（米）
+	TransitDistance float64 `json:"transit_distance"` // 起降点往返转场距离（米）
+	Climb           float64 `json:"climb"`            // 累计爬升（米）
+	Descent         float64 `json:"descent"`          // 累计下降（米）
+	HoverTime       float64 `json:"hover_time"`       // 悬停时间（秒）
+	FlightTime      float64 `json:"flight_time"`      // 预计飞行时间（秒），已按机型校准
+	RawFlightTime   float64 `json:"raw_flight_time"`  // 校准前的飞行时间（秒）
+	Duration        int     `json:"duration"`         // 预计飞行时间（分钟）
+
+	WindSpeed     float64 `json:"wind_speed"`     // 估算使用的风速（m/s）
+	WindDirection float64 `json:"wind_direction"` // 风向（度，风的来向）
+	PayloadWeight float64 `json:"payload_weight"` // 载荷重量（kg）
+
+	BatteryUsed       float64 `json:"battery_used"`       // 预计耗电（%），已按机型校准
+	RawBatteryUsed    float64 `json:"raw_battery_used"`   // 校准前的预计耗电（%）
+	CalibrationFactor float64 `json:"calibration_factor"` // 机型耗电校准系数
+	Samples           int     `json:"samples"`            // 校准样本数
+	Margin            float64 `json:"margin"`             // 置信余量（%）
+
+	StartBattery        int      `json:"start_battery"`         // 估算时的电量（%）
+	PredictedEndBattery float64  `json:"predicted_end_battery"` // 预计任务结束电量（%）
+	MinBattery          int      `json:"min_battery"`           // 任务结束时需保留的电量（%）
+	Feasible            bool     `json:"feasible"`              // 扣除置信余量后仍不低于保留电量
+	Warnings            []string `json:"warnings,omitempty"`
+
+	EstimatedAt time.Time `json:"estimated_at"`
+
+	// 任务完成后回填
+	EndBattery        *int       `json:"end_battery,omitempty"`
+	ActualBatteryUsed *float64   `json:"actual_battery_used,omitempty"`
+	ActualFlightTime  *float64   `json:"actual_flight_time,omitempty"` // 秒
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+// IsZero 是否尚未估算
+func (e EnergyEstimate) IsZero() bool {
+	return e.EstimatedAt.IsZero()
+}
+
+// LowerBound 扣除置信余量后的预计结束电量（%）
+func (e EnergyEstimate) LowerBound() float64 {
+	return e.PredictedEndBattery - e.Margin
+}
+
+// Value 实现 driver.Valuer
+func (e EnergyEstimate) Value() (driver.Value, error) {
+	return marshalTextColumn(e, e.IsZero())
+}
+
+// Scan 实现 sql.Scanner
+func (e *EnergyEstimate) Scan(value interface{}) error {
+	*e = EnergyEstimate{}
+	return unmarshalTextColumn(value, e)
+}
+
+// EnergyCalibration 机型耗电校准参数，由已完成任务的实际耗电与估算值比较得出
+type EnergyCalibration struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	Model           string    `json:"model" gorm:"uniqueIndex;not null;size:100"`
+	Samples         int       `json:"samples"`
+	BatteryFactor   float64   `json:"battery_factor"`   // 实际耗电 / 校准前估算耗电
+	BatteryVariance float64   `json:"battery_variance"` // 耗电比值的方差，用于计算置信余量
+	DurationFactor  float64   `json:"duration_factor"`  // 实际飞行时间 / 校准前估算时间
+	LastTaskID      uint      `json:"last_task_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (EnergyCalibration) TableName() string {
+	return "energy_calibrations"
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FirmwareRelease 固件版本目录
+type FirmwareRelease struct {
+	BaseModel
+	Model        string `json:"model" gorm:"not null;size:100;uniqueIndex:idx_firmware_model_version"` // 适用机型
+	Version      string `json:"version" gorm:"not null;size:50;uniqueIndex:idx_firmware_model_version"`
+	Checksum     string `json:"checksum" gorm:"not null;size:128"` // SHA-256
+	DownloadURL  string `json:"download_url" gorm:"size:500"`
+	Size         int64  `json:"size"`
+	ReleaseNotes string `json:"release_notes" gorm:"type:text"`
+	MinVersion   string `json:"min_version" gorm:"size:50"` // 升级到该版本前要求的最低已安装版本
+	CreatedBy    uint   `json:"created_by"`
+}
+
+// FirmwareRollout 固件分阶段发布活动
+type FirmwareRollout struct {
+	BaseModel
+	Name             string                `json:"name" gorm:"not null;size:100"`
+	ReleaseID        uint                  `json:"release_id" gorm:"not null;index"`
+	Release          FirmwareRelease       `json:"release" gorm:"foreignKey:ReleaseID"`
+	Status           FirmwareRolloutStatus `json:"status" gorm:"default:pending;size:20;index"`
+	CanaryPercent    int                   `json:"canary_percent" gorm:"default:10"` // 金丝雀阶段覆盖比例
+	BatchSize        int                   `json:"batch_size" gorm:"default:10"`     // 金丝雀之后每阶段的无人机数量
+	FailureThreshold int                   `json:"failure_threshold"`                // 允许的失败数，超过后触发失败处理
+	FailureAction    FirmwareFailureAction `json:"failure_action" gorm:"default:pause;size:20"`
+	AutoPromote      bool                  `json:"auto_promote"` // 金丝雀阶段完成后是否自动继续
+	CurrentStep      int                   `json:"current_step"`
+	TotalSteps       int                   `json:"total_steps"`
+	TotalTargets     int                   `json:"total_targets"`
+	SucceededCount   int                   `json:"succeeded_count"`
+	FailedCount      int                   `json:"failed_count"`
+	AcceptedFailures int                   `json:"accepted_failures"` // 恢复时已确认的失败数，不再计入阈值
+	PauseReason      string                `json:"pause_reason,omitempty" gorm:"size:255"`
+	CreatedBy        uint                  `json:"created_by"`
+	StartedAt        *time.Time            `json:"started_at"`
+	StepStartedAt    *time.Time            `json:"step_started_at"`
+	CompletedAt      *time.Time            `json:"completed_at"`
+}
+
+// FirmwareRolloutTarget 发布活动中的单架无人机
+type FirmwareRolloutTarget struct {
+	ID           uint                 `json:"id" gorm:"primaryKey"`
+	RolloutID    uint                 `json:"rollout_id" gorm:"not null;uniqueIndex:idx_rollout_drone;index:idx_rollout_step,priority:1"`
+	DroneID      uint                 `json:"drone_id" gorm:"not null;uniqueIndex:idx_rollout_drone"`
+	Step         int                  `json:"step" gorm:"index:idx_rollout_step,priority:2"`
+	Status       FirmwareTargetStatus `json:"status" gorm:"default:pending;size:20"`
+	FromVersion  string               `json:"from_version" gorm:"size:50"`
+	Error        string               `json:"error,omitempty" gorm:"type:text"`
+	DispatchedAt *time.Time           `json:"dispatched_at"`
+	CompletedAt  *time.Time           `json:"completed_at"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
+
+// FirmwareRolloutStatus 发布活动状态
+type FirmwareRolloutStatus string
+
+const (
+	FirmwareRolloutPending   FirmwareRolloutStatus = "pending"
+	FirmwareRolloutRunning   FirmwareRolloutStatus = "running"
+	FirmwareRolloutPaused    FirmwareRolloutStatus = "paused"
+	FirmwareRolloutCompleted FirmwareRolloutStatus = "completed"
+	FirmwareRolloutAborted   FirmwareRolloutStatus = "aborted"
+)
+
+// FirmwareFailureAction 失败数超过阈值后的处理方式
+type FirmwareFailureAction string
+
+const (
+	FirmwareFailurePause FirmwareFailureAction = "pause"
+	FirmwareFailureAbort FirmwareFailureAction = "abort"
+)
+
+// FirmwareTargetStatus 单机升级状态
+type FirmwareTargetStatus string
+
+const (
+	FirmwareTargetPending    FirmwareTargetStatus = "pending"
+	FirmwareTargetDispatched FirmwareTargetStatus = "dispatched"
+	FirmwareTargetSucceeded  FirmwareTargetStatus = "succeeded"
+	FirmwareTargetFailed     FirmwareTargetStatus = "failed"
+	FirmwareTargetSkipped    FirmwareTargetStatus = "skipped"
+)
+
+// TableName 指定表名
+func (FirmwareRelease) TableName() string {
+	return "firmware_releases"
+}
+
+// TableName 指定表名
+func (FirmwareRollout) TableName() string {
+	return "firmware_rollouts"
+}
+
+// TableName 指定表名
+func (FirmwareRolloutTarget) TableName() string {
+	return "firmware_rollout_targets"
+}
+
+// IsFinished 发布活动是否已结束
+func (r *FirmwareRollout) IsFinished() bool {
+	return r.Status == FirmwareRolloutCompleted || r.Status == FirmwareRolloutAborted
+}
+
+// IsFinished 单机升级是否已结束
+func (t *FirmwareRolloutTarget) IsFinished() bool {
+	return t.Status == FirmwareTargetSucceeded || t.Status == FirmwareTargetFailed || t.Status == FirmwareTargetSkipped
+}
+
+// FailureThresholdExceeded 未确认的失败数是否超过阈值
+func (r *FirmwareRollout) FailureThresholdExceeded() bool {
+	return r.FailedCount-r.AcceptedFailures > r.FailureThreshold
+}
+
+// CanUpgradeFrom 当前版本是否可直接升级到该版本
+func (f *FirmwareRelease) CanUpgradeFrom(current string) bool {
+	if CompareVersions(current, f.Version) >= 0 {
+		return false
+	}
+	return f.MinVersion == "" || CompareVersions(current, f.MinVersion) >= 0
+}
+
+// CompareVersions 比较版本号，返回 -1/0/1
+// 按语义化版本的优先级比较：忽略前缀 v 和 "+" 之后的构建信息；主版本部分逐段按数值比较，缺少的段视为 0（1.2 == 1.2.0）；
+// 带预发布标识的版本低于对应的正式版本（1.2.0-beta < 1.2.0），预发布标识按 "." 分段比较；空版本视为最低
+func CompareVersions(a, b string) int {
+	coreA, preA := splitVersion(a)
+	coreB, preB := splitVersion(b)
+
+	switch {
+	case len(coreA) == 0 && len(coreB) == 0:
+		return 0
+	case len(coreA) == 0:
+		return -1
+	case len(coreB) == 0:
+		return 1
+	}
+
+	for i := 0; i < len(coreA) || i < len(coreB); i++ {
+		sa, sb := "0", "0"
+		if i < len(coreA) {
+			sa = coreA[i]
+		}
+		if i < len(coreB) {
+			sb = coreB[i]
+		}
+		if c := compareIdentifiers(sa, sb); c != 0 {
+			return c
+		}
+	}
+
+	// 主版本相同时，没有预发布标识的正式版本更高
+	switch {
+	case len(preA) == 0 && len(preB) == 0:
+		return 0
+	case len(preA) == 0:
+		return 1
+	case len(preB) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(preA) && i < len(preB); i++ {
+		if c := compareIdentifiers(preA[i], preB[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(preA) < len(preB):
+		return -1
+	case len(preA) > len(preB):
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifiers 比较版本中的一段：数字按数值比较且低于非数字，非数字按字典序比较
+func compareIdentifiers(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+		return 0
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// splitVersion 拆分为主版本段和预发布标识段，去掉构建信息
+func splitVersion(v string) (core, prerelease []string) {
+	v = strings.TrimPrefix(strings.TrimSpace(strings.ToLower(v)), "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if v == "" {
+		return nil, nil
+	}
+
+	corePart, prePart, _ := strings.Cut(v, "-")
+	core = strings.Split(corePart, ".")
+	if prePart != "" {
+		prerelease = strings.Split(prePart, ".")
+	}
+	return core, prerelease
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+)
+
+// Fleet 机队
+type Fleet struct {
+	BaseModel
+	Name        string `json:"name" gorm:"unique;not null;size:100"`
+	Description string `json:"description" gorm:"type:text"`
+}
+
+// DroneGroup 机队内的无人机分组
+type DroneGroup struct {
+	BaseModel
+	FleetID     uint   `json:"fleet_id" gorm:"not null;uniqueIndex:idx_drone_group"`
+	Name        string `json:"name" gorm:"not null;size:100;uniqueIndex:idx_drone_group"`
+	Description string `json:"description" gorm:"type:text"`
+}
+
+// FleetMember 机队成员及其在机队内的角色
+type FleetMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	FleetID   uint      `json:"fleet_id" gorm:"not null;uniqueIndex:idx_fleet_member"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_fleet_member;index"`
+	Role      UserRole  `json:"role" gorm:"not null;size:20"` // viewer, operator, admin
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Fleet) TableName() string {
+	return "fleets"
+}
+
+// TableName 指定表名
+func (DroneGroup) TableName() string {
+	return "drone_groups"
+}
+
+// TableName 指定表名
+func (FleetMember) TableName() string {
+	return "fleet_members"
+}
@@ -0,0 +1,340 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"drone-control-system/pkg/geo"
+)
+
+// Geofence 地理围栏（禁飞区、限飞区、提示区）
+type Geofence struct {
+	BaseModel
+	Name        string        `json:"name" gorm:"not null;size:100"`
+	Description string        `json:"description" gorm:"type:text"`
+	Type        GeofenceType  `json:"type" gorm:"not null;size:20;index"`
+	Shape       GeofenceShape `json:"shape" gorm:"not null;size:20"`
+	Enabled     bool          `json:"enabled" gorm:"index"`
+
+	// 多边形顶点，shape 为 polygon 时使用
+	Vertices GeoPointList `json:"vertices" gorm:"type:text"`
+	// 圆形区域，shape 为 circle 时使用
+	CenterLatitude  float64 `json:"center_latitude" gorm:"type:decimal(10,8)"`
+	CenterLongitude float64 `json:"center_longitude" gorm:"type:decimal(11,8)"`
+	Radius          float64 `json:"radius"` // 半径（米）
+
+	// 高度范围（米），MaxAltitude 为0表示不限上限
+	MinAltitude float64 `json:"min_altitude"`
+	MaxAltitude float64 `json:"max_altitude"`
+
+	// 生效时间：绝对时间范围和每周重复的时间窗口，均为空表示始终生效
+	ActiveFrom  *time.Time         `json:"active_from"`
+	ActiveUntil *time.Time         `json:"active_until"`
+	Windows     GeofenceWindowList `json:"windows" gorm:"type:text"`
+	Timezone    string             `json:"timezone" gorm:"size:64"`
+
+	CreatedBy uint `json:"created_by"`
+}
+
+// GeofenceType 围栏类型
+type GeofenceType string
+
+const (
+	GeofenceNoFly      GeofenceType = "no_fly"     // 禁飞区，禁止进入
+	GeofenceRestricted GeofenceType = "restricted" // 限飞区，禁止规划穿越
+	GeofenceAdvisory   GeofenceType = "advisory"   // 提示区，仅告警
+)
+
+// GeofenceShape 围栏形状
+type GeofenceShape string
+
+const (
+	GeofenceShapePolygon GeofenceShape = "polygon"
+	GeofenceShapeCircle  GeofenceShape = "circle"
+)
+
+// GeofenceWindow 每周重复的生效时间窗口
+type GeofenceWindow struct {
+	Days  []time.Weekday `json:"days"`  // 生效的星期（0为周日），为空表示每天
+	Start string         `json:"start"` // 开始时间 HH:MM
+	End   string         `json:"end"`   // 结束时间 HH:MM，小于开始时间表示跨午夜
+}
+
+// TableName 指定表名
+func (Geofence) TableName() string {
+	return "geofences"
+}
+
+// IsValid 检查围栏类型是否合法
+func (t GeofenceType) IsValid() bool {
+	switch t {
+	case GeofenceNoFly, GeofenceRestricted, GeofenceAdvisory:
+		return true
+	default:
+		return false
+	}
+}
+
+// BlocksPlans 是否禁止任务航线穿越
+func (t GeofenceType) BlocksPlans() bool {
+	return t == GeofenceNoFly || t == GeofenceRestricted
+}
+
+// IsValid 检查围栏形状是否合法
+func (s GeofenceShape) IsValid() bool {
+	return s == GeofenceShapePolygon || s == GeofenceShapeCircle
+}
+
+// Validate 检查围栏几何、高度和时间配置
+func (g *Geofence) Validate() error {
+	if !g.Type.IsValid() {
+		return fmt.Errorf("unknown geofence type: %s", g.Type)
+	}
+
+	switch g.Shape {
+	case GeofenceShapePolygon:
+		if len(g.Vertices) < 3 {
+			return fmt.Errorf("polygon requires at least 3 vertices")
+		}
+		for i, p := range g.Vertices {
+			if p.Latitude < -90 || p.Latitude > 90 || p.Longitude < -180 || p.Longitude > 180 {
+				return fmt.Errorf("vertex %d is out of range", i)
+			}
+		}
+	case GeofenceShapeCircle:
+		if g.Radius <= 0 {
+			return fmt.Errorf("circle radius must be positive")
+		}
+		if g.CenterLatitude < -90 || g.CenterLatitude > 90 || g.CenterLongitude < -180 || g.CenterLongitude > 180 {
+			return fmt.Errorf("circle center is out of range")
+		}
+	default:
+		return fmt.Errorf("unknown geofence shape: %s", g.Shape)
+	}
+
+	if g.MinAltitude < 0 || g.MaxAltitude < 0 {
+		return fmt.Errorf("altitude band must not be negative")
+	}
+	if g.MaxAltitude > 0 && g.MaxAltitude <= g.MinAltitude {
+		return fmt.Errorf("max_altitude must be greater than min_altitude")
+	}
+	if g.ActiveFrom != nil && g.ActiveUntil != nil && !g.ActiveUntil.After(*g.ActiveFrom) {
+		return fmt.Errorf("active_until must be after active_from")
+	}
+	if _, err := g.Location(); err != nil {
+		return fmt.Errorf("unknown timezone: %s", g.Timezone)
+	}
+	for i, w := range g.Windows {
+		if err := w.validate(); err != nil {
+			return fmt.Errorf("window %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// Location 时间窗口使用的时区，未设置时为UTC
+func (g *Geofence) Location() (*time.Location, error) {
+	if g.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(g.Timezone)
+}
+
+// ActiveAt 判断围栏在指定时间是否生效
+func (g *Geofence) ActiveAt(t time.Time) bool {
+	if !g.Enabled {
+		return false
+	}
+	if g.ActiveFrom != nil && t.Before(*g.ActiveFrom) {
+		return false
+	}
+	if g.ActiveUntil != nil && !t.Before(*g.ActiveUntil) {
+		return false
+	}
+	if len(g.Windows) == 0 {
+		return true
+	}
+
+	loc, err := g.Location()
+	if err != nil {
+		loc = time.UTC
+	}
+	local := t.In(loc)
+	for _, w := range g.Windows {
+		if w.contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// InAltitudeBand 判断高度是否在围栏高度范围内
+func (g *Geofence) InAltitudeBand(altitude float64) bool {
+	if altitude < g.MinAltitude {
+		return false
+	}
+	return g.MaxAltitude == 0 || altitude <= g.MaxAltitude
+}
+
+// OverlapsAltitude 判断高度区间 [low, high] 是否与围栏高度范围重叠
+func (g *Geofence) OverlapsAltitude(low, high float64) bool {
+	if low > high {
+		low, high = high, low
+	}
+	if high < g.MinAltitude {
+		return false
+	}
+	return g.MaxAltitude == 0 || low <= g.MaxAltitude
+}
+
+// HorizontalDistance 计算点到围栏水平边界的距离（米），在围栏内时返回0
+func (g *Geofence) HorizontalDistance(p geo.Point) float64 {
+	if g.Shape == GeofenceShapeCircle {
+		d := geo.Distance(p.Latitude, p.Longitude, g.CenterLatitude, g.CenterLongitude) - g.Radius
+		if d < 0 {
+			return 0
+		}
+		return d
+	}
+	return geo.DistanceToPolygon(p, g.Vertices)
+}
+
+// Contains 判断三维位置是否在围栏内
+func (g *Geofence) Contains(p geo.Point, altitude float64) bool {
+	return g.InAltitudeBand(altitude) && g.HorizontalDistance(p) == 0
+}
+
+// IntersectsSegment 判断航段是否进入围栏，高度在两端之间线性变化
+func (g *Geofence) IntersectsSegment(a geo.Point, altA float64, b geo.Point, altB float64) bool {
+	if !g.OverlapsAltitude(altA, altB) {
+		return false
+	}
+	if g.Shape == GeofenceShapeCircle {
+		center := geo.Point{Latitude: g.CenterLatitude, Longitude: g.CenterLongitude}
+		return geo.DistanceToSegment(center, a, b) <= g.Radius
+	}
+	return geo.SegmentIntersectsPolygon(a, b, g.Vertices)
+}
+
+// validate 检查时间窗口配置
+func (w GeofenceWindow) validate() error {
+	if _, err := parseClock(w.Start); err != nil {
+		return fmt.Errorf("invalid start: %v", err)
+	}
+	if _, err := parseClock(w.End); err != nil {
+		return fmt.Errorf("invalid end: %v", err)
+	}
+	for _, d := range w.Days {
+		if d < time.Sunday || d > time.Saturday {
+			return fmt.Errorf("invalid weekday: %d", d)
+		}
+	}
+	return nil
+}
+
+// contains 判断本地时间是否在窗口内，跨午夜的窗口按开始日计算星期
+func (w GeofenceWindow) contains(local time.Time) bool {
+	start, err1 := parseClock(w.Start)
+	end, err2 := parseClock(w.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+	switch {
+	case start == end:
+		return w.onDay(day)
+	case start < end:
+		return minute >= start && minute < end && w.onDay(day)
+	case minute >= start:
+		return w.onDay(day)
+	case minute < end:
+		return w.onDay((day + 6) % 7)
+	default:
+		return false
+	}
+}
+
+// onDay 判断窗口在指定星期是否生效
+func (w GeofenceWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock 解析 HH:MM 为当天分钟数
+func parseClock(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, fmt.Errorf("invalid hour in %q", value)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid minute in %q", value)
+	}
+	return hour*60 + minute, nil
+}
+
+// GeoPointList 坐标点列表，以JSON存储在text列中
+type GeoPointList []geo.Point
+
+// Value 实现 driver.Valuer
+func (l GeoPointList) Value() (driver.Value, error) {
+	return marshalTextColumn(l, len(l) == 0)
+}
+
+// Scan 实现 sql.Scanner
+func (l *GeoPointList) Scan(value interface{}) error {
+	*l = nil
+	return unmarshalTextColumn(value, (*[]geo.Point)(l))
+}
+
+// GeofenceWindowList 时间窗口列表，以JSON存储在text列中
+type GeofenceWindowList []GeofenceWindow
+
+// Value 实现 driver.Valuer
+func (l GeofenceWindowList) Value() (driver.Value, error) {
+	return marshalTextColumn(l, len(l) == 0)
+}
+
+// Scan 实现 sql.Scanner
+func (l *GeofenceWindowList) Scan(value interface{}) error {
+	*l = nil
+	return unmarshalTextColumn(value, (*[]GeofenceWindow)(l))
+}
+
+// marshalTextColumn 将值序列化为JSON文本列，空值存为空字符串
+func marshalTextColumn(v interface{}, empty bool) (driver.Value, error) {
+	if empty {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// unmarshalTextColumn 从JSON文本列反序列化，空列保持零值
+func unmarshalTextColumn(value interface{}, v interface{}) error {
+	data, err := scanText(value)
+	if err != nil || len(data) == 0 {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MaintenanceRecord 维护工单
+type MaintenanceRecord struct {
+	BaseModel
+	DroneID     uint              `json:"drone_id" gorm:"not null;index"`
+	IntervalID  *uint             `json:"interval_id" gorm:"index"` // 对应的保养周期，非计划维护为空
+	Type        MaintenanceType   `json:"type" gorm:"not null;size:20"`
+	Status      MaintenanceStatus `json:"status" gorm:"default:open;size:20;index"`
+	Title       string            `json:"title" gorm:"not null;size:200"`
+	Description string            `json:"description" gorm:"type:text"`
+	Reason      string            `json:"reason" gorm:"size:500"` // 进入维护的原因
+	Parts       string            `json:"parts" gorm:"type:text"` // JSON格式的更换零件列表（[]MaintenancePart）
+	Technician  string            `json:"technician" gorm:"size:100"`
+	Notes       string            `json:"notes" gorm:"type:text"`
+
+	// 维护时的累计使用量，作为下次保养周期的起点
+	FlightHours  float64 `json:"flight_hours" gorm:"type:decimal(10,2)"`
+	FlightCycles int     `json:"flight_cycles"`
+
+	OpenedBy    uint       `json:"opened_by"` // 0 表示系统自动创建
+	SignedOffBy *uint      `json:"signed_off_by"`
+	SignedOffAt *time.Time `json:"signed_off_at"`
+	StartedAt   *time.Time `json:"started_at"`
+}
+
+// MaintenancePart 更换的零件
+type MaintenancePart struct {
+	PartNo   string `json:"part_no"`
+	Name     string `json:"name"`
+	SerialNo string `json:"serial_no,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+// MaintenanceType 维护类型
+type MaintenanceType string
+
+const (
+	MaintenanceTypeScheduled  MaintenanceType = "scheduled"  // 按保养周期
+	MaintenanceTypeInspection MaintenanceType = "inspection" // 检查
+	MaintenanceTypeRepair     MaintenanceType = "repair"     // 故障维修
+)
+
+// MaintenanceStatus 维护工单状态
+type MaintenanceStatus string
+
+const (
+	MaintenanceStatusOpen       MaintenanceStatus = "open"
+	MaintenanceStatusInProgress MaintenanceStatus = "in_progress"
+	MaintenanceStatusCompleted  MaintenanceStatus = "completed"
+	MaintenanceStatusCancelled  MaintenanceStatus = "cancelled"
+)
+
+// ServiceInterval 机型保养周期，任一阈值到达即需要保养，零值表示不限制
+type ServiceInterval struct {
+	BaseModel
+	Model        string  `json:"model" gorm:"not null;size:100;uniqueIndex:idx_service_interval"`
+	Name         string  `json:"name" gorm:"not null;size:100;uniqueIndex:idx_service_interval"`
+	Description  string  `json:"description" gorm:"type:text"`
+	FlightHours  float64 `json:"flight_hours" gorm:"type:decimal(10,2)"`
+	FlightCycles int     `json:"flight_cycles"`
+	CalendarDays int     `json:"calendar_days"`
+}
+
+// DroneUsage 无人机累计使用量，由任务和遥测历史增量累加
+type DroneUsage struct {
+	DroneID        uint       `json:"drone_id" gorm:"primaryKey;autoIncrement:false"`
+	FlightHours    float64    `json:"flight_hours" gorm:"type:decimal(10,2)"`
+	FlightCycles   int        `json:"flight_cycles"`
+	TrackingSince  time.Time  `json:"tracking_since"`
+	AccountedUntil time.Time  `json:"accounted_until"`  // 已统计到的时间点
+	LastAirborneAt *time.Time `json:"last_airborne_at"` // 跨统计窗口判断连续飞行
+	LastFlightAt   *time.Time `json:"last_flight_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (MaintenanceRecord) TableName() string {
+	return "maintenance_records"
+}
+
+// TableName 指定表名
+func (ServiceInterval) TableName() string {
+	return "service_intervals"
+}
+
+// TableName 指定表名
+func (DroneUsage) TableName() string {
+	return "drone_usage"
+}
+
+// IsOpen 工单是否未结束
+func (r *MaintenanceRecord) IsOpen() bool {
+	return r.Status == MaintenanceStatusOpen || r.Status == MaintenanceStatusInProgress
+}
+
+// ParseParts 解析更换零件列表
+func (r *MaintenanceRecord) ParseParts() ([]MaintenancePart, error) {
+	if r.Parts == "" {
+		return []MaintenancePart{}, nil
+	}
+
+	var parts []MaintenancePart
+	if err := json.Unmarshal([]byte(r.Parts), &parts); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// SetParts 序列化并保存更换零件列表
+func (r *MaintenanceRecord) SetParts(parts []MaintenancePart) error {
+	if len(parts) == 0 {
+		r.Parts = ""
+		return nil
+	}
+
+	data, err := json.Marshal(parts)
+	if err != nil {
+		return err
+	}
+	r.Parts = string(data)
+	return nil
+}
+
+// IsEmpty 是否未设置任何阈值
+func (i *ServiceInterval) IsEmpty() bool {
+	return i.FlightHours <= 0 && i.FlightCycles <= 0 && i.CalendarDays <= 0
+}
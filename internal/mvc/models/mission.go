@@ -0,0 +1,324 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MaxMissionWaypoints 单个任务允许的最大航点数
+const MaxMissionWaypoints = 1000
+
+// WaypointActionType 航点动作类型
+type WaypointActionType string
+
+const (
+	WaypointActionCapture     WaypointActionType = "capture"      // 拍摄
+	WaypointActionHover       WaypointActionType = "hover"        // 悬停
+	WaypointActionDropPayload WaypointActionType = "drop_payload" // 投放载荷
+)
+
+// IsValid 检查动作类型是否合法
+func (t WaypointActionType) IsValid() bool {
+	switch t {
+	case WaypointActionCapture, WaypointActionHover, WaypointActionDropPayload:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaypointAction 到达航点后执行的动作
+type WaypointAction struct {
+	Type     WaypointActionType `json:"type"`
+	Sensor   SensorType         `json:"sensor,omitempty"`   // capture 使用的传感器，为空时使用任意相机
+	Count    int                `json:"count,omitempty"`    // capture 拍摄张数，默认1
+	Duration float64            `json:"duration,omitempty"` // hover 悬停时长（秒）
+}
+
+// WaypointList 有序航点列表，以JSON存储在text列中
+type WaypointList []Waypoint
+
+// Value 实现 driver.Valuer
+func (l WaypointList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]Waypoint(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner
+func (l *WaypointList) Scan(value interface{}) error {
+	data, err := scanText(value)
+	if err != nil || len(data) == 0 {
+		*l = nil
+		return err
+	}
+	return json.Unmarshal(data, (*[]Waypoint)(l))
+}
+
+// UnmarshalJSON 兼容旧接口以JSON字符串传入的航点
+func (l *WaypointList) UnmarshalJSON(data []byte) error {
+	data, err := unwrapJSONString(data)
+	if err != nil || len(data) == 0 {
+		*l = nil
+		return err
+	}
+	return json.Unmarshal(data, (*[]Waypoint)(l))
+}
+
+// MissionPayload 任务载荷配置
+type MissionPayload struct {
+	Type        string  `json:"type,omitempty"`   // 载荷类型，如 parcel、sprayer、camera_gimbal
+	Weight      float64 `json:"weight,omitempty"` // 重量（kg）
+	Description string  `json:"description,omitempty"`
+}
+
+// IsEmpty 是否未配置载荷
+func (p MissionPayload) IsEmpty() bool {
+	return p.Type == "" && p.Weight == 0 && p.Description == ""
+}
+
+// Value 实现 driver.Valuer
+func (p MissionPayload) Value() (driver.Value, error) {
+	if p.IsEmpty() {
+		return "", nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner
+func (p *MissionPayload) Scan(value interface{}) error {
+	*p = MissionPayload{}
+	data, err := scanText(value)
+	if err != nil || len(data) == 0 {
+		return err
+	}
+	return p.decode(data)
+}
+
+// UnmarshalJSON 兼容旧接口以JSON字符串传入的载荷
+func (p *MissionPayload) UnmarshalJSON(data []byte) error {
+	*p = MissionPayload{}
+	data, err := unwrapJSONString(data)
+	if err != nil || len(data) == 0 {
+		return err
+	}
+
+	return p.decode(data)
+}
+
+// decode 解析载荷JSON，旧数据中的纯文本描述保存到 Description
+func (p *MissionPayload) decode(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' {
+		p.Description = string(data)
+		return nil
+	}
+
+	type plain MissionPayload
+	return json.Unmarshal(data, (*plain)(p))
+}
+
+// MissionIssue 任务计划校验问题，Waypoint 为空表示计划级别的问题
+type MissionIssue struct {
+	Waypoint *int   `json:"waypoint,omitempty"` // 航点序号（从0开始）
+	Action   *int   `json:"action,omitempty"`   // 航点动作序号
+	Field    string `json:"field"`
+	Message  string `json:"message"`
+}
+
+// String 可读的问题描述
+func (i MissionIssue) String() string {
+	switch {
+	case i.Waypoint != nil && i.Action != nil:
+		return fmt.Sprintf("waypoint %d action %d %s: %s", *i.Waypoint, *i.Action, i.Field, i.Message)
+	case i.Waypoint != nil:
+		return fmt.Sprintf("waypoint %d %s: %s", *i.Waypoint, i.Field, i.Message)
+	default:
+		return fmt.Sprintf("%s: %s", i.Field, i.Message)
+	}
+}
+
+// Validate 校验任务计划，caps 不为空时同时校验无人机机型限制（未登记的限制不校验）
+func (p *TaskPlan) Validate(caps *DroneCapabilities) []MissionIssue {
+	v := &missionValidator{plan: p, caps: caps}
+	v.validatePlan()
+	for i := range p.Waypoints {
+		v.validateWaypoint(i, &p.Waypoints[i])
+	}
+	return v.issues
+}
+
+// missionValidator 收集计划校验问题
+type missionValidator struct {
+	plan   *TaskPlan
+	caps   *DroneCapabilities
+	issues []MissionIssue
+}
+
+func (v *missionValidator) add(waypoint, action *int, field, format string, args ...interface{}) {
+	v.issues = append(v.issues, MissionIssue{
+		Waypoint: waypoint,
+		Action:   action,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// validatePlan 校验计划级别的限制
+func (v *missionValidator) validatePlan() {
+	p := v.plan
+	if len(p.Waypoints) > MaxMissionWaypoints {
+		v.add(nil, nil, "waypoints", "at most %d waypoints are allowed, got %d", MaxMissionWaypoints, len(p.Waypoints))
+	}
+	if p.MaxAltitude < 0 {
+		v.add(nil, nil, "max_altitude", "must not be negative")
+	}
+	if p.MaxSpeed < 0 {
+		v.add(nil, nil, "max_speed", "must not be negative")
+	}
+	if p.Duration < 0 {
+		v.add(nil, nil, "duration", "must not be negative")
+	}
+	if p.MinBattery < 0 || p.MinBattery >= 100 {
+		v.add(nil, nil, "min_battery", "must be between 0 and 99")
+	}
+	if p.Payload.Weight < 0 {
+		v.add(nil, nil, "payload.weight", "must not be negative")
+	}
+
+	if v.caps == nil {
+		return
+	}
+	if v.caps.MaxAltitude > 0 && p.MaxAltitude > v.caps.MaxAltitude {
+		v.add(nil, nil, "max_altitude", "%.1fm exceeds drone limit %.1fm", p.MaxAltitude, v.caps.MaxAltitude)
+	}
+	if v.caps.MaxSpeed > 0 && p.MaxSpeed > v.caps.MaxSpeed {
+		v.add(nil, nil, "max_speed", "%.1fm/s exceeds drone limit %.1fm/s", p.MaxSpeed, v.caps.MaxSpeed)
+	}
+	if v.caps.PayloadCapacity > 0 && p.Payload.Weight > v.caps.PayloadCapacity {
+		v.add(nil, nil, "payload.weight", "%.2fkg exceeds drone payload capacity %.2fkg", p.Payload.Weight, v.caps.PayloadCapacity)
+	}
+}
+
+// validateWaypoint 校验单个航点
+func (v *missionValidator) validateWaypoint(index int, wp *Waypoint) {
+	at := &index
+	p := v.plan
+
+	if wp.Latitude < -90 || wp.Latitude > 90 {
+		v.add(at, nil, "latitude", "must be between -90 and 90")
+	}
+	if wp.Longitude < -180 || wp.Longitude > 180 {
+		v.add(at, nil, "longitude", "must be between -180 and 180")
+	}
+
+	switch {
+	case wp.Altitude < 0:
+		v.add(at, nil, "altitude", "must not be negative")
+	case p.MaxAltitude > 0 && wp.Altitude > p.MaxAltitude:
+		v.add(at, nil, "altitude", "%.1fm exceeds plan max_altitude %.1fm", wp.Altitude, p.MaxAltitude)
+	case v.caps != nil && v.caps.MaxAltitude > 0 && wp.Altitude > v.caps.MaxAltitude:
+		v.add(at, nil, "altitude", "%.1fm exceeds drone limit %.1fm", wp.Altitude, v.caps.MaxAltitude)
+	}
+
+	switch {
+	case wp.Speed < 0:
+		v.add(at, nil, "speed", "must not be negative")
+	case p.MaxSpeed > 0 && wp.Speed > p.MaxSpeed:
+		v.add(at, nil, "speed", "%.1fm/s exceeds plan max_speed %.1fm/s", wp.Speed, p.MaxSpeed)
+	case v.caps != nil && v.caps.MaxSpeed > 0 && wp.Speed > v.caps.MaxSpeed:
+		v.add(at, nil, "speed", "%.1fm/s exceeds drone limit %.1fm/s", wp.Speed, v.caps.MaxSpeed)
+	}
+
+	if wp.HoldTime < 0 {
+		v.add(at, nil, "hold_time", "must not be negative")
+	}
+	if wp.Heading != nil && (*wp.Heading < 0 || *wp.Heading >= 360) {
+		v.add(at, nil, "heading", "must be in [0, 360)")
+	}
+
+	for i := range wp.Actions {
+		v.validateAction(at, i, &wp.Actions[i])
+	}
+}
+
+// validateAction 校验航点动作
+func (v *missionValidator) validateAction(at *int, index int, action *WaypointAction) {
+	ai := &index
+	if !action.Type.IsValid() {
+		v.add(at, ai, "type", "unknown action type: %s", action.Type)
+		return
+	}
+
+	switch action.Type {
+	case WaypointActionCapture:
+		if action.Count < 0 {
+			v.add(at, ai, "count", "must not be negative")
+		}
+		if action.Sensor != "" && !action.Sensor.IsValid() {
+			v.add(at, ai, "sensor", "unknown sensor type: %s", action.Sensor)
+			return
+		}
+		if v.caps == nil {
+			return
+		}
+		if action.Sensor != "" && !v.caps.HasSensor(action.Sensor) {
+			v.add(at, ai, "sensor", "drone has no %s sensor", action.Sensor)
+		} else if action.Sensor == "" && !v.caps.HasCamera() {
+			v.add(at, ai, "sensor", "drone has no camera sensor")
+		}
+
+	case WaypointActionHover:
+		if action.Duration <= 0 {
+			v.add(at, ai, "duration", "hover duration must be positive")
+		}
+
+	case WaypointActionDropPayload:
+		if v.plan.Payload.IsEmpty() {
+			v.add(at, ai, "type", "drop_payload requires a plan payload")
+		}
+		// 载重未登记（为0）时不校验，已登记时载荷重量在计划级别与载重比较
+	}
+}
+
+// scanText 读取数据库text列
+func scanText(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %T", value)
+	}
+}
+
+// unwrapJSONString 如果JSON值是字符串，返回其内容（旧接口以字符串传入嵌套JSON）
+func unwrapJSONString(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	if len(data) == 0 || data[0] != '"' {
+		return data, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace([]byte(s)), nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+)
+
+// PreflightStatus 起飞前检查结果
+type PreflightStatus string
+
+const (
+	PreflightPass PreflightStatus = "pass"
+	PreflightWarn PreflightStatus = "warn"
+	PreflightFail PreflightStatus = "fail"
+)
+
+// severity 结果严重程度，用于汇总
+func (s PreflightStatus) severity() int {
+	switch s {
+	case PreflightFail:
+		return 2
+	case PreflightWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// PreflightCheck 单项检查结果
+type PreflightCheck struct {
+	Code    string          `json:"code"` // 检查项，如 drone_status、battery、firmware
+	Status  PreflightStatus `json:"status"`
+	Message string          `json:"message"`
+}
+
+// PreflightReport 起飞前检查报告
+type PreflightReport struct {
+	TaskID    uint              `json:"task_id"`
+	DroneID   uint              `json:"drone_id"`
+	Status    PreflightStatus   `json:"status"` // 所有检查项中最严重的结果
+	Checks    []*PreflightCheck `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+
+	// 存在警告且已由管理员批准放行时设置
+	OverrideID *uint `json:"override_id,omitempty"`
+}
+
+// Add 添加检查结果并更新汇总状态
+func (r *PreflightReport) Add(code string, status PreflightStatus, message string) {
+	r.Checks = append(r.Checks, &PreflightCheck{Code: code, Status: status, Message: message})
+	if status.severity() > r.Status.severity() {
+		r.Status = status
+	}
+}
+
+// Codes 返回指定结果的检查项
+func (r *PreflightReport) Codes(status PreflightStatus) []string {
+	var codes []string
+	for _, check := range r.Checks {
+		if check.Status == status {
+			codes = append(codes, check.Code)
+		}
+	}
+	return codes
+}
+
+// PreflightOverride 管理员放行起飞前检查警告的审计记录
+type PreflightOverride struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TaskID    uint       `json:"task_id" gorm:"not null;index"`
+	DroneID   uint       `json:"drone_id" gorm:"index"`
+	UserID    uint       `json:"user_id" gorm:"not null"`
+	Reason    string     `json:"reason" gorm:"type:text;not null"`
+	Warnings  string     `json:"warnings" gorm:"size:255"` // 放行时的警告检查项，逗号分隔
+	Report    string     `json:"report" gorm:"type:text"`  // 放行时的完整检查报告（JSON）
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"` // 放行后任务启动的时间，每条记录只能使用一次
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PreflightOverride) TableName() string {
+	return "preflight_overrides"
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// TaskTracking 根据遥测位置推算的任务执行进度
+type TaskTracking struct {
+	Attempt      int               `json:"attempt"`       // 对应的执行次数，重试后重新推算
+	NextWaypoint int               `json:"next_waypoint"` // 下一个需要完成的航点下标，等于航点数时全部完成
+	Arrivals     []WaypointArrival `json:"arrivals,omitempty"`
+
+	TotalDistance     float64    `json:"total_distance"`     // 起始位置经各航点的总航程（米）
+	RemainingDistance float64    `json:"remaining_distance"` // 剩余航程（米）
+	ETA               *time.Time `json:"eta,omitempty"`      // 预计完成全部航点的时间
+
+	LastLatitude   float64   `json:"last_latitude"`
+	LastLongitude  float64   `json:"last_longitude"`
+	LastAltitude   float64   `json:"last_altitude"`
+	LastPositionAt time.Time `json:"last_position_at"`
+	LastProgressAt time.Time `json:"last_progress_at"` // 最近一次到达航点或剩余航程明显减少的时间
+
+	Stalled   bool       `json:"stalled"` // 超过时限没有推进
+	StalledAt *time.Time `json:"stalled_at,omitempty"`
+}
+
+// WaypointArrival 航点到达记录，停留时间结束或离开到达半径后视为完成
+type WaypointArrival struct {
+	Index       int        `json:"index"`
+	ArrivedAt   time.Time  `json:"arrived_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// IsZero 是否尚未开始推算
+func (t TaskTracking) IsZero() bool {
+	return t.Attempt == 0
+}
+
+// Value 实现 driver.Valuer
+func (t TaskTracking) Value() (driver.Value, error) {
+	return marshalTextColumn(t, t.IsZero())
+}
+
+// Scan 实现 sql.Scanner
+func (t *TaskTracking) Scan(value interface{}) error {
+	*t = TaskTracking{}
+	return unmarshalTextColumn(value, t)
+}
+
+// Holding 正在航点停留时返回到达记录
+func (t *TaskTracking) Holding() *WaypointArrival {
+	if len(t.Arrivals) == 0 {
+		return nil
+	}
+	last := &t.Arrivals[len(t.Arrivals)-1]
+	if last.Index != t.NextWaypoint || last.CompletedAt != nil {
+		return nil
+	}
+	return last
+}
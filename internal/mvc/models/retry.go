@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// TaskRetryPolicy 按任务类型配置的失败重试策略
+type TaskRetryPolicy struct {
+	ID                  uint          `json:"id" gorm:"primaryKey"`
+	TaskType            TaskType      `json:"task_type" gorm:"uniqueIndex;not null;size:20"`
+	MaxAttempts         int           `json:"max_attempts"`                           // 含首次执行的总次数，1表示不重试
+	BackoffSeconds      int           `json:"backoff_seconds"`                        // 第一次重试前的等待时间
+	BackoffMultiplier   float64       `json:"backoff_multiplier"`                     // 之后每次重试等待时间的倍数，小于1时按1
+	MaxBackoffSeconds   int           `json:"max_backoff_seconds"`                    // 等待时间上限，0表示不限制
+	RetryableErrorCodes ErrorCodeList `json:"retryable_error_codes" gorm:"type:text"` // 为空时任何失败都可重试
+	Strategy            RetryStrategy `json:"strategy" gorm:"size:20;default:auto"`
+	ReassignErrorCodes  ErrorCodeList `json:"reassign_error_codes" gorm:"type:text"` // auto 策略下遇到这些错误码时更换无人机
+	ResumeFromWaypoint  bool          `json:"resume_from_waypoint"`                  // 从上次最后完成的航点之后继续执行
+	CreatedAt           time.Time     `json:"created_at"`
+	UpdatedAt           time.Time     `json:"updated_at"`
+}
+
+// RetryStrategy 重试时的无人机选择策略
+type RetryStrategy string
+
+const (
+	RetrySameDrone RetryStrategy = "same_drone" // 始终使用原无人机
+	RetryReassign  RetryStrategy = "reassign"   // 始终由分配引擎选择其他无人机
+	RetryAuto      RetryStrategy = "auto"       // 原无人机可用且错误码不要求更换时使用原无人机，否则重新分配
+)
+
+// IsValid 检查重试策略是否合法
+func (s RetryStrategy) IsValid() bool {
+	switch s {
+	case RetrySameDrone, RetryReassign, RetryAuto:
+		return true
+	}
+	return false
+}
+
+// ErrorCodeList 错误码列表，以JSON存储在text列中
+type ErrorCodeList []string
+
+// Value 实现 driver.Valuer
+func (l ErrorCodeList) Value() (driver.Value, error) {
+	return marshalTextColumn([]string(l), len(l) == 0)
+}
+
+// Scan 实现 sql.Scanner
+func (l *ErrorCodeList) Scan(value interface{}) error {
+	*l = nil
+	return unmarshalTextColumn(value, (*[]string)(l))
+}
+
+// Contains 是否包含错误码
+func (l ErrorCodeList) Contains(code string) bool {
+	for _, c := range l {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// TableName 指定表名
+func (TaskRetryPolicy) TableName() string {
+	return "task_retry_policies"
+}
+
+// Retryable 失败的错误码是否允许重试
+func (p *TaskRetryPolicy) Retryable(code string) bool {
+	return len(p.RetryableErrorCodes) == 0 || p.RetryableErrorCodes.Contains(code)
+}
+
+// Backoff 第 attempt 次执行失败后到下一次执行的等待时间
+func (p *TaskRetryPolicy) Backoff(attempt int) time.Duration {
+	delay := float64(p.BackoffSeconds)
+	multiplier := p.BackoffMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	for i := 1; i < attempt; i++ {
+		delay *= multiplier
+		if p.MaxBackoffSeconds > 0 && delay >= float64(p.MaxBackoffSeconds) {
+			break
+		}
+	}
+	if p.MaxBackoffSeconds > 0 && delay > float64(p.MaxBackoffSeconds) {
+		delay = float64(p.MaxBackoffSeconds)
+	}
+	return time.Duration(delay * float64(time.Second))
+}
+
+// TaskAttempt 任务的一次执行记录，重试复用原任务，每次执行结束后追加一条
+type TaskAttempt struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	TaskID      uint       `json:"task_id" gorm:"not null;uniqueIndex:idx_task_attempt"`
+	Attempt     int        `json:"attempt" gorm:"not null;uniqueIndex:idx_task_attempt"` // 从1开始
+	DroneID     uint       `json:"drone_id" gorm:"index"`
+	Status      TaskStatus `json:"status" gorm:"size:20"` // 本次执行的结束状态
+	ErrorCode   string     `json:"error_code,omitempty" gorm:"size:50"`
+	ErrorDetail string     `json:"error_detail,omitempty" gorm:"type:text"`
+
+	// 本次执行的起始航点下标，以及根据轨迹判断已完成的最后一个航点下标（-1表示未完成任何航点）
+	StartWaypoint int `json:"start_waypoint"`
+	LastWaypoint  int `json:"last_waypoint"`
+
+	// 失败后的处理结果
+	Decision      RetryDecision `json:"decision" gorm:"size:20"`
+	Reason        string        `json:"reason,omitempty" gorm:"type:text"`
+	NextDroneID   *uint         `json:"next_drone_id,omitempty"`
+	NextAttemptAt *time.Time    `json:"next_attempt_at,omitempty"`
+
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// RetryDecision 执行结束后的重试决定
+type RetryDecision string
+
+const (
+	RetryDecisionNone         RetryDecision = "none"          // 执行成功或被取消，不需要重试
+	RetryDecisionRetry        RetryDecision = "retry"         // 在原无人机上重试
+	RetryDecisionReassign     RetryDecision = "reassign"      // 更换无人机重试
+	RetryDecisionNoPolicy     RetryDecision = "no_policy"     // 任务类型没有重试策略
+	RetryDecisionNotRetryable RetryDecision = "not_retryable" // 错误码不在可重试列表中
+	RetryDecisionExhausted    RetryDecision = "exhausted"     // 已达到最大执行次数
+	RetryDecisionNoDrone      RetryDecision = "no_drone"      // 没有可用的无人机
+)
+
+// TableName 指定表名
+func (TaskAttempt) TableName() string {
+	return "task_attempts"
+}
@@ -22,10 +22,43 @@ type Task struct {
 	User  User  `json:"user" gorm:"foreignKey:UserID"`
 	Drone Drone `json:"drone" gorm:"foreignKey:DroneID"`
 
+	// 任务所需无人机能力（JSON格式的CapabilityRequirements）
+	RequiredCapabilities string `json:"required_capabilities" gorm:"type:text"`
+
 	// 任务计划和结果
 	Plan   TaskPlan   `json:"plan" gorm:"embedded;embeddedPrefix:plan_"`
 	Result TaskResult `json:"result" gorm:"embedded;embeddedPrefix:result_"`
 
+	// 能耗估算，启动时按当时电量更新，完成后回填实际耗电
+	Energy EnergyEstimate `json:"energy" gorm:"type:text"`
+
+	// 根据遥测位置推算的航点完成情况、预计完成时间和停滞状态
+	Tracking TaskTracking `json:"tracking" gorm:"type:text"`
+
+	// 配送任务的取送地点、收件人、交付方式和状态节点
+	Delivery TaskDelivery `json:"delivery" gorm:"type:text"`
+
+	// 命中审批规则的任务的审批状态和审批记录
+	Approval TaskApproval `json:"approval" gorm:"type:text"`
+
+	// 自动分配的任务在无人机启动前不可用时会被重新分配
+	AutoAssigned   bool   `json:"auto_assigned" gorm:"default:false"`
+	AssignmentNote string `json:"assignment_note" gorm:"type:text"` // 自动分配的选择说明
+
+	// 周期任务生成的实例记录所属的周期计划
+	ScheduleID *uint `json:"schedule_id" gorm:"index"`
+
+	// 流程步骤生成的任务记录所属的流程实例
+	WorkflowRunID *uint `json:"workflow_run_id" gorm:"index"`
+
+	// 由任务模板实例化的任务记录模板及其版本
+	TemplateID      *uint `json:"template_id" gorm:"index"`
+	TemplateVersion int   `json:"template_version"`
+
+	// 失败重试复用原任务：Attempt 为当前第几次执行，ResumeWaypoint 为本次执行的起始航点下标
+	Attempt        int `json:"attempt" gorm:"default:1"`
+	ResumeWaypoint int `json:"resume_waypoint" gorm:"default:0"`
+
 	// 时间字段
 	ScheduledAt *time.Time `json:"scheduled_at"`
 	StartedAt   *time.Time `json:"started_at"`
@@ -47,12 +80,13 @@ const (
 type TaskStatus string
 
 const (
-	TaskStatusPending   TaskStatus = "pending"
-	TaskStatusScheduled TaskStatus = "scheduled"
-	TaskStatusRunning   TaskStatus = "running"
-	TaskStatusCompleted TaskStatus = "completed"
-	TaskStatusFailed    TaskStatus = "failed"
-	TaskStatusCancelled TaskStatus = "cancelled"
+	TaskStatusPending         TaskStatus = "pending"
+	TaskStatusPendingApproval TaskStatus = "pending_approval" // 等待管理员审批，审批通过后回到 pending
+	TaskStatusScheduled       TaskStatus = "scheduled"
+	TaskStatusRunning         TaskStatus = "running"
+	TaskStatusCompleted       TaskStatus = "completed"
+	TaskStatusFailed          TaskStatus = "failed"
+	TaskStatusCancelled       TaskStatus = "cancelled"
 )
 
 // TaskPriority 任务优先级
@@ -67,12 +101,25 @@ const (
 
 // TaskPlan 任务计划
 type TaskPlan struct {
-	Route       string  `json:"route" gorm:"type:text"`     // JSON格式的路径点
-	Waypoints   string  `json:"waypoints" gorm:"type:text"` // JSON格式的航点
-	MaxAltitude float64 `json:"max_altitude" gorm:"type:decimal(8,2)"`
-	MaxSpeed    float64 `json:"max_speed" gorm:"type:decimal(5,2)"`
-	Duration    int     `json:"duration"`                 // 预计执行时间（分钟）
-	Payload     string  `json:"payload" gorm:"type:text"` // JSON格式的载荷配置
+	Route       string         `json:"route" gorm:"type:text"`     // JSON格式的路径点
+	Waypoints   WaypointList   `json:"waypoints" gorm:"type:text"` // 有序航点，创建和更新时校验
+	MaxAltitude float64        `json:"max_altitude" gorm:"type:decimal(8,2)"`
+	MaxSpeed    float64        `json:"max_speed" gorm:"type:decimal(5,2)"`
+	Duration    int            `json:"duration"`                 // 预计执行时间（分钟），为0时按能耗估算填写
+	MinBattery  int            `json:"min_battery"`              // 任务结束时需保留的电量（%），为0时使用默认值
+	Payload     MissionPayload `json:"payload" gorm:"type:text"` // 载荷配置
+}
+
+// Waypoint 航点
+type Waypoint struct {
+	Latitude  float64          `json:"latitude"`
+	Longitude float64          `json:"longitude"`
+	Altitude  float64          `json:"altitude"`            // 相对起飞点高度（米）
+	Speed     float64          `json:"speed,omitempty"`     // 飞向该航点的速度（m/s），为0时使用默认速度
+	HoldTime  float64          `json:"hold_time,omitempty"` // 到达后停留时间（秒）
+	Heading   *float64         `json:"heading,omitempty"`   // 到达后机头朝向（度），为空时不调整
+	Actions   []WaypointAction `json:"actions,omitempty"`
+	Name      string           `json:"name,omitempty"`
 }
 
 // TaskResult 任务结果
@@ -84,6 +131,8 @@ type TaskResult struct {
 	Statistics  string `json:"statistics" gorm:"type:text"` // JSON格式的统计信息
 	ErrorCode   string `json:"error_code" gorm:"size:50"`
 	ErrorDetail string `json:"error_detail" gorm:"type:text"`
+
+	Proof DeliveryProof `json:"proof" gorm:"type:text"` // 配送任务的交付证明
 }
 
 // TableName 指定表名
@@ -101,6 +150,15 @@ func (t *Task) IsCompleted() bool {
 	return t.Status == TaskStatusCompleted || t.Status == TaskStatusFailed || t.Status == TaskStatusCancelled
 }
 
+// RemainingPlan 本次执行的计划，重试从 ResumeWaypoint 继续时只包含剩余航点
+func (t *Task) RemainingPlan() TaskPlan {
+	plan := t.Plan
+	if t.ResumeWaypoint > 0 && t.ResumeWaypoint < len(plan.Waypoints) {
+		plan.Waypoints = plan.Waypoints[t.ResumeWaypoint:]
+	}
+	return plan
+}
+
 // CanStart 检查任务是否可以开始
 func (t *Task) CanStart() bool {
 	return t.Status == TaskStatusPending || t.Status == TaskStatusScheduled
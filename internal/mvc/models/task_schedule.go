@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+// TaskSchedule 周期任务计划，按cron规则从任务定义生成任务实例
+type TaskSchedule struct {
+	BaseModel
+	Name     string          `json:"name" gorm:"not null;size:100"`
+	CronExpr string          `json:"cron_expr" gorm:"not null;size:100"` // 5字段cron表达式，如 "0 9 * * mon-fri"
+	Timezone string          `json:"timezone" gorm:"size:64"`            // IANA时区，为空时使用UTC
+	Enabled  bool            `json:"enabled" gorm:"index"`
+	Policy   MissedRunPolicy `json:"missed_run_policy" gorm:"column:missed_run_policy;default:run_once;size:20"`
+
+	// 生效区间，为空表示不限制
+	StartAt *time.Time `json:"start_at"`
+	EndAt   *time.Time `json:"end_at"`
+
+	// 任务定义，生成实例时复制
+	TaskName             string       `json:"task_name" gorm:"not null;size:100"`
+	TaskDescription      string       `json:"task_description" gorm:"type:text"`
+	TaskType             TaskType     `json:"task_type" gorm:"not null;size:20"`
+	TaskPriority         TaskPriority `json:"task_priority" gorm:"default:normal;size:20"`
+	DroneID              uint         `json:"drone_id" gorm:"not null"`
+	RequiredCapabilities string       `json:"required_capabilities" gorm:"type:text"` // JSON格式的CapabilityRequirements
+	Plan                 TaskPlan     `json:"plan" gorm:"embedded;embeddedPrefix:plan_"`
+	TaskDelivery         TaskDelivery `json:"task_delivery" gorm:"type:text"` // 配送任务的取送信息
+
+	CreatedBy  uint       `json:"created_by" gorm:"not null"`
+	NextRunAt  *time.Time `json:"next_run_at" gorm:"index"`
+	LastRunAt  *time.Time `json:"last_run_at"`
+	LastTaskID *uint      `json:"last_task_id"`
+	RunCount   int        `json:"run_count"`
+	SkipCount  int        `json:"skip_count"` // 因错过执行被跳过的次数
+}
+
+// MissedRunPolicy 停机等原因错过执行时间后的处理策略
+type MissedRunPolicy string
+
+const (
+	MissedRunSkip    MissedRunPolicy = "skip"     // 跳过错过的执行
+	MissedRunOnce    MissedRunPolicy = "run_once" // 只补执行一次
+	MissedRunCatchUp MissedRunPolicy = "run_all"  // 逐次补执行（有上限）
+)
+
+// TableName 指定表名
+func (TaskSchedule) TableName() string {
+	return "task_schedules"
+}
+
+// IsValid 检查策略是否有效
+func (p MissedRunPolicy) IsValid() bool {
+	switch p {
+	case MissedRunSkip, MissedRunOnce, MissedRunCatchUp:
+		return true
+	}
+	return false
+}
+
+// Location 计划使用的时区
+func (s *TaskSchedule) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// TaskTemplate 任务模板：带参数的任务计划
+// 同名模板按版本号区分，已保存的版本不再修改，修改通过克隆生成新版本，任务记录生成它的模板版本
+type TaskTemplate struct {
+	BaseModel
+	Name        string       `json:"name" gorm:"not null;size:100;uniqueIndex:idx_task_template_version"`
+	Version     int          `json:"version" gorm:"not null;uniqueIndex:idx_task_template_version"`
+	Description string       `json:"description" gorm:"type:text"`
+	TaskName    string       `json:"task_name" gorm:"size:100"` // 生成任务的名称，可包含参数占位符，为空时使用模板名称
+	Type        TaskType     `json:"type" gorm:"not null;size:20;index"`
+	Priority    TaskPriority `json:"priority" gorm:"default:normal;size:20"`
+
+	// 以下内容为JSON，字符串值可以是 ${参数} 或 ${参数.路径} 占位符，实例化时替换为参数值
+	Plan                 TemplateBody          `json:"plan" gorm:"type:text"`                  // TaskPlan
+	RequiredCapabilities TemplateBody          `json:"required_capabilities" gorm:"type:text"` // CapabilityRequirements
+	Delivery             TemplateBody          `json:"delivery,omitempty" gorm:"type:text"`    // 配送模板的 TaskDelivery
+	Parameters           TemplateParameterList `json:"parameters" gorm:"type:text"`
+
+	SourceID  *uint `json:"source_id"` // 克隆来源的模板版本
+	CreatedBy uint  `json:"created_by" gorm:"not null"`
+}
+
+// TemplateParameterType 模板参数类型
+type TemplateParameterType string
+
+const (
+	TemplateParamNumber    TemplateParameterType = "number"
+	TemplateParamInteger   TemplateParameterType = "integer"
+	TemplateParamString    TemplateParameterType = "string"
+	TemplateParamBoolean   TemplateParameterType = "boolean"
+	TemplateParamPoint     TemplateParameterType = "point"     // 目标位置 {latitude, longitude, altitude, name}
+	TemplateParamArea      TemplateParameterType = "area"      // 区域多边形顶点列表 [{latitude, longitude}]
+	TemplateParamWaypoints TemplateParameterType = "waypoints" // 航点列表
+)
+
+// IsValid 检查参数类型是否合法
+func (t TemplateParameterType) IsValid() bool {
+	switch t {
+	case TemplateParamNumber, TemplateParamInteger, TemplateParamString, TemplateParamBoolean,
+		TemplateParamPoint, TemplateParamArea, TemplateParamWaypoints:
+		return true
+	}
+	return false
+}
+
+// TemplateParameter 模板参数定义
+type TemplateParameter struct {
+	Name        string                `json:"name"` // 占位符引用的参数名
+	Type        TemplateParameterType `json:"type"`
+	Description string                `json:"description,omitempty"`
+	Required    bool                  `json:"required,omitempty"` // 实例化时必须提供，不使用默认值
+	Default     json.RawMessage       `json:"default,omitempty"`
+	Min         *float64              `json:"min,omitempty"`  // 数值参数的下限
+	Max         *float64              `json:"max,omitempty"`  // 数值参数的上限
+	Enum        []string              `json:"enum,omitempty"` // 字符串参数的可选值
+}
+
+// TemplatePoint point 类型参数的值，未提供的字段按零值补齐，占位符可以引用任一字段
+type TemplatePoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+	Name      string  `json:"name"`
+}
+
+// TemplateParameterList 模板参数列表，以JSON存储在text列中
+type TemplateParameterList []TemplateParameter
+
+// Value 实现 driver.Valuer
+func (l TemplateParameterList) Value() (driver.Value, error) {
+	return marshalTextColumn([]TemplateParameter(l), len(l) == 0)
+}
+
+// Scan 实现 sql.Scanner
+func (l *TemplateParameterList) Scan(value interface{}) error {
+	*l = nil
+	return unmarshalTextColumn(value, (*[]TemplateParameter)(l))
+}
+
+// Find 按名称查找参数
+func (l TemplateParameterList) Find(name string) *TemplateParameter {
+	for i := range l {
+		if l[i].Name == name {
+			return &l[i]
+		}
+	}
+	return nil
+}
+
+// TemplateBody 含参数占位符的JSON内容，以原文存储在text列中
+type TemplateBody json.RawMessage
+
+// IsEmpty 是否没有内容
+func (b TemplateBody) IsEmpty() bool {
+	trimmed := bytes.TrimSpace(b)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// MarshalJSON 实现 json.Marshaler
+func (b TemplateBody) MarshalJSON() ([]byte, error) {
+	if b.IsEmpty() {
+		return []byte("null"), nil
+	}
+	return b, nil
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler
+func (b *TemplateBody) UnmarshalJSON(data []byte) error {
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+// Value 实现 driver.Valuer
+func (b TemplateBody) Value() (driver.Value, error) {
+	if b.IsEmpty() {
+		return "", nil
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (b *TemplateBody) Scan(value interface{}) error {
+	data, err := scanText(value)
+	if err != nil {
+		return err
+	}
+	*b = append(TemplateBody(nil), data...)
+	return nil
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+)
+
+// DroneTelemetry 无人机遥测时序数据
+// 同一张表按精度档位分层存储：原始点、1分钟聚合、1小时聚合
+// 表按 (resolution, recorded_at) 做 RANGE COLUMNS 分区，分区列必须包含在主键中
+type DroneTelemetry struct {
+	ID          uint                `json:"-" gorm:"primaryKey;autoIncrement"`
+	DroneID     uint                `json:"drone_id" gorm:"not null;index:idx_telemetry_series,priority:1"`
+	Resolution  TelemetryResolution `json:"resolution" gorm:"primaryKey;not null;size:8;index:idx_telemetry_series,priority:2"`
+	RecordedAt  time.Time           `json:"recorded_at" gorm:"primaryKey;not null;index:idx_telemetry_series,priority:3"`
+	Latitude    float64             `json:"latitude" gorm:"type:decimal(10,8)"`
+	Longitude   float64             `json:"longitude" gorm:"type:decimal(11,8)"`
+	Altitude    float64             `json:"altitude" gorm:"type:decimal(8,2)"`
+	Heading     float64             `json:"heading" gorm:"type:decimal(5,2)"`
+	Speed       float64             `json:"speed" gorm:"type:decimal(6,2)"` // 地速 m/s
+	Battery     int                 `json:"battery"`                        // 电量百分比，-1表示未知
+	SampleCount int                 `json:"sample_count" gorm:"default:1"`  // 聚合点包含的原始点数
+	CreatedAt   time.Time           `json:"-"`
+}
+
+// TelemetryResolution 遥测数据精度
+type TelemetryResolution string
+
+const (
+	TelemetryResolutionRaw    TelemetryResolution = "raw"
+	TelemetryResolutionMinute TelemetryResolution = "1m"
+	TelemetryResolutionHour   TelemetryResolution = "1h"
+)
+
+// TableName 指定表名
+func (DroneTelemetry) TableName() string {
+	return "drone_telemetry"
+}
+
+// BucketSize 聚合档位对应的时间桶大小
+func (r TelemetryResolution) BucketSize() time.Duration {
+	switch r {
+	case TelemetryResolutionMinute:
+		return time.Minute
+	case TelemetryResolutionHour:
+		return time.Hour
+	default:
+		return 0
+	}
+}
+
+// Coarser 下一个更粗的聚合档位，已是最粗档位时返回空
+func (r TelemetryResolution) Coarser() TelemetryResolution {
+	switch r {
+	case TelemetryResolutionRaw:
+		return TelemetryResolutionMinute
+	case TelemetryResolutionMinute:
+		return TelemetryResolutionHour
+	default:
+		return ""
+	}
+}
+
+// IsValid 检查精度档位是否合法
+func (r TelemetryResolution) IsValid() bool {
+	switch r {
+	case TelemetryResolutionRaw, TelemetryResolutionMinute, TelemetryResolutionHour:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasBattery 是否包含电量数据
+func (t *DroneTelemetry) HasBattery() bool {
+	return t.Battery >= 0
+}
+
+// ToPosition 转换为位置信息
+func (t *DroneTelemetry) ToPosition() Position {
+	return Position{
+		Latitude:  t.Latitude,
+		Longitude: t.Longitude,
+		Altitude:  t.Altitude,
+		Heading:   t.Heading,
+	}
+}
@@ -38,6 +38,25 @@ const (
 	RoleViewer   UserRole = "viewer"
 )
 
+// Level 角色权限级别: admin > operator > viewer，未知角色为0
+func (r UserRole) Level() int {
+	switch r {
+	case RoleAdmin:
+		return 3
+	case RoleOperator:
+		return 2
+	case RoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// IsValid 检查角色是否合法
+func (r UserRole) IsValid() bool {
+	return r.Level() > 0
+}
+
 // UserStatus 用户状态
 type UserStatus string
 
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// WorkflowDefinition 多步骤任务流程定义，由任务模板和依赖关系组成的有向无环图
+type WorkflowDefinition struct {
+	BaseModel
+	Name        string           `json:"name" gorm:"not null;size:100"`
+	Description string           `json:"description" gorm:"type:text"`
+	Steps       WorkflowStepList `json:"steps" gorm:"type:text"`
+	CreatedBy   uint             `json:"created_by" gorm:"not null"`
+}
+
+// WorkflowStep 流程中的一个任务模板
+type WorkflowStep struct {
+	Key                  string                  `json:"key"` // 流程内唯一标识，依赖和数据传递引用该值
+	TaskName             string                  `json:"task_name"`
+	TaskDescription      string                  `json:"task_description,omitempty"`
+	TaskType             TaskType                `json:"task_type"`
+	TaskPriority         TaskPriority            `json:"task_priority,omitempty"`
+	DroneID              uint                    `json:"drone_id,omitempty"` // 为0时由分配引擎自动选择
+	Plan                 TaskPlan                `json:"plan"`
+	RequiredCapabilities *CapabilityRequirements `json:"required_capabilities,omitempty"`
+	Delivery             *TaskDelivery           `json:"delivery,omitempty"` // 配送步骤的取送信息
+	DependsOn            []WorkflowDependency    `json:"depends_on,omitempty"`
+	Inputs               []WorkflowInput         `json:"inputs,omitempty"`
+}
+
+// WorkflowDependency 步骤依赖，上游步骤结束且满足条件后才执行
+type WorkflowDependency struct {
+	Step      string            `json:"step"`
+	Condition WorkflowCondition `json:"condition,omitempty"` // 为空时为 on_success
+}
+
+// WorkflowCondition 依赖条件，按上游任务的 Result.Success 分支
+type WorkflowCondition string
+
+const (
+	WorkflowOnSuccess WorkflowCondition = "on_success" // 上游任务成功
+	WorkflowOnFailure WorkflowCondition = "on_failure" // 上游任务失败或被取消
+	WorkflowAlways    WorkflowCondition = "always"     // 上游步骤结束即可（包括被跳过）
+)
+
+// WorkflowInput 将上游任务 Result.Data 中的值写入本步骤计划
+type WorkflowInput struct {
+	Step     string `json:"step"`               // 上游步骤，必须是本步骤的直接或间接依赖
+	Path     string `json:"path,omitempty"`     // Result.Data 中的点分路径，如 "hotspots" 或 "points.0"，为空时取整个结果
+	Target   string `json:"target"`             // 计划字段，如 waypoints、payload、max_altitude
+	Optional bool   `json:"optional,omitempty"` // 上游没有该值时保留模板中的计划
+}
+
+// WorkflowInputTargets 可以由上游结果写入的计划字段
+var WorkflowInputTargets = map[string]bool{
+	"route":        true,
+	"waypoints":    true,
+	"max_altitude": true,
+	"max_speed":    true,
+	"duration":     true,
+	"min_battery":  true,
+	"payload":      true,
+}
+
+// WorkflowStepList 流程步骤列表，以JSON存储在text列中
+type WorkflowStepList []WorkflowStep
+
+// Value 实现 driver.Valuer
+func (l WorkflowStepList) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal([]WorkflowStep(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner
+func (l *WorkflowStepList) Scan(value interface{}) error {
+	data, err := scanText(value)
+	if err != nil || len(data) == 0 {
+		*l = nil
+		return err
+	}
+	return json.Unmarshal(data, (*[]WorkflowStep)(l))
+}
+
+// Find 按标识查找步骤
+func (l WorkflowStepList) Find(key string) *WorkflowStep {
+	for i := range l {
+		if l[i].Key == key {
+			return &l[i]
+		}
+	}
+	return nil
+}
+
+// EffectiveCondition 生效的依赖条件，为空时为 on_success
+func (d WorkflowDependency) EffectiveCondition() WorkflowCondition {
+	if d.Condition == "" {
+		return WorkflowOnSuccess
+	}
+	return d.Condition
+}
+
+// IsValid 检查依赖条件是否合法
+func (c WorkflowCondition) IsValid() bool {
+	switch c {
+	case "", WorkflowOnSuccess, WorkflowOnFailure, WorkflowAlways:
+		return true
+	}
+	return false
+}
+
+// WorkflowRun 流程执行实例，保存启动时的步骤快照，修改定义不影响已启动的实例
+type WorkflowRun struct {
+	BaseModel
+	DefinitionID uint               `json:"definition_id" gorm:"not null;index"`
+	Name         string             `json:"name" gorm:"not null;size:100"`
+	Status       WorkflowRunStatus  `json:"status" gorm:"default:running;size:20;index"`
+	Progress     int                `json:"progress" gorm:"default:0"`
+	Steps        WorkflowStepList   `json:"-" gorm:"type:text"`
+	StepRuns     []*WorkflowRunStep `json:"steps" gorm:"foreignKey:RunID"`
+	Error        string             `json:"error,omitempty" gorm:"type:text"`
+	CreatedBy    uint               `json:"created_by" gorm:"not null"`
+
+	// 自动分配无人机的步骤可选择的机队范围（JSON格式的FleetScope），为空时不限制
+	AssignmentScope string `json:"-" gorm:"type:text"`
+
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
+// WorkflowRunStatus 流程实例状态
+type WorkflowRunStatus string
+
+const (
+	WorkflowRunRunning   WorkflowRunStatus = "running"
+	WorkflowRunCompleted WorkflowRunStatus = "completed"
+	WorkflowRunFailed    WorkflowRunStatus = "failed"
+	WorkflowRunCancelled WorkflowRunStatus = "cancelled"
+)
+
+// WorkflowRunStep 流程实例中单个步骤的执行状态
+type WorkflowRunStep struct {
+	ID          uint               `json:"id" gorm:"primaryKey"`
+	RunID       uint               `json:"run_id" gorm:"not null;uniqueIndex:idx_workflow_run_step"`
+	StepKey     string             `json:"step_key" gorm:"not null;size:64;uniqueIndex:idx_workflow_run_step"`
+	Status      WorkflowStepStatus `json:"status" gorm:"default:pending;size:20"`
+	TaskID      *uint              `json:"task_id" gorm:"index"`
+	Progress    int                `json:"progress"`
+	Error       string             `json:"error,omitempty" gorm:"type:text"`
+	StartedAt   *time.Time         `json:"started_at"`
+	CompletedAt *time.Time         `json:"completed_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// WorkflowStepStatus 步骤执行状态
+type WorkflowStepStatus string
+
+const (
+	WorkflowStepPending   WorkflowStepStatus = "pending"
+	WorkflowStepRunning   WorkflowStepStatus = "running" // 已创建任务，任务可能尚在等待启动
+	WorkflowStepCompleted WorkflowStepStatus = "completed"
+	WorkflowStepFailed    WorkflowStepStatus = "failed"
+	WorkflowStepSkipped   WorkflowStepStatus = "skipped" // 依赖条件不满足
+	WorkflowStepCancelled WorkflowStepStatus = "cancelled"
+)
+
+// TableName 指定表名
+func (WorkflowDefinition) TableName() string {
+	return "workflow_definitions"
+}
+
+// TableName 指定表名
+func (WorkflowRun) TableName() string {
+	return "workflow_runs"
+}
+
+// TableName 指定表名
+func (WorkflowRunStep) TableName() string {
+	return "workflow_run_steps"
+}
+
+// IsFinished 流程实例是否已结束
+func (r *WorkflowRun) IsFinished() bool {
+	return r.Status == WorkflowRunCompleted || r.Status == WorkflowRunFailed || r.Status == WorkflowRunCancelled
+}
+
+// IsFinished 步骤是否已结束
+func (s *WorkflowRunStep) IsFinished() bool {
+	switch s.Status {
+	case WorkflowStepCompleted, WorkflowStepFailed, WorkflowStepSkipped, WorkflowStepCancelled:
+		return true
+	}
+	return false
+}
+
+// Satisfies 上游步骤的结果是否满足依赖条件，上游未结束时返回false
+func (s *WorkflowRunStep) Satisfies(condition WorkflowCondition) bool {
+	switch condition {
+	case WorkflowOnFailure:
+		return s.Status == WorkflowStepFailed || s.Status == WorkflowStepCancelled
+	case WorkflowAlways:
+		return s.IsFinished()
+	default:
+		return s.Status == WorkflowStepCompleted
+	}
+}
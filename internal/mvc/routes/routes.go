@@ -3,6 +3,7 @@ package routes
 import (
 	"drone-control-system/internal/mvc/controllers"
 	"drone-control-system/internal/mvc/middleware"
+	"drone-control-system/internal/mvc/models"
 	"drone-control-system/internal/mvc/services"
 	"drone-control-system/pkg/logger"
 
@@ -11,13 +12,25 @@ import (
 
 // Router 路由管理器
 type Router struct {
-	engine           *gin.Engine
-	logger           *logger.Logger
-	authMiddleware   *middleware.AuthMiddleware
-	userController   *controllers.UserController
-	droneController  *controllers.DroneController
-	websocketService services.WebSocketService
-	// taskController   *controllers.TaskController
+	engine                *gin.Engine
+	logger                *logger.Logger
+	authMiddleware        *middleware.AuthMiddleware
+	deviceAuthMiddleware  *middleware.DeviceAuthMiddleware
+	userController        *controllers.UserController
+	droneController       *controllers.DroneController
+	telemetryController   *controllers.TelemetryController
+	geoController         *controllers.GeoController
+	firmwareController    *controllers.FirmwareController
+	maintenanceController *controllers.MaintenanceController
+	batteryController     *controllers.BatteryController
+	fleetController       *controllers.FleetController
+	deviceController      *controllers.DeviceController
+	scheduleController    *controllers.ScheduleController
+	geofenceController    *controllers.GeofenceController
+	workflowController    *controllers.WorkflowController
+	artifactController    *controllers.ArtifactController
+	websocketService      services.WebSocketService
+	taskController        *controllers.TaskController
 	// alertController  *controllers.AlertController
 }
 
@@ -25,8 +38,21 @@ type Router struct {
 func NewRouter(
 	logger *logger.Logger,
 	authMiddleware *middleware.AuthMiddleware,
+	deviceAuthMiddleware *middleware.DeviceAuthMiddleware,
 	userController *controllers.UserController,
 	droneController *controllers.DroneController,
+	taskController *controllers.TaskController,
+	telemetryController *controllers.TelemetryController,
+	geoController *controllers.GeoController,
+	firmwareController *controllers.FirmwareController,
+	maintenanceController *controllers.MaintenanceController,
+	batteryController *controllers.BatteryController,
+	fleetController *controllers.FleetController,
+	deviceController *controllers.DeviceController,
+	scheduleController *controllers.ScheduleController,
+	geofenceController *controllers.GeofenceController,
+	workflowController *controllers.WorkflowController,
+	artifactController *controllers.ArtifactController,
 	websocketService services.WebSocketService,
 ) *Router {
 	// 设置Gin模式
@@ -35,12 +61,25 @@ func NewRouter(
 	engine := gin.New()
 
 	return &Router{
-		engine:           engine,
-		logger:           logger,
-		authMiddleware:   authMiddleware,
-		userController:   userController,
-		droneController:  droneController,
-		websocketService: websocketService,
+		engine:                engine,
+		logger:                logger,
+		authMiddleware:        authMiddleware,
+		deviceAuthMiddleware:  deviceAuthMiddleware,
+		userController:        userController,
+		droneController:       droneController,
+		taskController:        taskController,
+		telemetryController:   telemetryController,
+		geoController:         geoController,
+		firmwareController:    firmwareController,
+		maintenanceController: maintenanceController,
+		batteryController:     batteryController,
+		fleetController:       fleetController,
+		deviceController:      deviceController,
+		scheduleController:    scheduleController,
+		geofenceController:    geofenceController,
+		workflowController:    workflowController,
+		artifactController:    artifactController,
+		websocketService:      websocketService,
 	}
 }
 
@@ -64,6 +103,7 @@ func (r *Router) SetupRoutes() {
 		public := v1.Group("/public")
 		{
 			public.POST("/login", r.userController.Login)
+			public.GET("/artifacts/:id/download", r.artifactController.DownloadSignedArtifact)
 			// public.POST("/register", r.userController.Register) // 如果需要公开注册
 		}
 
@@ -77,16 +117,53 @@ func (r *Router) SetupRoutes() {
 			// 无人机相关路由
 			r.setupDroneRoutes(protected)
 
+			// 遥测轨迹路由
+			r.setupTelemetryRoutes(protected)
+
+			// 实时位置查询路由
+			r.setupGeoRoutes(protected)
+
 			// 任务相关路由
-			// r.setupTaskRoutes(protected)
+			r.setupTaskRoutes(protected)
+
+			// 周期任务计划路由
+			r.setupScheduleRoutes(protected)
+
+			// 多步骤流程路由
+			r.setupWorkflowRoutes(protected)
+
+			// 地理围栏路由
+			r.setupGeofenceRoutes(protected)
+
+			// 固件管理路由
+			r.setupFirmwareRoutes(protected)
+
+			// 维护管理路由
+			r.setupMaintenanceRoutes(protected)
+
+			// 电池组管理路由
+			r.setupBatteryRoutes(protected)
+
+			// 机队管理路由
+			r.setupFleetRoutes(protected)
+
+			// 设备凭证管理路由
+			r.setupDeviceCredentialRoutes(protected)
 
 			// 告警相关路由
 			// r.setupAlertRoutes(protected)
 		}
+
+		// 设备路由（设备凭证认证，只能访问自己的无人机）
+		device := v1.Group("/device/drones/:id")
+		device.Use(r.deviceAuthMiddleware.RequireDevice(), r.deviceAuthMiddleware.RequireOwnDrone("id"))
+		{
+			r.setupDeviceRoutes(device)
+		}
 	}
 
-	// WebSocket路由（如果需要）
-	r.engine.GET("/ws", r.handleWebSocket)
+	// WebSocket路由（可选认证，匿名连接只接收未分配机队的无人机事件）
+	r.engine.GET("/ws", r.authMiddleware.OptionalAuth(), r.handleWebSocket)
 }
 
 // setupUserRoutes 设置用户路由
@@ -127,6 +204,7 @@ func (r *Router) setupDroneRoutes(rg *gin.RouterGroup) {
 			operatorDrones.PUT("/:id/status", r.droneController.UpdateDroneStatus)
 			operatorDrones.PUT("/:id/position", r.droneController.UpdateDronePosition)
 			operatorDrones.PUT("/:id/battery", r.droneController.UpdateDroneBattery)
+			operatorDrones.POST("/:id/commands", r.droneController.SendCommand)
 		}
 
 		// 删除无人机（仅管理员）
@@ -137,8 +215,32 @@ func (r *Router) setupDroneRoutes(rg *gin.RouterGroup) {
 	}
 }
 
+// setupTelemetryRoutes 设置遥测轨迹路由
+func (r *Router) setupTelemetryRoutes(rg *gin.RouterGroup) {
+	drones := rg.Group("/drones")
+	{
+		drones.GET("/:id/track", r.telemetryController.GetDroneTrack)
+		drones.GET("/:id/track/export", r.telemetryController.ExportDroneTrack)
+	}
+
+	tasks := rg.Group("/tasks")
+	{
+		tasks.GET("/:id/track", r.telemetryController.GetTaskTrack)
+		tasks.GET("/:id/track/export", r.telemetryController.ExportTaskTrack)
+	}
+}
+
+// setupGeoRoutes 设置实时位置查询路由
+func (r *Router) setupGeoRoutes(rg *gin.RouterGroup) {
+	geo := rg.Group("/drones/geo")
+	{
+		geo.GET("/radius", r.geoController.GetDronesWithinRadius)
+		geo.GET("/nearest", r.geoController.GetNearestAvailableDrones)
+		geo.GET("/bbox", r.geoController.GetDronesWithinBounds)
+	}
+}
+
 // setupTaskRoutes 设置任务路由
-/*
 func (r *Router) setupTaskRoutes(rg *gin.RouterGroup) {
 	tasks := rg.Group("/tasks")
 	{
@@ -146,25 +248,297 @@ func (r *Router) setupTaskRoutes(rg *gin.RouterGroup) {
 		tasks.GET("/", r.taskController.ListTasks)
 		tasks.GET("/my", r.taskController.GetMyTasks)
 		tasks.GET("/:id", r.taskController.GetTask)
+		tasks.GET("/:id/energy", r.taskController.GetTaskEnergy)
+		tasks.GET("/energy-calibrations", r.taskController.ListEnergyCalibrations)
+		tasks.GET("/:id/attempts", r.taskController.GetTaskAttempts)
+		tasks.GET("/:id/delivery", r.taskController.GetTaskDelivery)
+		tasks.GET("/retry-policies", r.taskController.ListRetryPolicies)
+		tasks.GET("/approval-rules", r.taskController.ListApprovalRules)
+		tasks.GET("/approval-rules/:id", r.taskController.GetApprovalRule)
+		tasks.GET("/templates", r.taskController.ListTaskTemplates)
+		tasks.GET("/templates/:id", r.taskController.GetTaskTemplate)
+		tasks.GET("/:id/artifacts", r.artifactController.ListArtifacts)
+		tasks.GET("/:id/artifacts/:artifact_id", r.artifactController.GetArtifact)
+		tasks.GET("/:id/artifacts/:artifact_id/download", r.artifactController.DownloadArtifact)
+		tasks.GET("/:id/artifacts/:artifact_id/url", r.artifactController.GetArtifactURL)
 
 		// 操作任务（操作员及以上）
 		operatorTasks := tasks.Use(r.authMiddleware.RequireRole("operator"))
 		{
 			operatorTasks.POST("/", r.taskController.CreateTask)
+			operatorTasks.POST("/suggest-drones", r.taskController.SuggestDrones)
+			operatorTasks.POST("/plan-route", r.taskController.PlanRoute)
+			operatorTasks.POST("/verify-route", r.taskController.VerifyRoute)
+			operatorTasks.POST("/estimate-energy", r.taskController.EstimateEnergy)
+			operatorTasks.POST("/coverage/plan", r.taskController.PlanCoverage)
+			operatorTasks.POST("/coverage", r.taskController.CreateCoverageTasks)
+			operatorTasks.POST("/templates", r.taskController.CreateTaskTemplate)
+			operatorTasks.POST("/templates/:id/clone", r.taskController.CloneTaskTemplate)
+			operatorTasks.POST("/templates/:id/instantiate", r.taskController.InstantiateTaskTemplate)
+			operatorTasks.POST("/:id/energy", r.taskController.EstimateTaskEnergy)
+			operatorTasks.POST("/:id/energy/actual", r.taskController.RecordTaskEnergy)
+			operatorTasks.GET("/:id/candidates", r.taskController.GetTaskCandidates)
 			operatorTasks.PUT("/:id", r.taskController.UpdateTask)
+			operatorTasks.GET("/:id/preflight", r.taskController.GetPreflight)
+			operatorTasks.GET("/:id/preflight/overrides", r.taskController.ListPreflightOverrides)
 			operatorTasks.POST("/:id/start", r.taskController.StartTask)
 			operatorTasks.POST("/:id/stop", r.taskController.StopTask)
 			operatorTasks.PUT("/:id/progress", r.taskController.UpdateTaskProgress)
+			operatorTasks.POST("/:id/delivery/milestones", r.taskController.RecordDeliveryMilestone)
+			operatorTasks.POST("/:id/artifacts", r.artifactController.UploadArtifact)
+			operatorTasks.DELETE("/:id/artifacts/:artifact_id", r.artifactController.DeleteArtifact)
+			operatorTasks.POST("/:id/artifacts/uploads", r.artifactController.CreateArtifactUpload)
+			operatorTasks.GET("/:id/artifacts/uploads/:upload_id", r.artifactController.GetArtifactUpload)
+			operatorTasks.PATCH("/:id/artifacts/uploads/:upload_id", r.artifactController.AppendArtifactUpload)
+			operatorTasks.DELETE("/:id/artifacts/uploads/:upload_id", r.artifactController.CancelArtifactUpload)
 		}
 
 		// 删除任务（仅管理员）
 		adminTasks := tasks.Use(r.authMiddleware.RequireRole("admin"))
 		{
 			adminTasks.DELETE("/:id", r.taskController.DeleteTask)
+			adminTasks.POST("/:id/preflight/override", r.taskController.OverridePreflight)
+			adminTasks.PUT("/retry-policies/:type", r.taskController.SetRetryPolicy)
+			adminTasks.DELETE("/retry-policies/:type", r.taskController.DeleteRetryPolicy)
+			adminTasks.POST("/approval-rules", r.taskController.CreateApprovalRule)
+			adminTasks.PUT("/approval-rules/:id", r.taskController.UpdateApprovalRule)
+			adminTasks.DELETE("/approval-rules/:id", r.taskController.DeleteApprovalRule)
+			adminTasks.POST("/:id/approve", r.taskController.ApproveTask)
+			adminTasks.POST("/:id/reject", r.taskController.RejectTask)
+			adminTasks.DELETE("/templates/:id", r.taskController.DeleteTaskTemplate)
 		}
 	}
 }
-*/
+
+// setupScheduleRoutes 设置周期任务计划路由
+func (r *Router) setupScheduleRoutes(rg *gin.RouterGroup) {
+	schedules := rg.Group("/task-schedules")
+	{
+		// 查看计划（所有用户，再按机队角色过滤）
+		schedules.GET("", r.scheduleController.ListSchedules)
+		schedules.GET("/preview", r.scheduleController.PreviewSchedule)
+		schedules.GET("/:id", r.scheduleController.GetSchedule)
+		schedules.GET("/:id/tasks", r.scheduleController.GetScheduleTasks)
+	}
+
+	// 管理计划（操作员及以上）
+	operatorSchedules := rg.Group("/task-schedules")
+	operatorSchedules.Use(r.authMiddleware.RequireRole("operator"))
+	{
+		operatorSchedules.POST("", r.scheduleController.CreateSchedule)
+		operatorSchedules.PUT("/:id", r.scheduleController.UpdateSchedule)
+		operatorSchedules.DELETE("/:id", r.scheduleController.DeleteSchedule)
+	}
+}
+
+// setupWorkflowRoutes 设置多步骤流程路由
+func (r *Router) setupWorkflowRoutes(rg *gin.RouterGroup) {
+	workflows := rg.Group("/workflows")
+	{
+		// 查看流程定义（所有用户）
+		workflows.GET("", r.workflowController.ListWorkflows)
+		workflows.GET("/:id", r.workflowController.GetWorkflow)
+	}
+
+	runs := rg.Group("/workflow-runs")
+	{
+		// 查看流程实例状态和进度（所有用户）
+		runs.GET("", r.workflowController.ListWorkflowRuns)
+		runs.GET("/:id", r.workflowController.GetWorkflowRun)
+		runs.GET("/:id/tasks", r.workflowController.GetWorkflowRunTasks)
+	}
+
+	// 管理和启动流程（操作员及以上，再按步骤无人机的机队角色校验）
+	operatorWorkflows := rg.Group("/workflows")
+	operatorWorkflows.Use(r.authMiddleware.RequireRole("operator"))
+	{
+		operatorWorkflows.POST("", r.workflowController.CreateWorkflow)
+		operatorWorkflows.PUT("/:id", r.workflowController.UpdateWorkflow)
+		operatorWorkflows.DELETE("/:id", r.workflowController.DeleteWorkflow)
+		operatorWorkflows.POST("/:id/runs", r.workflowController.StartWorkflow)
+	}
+
+	operatorRuns := rg.Group("/workflow-runs")
+	operatorRuns.Use(r.authMiddleware.RequireRole("operator"))
+	{
+		operatorRuns.POST("/:id/cancel", r.workflowController.CancelWorkflowRun)
+	}
+}
+
+// setupGeofenceRoutes 设置地理围栏路由
+func (r *Router) setupGeofenceRoutes(rg *gin.RouterGroup) {
+	geofences := rg.Group("/geofences")
+	{
+		// 查看围栏和检查航线（所有用户）
+		geofences.GET("", r.geofenceController.ListGeofences)
+		geofences.GET("/:id", r.geofenceController.GetGeofence)
+		geofences.POST("/check-plan", r.geofenceController.CheckPlan)
+	}
+
+	// 管理围栏（管理员）
+	adminGeofences := rg.Group("/geofences")
+	adminGeofences.Use(r.authMiddleware.RequireRole("admin"))
+	{
+		adminGeofences.POST("", r.geofenceController.CreateGeofence)
+		adminGeofences.PUT("/:id", r.geofenceController.UpdateGeofence)
+		adminGeofences.DELETE("/:id", r.geofenceController.DeleteGeofence)
+	}
+}
+
+// setupFirmwareRoutes 设置固件管理路由
+func (r *Router) setupFirmwareRoutes(rg *gin.RouterGroup) {
+	firmware := rg.Group("/firmware")
+	{
+		// 查看固件目录、合规报告和发布活动（所有用户）
+		firmware.GET("/releases", r.firmwareController.ListReleases)
+		firmware.GET("/releases/:id", r.firmwareController.GetRelease)
+		firmware.GET("/compliance", r.firmwareController.GetComplianceReport)
+		firmware.GET("/rollouts", r.firmwareController.ListRollouts)
+		firmware.GET("/rollouts/:id", r.firmwareController.GetRollout)
+	}
+
+	// 回报升级结果（操作员及以上）
+	operatorFirmware := rg.Group("/firmware")
+	operatorFirmware.Use(r.authMiddleware.RequireRole("operator"))
+	{
+		operatorFirmware.POST("/rollouts/:id/targets/:drone_id/result", r.firmwareController.ReportUpdateResult)
+	}
+
+	// 管理固件和发布活动（仅管理员）
+	adminFirmware := rg.Group("/firmware")
+	adminFirmware.Use(r.authMiddleware.RequireRole("admin"))
+	{
+		adminFirmware.POST("/releases", r.firmwareController.CreateRelease)
+		adminFirmware.POST("/rollouts", r.firmwareController.CreateRollout)
+		adminFirmware.POST("/rollouts/:id/start", r.firmwareController.StartRollout)
+		adminFirmware.POST("/rollouts/:id/pause", r.firmwareController.PauseRollout)
+		adminFirmware.POST("/rollouts/:id/resume", r.firmwareController.ResumeRollout)
+		adminFirmware.POST("/rollouts/:id/abort", r.firmwareController.AbortRollout)
+	}
+}
+
+// setupMaintenanceRoutes 设置维护管理路由
+func (r *Router) setupMaintenanceRoutes(rg *gin.RouterGroup) {
+	drones := rg.Group("/drones")
+	{
+		drones.GET("/:id/maintenance", r.maintenanceController.GetDroneMaintenance)
+	}
+
+	maintenance := rg.Group("/maintenance")
+	{
+		// 查看工单和保养周期（所有用户）
+		maintenance.GET("/records", r.maintenanceController.ListRecords)
+		maintenance.GET("/records/:id", r.maintenanceController.GetRecord)
+		maintenance.GET("/intervals", r.maintenanceController.ListIntervals)
+	}
+
+	// 处理工单（操作员及以上）
+	operatorMaintenance := rg.Group("/maintenance")
+	operatorMaintenance.Use(r.authMiddleware.RequireRole("operator"))
+	{
+		operatorMaintenance.POST("/records", r.maintenanceController.CreateRecord)
+		operatorMaintenance.POST("/records/:id/start", r.maintenanceController.StartRecord)
+		operatorMaintenance.POST("/records/:id/cancel", r.maintenanceController.CancelRecord)
+	}
+
+	// 签字完成和保养周期管理（仅管理员）
+	adminMaintenance := rg.Group("/maintenance")
+	adminMaintenance.Use(r.authMiddleware.RequireRole("admin"))
+	{
+		adminMaintenance.POST("/records/:id/complete", r.maintenanceController.CompleteRecord)
+		adminMaintenance.POST("/intervals", r.maintenanceController.CreateInterval)
+		adminMaintenance.PUT("/intervals/:id", r.maintenanceController.UpdateInterval)
+		adminMaintenance.DELETE("/intervals/:id", r.maintenanceController.DeleteInterval)
+	}
+}
+
+// setupBatteryRoutes 设置电池组管理路由
+func (r *Router) setupBatteryRoutes(rg *gin.RouterGroup) {
+	drones := rg.Group("/drones")
+	{
+		drones.GET("/:id/battery-pack", r.batteryController.GetDronePack)
+	}
+
+	batteries := rg.Group("/batteries")
+	{
+		// 查看电池组（所有用户）
+		batteries.GET("", r.batteryController.ListPacks)
+		batteries.GET("/:id", r.batteryController.GetPack)
+		batteries.GET("/:id/history", r.batteryController.GetPackHistory)
+	}
+
+	// 登记和换装电池组（操作员及以上）
+	operatorBatteries := rg.Group("/batteries")
+	operatorBatteries.Use(r.authMiddleware.RequireRole("operator"))
+	{
+		operatorBatteries.POST("", r.batteryController.CreatePack)
+		operatorBatteries.PUT("/:id", r.batteryController.UpdatePack)
+		operatorBatteries.POST("/:id/install", r.batteryController.InstallPack)
+		operatorBatteries.POST("/:id/remove", r.batteryController.RemovePack)
+	}
+}
+
+// setupFleetRoutes 设置机队管理路由
+// 机队内的操作权限由控制器按机队角色校验
+func (r *Router) setupFleetRoutes(rg *gin.RouterGroup) {
+	fleets := rg.Group("/fleets")
+	{
+		// 查看机队（机队成员）
+		fleets.GET("", r.fleetController.ListFleets)
+		fleets.GET("/:id", r.fleetController.GetFleet)
+		fleets.GET("/:id/members", r.fleetController.ListMembers)
+		fleets.GET("/:id/groups", r.fleetController.ListGroups)
+	}
+
+	// 机队管理和批量操作（操作员及以上，再按机队角色校验）
+	operatorFleets := rg.Group("/fleets")
+	operatorFleets.Use(r.authMiddleware.RequireRole("operator"))
+	{
+		operatorFleets.PUT("/:id", r.fleetController.UpdateFleet)
+		operatorFleets.PUT("/:id/members/:user_id", r.fleetController.SetMember)
+		operatorFleets.DELETE("/:id/members/:user_id", r.fleetController.RemoveMember)
+		operatorFleets.POST("/:id/groups", r.fleetController.CreateGroup)
+		operatorFleets.DELETE("/:id/groups/:group_id", r.fleetController.DeleteGroup)
+		operatorFleets.POST("/:id/drones", r.fleetController.AssignDrones)
+		operatorFleets.POST("/:id/drones/remove", r.fleetController.UnassignDrones)
+		operatorFleets.PUT("/:id/status", r.fleetController.BulkUpdateStatus)
+		operatorFleets.POST("/:id/commands", r.fleetController.BulkCommand)
+	}
+
+	// 创建和删除机队（仅管理员）
+	adminFleets := rg.Group("/fleets")
+	adminFleets.Use(r.authMiddleware.RequireRole("admin"))
+	{
+		adminFleets.POST("", r.fleetController.CreateFleet)
+		adminFleets.DELETE("/:id", r.fleetController.DeleteFleet)
+	}
+}
+
+// setupDeviceCredentialRoutes 设置设备凭证管理路由
+func (r *Router) setupDeviceCredentialRoutes(rg *gin.RouterGroup) {
+	drones := rg.Group("/drones")
+	{
+		drones.GET("/:id/commands", r.deviceController.ListCommands)
+	}
+
+	// 签发、轮换和吊销凭证（仅管理员，再按机队角色校验）
+	adminDrones := rg.Group("/drones")
+	adminDrones.Use(r.authMiddleware.RequireRole("admin"))
+	{
+		adminDrones.GET("/:id/credentials", r.deviceController.ListCredentials)
+		adminDrones.POST("/:id/credentials", r.deviceController.IssueCredential)
+		adminDrones.POST("/:id/credentials/:credential_id/rotate", r.deviceController.RotateCredential)
+		adminDrones.POST("/:id/credentials/:credential_id/revoke", r.deviceController.RevokeCredential)
+	}
+}
+
+// setupDeviceRoutes 设置设备上报遥测和拉取指令的路由
+func (r *Router) setupDeviceRoutes(rg *gin.RouterGroup) {
+	rg.PUT("/position", r.droneController.ReportPosition)
+	rg.PUT("/battery", r.droneController.ReportBattery)
+	rg.GET("/commands", r.deviceController.PollCommands)
+	rg.POST("/commands/:command_id/ack", r.deviceController.AcknowledgeCommand)
+}
 
 // setupAlertRoutes 设置告警路由
 /*
@@ -213,16 +587,20 @@ func (r *Router) ping(c *gin.Context) {
 
 // handleWebSocket WebSocket处理
 func (r *Router) handleWebSocket(c *gin.Context) {
-	// 从JWT中获取用户ID（可选）
+	// 从JWT中获取用户ID和角色（可选）
 	var userID *uint
-	if userIDInterface, exists := c.Get("userID"); exists {
+	if userIDInterface, exists := c.Get("user_id"); exists {
 		if uid, ok := userIDInterface.(uint); ok {
 			userID = &uid
 		}
 	}
+	var role models.UserRole
+	if roleInterface, exists := c.Get("user_role"); exists {
+		role, _ = roleInterface.(models.UserRole)
+	}
 
 	// 升级HTTP连接为WebSocket
-	err := r.websocketService.HandleWebSocketConnection(c.Writer, c.Request, userID)
+	err := r.websocketService.HandleWebSocketConnection(c.Writer, c.Request, userID, role)
 	if err != nil {
 		r.logger.Error("Failed to upgrade WebSocket connection", map[string]interface{}{
 			"error": err.Error(),
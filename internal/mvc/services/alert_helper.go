@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+)
+
+// raiseAlert 持久化告警并发布告警创建事件
+// 告警持久化失败不影响事件发布，保证实时推送链路可用
+func raiseAlert(ctx context.Context, alertService AlertService, kafkaService KafkaService, log *logger.Logger, params *CreateAlertParams) {
+	var alertID uint
+	if alertService != nil {
+		alert, err := alertService.CreateAlert(ctx, params)
+		if err != nil {
+			log.WithFields(map[string]interface{}{
+				"error":    err.Error(),
+				"type":     params.Type,
+				"code":     params.Code,
+				"drone_id": params.DroneID,
+			}).Warn("Failed to persist alert")
+		} else if alert != nil {
+			alertID = alert.ID
+		}
+	}
+
+	if kafkaService == nil {
+		return
+	}
+
+	eventData := kafka.AlertCreatedEventData{
+		AlertID:   alertID,
+		Type:      string(params.Type),
+		Level:     string(params.Level),
+		Message:   params.Message,
+		Source:    params.Source,
+		DroneID:   params.DroneID,
+		TaskID:    params.TaskID,
+		Timestamp: time.Now(),
+	}
+
+	if err := kafkaService.PublishAlertEvent(ctx, kafka.AlertCreatedEvent, eventData); err != nil {
+		log.WithFields(map[string]interface{}{
+			"error": err.Error(),
+			"type":  params.Type,
+			"code":  params.Code,
+		}).Error("Failed to publish alert event")
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -44,11 +45,15 @@ type LocationAnomaly struct {
 type PredictedIssue struct {
 	Type        string        `json:"type"`
 	DroneID     uint          `json:"drone_id"`
+	PackID      uint          `json:"pack_id,omitempty"` // 电池类问题对应的电池组
 	Probability float64       `json:"probability"`
 	TimeToIssue time.Duration `json:"time_to_issue"`
 	Description string        `json:"description"`
 }
 
+// maintenancePredictionThreshold 保养周期消耗比例超过该值时给出预测
+const maintenancePredictionThreshold = 0.8
+
 // SmartAlertService 智能告警服务接口
 type SmartAlertService interface {
 	// 事件处理
@@ -68,14 +73,16 @@ type SmartAlertService interface {
 
 // AlertServiceImpl 智能告警服务实现
 type AlertServiceImpl struct {
-	logger       *logger.Logger
-	kafkaService KafkaService
+	logger             *logger.Logger
+	kafkaService       KafkaService
+	maintenanceService MaintenanceService // 为nil时不做维护预测
+	batteryService     BatteryService     // 为nil时电量历史按无人机统计
 
 	// 缓存和状态
 	alertPatterns   map[string]*AlertPattern
-	lastEventTime   map[uint]time.Time         // 每个无人机的最后事件时间
-	batteryHistory  map[uint][]BatteryReading  // 电量历史
-	locationHistory map[uint][]LocationReading // 位置历史
+	lastEventTime   map[uint]time.Time                 // 每个无人机的最后事件时间
+	batteryHistory  map[batterySource][]BatteryReading // 电量历史
+	locationHistory map[uint][]LocationReading         // 位置历史
 
 	mu sync.RWMutex
 }
@@ -83,10 +90,25 @@ type AlertServiceImpl struct {
 // BatteryReading 电量读数
 type BatteryReading struct {
 	DroneID   uint      `json:"drone_id"`
+	PackID    uint      `json:"pack_id,omitempty"` // 读数时安装的电池组，未登记时为0
 	Battery   int       `json:"battery"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// batterySource 电量历史的归属，已登记电池组的读数按电池组统计，否则按无人机统计
+type batterySource struct {
+	PackID  uint
+	DroneID uint // PackID 不为0时为0
+}
+
+// batterySourceOf 读数的归属
+func batterySourceOf(reading BatteryReading) batterySource {
+	if reading.PackID != 0 {
+		return batterySource{PackID: reading.PackID}
+	}
+	return batterySource{DroneID: reading.DroneID}
+}
+
 // LocationReading 位置读数
 type LocationReading struct {
 	DroneID   uint      `json:"drone_id"`
@@ -98,21 +120,30 @@ type LocationReading struct {
 }
 
 // NewAlertService 创建智能告警服务
-func NewSmartAlertService(logger *logger.Logger, kafkaService KafkaService) SmartAlertService {
+func NewSmartAlertService(
+	logger *logger.Logger,
+	kafkaService KafkaService,
+	maintenanceService MaintenanceService,
+	batteryService BatteryService,
+) SmartAlertService {
 	return &AlertServiceImpl{
-		logger:          logger,
-		kafkaService:    kafkaService,
-		alertPatterns:   make(map[string]*AlertPattern),
-		lastEventTime:   make(map[uint]time.Time),
-		batteryHistory:  make(map[uint][]BatteryReading),
-		locationHistory: make(map[uint][]LocationReading),
+		logger:             logger,
+		kafkaService:       kafkaService,
+		maintenanceService: maintenanceService,
+		batteryService:     batteryService,
+		alertPatterns:      make(map[string]*AlertPattern),
+		lastEventTime:      make(map[uint]time.Time),
+		batteryHistory:     make(map[batterySource][]BatteryReading),
+		locationHistory:    make(map[uint][]LocationReading),
 	}
 }
 
 // ProcessEvents 处理事件批次
 func (s *AlertServiceImpl) ProcessEvents(events []kafka.Event) (*EventPattern, error) {
+	// 电池组归属需要查询数据库，在加锁前解析
+	packs := s.resolveBatteryPacks(events)
+
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
 	pattern := &EventPattern{
 		AlertPatterns:   make(map[string]*AlertPattern),
@@ -124,8 +155,8 @@ func (s *AlertServiceImpl) ProcessEvents(events []kafka.Event) (*EventPattern, e
 		switch event.Type {
 		case kafka.DroneLocationUpdatedEvent:
 			s.processLocationEvent(event, pattern)
-		case kafka.DroneBatteryLowEvent:
-			s.processBatteryEvent(event, pattern)
+		case kafka.DroneBatteryLowEvent, kafka.DroneBatteryUpdatedEvent:
+			s.processBatteryEvent(event, pattern, packs)
 		case kafka.DroneStatusChangedEvent:
 			s.processStatusEvent(event, pattern)
 		case kafka.AlertCreatedEvent:
@@ -138,6 +169,10 @@ func (s *AlertServiceImpl) ProcessEvents(events []kafka.Event) (*EventPattern, e
 
 	// 预测性分析
 	s.performPredictiveAnalysis(pattern)
+	s.mu.Unlock()
+
+	// 维护预测需要查询数据库，不在锁内执行
+	s.performMaintenancePrediction(pattern, events)
 
 	return pattern, nil
 }
@@ -190,8 +225,53 @@ func (s *AlertServiceImpl) processLocationEvent(event kafka.Event, pattern *Even
 	s.checkLocationAnomalies(reading, pattern)
 }
 
+// resolveBatteryPacks 查询电量事件中各无人机当前安装的电池组
+func (s *AlertServiceImpl) resolveBatteryPacks(events []kafka.Event) map[uint]uint {
+	packs := make(map[uint]uint)
+	if s.batteryService == nil {
+		return packs
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, event := range events {
+		if event.Type != kafka.DroneBatteryLowEvent && event.Type != kafka.DroneBatteryUpdatedEvent {
+			continue
+		}
+		droneIDFloat, ok := event.Data["drone_id"].(float64)
+		if !ok {
+			continue
+		}
+		droneID := uint(droneIDFloat)
+		if _, resolved := packs[droneID]; resolved {
+			continue
+		}
+
+		packs[droneID] = s.installedPackID(ctx, droneID)
+	}
+	return packs
+}
+
+// installedPackID 无人机当前安装的电池组ID，未安装或查询失败时为0
+func (s *AlertServiceImpl) installedPackID(ctx context.Context, droneID uint) uint {
+	if s.batteryService == nil {
+		return 0
+	}
+
+	pack, err := s.batteryService.GetInstalledPack(ctx, droneID)
+	if err != nil {
+		s.logger.WithError(err).WithField("drone_id", droneID).Warn("Failed to resolve installed battery pack")
+		return 0
+	}
+	if pack == nil {
+		return 0
+	}
+	return pack.ID
+}
+
 // processBatteryEvent 处理电量事件
-func (s *AlertServiceImpl) processBatteryEvent(event kafka.Event, pattern *EventPattern) {
+func (s *AlertServiceImpl) processBatteryEvent(event kafka.Event, pattern *EventPattern, packs map[uint]uint) {
 	data := event.Data
 	if data == nil {
 		return
@@ -211,21 +291,23 @@ func (s *AlertServiceImpl) processBatteryEvent(event kafka.Event, pattern *Event
 
 	reading := BatteryReading{
 		DroneID:   droneID,
+		PackID:    packs[droneID],
 		Battery:   battery,
 		Timestamp: event.Timestamp,
 	}
 
 	// 存储电量历史
-	s.batteryHistory[droneID] = append(s.batteryHistory[droneID], reading)
+	source := batterySourceOf(reading)
+	s.batteryHistory[source] = append(s.batteryHistory[source], reading)
 
 	// 保持历史数据在合理范围内
-	if len(s.batteryHistory[droneID]) > 50 {
-		s.batteryHistory[droneID] = s.batteryHistory[droneID][1:]
+	if len(s.batteryHistory[source]) > 50 {
+		s.batteryHistory[source] = s.batteryHistory[source][1:]
 	}
 
 	// 计算电量消耗率
-	if len(s.batteryHistory[droneID]) >= 2 {
-		pattern.BatteryDrainRate = s.calculateBatteryDrainRate(droneID)
+	if len(s.batteryHistory[source]) >= 2 {
+		pattern.BatteryDrainRate = s.calculateBatteryDrainRate(source)
 	}
 }
 
@@ -306,8 +388,8 @@ func (s *AlertServiceImpl) calculateDistance(lat1, lon1, lat2, lon2 float64) flo
 }
 
 // calculateBatteryDrainRate 计算电量消耗率
-func (s *AlertServiceImpl) calculateBatteryDrainRate(droneID uint) float64 {
-	history := s.batteryHistory[droneID]
+func (s *AlertServiceImpl) calculateBatteryDrainRate(source batterySource) float64 {
+	history := s.batteryHistory[source]
 	if len(history) < 2 {
 		return 0
 	}
@@ -350,9 +432,9 @@ func (s *AlertServiceImpl) calculateSystemHealthScore(events []kafka.Event) floa
 // performPredictiveAnalysis 执行预测性分析
 func (s *AlertServiceImpl) performPredictiveAnalysis(pattern *EventPattern) {
 	// 预测电量耗尽
-	for droneID, history := range s.batteryHistory {
+	for source, history := range s.batteryHistory {
 		if len(history) >= 3 {
-			if issue, err := s.PredictBatteryDrain(droneID, []kafka.Event{}); err == nil && issue != nil {
+			if issue := s.predictBatteryDrain(source); issue != nil {
 				pattern.PredictedIssues = append(pattern.PredictedIssues, *issue)
 			}
 		}
@@ -360,41 +442,168 @@ func (s *AlertServiceImpl) performPredictiveAnalysis(pattern *EventPattern) {
 }
 
 // PredictBatteryDrain 预测电量耗尽
+// 按无人机当前安装的电池组查找电量历史
 func (s *AlertServiceImpl) PredictBatteryDrain(droneID uint, events []kafka.Event) (*PredictedIssue, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	source := batterySource{DroneID: droneID}
+	if packID := s.installedPackID(ctx, droneID); packID != 0 {
+		source = batterySource{PackID: packID}
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	history := s.batteryHistory[droneID]
+	return s.predictBatteryDrain(source), nil
+}
+
+// predictBatteryDrain 预测电量耗尽，调用方需持有锁
+func (s *AlertServiceImpl) predictBatteryDrain(source batterySource) *PredictedIssue {
+	history := s.batteryHistory[source]
 	if len(history) < 3 {
-		return nil, nil
+		return nil
 	}
 
-	drainRate := s.calculateBatteryDrainRate(droneID)
+	drainRate := s.calculateBatteryDrainRate(source)
 	if drainRate <= 0 {
-		return nil, nil
+		return nil
 	}
 
-	currentBattery := history[len(history)-1].Battery
-	hoursToEmpty := float64(currentBattery) / drainRate
+	last := history[len(history)-1]
+	hoursToEmpty := float64(last.Battery) / drainRate
 
 	if hoursToEmpty < 1 { // 1小时内耗尽
+		description := fmt.Sprintf("无人机 %d 预计在 %.1f 小时内电量耗尽", last.DroneID, hoursToEmpty)
+		if source.PackID != 0 {
+			description = fmt.Sprintf("无人机 %d 的电池组 %d 预计在 %.1f 小时内电量耗尽", last.DroneID, source.PackID, hoursToEmpty)
+		}
 		return &PredictedIssue{
 			Type:        "battery_drain",
-			DroneID:     droneID,
+			DroneID:     last.DroneID,
+			PackID:      source.PackID,
 			Probability: 0.9,
 			TimeToIssue: time.Duration(hoursToEmpty * float64(time.Hour)),
-			Description: fmt.Sprintf("无人机 %d 预计在 %.1f 小时内电量耗尽", droneID, hoursToEmpty),
-		}, nil
+			Description: description,
+		}
 	}
 
-	return nil, nil
+	return nil
 }
 
 // PredictMaintenanceNeeds 预测维护需求
+// 以最接近到期的保养周期为基础，近期事件中的故障信号会提高概率
 func (s *AlertServiceImpl) PredictMaintenanceNeeds(droneID uint, events []kafka.Event) (*PredictedIssue, error) {
-	// 简化的维护预测
-	// 实际实现会考虑飞行时间、故障历史等因素
-	return nil, nil
+	if s.maintenanceService == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	status, err := s.maintenanceService.GetMaintenanceStatus(ctx, droneID)
+	if err != nil {
+		if err == ErrDroneNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var worst *MaintenanceDue
+	for _, due := range status.Due {
+		if worst == nil || due.UsedFraction > worst.UsedFraction {
+			worst = due
+		}
+	}
+	if worst == nil {
+		return nil, nil
+	}
+
+	faults := countFaultSignals(droneID, events)
+	if !worst.Overdue && worst.UsedFraction < maintenancePredictionThreshold && faults == 0 {
+		return nil, nil
+	}
+
+	probability := worst.UsedFraction + 0.1*float64(faults)
+	if probability > 1 {
+		probability = 1
+	}
+
+	var timeToIssue time.Duration
+	if worst.EstimatedDueAt != nil && !worst.Overdue {
+		timeToIssue = time.Until(*worst.EstimatedDueAt)
+		if timeToIssue < 0 {
+			timeToIssue = 0
+		}
+	}
+
+	description := fmt.Sprintf("无人机 %d 的保养项目「%s」已消耗 %.0f%% 周期", droneID, worst.Name, worst.UsedFraction*100)
+	if worst.Overdue {
+		description = fmt.Sprintf("无人机 %d 的保养项目「%s」已逾期", droneID, worst.Name)
+	}
+	if faults > 0 {
+		description += fmt.Sprintf("，近期出现 %d 次故障信号", faults)
+	}
+
+	return &PredictedIssue{
+		Type:        "maintenance_due",
+		DroneID:     droneID,
+		Probability: probability,
+		TimeToIssue: timeToIssue,
+		Description: description,
+	}, nil
+}
+
+// performMaintenancePrediction 对本批事件涉及的无人机做维护预测
+func (s *AlertServiceImpl) performMaintenancePrediction(pattern *EventPattern, events []kafka.Event) {
+	if s.maintenanceService == nil {
+		return
+	}
+
+	seen := make(map[uint]bool)
+	for _, event := range events {
+		droneIDFloat, ok := event.Data["drone_id"].(float64)
+		if !ok || seen[uint(droneIDFloat)] {
+			continue
+		}
+		droneID := uint(droneIDFloat)
+		seen[droneID] = true
+
+		issue, err := s.PredictMaintenanceNeeds(droneID, events)
+		if err != nil {
+			s.logger.WithError(err).WithField("drone_id", droneID).Warn("Failed to predict maintenance needs")
+			continue
+		}
+		if issue != nil {
+			pattern.PredictedIssues = append(pattern.PredictedIssues, *issue)
+		}
+	}
+}
+
+// countFaultSignals 统计事件中该无人机的故障信号（严重告警、进入故障状态、任务失败）
+func countFaultSignals(droneID uint, events []kafka.Event) int {
+	count := 0
+	for _, event := range events {
+		id, ok := event.Data["drone_id"].(float64)
+		if !ok || uint(id) != droneID {
+			continue
+		}
+
+		switch event.Type {
+		case kafka.AlertCreatedEvent:
+			level, _ := event.Data["level"].(string)
+			if level == string(models.AlertLevelError) || level == string(models.AlertLevelCritical) {
+				count++
+			}
+		case kafka.DroneStatusChangedEvent:
+			if status, _ := event.Data["new_status"].(string); status == string(models.DroneStatusError) {
+				count++
+			}
+		case kafka.TaskFailedEvent:
+			count++
+		}
+	}
+	return count
 }
 
 // AggregateAlerts 聚合告警
@@ -0,0 +1,623 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ApprovalConfig 任务审批配置
+type ApprovalConfig struct {
+	AllowSelfApproval bool          `yaml:"allow_self_approval" json:"allow_self_approval"` // 是否允许管理员审批自己创建的任务
+	WindowStep        time.Duration `yaml:"window_step" json:"window_step"`                 // 检查飞行时段是否落入时间窗口的采样间隔
+}
+
+// DefaultApprovalConfig 默认任务审批配置
+func DefaultApprovalConfig() *ApprovalConfig {
+	return &ApprovalConfig{
+		AllowSelfApproval: false,
+		WindowStep:        10 * time.Minute,
+	}
+}
+
+// ApprovalRuleParams 创建或修改审批规则参数
+type ApprovalRuleParams struct {
+	Name          string                  `json:"name"`
+	Description   string                  `json:"description"`
+	Enabled       *bool                   `json:"enabled"` // 创建时为空表示启用
+	Priorities    []models.TaskPriority   `json:"priorities"`
+	TaskTypes     []models.TaskType       `json:"task_types"`
+	GeofenceIDs   []uint                  `json:"geofence_ids"`
+	GeofenceTypes []models.GeofenceType   `json:"geofence_types"`
+	Windows       []models.GeofenceWindow `json:"windows"`
+	Timezone      string                  `json:"timezone"`
+	CreatedBy     uint                    `json:"created_by"`
+}
+
+// ApprovalService 任务审批服务接口
+// 命中启用规则的任务创建后进入待审批状态，管理员审批通过后回到待处理，驳回后取消；
+// 审批前的任务不能启动，审批后修改了影响规则的字段需要重新审批。
+type ApprovalService interface {
+	// 审批规则管理
+	ListRules(ctx context.Context) ([]*models.ApprovalRule, error)
+	GetRule(ctx context.Context, id uint) (*models.ApprovalRule, error)
+	CreateRule(ctx context.Context, params *ApprovalRuleParams) (*models.ApprovalRule, error)
+	UpdateRule(ctx context.Context, id uint, params *ApprovalRuleParams) (*models.ApprovalRule, error)
+	DeleteRule(ctx context.Context, id uint) error
+
+	// Evaluate 返回任务命中的审批规则，未命中时为空
+	Evaluate(ctx context.Context, task *models.Task) ([]models.ApprovalReason, error)
+	// RequestApproval 按当前规则重新评估未启动的任务：命中的规则和条件变化时置为待审批（之前的审批作废），
+	// 待审批的任务不再命中时撤回审批
+	RequestApproval(ctx context.Context, taskID uint) (*models.Task, error)
+	// Approve 审批通过，任务回到待处理
+	Approve(ctx context.Context, taskID, userID uint, comment string) (*models.Task, error)
+	// Reject 驳回，任务被取消，必须填写意见
+	Reject(ctx context.Context, taskID, userID uint, comment string) (*models.Task, error)
+	// Authorize 检查任务是否可以启动，需要审批而未审批时返回错误
+	Authorize(ctx context.Context, taskID uint) error
+}
+
+// ApprovalServiceImpl 任务审批服务实现
+type ApprovalServiceImpl struct {
+	config          *ApprovalConfig
+	db              *gorm.DB
+	geofenceService GeofenceService
+	kafkaService    KafkaService
+	logger          *logger.Logger
+}
+
+// NewApprovalService 创建任务审批服务
+// geofenceService 为nil时区域条件不会命中
+func NewApprovalService(
+	config *ApprovalConfig,
+	db *gorm.DB,
+	geofenceService GeofenceService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) ApprovalService {
+	if config == nil {
+		config = DefaultApprovalConfig()
+	}
+	if config.WindowStep <= 0 {
+		config.WindowStep = DefaultApprovalConfig().WindowStep
+	}
+
+	return &ApprovalServiceImpl{
+		config:          config,
+		db:              db,
+		geofenceService: geofenceService,
+		kafkaService:    kafkaService,
+		logger:          logger,
+	}
+}
+
+// ListRules 获取全部审批规则
+func (s *ApprovalServiceImpl) ListRules(ctx context.Context) ([]*models.ApprovalRule, error) {
+	var rules []*models.ApprovalRule
+	if err := s.db.WithContext(ctx).Order("id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list approval rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetRule 获取审批规则
+func (s *ApprovalServiceImpl) GetRule(ctx context.Context, id uint) (*models.ApprovalRule, error) {
+	var rule models.ApprovalRule
+	if err := s.db.WithContext(ctx).First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApprovalRuleNotFound
+		}
+		return nil, fmt.Errorf("failed to get approval rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// CreateRule 创建审批规则
+func (s *ApprovalServiceImpl) CreateRule(ctx context.Context, params *ApprovalRuleParams) (*models.ApprovalRule, error) {
+	rule := &models.ApprovalRule{Enabled: true, CreatedBy: params.CreatedBy}
+	if err := applyApprovalRule(rule, params); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create approval rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateRule 替换审批规则的条件，已提交的审批不受影响
+func (s *ApprovalServiceImpl) UpdateRule(ctx context.Context, id uint, params *ApprovalRuleParams) (*models.ApprovalRule, error) {
+	rule, err := s.GetRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyApprovalRule(rule, params); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Save(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to update approval rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule 删除审批规则，已提交的审批仍需处理
+func (s *ApprovalServiceImpl) DeleteRule(ctx context.Context, id uint) error {
+	res := s.db.WithContext(ctx).Delete(&models.ApprovalRule{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete approval rule: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrApprovalRuleNotFound
+	}
+	return nil
+}
+
+// Evaluate 返回任务命中的审批规则
+func (s *ApprovalServiceImpl) Evaluate(ctx context.Context, task *models.Task) ([]models.ApprovalReason, error) {
+	var rules []*models.ApprovalRule
+	if err := s.db.WithContext(ctx).Where("enabled = ?", true).Order("id").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to load approval rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	start := plannedAt(task.ScheduledAt)
+	var fences []*models.Geofence
+	if s.geofenceService != nil && len(task.Plan.Waypoints) > 0 {
+		for _, rule := range rules {
+			if rule.HasZone() {
+				var err error
+				if fences, err = s.geofenceService.ActiveGeofences(ctx, start); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+
+	var reasons []models.ApprovalReason
+	for _, rule := range rules {
+		if conditions, ok := s.matchRule(rule, task, start, fences); ok {
+			reasons = append(reasons, models.ApprovalReason{
+				RuleID:     rule.ID,
+				RuleName:   rule.Name,
+				Conditions: conditions,
+			})
+		}
+	}
+	return reasons, nil
+}
+
+// matchRule 检查任务是否满足规则的全部条件，返回满足的条件说明
+func (s *ApprovalServiceImpl) matchRule(rule *models.ApprovalRule, task *models.Task, start time.Time, fences []*models.Geofence) ([]string, bool) {
+	var conditions []string
+
+	if len(rule.Priorities) > 0 {
+		if !containsValue(rule.Priorities, task.Priority) {
+			return nil, false
+		}
+		conditions = append(conditions, fmt.Sprintf("priority is %s", task.Priority))
+	}
+
+	if len(rule.TaskTypes) > 0 {
+		if !containsValue(rule.TaskTypes, task.Type) {
+			return nil, false
+		}
+		conditions = append(conditions, fmt.Sprintf("task type is %s", task.Type))
+	}
+
+	if rule.HasZone() {
+		var crossed []string
+		for _, fence := range fences {
+			if !containsValue(rule.GeofenceIDs, fence.ID) && !containsValue(rule.GeofenceTypes, fence.Type) {
+				continue
+			}
+			if _, ok := planIntersection(fence, task.Plan.Waypoints); ok {
+				crossed = append(crossed, fmt.Sprintf("%s (%d)", fence.Name, fence.ID))
+			}
+		}
+		if len(crossed) == 0 {
+			return nil, false
+		}
+		conditions = append(conditions, "route crosses "+strings.Join(crossed, ", "))
+	}
+
+	if len(rule.Windows) > 0 {
+		window, ok := s.windowHit(rule, start, task.Plan.Duration)
+		if !ok {
+			return nil, false
+		}
+		conditions = append(conditions, fmt.Sprintf("flight time falls in window %s-%s", window.Start, window.End))
+	}
+
+	return conditions, true
+}
+
+// windowHit 按采样间隔检查从计划开始到预计结束之间是否有时刻落在规则的时间窗口内，返回命中的窗口
+func (s *ApprovalServiceImpl) windowHit(rule *models.ApprovalRule, start time.Time, durationMinutes int) (models.GeofenceWindow, bool) {
+	end := start.Add(time.Duration(durationMinutes) * time.Minute)
+	for at := start; at.Before(end); at = at.Add(s.config.WindowStep) {
+		if window, ok := rule.WindowAt(at); ok {
+			return window, true
+		}
+	}
+	return rule.WindowAt(end)
+}
+
+// RequestApproval 按当前规则重新评估未启动的任务
+func (s *ApprovalServiceImpl) RequestApproval(ctx context.Context, taskID uint) (*models.Task, error) {
+	task, err := s.loadTask(ctx, s.db, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !awaitsStart(task) {
+		return task, nil
+	}
+
+	reasons, err := s.Evaluate(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	var requested bool
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		task, err = s.loadTask(ctx, tx.Clauses(clause.Locking{Strength: "UPDATE"}), taskID)
+		if err != nil {
+			return err
+		}
+		if !awaitsStart(task) {
+			return nil
+		}
+
+		now := time.Now()
+		approval := task.Approval
+		status := task.Status
+		sameReasons := len(reasons) > 0 && models.SameApprovalReasons(approval.Reasons, reasons)
+		switch {
+		case sameReasons && task.Status == models.TaskStatusPendingApproval:
+			// 命中的规则和条件未变，保留待审批记录，审批时按当时的内容记录摘要
+			return nil
+		case sameReasons && approval.Status == models.ApprovalApproved && approval.Digest == models.ApprovalDigest(task):
+			// 审批后计划、排期、类型和优先级均未变，保留审批结果
+			return nil
+		case len(reasons) > 0:
+			// 之前的审批作废，按本次命中的规则重新审批
+			comment := ""
+			switch {
+			case approval.Status == models.ApprovalApproved && sameReasons:
+				comment = "plan, schedule, type or priority changed after approval"
+			case approval.Status == models.ApprovalApproved:
+				comment = "matched approval rules changed after approval"
+			}
+			approval.Status = models.ApprovalPending
+			approval.Reasons = reasons
+			approval.RequestedBy = task.UserID
+			approval.RequestedAt = &now
+			approval.DecidedBy = 0
+			approval.DecidedAt = nil
+			approval.Comment = ""
+			approval.Digest = ""
+			approval.History = append(approval.History, models.ApprovalEvent{
+				Action:  models.ApprovalRequested,
+				UserID:  task.UserID,
+				Comment: comment,
+				Reasons: reasons,
+				At:      now,
+			})
+			status = models.TaskStatusPendingApproval
+			requested = true
+		case task.Status == models.TaskStatusPendingApproval:
+			approval.Status = ""
+			approval.Reasons = nil
+			approval.History = append(approval.History, models.ApprovalEvent{
+				Action:  models.ApprovalWithdrawn,
+				Comment: "task no longer matches any approval rule",
+				At:      now,
+			})
+			status = models.TaskStatusPending
+		default:
+			return nil
+		}
+
+		if err := tx.Model(task).Updates(map[string]interface{}{
+			"status":   status,
+			"approval": approval,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update task approval: %w", err)
+		}
+		task.Status = status
+		task.Approval = approval
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if requested {
+		s.logger.WithFields(map[string]interface{}{
+			"task_id": task.ID,
+			"rules":   len(reasons),
+		}).Info("Task requires approval")
+		s.publish(ctx, kafka.TaskApprovalRequestedEvent, task)
+	}
+	return task, nil
+}
+
+// Approve 审批通过
+func (s *ApprovalServiceImpl) Approve(ctx context.Context, taskID, userID uint, comment string) (*models.Task, error) {
+	return s.decide(ctx, taskID, userID, comment, models.ApprovalApproved)
+}
+
+// Reject 驳回并取消任务
+func (s *ApprovalServiceImpl) Reject(ctx context.Context, taskID, userID uint, comment string) (*models.Task, error) {
+	if strings.TrimSpace(comment) == "" {
+		return nil, fmt.Errorf("%w: a comment is required to reject a task", ErrInvalidApproval)
+	}
+	return s.decide(ctx, taskID, userID, comment, models.ApprovalRejected)
+}
+
+// decide 记录审批结果并更新任务状态
+func (s *ApprovalServiceImpl) decide(ctx context.Context, taskID, userID uint, comment string, decision models.ApprovalStatus) (*models.Task, error) {
+	var task *models.Task
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		task, err = s.loadTask(ctx, tx.Clauses(clause.Locking{Strength: "UPDATE"}), taskID)
+		if err != nil {
+			return err
+		}
+		if task.Status != models.TaskStatusPendingApproval {
+			return ErrTaskNotPendingApproval
+		}
+		if !s.config.AllowSelfApproval && task.UserID == userID {
+			return ErrSelfApproval
+		}
+
+		now := time.Now()
+		approval := task.Approval
+		approval.Status = decision
+		approval.DecidedBy = userID
+		approval.DecidedAt = &now
+		approval.Comment = comment
+		approval.Digest = models.ApprovalDigest(task)
+		action := models.ApprovalGranted
+		if decision == models.ApprovalRejected {
+			action = models.ApprovalDenied
+		}
+		approval.History = append(approval.History, models.ApprovalEvent{
+			Action:  action,
+			UserID:  userID,
+			Comment: comment,
+			At:      now,
+		})
+
+		updates := map[string]interface{}{
+			"status":   models.TaskStatusPending,
+			"approval": approval,
+		}
+		if decision == models.ApprovalRejected {
+			updates["status"] = models.TaskStatusCancelled
+			updates["completed_at"] = now
+			updates["result_success"] = false
+			updates["result_error_code"] = "APPROVAL_REJECTED"
+			updates["result_error_detail"] = comment
+		}
+		if err := tx.Model(task).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to record approval decision: %w", err)
+		}
+
+		task.Status = models.TaskStatusPending
+		if decision == models.ApprovalRejected {
+			task.Status = models.TaskStatusCancelled
+			task.CompletedAt = &now
+			task.Result.Success = false
+			task.Result.ErrorCode = "APPROVAL_REJECTED"
+			task.Result.ErrorDetail = comment
+		}
+		task.Approval = approval
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"task_id":  task.ID,
+		"decision": decision,
+		"user_id":  userID,
+	}).Info("Task approval decided")
+	s.publish(ctx, kafka.TaskApprovalDecidedEvent, task)
+	return task, nil
+}
+
+// Authorize 检查任务是否可以启动
+// 审批通过的任务直接放行；未经审批的任务按当前规则评估，命中时提交审批并拒绝启动
+func (s *ApprovalServiceImpl) Authorize(ctx context.Context, taskID uint) error {
+	task, err := s.loadTask(ctx, s.db, taskID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case task.Status == models.TaskStatusPendingApproval:
+		return ErrApprovalPending
+	case task.Approval.Status == models.ApprovalApproved && task.Approval.Digest == models.ApprovalDigest(task):
+		// 审批后内容未变才能启动；变更过（包括没有摘要的旧记录）时重新评估，命中规则则重新审批
+		return nil
+	case !awaitsStart(task):
+		// 其他状态由任务服务拒绝
+		return nil
+	}
+
+	task, err = s.RequestApproval(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if task.Status == models.TaskStatusPendingApproval {
+		return ErrApprovalRequired
+	}
+	return nil
+}
+
+// loadTask 加载任务
+func (s *ApprovalServiceImpl) loadTask(ctx context.Context, db *gorm.DB, id uint) (*models.Task, error) {
+	var task models.Task
+	if err := db.WithContext(ctx).First(&task, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+	return &task, nil
+}
+
+// publish 发布任务审批事件
+func (s *ApprovalServiceImpl) publish(ctx context.Context, eventType kafka.EventType, task *models.Task) {
+	if s.kafkaService == nil {
+		return
+	}
+
+	if err := s.kafkaService.PublishTaskEvent(ctx, eventType, kafka.TaskApprovalEventData{
+		TaskID:      task.ID,
+		TaskName:    task.Name,
+		DroneID:     task.DroneID,
+		Priority:    string(task.Priority),
+		Status:      string(task.Approval.Status),
+		RequestedBy: task.Approval.RequestedBy,
+		DecidedBy:   task.Approval.DecidedBy,
+		Comment:     task.Approval.Comment,
+		Reasons:     task.Approval.Reasons,
+		Timestamp:   time.Now(),
+	}); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to publish task approval event")
+	}
+}
+
+// awaitsStart 任务尚未启动，可以提交或撤回审批
+func awaitsStart(task *models.Task) bool {
+	return task.Status == models.TaskStatusPending || task.Status == models.TaskStatusScheduled ||
+		task.Status == models.TaskStatusPendingApproval
+}
+
+// containsValue 判断列表中是否包含指定值
+func containsValue[T comparable](list []T, value T) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// applyApprovalRule 将参数写入规则并校验
+func applyApprovalRule(rule *models.ApprovalRule, params *ApprovalRuleParams) error {
+	if strings.TrimSpace(params.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidApprovalRule)
+	}
+
+	rule.Name = strings.TrimSpace(params.Name)
+	rule.Description = params.Description
+	if params.Enabled != nil {
+		rule.Enabled = *params.Enabled
+	}
+	rule.Priorities = params.Priorities
+	rule.TaskTypes = params.TaskTypes
+	rule.GeofenceIDs = params.GeofenceIDs
+	rule.GeofenceTypes = params.GeofenceTypes
+	rule.Windows = params.Windows
+	rule.Timezone = params.Timezone
+
+	if err := rule.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidApprovalRule, err)
+	}
+	return nil
+}
+
+// ApprovalTaskService 命中审批规则的任务在审批通过前不能启动的任务服务装饰器
+// 需要位于调度器和流程使用的任务服务之内，所有创建和启动任务的路径都经过审批检查
+type ApprovalTaskService struct {
+	TaskService
+	approvalService ApprovalService
+	logger          *logger.Logger
+}
+
+// NewApprovalTaskService 创建审批任务服务
+func NewApprovalTaskService(next TaskService, approvalService ApprovalService, logger *logger.Logger) TaskService {
+	return &ApprovalTaskService{
+		TaskService:     next,
+		approvalService: approvalService,
+		logger:          logger,
+	}
+}
+
+// CreateTask 创建后评估审批规则，命中时任务进入待审批
+func (s *ApprovalTaskService) CreateTask(ctx context.Context, params *CreateTaskParams) (*models.Task, error) {
+	task, err := s.TaskService.CreateTask(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// 评估失败只记录日志，启动前会再次评估
+	updated, err := s.approvalService.RequestApproval(ctx, task.ID)
+	if err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to evaluate approval rules for task")
+		return task, nil
+	}
+	task.Status = updated.Status
+	task.Approval = updated.Approval
+	return task, nil
+}
+
+// UpdateTask 待审批的任务不能直接修改状态；修改影响规则的字段后重新评估
+func (s *ApprovalTaskService) UpdateTask(ctx context.Context, id uint, params *UpdateTaskParams) (*models.Task, error) {
+	if params.Status != "" {
+		if params.Status == models.TaskStatusPendingApproval {
+			return nil, fmt.Errorf("%w: status pending_approval is set by approval rules", ErrInvalidData)
+		}
+		current, err := s.TaskService.GetTaskByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if current.Status == models.TaskStatusPendingApproval && params.Status != models.TaskStatusCancelled {
+			return nil, ErrApprovalPending
+		}
+	}
+
+	task, err := s.TaskService.UpdateTask(ctx, id, params)
+	if err != nil {
+		return nil, err
+	}
+	// 规则不涉及无人机，改派（包括自动改派）不需要重新审批；
+	// 计划、排期、类型或优先级变更后，即使命中的规则不变也要重新审批（按审批时记录的摘要判断）
+	if params.Type == "" && params.Priority == "" && params.Plan == nil && params.ScheduledAt == nil {
+		return task, nil
+	}
+
+	updated, err := s.approvalService.RequestApproval(ctx, id)
+	if err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Warn("Failed to re-evaluate approval rules for task")
+		return task, nil
+	}
+	task.Status = updated.Status
+	task.Approval = updated.Approval
+	return task, nil
+}
+
+// StartTask 未经审批时拒绝启动
+func (s *ApprovalTaskService) StartTask(ctx context.Context, id uint) error {
+	if err := s.approvalService.Authorize(ctx, id); err != nil {
+		return err
+	}
+	return s.TaskService.StartTask(ctx, id)
+}
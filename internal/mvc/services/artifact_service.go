@@ -0,0 +1,978 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/blobstore"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/logger"
+	"drone-control-system/pkg/mediameta"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// artifactLeaderKey 任务文件清理主节点锁
+	artifactLeaderKey = "task:artifact:leader"
+	// artifactUploadLockKey 断点续传追加锁，同一上传同时只允许一个请求写入
+	artifactUploadLockKey = "task:artifact:upload:%d:lock"
+	// artifactCleanupBatch 每批清理的记录数
+	artifactCleanupBatch = 500
+	// sniffLength 内容类型检测读取的字节数
+	sniffLength = 512
+)
+
+// ArtifactConfig 任务文件配置
+type ArtifactConfig struct {
+	MaxSize         int64         `yaml:"max_size" json:"max_size"`                 // 单个文件大小上限（字节），0表示不限制
+	Retention       time.Duration `yaml:"retention" json:"retention"`               // 文件保留时间，0表示保留到任务删除
+	UploadTTL       time.Duration `yaml:"upload_ttl" json:"upload_ttl"`             // 断点续传上传的有效期
+	SigningSecret   string        `yaml:"signing_secret" json:"-"`                  // 下载链接签名密钥
+	URLTTL          time.Duration `yaml:"url_ttl" json:"url_ttl"`                   // 下载链接有效期
+	PublicBaseURL   string        `yaml:"public_base_url" json:"public_base_url"`   // 下载链接的外部访问地址，为空时生成相对路径
+	CleanupInterval time.Duration `yaml:"cleanup_interval" json:"cleanup_interval"` // 过期文件和上传清理间隔
+	LockTTL         time.Duration `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultArtifactConfig 默认任务文件配置
+func DefaultArtifactConfig() *ArtifactConfig {
+	return &ArtifactConfig{
+		MaxSize:         2 << 30,
+		Retention:       90 * 24 * time.Hour,
+		UploadTTL:       24 * time.Hour,
+		URLTTL:          15 * time.Minute,
+		CleanupInterval: 10 * time.Minute,
+		LockTTL:         2 * time.Minute,
+	}
+}
+
+// UploadArtifactParams 上传任务文件参数
+type UploadArtifactParams struct {
+	TaskID      uint                `json:"task_id"`
+	Kind        models.ArtifactKind `json:"kind"` // 为空时按内容类型推断
+	FileName    string              `json:"file_name"`
+	ContentType string              `json:"content_type"` // 客户端声明的类型，只在无法从内容和扩展名识别时使用
+	Checksum    string              `json:"checksum"`     // 可选的SHA-256（十六进制），不一致时拒绝
+	UserID      uint                `json:"user_id"`
+}
+
+// CreateArtifactUploadParams 创建断点续传上传参数
+type CreateArtifactUploadParams struct {
+	TaskID      uint                `json:"task_id"`
+	Kind        models.ArtifactKind `json:"kind"`
+	FileName    string              `json:"file_name"`
+	ContentType string              `json:"content_type"`
+	Size        int64               `json:"size"`
+	Checksum    string              `json:"checksum"`
+	UserID      uint                `json:"user_id"`
+}
+
+// ArtifactService 任务文件服务接口
+// 文件内容保存在对象存储中，数据库记录文件信息、校验和和提取的元数据，
+// 并把文件列表同步到任务结果的 files 字段。多实例部署时只有主节点执行过期清理。
+type ArtifactService interface {
+	// Upload 一次性上传文件
+	Upload(ctx context.Context, params *UploadArtifactParams, r io.Reader) (*models.TaskArtifact, error)
+
+	// 断点续传：创建上传后按偏移量分段追加，收到全部字节后自动转为任务文件
+	CreateUpload(ctx context.Context, params *CreateArtifactUploadParams) (*models.ArtifactUpload, error)
+	GetUpload(ctx context.Context, taskID, uploadID uint) (*models.ArtifactUpload, error)
+	// AppendUpload 从 offset 处追加数据，offset 与已接收字节数不一致时返回 ErrUploadOffset 和当前上传状态；
+	// 同一上传正在被其他请求追加时返回 ErrUploadBusy；上传完成时返回生成的任务文件
+	AppendUpload(ctx context.Context, taskID, uploadID uint, offset int64, r io.Reader) (*models.ArtifactUpload, *models.TaskArtifact, error)
+	CancelUpload(ctx context.Context, taskID, uploadID uint) error
+
+	ListArtifacts(ctx context.Context, taskID uint, kind models.ArtifactKind) ([]*models.TaskArtifact, error)
+	GetArtifact(ctx context.Context, taskID, artifactID uint) (*models.TaskArtifact, error)
+	OpenArtifact(ctx context.Context, taskID, artifactID uint) (*models.TaskArtifact, blobstore.Object, error)
+	DeleteArtifact(ctx context.Context, taskID, artifactID uint) error
+	// DeleteTaskArtifacts 删除任务的全部文件和未完成的上传
+	DeleteTaskArtifacts(ctx context.Context, taskID uint) error
+
+	// SignURL 生成有时效的免登录下载链接
+	SignURL(artifact *models.TaskArtifact) (string, time.Time, error)
+	// OpenSigned 校验下载链接签名并打开文件
+	OpenSigned(ctx context.Context, artifactID uint, expires int64, signature string) (*models.TaskArtifact, blobstore.Object, error)
+
+	// 服务管理（过期清理）
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// ArtifactServiceImpl 任务文件服务实现
+type ArtifactServiceImpl struct {
+	config   *ArtifactConfig
+	db       *gorm.DB
+	store    blobstore.Store
+	locks    *database.LockService
+	election *database.LeaderElection
+	logger   *logger.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewArtifactService 创建任务文件服务
+func NewArtifactService(
+	config *ArtifactConfig,
+	db *gorm.DB,
+	lockService *database.LockService,
+	store blobstore.Store,
+	logger *logger.Logger,
+) ArtifactService {
+	if config == nil {
+		config = DefaultArtifactConfig()
+	}
+
+	return &ArtifactServiceImpl{
+		config:   config,
+		db:       db,
+		store:    store,
+		locks:    lockService,
+		election: database.NewLeaderElection(lockService, artifactLeaderKey, config.LockTTL),
+		logger:   logger,
+	}
+}
+
+// Upload 边接收边计算校验和并写入存储，超过大小上限或校验和不一致时删除已写入的内容
+func (s *ArtifactServiceImpl) Upload(ctx context.Context, params *UploadArtifactParams, r io.Reader) (*models.TaskArtifact, error) {
+	fileName, checksum, err := s.validateFile(params.Kind, params.FileName, params.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureTask(ctx, params.TaskID); err != nil {
+		return nil, err
+	}
+
+	key, err := newArtifactKey(params.TaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReaderSize(r, sniffLength)
+	head, err := reader.Peek(sniffLength)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	contentType := detectContentType(head, fileName, params.ContentType)
+
+	var body io.Reader = reader
+	if s.config.MaxSize > 0 {
+		body = io.LimitReader(reader, s.config.MaxSize+1)
+	}
+	hasher := sha256.New()
+	size, err := s.store.Put(ctx, key, io.TeeReader(body, hasher))
+	if err != nil {
+		s.discardBlob(key)
+		return nil, fmt.Errorf("failed to store artifact: %w", err)
+	}
+
+	switch {
+	case s.config.MaxSize > 0 && size > s.config.MaxSize:
+		s.discardBlob(key)
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrArtifactTooLarge, s.config.MaxSize)
+	case size == 0:
+		s.discardBlob(key)
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidArtifact)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	if checksum != "" && checksum != sum {
+		s.discardBlob(key)
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, checksum, sum)
+	}
+
+	artifact := &models.TaskArtifact{
+		TaskID:      params.TaskID,
+		Kind:        params.Kind,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		Checksum:    sum,
+		StorageKey:  key,
+		UploadedBy:  params.UserID,
+		Metadata:    s.extractMetadata(ctx, key, size),
+	}
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.saveArtifact(ctx, tx, artifact)
+	}); err != nil {
+		s.discardBlob(key)
+		return nil, err
+	}
+	return artifact, nil
+}
+
+// CreateUpload 创建断点续传上传，数据在第一次追加时写入
+func (s *ArtifactServiceImpl) CreateUpload(ctx context.Context, params *CreateArtifactUploadParams) (*models.ArtifactUpload, error) {
+	fileName, checksum, err := s.validateFile(params.Kind, params.FileName, params.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case params.Size <= 0:
+		return nil, fmt.Errorf("%w: size must be positive", ErrInvalidArtifact)
+	case s.config.MaxSize > 0 && params.Size > s.config.MaxSize:
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrArtifactTooLarge, s.config.MaxSize)
+	}
+	if err := s.ensureTask(ctx, params.TaskID); err != nil {
+		return nil, err
+	}
+
+	key, err := newArtifactKey(params.TaskID)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &models.ArtifactUpload{
+		TaskID:      params.TaskID,
+		Kind:        params.Kind,
+		FileName:    fileName,
+		ContentType: strings.TrimSpace(params.ContentType),
+		Size:        params.Size,
+		Checksum:    checksum,
+		StorageKey:  key,
+		CreatedBy:   params.UserID,
+		ExpiresAt:   time.Now().Add(s.config.UploadTTL),
+	}
+	if err := s.db.WithContext(ctx).Create(upload).Error; err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	return upload, nil
+}
+
+// GetUpload 获取上传状态
+func (s *ArtifactServiceImpl) GetUpload(ctx context.Context, taskID, uploadID uint) (*models.ArtifactUpload, error) {
+	var upload models.ArtifactUpload
+	if err := s.db.WithContext(ctx).Where("id = ? AND task_id = ?", uploadID, taskID).First(&upload).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	return &upload, nil
+}
+
+// AppendUpload 追加上传数据
+// 用 Redis 锁保证同一上传的追加串行执行，写入期间不持有数据库事务和行锁；
+// 写入中断时保存已写入的字节数，客户端可从该位置继续
+func (s *ArtifactServiceImpl) AppendUpload(ctx context.Context, taskID, uploadID uint, offset int64, r io.Reader) (*models.ArtifactUpload, *models.TaskArtifact, error) {
+	lockCtx, release, err := s.lockUpload(ctx, uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	// 续约失败时 lockCtx 被取消，停止写入，避免与获得锁的其他请求并发追加
+	ctx = lockCtx
+
+	// 获得锁后再读取记录，前一个请求可能已推进偏移量或完成上传
+	upload, err := s.GetUpload(ctx, taskID, uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, nil, ErrUploadNotFound
+	}
+
+	// 以存储中的实际大小为准，上一次追加可能在写入后、更新记录前中断
+	current, err := s.blobSize(ctx, upload.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.saveUploadOffset(ctx, upload, current); err != nil {
+		return nil, nil, err
+	}
+	if offset != upload.Offset {
+		return upload, nil, fmt.Errorf("%w: expected %d", ErrUploadOffset, upload.Offset)
+	}
+
+	size, appendErr := s.store.Append(ctx, upload.StorageKey, offset, io.LimitReader(r, upload.Size-offset))
+	if errors.Is(context.Cause(ctx), errUploadLockLost) {
+		return upload, nil, fmt.Errorf("%w: %v", ErrUploadBusy, errUploadLockLost)
+	}
+	if err := s.saveUploadOffset(ctx, upload, size); err != nil {
+		return nil, nil, err
+	}
+	if errors.Is(appendErr, blobstore.ErrOffsetMismatch) {
+		return upload, nil, fmt.Errorf("%w: expected %d", ErrUploadOffset, size)
+	}
+	if appendErr != nil {
+		return upload, nil, fmt.Errorf("failed to append upload: %w", appendErr)
+	}
+	if upload.Offset < upload.Size {
+		return upload, nil, nil
+	}
+
+	artifact, err := s.finishUpload(ctx, upload)
+	return upload, artifact, err
+}
+
+// errUploadLockLost 追加期间续约失败，锁可能已被其他请求获得
+var errUploadLockLost = errors.New("upload lock lost")
+
+// lockUpload 获取上传的追加锁，写入期间按锁有效期的三分之一续约
+// 返回的 context 在续约失败或锁已失效时被取消，cause 为 errUploadLockLost；返回释放函数
+func (s *ArtifactServiceImpl) lockUpload(ctx context.Context, uploadID uint) (context.Context, func(), error) {
+	key := fmt.Sprintf(artifactUploadLockKey, uploadID)
+	token := fmt.Sprintf("%s-%d", s.election.Identity(), time.Now().UnixNano())
+
+	acquired, err := s.locks.AcquireLock(ctx, key, token, s.config.LockTTL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire upload lock: %w", err)
+	}
+	if !acquired {
+		return nil, nil, ErrUploadBusy
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(s.config.LockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				extended, err := s.locks.ExtendLock(context.Background(), key, token, s.config.LockTTL)
+				if err == nil && extended {
+					continue
+				}
+				if err == nil {
+					err = errUploadLockLost
+				}
+				s.logger.WithError(err).WithField("upload_id", uploadID).Warn("Failed to extend upload lock, aborting append")
+				cancel(errUploadLockLost)
+				return
+			}
+		}
+	}()
+
+	return lockCtx, func() {
+		close(done)
+		cancel(nil)
+		releaseCtx, cancelRelease := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancelRelease()
+		if err := s.locks.ReleaseLock(releaseCtx, key, token); err != nil {
+			s.logger.WithError(err).WithField("upload_id", uploadID).Warn("Failed to release upload lock")
+		}
+	}, nil
+}
+
+// saveUploadOffset 更新已接收的字节数，未变化时不写库
+func (s *ArtifactServiceImpl) saveUploadOffset(ctx context.Context, upload *models.ArtifactUpload, offset int64) error {
+	if offset == upload.Offset {
+		return nil
+	}
+	if err := s.db.WithContext(ctx).Model(upload).UpdateColumn("offset", offset).Error; err != nil {
+		return fmt.Errorf("failed to update upload offset: %w", err)
+	}
+	upload.Offset = offset
+	return nil
+}
+
+// finishUpload 校验完整文件并转为任务文件，校验和不一致时删除上传，客户端需要重新上传
+// 校验和元数据提取在事务外完成，事务只用于保存任务文件和删除上传记录
+func (s *ArtifactServiceImpl) finishUpload(ctx context.Context, upload *models.ArtifactUpload) (*models.TaskArtifact, error) {
+	sum, head, err := s.hashBlob(ctx, upload.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Checksum != "" && upload.Checksum != sum {
+		if err := s.store.Delete(ctx, upload.StorageKey); err != nil {
+			return nil, fmt.Errorf("failed to delete upload data: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Unscoped().Delete(upload).Error; err != nil {
+			return nil, fmt.Errorf("failed to delete upload: %w", err)
+		}
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, upload.Checksum, sum)
+	}
+
+	artifact := &models.TaskArtifact{
+		TaskID:      upload.TaskID,
+		Kind:        upload.Kind,
+		FileName:    upload.FileName,
+		ContentType: detectContentType(head, upload.FileName, upload.ContentType),
+		Size:        upload.Size,
+		Checksum:    sum,
+		StorageKey:  upload.StorageKey,
+		UploadedBy:  upload.CreatedBy,
+		Metadata:    s.extractMetadata(ctx, upload.StorageKey, upload.Size),
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.saveArtifact(ctx, tx, artifact); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(upload).Error; err != nil {
+			return fmt.Errorf("failed to delete upload: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+// CancelUpload 取消上传并删除已接收的数据
+func (s *ArtifactServiceImpl) CancelUpload(ctx context.Context, taskID, uploadID uint) error {
+	upload, err := s.GetUpload(ctx, taskID, uploadID)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, upload.StorageKey); err != nil {
+		return fmt.Errorf("failed to delete upload data: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Unscoped().Delete(upload).Error; err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
+
+// ListArtifacts 获取任务文件列表，kind 为空时返回全部类别
+func (s *ArtifactServiceImpl) ListArtifacts(ctx context.Context, taskID uint, kind models.ArtifactKind) ([]*models.TaskArtifact, error) {
+	query := s.db.WithContext(ctx).Where("task_id = ?", taskID)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var artifacts []*models.TaskArtifact
+	if err := query.Order("id ASC").Find(&artifacts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	return artifacts, nil
+}
+
+// GetArtifact 获取任务文件
+func (s *ArtifactServiceImpl) GetArtifact(ctx context.Context, taskID, artifactID uint) (*models.TaskArtifact, error) {
+	var artifact models.TaskArtifact
+	if err := s.db.WithContext(ctx).Where("id = ? AND task_id = ?", artifactID, taskID).First(&artifact).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrArtifactNotFound
+		}
+		return nil, fmt.Errorf("failed to get artifact: %w", err)
+	}
+	return &artifact, nil
+}
+
+// OpenArtifact 打开任务文件，调用方负责关闭
+func (s *ArtifactServiceImpl) OpenArtifact(ctx context.Context, taskID, artifactID uint) (*models.TaskArtifact, blobstore.Object, error) {
+	artifact, err := s.GetArtifact(ctx, taskID, artifactID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.open(ctx, artifact)
+}
+
+// DeleteArtifact 删除任务文件
+func (s *ArtifactServiceImpl) DeleteArtifact(ctx context.Context, taskID, artifactID uint) error {
+	artifact, err := s.GetArtifact(ctx, taskID, artifactID)
+	if err != nil {
+		return err
+	}
+	if err := s.removeArtifact(ctx, artifact); err != nil {
+		return err
+	}
+	return s.syncResultFiles(ctx, s.db.WithContext(ctx), taskID)
+}
+
+// DeleteTaskArtifacts 删除任务的全部文件和上传，单个文件失败时继续处理其余文件并返回第一个错误
+func (s *ArtifactServiceImpl) DeleteTaskArtifacts(ctx context.Context, taskID uint) error {
+	var artifacts []*models.TaskArtifact
+	if err := s.db.WithContext(ctx).Where("task_id = ?", taskID).Find(&artifacts).Error; err != nil {
+		return fmt.Errorf("failed to list artifacts: %w", err)
+	}
+	var uploads []*models.ArtifactUpload
+	if err := s.db.WithContext(ctx).Where("task_id = ?", taskID).Find(&uploads).Error; err != nil {
+		return fmt.Errorf("failed to list uploads: %w", err)
+	}
+
+	var firstErr error
+	for _, artifact := range artifacts {
+		if err := s.removeArtifact(ctx, artifact); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for _, upload := range uploads {
+		if err := s.removeUpload(ctx, upload); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// SignURL 生成下载链接，签名覆盖文件ID和过期时间
+func (s *ArtifactServiceImpl) SignURL(artifact *models.TaskArtifact) (string, time.Time, error) {
+	if s.config.SigningSecret == "" {
+		return "", time.Time{}, fmt.Errorf("artifact URL signing secret is not configured")
+	}
+
+	expiresAt := time.Now().Add(s.config.URLTTL).Truncate(time.Second)
+	expires := expiresAt.Unix()
+	url := fmt.Sprintf("%s/api/v1/public/artifacts/%d/download?expires=%d&signature=%s",
+		strings.TrimRight(s.config.PublicBaseURL, "/"), artifact.ID, expires, s.sign(artifact.ID, expires))
+	return url, expiresAt, nil
+}
+
+// OpenSigned 校验签名和过期时间后打开文件
+func (s *ArtifactServiceImpl) OpenSigned(ctx context.Context, artifactID uint, expires int64, signature string) (*models.TaskArtifact, blobstore.Object, error) {
+	if s.config.SigningSecret == "" || time.Now().Unix() > expires ||
+		!hmac.Equal([]byte(signature), []byte(s.sign(artifactID, expires))) {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	var artifact models.TaskArtifact
+	if err := s.db.WithContext(ctx).First(&artifact, artifactID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrArtifactNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get artifact: %w", err)
+	}
+	return s.open(ctx, &artifact)
+}
+
+// sign 计算下载链接签名
+func (s *ArtifactServiceImpl) sign(artifactID uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.config.SigningSecret))
+	mac.Write([]byte(strconv.FormatUint(uint64(artifactID), 10) + ":" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// open 打开文件内容，存储中缺失时视为文件不存在
+func (s *ArtifactServiceImpl) open(ctx context.Context, artifact *models.TaskArtifact) (*models.TaskArtifact, blobstore.Object, error) {
+	object, err := s.store.Open(ctx, artifact.StorageKey)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, nil, ErrArtifactNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open artifact: %w", err)
+	}
+	return artifact, object, nil
+}
+
+// validateFile 校验文件类别、文件名和校验和，返回规范化的文件名和校验和
+func (s *ArtifactServiceImpl) validateFile(kind models.ArtifactKind, fileName, checksum string) (string, string, error) {
+	if kind != "" && !kind.IsValid() {
+		return "", "", fmt.Errorf("%w: unknown kind %q", ErrInvalidArtifact, kind)
+	}
+
+	name := sanitizeFileName(fileName)
+	if len(name) > 255 {
+		return "", "", fmt.Errorf("%w: file name is too long", ErrInvalidArtifact)
+	}
+
+	checksum = strings.ToLower(strings.TrimSpace(checksum))
+	if checksum != "" {
+		if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+			return "", "", fmt.Errorf("%w: checksum must be a hex SHA-256 digest", ErrInvalidArtifact)
+		}
+	}
+	return name, checksum, nil
+}
+
+// ensureTask 检查任务存在
+func (s *ArtifactServiceImpl) ensureTask(ctx context.Context, taskID uint) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Task{}).Where("id = ?", taskID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+	if count == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// saveArtifact 补全类别和过期时间后保存，并同步任务结果中的文件列表
+// 元数据需要读取文件内容，由调用方在事务外提取
+func (s *ArtifactServiceImpl) saveArtifact(ctx context.Context, tx *gorm.DB, artifact *models.TaskArtifact) error {
+	if artifact.Kind == "" {
+		artifact.Kind = models.ArtifactKindFor(artifact.ContentType)
+	}
+	if s.config.Retention > 0 {
+		expiresAt := time.Now().Add(s.config.Retention)
+		artifact.ExpiresAt = &expiresAt
+	}
+
+	if err := tx.Create(artifact).Error; err != nil {
+		return fmt.Errorf("failed to create artifact: %w", err)
+	}
+	return s.syncResultFiles(ctx, tx, artifact.TaskID)
+}
+
+// extractMetadata 提取拍摄时间和地理标签，失败只记录日志
+func (s *ArtifactServiceImpl) extractMetadata(ctx context.Context, key string, size int64) models.ArtifactMetadata {
+	object, err := s.store.Open(ctx, key)
+	if err != nil {
+		s.logger.WithError(err).WithField("key", key).Warn("Failed to open artifact for metadata extraction")
+		return models.ArtifactMetadata{}
+	}
+	defer object.Close()
+
+	meta, err := mediameta.Extract(object, size)
+	if err != nil {
+		s.logger.WithError(err).WithField("key", key).Warn("Failed to extract artifact metadata")
+		return models.ArtifactMetadata{}
+	}
+	return models.ArtifactMetadata{
+		CapturedAt: meta.CapturedAt,
+		Latitude:   meta.Latitude,
+		Longitude:  meta.Longitude,
+		Altitude:   meta.Altitude,
+		Make:       meta.Make,
+		Model:      meta.Model,
+	}
+}
+
+// hashBlob 计算存储中对象的SHA-256，并返回开头用于检测内容类型的字节
+func (s *ArtifactServiceImpl) hashBlob(ctx context.Context, key string) (string, []byte, error) {
+	object, err := s.store.Open(ctx, key)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open upload data: %w", err)
+	}
+	defer object.Close()
+
+	hasher := sha256.New()
+	head := &headWriter{limit: sniffLength}
+	if _, err := io.Copy(io.MultiWriter(hasher, head), object); err != nil {
+		return "", nil, fmt.Errorf("failed to read upload data: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), head.data, nil
+}
+
+// blobSize 获取对象当前大小，不存在时为0
+func (s *ArtifactServiceImpl) blobSize(ctx context.Context, key string) (int64, error) {
+	info, err := s.store.Stat(ctx, key)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to stat upload data: %w", err)
+	}
+	return info.Size, nil
+}
+
+// syncResultFiles 将任务文件列表写入任务结果的 files 字段
+func (s *ArtifactServiceImpl) syncResultFiles(ctx context.Context, tx *gorm.DB, taskID uint) error {
+	var artifacts []*models.TaskArtifact
+	if err := tx.Where("task_id = ?", taskID).Order("id ASC").Find(&artifacts).Error; err != nil {
+		return fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	files := make([]resultFile, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		files = append(files, resultFile{
+			ID:          artifact.ID,
+			Kind:        artifact.Kind,
+			FileName:    artifact.FileName,
+			ContentType: artifact.ContentType,
+			Size:        artifact.Size,
+			Checksum:    artifact.Checksum,
+			CapturedAt:  artifact.Metadata.CapturedAt,
+		})
+	}
+
+	value := ""
+	if len(files) > 0 {
+		data, err := json.Marshal(files)
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	}
+	if err := tx.Model(&models.Task{}).Where("id = ?", taskID).UpdateColumn("result_files", value).Error; err != nil {
+		return fmt.Errorf("failed to update task result files: %w", err)
+	}
+	return nil
+}
+
+// resultFile 任务结果 files 字段中的文件条目
+type resultFile struct {
+	ID          uint                `json:"id"`
+	Kind        models.ArtifactKind `json:"kind"`
+	FileName    string              `json:"file_name"`
+	ContentType string              `json:"content_type"`
+	Size        int64               `json:"size"`
+	Checksum    string              `json:"checksum"`
+	CapturedAt  *time.Time          `json:"captured_at,omitempty"`
+}
+
+// removeArtifact 先删除存储中的内容再删除记录，内容删除失败时保留记录以便重试
+func (s *ArtifactServiceImpl) removeArtifact(ctx context.Context, artifact *models.TaskArtifact) error {
+	if err := s.store.Delete(ctx, artifact.StorageKey); err != nil {
+		return fmt.Errorf("failed to delete artifact data: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Unscoped().Delete(artifact).Error; err != nil {
+		return fmt.Errorf("failed to delete artifact: %w", err)
+	}
+	return nil
+}
+
+// removeUpload 删除上传的数据和记录
+func (s *ArtifactServiceImpl) removeUpload(ctx context.Context, upload *models.ArtifactUpload) error {
+	if err := s.store.Delete(ctx, upload.StorageKey); err != nil {
+		return fmt.Errorf("failed to delete upload data: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Unscoped().Delete(upload).Error; err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
+
+// discardBlob 删除写入失败或被拒绝的内容，请求可能已取消，使用独立的上下文
+func (s *ArtifactServiceImpl) discardBlob(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.store.Delete(ctx, key); err != nil {
+		s.logger.WithError(err).WithField("key", key).Warn("Failed to discard artifact data")
+	}
+}
+
+// Start 启动过期清理
+func (s *ArtifactServiceImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.cleanupLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"cleanup_interval": s.config.CleanupInterval.String(),
+		"retention":        s.config.Retention.String(),
+		"instance":         s.election.Identity(),
+	}).Info("Artifact service started")
+	return nil
+}
+
+// Stop 停止清理并释放主节点锁
+func (s *ArtifactServiceImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release artifact leader lock")
+	}
+
+	s.logger.Info("Artifact service stopped")
+	return nil
+}
+
+// cleanupLoop 周期清理过期文件、已删除任务的文件和过期上传
+func (s *ArtifactServiceImpl) cleanupLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.CleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Artifact leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+			s.cleanup(s.ctx)
+		}
+	}
+}
+
+// orphanCondition 所属任务已删除
+const orphanCondition = "NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.id = %s.task_id AND tasks.deleted_at IS NULL)"
+
+// cleanup 分批清理，某批中有删除失败的记录时停止，留到下一轮重试
+func (s *ArtifactServiceImpl) cleanup(ctx context.Context) {
+	now := time.Now()
+
+	expired := s.db.WithContext(ctx).Where("expires_at IS NOT NULL AND expires_at <= ?", now)
+	s.cleanupArtifacts(ctx, expired, true)
+
+	orphaned := s.db.WithContext(ctx).Where(fmt.Sprintf(orphanCondition, "task_artifacts"))
+	s.cleanupArtifacts(ctx, orphaned, false)
+
+	for ctx.Err() == nil {
+		var uploads []*models.ArtifactUpload
+		if err := s.db.WithContext(ctx).
+			Where("expires_at <= ? OR "+fmt.Sprintf(orphanCondition, "artifact_uploads"), now).
+			Limit(artifactCleanupBatch).Find(&uploads).Error; err != nil {
+			s.logger.WithError(err).Error("Failed to load stale uploads")
+			return
+		}
+
+		removed := 0
+		for _, upload := range uploads {
+			if err := s.removeUpload(ctx, upload); err != nil {
+				s.logger.WithError(err).WithField("upload_id", upload.ID).Warn("Failed to remove stale upload")
+				continue
+			}
+			removed++
+		}
+		if removed > 0 {
+			s.logger.WithField("count", removed).Info("Removed stale artifact uploads")
+		}
+		if removed < artifactCleanupBatch {
+			return
+		}
+	}
+}
+
+// cleanupArtifacts 删除满足条件的文件，resync 为 true 时更新仍存在的任务的文件列表
+func (s *ArtifactServiceImpl) cleanupArtifacts(ctx context.Context, query *gorm.DB, resync bool) {
+	for ctx.Err() == nil {
+		var artifacts []*models.TaskArtifact
+		if err := query.Session(&gorm.Session{}).Limit(artifactCleanupBatch).Find(&artifacts).Error; err != nil {
+			s.logger.WithError(err).Error("Failed to load artifacts for cleanup")
+			return
+		}
+
+		removed := 0
+		tasks := make(map[uint]struct{})
+		for _, artifact := range artifacts {
+			if err := s.removeArtifact(ctx, artifact); err != nil {
+				s.logger.WithError(err).WithField("artifact_id", artifact.ID).Warn("Failed to remove artifact")
+				continue
+			}
+			tasks[artifact.TaskID] = struct{}{}
+			removed++
+		}
+		if resync {
+			for taskID := range tasks {
+				if err := s.syncResultFiles(ctx, s.db.WithContext(ctx), taskID); err != nil {
+					s.logger.WithError(err).WithField("task_id", taskID).Warn("Failed to update task result files")
+				}
+			}
+		}
+		if removed > 0 {
+			s.logger.WithField("count", removed).Info("Removed task artifacts")
+		}
+		if removed < artifactCleanupBatch {
+			return
+		}
+	}
+}
+
+// newArtifactKey 生成存储键 tasks/<任务ID>/<随机值>
+func newArtifactKey(taskID uint) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate storage key: %w", err)
+	}
+	return fmt.Sprintf("tasks/%d/%s", taskID, hex.EncodeToString(id)), nil
+}
+
+// sanitizeFileName 去掉路径和控制字符，只保留文件名
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "artifact"
+	}
+	return name
+}
+
+// detectContentType 优先按内容检测，无法识别时按扩展名，最后使用客户端声明的类型
+func detectContentType(head []byte, fileName, declared string) string {
+	detected := http.DetectContentType(head)
+	if detected != "application/octet-stream" && !strings.HasPrefix(detected, "text/plain") {
+		return detected
+	}
+	if byExt := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName))); byExt != "" {
+		return byExt
+	}
+	if declared = strings.TrimSpace(declared); declared != "" {
+		if _, _, err := mime.ParseMediaType(declared); err == nil {
+			return declared
+		}
+	}
+	return detected
+}
+
+// headWriter 只保留写入内容的前 limit 个字节
+type headWriter struct {
+	limit int
+	data  []byte
+}
+
+func (w *headWriter) Write(p []byte) (int, error) {
+	if room := w.limit - len(w.data); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		w.data = append(w.data, p[:room]...)
+	}
+	return len(p), nil
+}
+
+// ArtifactTaskService 任务文件清理装饰器，任务删除后删除其文件
+type ArtifactTaskService struct {
+	TaskService
+	artifactService ArtifactService
+	logger          *logger.Logger
+}
+
+// NewArtifactTaskService 创建任务文件清理任务服务
+func NewArtifactTaskService(next TaskService, artifactService ArtifactService, logger *logger.Logger) TaskService {
+	return &ArtifactTaskService{
+		TaskService:     next,
+		artifactService: artifactService,
+		logger:          logger,
+	}
+}
+
+// DeleteTask 删除任务后删除其文件，失败只记录日志，由过期清理补充处理
+func (s *ArtifactTaskService) DeleteTask(ctx context.Context, id uint) error {
+	if err := s.TaskService.DeleteTask(ctx, id); err != nil {
+		return err
+	}
+	if err := s.artifactService.DeleteTaskArtifacts(ctx, id); err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Warn("Failed to delete task artifacts")
+	}
+	return nil
+}
@@ -0,0 +1,528 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// assignmentLeaderKey 自动重新分配巡检主节点锁
+const assignmentLeaderKey = "task:assignment:leader"
+
+// AssignmentConfig 自动分配配置
+type AssignmentConfig struct {
+	// 评分权重，总分为各项得分（0-1）的加权平均
+	DistanceWeight float64 `yaml:"distance_weight" json:"distance_weight"`
+	BatteryWeight  float64 `yaml:"battery_weight" json:"battery_weight"`
+	WorkloadWeight float64 `yaml:"workload_weight" json:"workload_weight"`
+
+	MaxDistance   float64       `yaml:"max_distance" json:"max_distance"`     // 距首个航点超过该距离（米）不参与分配
+	CheckInterval time.Duration `yaml:"check_interval" json:"check_interval"` // 重新分配巡检间隔
+	LockTTL       time.Duration `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultAssignmentConfig 默认自动分配配置
+func DefaultAssignmentConfig() *AssignmentConfig {
+	return &AssignmentConfig{
+		DistanceWeight: 0.4,
+		BatteryWeight:  0.4,
+		WorkloadWeight: 0.2,
+		MaxDistance:    20000,
+		CheckInterval:  time.Minute,
+		LockTTL:        2 * time.Minute,
+	}
+}
+
+// AssignmentRequest 分配请求
+type AssignmentRequest struct {
+	Plan                 models.TaskPlan                `json:"plan"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+	ExcludeDroneIDs      []uint                         `json:"exclude_drone_ids"`
+	Scope                *FleetScope                    `json:"scope"` // 可选择的机队范围，为nil时不限制
+}
+
+// AssignmentCandidate 候选无人机及评分
+type AssignmentCandidate struct {
+	DroneID         uint               `json:"drone_id"`
+	SerialNo        string             `json:"serial_no"`
+	Status          models.DroneStatus `json:"status"`
+	Eligible        bool               `json:"eligible"`
+	Score           float64            `json:"score"`    // 0-100，不可分配时为0
+	Distance        float64            `json:"distance"` // 距首个航点（米），无航点时为0
+	Battery         int                `json:"battery"`
+	RequiredBattery int                `json:"required_battery"` // 估算任务所需电量（%），含置信余量和保留电量
+	QueuedTasks     int                `json:"queued_tasks"`     // 已分配未结束的任务数
+	Reasons         []string           `json:"reasons,omitempty"`
+	Explanation     string             `json:"explanation"`
+}
+
+// AssignmentService 任务自动分配服务接口
+type AssignmentService interface {
+	// SuggestDrones 按评分返回候选无人机，可分配的排在前面
+	SuggestDrones(ctx context.Context, req *AssignmentRequest, limit int) ([]*AssignmentCandidate, error)
+	// SelectDrone 选出评分最高的可分配无人机，没有时返回 ErrNoDroneAvailable
+	SelectDrone(ctx context.Context, req *AssignmentRequest) (*AssignmentCandidate, error)
+	// ReassignDroneTasks 无人机不可用时重新分配其自动分配且未开始的任务
+	ReassignDroneTasks(ctx context.Context, droneID uint) error
+
+	// 服务管理（定期巡检需要重新分配的任务）
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// AssignmentServiceImpl 任务自动分配服务实现
+type AssignmentServiceImpl struct {
+	config        *AssignmentConfig
+	db            *gorm.DB
+	election      *database.LeaderElection
+	taskService   TaskService
+	energyService EnergyService
+	kafkaService  KafkaService
+	logger        *logger.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewAssignmentService 创建任务自动分配服务
+// taskService 用于重新分配时更换无人机，不需要包含自动分配装饰器
+func NewAssignmentService(
+	config *AssignmentConfig,
+	db *gorm.DB,
+	lockService *database.LockService,
+	taskService TaskService,
+	energyService EnergyService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) AssignmentService {
+	if config == nil {
+		config = DefaultAssignmentConfig()
+	}
+
+	return &AssignmentServiceImpl{
+		config:        config,
+		db:            db,
+		election:      database.NewLeaderElection(lockService, assignmentLeaderKey, config.LockTTL),
+		taskService:   taskService,
+		energyService: energyService,
+		kafkaService:  kafkaService,
+		logger:        logger,
+	}
+}
+
+// SuggestDrones 按评分返回候选无人机
+func (s *AssignmentServiceImpl) SuggestDrones(ctx context.Context, req *AssignmentRequest, limit int) ([]*AssignmentCandidate, error) {
+	waypoints := req.Plan.Waypoints
+	if req.RequiredCapabilities != nil {
+		if err := req.RequiredCapabilities.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCapabilities, err)
+		}
+	}
+
+	var drones []*models.Drone
+	if err := s.db.WithContext(ctx).Find(&drones).Error; err != nil {
+		return nil, fmt.Errorf("failed to load drones: %w", err)
+	}
+
+	workload, err := s.loadWorkload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[uint]bool, len(req.ExcludeDroneIDs))
+	for _, id := range req.ExcludeDroneIDs {
+		excluded[id] = true
+	}
+
+	candidates := make([]*AssignmentCandidate, 0, len(drones))
+	for _, drone := range drones {
+		if excluded[drone.ID] || (req.Scope != nil && !req.Scope.Contains(drone.FleetID)) {
+			continue
+		}
+		candidates = append(candidates, s.evaluate(ctx, drone, req, waypoints, workload[drone.ID]))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Eligible != candidates[j].Eligible {
+			return candidates[i].Eligible
+		}
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].DroneID < candidates[j].DroneID
+	})
+
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// SelectDrone 选出评分最高的可分配无人机
+func (s *AssignmentServiceImpl) SelectDrone(ctx context.Context, req *AssignmentRequest) (*AssignmentCandidate, error) {
+	candidates, err := s.SuggestDrones(ctx, req, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 || !candidates[0].Eligible {
+		return nil, ErrNoDroneAvailable
+	}
+	return candidates[0], nil
+}
+
+// ReassignDroneTasks 重新分配无人机上自动分配且未开始的任务
+// 手动指定无人机的任务不会被改动
+func (s *AssignmentServiceImpl) ReassignDroneTasks(ctx context.Context, droneID uint) error {
+	var drone models.Drone
+	if err := s.db.WithContext(ctx).First(&drone, droneID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrDroneNotFound
+		}
+		return fmt.Errorf("failed to get drone: %w", err)
+	}
+	if drone.IsAvailable() {
+		return nil
+	}
+
+	var tasks []*models.Task
+	if err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND auto_assigned = ? AND status IN ?", droneID, true,
+			[]models.TaskStatus{models.TaskStatusPending, models.TaskStatusScheduled}).
+		Find(&tasks).Error; err != nil {
+		return fmt.Errorf("failed to load tasks for reassignment: %w", err)
+	}
+
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.reassignTask(ctx, task, &drone)
+	}
+	return nil
+}
+
+// Start 启动重新分配巡检
+func (s *AssignmentServiceImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.checkLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"check_interval": s.config.CheckInterval.String(),
+		"instance":       s.election.Identity(),
+	}).Info("Assignment service started")
+	return nil
+}
+
+// Stop 停止巡检并释放主节点锁
+func (s *AssignmentServiceImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release assignment leader lock")
+	}
+
+	s.logger.Info("Assignment service stopped")
+	return nil
+}
+
+// checkLoop 周期检查自动分配任务的无人机是否仍然可用
+// 状态变化事件会即时触发重新分配，巡检用于兜底电量下降等没有状态事件的情况
+func (s *AssignmentServiceImpl) checkLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Assignment leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+			s.checkAssignments(s.ctx)
+		}
+	}
+}
+
+// checkAssignments 重新分配无人机已不可用的自动分配任务
+func (s *AssignmentServiceImpl) checkAssignments(ctx context.Context) {
+	var droneIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("auto_assigned = ? AND status IN ?", true,
+			[]models.TaskStatus{models.TaskStatusPending, models.TaskStatusScheduled}).
+		Distinct().Pluck("drone_id", &droneIDs).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to load auto-assigned tasks")
+		return
+	}
+
+	for _, droneID := range droneIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.ReassignDroneTasks(ctx, droneID); err != nil && err != ErrDroneNotFound {
+			s.logger.WithError(err).WithField("drone_id", droneID).Error("Failed to reassign drone tasks")
+		}
+	}
+}
+
+// reassignTask 为任务选择新的无人机
+func (s *AssignmentServiceImpl) reassignTask(ctx context.Context, task *models.Task, previous *models.Drone) {
+	fields := map[string]interface{}{
+		"task_id":        task.ID,
+		"previous_drone": previous.ID,
+	}
+	req, err := task.ParseRequiredCapabilities()
+	if err != nil {
+		s.logger.WithError(err).WithFields(fields).Warn("Invalid task capabilities, cannot reassign task")
+		return
+	}
+
+	candidate, err := s.SelectDrone(ctx, &AssignmentRequest{
+		Plan:                 task.RemainingPlan(),
+		RequiredCapabilities: req,
+		ExcludeDroneIDs:      []uint{previous.ID},
+		Scope:                droneScope(previous),
+	})
+	if err != nil {
+		s.logger.WithError(err).WithFields(fields).Warn("No replacement drone available for task")
+		return
+	}
+
+	if _, err := s.taskService.UpdateTask(ctx, task.ID, &UpdateTaskParams{DroneID: &candidate.DroneID}); err != nil {
+		s.logger.WithError(err).WithFields(fields).Error("Failed to reassign task")
+		return
+	}
+
+	note := fmt.Sprintf("reassigned from drone %d (%s): %s", previous.ID, previous.Status, candidate.Explanation)
+	if err := s.db.WithContext(ctx).Model(&models.Task{}).Where("id = ?", task.ID).
+		Update("assignment_note", note).Error; err != nil {
+		s.logger.WithError(err).WithFields(fields).Warn("Failed to record assignment note")
+	}
+
+	fields["new_drone"] = candidate.DroneID
+	s.logger.WithFields(fields).Info("Task reassigned")
+
+	if s.kafkaService != nil {
+		if err := s.kafkaService.PublishTaskEvent(ctx, kafka.TaskReassignedEvent, kafka.TaskReassignedEventData{
+			TaskID:          task.ID,
+			TaskName:        task.Name,
+			PreviousDroneID: previous.ID,
+			DroneID:         candidate.DroneID,
+			Reason:          note,
+			Timestamp:       time.Now(),
+		}); err != nil {
+			s.logger.WithError(err).WithFields(fields).Warn("Failed to publish task reassigned event")
+		}
+	}
+}
+
+// droneScope 重新分配只在原无人机所在机队内进行，未分配机队时在未分配的无人机中选择
+func droneScope(drone *models.Drone) *FleetScope {
+	if drone.FleetID == nil {
+		return &FleetScope{IncludeUnassigned: true}
+	}
+	return &FleetScope{FleetIDs: []uint{*drone.FleetID}}
+}
+
+// evaluate 计算无人机的评分和说明
+func (s *AssignmentServiceImpl) evaluate(ctx context.Context, drone *models.Drone, req *AssignmentRequest, waypoints []models.Waypoint, queued int) *AssignmentCandidate {
+	c := &AssignmentCandidate{
+		DroneID:     drone.ID,
+		SerialNo:    drone.SerialNo,
+		Status:      drone.Status,
+		Battery:     drone.Battery,
+		QueuedTasks: queued,
+		Eligible:    true,
+	}
+
+	if !drone.IsAvailable() {
+		c.Eligible = false
+		c.Reasons = append(c.Reasons, fmt.Sprintf("not available (status %s, battery %d%%)", drone.Status, drone.Battery))
+	}
+
+	caps, err := drone.ParseCapabilities()
+	if err != nil {
+		c.Eligible = false
+		c.Reasons = append(c.Reasons, "invalid capability data")
+		caps = &models.DroneCapabilities{}
+	} else if missing := caps.Missing(req.RequiredCapabilities); len(missing) > 0 {
+		c.Eligible = false
+		c.Reasons = append(c.Reasons, "missing capabilities: "+strings.Join(missing, ", "))
+	}
+	if issues := req.Plan.Validate(caps); len(issues) > 0 {
+		c.Eligible = false
+		c.Reasons = append(c.Reasons, "mission exceeds model limits: "+issues[0].String())
+	}
+
+	// 转场距离和任务航程
+	var missionLength float64
+	if len(waypoints) > 0 {
+		c.Distance = geo.Distance(drone.Position.Latitude, drone.Position.Longitude, waypoints[0].Latitude, waypoints[0].Longitude)
+		points := make([]geo.Point, len(waypoints))
+		for i, wp := range waypoints {
+			points[i] = geo.Point{Latitude: wp.Latitude, Longitude: wp.Longitude}
+		}
+		missionLength = geo.PathLength(points)
+
+		if c.Distance > s.config.MaxDistance {
+			c.Eligible = false
+			c.Reasons = append(c.Reasons, fmt.Sprintf("%.1f km from first waypoint exceeds %.1f km limit", c.Distance/1000, s.config.MaxDistance/1000))
+		}
+		// 往返转场加任务航程不能超过无人机航程
+		if caps.MaxRange > 0 && 2*c.Distance+missionLength > caps.MaxRange {
+			c.Eligible = false
+			c.Reasons = append(c.Reasons, fmt.Sprintf("round trip %.1f km exceeds range %.1f km", (2*c.Distance+missionLength)/1000, caps.MaxRange/1000))
+		}
+	}
+
+	// 电量：估算耗电加置信余量，任务结束时不低于保留电量
+	estimate, err := s.energyService.Estimate(ctx, &EnergyEstimateParams{Drone: drone, Plan: &req.Plan})
+	if err != nil {
+		c.Eligible = false
+		c.Reasons = append(c.Reasons, fmt.Sprintf("energy estimate failed: %v", err))
+		return c
+	}
+	c.RequiredBattery = int(math.Ceil(estimate.BatteryUsed+estimate.Margin)) + estimate.MinBattery
+	if drone.Battery < c.RequiredBattery {
+		c.Eligible = false
+		c.Reasons = append(c.Reasons, fmt.Sprintf("battery %d%% below estimated %d%% needed", drone.Battery, c.RequiredBattery))
+	}
+
+	if c.Eligible {
+		c.Score = s.score(c)
+	}
+	c.Explanation = s.explain(c, len(waypoints) > 0)
+	return c
+}
+
+// score 加权评分（0-100）
+func (s *AssignmentServiceImpl) score(c *AssignmentCandidate) float64 {
+	distanceScore := 1.0
+	if s.config.MaxDistance > 0 {
+		distanceScore = math.Max(0, 1-c.Distance/s.config.MaxDistance)
+	}
+	batteryScore := math.Max(0, math.Min(1, float64(c.Battery-c.RequiredBattery)/float64(100-c.RequiredBattery+1)))
+	workloadScore := 1 / float64(1+c.QueuedTasks)
+
+	totalWeight := s.config.DistanceWeight + s.config.BatteryWeight + s.config.WorkloadWeight
+	if totalWeight <= 0 {
+		return 0
+	}
+	score := (distanceScore*s.config.DistanceWeight + batteryScore*s.config.BatteryWeight + workloadScore*s.config.WorkloadWeight) / totalWeight
+	return math.Round(score*1000) / 10
+}
+
+// explain 生成可读的选择说明
+func (s *AssignmentServiceImpl) explain(c *AssignmentCandidate, hasWaypoints bool) string {
+	parts := make([]string, 0, 4)
+	if hasWaypoints {
+		parts = append(parts, fmt.Sprintf("%.1f km from first waypoint", c.Distance/1000))
+	}
+	parts = append(parts, fmt.Sprintf("battery %d%% vs ~%d%% needed", c.Battery, c.RequiredBattery))
+	parts = append(parts, fmt.Sprintf("%d queued task(s)", c.QueuedTasks))
+
+	summary := fmt.Sprintf("drone %d (%s): %s", c.DroneID, c.SerialNo, strings.Join(parts, ", "))
+	if !c.Eligible {
+		return summary + "; not eligible: " + strings.Join(c.Reasons, "; ")
+	}
+	return fmt.Sprintf("%s; score %.1f", summary, c.Score)
+}
+
+// loadWorkload 统计各无人机已分配未结束的任务数
+func (s *AssignmentServiceImpl) loadWorkload(ctx context.Context) (map[uint]int, error) {
+	var rows []struct {
+		DroneID uint
+		Count   int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Task{}).
+		Select("drone_id, COUNT(*) AS count").
+		Where("status IN ?", []models.TaskStatus{models.TaskStatusPending, models.TaskStatusPendingApproval,
+			models.TaskStatusScheduled, models.TaskStatusRunning}).
+		Group("drone_id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count drone workload: %w", err)
+	}
+
+	workload := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		workload[row.DroneID] = row.Count
+	}
+	return workload, nil
+}
+
+// AssignmentTaskService 创建任务未指定无人机时自动分配的任务服务装饰器
+type AssignmentTaskService struct {
+	TaskService
+	assignmentService AssignmentService
+}
+
+// NewAssignmentTaskService 创建自动分配任务服务
+func NewAssignmentTaskService(next TaskService, assignmentService AssignmentService) TaskService {
+	return &AssignmentTaskService{
+		TaskService:       next,
+		assignmentService: assignmentService,
+	}
+}
+
+// CreateTask 未指定无人机时选择评分最高的可用无人机
+func (s *AssignmentTaskService) CreateTask(ctx context.Context, params *CreateTaskParams) (*models.Task, error) {
+	if params.DroneID != 0 {
+		return s.TaskService.CreateTask(ctx, params)
+	}
+
+	candidate, err := s.assignmentService.SelectDrone(ctx, &AssignmentRequest{
+		Plan:                 params.Plan,
+		RequiredCapabilities: params.RequiredCapabilities,
+		Scope:                params.AssignmentScope,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params.DroneID = candidate.DroneID
+	params.AutoAssigned = true
+	params.AssignmentNote = candidate.Explanation
+	return s.TaskService.CreateTask(ctx, params)
+}
@@ -0,0 +1,567 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// batteryPackCacheTTL 无人机当前电池组缓存有效期，多实例下换电后最长延迟该时间生效
+	batteryPackCacheTTL = time.Minute
+)
+
+// BatteryConfig 电池管理配置
+type BatteryConfig struct {
+	LowHealthThreshold      float64 `yaml:"low_health_threshold" json:"low_health_threshold"`           // 健康度低于该值产生警告
+	CriticalHealthThreshold float64 `yaml:"critical_health_threshold" json:"critical_health_threshold"` // 健康度低于该值产生严重告警
+}
+
+// DefaultBatteryConfig 默认电池管理配置
+func DefaultBatteryConfig() *BatteryConfig {
+	return &BatteryConfig{
+		LowHealthThreshold:      80,
+		CriticalHealthThreshold: 70,
+	}
+}
+
+// CreateBatteryPackParams 登记电池组参数
+type CreateBatteryPackParams struct {
+	SerialNo      string                  `json:"serial_no"`
+	Chemistry     models.BatteryChemistry `json:"chemistry"`
+	CapacityMah   int                     `json:"capacity_mah"`
+	RatedCycles   int                     `json:"rated_cycles"`
+	CycleCount    int                     `json:"cycle_count"`     // 已有的循环次数（旧电池登记时使用）
+	StateOfHealth *float64                `json:"state_of_health"` // 为空时按循环次数估算
+	Notes         string                  `json:"notes"`
+}
+
+// UpdateBatteryPackParams 更新电池组参数
+type UpdateBatteryPackParams struct {
+	CapacityMah   *int                     `json:"capacity_mah"`
+	RatedCycles   *int                     `json:"rated_cycles"`
+	StateOfHealth *float64                 `json:"state_of_health"` // 人工或BMS测得的健康度
+	Status        models.BatteryPackStatus `json:"status"`          // 仅支持 available 与 retired 之间切换
+	Notes         *string                  `json:"notes"`
+}
+
+// ListBatteryPacksParams 电池组列表参数
+type ListBatteryPacksParams struct {
+	Offset    int                      `json:"offset"`
+	Limit     int                      `json:"limit"`
+	Status    models.BatteryPackStatus `json:"status"`
+	DroneID   uint                     `json:"drone_id"`
+	MaxHealth float64                  `json:"max_health"` // 大于0时只返回健康度不高于该值的电池组
+	Scope     *FleetScope              `json:"scope"`      // 调用者可访问的机队范围，未安装的电池组不受限制；为nil时不限制
+}
+
+// BatteryService 电池组管理服务接口
+type BatteryService interface {
+	CreatePack(ctx context.Context, params *CreateBatteryPackParams) (*models.BatteryPack, error)
+	GetPack(ctx context.Context, id uint) (*models.BatteryPack, error)
+	ListPacks(ctx context.Context, params *ListBatteryPacksParams) ([]*models.BatteryPack, int64, error)
+	UpdatePack(ctx context.Context, id uint, params *UpdateBatteryPackParams) (*models.BatteryPack, error)
+
+	// InstallPack 安装电池组，无人机上已有的电池组会被自动拆下
+	InstallPack(ctx context.Context, packID, droneID, userID uint) error
+	RemovePack(ctx context.Context, packID, userID uint, reason string) error
+	GetPackHistory(ctx context.Context, packID uint) ([]*models.BatteryInstallation, error)
+	// GetInstalledPack 获取无人机当前安装的电池组，未安装时返回nil
+	GetInstalledPack(ctx context.Context, droneID uint) (*models.BatteryPack, error)
+
+	// RecordReading 记录无人机电量读数，累计到当前安装的电池组
+	// stateOfHealth 为设备上报的健康度，未上报时为nil
+	RecordReading(ctx context.Context, droneID uint, level int, stateOfHealth *float64, at time.Time) error
+}
+
+// installedPackEntry 无人机当前电池组缓存项，packID为0表示未安装
+type installedPackEntry struct {
+	packID   uint
+	loadedAt time.Time
+}
+
+// BatteryServiceImpl 电池组管理服务实现
+type BatteryServiceImpl struct {
+	config       *BatteryConfig
+	db           *gorm.DB
+	alertService AlertService
+	kafkaService KafkaService
+	logger       *logger.Logger
+
+	installed   map[uint]installedPackEntry
+	installedMu sync.Mutex
+}
+
+// NewBatteryService 创建电池组管理服务
+func NewBatteryService(
+	config *BatteryConfig,
+	db *gorm.DB,
+	alertService AlertService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) BatteryService {
+	if config == nil {
+		config = DefaultBatteryConfig()
+	}
+
+	return &BatteryServiceImpl{
+		config:       config,
+		db:           db,
+		alertService: alertService,
+		kafkaService: kafkaService,
+		logger:       logger,
+		installed:    make(map[uint]installedPackEntry),
+	}
+}
+
+// CreatePack 登记电池组
+func (s *BatteryServiceImpl) CreatePack(ctx context.Context, params *CreateBatteryPackParams) (*models.BatteryPack, error) {
+	if params == nil || strings.TrimSpace(params.SerialNo) == "" {
+		return nil, fmt.Errorf("%w: serial_no is required", ErrInvalidData)
+	}
+	if !params.Chemistry.IsValid() {
+		return nil, fmt.Errorf("%w: unknown battery chemistry: %s", ErrInvalidData, params.Chemistry)
+	}
+	if params.CapacityMah < 0 || params.RatedCycles < 0 || params.CycleCount < 0 {
+		return nil, fmt.Errorf("%w: capacity and cycles must not be negative", ErrInvalidData)
+	}
+	if params.StateOfHealth != nil && !validHealth(*params.StateOfHealth) {
+		return nil, fmt.Errorf("%w: state_of_health must be between 0 and 100", ErrInvalidData)
+	}
+
+	pack := &models.BatteryPack{
+		SerialNo:         strings.TrimSpace(params.SerialNo),
+		Chemistry:        params.Chemistry,
+		CapacityMah:      params.CapacityMah,
+		RatedCycles:      params.RatedCycles,
+		CycleCount:       params.CycleCount,
+		ChargeThroughput: float64(params.CycleCount) * 100,
+		Status:           models.BatteryPackAvailable,
+		Notes:            params.Notes,
+	}
+	if pack.RatedCycles == 0 {
+		pack.RatedCycles = 300
+	}
+	pack.StateOfHealth = pack.EstimatedHealth()
+	if params.StateOfHealth != nil {
+		pack.StateOfHealth = *params.StateOfHealth
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.BatteryPack{}).Where("serial_no = ?", pack.SerialNo).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check battery pack: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrBatteryPackExists
+	}
+
+	if err := s.db.WithContext(ctx).Create(pack).Error; err != nil {
+		return nil, fmt.Errorf("failed to create battery pack: %w", err)
+	}
+
+	s.checkHealth(ctx, pack)
+	return pack, nil
+}
+
+// GetPack 获取电池组
+func (s *BatteryServiceImpl) GetPack(ctx context.Context, id uint) (*models.BatteryPack, error) {
+	var pack models.BatteryPack
+	if err := s.db.WithContext(ctx).First(&pack, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBatteryPackNotFound
+		}
+		return nil, fmt.Errorf("failed to get battery pack: %w", err)
+	}
+	return &pack, nil
+}
+
+// ListPacks 获取电池组列表
+func (s *BatteryServiceImpl) ListPacks(ctx context.Context, params *ListBatteryPacksParams) ([]*models.BatteryPack, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.BatteryPack{})
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	if params.DroneID != 0 {
+		query = query.Where("drone_id = ?", params.DroneID)
+	}
+	if params.MaxHealth > 0 {
+		query = query.Where("state_of_health <= ?", params.MaxHealth)
+	}
+	if params.Scope != nil {
+		if droneIDs, ok := scopedDroneIDs(s.db.WithContext(ctx), params.Scope); ok {
+			query = query.Where("drone_id IS NULL OR drone_id IN (?)", droneIDs)
+		} else {
+			query = query.Where("drone_id IS NULL")
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count battery packs: %w", err)
+	}
+
+	var packs []*models.BatteryPack
+	if err := query.Order("id").Offset(params.Offset).Limit(params.Limit).Find(&packs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list battery packs: %w", err)
+	}
+	return packs, total, nil
+}
+
+// UpdatePack 更新电池组
+func (s *BatteryServiceImpl) UpdatePack(ctx context.Context, id uint, params *UpdateBatteryPackParams) (*models.BatteryPack, error) {
+	pack, err := s.GetPack(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if params.CapacityMah != nil {
+		if *params.CapacityMah < 0 {
+			return nil, fmt.Errorf("%w: capacity must not be negative", ErrInvalidData)
+		}
+		updates["capacity_mah"] = *params.CapacityMah
+	}
+	if params.RatedCycles != nil {
+		if *params.RatedCycles <= 0 {
+			return nil, fmt.Errorf("%w: rated_cycles must be positive", ErrInvalidData)
+		}
+		updates["rated_cycles"] = *params.RatedCycles
+	}
+	if params.StateOfHealth != nil {
+		if !validHealth(*params.StateOfHealth) {
+			return nil, fmt.Errorf("%w: state_of_health must be between 0 and 100", ErrInvalidData)
+		}
+		updates["state_of_health"] = *params.StateOfHealth
+	}
+	if params.Notes != nil {
+		updates["notes"] = *params.Notes
+	}
+	switch params.Status {
+	case "":
+	case models.BatteryPackRetired, models.BatteryPackAvailable:
+		if pack.Status == models.BatteryPackInstalled {
+			return nil, ErrBatteryPackInUse
+		}
+		updates["status"] = params.Status
+	default:
+		return nil, fmt.Errorf("%w: status can only be set to available or retired", ErrInvalidData)
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.WithContext(ctx).Model(pack).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update battery pack: %w", err)
+		}
+	}
+
+	pack, err = s.GetPack(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if params.StateOfHealth != nil {
+		s.checkHealth(ctx, pack)
+	}
+	return pack, nil
+}
+
+// InstallPack 安装电池组
+func (s *BatteryServiceImpl) InstallPack(ctx context.Context, packID, droneID, userID uint) error {
+	pack, err := s.GetPack(ctx, packID)
+	if err != nil {
+		return err
+	}
+	switch pack.Status {
+	case models.BatteryPackRetired:
+		return ErrBatteryPackRetired
+	case models.BatteryPackInstalled:
+		if pack.DroneID != nil && *pack.DroneID == droneID {
+			return nil
+		}
+		return ErrBatteryPackInUse
+	}
+
+	var drone models.Drone
+	if err := s.db.WithContext(ctx).First(&drone, droneID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDroneNotFound
+		}
+		return fmt.Errorf("failed to get drone: %w", err)
+	}
+	if drone.Status == models.DroneStatusFlying {
+		return ErrDroneInUse
+	}
+
+	now := time.Now()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 换电：先拆下无人机上的旧电池组
+		var current []*models.BatteryPack
+		if err := tx.Where("drone_id = ? AND status = ?", droneID, models.BatteryPackInstalled).Find(&current).Error; err != nil {
+			return err
+		}
+		for _, old := range current {
+			if err := removeInstalledPack(tx, old, userID, "swapped", now); err != nil {
+				return err
+			}
+		}
+
+		res := tx.Model(&models.BatteryPack{}).
+			Where("id = ? AND status = ?", packID, models.BatteryPackAvailable).
+			Updates(map[string]interface{}{
+				"status":       models.BatteryPackInstalled,
+				"drone_id":     droneID,
+				"installed_at": now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrBatteryPackInUse
+		}
+
+		return tx.Create(&models.BatteryInstallation{
+			PackID:          packID,
+			DroneID:         droneID,
+			InstalledAt:     now,
+			InstalledBy:     userID,
+			CyclesAtInstall: pack.CycleCount,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrBatteryPackInUse) {
+			return err
+		}
+		return fmt.Errorf("failed to install battery pack: %w", err)
+	}
+
+	s.setInstalled(droneID, packID)
+	s.logger.WithFields(map[string]interface{}{
+		"pack_id":  packID,
+		"serial":   pack.SerialNo,
+		"drone_id": droneID,
+	}).Info("Battery pack installed")
+	return nil
+}
+
+// RemovePack 拆下电池组
+func (s *BatteryServiceImpl) RemovePack(ctx context.Context, packID, userID uint, reason string) error {
+	pack, err := s.GetPack(ctx, packID)
+	if err != nil {
+		return err
+	}
+	if pack.Status != models.BatteryPackInstalled || pack.DroneID == nil {
+		return ErrBatteryPackNotInstalled
+	}
+	droneID := *pack.DroneID
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return removeInstalledPack(tx, pack, userID, reason, time.Now())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove battery pack: %w", err)
+	}
+
+	s.setInstalled(droneID, 0)
+	s.logger.WithFields(map[string]interface{}{
+		"pack_id":  packID,
+		"drone_id": droneID,
+		"reason":   reason,
+	}).Info("Battery pack removed")
+	return nil
+}
+
+// GetPackHistory 获取电池组安装历史
+func (s *BatteryServiceImpl) GetPackHistory(ctx context.Context, packID uint) ([]*models.BatteryInstallation, error) {
+	if _, err := s.GetPack(ctx, packID); err != nil {
+		return nil, err
+	}
+
+	var history []*models.BatteryInstallation
+	if err := s.db.WithContext(ctx).Where("pack_id = ?", packID).Order("installed_at DESC").Find(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to get battery pack history: %w", err)
+	}
+	return history, nil
+}
+
+// GetInstalledPack 获取无人机当前安装的电池组
+func (s *BatteryServiceImpl) GetInstalledPack(ctx context.Context, droneID uint) (*models.BatteryPack, error) {
+	s.installedMu.Lock()
+	entry, ok := s.installed[droneID]
+	s.installedMu.Unlock()
+
+	if ok && time.Since(entry.loadedAt) < batteryPackCacheTTL {
+		if entry.packID == 0 {
+			return nil, nil
+		}
+		pack, err := s.GetPack(ctx, entry.packID)
+		if err == nil && pack.Status == models.BatteryPackInstalled && pack.DroneID != nil && *pack.DroneID == droneID {
+			return pack, nil
+		}
+	}
+
+	var pack models.BatteryPack
+	err := s.db.WithContext(ctx).Where("drone_id = ? AND status = ?", droneID, models.BatteryPackInstalled).First(&pack).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.setInstalled(droneID, 0)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get installed battery pack: %w", err)
+	}
+
+	s.setInstalled(droneID, pack.ID)
+	return &pack, nil
+}
+
+// RecordReading 记录电量读数
+// 电量上升的部分累计为充入电量，每累计100%记为一次等效完整循环
+func (s *BatteryServiceImpl) RecordReading(ctx context.Context, droneID uint, level int, stateOfHealth *float64, at time.Time) error {
+	if level < 0 || level > 100 {
+		return ErrInvalidData
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	pack, err := s.GetInstalledPack(ctx, droneID)
+	if err != nil || pack == nil {
+		return err
+	}
+
+	// 乱序的旧读数不参与统计
+	if pack.LastReadingAt != nil && at.Before(*pack.LastReadingAt) {
+		return nil
+	}
+
+	updates := map[string]interface{}{
+		"last_level":      level,
+		"last_reading_at": at,
+	}
+	if pack.LastLevel != nil && level > *pack.LastLevel {
+		pack.ChargeThroughput += float64(level - *pack.LastLevel)
+		updates["charge_throughput"] = pack.ChargeThroughput
+
+		if cycles := int(pack.ChargeThroughput / 100); cycles > pack.CycleCount {
+			pack.CycleCount = cycles
+			updates["cycle_count"] = cycles
+		}
+	}
+
+	// 优先使用设备上报的健康度，否则按循环次数估算（估算值只降不升）
+	health := pack.StateOfHealth
+	if stateOfHealth != nil && validHealth(*stateOfHealth) {
+		health = *stateOfHealth
+	} else if estimated := pack.EstimatedHealth(); estimated < health {
+		health = estimated
+	}
+	if math.Abs(health-pack.StateOfHealth) >= 0.01 {
+		pack.StateOfHealth = health
+		updates["state_of_health"] = health
+	}
+
+	if pack.LastLevel != nil && *pack.LastLevel == level && len(updates) == 2 {
+		// 电量未变化，只在间隔较长时刷新读数时间
+		if pack.LastReadingAt != nil && at.Sub(*pack.LastReadingAt) < time.Minute {
+			return nil
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.BatteryPack{}).Where("id = ?", pack.ID).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update battery pack: %w", err)
+	}
+
+	if _, ok := updates["state_of_health"]; ok {
+		s.checkHealth(ctx, pack)
+	}
+	return nil
+}
+
+// checkHealth 健康度跌破阈值时产生电池告警，每个等级只告警一次
+func (s *BatteryServiceImpl) checkHealth(ctx context.Context, pack *models.BatteryPack) {
+	level := models.BatteryHealthGood
+	alertLevel := models.AlertLevelWarning
+	switch {
+	case pack.StateOfHealth < s.config.CriticalHealthThreshold:
+		level = models.BatteryHealthCritical
+		alertLevel = models.AlertLevelCritical
+	case pack.StateOfHealth < s.config.LowHealthThreshold:
+		level = models.BatteryHealthLow
+	}
+
+	if level == pack.HealthAlertLevel {
+		return
+	}
+
+	// 健康度回升（如人工校准）只重置告警等级
+	escalated := level != models.BatteryHealthGood &&
+		(pack.HealthAlertLevel == models.BatteryHealthGood || level == models.BatteryHealthCritical)
+
+	if err := s.db.WithContext(ctx).Model(&models.BatteryPack{}).Where("id = ?", pack.ID).
+		Update("health_alert_level", level).Error; err != nil {
+		s.logger.WithError(err).WithField("pack_id", pack.ID).Warn("Failed to update battery health alert level")
+		return
+	}
+	pack.HealthAlertLevel = level
+
+	if !escalated {
+		return
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"pack_id":         pack.ID,
+		"serial":          pack.SerialNo,
+		"state_of_health": pack.StateOfHealth,
+		"cycle_count":     pack.CycleCount,
+	}).Warn("Battery pack health degraded")
+
+	raiseAlert(ctx, s.alertService, s.kafkaService, s.logger, &CreateAlertParams{
+		Title: "电池健康度过低",
+		Message: fmt.Sprintf("电池组 %s 健康度降至 %.1f%%（循环 %d 次），建议更换",
+			pack.SerialNo, pack.StateOfHealth, pack.CycleCount),
+		Type:    models.AlertTypeBattery,
+		Level:   alertLevel,
+		Source:  "battery-service",
+		Code:    "BATTERY_LOW_HEALTH",
+		DroneID: pack.DroneID,
+	})
+}
+
+// setInstalled 更新无人机当前电池组缓存
+func (s *BatteryServiceImpl) setInstalled(droneID, packID uint) {
+	s.installedMu.Lock()
+	defer s.installedMu.Unlock()
+	s.installed[droneID] = installedPackEntry{packID: packID, loadedAt: time.Now()}
+}
+
+// removeInstalledPack 在事务内拆下电池组并关闭安装记录
+func removeInstalledPack(tx *gorm.DB, pack *models.BatteryPack, userID uint, reason string, at time.Time) error {
+	if err := tx.Model(&models.BatteryPack{}).Where("id = ?", pack.ID).Updates(map[string]interface{}{
+		"status":       models.BatteryPackAvailable,
+		"drone_id":     nil,
+		"installed_at": nil,
+	}).Error; err != nil {
+		return err
+	}
+
+	cycles := pack.CycleCount
+	return tx.Model(&models.BatteryInstallation{}).
+		Where("pack_id = ? AND removed_at IS NULL", pack.ID).
+		Updates(map[string]interface{}{
+			"removed_at":        at,
+			"removed_by":        userID,
+			"remove_reason":     reason,
+			"cycles_at_removal": cycles,
+		}).Error
+}
+
+// validHealth 健康度是否在合法范围
+func validHealth(health float64) bool {
+	return health >= 0 && health <= 100
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"drone-control-system/internal/mvc/models"
+)
+
+// CapabilityMismatchError 无人机能力不满足任务要求
+type CapabilityMismatchError struct {
+	DroneID uint     `json:"drone_id"`
+	Missing []string `json:"missing"`
+}
+
+func (e *CapabilityMismatchError) Error() string {
+	return fmt.Sprintf("drone %d does not satisfy required capabilities: %s", e.DroneID, strings.Join(e.Missing, ", "))
+}
+
+// Unwrap 支持 errors.Is(err, ErrCapabilityMismatch)
+func (e *CapabilityMismatchError) Unwrap() error {
+	return ErrCapabilityMismatch
+}
+
+// CheckDroneCapabilities 检查无人机是否满足能力要求
+func CheckDroneCapabilities(drone *models.Drone, req *models.CapabilityRequirements) error {
+	if req.IsEmpty() {
+		return nil
+	}
+
+	caps, err := drone.ParseCapabilities()
+	if err != nil {
+		return fmt.Errorf("%w: drone %d: %v", ErrInvalidCapabilities, drone.ID, err)
+	}
+
+	if missing := caps.Missing(req); len(missing) > 0 {
+		return &CapabilityMismatchError{DroneID: drone.ID, Missing: missing}
+	}
+	return nil
+}
+
+// FilterDronesByCapabilities 过滤出满足能力要求的无人机，供 GetAvailableDrones 实现使用
+func FilterDronesByCapabilities(drones []*models.Drone, req *models.CapabilityRequirements) []*models.Drone {
+	if req.IsEmpty() {
+		return drones
+	}
+
+	result := make([]*models.Drone, 0, len(drones))
+	for _, drone := range drones {
+		if CheckDroneCapabilities(drone, req) == nil {
+			result = append(result, drone)
+		}
+	}
+	return result
+}
+
+// CapabilityTaskService 在任务分配和启动时校验无人机能力的任务服务装饰器
+type CapabilityTaskService struct {
+	TaskService
+	droneService DroneService
+}
+
+// NewCapabilityTaskService 创建能力校验任务服务
+func NewCapabilityTaskService(next TaskService, droneService DroneService) TaskService {
+	return &CapabilityTaskService{
+		TaskService:  next,
+		droneService: droneService,
+	}
+}
+
+// CreateTask 创建任务前校验能力要求和指派的无人机
+func (s *CapabilityTaskService) CreateTask(ctx context.Context, params *CreateTaskParams) (*models.Task, error) {
+	if params.RequiredCapabilities != nil {
+		if err := params.RequiredCapabilities.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCapabilities, err)
+		}
+	}
+
+	if params.DroneID != 0 {
+		if err := s.checkAssignment(ctx, params.DroneID, params.RequiredCapabilities); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.TaskService.CreateTask(ctx, params)
+}
+
+// UpdateTask 更换无人机或修改能力要求时重新校验
+func (s *CapabilityTaskService) UpdateTask(ctx context.Context, id uint, params *UpdateTaskParams) (*models.Task, error) {
+	if params.DroneID == nil && params.RequiredCapabilities == nil {
+		return s.TaskService.UpdateTask(ctx, id, params)
+	}
+
+	task, err := s.TaskService.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	droneID := task.DroneID
+	if params.DroneID != nil {
+		droneID = *params.DroneID
+	}
+
+	req := params.RequiredCapabilities
+	if req != nil {
+		if err := req.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCapabilities, err)
+		}
+	} else if req, err = task.ParseRequiredCapabilities(); err != nil {
+		return nil, fmt.Errorf("%w: task %d: %v", ErrInvalidCapabilities, id, err)
+	}
+
+	if droneID != 0 {
+		if err := s.checkAssignment(ctx, droneID, req); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.TaskService.UpdateTask(ctx, id, params)
+}
+
+// StartTask 启动前再次校验，防止分配后无人机能力被修改
+func (s *CapabilityTaskService) StartTask(ctx context.Context, id uint) error {
+	task, err := s.TaskService.GetTaskByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	req, err := task.ParseRequiredCapabilities()
+	if err != nil {
+		return fmt.Errorf("%w: task %d: %v", ErrInvalidCapabilities, id, err)
+	}
+
+	if err := s.checkAssignment(ctx, task.DroneID, req); err != nil {
+		return err
+	}
+
+	return s.TaskService.StartTask(ctx, id)
+}
+
+// checkAssignment 校验指定无人机是否满足能力要求
+func (s *CapabilityTaskService) checkAssignment(ctx context.Context, droneID uint, req *models.CapabilityRequirements) error {
+	if req.IsEmpty() {
+		return nil
+	}
+
+	drone, err := s.droneService.GetDroneByID(ctx, droneID)
+	if err != nil {
+		return err
+	}
+
+	return CheckDroneCapabilities(drone, req)
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/coverage"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/logger"
+)
+
+// CoverageConfig 测绘覆盖规划配置
+type CoverageConfig struct {
+	DefaultSpeed float64 // 请求未指定时的航段飞行速度（m/s）
+	TurnMargin   float64 // 航段两端外延距离（米），掉头在测区外完成
+	TurnTime     float64 // 每次掉头的额外耗时（秒）
+	MaxDrones    int     // 单个测区最多切分的无人机数
+}
+
+// DefaultCoverageConfig 默认测绘覆盖规划配置
+func DefaultCoverageConfig() *CoverageConfig {
+	return &CoverageConfig{
+		DefaultSpeed: 8,
+		TurnMargin:   15,
+		TurnTime:     8,
+		MaxDrones:    10,
+	}
+}
+
+// CoverageParams 测绘覆盖规划参数
+type CoverageParams struct {
+	Polygon      []geo.Point       `json:"polygon"`
+	Altitude     float64           `json:"altitude"` // 相对起飞点高度（米）
+	Camera       coverage.Camera   `json:"camera"`
+	Sensor       models.SensorType `json:"sensor"`        // 拍照使用的传感器，为空时使用任意相机
+	FrontOverlap float64           `json:"front_overlap"` // 航向重叠率（%）
+	SideOverlap  float64           `json:"side_overlap"`  // 旁向重叠率（%）
+	Angle        *float64          `json:"angle"`         // 扫描线方位角（度），为空时自动选择
+	Speed        float64           `json:"speed"`         // 航段飞行速度（m/s），为0时使用默认值
+	Drones       int               `json:"drones"`        // 并行的无人机数，为0时为1
+}
+
+// CoveragePart 一架无人机负责的部分
+type CoveragePart struct {
+	Index     int             `json:"index"`
+	FirstLine int             `json:"first_line"`
+	LastLine  int             `json:"last_line"`
+	Photos    int             `json:"photos"`
+	Distance  float64         `json:"distance"` // 航程（米）
+	Duration  float64         `json:"duration"` // 估算飞行时间（秒），不含往返测区
+	Plan      models.TaskPlan `json:"plan"`
+}
+
+// CoverageResult 测绘覆盖规划结果
+type CoverageResult struct {
+	Angle           float64        `json:"angle"`
+	LineSpacing     float64        `json:"line_spacing"`
+	TriggerDistance float64        `json:"trigger_distance"`
+	FootprintWidth  float64        `json:"footprint_width"`
+	FootprintLength float64        `json:"footprint_length"`
+	Area            float64        `json:"area"`
+	Lines           int            `json:"lines"`
+	Photos          int            `json:"photos"`
+	Distance        float64        `json:"distance"`
+	Duration        float64        `json:"duration"`
+	Parts           []CoveragePart `json:"parts"`
+}
+
+// CoverageTaskParams 按覆盖规划创建测绘任务的参数
+type CoverageTaskParams struct {
+	CoverageParams
+	Name                 string                         `json:"name"`
+	Description          string                         `json:"description"`
+	Priority             models.TaskPriority            `json:"priority"`
+	UserID               uint                           `json:"user_id"`
+	DroneIDs             []uint                         `json:"drone_ids"` // 按部分顺序指定无人机，为空时逐个自动分配不同的无人机
+	ScheduledAt          *time.Time                     `json:"scheduled_at"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+	AssignmentScope      *FleetScope                    `json:"assignment_scope"` // 自动分配时可选择的机队范围
+}
+
+// CoverageTasks 创建的测绘任务及对应的规划
+type CoverageTasks struct {
+	Coverage *CoverageResult `json:"coverage"`
+	Tasks    []*models.Task  `json:"tasks"`
+}
+
+// CoverageService 测绘覆盖规划服务接口
+type CoverageService interface {
+	// PlanCoverage 生成测区的往返式航线和拍照点，并按无人机数切分，不保存
+	PlanCoverage(ctx context.Context, params *CoverageParams) (*CoverageResult, error)
+	// CreateCoverageTasks 按覆盖规划为每架无人机创建一个测绘任务
+	CreateCoverageTasks(ctx context.Context, params *CoverageTaskParams) (*CoverageTasks, error)
+}
+
+// CoverageServiceImpl 测绘覆盖规划服务实现
+type CoverageServiceImpl struct {
+	config            *CoverageConfig
+	taskService       TaskService
+	assignmentService AssignmentService
+	logger            *logger.Logger
+}
+
+// NewCoverageService 创建测绘覆盖规划服务
+func NewCoverageService(
+	config *CoverageConfig,
+	taskService TaskService,
+	assignmentService AssignmentService,
+	logger *logger.Logger,
+) CoverageService {
+	if config == nil {
+		config = DefaultCoverageConfig()
+	}
+
+	return &CoverageServiceImpl{
+		config:            config,
+		taskService:       taskService,
+		assignmentService: assignmentService,
+		logger:            logger,
+	}
+}
+
+// PlanCoverage 生成覆盖航线
+func (s *CoverageServiceImpl) PlanCoverage(ctx context.Context, params *CoverageParams) (*CoverageResult, error) {
+	if params == nil {
+		return nil, fmt.Errorf("%w: coverage parameters are required", ErrInvalidCoverage)
+	}
+	drones := params.Drones
+	if drones == 0 {
+		drones = 1
+	}
+	if drones < 0 || drones > s.config.MaxDrones {
+		return nil, fmt.Errorf("%w: drones must be between 1 and %d", ErrInvalidCoverage, s.config.MaxDrones)
+	}
+	if params.Sensor != "" && !params.Sensor.IsValid() {
+		return nil, fmt.Errorf("%w: unknown sensor type: %s", ErrInvalidCoverage, params.Sensor)
+	}
+	speed := params.Speed
+	if speed == 0 {
+		speed = s.config.DefaultSpeed
+	}
+
+	plan, err := coverage.Generate(coverage.Params{
+		Polygon:      params.Polygon,
+		Altitude:     params.Altitude,
+		Camera:       params.Camera,
+		FrontOverlap: params.FrontOverlap,
+		SideOverlap:  params.SideOverlap,
+		Angle:        params.Angle,
+		Speed:        speed,
+		TurnMargin:   s.config.TurnMargin,
+		TurnTime:     s.config.TurnTime,
+		Parts:        drones,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCoverage, err)
+	}
+
+	result := &CoverageResult{
+		Angle:           plan.Angle,
+		LineSpacing:     plan.LineSpacing,
+		TriggerDistance: plan.TriggerDistance,
+		FootprintWidth:  plan.FootprintWidth,
+		FootprintLength: plan.FootprintLength,
+		Area:            plan.Area,
+		Lines:           plan.Lines,
+		Photos:          plan.Photos,
+		Distance:        plan.Distance,
+		Duration:        plan.Duration,
+		Parts:           make([]CoveragePart, len(plan.Parts)),
+	}
+	for i, part := range plan.Parts {
+		if len(part.Points) > models.MaxMissionWaypoints {
+			return nil, fmt.Errorf("%w: part %d needs %d waypoints (limit %d), use more drones, a higher altitude or lower overlap",
+				ErrInvalidCoverage, i, len(part.Points), models.MaxMissionWaypoints)
+		}
+		result.Parts[i] = CoveragePart{
+			Index:     part.Index,
+			FirstLine: part.FirstLine,
+			LastLine:  part.LastLine,
+			Photos:    part.Photos,
+			Distance:  part.Distance,
+			Duration:  part.Duration,
+			Plan:      coverageTaskPlan(part, params.Altitude, speed, params.Sensor),
+		}
+	}
+	return result, nil
+}
+
+// CreateCoverageTasks 规划后为每个部分创建测绘任务，任一任务创建失败时删除已创建的任务
+func (s *CoverageServiceImpl) CreateCoverageTasks(ctx context.Context, params *CoverageTaskParams) (*CoverageTasks, error) {
+	if len(params.DroneIDs) > 0 {
+		if params.Drones != 0 && params.Drones != len(params.DroneIDs) {
+			return nil, fmt.Errorf("%w: drones (%d) does not match drone_ids (%d)", ErrInvalidCoverage, params.Drones, len(params.DroneIDs))
+		}
+		params.Drones = len(params.DroneIDs)
+		seen := make(map[uint]bool, len(params.DroneIDs))
+		for _, id := range params.DroneIDs {
+			if id == 0 || seen[id] {
+				return nil, fmt.Errorf("%w: drone_ids must be distinct and non-zero", ErrInvalidCoverage)
+			}
+			seen[id] = true
+		}
+	}
+
+	result, err := s.PlanCoverage(ctx, &params.CoverageParams)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*models.Task, 0, len(result.Parts))
+	used := make([]uint, 0, len(result.Parts))
+	for i, part := range result.Parts {
+		create := &CreateTaskParams{
+			Name:                 params.Name,
+			Description:          params.Description,
+			Type:                 models.TaskTypeMapping,
+			Priority:             params.Priority,
+			UserID:               params.UserID,
+			Plan:                 part.Plan,
+			ScheduledAt:          params.ScheduledAt,
+			RequiredCapabilities: params.RequiredCapabilities,
+			AssignmentScope:      params.AssignmentScope,
+		}
+		if len(result.Parts) > 1 {
+			create.Name = fmt.Sprintf("%s (%d/%d)", params.Name, i+1, len(result.Parts))
+		}
+
+		// 多机并行时各部分必须由不同的无人机执行，自动分配时排除已选的无人机
+		if len(params.DroneIDs) > 0 {
+			create.DroneID = params.DroneIDs[i]
+		} else {
+			candidate, err := s.assignmentService.SelectDrone(ctx, &AssignmentRequest{
+				Plan:                 part.Plan,
+				RequiredCapabilities: params.RequiredCapabilities,
+				ExcludeDroneIDs:      used,
+				Scope:                params.AssignmentScope,
+			})
+			if err != nil {
+				s.rollback(ctx, tasks)
+				return nil, err
+			}
+			create.DroneID = candidate.DroneID
+			create.AutoAssigned = true
+			create.AssignmentNote = candidate.Explanation
+		}
+
+		task, err := s.taskService.CreateTask(ctx, create)
+		if err != nil {
+			s.rollback(ctx, tasks)
+			return nil, err
+		}
+		tasks = append(tasks, task)
+		used = append(used, task.DroneID)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"tasks":    len(tasks),
+		"lines":    result.Lines,
+		"photos":   result.Photos,
+		"area":     result.Area,
+		"duration": result.Duration,
+	}).Info("Coverage mapping tasks created")
+
+	return &CoverageTasks{Coverage: result, Tasks: tasks}, nil
+}
+
+// rollback 删除部分创建成功的任务
+func (s *CoverageServiceImpl) rollback(ctx context.Context, tasks []*models.Task) {
+	for _, task := range tasks {
+		if err := s.taskService.DeleteTask(ctx, task.ID); err != nil && !errors.Is(err, ErrTaskNotFound) {
+			s.logger.WithFields(map[string]interface{}{
+				"task_id": task.ID,
+				"error":   err.Error(),
+			}).Error("Failed to delete coverage task after partial failure")
+		}
+	}
+}
+
+// coverageTaskPlan 将覆盖航线转换为任务计划：拍照点带拍照动作，航段外延点仅用于进入和掉头
+func coverageTaskPlan(part coverage.Part, altitude, speed float64, sensor models.SensorType) models.TaskPlan {
+	waypoints := make(models.WaypointList, len(part.Points))
+	photo := 0
+	for i, p := range part.Points {
+		wp := models.Waypoint{
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Altitude:  altitude,
+			Speed:     speed,
+		}
+		if p.Photo {
+			photo++
+			wp.Name = fmt.Sprintf("line-%d-photo-%d", p.Line, photo)
+			wp.Actions = []models.WaypointAction{{Type: models.WaypointActionCapture, Sensor: sensor}}
+		} else {
+			wp.Name = fmt.Sprintf("line-%d-turn", p.Line)
+		}
+		waypoints[i] = wp
+	}
+
+	return models.TaskPlan{
+		Waypoints:   waypoints,
+		MaxAltitude: altitude,
+		MaxSpeed:    speed,
+	}
+}
@@ -0,0 +1,481 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DeliveryConfig 配送任务配置
+type DeliveryConfig struct {
+	CruiseAltitude  float64       // 自动生成航点时的飞行高度（米）
+	HandoffAltitude float64       // 绞盘和投放交付时在投递点上方的悬停高度（米）
+	HandoffHoldTime float64       // 在取货点和投递点的停留时间（秒）
+	ProofRadius     float64       // 交付证明定位与投递点的最大距离（米）
+	ProofMaxAge     time.Duration // 交付证明拍摄时间与记录时间的最大间隔
+}
+
+// DefaultDeliveryConfig 默认配送任务配置
+func DefaultDeliveryConfig() *DeliveryConfig {
+	return &DeliveryConfig{
+		CruiseAltitude:  60,
+		HandoffAltitude: 10,
+		HandoffHoldTime: 30,
+		ProofRadius:     50,
+		ProofMaxAge:     10 * time.Minute,
+	}
+}
+
+// DeliveryMilestoneParams 记录配送状态节点参数
+type DeliveryMilestoneParams struct {
+	Status    models.DeliveryStatus `json:"status"`
+	Latitude  *float64              `json:"latitude"` // 为空时使用无人机当前位置
+	Longitude *float64              `json:"longitude"`
+	Note      string                `json:"note"`
+	UserID    uint                  `json:"user_id"`
+	Proof     *DeliveryProofParams  `json:"proof"` // delivered 时必填
+}
+
+// DeliveryProofParams 交付证明参数
+type DeliveryProofParams struct {
+	PhotoRef   string     `json:"photo_ref"`
+	Latitude   *float64   `json:"latitude"` // 为空时使用无人机当前位置
+	Longitude  *float64   `json:"longitude"`
+	Altitude   float64    `json:"altitude"`
+	Accuracy   float64    `json:"accuracy"`
+	CapturedAt *time.Time `json:"captured_at"` // 为空时使用记录时间
+}
+
+// DeliveryService 配送任务服务接口
+type DeliveryService interface {
+	// PrepareDelivery 校验配送信息并补全计划：校验载荷，未给出航点时按取货点和投递点生成
+	PrepareDelivery(delivery *models.TaskDelivery, plan *models.TaskPlan) error
+	// SaveDelivery 保存任务的配送信息
+	SaveDelivery(ctx context.Context, taskID uint, delivery *models.TaskDelivery) error
+	// RecordMilestone 记录配送状态节点，交付时保存交付证明，并发布任务事件
+	RecordMilestone(ctx context.Context, taskID uint, params *DeliveryMilestoneParams) (*models.Task, error)
+}
+
+// DeliveryServiceImpl 配送任务服务实现
+type DeliveryServiceImpl struct {
+	config       *DeliveryConfig
+	db           *gorm.DB
+	kafkaService KafkaService
+	logger       *logger.Logger
+}
+
+// NewDeliveryService 创建配送任务服务
+func NewDeliveryService(config *DeliveryConfig, db *gorm.DB, kafkaService KafkaService, logger *logger.Logger) DeliveryService {
+	if config == nil {
+		config = DefaultDeliveryConfig()
+	}
+
+	return &DeliveryServiceImpl{
+		config:       config,
+		db:           db,
+		kafkaService: kafkaService,
+		logger:       logger,
+	}
+}
+
+// PrepareDelivery 校验配送信息，重置状态为待取货
+func (s *DeliveryServiceImpl) PrepareDelivery(delivery *models.TaskDelivery, plan *models.TaskPlan) error {
+	var problems []string
+	if !delivery.Handoff.IsValid() {
+		problems = append(problems, fmt.Sprintf("unknown handoff method: %q", delivery.Handoff))
+	}
+	for _, loc := range []struct {
+		name     string
+		location models.DeliveryLocation
+	}{{"pickup", delivery.Pickup}, {"dropoff", delivery.Dropoff}} {
+		lat, lon := loc.location.Latitude, loc.location.Longitude
+		if lat == 0 && lon == 0 {
+			problems = append(problems, loc.name+" location is required")
+		} else if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+			problems = append(problems, loc.name+" location out of range")
+		}
+	}
+	if strings.TrimSpace(delivery.Recipient.Name) == "" {
+		problems = append(problems, "recipient name is required")
+	}
+	if delivery.Recipient.Phone == "" && delivery.Recipient.Email == "" {
+		problems = append(problems, "recipient phone or email is required")
+	}
+	if plan.Payload.Weight <= 0 {
+		problems = append(problems, "plan payload weight is required")
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidDelivery, strings.Join(problems, "; "))
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Milestones = nil
+	if plan.Payload.Type == "" {
+		plan.Payload.Type = "parcel"
+	}
+	if len(plan.Waypoints) == 0 {
+		plan.Waypoints = s.deliveryWaypoints(delivery)
+	}
+	return nil
+}
+
+// deliveryWaypoints 生成取货点到投递点的航点，投递点按交付方式设置高度和动作
+func (s *DeliveryServiceImpl) deliveryWaypoints(delivery *models.TaskDelivery) models.WaypointList {
+	cruise := s.config.CruiseAltitude
+	pickup := models.Waypoint{
+		Latitude:  delivery.Pickup.Latitude,
+		Longitude: delivery.Pickup.Longitude,
+		Altitude:  delivery.Pickup.Altitude,
+		HoldTime:  s.config.HandoffHoldTime,
+		Name:      "pickup",
+	}
+	dropoff := models.Waypoint{
+		Latitude:  delivery.Dropoff.Latitude,
+		Longitude: delivery.Dropoff.Longitude,
+		Name:      "dropoff",
+	}
+
+	switch delivery.Handoff {
+	case models.HandoffLanding:
+		dropoff.Altitude = delivery.Dropoff.Altitude
+		dropoff.HoldTime = s.config.HandoffHoldTime
+	case models.HandoffWinch:
+		dropoff.Altitude = delivery.Dropoff.Altitude + s.config.HandoffAltitude
+		dropoff.Actions = []models.WaypointAction{
+			{Type: models.WaypointActionHover, Duration: s.config.HandoffHoldTime},
+			{Type: models.WaypointActionDropPayload},
+		}
+	case models.HandoffDrop:
+		dropoff.Altitude = delivery.Dropoff.Altitude + s.config.HandoffAltitude
+		dropoff.Actions = []models.WaypointAction{{Type: models.WaypointActionDropPayload}}
+	}
+
+	return models.WaypointList{
+		pickup,
+		{Latitude: pickup.Latitude, Longitude: pickup.Longitude, Altitude: cruise, Name: "pickup-climb"},
+		{Latitude: dropoff.Latitude, Longitude: dropoff.Longitude, Altitude: cruise, Name: "dropoff-approach"},
+		dropoff,
+	}
+}
+
+// SaveDelivery 保存配送信息
+func (s *DeliveryServiceImpl) SaveDelivery(ctx context.Context, taskID uint, delivery *models.TaskDelivery) error {
+	if err := s.db.WithContext(ctx).Model(&models.Task{}).Where("id = ?", taskID).
+		Update("delivery", *delivery).Error; err != nil {
+		return fmt.Errorf("failed to save task delivery: %w", err)
+	}
+	return nil
+}
+
+// RecordMilestone 锁定任务行后检查状态流转，多实例同时记录同一节点时只有一个生效
+func (s *DeliveryServiceImpl) RecordMilestone(ctx context.Context, taskID uint, params *DeliveryMilestoneParams) (*models.Task, error) {
+	now := time.Now()
+	var task models.Task
+	var previous models.DeliveryStatus
+	var milestone models.DeliveryMilestone
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task, taskID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTaskNotFound
+			}
+			return fmt.Errorf("failed to lock task: %w", err)
+		}
+		if task.Type != models.TaskTypeDelivery || task.Delivery.IsZero() {
+			return fmt.Errorf("%w: task %d is not a delivery task", ErrInvalidDelivery, taskID)
+		}
+
+		previous = task.Delivery.Status
+		if !previous.CanTransitionTo(params.Status) {
+			return fmt.Errorf("%w: %s -> %s", ErrDeliveryTransition, previous, params.Status)
+		}
+		// 取货、出发和交付发生在执行中，带回载荷也可能在任务失败或停止后记录
+		if params.Status != models.DeliveryReturned && task.Status != models.TaskStatusRunning {
+			return fmt.Errorf("%w: task is %s", ErrDeliveryTransition, task.Status)
+		}
+
+		lat, lon, err := s.position(tx, &task, params.Latitude, params.Longitude)
+		if err != nil {
+			return err
+		}
+		milestone = models.DeliveryMilestone{
+			Status:    params.Status,
+			At:        now,
+			Latitude:  lat,
+			Longitude: lon,
+			UserID:    params.UserID,
+			Note:      params.Note,
+		}
+
+		updates := map[string]interface{}{}
+		if params.Status == models.DeliveryDelivered {
+			proof, err := s.proof(tx, &task, params.Proof, now)
+			if err != nil {
+				return err
+			}
+			task.Result.Proof = *proof
+			updates["result_proof"] = *proof
+		}
+
+		task.Delivery.Status = params.Status
+		task.Delivery.Milestones = append(task.Delivery.Milestones, milestone)
+		updates["delivery"] = task.Delivery
+		if err := tx.Model(&models.Task{}).Where("id = ?", task.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to record delivery milestone: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"task_id":  task.ID,
+		"drone_id": task.DroneID,
+		"previous": previous,
+		"status":   params.Status,
+	}).Info("Delivery milestone recorded")
+	s.publishMilestone(ctx, &task, previous, &milestone)
+
+	return &task, nil
+}
+
+// position 使用给出的坐标，未给出时使用无人机当前位置
+func (s *DeliveryServiceImpl) position(tx *gorm.DB, task *models.Task, lat, lon *float64) (float64, float64, error) {
+	if (lat == nil) != (lon == nil) {
+		return 0, 0, fmt.Errorf("%w: latitude and longitude must be given together", ErrInvalidDelivery)
+	}
+	if lat != nil {
+		if *lat < -90 || *lat > 90 || *lon < -180 || *lon > 180 {
+			return 0, 0, fmt.Errorf("%w: position out of range", ErrInvalidDelivery)
+		}
+		return *lat, *lon, nil
+	}
+
+	var drone models.Drone
+	if err := tx.Select("id", "pos_latitude", "pos_longitude").First(&drone, task.DroneID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, 0, ErrDroneNotFound
+		}
+		return 0, 0, fmt.Errorf("failed to get drone position: %w", err)
+	}
+	return drone.Position.Latitude, drone.Position.Longitude, nil
+}
+
+// proof 校验交付证明：必须有照片，定位在投递点附近，拍摄时间不早于取货且不晚于当前
+func (s *DeliveryServiceImpl) proof(tx *gorm.DB, task *models.Task, params *DeliveryProofParams, now time.Time) (*models.DeliveryProof, error) {
+	if params == nil || strings.TrimSpace(params.PhotoRef) == "" {
+		return nil, fmt.Errorf("%w: proof of delivery with photo_ref is required", ErrInvalidDelivery)
+	}
+
+	lat, lon, err := s.position(tx, task, params.Latitude, params.Longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	capturedAt := now
+	if params.CapturedAt != nil {
+		capturedAt = *params.CapturedAt
+	}
+	if capturedAt.After(now.Add(time.Minute)) {
+		return nil, fmt.Errorf("%w: proof captured_at is in the future", ErrInvalidDelivery)
+	}
+	if s.config.ProofMaxAge > 0 && now.Sub(capturedAt) > s.config.ProofMaxAge {
+		return nil, fmt.Errorf("%w: proof is older than %s", ErrInvalidDelivery, s.config.ProofMaxAge)
+	}
+	for _, m := range task.Delivery.Milestones {
+		if m.Status == models.DeliveryPickedUp && capturedAt.Before(m.At) {
+			return nil, fmt.Errorf("%w: proof was captured before pickup", ErrInvalidDelivery)
+		}
+	}
+
+	dropoff := task.Delivery.Dropoff
+	distance := geo.Distance(lat, lon, dropoff.Latitude, dropoff.Longitude)
+	if s.config.ProofRadius > 0 && distance > s.config.ProofRadius {
+		return nil, fmt.Errorf("%w: proof location is %.0fm from dropoff (limit %.0fm)", ErrInvalidDelivery, distance, s.config.ProofRadius)
+	}
+
+	return &models.DeliveryProof{
+		PhotoRef:   strings.TrimSpace(params.PhotoRef),
+		Latitude:   lat,
+		Longitude:  lon,
+		Altitude:   params.Altitude,
+		Accuracy:   params.Accuracy,
+		CapturedAt: capturedAt,
+		Distance:   distance,
+	}, nil
+}
+
+// publishMilestone 发布配送状态节点事件
+func (s *DeliveryServiceImpl) publishMilestone(ctx context.Context, task *models.Task, previous models.DeliveryStatus, milestone *models.DeliveryMilestone) {
+	if s.kafkaService == nil {
+		return
+	}
+
+	data := kafka.TaskDeliveryEventData{
+		TaskID:         task.ID,
+		TaskName:       task.Name,
+		DroneID:        task.DroneID,
+		Status:         string(milestone.Status),
+		PreviousStatus: string(previous),
+		Latitude:       milestone.Latitude,
+		Longitude:      milestone.Longitude,
+		Note:           milestone.Note,
+		Timestamp:      milestone.At,
+	}
+	if milestone.Status == models.DeliveryDelivered {
+		data.Proof = task.Result.Proof
+	}
+	if err := s.kafkaService.PublishTaskEvent(ctx, kafka.TaskDeliveryMilestoneEvent, data); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to publish delivery milestone event")
+	}
+}
+
+// withPayloadRequirement 把载荷重量合并到能力要求中，使指派校验和自动分配只考虑载重足够的无人机
+func withPayloadRequirement(req *models.CapabilityRequirements, weight float64) *models.CapabilityRequirements {
+	if weight <= 0 {
+		return req
+	}
+	merged := models.CapabilityRequirements{}
+	if req != nil {
+		merged = *req
+	}
+	if merged.MinPayloadCapacity < weight {
+		merged.MinPayloadCapacity = weight
+	}
+	return &merged
+}
+
+// DeliveryTaskService 校验和保存配送信息的任务服务装饰器
+type DeliveryTaskService struct {
+	TaskService
+	deliveryService DeliveryService
+}
+
+// NewDeliveryTaskService 创建配送任务服务
+func NewDeliveryTaskService(next TaskService, deliveryService DeliveryService) TaskService {
+	return &DeliveryTaskService{
+		TaskService:     next,
+		deliveryService: deliveryService,
+	}
+}
+
+// CreateTask 配送任务必须给出配送信息，创建后保存
+func (s *DeliveryTaskService) CreateTask(ctx context.Context, params *CreateTaskParams) (*models.Task, error) {
+	if params.Type != models.TaskTypeDelivery {
+		if params.Delivery != nil {
+			return nil, fmt.Errorf("%w: delivery details are only allowed for delivery tasks", ErrInvalidDelivery)
+		}
+		return s.TaskService.CreateTask(ctx, params)
+	}
+	if params.Delivery == nil {
+		return nil, fmt.Errorf("%w: delivery tasks require delivery details", ErrInvalidDelivery)
+	}
+
+	delivery := *params.Delivery
+	if err := s.deliveryService.PrepareDelivery(&delivery, &params.Plan); err != nil {
+		return nil, err
+	}
+	params.RequiredCapabilities = withPayloadRequirement(params.RequiredCapabilities, params.Plan.Payload.Weight)
+
+	task, err := s.TaskService.CreateTask(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.deliveryService.SaveDelivery(ctx, task.ID, &delivery); err != nil {
+		return nil, err
+	}
+	task.Delivery = delivery
+	return task, nil
+}
+
+// UpdateTask 取货前可以修改配送信息，修改计划时重新校验载荷
+func (s *DeliveryTaskService) UpdateTask(ctx context.Context, id uint, params *UpdateTaskParams) (*models.Task, error) {
+	if params.Delivery == nil && params.Plan == nil && params.Type == "" {
+		return s.TaskService.UpdateTask(ctx, id, params)
+	}
+
+	current, err := s.TaskService.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	taskType := current.Type
+	if params.Type != "" {
+		taskType = params.Type
+	}
+	if taskType != models.TaskTypeDelivery {
+		if params.Delivery != nil {
+			return nil, fmt.Errorf("%w: delivery details are only allowed for delivery tasks", ErrInvalidDelivery)
+		}
+		return s.TaskService.UpdateTask(ctx, id, params)
+	}
+
+	delivery := current.Delivery
+	if params.Delivery != nil {
+		if !current.Delivery.IsZero() && current.Delivery.Status != models.DeliveryPending {
+			return nil, fmt.Errorf("%w: delivery is already %s", ErrDeliveryTransition, current.Delivery.Status)
+		}
+		delivery = *params.Delivery
+	} else if delivery.IsZero() {
+		return nil, fmt.Errorf("%w: delivery tasks require delivery details", ErrInvalidDelivery)
+	}
+
+	plan := current.Plan
+	if params.Plan != nil {
+		plan = *params.Plan
+	}
+	if params.Delivery != nil || params.Plan != nil {
+		if delivery.Status == "" || delivery.Status == models.DeliveryPending {
+			if err := s.deliveryService.PrepareDelivery(&delivery, &plan); err != nil {
+				return nil, err
+			}
+		} else if plan.Payload.Weight <= 0 {
+			return nil, fmt.Errorf("%w: plan payload weight is required", ErrInvalidDelivery)
+		}
+		params.Plan = &plan
+	}
+
+	req := params.RequiredCapabilities
+	if req == nil {
+		if req, err = current.ParseRequiredCapabilities(); err != nil {
+			return nil, fmt.Errorf("%w: task %d: %v", ErrInvalidCapabilities, id, err)
+		}
+	}
+	params.RequiredCapabilities = withPayloadRequirement(req, plan.Payload.Weight)
+
+	task, err := s.TaskService.UpdateTask(ctx, id, params)
+	if err != nil {
+		return nil, err
+	}
+	if params.Delivery != nil || current.Delivery.IsZero() {
+		if err := s.deliveryService.SaveDelivery(ctx, id, &delivery); err != nil {
+			return nil, err
+		}
+		task.Delivery = delivery
+	}
+	return task, nil
+}
+
+// CompleteTask 配送任务在记录交付证明后才能成功完成
+func (s *DeliveryTaskService) CompleteTask(ctx context.Context, id uint, success bool, message string) error {
+	if success {
+		task, err := s.TaskService.GetTaskByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if task.Type == models.TaskTypeDelivery && task.Delivery.Status != models.DeliveryDelivered {
+			return fmt.Errorf("%w: delivery is %s", ErrDeliveryNotConfirmed, task.Delivery.Status)
+		}
+	}
+	return s.TaskService.CompleteTask(ctx, id, success, message)
+}
@@ -0,0 +1,540 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// deviceKeyScheme API密钥前缀标识，格式为 dk_<prefix>_<secret>
+	deviceKeyScheme = "dk"
+	// deviceUsageWriteInterval 凭证最近使用时间的最小写入间隔
+	deviceUsageWriteInterval = time.Minute
+)
+
+// DeviceConfig 设备接入配置
+type DeviceConfig struct {
+	RotationGracePeriod time.Duration `yaml:"rotation_grace_period" json:"rotation_grace_period"` // 轮换后旧凭证的保留时间
+	CommandTTL          time.Duration `yaml:"command_ttl" json:"command_ttl"`                     // 指令未被拉取的过期时间
+	FingerprintHeader   string        `yaml:"fingerprint_header" json:"fingerprint_header"`       // TLS终止代理传递证书指纹的请求头，为空时只信任直连TLS
+}
+
+// DefaultDeviceConfig 默认设备接入配置
+func DefaultDeviceConfig() *DeviceConfig {
+	return &DeviceConfig{
+		RotationGracePeriod: 24 * time.Hour,
+		CommandTTL:          10 * time.Minute,
+	}
+}
+
+// IssueCredentialParams 签发设备凭证参数
+type IssueCredentialParams struct {
+	Type        models.DeviceCredentialType `json:"type"`
+	Fingerprint string                      `json:"fingerprint"` // mtls 类型必填
+	Description string                      `json:"description"`
+	ExpiresAt   *time.Time                  `json:"expires_at"`
+	CreatedBy   uint                        `json:"created_by"`
+}
+
+// IssuedCredential 签发结果，APIKey 明文只在签发时返回一次
+type IssuedCredential struct {
+	Credential *models.DeviceCredential `json:"credential"`
+	APIKey     string                   `json:"api_key,omitempty"`
+}
+
+// IssueCommandParams 下发指令参数
+type IssueCommandParams struct {
+	DroneID  uint                   `json:"drone_id"`
+	Command  models.DroneCommand    `json:"command"`
+	Params   map[string]interface{} `json:"params"`
+	FleetID  *uint                  `json:"fleet_id"`
+	IssuedBy uint                   `json:"issued_by"`
+}
+
+// DeviceService 设备接入服务接口
+type DeviceService interface {
+	// 凭证管理
+	IssueCredential(ctx context.Context, droneID uint, params *IssueCredentialParams) (*IssuedCredential, error)
+	ListCredentials(ctx context.Context, droneID uint) ([]*models.DeviceCredential, error)
+	// RotateCredential 签发同类型的新凭证，旧凭证在宽限期后失效
+	RotateCredential(ctx context.Context, droneID, credentialID uint, params *IssueCredentialParams) (*IssuedCredential, error)
+	RevokeCredential(ctx context.Context, droneID, credentialID, userID uint) error
+
+	// CheckFingerprint 检查证书指纹格式，以及是否已绑定到其他无人机
+	CheckFingerprint(ctx context.Context, fingerprint string) error
+
+	// 设备认证，apiKey和fingerprint二选一
+	Authenticate(ctx context.Context, apiKey, fingerprint string) (*models.DeviceCredential, error)
+
+	// 指令通道
+	IssueCommand(ctx context.Context, params *IssueCommandParams) (*models.DroneCommandRecord, error)
+	ListCommands(ctx context.Context, droneID uint, limit int) ([]*models.DroneCommandRecord, error)
+	// PollCommands 设备拉取待执行指令，返回的指令标记为已送达
+	PollCommands(ctx context.Context, droneID uint) ([]*models.DroneCommandRecord, error)
+	AcknowledgeCommand(ctx context.Context, droneID uint, commandID string, success bool, result string) error
+}
+
+// DeviceServiceImpl 设备接入服务实现
+type DeviceServiceImpl struct {
+	config       *DeviceConfig
+	db           *gorm.DB
+	kafkaService KafkaService
+	logger       *logger.Logger
+
+	lastUsed   map[uint]time.Time // 凭证最近一次写入使用时间
+	lastUsedMu sync.Mutex
+}
+
+// NewDeviceService 创建设备接入服务
+func NewDeviceService(config *DeviceConfig, db *gorm.DB, kafkaService KafkaService, logger *logger.Logger) DeviceService {
+	if config == nil {
+		config = DefaultDeviceConfig()
+	}
+
+	return &DeviceServiceImpl{
+		config:       config,
+		db:           db,
+		kafkaService: kafkaService,
+		logger:       logger,
+		lastUsed:     make(map[uint]time.Time),
+	}
+}
+
+// IssueCredential 签发设备凭证
+func (s *DeviceServiceImpl) IssueCredential(ctx context.Context, droneID uint, params *IssueCredentialParams) (*IssuedCredential, error) {
+	if err := s.checkDrone(ctx, droneID); err != nil {
+		return nil, err
+	}
+
+	credential, apiKey, err := s.buildCredential(ctx, droneID, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Create(credential).Error; err != nil {
+		return nil, fmt.Errorf("failed to create device credential: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"drone_id":      droneID,
+		"credential_id": credential.ID,
+		"type":          credential.Type,
+	}).Info("Device credential issued")
+	return &IssuedCredential{Credential: credential, APIKey: apiKey}, nil
+}
+
+// ListCredentials 获取无人机的设备凭证
+func (s *DeviceServiceImpl) ListCredentials(ctx context.Context, droneID uint) ([]*models.DeviceCredential, error) {
+	var credentials []*models.DeviceCredential
+	if err := s.db.WithContext(ctx).Where("drone_id = ?", droneID).Order("id DESC").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("failed to list device credentials: %w", err)
+	}
+	return credentials, nil
+}
+
+// RotateCredential 轮换设备凭证
+func (s *DeviceServiceImpl) RotateCredential(ctx context.Context, droneID, credentialID uint, params *IssueCredentialParams) (*IssuedCredential, error) {
+	old, err := s.getCredential(ctx, droneID, credentialID)
+	if err != nil {
+		return nil, err
+	}
+	if !old.IsUsable(time.Now()) {
+		return nil, ErrCredentialRevoked
+	}
+
+	rotated := *params
+	rotated.Type = old.Type
+	if rotated.Description == "" {
+		rotated.Description = old.Description
+	}
+	if old.Type == models.DeviceCredentialMTLS && rotated.Fingerprint == "" {
+		return nil, fmt.Errorf("%w: fingerprint of the new certificate is required", ErrInvalidData)
+	}
+
+	credential, apiKey, err := s.buildCredential(ctx, droneID, &rotated)
+	if err != nil {
+		return nil, err
+	}
+	credential.RotatedFrom = &old.ID
+
+	graceUntil := time.Now().Add(s.config.RotationGracePeriod)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(credential).Error; err != nil {
+			return err
+		}
+		// 旧凭证保留宽限期，便于设备切换
+		if old.ExpiresAt == nil || old.ExpiresAt.After(graceUntil) {
+			return tx.Model(old).Update("expires_at", graceUntil).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate device credential: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"drone_id":       droneID,
+		"credential_id":  credential.ID,
+		"rotated_from":   old.ID,
+		"old_expires_at": graceUntil,
+	}).Info("Device credential rotated")
+	return &IssuedCredential{Credential: credential, APIKey: apiKey}, nil
+}
+
+// RevokeCredential 吊销设备凭证，立即生效
+func (s *DeviceServiceImpl) RevokeCredential(ctx context.Context, droneID, credentialID, userID uint) error {
+	now := time.Now()
+	res := s.db.WithContext(ctx).Model(&models.DeviceCredential{}).
+		Where("id = ? AND drone_id = ? AND status = ?", credentialID, droneID, models.DeviceCredentialActive).
+		Updates(map[string]interface{}{
+			"status":     models.DeviceCredentialRevoked,
+			"revoked_at": now,
+			"revoked_by": userID,
+		})
+	if res.Error != nil {
+		return fmt.Errorf("failed to revoke device credential: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		if _, err := s.getCredential(ctx, droneID, credentialID); err != nil {
+			return err
+		}
+		return ErrCredentialRevoked
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"drone_id":      droneID,
+		"credential_id": credentialID,
+		"revoked_by":    userID,
+	}).Info("Device credential revoked")
+	return nil
+}
+
+// Authenticate 校验设备凭证
+func (s *DeviceServiceImpl) Authenticate(ctx context.Context, apiKey, fingerprint string) (*models.DeviceCredential, error) {
+	var candidates []*models.DeviceCredential
+	switch {
+	case apiKey != "":
+		prefix, ok := parseDeviceKey(apiKey)
+		if !ok {
+			return nil, ErrInvalidDeviceCredential
+		}
+		if err := s.db.WithContext(ctx).
+			Where("key_prefix = ? AND type = ? AND status = ?", prefix, models.DeviceCredentialAPIKey, models.DeviceCredentialActive).
+			Find(&candidates).Error; err != nil {
+			return nil, fmt.Errorf("failed to look up device credential: %w", err)
+		}
+	case fingerprint != "":
+		fingerprint = normalizeFingerprint(fingerprint)
+		if err := s.db.WithContext(ctx).
+			Where("fingerprint = ? AND type = ? AND status = ?", fingerprint, models.DeviceCredentialMTLS, models.DeviceCredentialActive).
+			Find(&candidates).Error; err != nil {
+			return nil, fmt.Errorf("failed to look up device credential: %w", err)
+		}
+	default:
+		return nil, ErrInvalidDeviceCredential
+	}
+
+	now := time.Now()
+	keyHash := hashDeviceKey(apiKey)
+	for _, candidate := range candidates {
+		if !candidate.IsUsable(now) {
+			continue
+		}
+		if apiKey != "" && subtle.ConstantTimeCompare([]byte(candidate.KeyHash), []byte(keyHash)) != 1 {
+			continue
+		}
+
+		s.touch(ctx, candidate, now)
+		return candidate, nil
+	}
+	return nil, ErrInvalidDeviceCredential
+}
+
+// IssueCommand 下发指令：持久化供设备拉取，同时发布事件供其他订阅方使用
+func (s *DeviceServiceImpl) IssueCommand(ctx context.Context, params *IssueCommandParams) (*models.DroneCommandRecord, error) {
+	if !params.Command.IsValid() {
+		return nil, fmt.Errorf("%w: unknown command: %s", ErrInvalidData, params.Command)
+	}
+
+	record := &models.DroneCommandRecord{
+		CommandID: fmt.Sprintf("cmd-%d-%d", params.DroneID, time.Now().UnixNano()),
+		DroneID:   params.DroneID,
+		Command:   params.Command,
+		FleetID:   params.FleetID,
+		IssuedBy:  params.IssuedBy,
+		Status:    models.DroneCommandPending,
+		ExpiresAt: time.Now().Add(s.config.CommandTTL),
+	}
+	if len(params.Params) > 0 {
+		data, err := json.Marshal(params.Params)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid command params: %v", ErrInvalidData, err)
+		}
+		record.Params = string(data)
+	}
+
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		return nil, fmt.Errorf("failed to store drone command: %w", err)
+	}
+
+	if s.kafkaService != nil {
+		if err := s.kafkaService.PublishDroneEvent(ctx, kafka.DroneCommandIssuedEvent, kafka.DroneCommandEventData{
+			CommandID: record.CommandID,
+			DroneID:   record.DroneID,
+			Command:   string(record.Command),
+			Params:    params.Params,
+			FleetID:   record.FleetID,
+			IssuedBy:  record.IssuedBy,
+			Timestamp: record.CreatedAt,
+		}); err != nil {
+			// 设备仍可通过拉取获取指令
+			s.logger.WithError(err).WithField("command_id", record.CommandID).Warn("Failed to publish drone command event")
+		}
+	}
+	return record, nil
+}
+
+// ListCommands 获取无人机最近的指令
+func (s *DeviceServiceImpl) ListCommands(ctx context.Context, droneID uint, limit int) ([]*models.DroneCommandRecord, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var commands []*models.DroneCommandRecord
+	if err := s.db.WithContext(ctx).Where("drone_id = ?", droneID).Order("id DESC").Limit(limit).Find(&commands).Error; err != nil {
+		return nil, fmt.Errorf("failed to list drone commands: %w", err)
+	}
+	return commands, nil
+}
+
+// PollCommands 设备拉取待执行指令
+func (s *DeviceServiceImpl) PollCommands(ctx context.Context, droneID uint) ([]*models.DroneCommandRecord, error) {
+	now := time.Now()
+
+	// 过期未拉取的指令不再下发
+	if err := s.db.WithContext(ctx).Model(&models.DroneCommandRecord{}).
+		Where("drone_id = ? AND status = ? AND expires_at <= ?", droneID, models.DroneCommandPending, now).
+		Update("status", models.DroneCommandExpired).Error; err != nil {
+		return nil, fmt.Errorf("failed to expire drone commands: %w", err)
+	}
+
+	var commands []*models.DroneCommandRecord
+	if err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND status = ?", droneID, models.DroneCommandPending).
+		Order("id").Find(&commands).Error; err != nil {
+		return nil, fmt.Errorf("failed to poll drone commands: %w", err)
+	}
+	if len(commands) == 0 {
+		return commands, nil
+	}
+
+	ids := make([]uint, len(commands))
+	for i, command := range commands {
+		ids[i] = command.ID
+		command.Status = models.DroneCommandDelivered
+		command.DeliveredAt = &now
+	}
+	if err := s.db.WithContext(ctx).Model(&models.DroneCommandRecord{}).
+		Where("id IN ? AND status = ?", ids, models.DroneCommandPending).
+		Updates(map[string]interface{}{
+			"status":       models.DroneCommandDelivered,
+			"delivered_at": now,
+		}).Error; err != nil {
+		return nil, fmt.Errorf("failed to mark drone commands delivered: %w", err)
+	}
+	return commands, nil
+}
+
+// AcknowledgeCommand 设备确认指令执行结果
+func (s *DeviceServiceImpl) AcknowledgeCommand(ctx context.Context, droneID uint, commandID string, success bool, result string) error {
+	status := models.DroneCommandSucceeded
+	if !success {
+		status = models.DroneCommandFailed
+	}
+	if len(result) > 500 {
+		result = result[:500]
+	}
+
+	res := s.db.WithContext(ctx).Model(&models.DroneCommandRecord{}).
+		Where("command_id = ? AND drone_id = ? AND status IN ?", commandID, droneID,
+			[]models.DroneCommandStatus{models.DroneCommandPending, models.DroneCommandDelivered}).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"acknowledged_at": time.Now(),
+			"result":          result,
+		})
+	if res.Error != nil {
+		return fmt.Errorf("failed to acknowledge drone command: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.DroneCommandRecord{}).
+			Where("command_id = ? AND drone_id = ?", commandID, droneID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to get drone command: %w", err)
+		}
+		if count == 0 {
+			return ErrCommandNotFound
+		}
+		return ErrCommandAlreadyAcknowledged
+	}
+	return nil
+}
+
+// buildCredential 生成凭证记录，api_key 类型同时返回密钥明文
+func (s *DeviceServiceImpl) buildCredential(ctx context.Context, droneID uint, params *IssueCredentialParams) (*models.DeviceCredential, string, error) {
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidData)
+	}
+
+	credential := &models.DeviceCredential{
+		DroneID:     droneID,
+		Type:        params.Type,
+		Status:      models.DeviceCredentialActive,
+		Description: params.Description,
+		ExpiresAt:   params.ExpiresAt,
+		CreatedBy:   params.CreatedBy,
+	}
+
+	switch params.Type {
+	case models.DeviceCredentialAPIKey, "":
+		credential.Type = models.DeviceCredentialAPIKey
+		apiKey, prefix, err := generateDeviceKey()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate device key: %w", err)
+		}
+		credential.KeyPrefix = prefix
+		credential.KeyHash = hashDeviceKey(apiKey)
+		return credential, apiKey, nil
+
+	case models.DeviceCredentialMTLS:
+		fingerprint := normalizeFingerprint(params.Fingerprint)
+		if err := s.checkFingerprint(ctx, fingerprint, droneID); err != nil {
+			return nil, "", err
+		}
+		credential.Fingerprint = fingerprint
+		return credential, "", nil
+
+	default:
+		return nil, "", fmt.Errorf("%w: unknown credential type: %s", ErrInvalidData, params.Type)
+	}
+}
+
+// CheckFingerprint 检查证书指纹，创建无人机前调用以免创建后才发现证书不可用
+func (s *DeviceServiceImpl) CheckFingerprint(ctx context.Context, fingerprint string) error {
+	return s.checkFingerprint(ctx, normalizeFingerprint(fingerprint), 0)
+}
+
+// checkFingerprint 检查已规范化的证书指纹，同一证书不能绑定到多架无人机
+func (s *DeviceServiceImpl) checkFingerprint(ctx context.Context, fingerprint string, droneID uint) error {
+	if !isHexChecksum(fingerprint) {
+		return fmt.Errorf("%w: fingerprint must be a SHA-256 certificate fingerprint", ErrInvalidData)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.DeviceCredential{}).
+		Where("fingerprint = ? AND status = ? AND drone_id <> ?", fingerprint, models.DeviceCredentialActive, droneID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check certificate fingerprint: %w", err)
+	}
+	if count > 0 {
+		return ErrCredentialExists
+	}
+	return nil
+}
+
+// getCredential 获取属于无人机的凭证
+func (s *DeviceServiceImpl) getCredential(ctx context.Context, droneID, credentialID uint) (*models.DeviceCredential, error) {
+	var credential models.DeviceCredential
+	if err := s.db.WithContext(ctx).Where("id = ? AND drone_id = ?", credentialID, droneID).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, fmt.Errorf("failed to get device credential: %w", err)
+	}
+	return &credential, nil
+}
+
+// checkDrone 检查无人机是否存在
+func (s *DeviceServiceImpl) checkDrone(ctx context.Context, droneID uint) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Drone{}).Where("id = ?", droneID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check drone: %w", err)
+	}
+	if count == 0 {
+		return ErrDroneNotFound
+	}
+	return nil
+}
+
+// touch 更新凭证最近使用时间，限制写入频率
+func (s *DeviceServiceImpl) touch(ctx context.Context, credential *models.DeviceCredential, now time.Time) {
+	s.lastUsedMu.Lock()
+	last, ok := s.lastUsed[credential.ID]
+	if ok && now.Sub(last) < deviceUsageWriteInterval {
+		s.lastUsedMu.Unlock()
+		return
+	}
+	s.lastUsed[credential.ID] = now
+	s.lastUsedMu.Unlock()
+
+	if err := s.db.WithContext(ctx).Model(&models.DeviceCredential{}).Where("id = ?", credential.ID).
+		Update("last_used_at", now).Error; err != nil {
+		s.logger.WithError(err).WithField("credential_id", credential.ID).Warn("Failed to update device credential usage")
+	}
+}
+
+// generateDeviceKey 生成API密钥，返回密钥和查找前缀
+func generateDeviceKey() (string, string, error) {
+	prefix := make([]byte, 4)
+	secret := make([]byte, 24)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefixHex := hex.EncodeToString(prefix)
+	return fmt.Sprintf("%s_%s_%s", deviceKeyScheme, prefixHex, hex.EncodeToString(secret)), prefixHex, nil
+}
+
+// parseDeviceKey 解析API密钥前缀
+func parseDeviceKey(apiKey string) (string, bool) {
+	parts := strings.Split(apiKey, "_")
+	if len(parts) != 3 || parts[0] != deviceKeyScheme || len(parts[1]) != 8 || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// hashDeviceKey 计算API密钥摘要
+func hashDeviceKey(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint 统一证书指纹格式（去掉冒号，转小写）
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
+
+// CertificateFingerprint 计算DER编码证书的SHA-256指纹
+func CertificateFingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
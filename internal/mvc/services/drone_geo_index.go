@@ -0,0 +1,441 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// droneGeoKey 在线无人机实时位置（GEO集合）
+	droneGeoKey = "monitor:drones:geo"
+	// droneGeoStateKey 在线无人机实时状态（Hash，field为无人机ID，value为JSON）
+	droneGeoStateKey = "monitor:drones:geo:state"
+	// droneGeoUpdatedKey 在线无人机位置更新时间（有序集合，score为Unix毫秒）
+	droneGeoUpdatedKey = "monitor:drones:geo:updated"
+
+	// nearestOverfetch 最近邻查询时预取倍数，用于弥补状态/电量过滤掉的结果
+	nearestOverfetch = 5
+	// geoPurgeBatch 每次写入位置时顺带清理的过期条目上限
+	geoPurgeBatch = 100
+)
+
+// updatePositionScript 原子地合并状态并写入位置，同时清理过期条目
+// KEYS: GEO集合、状态Hash、更新时间集合
+// ARGV: 成员、经度、纬度、高度、更新时间、更新时间（毫秒）、过期截止（毫秒）、索引中没有该无人机时的初始状态
+// 乱序到达的旧位置、已过期的位置和离线无人机不写入，返回是否写入
+var updatePositionScript = redis.NewScript(`
+	local last = redis.call("ZSCORE", KEYS[3], ARGV[1])
+	if last and tonumber(last) > tonumber(ARGV[6]) then
+		return 0
+	end
+
+	local raw = redis.call("HGET", KEYS[2], ARGV[1])
+	local state
+	if raw then
+		state = cjson.decode(raw)
+	elseif ARGV[8] ~= "" then
+		state = cjson.decode(ARGV[8])
+	end
+
+	local written = 0
+	if state and state.status ~= "offline" and tonumber(ARGV[6]) >= tonumber(ARGV[7]) then
+		state.altitude = tonumber(ARGV[4])
+		state.updated_at = ARGV[5]
+		redis.call("GEOADD", KEYS[1], ARGV[2], ARGV[3], ARGV[1])
+		redis.call("HSET", KEYS[2], ARGV[1], cjson.encode(state))
+		redis.call("ZADD", KEYS[3], ARGV[6], ARGV[1])
+		written = 1
+	end
+
+	local expired = redis.call("ZRANGEBYSCORE", KEYS[3], "-inf", "(" .. ARGV[7], "LIMIT", 0, ` + strconv.Itoa(geoPurgeBatch) + `)
+	for _, member in ipairs(expired) do
+		redis.call("ZREM", KEYS[1], member)
+		redis.call("HDEL", KEYS[2], member)
+		redis.call("ZREM", KEYS[3], member)
+	end
+	return written
+`)
+
+// updateStateScript 原子地修改已在索引中的无人机状态的一个字段
+// KEYS: 状态Hash；ARGV: 成员、字段名、JSON编码的字段值
+var updateStateScript = redis.NewScript(`
+	local raw = redis.call("HGET", KEYS[1], ARGV[1])
+	if not raw then
+		return 0
+	end
+	local state = cjson.decode(raw)
+	state[ARGV[2]] = cjson.decode(ARGV[3])
+	redis.call("HSET", KEYS[1], ARGV[1], cjson.encode(state))
+	return 1
+`)
+
+// GeoIndexConfig 位置索引配置
+type GeoIndexConfig struct {
+	StaleAfter time.Duration `yaml:"stale_after" json:"stale_after"` // 超过该时间未更新的位置视为过期
+}
+
+// DefaultGeoIndexConfig 默认位置索引配置
+func DefaultGeoIndexConfig() *GeoIndexConfig {
+	return &GeoIndexConfig{
+		StaleAfter: 2 * time.Minute,
+	}
+}
+
+// NearbyDrone 位置查询结果
+type NearbyDrone struct {
+	DroneID   uint               `json:"drone_id"`
+	Latitude  float64            `json:"latitude"`
+	Longitude float64            `json:"longitude"`
+	Altitude  float64            `json:"altitude"`
+	Distance  float64            `json:"distance,omitempty"` // 与查询中心的距离（米）
+	Status    models.DroneStatus `json:"status"`
+	Battery   int                `json:"battery"` // -1表示未知
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// BoundingBox 经纬度矩形范围
+type BoundingBox struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// DroneGeoIndex 在线无人机位置索引接口
+type DroneGeoIndex interface {
+	UpdatePosition(ctx context.Context, droneID uint, position models.Position, at time.Time) error
+	UpdateBattery(ctx context.Context, droneID uint, battery int) error
+	UpdateStatus(ctx context.Context, droneID uint, status models.DroneStatus) error
+	Remove(ctx context.Context, droneID uint) error
+
+	// WithinRadius 查询距离中心点radius米内的无人机，按距离升序
+	WithinRadius(ctx context.Context, latitude, longitude, radius float64, limit int) ([]*NearbyDrone, error)
+	// NearestAvailable 查询最近的N架空闲且电量不低于minBattery的无人机
+	NearestAvailable(ctx context.Context, latitude, longitude float64, count, minBattery int) ([]*NearbyDrone, error)
+	// WithinBounds 查询矩形范围内的无人机
+	WithinBounds(ctx context.Context, box BoundingBox) ([]*NearbyDrone, error)
+}
+
+// geoState 位置索引中缓存的无人机状态
+type geoState struct {
+	Altitude  float64            `json:"altitude"`
+	Status    models.DroneStatus `json:"status,omitempty"`
+	Battery   int                `json:"battery"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// DroneGeoIndexImpl 基于Redis GEO的位置索引实现
+type DroneGeoIndexImpl struct {
+	config       *GeoIndexConfig
+	redis        *redis.Client
+	droneService DroneService
+	logger       *logger.Logger
+}
+
+// NewDroneGeoIndex 创建无人机位置索引
+func NewDroneGeoIndex(config *GeoIndexConfig, redisClient *redis.Client, droneService DroneService, logger *logger.Logger) DroneGeoIndex {
+	if config == nil {
+		config = DefaultGeoIndexConfig()
+	}
+
+	return &DroneGeoIndexImpl{
+		config:       config,
+		redis:        redisClient,
+		droneService: droneService,
+		logger:       logger,
+	}
+}
+
+// UpdatePosition 更新无人机位置
+// 无人机首次进入索引时按数据库状态初始化，离线的无人机不入索引
+func (g *DroneGeoIndexImpl) UpdatePosition(ctx context.Context, droneID uint, position models.Position, at time.Time) error {
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	member := droneMember(droneID)
+	exists, err := g.redis.HExists(ctx, droneGeoStateKey, member).Result()
+	if err != nil {
+		return fmt.Errorf("failed to load drone geo state: %w", err)
+	}
+
+	// 已在索引中时由脚本合并现有状态，期间被移出索引的无人机不会重新加入
+	initial := ""
+	if !exists {
+		state := g.initialState(ctx, droneID)
+		if state.Status == models.DroneStatusOffline {
+			return nil
+		}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		initial = string(data)
+	}
+
+	staleBefore := time.Now().Add(-g.config.StaleAfter)
+	err = updatePositionScript.Run(ctx, g.redis,
+		[]string{droneGeoKey, droneGeoStateKey, droneGeoUpdatedKey},
+		member, position.Longitude, position.Latitude, position.Altitude,
+		at.Format(time.RFC3339Nano), at.UnixMilli(), staleBefore.UnixMilli(), initial,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to update drone geo index: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBattery 更新索引中的电量，未在索引中的无人机忽略
+func (g *DroneGeoIndexImpl) UpdateBattery(ctx context.Context, droneID uint, battery int) error {
+	return g.updateState(ctx, droneID, "battery", battery)
+}
+
+// UpdateStatus 更新索引中的状态，离线时移出索引
+func (g *DroneGeoIndexImpl) UpdateStatus(ctx context.Context, droneID uint, status models.DroneStatus) error {
+	if status == models.DroneStatusOffline {
+		return g.Remove(ctx, droneID)
+	}
+
+	return g.updateState(ctx, droneID, "status", status)
+}
+
+// Remove 从索引中移除无人机
+func (g *DroneGeoIndexImpl) Remove(ctx context.Context, droneID uint) error {
+	member := droneMember(droneID)
+	_, err := g.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, droneGeoKey, member)
+		pipe.HDel(ctx, droneGeoStateKey, member)
+		pipe.ZRem(ctx, droneGeoUpdatedKey, member)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove drone from geo index: %w", err)
+	}
+	return nil
+}
+
+// WithinRadius 半径查询
+func (g *DroneGeoIndexImpl) WithinRadius(ctx context.Context, latitude, longitude, radius float64, limit int) ([]*NearbyDrone, error) {
+	if radius <= 0 {
+		return nil, ErrInvalidData
+	}
+
+	locations, err := g.redis.GeoSearchLocation(ctx, droneGeoKey, &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{
+			Longitude:  longitude,
+			Latitude:   latitude,
+			Radius:     radius,
+			RadiusUnit: "m",
+			Sort:       "ASC",
+			Count:      limit,
+		},
+		WithCoord: true,
+		WithDist:  true,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search drones by radius: %w", err)
+	}
+
+	return g.resolve(ctx, locations, nil)
+}
+
+// NearestAvailable 最近可用无人机查询
+func (g *DroneGeoIndexImpl) NearestAvailable(ctx context.Context, latitude, longitude float64, count, minBattery int) ([]*NearbyDrone, error) {
+	if count <= 0 {
+		return nil, ErrInvalidData
+	}
+
+	available := func(d *NearbyDrone) bool {
+		return d.Status == models.DroneStatusOnline && d.Battery >= minBattery
+	}
+
+	// 先按预取倍数查询，不足时再做一次全量查询
+	fetch := count * nearestOverfetch
+	for {
+		locations, err := g.redis.GeoSearchLocation(ctx, droneGeoKey, &redis.GeoSearchLocationQuery{
+			GeoSearchQuery: redis.GeoSearchQuery{
+				Longitude:  longitude,
+				Latitude:   latitude,
+				Radius:     math.Pi * geo.EarthRadius, // 覆盖全球
+				RadiusUnit: "m",
+				Sort:       "ASC",
+				Count:      fetch,
+			},
+			WithCoord: true,
+			WithDist:  true,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to search nearest drones: %w", err)
+		}
+
+		drones, err := g.resolve(ctx, locations, available)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(drones) >= count {
+			return drones[:count], nil
+		}
+		if fetch == 0 || len(locations) < fetch {
+			return drones, nil
+		}
+		fetch = 0
+	}
+}
+
+// WithinBounds 矩形范围查询
+func (g *DroneGeoIndexImpl) WithinBounds(ctx context.Context, box BoundingBox) ([]*NearbyDrone, error) {
+	if box.MinLatitude >= box.MaxLatitude || box.MinLongitude >= box.MaxLongitude {
+		return nil, ErrInvalidData
+	}
+
+	centerLat := (box.MinLatitude + box.MaxLatitude) / 2
+	centerLon := (box.MinLongitude + box.MaxLongitude) / 2
+
+	// Redis按中心点和宽高搜索，取最宽处（靠近赤道的一边）保证覆盖，结果再按经纬度精确过滤
+	edgeLat := box.MinLatitude
+	if math.Abs(box.MaxLatitude) < math.Abs(box.MinLatitude) {
+		edgeLat = box.MaxLatitude
+	}
+	if box.MinLatitude < 0 && box.MaxLatitude > 0 {
+		edgeLat = 0
+	}
+	width := geo.Distance(edgeLat, box.MinLongitude, edgeLat, box.MaxLongitude)
+	height := geo.Distance(box.MinLatitude, centerLon, box.MaxLatitude, centerLon)
+
+	locations, err := g.redis.GeoSearchLocation(ctx, droneGeoKey, &redis.GeoSearchLocationQuery{
+		GeoSearchQuery: redis.GeoSearchQuery{
+			Longitude: centerLon,
+			Latitude:  centerLat,
+			BoxWidth:  width,
+			BoxHeight: height,
+			BoxUnit:   "m",
+		},
+		WithCoord: true,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search drones by box: %w", err)
+	}
+
+	inside := func(d *NearbyDrone) bool {
+		return d.Latitude >= box.MinLatitude && d.Latitude <= box.MaxLatitude &&
+			d.Longitude >= box.MinLongitude && d.Longitude <= box.MaxLongitude
+	}
+
+	drones, err := g.resolve(ctx, locations, inside)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(drones, func(i, j int) bool {
+		return drones[i].DroneID < drones[j].DroneID
+	})
+	return drones, nil
+}
+
+// resolve 合并位置和状态，过滤并清理过期条目
+func (g *DroneGeoIndexImpl) resolve(ctx context.Context, locations []redis.GeoLocation, filter func(*NearbyDrone) bool) ([]*NearbyDrone, error) {
+	if len(locations) == 0 {
+		return []*NearbyDrone{}, nil
+	}
+
+	members := make([]string, 0, len(locations))
+	for _, loc := range locations {
+		members = append(members, loc.Name)
+	}
+
+	values, err := g.redis.HMGet(ctx, droneGeoStateKey, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load drone geo state: %w", err)
+	}
+
+	staleBefore := time.Now().Add(-g.config.StaleAfter)
+	drones := make([]*NearbyDrone, 0, len(locations))
+	for i, loc := range locations {
+		droneID, err := strconv.ParseUint(loc.Name, 10, 32)
+		if err != nil {
+			continue
+		}
+
+		state := &geoState{Battery: -1}
+		if raw, ok := values[i].(string); ok {
+			if err := json.Unmarshal([]byte(raw), state); err != nil {
+				state = &geoState{Battery: -1}
+			}
+		}
+
+		// 长时间未更新的位置视为过期，顺带清理
+		if state.UpdatedAt.Before(staleBefore) {
+			if err := g.Remove(ctx, uint(droneID)); err != nil {
+				g.logger.WithFields(map[string]interface{}{
+					"drone_id": droneID,
+					"error":    err.Error(),
+				}).Warn("Failed to remove stale geo entry")
+			}
+			continue
+		}
+
+		drone := &NearbyDrone{
+			DroneID:   uint(droneID),
+			Latitude:  loc.Latitude,
+			Longitude: loc.Longitude,
+			Altitude:  state.Altitude,
+			Distance:  loc.Dist,
+			Status:    state.Status,
+			Battery:   state.Battery,
+			UpdatedAt: state.UpdatedAt,
+		}
+		if filter != nil && !filter(drone) {
+			continue
+		}
+		drones = append(drones, drone)
+	}
+
+	return drones, nil
+}
+
+// initialState 无人机首次进入索引时从数据库加载状态和电量
+func (g *DroneGeoIndexImpl) initialState(ctx context.Context, droneID uint) *geoState {
+	state := &geoState{Battery: -1}
+	if g.droneService == nil {
+		return state
+	}
+
+	drone, err := g.droneService.GetDroneByID(ctx, droneID)
+	if err != nil {
+		g.logger.WithFields(map[string]interface{}{
+			"drone_id": droneID,
+			"error":    err.Error(),
+		}).Debug("Failed to load drone for geo index")
+		return state
+	}
+
+	state.Status = drone.Status
+	state.Battery = drone.Battery
+	return state
+}
+
+// updateState 修改已在索引中的无人机状态字段，尚无位置的无人机不入索引
+func (g *DroneGeoIndexImpl) updateState(ctx context.Context, droneID uint, field string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	if err := updateStateScript.Run(ctx, g.redis, []string{droneGeoStateKey}, droneMember(droneID), field, data).Err(); err != nil {
+		return fmt.Errorf("failed to update drone geo state: %w", err)
+	}
+	return nil
+}
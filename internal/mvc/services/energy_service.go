@@ -0,0 +1,710 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/llm"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EnergyConfig 能耗估算配置
+type EnergyConfig struct {
+	CruiseSpeed       float64 `yaml:"cruise_speed" json:"cruise_speed"`               // 未登记巡航速度的机型使用的速度（m/s）
+	ClimbSpeed        float64 `yaml:"climb_speed" json:"climb_speed"`                 // 默认爬升速度（m/s）
+	DescentSpeed      float64 `yaml:"descent_speed" json:"descent_speed"`             // 默认下降速度（m/s）
+	DefaultFlightTime int     `yaml:"default_flight_time" json:"default_flight_time"` // 未登记续航的机型按该续航（分钟）估算
+	BatteryReserve    int     `yaml:"battery_reserve" json:"battery_reserve"`         // 计划未指定时任务结束需保留的电量（%）
+
+	// 相对巡航的功率系数
+	ClimbPowerFactor   float64 `yaml:"climb_power_factor" json:"climb_power_factor"`
+	DescentPowerFactor float64 `yaml:"descent_power_factor" json:"descent_power_factor"`
+	HoverPowerFactor   float64 `yaml:"hover_power_factor" json:"hover_power_factor"`
+	// 风速达到抗风上限时增加的功率比例，按风速平方缩放
+	WindPowerFactor float64 `yaml:"wind_power_factor" json:"wind_power_factor"`
+	MaxWindSpeed    float64 `yaml:"max_wind_speed" json:"max_wind_speed"` // 未登记抗风能力的机型使用的上限（m/s）
+	// 满载时增加的功率比例，按载荷占最大载重的比例缩放
+	PayloadPowerFactor     float64 `yaml:"payload_power_factor" json:"payload_power_factor"`
+	DefaultPayloadCapacity float64 `yaml:"default_payload_capacity" json:"default_payload_capacity"` // 未登记载重的机型使用的最大载重（kg）
+
+	// 置信余量：样本不足时按耗电的比例，样本充足时按校准比值的两倍标准差
+	DefaultMargin float64 `yaml:"default_margin" json:"default_margin"`
+	MinMargin     float64 `yaml:"min_margin" json:"min_margin"` // 最小置信余量（%）
+	MinSamples    int     `yaml:"min_samples" json:"min_samples"`
+
+	CalibrationWeight   float64       `yaml:"calibration_weight" json:"calibration_weight"`       // 新样本在校准系数中的权重
+	MinCalibrationUsage float64       `yaml:"min_calibration_usage" json:"min_calibration_usage"` // 估算耗电低于该值（%）的任务不参与校准
+	CacheTTL            time.Duration `yaml:"cache_ttl" json:"cache_ttl"`
+}
+
+// DefaultEnergyConfig 默认能耗估算配置
+func DefaultEnergyConfig() *EnergyConfig {
+	return &EnergyConfig{
+		CruiseSpeed:            10,
+		ClimbSpeed:             3,
+		DescentSpeed:           2,
+		DefaultFlightTime:      25,
+		BatteryReserve:         20,
+		ClimbPowerFactor:       1.5,
+		DescentPowerFactor:     0.8,
+		HoverPowerFactor:       1.1,
+		WindPowerFactor:        0.3,
+		MaxWindSpeed:           12,
+		PayloadPowerFactor:     0.3,
+		DefaultPayloadCapacity: 2,
+		DefaultMargin:          0.15,
+		MinMargin:              3,
+		MinSamples:             5,
+		CalibrationWeight:      0.2,
+		MinCalibrationUsage:    5,
+		CacheTTL:               5 * time.Minute,
+	}
+}
+
+// 校准比值超出该范围的样本视为异常（如中途换电池），不参与校准
+const (
+	minCalibrationRatio = 0.3
+	maxCalibrationRatio = 3.0
+)
+
+// EnergyEstimateParams 能耗估算参数
+type EnergyEstimateParams struct {
+	DroneID      uint             `json:"drone_id"` // Drone 为空时按ID加载
+	Drone        *models.Drone    `json:"-"`
+	Plan         *models.TaskPlan `json:"plan"`
+	Weather      *llm.WeatherInfo `json:"weather"`       // 为空时从气象数据源获取，未配置数据源时按无风估算
+	StartBattery *int             `json:"start_battery"` // 起飞电量（%），为空时使用无人机当前电量
+}
+
+// EnergyInsufficientError 任务所需电量超出可用电量
+type EnergyInsufficientError struct {
+	DroneID  uint                   `json:"drone_id"`
+	Estimate *models.EnergyEstimate `json:"estimate"`
+}
+
+func (e *EnergyInsufficientError) Error() string {
+	return fmt.Sprintf("drone %d: mission needs ~%.0f%% battery (±%.0f%%), ending at %.0f%% from %d%%, below the %d%% reserve",
+		e.DroneID, e.Estimate.BatteryUsed, e.Estimate.Margin, e.Estimate.PredictedEndBattery,
+		e.Estimate.StartBattery, e.Estimate.MinBattery)
+}
+
+// Unwrap 支持 errors.Is(err, ErrInsufficientBattery)
+func (e *EnergyInsufficientError) Unwrap() error {
+	return ErrInsufficientBattery
+}
+
+// EnergyService 任务能耗估算服务接口
+type EnergyService interface {
+	// Estimate 估算航程、爬升、悬停时间和耗电，不保存
+	Estimate(ctx context.Context, params *EnergyEstimateParams) (*models.EnergyEstimate, error)
+	// EstimateTask 按任务计划和无人机当前电量估算并保存到任务
+	EstimateTask(ctx context.Context, taskID uint) (*models.EnergyEstimate, error)
+	// RecordActual 任务完成后回填实际耗电并校准机型，endBattery 为空时使用无人机当前电量
+	RecordActual(ctx context.Context, taskID uint, endBattery *int) (*models.EnergyEstimate, error)
+
+	GetCalibration(ctx context.Context, model string) (*models.EnergyCalibration, error)
+	ListCalibrations(ctx context.Context) ([]*models.EnergyCalibration, error)
+}
+
+// calibrationEntry 机型校准缓存项，calibration 为nil表示尚无样本
+type calibrationEntry struct {
+	calibration *models.EnergyCalibration
+	loadedAt    time.Time
+}
+
+// EnergyServiceImpl 能耗估算服务实现
+type EnergyServiceImpl struct {
+	config          *EnergyConfig
+	db              *gorm.DB
+	taskService     TaskService
+	weatherProvider WeatherProvider
+	logger          *logger.Logger
+
+	cache   map[string]calibrationEntry
+	cacheMu sync.Mutex
+}
+
+// NewEnergyService 创建能耗估算服务，weatherProvider 为nil时按无风估算
+func NewEnergyService(
+	config *EnergyConfig,
+	db *gorm.DB,
+	taskService TaskService,
+	weatherProvider WeatherProvider,
+	logger *logger.Logger,
+) EnergyService {
+	if config == nil {
+		config = DefaultEnergyConfig()
+	}
+
+	return &EnergyServiceImpl{
+		config:          config,
+		db:              db,
+		taskService:     taskService,
+		weatherProvider: weatherProvider,
+		logger:          logger,
+		cache:           make(map[string]calibrationEntry),
+	}
+}
+
+// Estimate 估算任务能耗
+func (s *EnergyServiceImpl) Estimate(ctx context.Context, params *EnergyEstimateParams) (*models.EnergyEstimate, error) {
+	if params == nil || params.Plan == nil || (params.Drone == nil && params.DroneID == 0) {
+		return nil, fmt.Errorf("%w: drone and plan are required", ErrInvalidData)
+	}
+	drone := params.Drone
+	if drone == nil {
+		drone = &models.Drone{}
+		if err := s.db.WithContext(ctx).First(drone, params.DroneID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrDroneNotFound
+			}
+			return nil, fmt.Errorf("failed to load drone: %w", err)
+		}
+	}
+
+	caps, err := drone.ParseCapabilities()
+	if err != nil {
+		caps = &models.DroneCapabilities{}
+	}
+
+	weather := params.Weather
+	if weather == nil {
+		weather = s.currentWeather(ctx, drone, params.Plan)
+	}
+
+	calibration, err := s.calibration(ctx, drone.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	startBattery := drone.Battery
+	if params.StartBattery != nil {
+		startBattery = *params.StartBattery
+	}
+	return s.estimate(drone, caps, params.Plan, weather, calibration, startBattery), nil
+}
+
+// EstimateTask 估算并保存任务能耗，计划未填写时长时同时填写
+func (s *EnergyServiceImpl) EstimateTask(ctx context.Context, taskID uint) (*models.EnergyEstimate, error) {
+	task, err := s.taskService.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重试的任务只估算剩余航点
+	plan := task.RemainingPlan()
+	estimate, err := s.Estimate(ctx, &EnergyEstimateParams{DroneID: task.DroneID, Plan: &plan})
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"energy": *estimate}
+	if task.Plan.Duration == 0 && task.ResumeWaypoint == 0 {
+		updates["plan_duration"] = estimate.Duration
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Task{}).Where("id = ?", taskID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to save energy estimate: %w", err)
+	}
+	return estimate, nil
+}
+
+// RecordActual 回填实际耗电和飞行时间，并按实际与校准前估算的比值更新机型校准
+// 每个任务只记录一次，重复调用返回已记录的结果
+func (s *EnergyServiceImpl) RecordActual(ctx context.Context, taskID uint, endBattery *int) (*models.EnergyEstimate, error) {
+	if endBattery != nil && (*endBattery < 0 || *endBattery > 100) {
+		return nil, fmt.Errorf("%w: battery must be between 0 and 100", ErrInvalidData)
+	}
+
+	var task models.Task
+	recorded := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁定任务行，避免并发完成时重复回填和校准
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&task, taskID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTaskNotFound
+			}
+			return err
+		}
+		if task.Energy.IsZero() {
+			return ErrEnergyNotEstimated
+		}
+		if task.Energy.ActualBatteryUsed != nil {
+			return nil
+		}
+
+		end := endBattery
+		if end == nil {
+			var drone models.Drone
+			if err := tx.Select("battery").First(&drone, task.DroneID).Error; err != nil {
+				return fmt.Errorf("failed to load drone battery: %w", err)
+			}
+			end = &drone.Battery
+		}
+
+		completedAt := time.Now()
+		if task.CompletedAt != nil {
+			completedAt = *task.CompletedAt
+		}
+		used := float64(task.Energy.StartBattery - *end)
+		task.Energy.EndBattery = end
+		task.Energy.ActualBatteryUsed = &used
+		task.Energy.CompletedAt = &completedAt
+		if task.StartedAt != nil {
+			flightTime := completedAt.Sub(*task.StartedAt).Seconds()
+			task.Energy.ActualFlightTime = &flightTime
+		}
+
+		recorded = true
+		return tx.Model(&models.Task{}).Where("id = ?", taskID).Update("energy", task.Energy).Error
+	})
+	if err != nil {
+		if err == ErrTaskNotFound || err == ErrEnergyNotEstimated {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to record actual energy usage: %w", err)
+	}
+
+	if recorded {
+		if err := s.calibrate(ctx, &task, &task.Energy); err != nil {
+			s.logger.WithError(err).WithField("task_id", taskID).Warn("Failed to update energy calibration")
+		}
+	}
+	return &task.Energy, nil
+}
+
+// GetCalibration 获取机型校准参数
+func (s *EnergyServiceImpl) GetCalibration(ctx context.Context, model string) (*models.EnergyCalibration, error) {
+	var calibration models.EnergyCalibration
+	if err := s.db.WithContext(ctx).Where("model = ?", model).First(&calibration).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCalibrationNotFound
+		}
+		return nil, fmt.Errorf("failed to get energy calibration: %w", err)
+	}
+	return &calibration, nil
+}
+
+// ListCalibrations 获取所有机型校准参数
+func (s *EnergyServiceImpl) ListCalibrations(ctx context.Context) ([]*models.EnergyCalibration, error) {
+	var calibrations []*models.EnergyCalibration
+	if err := s.db.WithContext(ctx).Order("model").Find(&calibrations).Error; err != nil {
+		return nil, fmt.Errorf("failed to list energy calibrations: %w", err)
+	}
+	return calibrations, nil
+}
+
+// estimate 按航段累计飞行时间和耗电
+// 耗电以额定续航对应的巡航耗电速率为基准，按爬升、下降、悬停、风和载荷调整
+func (s *EnergyServiceImpl) estimate(
+	drone *models.Drone,
+	caps *models.DroneCapabilities,
+	plan *models.TaskPlan,
+	weather *llm.WeatherInfo,
+	calibration *models.EnergyCalibration,
+	startBattery int,
+) *models.EnergyEstimate {
+	e := &models.EnergyEstimate{
+		Model:             drone.Model,
+		PayloadWeight:     plan.Payload.Weight,
+		CalibrationFactor: 1,
+		StartBattery:      startBattery,
+		MinBattery:        plan.MinBattery,
+		EstimatedAt:       time.Now(),
+	}
+	if e.MinBattery <= 0 {
+		e.MinBattery = s.config.BatteryReserve
+	}
+	if weather != nil {
+		e.WindSpeed = weather.WindSpeed
+		e.WindDirection = weather.WindDirection
+	}
+
+	flightTime := caps.MaxFlightTime
+	if flightTime <= 0 {
+		flightTime = s.config.DefaultFlightTime
+	}
+	cruise := firstPositive(caps.CruiseSpeed, s.config.CruiseSpeed)
+	if plan.MaxSpeed > 0 {
+		cruise = math.Min(cruise, plan.MaxSpeed)
+	}
+	climbSpeed := firstPositive(caps.ClimbSpeed, s.config.ClimbSpeed)
+	descentSpeed := firstPositive(caps.DescentSpeed, s.config.DescentSpeed)
+
+	// 风和载荷对整个任务的功率影响
+	windFactor, payloadFactor := 1.0, 1.0
+	windLimit := firstPositive(caps.MaxWindSpeed, s.config.MaxWindSpeed)
+	if windLimit > 0 {
+		windFactor += s.config.WindPowerFactor * math.Pow(e.WindSpeed/windLimit, 2)
+	}
+	if capacity := firstPositive(caps.PayloadCapacity, s.config.DefaultPayloadCapacity); capacity > 0 {
+		payloadFactor += s.config.PayloadPowerFactor * e.PayloadWeight / capacity
+	}
+	if caps.PayloadCapacity > 0 && e.PayloadWeight > caps.PayloadCapacity {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("payload %.1fkg exceeds capacity %.1fkg", e.PayloadWeight, caps.PayloadCapacity))
+	}
+	if windLimit > 0 && e.WindSpeed > windLimit {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("wind %.1fm/s exceeds limit %.1fm/s", e.WindSpeed, windLimit))
+	}
+
+	// 起飞点 -> 各航点 -> 返回起飞点，起降点高度为0
+	home := models.Waypoint{Latitude: drone.Position.Latitude, Longitude: drone.Position.Longitude}
+	route := make([]models.Waypoint, 0, len(plan.Waypoints)+2)
+	route = append(route, home)
+	route = append(route, plan.Waypoints...)
+	route = append(route, home)
+
+	var cruiseTime, climbTime, descentTime float64
+	for i := 1; i < len(route); i++ {
+		from, to := route[i-1], route[i]
+		transit := i == 1 || i == len(route)-1
+
+		distance := geo.Distance(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+		if transit {
+			e.TransitDistance += distance
+		} else {
+			e.Distance += distance
+		}
+
+		speed := cruise
+		if !transit && to.Speed > 0 {
+			speed = to.Speed
+			if caps.MaxSpeed > 0 {
+				speed = math.Min(speed, caps.MaxSpeed)
+			}
+		}
+		if distance > 0 {
+			bearing := geo.Bearing(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+			groundSpeed, ok := groundSpeed(speed, bearing, e.WindSpeed, e.WindDirection)
+			if !ok {
+				e.Warnings = append(e.Warnings, fmt.Sprintf("leg %d: wind %.1fm/s exceeds airspeed %.1fm/s", i, e.WindSpeed, speed))
+				groundSpeed = math.Max(speed-e.WindSpeed, 1)
+			}
+			cruiseTime += distance / groundSpeed
+		}
+
+		if dh := to.Altitude - from.Altitude; dh > 0 {
+			e.Climb += dh
+			climbTime += dh / climbSpeed
+		} else {
+			e.Descent -= dh
+			descentTime += -dh / descentSpeed
+		}
+
+		if i < len(route)-1 {
+			e.HoverTime += to.HoldTime
+			for _, action := range to.Actions {
+				if action.Type == models.WaypointActionHover {
+					e.HoverTime += action.Duration
+				}
+			}
+		}
+	}
+
+	// 额定续航下每秒巡航耗电（%）
+	rate := 100 / (float64(flightTime) * 60)
+	units := cruiseTime*windFactor +
+		climbTime*s.config.ClimbPowerFactor +
+		descentTime*s.config.DescentPowerFactor +
+		e.HoverTime*s.config.HoverPowerFactor*windFactor
+	e.RawBatteryUsed = units * rate * payloadFactor
+	e.RawFlightTime = cruiseTime + climbTime + descentTime + e.HoverTime
+
+	e.FlightTime = e.RawFlightTime
+	spread := s.config.DefaultMargin
+	if calibration != nil && calibration.BatteryFactor > 0 {
+		e.Samples = calibration.Samples
+		e.CalibrationFactor = calibration.BatteryFactor
+		if calibration.DurationFactor > 0 {
+			e.FlightTime = e.RawFlightTime * calibration.DurationFactor
+		}
+		if calibration.Samples >= s.config.MinSamples {
+			spread = 2 * math.Sqrt(calibration.BatteryVariance) / calibration.BatteryFactor
+		}
+	}
+	e.BatteryUsed = e.RawBatteryUsed * e.CalibrationFactor
+	e.Margin = math.Max(e.BatteryUsed*spread, s.config.MinMargin)
+
+	e.Duration = int(math.Ceil(e.FlightTime / 60))
+	e.PredictedEndBattery = float64(startBattery) - e.BatteryUsed
+	e.Feasible = e.LowerBound() >= float64(e.MinBattery) && len(e.Warnings) == 0
+
+	if plan.Duration > 0 && float64(plan.Duration)*60 < e.FlightTime*0.8 {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("declared duration %d min is shorter than estimated %d min", plan.Duration, e.Duration))
+	}
+	if caps.MaxRange > 0 && e.Distance+e.TransitDistance > caps.MaxRange {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("total distance %.1f km exceeds range %.1f km", (e.Distance+e.TransitDistance)/1000, caps.MaxRange/1000))
+		e.Feasible = false
+	}
+	return e
+}
+
+// groundSpeed 按风速风向计算沿航迹的地速，侧风超过空速或逆风大于空速时返回 false
+func groundSpeed(airspeed, bearing, windSpeed, windFrom float64) (float64, bool) {
+	if windSpeed <= 0 {
+		return airspeed, airspeed > 0
+	}
+	// 风向为来向，顺风分量为风的去向在航迹方向上的投影
+	angle := (bearing - windFrom - 180) * math.Pi / 180
+	along := windSpeed * math.Cos(angle)
+	cross := windSpeed * math.Sin(angle)
+	if math.Abs(cross) >= airspeed {
+		return 0, false
+	}
+	speed := math.Sqrt(airspeed*airspeed-cross*cross) + along
+	return speed, speed > 0.5
+}
+
+// firstPositive 返回第一个正数，均不为正时返回最后一个值
+func firstPositive(values ...float64) float64 {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return values[len(values)-1]
+}
+
+// currentWeather 获取任务起点天气，获取失败时按无风估算
+func (s *EnergyServiceImpl) currentWeather(ctx context.Context, drone *models.Drone, plan *models.TaskPlan) *llm.WeatherInfo {
+	if s.weatherProvider == nil {
+		return nil
+	}
+
+	latitude, longitude := drone.Position.Latitude, drone.Position.Longitude
+	if len(plan.Waypoints) > 0 {
+		latitude, longitude = plan.Waypoints[0].Latitude, plan.Waypoints[0].Longitude
+	}
+	conditions, err := s.weatherProvider.CurrentWeather(ctx, latitude, longitude)
+	if err != nil {
+		s.logger.WithError(err).WithField("drone_id", drone.ID).Warn("Failed to get weather for energy estimate")
+		return nil
+	}
+	return &llm.WeatherInfo{
+		WindSpeed:     conditions.WindSpeed,
+		WindDirection: conditions.WindDirection,
+		Visibility:    conditions.Visibility,
+	}
+}
+
+// calibration 获取机型校准参数（带缓存），尚无样本时返回nil
+func (s *EnergyServiceImpl) calibration(ctx context.Context, model string) (*models.EnergyCalibration, error) {
+	if model == "" {
+		return nil, nil
+	}
+
+	s.cacheMu.Lock()
+	entry, ok := s.cache[model]
+	s.cacheMu.Unlock()
+	if ok && time.Since(entry.loadedAt) < s.config.CacheTTL {
+		return entry.calibration, nil
+	}
+
+	calibration, err := s.GetCalibration(ctx, model)
+	if err != nil && err != ErrCalibrationNotFound {
+		return nil, err
+	}
+
+	s.cacheMu.Lock()
+	s.cache[model] = calibrationEntry{calibration: calibration, loadedAt: time.Now()}
+	s.cacheMu.Unlock()
+	return calibration, nil
+}
+
+// calibrate 将实际与校准前估算的比值计入机型校准，首批样本取均值，之后按权重平滑
+func (s *EnergyServiceImpl) calibrate(ctx context.Context, task *models.Task, estimate *models.EnergyEstimate) error {
+	if estimate.Model == "" || estimate.RawBatteryUsed < s.config.MinCalibrationUsage {
+		return nil
+	}
+	ratio := *estimate.ActualBatteryUsed / estimate.RawBatteryUsed
+	if ratio < minCalibrationRatio || ratio > maxCalibrationRatio {
+		s.logger.WithFields(map[string]interface{}{
+			"task_id":   task.ID,
+			"model":     estimate.Model,
+			"estimated": estimate.RawBatteryUsed,
+			"actual":    *estimate.ActualBatteryUsed,
+		}).Warn("Energy usage outlier ignored for calibration")
+		return nil
+	}
+	durationRatio := 0.0
+	if estimate.ActualFlightTime != nil && estimate.RawFlightTime > 0 {
+		durationRatio = *estimate.ActualFlightTime / estimate.RawFlightTime
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var calibration models.EnergyCalibration
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("model = ?", estimate.Model).First(&calibration).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		calibration.Model = estimate.Model
+		calibration.Samples++
+		weight := math.Max(1/float64(calibration.Samples), s.config.CalibrationWeight)
+		if calibration.Samples == 1 {
+			calibration.BatteryFactor = ratio
+			calibration.BatteryVariance = 0
+		} else {
+			deviation := ratio - calibration.BatteryFactor
+			calibration.BatteryFactor += weight * deviation
+			calibration.BatteryVariance = (1 - weight) * (calibration.BatteryVariance + weight*deviation*deviation)
+		}
+		if durationRatio > 0 {
+			if calibration.DurationFactor <= 0 {
+				calibration.DurationFactor = durationRatio
+			} else {
+				calibration.DurationFactor += weight * (durationRatio - calibration.DurationFactor)
+			}
+		}
+		calibration.LastTaskID = task.ID
+		return tx.Save(&calibration).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cacheMu.Lock()
+	delete(s.cache, estimate.Model)
+	s.cacheMu.Unlock()
+
+	s.logger.WithFields(map[string]interface{}{
+		"task_id": task.ID,
+		"model":   estimate.Model,
+		"ratio":   ratio,
+	}).Info("Energy calibration updated")
+	return nil
+}
+
+// EnergyTaskService 估算并保存任务能耗、完成后回填实际耗电的任务服务装饰器
+type EnergyTaskService struct {
+	TaskService
+	energyService EnergyService
+	droneService  DroneService
+	logger        *logger.Logger
+}
+
+// NewEnergyTaskService 创建能耗估算任务服务
+func NewEnergyTaskService(next TaskService, energyService EnergyService, droneService DroneService, logger *logger.Logger) TaskService {
+	return &EnergyTaskService{
+		TaskService:   next,
+		energyService: energyService,
+		droneService:  droneService,
+		logger:        logger,
+	}
+}
+
+// CreateTask 拒绝满电也无法完成的任务，创建后保存估算
+func (s *EnergyTaskService) CreateTask(ctx context.Context, params *CreateTaskParams) (*models.Task, error) {
+	if err := s.checkEndurance(ctx, params.DroneID, &params.Plan); err != nil {
+		return nil, err
+	}
+
+	task, err := s.TaskService.CreateTask(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	s.refresh(ctx, task)
+	return task, nil
+}
+
+// UpdateTask 修改计划或无人机时重新估算
+func (s *EnergyTaskService) UpdateTask(ctx context.Context, id uint, params *UpdateTaskParams) (*models.Task, error) {
+	if params.Plan == nil && params.DroneID == nil {
+		return s.TaskService.UpdateTask(ctx, id, params)
+	}
+
+	current, err := s.TaskService.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	droneID := current.DroneID
+	if params.DroneID != nil {
+		droneID = *params.DroneID
+	}
+	plan := &current.Plan
+	if params.Plan != nil {
+		plan = params.Plan
+	}
+	if err := s.checkEndurance(ctx, droneID, plan); err != nil {
+		return nil, err
+	}
+
+	task, err := s.TaskService.UpdateTask(ctx, id, params)
+	if err != nil {
+		return nil, err
+	}
+	s.refresh(ctx, task)
+	return task, nil
+}
+
+// StartTask 启动成功后按起飞时的电量和天气更新估算，作为完成后比较的基准
+func (s *EnergyTaskService) StartTask(ctx context.Context, id uint) error {
+	if err := s.TaskService.StartTask(ctx, id); err != nil {
+		return err
+	}
+	if _, err := s.energyService.EstimateTask(ctx, id); err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Warn("Failed to record start energy estimate")
+	}
+	return nil
+}
+
+// CompleteTask 成功完成后回填实际耗电并校准机型
+func (s *EnergyTaskService) CompleteTask(ctx context.Context, id uint, success bool, message string) error {
+	if err := s.TaskService.CompleteTask(ctx, id, success, message); err != nil {
+		return err
+	}
+	if !success {
+		return nil
+	}
+	if _, err := s.energyService.RecordActual(ctx, id, nil); err != nil && err != ErrEnergyNotEstimated {
+		s.logger.WithError(err).WithField("task_id", id).Warn("Failed to record actual energy usage")
+	}
+	return nil
+}
+
+// checkEndurance 按满电估算，仍低于保留电量的任务无论何时执行都无法完成
+// 起飞时的实际电量由起飞前检查负责
+func (s *EnergyTaskService) checkEndurance(ctx context.Context, droneID uint, plan *models.TaskPlan) error {
+	if droneID == 0 || len(plan.Waypoints) == 0 {
+		return nil
+	}
+	drone, err := s.droneService.GetDroneByID(ctx, droneID)
+	if err != nil {
+		return err
+	}
+
+	full := 100
+	estimate, err := s.energyService.Estimate(ctx, &EnergyEstimateParams{Drone: drone, Plan: plan, StartBattery: &full})
+	if err != nil {
+		return err
+	}
+	if estimate.LowerBound() < float64(estimate.MinBattery) {
+		return &EnergyInsufficientError{DroneID: droneID, Estimate: estimate}
+	}
+	return nil
+}
+
+// refresh 保存任务估算，失败只记录日志
+func (s *EnergyTaskService) refresh(ctx context.Context, task *models.Task) {
+	if task == nil || task.DroneID == 0 {
+		return
+	}
+	estimate, err := s.energyService.EstimateTask(ctx, task.ID)
+	if err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to save energy estimate")
+		return
+	}
+	task.Energy = *estimate
+	if task.Plan.Duration == 0 {
+		task.Plan.Duration = estimate.Duration
+	}
+}
@@ -15,16 +15,103 @@ var (
 	ErrDroneNotAvailable = errors.New("drone not available")
 	ErrDroneInUse        = errors.New("drone is in use")
 
+	ErrInvalidCapabilities = errors.New("invalid capabilities")
+	ErrCapabilityMismatch  = errors.New("drone does not satisfy required capabilities")
+	ErrInvalidMission      = errors.New("invalid mission plan")
+
 	ErrTaskNotFound       = errors.New("task not found")
 	ErrTaskExists         = errors.New("task already exists")
 	ErrTaskNotRunning     = errors.New("task is not running")
 	ErrTaskAlreadyRunning = errors.New("task is already running")
 	ErrTaskCannotStart    = errors.New("task cannot start")
+	ErrTaskNotStarted     = errors.New("task has not started")
+
+	ErrFirmwareNotFound    = errors.New("firmware release not found")
+	ErrFirmwareExists      = errors.New("firmware release already exists")
+	ErrRolloutNotFound     = errors.New("firmware rollout not found")
+	ErrRolloutInvalidState = errors.New("firmware rollout is not in a valid state for this operation")
+	ErrNoRolloutTargets    = errors.New("no drones eligible for firmware rollout")
+
+	ErrMaintenanceNotFound     = errors.New("maintenance record not found")
+	ErrMaintenanceInvalidState = errors.New("maintenance record is not in a valid state for this operation")
+	ErrServiceIntervalNotFound = errors.New("service interval not found")
+	ErrServiceIntervalExists   = errors.New("service interval already exists")
+
+	ErrBatteryPackNotFound     = errors.New("battery pack not found")
+	ErrBatteryPackExists       = errors.New("battery pack already exists")
+	ErrBatteryPackInUse        = errors.New("battery pack is installed on another drone")
+	ErrBatteryPackRetired      = errors.New("battery pack is retired")
+	ErrBatteryPackNotInstalled = errors.New("battery pack is not installed")
+
+	ErrFleetNotFound       = errors.New("fleet not found")
+	ErrFleetExists         = errors.New("fleet already exists")
+	ErrFleetNotEmpty       = errors.New("fleet still has drones assigned")
+	ErrFleetMemberNotFound = errors.New("fleet member not found")
+	ErrDroneNotInFleet     = errors.New("drone does not belong to the fleet")
+	ErrDroneGroupNotFound  = errors.New("drone group not found")
+	ErrDroneGroupExists    = errors.New("drone group already exists")
+
+	ErrCredentialNotFound         = errors.New("device credential not found")
+	ErrCredentialExists           = errors.New("certificate is already bound to another drone")
+	ErrCredentialRevoked          = errors.New("device credential is revoked or expired")
+	ErrInvalidDeviceCredential    = errors.New("invalid device credential")
+	ErrCommandNotFound            = errors.New("drone command not found")
+	ErrCommandAlreadyAcknowledged = errors.New("drone command already acknowledged or expired")
+
+	ErrScheduleNotFound = errors.New("task schedule not found")
+	ErrNoDroneAvailable = errors.New("no eligible drone available for task")
+	ErrInvalidSchedule  = errors.New("invalid task schedule")
+
+	ErrWorkflowNotFound    = errors.New("workflow definition not found")
+	ErrWorkflowRunNotFound = errors.New("workflow run not found")
+	ErrInvalidWorkflow     = errors.New("invalid workflow definition")
+	ErrWorkflowRunFinished = errors.New("workflow run has already finished")
+
+	ErrTemplateNotFound = errors.New("task template not found")
+	ErrInvalidTemplate  = errors.New("invalid task template")
+
+	ErrArtifactNotFound = errors.New("task artifact not found")
+	ErrUploadNotFound   = errors.New("artifact upload not found")
+	ErrInvalidArtifact  = errors.New("invalid task artifact")
+	ErrArtifactTooLarge = errors.New("artifact exceeds size limit")
+	ErrChecksumMismatch = errors.New("artifact checksum mismatch")
+	ErrUploadOffset     = errors.New("upload offset does not match received bytes")
+	ErrUploadBusy       = errors.New("upload is being appended by another request")
+	ErrInvalidSignature = errors.New("invalid or expired download signature")
+
+	ErrApprovalRuleNotFound   = errors.New("approval rule not found")
+	ErrInvalidApprovalRule    = errors.New("invalid approval rule")
+	ErrApprovalRequired       = errors.New("task requires approval before it can start")
+	ErrApprovalPending        = errors.New("task is awaiting approval")
+	ErrTaskNotPendingApproval = errors.New("task is not awaiting approval")
+	ErrSelfApproval           = errors.New("approvers cannot approve their own tasks")
+	ErrInvalidApproval        = errors.New("invalid approval decision")
+
+	ErrRetryPolicyNotFound = errors.New("task retry policy not found")
+	ErrInvalidRetryPolicy  = errors.New("invalid task retry policy")
+
+	ErrGeofenceNotFound  = errors.New("geofence not found")
+	ErrGeofenceViolation = errors.New("task plan crosses a restricted geofence")
+	ErrNoRoute           = errors.New("no collision-free route found")
+	ErrInvalidCoverage   = errors.New("invalid coverage plan")
+
+	ErrInvalidDelivery      = errors.New("invalid delivery")
+	ErrDeliveryTransition   = errors.New("invalid delivery status transition")
+	ErrDeliveryNotConfirmed = errors.New("delivery has not been confirmed")
+
+	ErrInsufficientBattery = errors.New("not enough battery for mission")
+	ErrEnergyNotEstimated  = errors.New("task has no energy estimate")
+	ErrCalibrationNotFound = errors.New("energy calibration not found")
+
+	ErrPreflightFailed         = errors.New("pre-flight checks did not pass")
+	ErrNothingToOverride       = errors.New("pre-flight report has no warnings to override")
+	ErrPreflightNotOverridable = errors.New("pre-flight failures cannot be overridden")
 
 	ErrAlertNotFound        = errors.New("alert not found")
 	ErrAlertAlreadyResolved = errors.New("alert already resolved")
 
 	ErrInvalidData      = errors.New("invalid data")
+	ErrInvalidTimeRange = errors.New("invalid time range")
 	ErrPermissionDenied = errors.New("permission denied")
 	ErrInternalError    = errors.New("internal error")
 )
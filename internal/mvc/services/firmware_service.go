@@ -0,0 +1,1045 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// firmwareLeaderKey 固件发布调度主节点锁
+	firmwareLeaderKey = "firmware:rollout:leader"
+)
+
+// FirmwareConfig 固件发布配置
+type FirmwareConfig struct {
+	ScanInterval    time.Duration `yaml:"scan_interval" json:"scan_interval"`       // 调度扫描间隔
+	DispatchTimeout time.Duration `yaml:"dispatch_timeout" json:"dispatch_timeout"` // 下发后未回报结果即判定失败
+	WaitTimeout     time.Duration `yaml:"wait_timeout" json:"wait_timeout"`         // 阶段内无人机持续不满足升级条件时跳过
+	LockTTL         time.Duration `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultFirmwareConfig 默认固件发布配置
+func DefaultFirmwareConfig() *FirmwareConfig {
+	return &FirmwareConfig{
+		ScanInterval:    15 * time.Second,
+		DispatchTimeout: 30 * time.Minute,
+		WaitTimeout:     2 * time.Hour,
+		LockTTL:         time.Minute,
+	}
+}
+
+// CreateFirmwareReleaseParams 登记固件版本参数
+type CreateFirmwareReleaseParams struct {
+	Model        string `json:"model"`
+	Version      string `json:"version"`
+	Checksum     string `json:"checksum"`
+	DownloadURL  string `json:"download_url"`
+	Size         int64  `json:"size"`
+	ReleaseNotes string `json:"release_notes"`
+	MinVersion   string `json:"min_version"`
+	CreatedBy    uint   `json:"created_by"`
+}
+
+// CreateRolloutParams 创建发布活动参数
+type CreateRolloutParams struct {
+	Name             string                       `json:"name"`
+	ReleaseID        uint                         `json:"release_id"`
+	DroneIDs         []uint                       `json:"drone_ids"` // 为空时覆盖该机型全部待升级无人机
+	CanaryPercent    int                          `json:"canary_percent"`
+	BatchSize        int                          `json:"batch_size"`
+	FailureThreshold int                          `json:"failure_threshold"`
+	FailureAction    models.FirmwareFailureAction `json:"failure_action"`
+	AutoPromote      bool                         `json:"auto_promote"`
+	CreatedBy        uint                         `json:"created_by"`
+}
+
+// FirmwareUpdateResult 单机升级结果回报
+type FirmwareUpdateResult struct {
+	RolloutID uint   `json:"rollout_id"`
+	DroneID   uint   `json:"drone_id"`
+	Success   bool   `json:"success"`
+	Version   string `json:"version"` // 升级后实际运行的版本，为空时使用目标版本
+	Message   string `json:"message"`
+}
+
+// FirmwareCompliance 固件合规状态
+type FirmwareCompliance string
+
+const (
+	FirmwareUpToDate     FirmwareCompliance = "up_to_date"
+	FirmwareOutdated     FirmwareCompliance = "outdated"
+	FirmwareBelowMinimum FirmwareCompliance = "below_minimum" // 低于最新版本要求的最低版本，需要先升级到中间版本
+	FirmwareNoRelease    FirmwareCompliance = "no_release"
+)
+
+// DroneFirmwareCompliance 单机固件合规信息
+type DroneFirmwareCompliance struct {
+	DroneID       uint               `json:"drone_id"`
+	SerialNo      string             `json:"serial_no"`
+	Model         string             `json:"model"`
+	Status        models.DroneStatus `json:"status"`
+	Firmware      string             `json:"firmware"`
+	LatestVersion string             `json:"latest_version,omitempty"`
+	Compliance    FirmwareCompliance `json:"compliance"`
+}
+
+// FirmwareComplianceReport 固件合规报告
+type FirmwareComplianceReport struct {
+	Model        string                     `json:"model,omitempty"`
+	GeneratedAt  time.Time                  `json:"generated_at"`
+	Total        int                        `json:"total"`
+	UpToDate     int                        `json:"up_to_date"`
+	Outdated     int                        `json:"outdated"`
+	BelowMinimum int                        `json:"below_minimum"`
+	NoRelease    int                        `json:"no_release"`
+	Drones       []*DroneFirmwareCompliance `json:"drones"`
+}
+
+// FirmwareService 固件管理服务接口
+type FirmwareService interface {
+	// 固件目录
+	CreateRelease(ctx context.Context, params *CreateFirmwareReleaseParams) (*models.FirmwareRelease, error)
+	GetRelease(ctx context.Context, id uint) (*models.FirmwareRelease, error)
+	ListReleases(ctx context.Context, model string) ([]*models.FirmwareRelease, error)
+	// GetComplianceReport 固件合规报告，onlyOutdated为true时只返回需要升级的无人机
+	GetComplianceReport(ctx context.Context, model string, onlyOutdated bool) (*FirmwareComplianceReport, error)
+
+	// 分阶段发布
+	CreateRollout(ctx context.Context, params *CreateRolloutParams) (*models.FirmwareRollout, error)
+	GetRollout(ctx context.Context, id uint) (*models.FirmwareRollout, error)
+	ListRollouts(ctx context.Context, status models.FirmwareRolloutStatus) ([]*models.FirmwareRollout, error)
+	GetRolloutTargets(ctx context.Context, id uint) ([]*models.FirmwareRolloutTarget, error)
+	StartRollout(ctx context.Context, id uint) error
+	PauseRollout(ctx context.Context, id uint, reason string) error
+	ResumeRollout(ctx context.Context, id uint) error
+	AbortRollout(ctx context.Context, id uint, reason string) error
+	ReportUpdateResult(ctx context.Context, result *FirmwareUpdateResult) error
+
+	// 服务管理（发布调度）
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// FirmwareServiceImpl 固件管理服务实现
+// 发布调度只在选举出的主节点执行，单机状态变更均使用条件更新保证幂等
+type FirmwareServiceImpl struct {
+	config       *FirmwareConfig
+	db           *gorm.DB
+	election     *database.LeaderElection
+	kafkaService KafkaService
+	logger       *logger.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewFirmwareService 创建固件管理服务
+func NewFirmwareService(
+	config *FirmwareConfig,
+	db *gorm.DB,
+	lockService *database.LockService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) FirmwareService {
+	if config == nil {
+		config = DefaultFirmwareConfig()
+	}
+
+	return &FirmwareServiceImpl{
+		config:       config,
+		db:           db,
+		election:     database.NewLeaderElection(lockService, firmwareLeaderKey, config.LockTTL),
+		kafkaService: kafkaService,
+		logger:       logger,
+	}
+}
+
+// CreateRelease 登记固件版本
+func (s *FirmwareServiceImpl) CreateRelease(ctx context.Context, params *CreateFirmwareReleaseParams) (*models.FirmwareRelease, error) {
+	if params == nil || strings.TrimSpace(params.Model) == "" || strings.TrimSpace(params.Version) == "" {
+		return nil, fmt.Errorf("%w: model and version are required", ErrInvalidData)
+	}
+	if !isHexChecksum(params.Checksum) {
+		return nil, fmt.Errorf("%w: checksum must be a hex encoded SHA-256 digest", ErrInvalidData)
+	}
+	if params.MinVersion != "" && models.CompareVersions(params.MinVersion, params.Version) >= 0 {
+		return nil, fmt.Errorf("%w: min_version must be lower than version", ErrInvalidData)
+	}
+
+	release := &models.FirmwareRelease{
+		Model:        strings.TrimSpace(params.Model),
+		Version:      strings.TrimSpace(params.Version),
+		Checksum:     strings.ToLower(params.Checksum),
+		DownloadURL:  params.DownloadURL,
+		Size:         params.Size,
+		ReleaseNotes: params.ReleaseNotes,
+		MinVersion:   strings.TrimSpace(params.MinVersion),
+		CreatedBy:    params.CreatedBy,
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.FirmwareRelease{}).
+		Where("model = ? AND version = ?", release.Model, release.Version).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check firmware release: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrFirmwareExists
+	}
+
+	if err := s.db.WithContext(ctx).Create(release).Error; err != nil {
+		return nil, fmt.Errorf("failed to create firmware release: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"release_id": release.ID,
+		"model":      release.Model,
+		"version":    release.Version,
+	}).Info("Firmware release created")
+	return release, nil
+}
+
+// GetRelease 获取固件版本
+func (s *FirmwareServiceImpl) GetRelease(ctx context.Context, id uint) (*models.FirmwareRelease, error) {
+	var release models.FirmwareRelease
+	if err := s.db.WithContext(ctx).First(&release, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFirmwareNotFound
+		}
+		return nil, fmt.Errorf("failed to get firmware release: %w", err)
+	}
+	return &release, nil
+}
+
+// ListReleases 获取固件版本列表，按版本从新到旧排序
+func (s *FirmwareServiceImpl) ListReleases(ctx context.Context, model string) ([]*models.FirmwareRelease, error) {
+	query := s.db.WithContext(ctx)
+	if model != "" {
+		query = query.Where("model = ?", model)
+	}
+
+	var releases []*models.FirmwareRelease
+	if err := query.Find(&releases).Error; err != nil {
+		return nil, fmt.Errorf("failed to list firmware releases: %w", err)
+	}
+
+	sort.SliceStable(releases, func(i, j int) bool {
+		if releases[i].Model != releases[j].Model {
+			return releases[i].Model < releases[j].Model
+		}
+		return models.CompareVersions(releases[i].Version, releases[j].Version) > 0
+	})
+	return releases, nil
+}
+
+// GetComplianceReport 生成固件合规报告
+func (s *FirmwareServiceImpl) GetComplianceReport(ctx context.Context, model string, onlyOutdated bool) (*FirmwareComplianceReport, error) {
+	releases, err := s.ListReleases(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+
+	// ListReleases 已按版本倒序，每个机型第一条即最新版本
+	latest := make(map[string]*models.FirmwareRelease)
+	for _, release := range releases {
+		if _, ok := latest[release.Model]; !ok {
+			latest[release.Model] = release
+		}
+	}
+
+	query := s.db.WithContext(ctx).Order("id")
+	if model != "" {
+		query = query.Where("model = ?", model)
+	}
+	var drones []*models.Drone
+	if err := query.Find(&drones).Error; err != nil {
+		return nil, fmt.Errorf("failed to list drones: %w", err)
+	}
+
+	report := &FirmwareComplianceReport{
+		Model:       model,
+		GeneratedAt: time.Now(),
+		Drones:      make([]*DroneFirmwareCompliance, 0, len(drones)),
+	}
+
+	for _, drone := range drones {
+		item := &DroneFirmwareCompliance{
+			DroneID:  drone.ID,
+			SerialNo: drone.SerialNo,
+			Model:    drone.Model,
+			Status:   drone.Status,
+			Firmware: drone.Firmware,
+		}
+
+		release, ok := latest[drone.Model]
+		switch {
+		case !ok:
+			item.Compliance = FirmwareNoRelease
+			report.NoRelease++
+		case models.CompareVersions(drone.Firmware, release.Version) >= 0:
+			item.Compliance = FirmwareUpToDate
+			report.UpToDate++
+		case release.CanUpgradeFrom(drone.Firmware):
+			item.Compliance = FirmwareOutdated
+			report.Outdated++
+		default:
+			item.Compliance = FirmwareBelowMinimum
+			report.BelowMinimum++
+		}
+		if ok {
+			item.LatestVersion = release.Version
+		}
+		report.Total++
+
+		if onlyOutdated && item.Compliance != FirmwareOutdated && item.Compliance != FirmwareBelowMinimum {
+			continue
+		}
+		report.Drones = append(report.Drones, item)
+	}
+
+	return report, nil
+}
+
+// CreateRollout 创建发布活动并划分阶段
+// 第一阶段为金丝雀阶段，之后按批次大小划分；已是最新版本或低于最低版本要求的无人机不纳入
+func (s *FirmwareServiceImpl) CreateRollout(ctx context.Context, params *CreateRolloutParams) (*models.FirmwareRollout, error) {
+	if params == nil || strings.TrimSpace(params.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidData)
+	}
+	if params.CanaryPercent < 0 || params.CanaryPercent > 100 || params.BatchSize < 0 || params.FailureThreshold < 0 {
+		return nil, fmt.Errorf("%w: invalid rollout parameters", ErrInvalidData)
+	}
+	if params.FailureAction == "" {
+		params.FailureAction = models.FirmwareFailurePause
+	}
+	if params.FailureAction != models.FirmwareFailurePause && params.FailureAction != models.FirmwareFailureAbort {
+		return nil, fmt.Errorf("%w: failure_action must be pause or abort", ErrInvalidData)
+	}
+
+	release, err := s.GetRelease(ctx, params.ReleaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).Where("model = ?", release.Model).Order("id")
+	if len(params.DroneIDs) > 0 {
+		query = query.Where("id IN ?", params.DroneIDs)
+	}
+	var drones []*models.Drone
+	if err := query.Find(&drones).Error; err != nil {
+		return nil, fmt.Errorf("failed to list drones: %w", err)
+	}
+	if len(params.DroneIDs) > 0 && len(drones) != len(uniqueIDs(params.DroneIDs)) {
+		return nil, fmt.Errorf("%w: some drones do not exist or are not model %s", ErrInvalidData, release.Model)
+	}
+
+	eligible := make([]*models.Drone, 0, len(drones))
+	for _, drone := range drones {
+		if release.CanUpgradeFrom(drone.Firmware) {
+			eligible = append(eligible, drone)
+		}
+	}
+	if len(eligible) == 0 {
+		return nil, ErrNoRolloutTargets
+	}
+
+	steps := planRolloutSteps(len(eligible), params.CanaryPercent, params.BatchSize)
+
+	rollout := &models.FirmwareRollout{
+		Name:             strings.TrimSpace(params.Name),
+		ReleaseID:        release.ID,
+		Status:           models.FirmwareRolloutPending,
+		CanaryPercent:    params.CanaryPercent,
+		BatchSize:        params.BatchSize,
+		FailureThreshold: params.FailureThreshold,
+		FailureAction:    params.FailureAction,
+		AutoPromote:      params.AutoPromote,
+		TotalSteps:       steps[len(steps)-1],
+		TotalTargets:     len(eligible),
+		CreatedBy:        params.CreatedBy,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Release").Create(rollout).Error; err != nil {
+			return err
+		}
+
+		targets := make([]*models.FirmwareRolloutTarget, len(eligible))
+		for i, drone := range eligible {
+			targets[i] = &models.FirmwareRolloutTarget{
+				RolloutID:   rollout.ID,
+				DroneID:     drone.ID,
+				Step:        steps[i],
+				Status:      models.FirmwareTargetPending,
+				FromVersion: drone.Firmware,
+			}
+		}
+		return tx.CreateInBatches(targets, 500).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create firmware rollout: %w", err)
+	}
+
+	rollout.Release = *release
+	s.logger.WithFields(map[string]interface{}{
+		"rollout_id":  rollout.ID,
+		"release_id":  release.ID,
+		"version":     release.Version,
+		"targets":     rollout.TotalTargets,
+		"total_steps": rollout.TotalSteps,
+	}).Info("Firmware rollout created")
+	return rollout, nil
+}
+
+// GetRollout 获取发布活动
+func (s *FirmwareServiceImpl) GetRollout(ctx context.Context, id uint) (*models.FirmwareRollout, error) {
+	var rollout models.FirmwareRollout
+	if err := s.db.WithContext(ctx).Preload("Release").First(&rollout, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRolloutNotFound
+		}
+		return nil, fmt.Errorf("failed to get firmware rollout: %w", err)
+	}
+	return &rollout, nil
+}
+
+// ListRollouts 获取发布活动列表
+func (s *FirmwareServiceImpl) ListRollouts(ctx context.Context, status models.FirmwareRolloutStatus) ([]*models.FirmwareRollout, error) {
+	query := s.db.WithContext(ctx).Preload("Release").Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var rollouts []*models.FirmwareRollout
+	if err := query.Find(&rollouts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list firmware rollouts: %w", err)
+	}
+	return rollouts, nil
+}
+
+// GetRolloutTargets 获取发布活动的无人机列表
+func (s *FirmwareServiceImpl) GetRolloutTargets(ctx context.Context, id uint) ([]*models.FirmwareRolloutTarget, error) {
+	if _, err := s.GetRollout(ctx, id); err != nil {
+		return nil, err
+	}
+
+	var targets []*models.FirmwareRolloutTarget
+	if err := s.db.WithContext(ctx).Where("rollout_id = ?", id).Order("step, drone_id").Find(&targets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list rollout targets: %w", err)
+	}
+	return targets, nil
+}
+
+// StartRollout 启动发布活动
+func (s *FirmwareServiceImpl) StartRollout(ctx context.Context, id uint) error {
+	now := time.Now()
+	rollout, err := s.transition(ctx, id, []models.FirmwareRolloutStatus{models.FirmwareRolloutPending}, map[string]interface{}{
+		"status":          models.FirmwareRolloutRunning,
+		"current_step":    1,
+		"started_at":      now,
+		"step_started_at": now,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publishRolloutStatus(ctx, rollout, "started", "")
+	s.publishStepStarted(ctx, rollout)
+	return nil
+}
+
+// PauseRollout 暂停发布活动，已下发的升级不受影响
+func (s *FirmwareServiceImpl) PauseRollout(ctx context.Context, id uint, reason string) error {
+	rollout, err := s.transition(ctx, id, []models.FirmwareRolloutStatus{models.FirmwareRolloutRunning}, map[string]interface{}{
+		"status":       models.FirmwareRolloutPaused,
+		"pause_reason": reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publishRolloutStatus(ctx, rollout, "paused", reason)
+	return nil
+}
+
+// ResumeRollout 恢复发布活动，当前失败数视为已确认
+func (s *FirmwareServiceImpl) ResumeRollout(ctx context.Context, id uint) error {
+	rollout, err := s.transition(ctx, id, []models.FirmwareRolloutStatus{models.FirmwareRolloutPaused}, map[string]interface{}{
+		"status":            models.FirmwareRolloutRunning,
+		"pause_reason":      "",
+		"accepted_failures": gorm.Expr("failed_count"),
+		"step_started_at":   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publishRolloutStatus(ctx, rollout, "resumed", "")
+
+	// 金丝雀确认后恢复时，新阶段尚未开始下发
+	var started int64
+	if err := s.db.WithContext(ctx).Model(&models.FirmwareRolloutTarget{}).
+		Where("rollout_id = ? AND step = ? AND status <> ?", rollout.ID, rollout.CurrentStep, models.FirmwareTargetPending).
+		Count(&started).Error; err == nil && started == 0 {
+		s.publishStepStarted(ctx, rollout)
+	}
+	return nil
+}
+
+// AbortRollout 终止发布活动，未下发的无人机标记为跳过
+func (s *FirmwareServiceImpl) AbortRollout(ctx context.Context, id uint, reason string) error {
+	rollout, err := s.abort(ctx, id, reason)
+	if err != nil {
+		return err
+	}
+
+	s.publishRolloutStatus(ctx, rollout, "aborted", reason)
+	return nil
+}
+
+// ReportUpdateResult 记录单机升级结果
+func (s *FirmwareServiceImpl) ReportUpdateResult(ctx context.Context, result *FirmwareUpdateResult) error {
+	if result == nil || result.RolloutID == 0 || result.DroneID == 0 {
+		return ErrInvalidData
+	}
+
+	rollout, err := s.GetRollout(ctx, result.RolloutID)
+	if err != nil {
+		return err
+	}
+
+	status := models.FirmwareTargetFailed
+	counter := "failed_count"
+	if result.Success {
+		status = models.FirmwareTargetSucceeded
+		counter = "succeeded_count"
+	}
+	version := result.Version
+	if version == "" {
+		version = rollout.Release.Version
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.FirmwareRolloutTarget{}).
+			Where("rollout_id = ? AND drone_id = ? AND status = ?", result.RolloutID, result.DroneID, models.FirmwareTargetDispatched).
+			Updates(map[string]interface{}{
+				"status":       status,
+				"error":        result.Message,
+				"completed_at": time.Now(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRolloutInvalidState
+		}
+
+		if err := tx.Model(&models.FirmwareRollout{}).Where("id = ?", result.RolloutID).
+			UpdateColumn(counter, gorm.Expr(counter+" + 1")).Error; err != nil {
+			return err
+		}
+
+		if result.Success {
+			return tx.Model(&models.Drone{}).Where("id = ?", result.DroneID).Update("firmware", version).Error
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrRolloutInvalidState) {
+			return err
+		}
+		return fmt.Errorf("failed to record firmware update result: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"rollout_id": result.RolloutID,
+		"drone_id":   result.DroneID,
+		"success":    result.Success,
+		"version":    version,
+		"message":    result.Message,
+	}).Info("Firmware update result reported")
+	return nil
+}
+
+// Start 启动发布调度
+func (s *FirmwareServiceImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.scheduleLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"scan_interval":    s.config.ScanInterval.String(),
+		"dispatch_timeout": s.config.DispatchTimeout.String(),
+		"instance":         s.election.Identity(),
+	}).Info("Firmware rollout scheduler started")
+	return nil
+}
+
+// Stop 停止发布调度并释放主节点锁
+func (s *FirmwareServiceImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release firmware leader lock")
+	}
+
+	s.logger.Info("Firmware rollout scheduler stopped")
+	return nil
+}
+
+// scheduleLoop 周期推进运行中的发布活动
+func (s *FirmwareServiceImpl) scheduleLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Firmware leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+
+			rollouts, err := s.ListRollouts(s.ctx, models.FirmwareRolloutRunning)
+			if err != nil {
+				s.logger.WithError(err).Error("Failed to load running rollouts")
+				continue
+			}
+			for _, rollout := range rollouts {
+				s.advanceRollout(s.ctx, rollout)
+			}
+		}
+	}
+}
+
+// advanceRollout 推进单个发布活动：超时判定、失败处理、下发当前阶段、阶段切换
+func (s *FirmwareServiceImpl) advanceRollout(ctx context.Context, rollout *models.FirmwareRollout) {
+	log := s.logger.WithField("rollout_id", rollout.ID)
+
+	if err := s.expireDispatched(ctx, rollout); err != nil {
+		log.WithError(err).Error("Failed to expire dispatched firmware updates")
+		return
+	}
+
+	// 重新加载计数，expireDispatched 和结果回报都可能修改
+	current, err := s.GetRollout(ctx, rollout.ID)
+	if err != nil || current.Status != models.FirmwareRolloutRunning {
+		return
+	}
+	rollout = current
+
+	if rollout.FailureThresholdExceeded() {
+		s.handleFailureThreshold(ctx, rollout)
+		return
+	}
+
+	var targets []*models.FirmwareRolloutTarget
+	if err := s.db.WithContext(ctx).
+		Where("rollout_id = ? AND step = ?", rollout.ID, rollout.CurrentStep).
+		Find(&targets).Error; err != nil {
+		log.WithError(err).Error("Failed to load rollout step targets")
+		return
+	}
+
+	finished := true
+	for _, target := range targets {
+		if target.Status == models.FirmwareTargetPending {
+			s.dispatchTarget(ctx, rollout, target)
+		}
+		if !target.IsFinished() {
+			finished = false
+		}
+	}
+	if !finished {
+		return
+	}
+
+	s.completeStep(ctx, rollout, targets)
+}
+
+// dispatchTarget 满足条件时向无人机下发升级指令
+// 只有在线且未飞行的无人机才会下发，其余保持等待，超过等待时限后跳过
+func (s *FirmwareServiceImpl) dispatchTarget(ctx context.Context, rollout *models.FirmwareRollout, target *models.FirmwareRolloutTarget) {
+	var drone models.Drone
+	err := s.db.WithContext(ctx).First(&drone, target.DroneID).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		s.skipTarget(ctx, target, "drone no longer exists")
+		return
+	case err != nil:
+		s.logger.WithError(err).WithField("drone_id", target.DroneID).Warn("Failed to load rollout target drone")
+		return
+	}
+
+	release := &rollout.Release
+	if models.CompareVersions(drone.Firmware, release.Version) >= 0 {
+		s.skipTarget(ctx, target, fmt.Sprintf("already on firmware %s", drone.Firmware))
+		return
+	}
+	if !release.CanUpgradeFrom(drone.Firmware) {
+		s.skipTarget(ctx, target, fmt.Sprintf("firmware %s is below minimum version %s", drone.Firmware, release.MinVersion))
+		return
+	}
+
+	if drone.Status != models.DroneStatusOnline {
+		if rollout.StepStartedAt != nil && time.Since(*rollout.StepStartedAt) > s.config.WaitTimeout {
+			s.skipTarget(ctx, target, fmt.Sprintf("drone not available for update (status %s)", drone.Status))
+		}
+		return
+	}
+
+	now := time.Now()
+	res := s.db.WithContext(ctx).Model(&models.FirmwareRolloutTarget{}).
+		Where("id = ? AND status = ?", target.ID, models.FirmwareTargetPending).
+		Updates(map[string]interface{}{
+			"status":        models.FirmwareTargetDispatched,
+			"from_version":  drone.Firmware,
+			"dispatched_at": now,
+		})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return
+	}
+	target.Status = models.FirmwareTargetDispatched
+	target.DispatchedAt = &now
+
+	if s.kafkaService == nil {
+		return
+	}
+	if err := s.kafkaService.PublishDroneEvent(ctx, kafka.FirmwareUpdateRequestedEvent, kafka.FirmwareUpdateRequestedEventData{
+		RolloutID:   rollout.ID,
+		DroneID:     drone.ID,
+		FromVersion: drone.Firmware,
+		Version:     release.Version,
+		DownloadURL: release.DownloadURL,
+		Checksum:    release.Checksum,
+		Size:        release.Size,
+		Timestamp:   now,
+	}); err != nil {
+		s.logger.WithFields(map[string]interface{}{
+			"rollout_id": rollout.ID,
+			"drone_id":   drone.ID,
+			"error":      err.Error(),
+		}).Error("Failed to publish firmware update request")
+	}
+}
+
+// skipTarget 将等待中的无人机标记为跳过
+func (s *FirmwareServiceImpl) skipTarget(ctx context.Context, target *models.FirmwareRolloutTarget, reason string) {
+	res := s.db.WithContext(ctx).Model(&models.FirmwareRolloutTarget{}).
+		Where("id = ? AND status = ?", target.ID, models.FirmwareTargetPending).
+		Updates(map[string]interface{}{
+			"status":       models.FirmwareTargetSkipped,
+			"error":        reason,
+			"completed_at": time.Now(),
+		})
+	if res.Error == nil && res.RowsAffected > 0 {
+		target.Status = models.FirmwareTargetSkipped
+		target.Error = reason
+	}
+}
+
+// expireDispatched 将超时未回报结果的升级判定为失败
+func (s *FirmwareServiceImpl) expireDispatched(ctx context.Context, rollout *models.FirmwareRollout) error {
+	cutoff := time.Now().Add(-s.config.DispatchTimeout)
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.FirmwareRolloutTarget{}).
+			Where("rollout_id = ? AND status = ? AND dispatched_at < ?", rollout.ID, models.FirmwareTargetDispatched, cutoff).
+			Updates(map[string]interface{}{
+				"status":       models.FirmwareTargetFailed,
+				"error":        fmt.Sprintf("no result reported within %s", s.config.DispatchTimeout),
+				"completed_at": time.Now(),
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return tx.Model(&models.FirmwareRollout{}).Where("id = ?", rollout.ID).
+			UpdateColumn("failed_count", gorm.Expr("failed_count + ?", res.RowsAffected)).Error
+	})
+}
+
+// handleFailureThreshold 失败数超过阈值时按配置暂停或终止
+func (s *FirmwareServiceImpl) handleFailureThreshold(ctx context.Context, rollout *models.FirmwareRollout) {
+	reason := fmt.Sprintf("%d failed updates exceed threshold %d", rollout.FailedCount-rollout.AcceptedFailures, rollout.FailureThreshold)
+
+	var err error
+	if rollout.FailureAction == models.FirmwareFailureAbort {
+		err = s.AbortRollout(ctx, rollout.ID, reason)
+	} else {
+		err = s.PauseRollout(ctx, rollout.ID, reason)
+	}
+	if err != nil {
+		s.logger.WithError(err).WithField("rollout_id", rollout.ID).Error("Failed to apply rollout failure action")
+		return
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"rollout_id": rollout.ID,
+		"action":     rollout.FailureAction,
+		"reason":     reason,
+	}).Warn("Firmware rollout failure threshold exceeded")
+}
+
+// completeStep 当前阶段全部结束后进入下一阶段或完成发布
+// 金丝雀阶段结束后未开启自动推进时暂停，等待人工确认
+func (s *FirmwareServiceImpl) completeStep(ctx context.Context, rollout *models.FirmwareRollout, targets []*models.FirmwareRolloutTarget) {
+	s.publishRolloutEvent(ctx, kafka.FirmwareRolloutStepEvent, rollout, "completed", targetDroneIDs(targets), "")
+
+	now := time.Now()
+	if rollout.CurrentStep >= rollout.TotalSteps {
+		updated, err := s.transition(ctx, rollout.ID, []models.FirmwareRolloutStatus{models.FirmwareRolloutRunning}, map[string]interface{}{
+			"status":       models.FirmwareRolloutCompleted,
+			"completed_at": now,
+		})
+		if err != nil {
+			s.logger.WithError(err).WithField("rollout_id", rollout.ID).Error("Failed to complete firmware rollout")
+			return
+		}
+		s.publishRolloutStatus(ctx, updated, "completed", "")
+		return
+	}
+
+	updates := map[string]interface{}{
+		"current_step":    rollout.CurrentStep + 1,
+		"step_started_at": now,
+	}
+	holdForCanary := rollout.CurrentStep == 1 && rollout.CanaryPercent > 0 && !rollout.AutoPromote
+	if holdForCanary {
+		updates["status"] = models.FirmwareRolloutPaused
+		updates["pause_reason"] = "canary step completed, awaiting promotion"
+	}
+
+	updated, err := s.transition(ctx, rollout.ID, []models.FirmwareRolloutStatus{models.FirmwareRolloutRunning}, updates)
+	if err != nil {
+		s.logger.WithError(err).WithField("rollout_id", rollout.ID).Error("Failed to advance firmware rollout")
+		return
+	}
+
+	if holdForCanary {
+		s.publishRolloutStatus(ctx, updated, "paused", updated.PauseReason)
+		return
+	}
+	s.publishStepStarted(ctx, updated)
+}
+
+// transition 在允许的状态下更新发布活动，返回更新后的记录
+func (s *FirmwareServiceImpl) transition(ctx context.Context, id uint, from []models.FirmwareRolloutStatus, updates map[string]interface{}) (*models.FirmwareRollout, error) {
+	res := s.db.WithContext(ctx).Model(&models.FirmwareRollout{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to update firmware rollout: %w", res.Error)
+	}
+
+	rollout, err := s.GetRollout(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrRolloutInvalidState
+	}
+	return rollout, nil
+}
+
+// abort 终止发布活动并跳过未下发的无人机
+func (s *FirmwareServiceImpl) abort(ctx context.Context, id uint, reason string) (*models.FirmwareRollout, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.FirmwareRollout{}).
+			Where("id = ? AND status IN ?", id, []models.FirmwareRolloutStatus{
+				models.FirmwareRolloutPending, models.FirmwareRolloutRunning, models.FirmwareRolloutPaused,
+			}).
+			Updates(map[string]interface{}{
+				"status":       models.FirmwareRolloutAborted,
+				"pause_reason": reason,
+				"completed_at": time.Now(),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrRolloutInvalidState
+		}
+
+		return tx.Model(&models.FirmwareRolloutTarget{}).
+			Where("rollout_id = ? AND status = ?", id, models.FirmwareTargetPending).
+			Updates(map[string]interface{}{
+				"status":       models.FirmwareTargetSkipped,
+				"error":        "rollout aborted",
+				"completed_at": time.Now(),
+			}).Error
+	})
+
+	if errors.Is(err, ErrRolloutInvalidState) {
+		if _, getErr := s.GetRollout(ctx, id); getErr != nil {
+			return nil, getErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to abort firmware rollout: %w", err)
+	}
+
+	return s.GetRollout(ctx, id)
+}
+
+// publishStepStarted 发布阶段开始事件
+func (s *FirmwareServiceImpl) publishStepStarted(ctx context.Context, rollout *models.FirmwareRollout) {
+	var droneIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.FirmwareRolloutTarget{}).
+		Where("rollout_id = ? AND step = ?", rollout.ID, rollout.CurrentStep).
+		Order("drone_id").
+		Pluck("drone_id", &droneIDs).Error; err != nil {
+		s.logger.WithError(err).WithField("rollout_id", rollout.ID).Warn("Failed to load rollout step drones")
+	}
+
+	s.publishRolloutEvent(ctx, kafka.FirmwareRolloutStepEvent, rollout, "started", droneIDs, "")
+}
+
+// publishRolloutStatus 发布活动状态变更事件
+func (s *FirmwareServiceImpl) publishRolloutStatus(ctx context.Context, rollout *models.FirmwareRollout, action, reason string) {
+	s.logger.WithFields(map[string]interface{}{
+		"rollout_id": rollout.ID,
+		"action":     action,
+		"step":       rollout.CurrentStep,
+		"reason":     reason,
+	}).Info("Firmware rollout status changed")
+
+	s.publishRolloutEvent(ctx, kafka.FirmwareRolloutStatusEvent, rollout, action, nil, reason)
+}
+
+// publishRolloutEvent 发布固件发布活动事件
+func (s *FirmwareServiceImpl) publishRolloutEvent(ctx context.Context, eventType kafka.EventType, rollout *models.FirmwareRollout, action string, droneIDs []uint, reason string) {
+	if s.kafkaService == nil {
+		return
+	}
+
+	eventData := kafka.FirmwareRolloutEventData{
+		RolloutID:  rollout.ID,
+		ReleaseID:  rollout.ReleaseID,
+		Model:      rollout.Release.Model,
+		Version:    rollout.Release.Version,
+		Status:     string(rollout.Status),
+		Step:       rollout.CurrentStep,
+		TotalSteps: rollout.TotalSteps,
+		Action:     action,
+		DroneIDs:   droneIDs,
+		Succeeded:  rollout.SucceededCount,
+		Failed:     rollout.FailedCount,
+		Reason:     reason,
+		Timestamp:  time.Now(),
+	}
+
+	if err := s.kafkaService.PublishDroneEvent(ctx, eventType, eventData); err != nil {
+		s.logger.WithFields(map[string]interface{}{
+			"rollout_id": rollout.ID,
+			"event_type": eventType,
+			"error":      err.Error(),
+		}).Error("Failed to publish firmware rollout event")
+	}
+}
+
+// planRolloutSteps 为按顺序排列的无人机分配阶段号（从1开始）
+func planRolloutSteps(total, canaryPercent, batchSize int) []int {
+	steps := make([]int, total)
+
+	canary := 0
+	if canaryPercent > 0 {
+		canary = (total*canaryPercent + 99) / 100
+		if canary < 1 {
+			canary = 1
+		}
+	}
+	if batchSize <= 0 {
+		batchSize = total
+	}
+
+	step := 0
+	for i := 0; i < total; i++ {
+		switch {
+		case i < canary:
+			step = 1
+		case (i-canary)%batchSize == 0:
+			step++
+		}
+		steps[i] = step
+	}
+	return steps
+}
+
+// targetDroneIDs 提取无人机ID列表
+func targetDroneIDs(targets []*models.FirmwareRolloutTarget) []uint {
+	ids := make([]uint, len(targets))
+	for i, target := range targets {
+		ids[i] = target.DroneID
+	}
+	return ids
+}
+
+// uniqueIDs 去重
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// isHexChecksum 校验SHA-256十六进制摘要
+func isHexChecksum(checksum string) bool {
+	if len(checksum) != 64 {
+		return false
+	}
+	for _, r := range strings.ToLower(checksum) {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
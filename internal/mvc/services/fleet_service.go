@@ -0,0 +1,598 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// fleetCacheTTL 机队成员和无人机归属缓存有效期，多实例下权限变更最长延迟该时间生效
+	fleetCacheTTL = time.Minute
+)
+
+// FleetScope 按机队限制可访问的无人机范围，为nil时不限制
+type FleetScope struct {
+	FleetIDs          []uint `json:"fleet_ids"`          // 可访问的机队
+	IncludeUnassigned bool   `json:"include_unassigned"` // 是否包含未分配机队的无人机
+}
+
+// FleetAccess 用户的机队权限
+// 全局管理员拥有所有机队的管理员权限；全局观察员在任何机队内最多只读；
+// 其他用户在机队内的权限由成员角色决定，不是成员则无权访问该机队的无人机。
+// 未分配机队的无人机仍按全局角色控制。
+type FleetAccess struct {
+	UserID uint                     `json:"user_id"`
+	Role   models.UserRole          `json:"role"`
+	Fleets map[uint]models.UserRole `json:"fleets"`
+}
+
+// IsAdmin 是否为全局管理员
+func (a *FleetAccess) IsAdmin() bool {
+	return a.Role == models.RoleAdmin
+}
+
+// RoleFor 用户对某机队（为nil表示未分配机队）的有效角色，无权访问时为空
+func (a *FleetAccess) RoleFor(fleetID *uint) models.UserRole {
+	if a.IsAdmin() || fleetID == nil {
+		return a.Role
+	}
+
+	role, ok := a.Fleets[*fleetID]
+	if !ok {
+		return ""
+	}
+	if a.Role == models.RoleViewer && role.Level() > models.RoleViewer.Level() {
+		return models.RoleViewer
+	}
+	return role
+}
+
+// Allows 检查用户在机队内是否具备所需角色
+func (a *FleetAccess) Allows(fleetID *uint, required models.UserRole) bool {
+	return a.RoleFor(fleetID).Level() >= required.Level()
+}
+
+// Scope 用户具备所需角色的无人机范围，全局管理员返回nil
+func (a *FleetAccess) Scope(required models.UserRole) *FleetScope {
+	if a.IsAdmin() {
+		return nil
+	}
+
+	scope := &FleetScope{
+		FleetIDs:          []uint{},
+		IncludeUnassigned: a.Role.Level() >= required.Level(),
+	}
+	for fleetID := range a.Fleets {
+		id := fleetID
+		if a.Allows(&id, required) {
+			scope.FleetIDs = append(scope.FleetIDs, id)
+		}
+	}
+	return scope
+}
+
+// Contains 检查无人机所属机队是否在范围内
+func (s *FleetScope) Contains(fleetID *uint) bool {
+	if s == nil {
+		return true
+	}
+	if fleetID == nil {
+		return s.IncludeUnassigned
+	}
+	for _, id := range s.FleetIDs {
+		if id == *fleetID {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedDroneIDs 范围内无人机ID的子查询，范围内没有任何无人机时返回 false
+func scopedDroneIDs(db *gorm.DB, scope *FleetScope) (*gorm.DB, bool) {
+	query := db.Model(&models.Drone{}).Select("id")
+	switch {
+	case len(scope.FleetIDs) > 0 && scope.IncludeUnassigned:
+		return query.Where("fleet_id IN ? OR fleet_id IS NULL", scope.FleetIDs), true
+	case len(scope.FleetIDs) > 0:
+		return query.Where("fleet_id IN ?", scope.FleetIDs), true
+	case scope.IncludeUnassigned:
+		return query.Where("fleet_id IS NULL"), true
+	default:
+		return nil, false
+	}
+}
+
+// FleetParams 机队参数
+type FleetParams struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// FleetService 机队管理服务接口
+type FleetService interface {
+	CreateFleet(ctx context.Context, params *FleetParams) (*models.Fleet, error)
+	GetFleet(ctx context.Context, id uint) (*models.Fleet, error)
+	// ListFleets 获取机队列表，scope为nil时返回全部
+	ListFleets(ctx context.Context, scope *FleetScope) ([]*models.Fleet, error)
+	UpdateFleet(ctx context.Context, id uint, params *FleetParams) (*models.Fleet, error)
+	DeleteFleet(ctx context.Context, id uint) error
+
+	// 成员管理
+	ListMembers(ctx context.Context, fleetID uint) ([]*models.FleetMember, error)
+	SetMember(ctx context.Context, fleetID, userID uint, role models.UserRole) (*models.FleetMember, error)
+	RemoveMember(ctx context.Context, fleetID, userID uint) error
+
+	// 分组管理
+	CreateGroup(ctx context.Context, fleetID uint, params *FleetParams) (*models.DroneGroup, error)
+	ListGroups(ctx context.Context, fleetID uint) ([]*models.DroneGroup, error)
+	DeleteGroup(ctx context.Context, fleetID, groupID uint) error
+
+	// 无人机归属
+	AssignDrones(ctx context.Context, fleetID uint, groupID *uint, droneIDs []uint) error
+	UnassignDrones(ctx context.Context, fleetID uint, droneIDs []uint) error
+	// ListFleetDroneIDs 获取机队（或机队内分组）的无人机ID
+	ListFleetDroneIDs(ctx context.Context, fleetID uint, groupID *uint) ([]uint, error)
+	// GetDroneFleet 获取无人机所属机队，未分配时返回nil
+	GetDroneFleet(ctx context.Context, droneID uint) (*uint, error)
+
+	// GetAccess 获取用户的机队权限
+	GetAccess(ctx context.Context, userID uint, role models.UserRole) (*FleetAccess, error)
+}
+
+// cachedMemberships 用户机队角色缓存项
+type cachedMemberships struct {
+	fleets   map[uint]models.UserRole
+	loadedAt time.Time
+}
+
+// cachedDroneFleet 无人机所属机队缓存项
+type cachedDroneFleet struct {
+	fleetID  *uint
+	loadedAt time.Time
+}
+
+// FleetServiceImpl 机队管理服务实现
+type FleetServiceImpl struct {
+	db     *gorm.DB
+	logger *logger.Logger
+
+	members     map[uint]cachedMemberships
+	droneFleets map[uint]cachedDroneFleet
+	cacheMu     sync.Mutex
+}
+
+// NewFleetService 创建机队管理服务
+func NewFleetService(db *gorm.DB, logger *logger.Logger) FleetService {
+	return &FleetServiceImpl{
+		db:          db,
+		logger:      logger,
+		members:     make(map[uint]cachedMemberships),
+		droneFleets: make(map[uint]cachedDroneFleet),
+	}
+}
+
+// CreateFleet 创建机队
+func (s *FleetServiceImpl) CreateFleet(ctx context.Context, params *FleetParams) (*models.Fleet, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidData)
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Fleet{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check fleet: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrFleetExists
+	}
+
+	fleet := &models.Fleet{Name: name, Description: params.Description}
+	if err := s.db.WithContext(ctx).Create(fleet).Error; err != nil {
+		return nil, fmt.Errorf("failed to create fleet: %w", err)
+	}
+	return fleet, nil
+}
+
+// GetFleet 获取机队
+func (s *FleetServiceImpl) GetFleet(ctx context.Context, id uint) (*models.Fleet, error) {
+	var fleet models.Fleet
+	if err := s.db.WithContext(ctx).First(&fleet, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFleetNotFound
+		}
+		return nil, fmt.Errorf("failed to get fleet: %w", err)
+	}
+	return &fleet, nil
+}
+
+// ListFleets 获取机队列表
+func (s *FleetServiceImpl) ListFleets(ctx context.Context, scope *FleetScope) ([]*models.Fleet, error) {
+	query := s.db.WithContext(ctx).Model(&models.Fleet{})
+	if scope != nil {
+		if len(scope.FleetIDs) == 0 {
+			return []*models.Fleet{}, nil
+		}
+		query = query.Where("id IN ?", scope.FleetIDs)
+	}
+
+	var fleets []*models.Fleet
+	if err := query.Order("name").Find(&fleets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list fleets: %w", err)
+	}
+	return fleets, nil
+}
+
+// UpdateFleet 更新机队
+func (s *FleetServiceImpl) UpdateFleet(ctx context.Context, id uint, params *FleetParams) (*models.Fleet, error) {
+	fleet, err := s.GetFleet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"description": params.Description}
+	if name := strings.TrimSpace(params.Name); name != "" && name != fleet.Name {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.Fleet{}).Where("name = ? AND id <> ?", name, id).Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to check fleet: %w", err)
+		}
+		if count > 0 {
+			return nil, ErrFleetExists
+		}
+		updates["name"] = name
+	}
+
+	if err := s.db.WithContext(ctx).Model(fleet).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update fleet: %w", err)
+	}
+	return s.GetFleet(ctx, id)
+}
+
+// DeleteFleet 删除机队，机队内仍有无人机时拒绝删除
+func (s *FleetServiceImpl) DeleteFleet(ctx context.Context, id uint) error {
+	if _, err := s.GetFleet(ctx, id); err != nil {
+		return err
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Drone{}).Where("fleet_id = ?", id).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count fleet drones: %w", err)
+	}
+	if count > 0 {
+		return ErrFleetNotEmpty
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("fleet_id = ?", id).Delete(&models.FleetMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("fleet_id = ?", id).Delete(&models.DroneGroup{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Fleet{}, id).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete fleet: %w", err)
+	}
+
+	s.invalidateMembers()
+	return nil
+}
+
+// ListMembers 获取机队成员
+func (s *FleetServiceImpl) ListMembers(ctx context.Context, fleetID uint) ([]*models.FleetMember, error) {
+	if _, err := s.GetFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+
+	var members []*models.FleetMember
+	if err := s.db.WithContext(ctx).Where("fleet_id = ?", fleetID).Order("user_id").Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to list fleet members: %w", err)
+	}
+	return members, nil
+}
+
+// SetMember 添加成员或修改成员角色
+func (s *FleetServiceImpl) SetMember(ctx context.Context, fleetID, userID uint, role models.UserRole) (*models.FleetMember, error) {
+	if !role.IsValid() {
+		return nil, fmt.Errorf("%w: unknown fleet role: %s", ErrInvalidData, role)
+	}
+	if _, err := s.GetFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var member models.FleetMember
+	err := s.db.WithContext(ctx).Where("fleet_id = ? AND user_id = ?", fleetID, userID).First(&member).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		member = models.FleetMember{FleetID: fleetID, UserID: userID, Role: role}
+		err = s.db.WithContext(ctx).Create(&member).Error
+	case err == nil:
+		member.Role = role
+		err = s.db.WithContext(ctx).Model(&member).Update("role", role).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set fleet member: %w", err)
+	}
+
+	s.invalidateUser(userID)
+	return &member, nil
+}
+
+// RemoveMember 移除机队成员
+func (s *FleetServiceImpl) RemoveMember(ctx context.Context, fleetID, userID uint) error {
+	res := s.db.WithContext(ctx).Where("fleet_id = ? AND user_id = ?", fleetID, userID).Delete(&models.FleetMember{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to remove fleet member: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrFleetMemberNotFound
+	}
+
+	s.invalidateUser(userID)
+	return nil
+}
+
+// CreateGroup 创建分组
+func (s *FleetServiceImpl) CreateGroup(ctx context.Context, fleetID uint, params *FleetParams) (*models.DroneGroup, error) {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidData)
+	}
+	if _, err := s.GetFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.DroneGroup{}).
+		Where("fleet_id = ? AND name = ?", fleetID, name).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check drone group: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrDroneGroupExists
+	}
+
+	group := &models.DroneGroup{FleetID: fleetID, Name: name, Description: params.Description}
+	if err := s.db.WithContext(ctx).Create(group).Error; err != nil {
+		return nil, fmt.Errorf("failed to create drone group: %w", err)
+	}
+	return group, nil
+}
+
+// ListGroups 获取机队分组
+func (s *FleetServiceImpl) ListGroups(ctx context.Context, fleetID uint) ([]*models.DroneGroup, error) {
+	if _, err := s.GetFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+
+	var groups []*models.DroneGroup
+	if err := s.db.WithContext(ctx).Where("fleet_id = ?", fleetID).Order("name").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to list drone groups: %w", err)
+	}
+	return groups, nil
+}
+
+// DeleteGroup 删除分组，组内无人机保留在机队中
+func (s *FleetServiceImpl) DeleteGroup(ctx context.Context, fleetID, groupID uint) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND fleet_id = ?", groupID, fleetID).Delete(&models.DroneGroup{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrDroneGroupNotFound
+		}
+		return tx.Model(&models.Drone{}).Where("group_id = ?", groupID).Update("group_id", nil).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrDroneGroupNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete drone group: %w", err)
+	}
+	return nil
+}
+
+// AssignDrones 将无人机分配到机队（及分组），会从原机队移出
+func (s *FleetServiceImpl) AssignDrones(ctx context.Context, fleetID uint, groupID *uint, droneIDs []uint) error {
+	droneIDs = uniqueIDs(droneIDs)
+	if len(droneIDs) == 0 {
+		return fmt.Errorf("%w: drone_ids is required", ErrInvalidData)
+	}
+	if _, err := s.GetFleet(ctx, fleetID); err != nil {
+		return err
+	}
+	if groupID != nil {
+		if err := s.checkGroup(ctx, fleetID, *groupID); err != nil {
+			return err
+		}
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Drone{}).Where("id IN ?", droneIDs).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check drones: %w", err)
+	}
+	if int(count) != len(droneIDs) {
+		return ErrDroneNotFound
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Drone{}).Where("id IN ?", droneIDs).Updates(map[string]interface{}{
+		"fleet_id": fleetID,
+		"group_id": groupID,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to assign drones: %w", err)
+	}
+
+	id := fleetID
+	s.setDroneFleets(droneIDs, &id)
+	s.logger.WithFields(map[string]interface{}{
+		"fleet_id": fleetID,
+		"group_id": groupID,
+		"drones":   len(droneIDs),
+	}).Info("Drones assigned to fleet")
+	return nil
+}
+
+// UnassignDrones 将无人机移出机队，包含不属于该机队的无人机时全部不处理
+func (s *FleetServiceImpl) UnassignDrones(ctx context.Context, fleetID uint, droneIDs []uint) error {
+	droneIDs = uniqueIDs(droneIDs)
+	if len(droneIDs) == 0 {
+		return fmt.Errorf("%w: drone_ids is required", ErrInvalidData)
+	}
+
+	var removed []uint
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Drone{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND fleet_id = ?", droneIDs, fleetID).
+			Pluck("id", &removed).Error; err != nil {
+			return fmt.Errorf("failed to check fleet drones: %w", err)
+		}
+		if len(removed) != len(droneIDs) {
+			return ErrDroneNotInFleet
+		}
+
+		if err := tx.Model(&models.Drone{}).Where("id IN ?", removed).Updates(map[string]interface{}{
+			"fleet_id": nil,
+			"group_id": nil,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to unassign drones: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.setDroneFleets(removed, nil)
+	return nil
+}
+
+// ListFleetDroneIDs 获取机队（或分组）的无人机ID
+func (s *FleetServiceImpl) ListFleetDroneIDs(ctx context.Context, fleetID uint, groupID *uint) ([]uint, error) {
+	if _, err := s.GetFleet(ctx, fleetID); err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Drone{}).Where("fleet_id = ?", fleetID)
+	if groupID != nil {
+		if err := s.checkGroup(ctx, fleetID, *groupID); err != nil {
+			return nil, err
+		}
+		query = query.Where("group_id = ?", *groupID)
+	}
+
+	var ids []uint
+	if err := query.Order("id").Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to list fleet drones: %w", err)
+	}
+	return ids, nil
+}
+
+// GetDroneFleet 获取无人机所属机队
+func (s *FleetServiceImpl) GetDroneFleet(ctx context.Context, droneID uint) (*uint, error) {
+	s.cacheMu.Lock()
+	entry, ok := s.droneFleets[droneID]
+	s.cacheMu.Unlock()
+	if ok && time.Since(entry.loadedAt) < fleetCacheTTL {
+		return entry.fleetID, nil
+	}
+
+	var drone models.Drone
+	if err := s.db.WithContext(ctx).Select("id", "fleet_id").First(&drone, droneID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDroneNotFound
+		}
+		return nil, fmt.Errorf("failed to get drone fleet: %w", err)
+	}
+
+	s.setDroneFleets([]uint{droneID}, drone.FleetID)
+	return drone.FleetID, nil
+}
+
+// GetAccess 获取用户的机队权限
+func (s *FleetServiceImpl) GetAccess(ctx context.Context, userID uint, role models.UserRole) (*FleetAccess, error) {
+	access := &FleetAccess{UserID: userID, Role: role, Fleets: map[uint]models.UserRole{}}
+	if role == models.RoleAdmin || userID == 0 {
+		return access, nil
+	}
+
+	s.cacheMu.Lock()
+	entry, ok := s.members[userID]
+	s.cacheMu.Unlock()
+	if ok && time.Since(entry.loadedAt) < fleetCacheTTL {
+		access.Fleets = entry.fleets
+		return access, nil
+	}
+
+	var members []*models.FleetMember
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to load fleet memberships: %w", err)
+	}
+
+	fleets := make(map[uint]models.UserRole, len(members))
+	for _, member := range members {
+		fleets[member.FleetID] = member.Role
+	}
+
+	s.cacheMu.Lock()
+	s.members[userID] = cachedMemberships{fleets: fleets, loadedAt: time.Now()}
+	s.cacheMu.Unlock()
+
+	access.Fleets = fleets
+	return access, nil
+}
+
+// checkGroup 检查分组是否属于机队
+func (s *FleetServiceImpl) checkGroup(ctx context.Context, fleetID, groupID uint) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.DroneGroup{}).
+		Where("id = ? AND fleet_id = ?", groupID, fleetID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check drone group: %w", err)
+	}
+	if count == 0 {
+		return ErrDroneGroupNotFound
+	}
+	return nil
+}
+
+// setDroneFleets 更新无人机所属机队缓存
+func (s *FleetServiceImpl) setDroneFleets(droneIDs []uint, fleetID *uint) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	now := time.Now()
+	for _, id := range droneIDs {
+		s.droneFleets[id] = cachedDroneFleet{fleetID: fleetID, loadedAt: now}
+	}
+}
+
+// invalidateUser 清除用户机队角色缓存
+func (s *FleetServiceImpl) invalidateUser(userID uint) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	delete(s.members, userID)
+}
+
+// invalidateMembers 清除全部机队角色缓存
+func (s *FleetServiceImpl) invalidateMembers() {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.members = make(map[uint]cachedMemberships)
+}
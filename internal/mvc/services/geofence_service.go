@@ -0,0 +1,570 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// GeofenceConfig 地理围栏配置
+type GeofenceConfig struct {
+	ApproachDistance float64       `yaml:"approach_distance" json:"approach_distance"` // 距离围栏边界小于该值（米）时产生接近告警
+	CacheTTL         time.Duration `yaml:"cache_ttl" json:"cache_ttl"`                 // 围栏缓存有效期，多实例下修改后最长延迟该时间生效
+}
+
+// DefaultGeofenceConfig 默认地理围栏配置
+func DefaultGeofenceConfig() *GeofenceConfig {
+	return &GeofenceConfig{
+		ApproachDistance: 200,
+		CacheTTL:         30 * time.Second,
+	}
+}
+
+// GeofenceParams 创建或更新围栏参数，更新时只修改非零字段，设置 Shape 时替换整个几何
+type GeofenceParams struct {
+	Name            string                  `json:"name"`
+	Description     *string                 `json:"description"`
+	Type            models.GeofenceType     `json:"type"`
+	Shape           models.GeofenceShape    `json:"shape"`
+	Enabled         *bool                   `json:"enabled"`
+	Vertices        []geo.Point             `json:"vertices"`
+	CenterLatitude  float64                 `json:"center_latitude"`
+	CenterLongitude float64                 `json:"center_longitude"`
+	Radius          float64                 `json:"radius"`
+	MinAltitude     *float64                `json:"min_altitude"`
+	MaxAltitude     *float64                `json:"max_altitude"`
+	ActiveFrom      *time.Time              `json:"active_from"`
+	ActiveUntil     *time.Time              `json:"active_until"`
+	Windows         []models.GeofenceWindow `json:"windows"` // 更新时为nil表示不修改，空列表表示清除
+	Timezone        *string                 `json:"timezone"`
+	CreatedBy       uint                    `json:"created_by"`
+}
+
+// ListGeofencesParams 围栏列表参数
+type ListGeofencesParams struct {
+	Offset  int                 `json:"offset"`
+	Limit   int                 `json:"limit"`
+	Type    models.GeofenceType `json:"type"`
+	Enabled *bool               `json:"enabled"`
+}
+
+// GeofenceState 无人机相对围栏的状态
+type GeofenceState string
+
+const (
+	GeofenceStateClear    GeofenceState = ""
+	GeofenceStateApproach GeofenceState = "approach"
+	GeofenceStateBreach   GeofenceState = "breach"
+)
+
+// GeofenceHit 位置检查命中的围栏
+type GeofenceHit struct {
+	GeofenceID uint                `json:"geofence_id"`
+	Name       string              `json:"name"`
+	Type       models.GeofenceType `json:"type"`
+	State      GeofenceState       `json:"state"`
+	Distance   float64             `json:"distance"` // 距围栏水平边界的距离（米），在围栏内时为0
+}
+
+// GeofenceViolation 任务航线穿越的围栏
+type GeofenceViolation struct {
+	GeofenceID uint                `json:"geofence_id"`
+	Name       string              `json:"name"`
+	Type       models.GeofenceType `json:"type"`
+	Waypoint   int                 `json:"waypoint"` // 航段终点航点序号，单航点计划时为0
+}
+
+// GeofenceViolationError 任务航线穿越限制区域
+type GeofenceViolationError struct {
+	Violations []GeofenceViolation `json:"violations"`
+}
+
+func (e *GeofenceViolationError) Error() string {
+	names := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		names = append(names, fmt.Sprintf("%s (waypoint %d)", v.Name, v.Waypoint))
+	}
+	return fmt.Sprintf("task plan crosses restricted geofence: %s", strings.Join(names, ", "))
+}
+
+// Unwrap 支持 errors.Is(err, ErrGeofenceViolation)
+func (e *GeofenceViolationError) Unwrap() error {
+	return ErrGeofenceViolation
+}
+
+// GeofenceService 地理围栏服务接口
+type GeofenceService interface {
+	CreateGeofence(ctx context.Context, params *GeofenceParams) (*models.Geofence, error)
+	GetGeofence(ctx context.Context, id uint) (*models.Geofence, error)
+	ListGeofences(ctx context.Context, params *ListGeofencesParams) ([]*models.Geofence, int64, error)
+	UpdateGeofence(ctx context.Context, id uint, params *GeofenceParams) (*models.Geofence, error)
+	DeleteGeofence(ctx context.Context, id uint) error
+
+	// CheckPosition 检查无人机位置，进入或接近围栏时产生安全告警
+	CheckPosition(ctx context.Context, droneID uint, position models.Position, at time.Time) ([]*GeofenceHit, error)
+	// CheckPlan 检查任务航线在指定时间是否穿越禁飞区或限飞区
+	CheckPlan(ctx context.Context, plan *models.TaskPlan, at time.Time) error
+	// ActiveGeofences 返回指定时间生效的围栏
+	ActiveGeofences(ctx context.Context, at time.Time) ([]*models.Geofence, error)
+}
+
+// GeofenceServiceImpl 地理围栏服务实现
+type GeofenceServiceImpl struct {
+	config       *GeofenceConfig
+	db           *gorm.DB
+	alertService AlertService
+	kafkaService KafkaService
+	logger       *logger.Logger
+
+	cache       []*models.Geofence
+	cacheLoaded time.Time
+	cacheMu     sync.Mutex
+
+	// 无人机当前所处的围栏状态，只在状态升级时告警
+	states   map[uint]map[uint]GeofenceState
+	statesMu sync.Mutex
+}
+
+// NewGeofenceService 创建地理围栏服务
+func NewGeofenceService(
+	config *GeofenceConfig,
+	db *gorm.DB,
+	alertService AlertService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) GeofenceService {
+	if config == nil {
+		config = DefaultGeofenceConfig()
+	}
+
+	return &GeofenceServiceImpl{
+		config:       config,
+		db:           db,
+		alertService: alertService,
+		kafkaService: kafkaService,
+		logger:       logger,
+		states:       make(map[uint]map[uint]GeofenceState),
+	}
+}
+
+// CreateGeofence 创建围栏
+func (s *GeofenceServiceImpl) CreateGeofence(ctx context.Context, params *GeofenceParams) (*models.Geofence, error) {
+	if params == nil || strings.TrimSpace(params.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidData)
+	}
+
+	fence := &models.Geofence{
+		Name:      strings.TrimSpace(params.Name),
+		Type:      params.Type,
+		Enabled:   true,
+		CreatedBy: params.CreatedBy,
+	}
+	applyGeofenceParams(fence, params)
+
+	if err := fence.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	if err := s.db.WithContext(ctx).Create(fence).Error; err != nil {
+		return nil, fmt.Errorf("failed to create geofence: %w", err)
+	}
+	s.invalidateCache()
+
+	s.logger.WithFields(map[string]interface{}{
+		"geofence_id": fence.ID,
+		"type":        fence.Type,
+		"shape":       fence.Shape,
+	}).Info("Geofence created")
+	return fence, nil
+}
+
+// GetGeofence 获取围栏
+func (s *GeofenceServiceImpl) GetGeofence(ctx context.Context, id uint) (*models.Geofence, error) {
+	var fence models.Geofence
+	if err := s.db.WithContext(ctx).First(&fence, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGeofenceNotFound
+		}
+		return nil, fmt.Errorf("failed to get geofence: %w", err)
+	}
+	return &fence, nil
+}
+
+// ListGeofences 获取围栏列表
+func (s *GeofenceServiceImpl) ListGeofences(ctx context.Context, params *ListGeofencesParams) ([]*models.Geofence, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.Geofence{})
+	if params.Type != "" {
+		query = query.Where("type = ?", params.Type)
+	}
+	if params.Enabled != nil {
+		query = query.Where("enabled = ?", *params.Enabled)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count geofences: %w", err)
+	}
+
+	var fences []*models.Geofence
+	if err := query.Order("id").Offset(params.Offset).Limit(params.Limit).Find(&fences).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list geofences: %w", err)
+	}
+	return fences, total, nil
+}
+
+// UpdateGeofence 更新围栏
+func (s *GeofenceServiceImpl) UpdateGeofence(ctx context.Context, id uint, params *GeofenceParams) (*models.Geofence, error) {
+	fence, err := s.GetGeofence(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(params.Name); name != "" {
+		fence.Name = name
+	}
+	if params.Type != "" {
+		fence.Type = params.Type
+	}
+	applyGeofenceParams(fence, params)
+
+	if err := fence.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	if err := s.db.WithContext(ctx).Save(fence).Error; err != nil {
+		return nil, fmt.Errorf("failed to update geofence: %w", err)
+	}
+	s.invalidateCache()
+	return fence, nil
+}
+
+// DeleteGeofence 删除围栏
+func (s *GeofenceServiceImpl) DeleteGeofence(ctx context.Context, id uint) error {
+	res := s.db.WithContext(ctx).Delete(&models.Geofence{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete geofence: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrGeofenceNotFound
+	}
+	s.invalidateCache()
+	return nil
+}
+
+// CheckPosition 检查无人机位置
+func (s *GeofenceServiceImpl) CheckPosition(ctx context.Context, droneID uint, position models.Position, at time.Time) ([]*GeofenceHit, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	fences, err := s.ActiveGeofences(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	point := geo.Point{Latitude: position.Latitude, Longitude: position.Longitude}
+	var hits []*GeofenceHit
+	current := make(map[uint]GeofenceState)
+	for _, fence := range fences {
+		if !fence.InAltitudeBand(position.Altitude) {
+			continue
+		}
+
+		distance := fence.HorizontalDistance(point)
+		state := GeofenceStateClear
+		switch {
+		case distance == 0:
+			state = GeofenceStateBreach
+		case distance <= s.config.ApproachDistance:
+			state = GeofenceStateApproach
+		default:
+			continue
+		}
+
+		current[fence.ID] = state
+		hits = append(hits, &GeofenceHit{
+			GeofenceID: fence.ID,
+			Name:       fence.Name,
+			Type:       fence.Type,
+			State:      state,
+			Distance:   distance,
+		})
+	}
+
+	previous := s.swapStates(droneID, current)
+	for _, hit := range hits {
+		if escalated(previous[hit.GeofenceID], hit.State) {
+			s.raiseGeofenceAlert(ctx, droneID, position, hit)
+		}
+	}
+	return hits, nil
+}
+
+// CheckPlan 检查任务航线
+func (s *GeofenceServiceImpl) CheckPlan(ctx context.Context, plan *models.TaskPlan, at time.Time) error {
+	if plan == nil || len(plan.Waypoints) == 0 {
+		return nil
+	}
+
+	fences, err := s.ActiveGeofences(ctx, at)
+	if err != nil {
+		return err
+	}
+
+	var violations []GeofenceViolation
+	for _, fence := range fences {
+		if !fence.Type.BlocksPlans() {
+			continue
+		}
+		if index, ok := planIntersection(fence, plan.Waypoints); ok {
+			violations = append(violations, GeofenceViolation{
+				GeofenceID: fence.ID,
+				Name:       fence.Name,
+				Type:       fence.Type,
+				Waypoint:   index,
+			})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &GeofenceViolationError{Violations: violations}
+	}
+	return nil
+}
+
+// planIntersection 返回航线首个进入围栏的航段终点序号
+func planIntersection(fence *models.Geofence, waypoints models.WaypointList) (int, bool) {
+	first := waypoints[0]
+	if len(waypoints) == 1 {
+		return 0, fence.Contains(geo.Point{Latitude: first.Latitude, Longitude: first.Longitude}, first.Altitude)
+	}
+
+	for i := 1; i < len(waypoints); i++ {
+		a, b := waypoints[i-1], waypoints[i]
+		if fence.IntersectsSegment(
+			geo.Point{Latitude: a.Latitude, Longitude: a.Longitude}, a.Altitude,
+			geo.Point{Latitude: b.Latitude, Longitude: b.Longitude}, b.Altitude,
+		) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// ActiveGeofences 返回指定时间生效的围栏
+func (s *GeofenceServiceImpl) ActiveGeofences(ctx context.Context, at time.Time) ([]*models.Geofence, error) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	if s.cache == nil || time.Since(s.cacheLoaded) > s.config.CacheTTL {
+		var fences []*models.Geofence
+		if err := s.db.WithContext(ctx).Where("enabled = ?", true).Find(&fences).Error; err != nil {
+			return nil, fmt.Errorf("failed to load geofences: %w", err)
+		}
+		s.cache = fences
+		s.cacheLoaded = time.Now()
+	}
+
+	active := make([]*models.Geofence, 0, len(s.cache))
+	for _, fence := range s.cache {
+		if fence.ActiveAt(at) {
+			active = append(active, fence)
+		}
+	}
+	return active, nil
+}
+
+// invalidateCache 围栏修改后清空本实例缓存
+func (s *GeofenceServiceImpl) invalidateCache() {
+	s.cacheMu.Lock()
+	s.cache = nil
+	s.cacheMu.Unlock()
+}
+
+// swapStates 替换无人机的围栏状态，返回之前的状态
+func (s *GeofenceServiceImpl) swapStates(droneID uint, current map[uint]GeofenceState) map[uint]GeofenceState {
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+
+	previous := s.states[droneID]
+	if len(current) == 0 {
+		delete(s.states, droneID)
+	} else {
+		s.states[droneID] = current
+	}
+	return previous
+}
+
+// escalated 判断状态是否升级（未接近→接近→进入）
+func escalated(previous, current GeofenceState) bool {
+	switch current {
+	case GeofenceStateBreach:
+		return previous != GeofenceStateBreach
+	case GeofenceStateApproach:
+		return previous == GeofenceStateClear
+	default:
+		return false
+	}
+}
+
+// raiseGeofenceAlert 产生围栏安全告警
+func (s *GeofenceServiceImpl) raiseGeofenceAlert(ctx context.Context, droneID uint, position models.Position, hit *GeofenceHit) {
+	level := models.AlertLevelWarning
+	title := "无人机接近限制区域"
+	message := fmt.Sprintf("无人机 %d 距%s %.0f 米", droneID, hit.Name, hit.Distance)
+	code := "GEOFENCE_APPROACH"
+	if hit.Type == models.GeofenceAdvisory {
+		level = models.AlertLevelInfo
+	}
+
+	if hit.State == GeofenceStateBreach {
+		title = "无人机进入限制区域"
+		message = fmt.Sprintf("无人机 %d 进入%s（%.6f, %.6f, 高度 %.1fm）",
+			droneID, hit.Name, position.Latitude, position.Longitude, position.Altitude)
+		code = "GEOFENCE_BREACH"
+		switch hit.Type {
+		case models.GeofenceNoFly:
+			level = models.AlertLevelCritical
+		case models.GeofenceRestricted:
+			level = models.AlertLevelError
+		default:
+			level = models.AlertLevelWarning
+		}
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"drone_id":    droneID,
+		"geofence_id": hit.GeofenceID,
+		"state":       hit.State,
+		"distance":    hit.Distance,
+	}).Warn("Geofence alert")
+
+	raiseAlert(ctx, s.alertService, s.kafkaService, s.logger, &CreateAlertParams{
+		Title:   title,
+		Message: message,
+		Type:    models.AlertTypeSecurity,
+		Level:   level,
+		Source:  "geofence-service",
+		Code:    code,
+		Data:    fmt.Sprintf(`{"geofence_id":%d,"state":"%s","distance":%.1f}`, hit.GeofenceID, hit.State, hit.Distance),
+		DroneID: &droneID,
+	})
+}
+
+// applyGeofenceParams 将几何、高度和时间参数写入围栏
+func applyGeofenceParams(fence *models.Geofence, params *GeofenceParams) {
+	if params.Description != nil {
+		fence.Description = *params.Description
+	}
+	if params.Enabled != nil {
+		fence.Enabled = *params.Enabled
+	}
+	if params.Shape != "" {
+		fence.Shape = params.Shape
+		fence.Vertices = nil
+		fence.CenterLatitude, fence.CenterLongitude, fence.Radius = 0, 0, 0
+		if params.Shape == models.GeofenceShapePolygon {
+			fence.Vertices = params.Vertices
+		} else {
+			fence.CenterLatitude = params.CenterLatitude
+			fence.CenterLongitude = params.CenterLongitude
+			fence.Radius = params.Radius
+		}
+	}
+	if params.MinAltitude != nil {
+		fence.MinAltitude = *params.MinAltitude
+	}
+	if params.MaxAltitude != nil {
+		fence.MaxAltitude = *params.MaxAltitude
+	}
+	if params.ActiveFrom != nil {
+		fence.ActiveFrom = params.ActiveFrom
+	}
+	if params.ActiveUntil != nil {
+		fence.ActiveUntil = params.ActiveUntil
+	}
+	if params.Windows != nil {
+		fence.Windows = params.Windows
+	}
+	if params.Timezone != nil {
+		fence.Timezone = *params.Timezone
+	}
+}
+
+// GeofenceTaskService 在任务创建、更新和启动时检查航线是否穿越限制区域的任务服务装饰器
+type GeofenceTaskService struct {
+	TaskService
+	geofenceService GeofenceService
+}
+
+// NewGeofenceTaskService 创建围栏检查任务服务
+func NewGeofenceTaskService(next TaskService, geofenceService GeofenceService) TaskService {
+	return &GeofenceTaskService{
+		TaskService:     next,
+		geofenceService: geofenceService,
+	}
+}
+
+// CreateTask 按计划执行时间检查航线
+func (s *GeofenceTaskService) CreateTask(ctx context.Context, params *CreateTaskParams) (*models.Task, error) {
+	if err := s.geofenceService.CheckPlan(ctx, &params.Plan, plannedAt(params.ScheduledAt)); err != nil {
+		return nil, err
+	}
+	return s.TaskService.CreateTask(ctx, params)
+}
+
+// UpdateTask 修改计划或执行时间时重新检查
+func (s *GeofenceTaskService) UpdateTask(ctx context.Context, id uint, params *UpdateTaskParams) (*models.Task, error) {
+	if params.Plan == nil && params.ScheduledAt == nil {
+		return s.TaskService.UpdateTask(ctx, id, params)
+	}
+
+	task, err := s.TaskService.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &task.Plan
+	if params.Plan != nil {
+		plan = params.Plan
+	}
+	scheduledAt := task.ScheduledAt
+	if params.ScheduledAt != nil {
+		scheduledAt = params.ScheduledAt
+	}
+
+	if err := s.geofenceService.CheckPlan(ctx, plan, plannedAt(scheduledAt)); err != nil {
+		return nil, err
+	}
+	return s.TaskService.UpdateTask(ctx, id, params)
+}
+
+// StartTask 启动前按当前生效的围栏再次检查
+func (s *GeofenceTaskService) StartTask(ctx context.Context, id uint) error {
+	task, err := s.TaskService.GetTaskByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.geofenceService.CheckPlan(ctx, &task.Plan, time.Now()); err != nil {
+		return err
+	}
+	return s.TaskService.StartTask(ctx, id)
+}
+
+// plannedAt 任务计划执行时间，未设置或已过去时使用当前时间
+func plannedAt(scheduledAt *time.Time) time.Time {
+	now := time.Now()
+	if scheduledAt == nil || scheduledAt.Before(now) {
+		return now
+	}
+	return *scheduledAt
+}
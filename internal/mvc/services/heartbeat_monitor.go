@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// heartbeatKey 无人机最后心跳时间（有序集合，score为Unix时间戳）
+	heartbeatKey = "monitor:drones:heartbeat"
+	// heartbeatLeaderKey 心跳扫描主节点锁
+	heartbeatLeaderKey = "monitor:heartbeat:leader"
+)
+
+// popStaleScript 原子地取出并移除心跳早于截止时间的无人机，返回 member、score 交替的列表
+// 扫描和移除之间到达的心跳会把 score 推到截止时间之后，不会被误删
+var popStaleScript = redis.NewScript(`
+	local stale = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "WITHSCORES")
+	for i = 1, #stale, 2 do
+		redis.call("ZREM", KEYS[1], stale[i])
+	end
+	return stale
+`)
+
+// HeartbeatConfig 心跳监控配置
+type HeartbeatConfig struct {
+	Timeout      time.Duration `yaml:"timeout" json:"timeout"`             // 超过该时间无遥测即判定离线
+	ScanInterval time.Duration `yaml:"scan_interval" json:"scan_interval"` // 扫描间隔
+	LockTTL      time.Duration `yaml:"lock_ttl" json:"lock_ttl"`           // 主节点锁有效期
+}
+
+// DefaultHeartbeatConfig 默认心跳监控配置
+func DefaultHeartbeatConfig() *HeartbeatConfig {
+	return &HeartbeatConfig{
+		Timeout:      30 * time.Second,
+		ScanInterval: 5 * time.Second,
+		LockTTL:      15 * time.Second,
+	}
+}
+
+// HeartbeatMonitor 无人机心跳监控接口
+type HeartbeatMonitor interface {
+	// RecordHeartbeat 记录一次遥测心跳，离线后恢复遥测时触发上线事件
+	RecordHeartbeat(ctx context.Context, droneID uint, at time.Time) error
+	// LastSeen 获取无人机最后心跳时间
+	LastSeen(ctx context.Context, droneID uint) (*time.Time, error)
+	// Watch 将不在心跳集合中的无人机重新纳入监控，从当前时间开始计算超时
+	Watch(ctx context.Context, droneID uint) error
+
+	// 服务管理
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// HeartbeatMonitorImpl 心跳监控实现
+// 所有实例都写入心跳，只有选举出的主节点执行超时扫描
+type HeartbeatMonitorImpl struct {
+	config       *HeartbeatConfig
+	redis        *redis.Client
+	election     *database.LeaderElection
+	droneService DroneService
+	alertService AlertService
+	kafkaService KafkaService
+	logger       *logger.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewHeartbeatMonitor 创建心跳监控
+func NewHeartbeatMonitor(
+	config *HeartbeatConfig,
+	redisClient *redis.Client,
+	lockService *database.LockService,
+	droneService DroneService,
+	alertService AlertService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) HeartbeatMonitor {
+	if config == nil {
+		config = DefaultHeartbeatConfig()
+	}
+
+	return &HeartbeatMonitorImpl{
+		config:       config,
+		redis:        redisClient,
+		election:     database.NewLeaderElection(lockService, heartbeatLeaderKey, config.LockTTL),
+		droneService: droneService,
+		alertService: alertService,
+		kafkaService: kafkaService,
+		logger:       logger,
+	}
+}
+
+// RecordHeartbeat 记录心跳
+func (m *HeartbeatMonitorImpl) RecordHeartbeat(ctx context.Context, droneID uint, at time.Time) error {
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	// GT: 只允许时间前移，避免乱序消息回退心跳
+	added, err := m.redis.ZAddArgs(ctx, heartbeatKey, redis.ZAddArgs{
+		GT:      true,
+		Members: []redis.Z{{Score: float64(at.Unix()), Member: droneMember(droneID)}},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+
+	// 新加入集合说明此前未在线（首次上报或已被判定离线）
+	if added > 0 {
+		m.handleReconnect(ctx, droneID, at)
+	}
+
+	return nil
+}
+
+// LastSeen 获取最后心跳时间
+func (m *HeartbeatMonitorImpl) LastSeen(ctx context.Context, droneID uint) (*time.Time, error) {
+	score, err := m.redis.ZScore(ctx, heartbeatKey, droneMember(droneID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heartbeat: %w", err)
+	}
+
+	lastSeen := time.Unix(int64(score), 0)
+	return &lastSeen, nil
+}
+
+// Watch 重新纳入监控，已有心跳记录时不变
+func (m *HeartbeatMonitorImpl) Watch(ctx context.Context, droneID uint) error {
+	// NX: 不覆盖已有心跳，也不会触发上线事件
+	if err := m.redis.ZAddNX(ctx, heartbeatKey, &redis.Z{
+		Score:  float64(time.Now().Unix()),
+		Member: droneMember(droneID),
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to watch heartbeat: %w", err)
+	}
+	return nil
+}
+
+// Start 启动心跳扫描
+func (m *HeartbeatMonitorImpl) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return nil
+	}
+
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.running = true
+
+	m.wg.Add(1)
+	go m.scanLoop()
+
+	m.logger.WithFields(map[string]interface{}{
+		"timeout":       m.config.Timeout.String(),
+		"scan_interval": m.config.ScanInterval.String(),
+		"instance":      m.election.Identity(),
+	}).Info("Heartbeat monitor started")
+	return nil
+}
+
+// Stop 停止心跳扫描并释放主节点锁
+func (m *HeartbeatMonitorImpl) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.running {
+		return nil
+	}
+
+	m.cancel()
+	m.wg.Wait()
+	m.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := m.election.Resign(ctx); err != nil {
+		m.logger.WithError(err).Warn("Failed to release heartbeat leader lock")
+	}
+
+	m.logger.Info("Heartbeat monitor stopped")
+	return nil
+}
+
+// scanLoop 周期扫描超时无人机
+func (m *HeartbeatMonitorImpl) scanLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.config.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := m.election.TryAcquire(m.ctx)
+			if err != nil {
+				m.logger.WithError(err).Warn("Heartbeat leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+			m.scanStaleDrones(m.ctx)
+		}
+	}
+}
+
+// scanStaleDrones 查找并处理超时无人机
+func (m *HeartbeatMonitorImpl) scanStaleDrones(ctx context.Context) {
+	cutoff := time.Now().Add(-m.config.Timeout).Unix()
+
+	// 先移出集合，恢复遥测时才能重新触发上线事件
+	stale, err := popStaleScript.Run(ctx, m.redis, []string{heartbeatKey}, cutoff).StringSlice()
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to scan heartbeats")
+		return
+	}
+
+	for i := 0; i+1 < len(stale); i += 2 {
+		droneID, err := strconv.ParseUint(stale[i], 10, 32)
+		if err != nil {
+			continue
+		}
+		score, err := strconv.ParseFloat(stale[i+1], 64)
+		if err != nil {
+			continue
+		}
+
+		m.handleDisconnect(ctx, uint(droneID), time.Unix(int64(score), 0))
+	}
+}
+
+// handleDisconnect 处理无人机失联
+func (m *HeartbeatMonitorImpl) handleDisconnect(ctx context.Context, droneID uint, lastSeen time.Time) {
+	drone, err := m.droneService.GetDroneByID(ctx, droneID)
+	if err != nil {
+		m.logger.WithFields(map[string]interface{}{
+			"drone_id": droneID,
+			"error":    err.Error(),
+		}).Warn("Failed to load silent drone")
+		// 已删除的无人机不再监控，其他错误放回集合等待下次扫描重试
+		if err != ErrDroneNotFound {
+			m.restoreHeartbeat(ctx, droneID, lastSeen)
+		}
+		return
+	}
+
+	// 维护中、故障或已离线的无人机不再重复处理
+	if !shouldMarkOffline(drone.Status) {
+		return
+	}
+
+	oldStatus := drone.Status
+	if err := m.droneService.UpdateDroneStatus(ctx, droneID, models.DroneStatusOffline); err != nil {
+		m.logger.WithFields(map[string]interface{}{
+			"drone_id": droneID,
+			"error":    err.Error(),
+		}).Error("Failed to mark drone offline")
+		m.restoreHeartbeat(ctx, droneID, lastSeen)
+		return
+	}
+
+	silence := time.Since(lastSeen).Round(time.Second)
+	m.logger.WithFields(map[string]interface{}{
+		"drone_id":   droneID,
+		"old_status": oldStatus,
+		"last_seen":  lastSeen,
+		"silence":    silence.String(),
+	}).Warn("Drone heartbeat timed out, marked offline")
+
+	m.publishConnectionEvent(ctx, kafka.DroneDisconnectedEvent, drone, oldStatus, models.DroneStatusOffline,
+		fmt.Sprintf("no telemetry for %s", silence))
+
+	// 飞行中失联为严重告警
+	level := models.AlertLevelWarning
+	if oldStatus == models.DroneStatusFlying {
+		level = models.AlertLevelCritical
+	}
+
+	raiseAlert(ctx, m.alertService, m.kafkaService, m.logger, &CreateAlertParams{
+		Title:   "无人机失联",
+		Message: fmt.Sprintf("无人机 %s 已 %s 未上报遥测，状态由 %s 变更为离线", drone.SerialNo, silence, oldStatus),
+		Type:    models.AlertTypeNetwork,
+		Level:   level,
+		Source:  "heartbeat-monitor",
+		Code:    "DRONE_HEARTBEAT_TIMEOUT",
+		DroneID: &droneID,
+	})
+}
+
+// handleReconnect 处理无人机恢复遥测
+func (m *HeartbeatMonitorImpl) handleReconnect(ctx context.Context, droneID uint, at time.Time) {
+	drone, err := m.droneService.GetDroneByID(ctx, droneID)
+	if err != nil {
+		m.logger.WithFields(map[string]interface{}{
+			"drone_id": droneID,
+			"error":    err.Error(),
+		}).Warn("Failed to load reconnected drone")
+		return
+	}
+
+	oldStatus := drone.Status
+	newStatus := oldStatus
+	if oldStatus == models.DroneStatusOffline {
+		newStatus = models.DroneStatusOnline
+		if err := m.droneService.UpdateDroneStatus(ctx, droneID, newStatus); err != nil {
+			m.logger.WithFields(map[string]interface{}{
+				"drone_id": droneID,
+				"error":    err.Error(),
+			}).Error("Failed to mark drone online")
+			return
+		}
+	}
+
+	m.logger.WithFields(map[string]interface{}{
+		"drone_id":   droneID,
+		"old_status": oldStatus,
+		"new_status": newStatus,
+		"at":         at,
+	}).Info("Drone telemetry resumed")
+
+	// 首次上报或维护、故障期间恢复遥测时状态未变化，不发布上线事件
+	if oldStatus == models.DroneStatusOffline {
+		m.publishConnectionEvent(ctx, kafka.DroneConnectedEvent, drone, oldStatus, newStatus, "telemetry resumed")
+	}
+}
+
+// restoreHeartbeat 处理失联失败时按原心跳时间放回集合，下次扫描会重新处理
+func (m *HeartbeatMonitorImpl) restoreHeartbeat(ctx context.Context, droneID uint, lastSeen time.Time) {
+	// NX: 期间已恢复遥测时保留新的心跳
+	if err := m.redis.ZAddNX(ctx, heartbeatKey, &redis.Z{
+		Score:  float64(lastSeen.Unix()),
+		Member: droneMember(droneID),
+	}).Err(); err != nil {
+		m.logger.WithFields(map[string]interface{}{
+			"drone_id": droneID,
+			"error":    err.Error(),
+		}).Error("Failed to restore heartbeat")
+	}
+}
+
+// publishConnectionEvent 发布连接状态事件
+func (m *HeartbeatMonitorImpl) publishConnectionEvent(ctx context.Context, eventType kafka.EventType, drone *models.Drone, oldStatus, newStatus models.DroneStatus, reason string) {
+	if m.kafkaService == nil {
+		return
+	}
+
+	eventData := kafka.DroneStatusChangedEventData{
+		DroneID:   drone.ID,
+		DroneName: drone.SerialNo,
+		OldStatus: string(oldStatus),
+		NewStatus: string(newStatus),
+		Reason:    reason,
+		Location: &kafka.Location{
+			Latitude:  drone.Position.Latitude,
+			Longitude: drone.Position.Longitude,
+			Altitude:  drone.Position.Altitude,
+			Heading:   drone.Position.Heading,
+		},
+		Battery:   drone.Battery,
+		Timestamp: time.Now(),
+	}
+
+	if err := m.kafkaService.PublishDroneEvent(ctx, eventType, eventData); err != nil {
+		m.logger.WithFields(map[string]interface{}{
+			"drone_id":   drone.ID,
+			"event_type": eventType,
+			"error":      err.Error(),
+		}).Error("Failed to publish drone connection event")
+	}
+}
+
+// shouldMarkOffline 判断失联时是否需要变更为离线状态
+func shouldMarkOffline(status models.DroneStatus) bool {
+	switch status {
+	case models.DroneStatusOnline, models.DroneStatusFlying, models.DroneStatusCharging:
+		return true
+	default:
+		return false
+	}
+}
+
+// droneMember 无人机在Redis集合中的成员名
+func droneMember(droneID uint) string {
+	return strconv.FormatUint(uint64(droneID), 10)
+}
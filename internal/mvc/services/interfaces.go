@@ -66,35 +66,39 @@ type DroneService interface {
 	UpdateDroneStatus(ctx context.Context, id uint, status models.DroneStatus) error
 	UpdateDronePosition(ctx context.Context, id uint, position models.Position) error
 	UpdateDroneBattery(ctx context.Context, id uint, battery int) error
-	GetAvailableDrones(ctx context.Context) ([]*models.Drone, error)
+	// GetAvailableDrones 获取可用无人机，filter为nil时不做能力过滤
+	GetAvailableDrones(ctx context.Context, filter *models.CapabilityRequirements) ([]*models.Drone, error)
 }
 
 // CreateDroneParams 创建无人机参数
 type CreateDroneParams struct {
-	SerialNo     string   `json:"serial_no"`
-	Model        string   `json:"model"`
-	Capabilities []string `json:"capabilities"`
-	Firmware     string   `json:"firmware"`
-	Version      string   `json:"version"`
+	SerialNo     string                    `json:"serial_no"`
+	Model        string                    `json:"model"`
+	Capabilities *models.DroneCapabilities `json:"capabilities"`
+	Firmware     string                    `json:"firmware"`
+	Version      string                    `json:"version"`
 }
 
 // UpdateDroneParams 更新无人机参数
 type UpdateDroneParams struct {
-	Model        string             `json:"model"`
-	Status       models.DroneStatus `json:"status"`
-	Position     *models.Position   `json:"position"`
-	Battery      *int               `json:"battery"`
-	Capabilities []string           `json:"capabilities"`
-	Firmware     string             `json:"firmware"`
-	Version      string             `json:"version"`
+	Model        string                    `json:"model"`
+	Status       models.DroneStatus        `json:"status"`
+	Position     *models.Position          `json:"position"`
+	Battery      *int                      `json:"battery"`
+	Capabilities *models.DroneCapabilities `json:"capabilities"`
+	Firmware     string                    `json:"firmware"`
+	Version      string                    `json:"version"`
 }
 
 // ListDronesParams 无人机列表参数
 type ListDronesParams struct {
-	Offset int                `json:"offset"`
-	Limit  int                `json:"limit"`
-	Status models.DroneStatus `json:"status"`
-	Search string             `json:"search"`
+	Offset  int                `json:"offset"`
+	Limit   int                `json:"limit"`
+	Status  models.DroneStatus `json:"status"`
+	Search  string             `json:"search"`
+	FleetID uint               `json:"fleet_id"`
+	GroupID uint               `json:"group_id"`
+	Scope   *FleetScope        `json:"scope"` // 调用者可访问的机队范围，为nil时不限制
 }
 
 // TaskService 任务服务接口
@@ -122,6 +126,24 @@ type CreateTaskParams struct {
 	DroneID     uint                `json:"drone_id"`
 	Plan        models.TaskPlan     `json:"plan"`
 	ScheduledAt *time.Time          `json:"scheduled_at"`
+	ScheduleID  *uint               `json:"schedule_id"` // 由周期计划生成时设置
+
+	WorkflowRunID *uint `json:"workflow_run_id"` // 由流程步骤生成时设置
+
+	// 由任务模板实例化时设置
+	TemplateID      *uint `json:"template_id"`
+	TemplateVersion int   `json:"template_version"`
+
+	// 由分配引擎选择无人机时设置
+	AutoAssigned    bool        `json:"auto_assigned"`
+	AssignmentNote  string      `json:"assignment_note"`
+	AssignmentScope *FleetScope `json:"assignment_scope"` // 自动分配时可选择的机队范围，为nil时不限制
+
+	AutoRoute bool `json:"auto_route"` // 按生效的禁飞区和限飞区自动插入绕行航点
+
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+
+	Delivery *models.TaskDelivery `json:"delivery"` // 配送任务必填
 }
 
 // UpdateTaskParams 更新任务参数
@@ -135,6 +157,11 @@ type UpdateTaskParams struct {
 	Plan        *models.TaskPlan    `json:"plan"`
 	Progress    *int                `json:"progress"`
 	ScheduledAt *time.Time          `json:"scheduled_at"`
+	AutoRoute   bool                `json:"auto_route"` // 修改计划时自动插入绕行航点
+
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+
+	Delivery *models.TaskDelivery `json:"delivery"` // 取货前可修改
 }
 
 // ListTasksParams 任务列表参数
@@ -146,6 +173,12 @@ type ListTasksParams struct {
 	UserID  uint              `json:"user_id"`
 	DroneID uint              `json:"drone_id"`
 	Search  string            `json:"search"`
+	FleetID uint              `json:"fleet_id"` // 按任务无人机所属机队过滤
+	Scope   *FleetScope       `json:"scope"`    // 调用者可访问的机队范围，为nil时不限制
+
+	ScheduleID    uint `json:"schedule_id"`     // 按生成任务的周期计划过滤
+	WorkflowRunID uint `json:"workflow_run_id"` // 按生成任务的流程实例过滤
+	TemplateID    uint `json:"template_id"`     // 按实例化任务的模板版本过滤
 }
 
 // AlertService 告警服务接口
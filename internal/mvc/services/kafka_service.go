@@ -14,6 +14,9 @@ type KafkaService interface {
 	PublishUserEvent(ctx context.Context, eventType kafka.EventType, data interface{}) error
 	PublishAlertEvent(ctx context.Context, eventType kafka.EventType, data interface{}) error
 
+	// 订阅事件（需在Start之前注册）
+	RegisterHandler(topic string, handler kafka.MessageHandler)
+
 	// 管理方法
 	Start(ctx context.Context) error
 	Stop() error
@@ -63,6 +66,11 @@ func (s *KafkaServiceImpl) PublishAlertEvent(ctx context.Context, eventType kafk
 	return s.manager.PublishAlertEvent(ctx, event)
 }
 
+// RegisterHandler 注册主题消息处理器
+func (s *KafkaServiceImpl) RegisterHandler(topic string, handler kafka.MessageHandler) {
+	s.manager.RegisterHandler(topic, handler)
+}
+
 // Start 启动Kafka服务
 func (s *KafkaServiceImpl) Start(ctx context.Context) error {
 	if err := s.manager.Initialize(ctx); err != nil {
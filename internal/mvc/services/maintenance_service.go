@@ -0,0 +1,1001 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maintenanceLeaderKey 使用量统计和保养到期检查主节点锁
+	maintenanceLeaderKey = "maintenance:check:leader"
+
+	// maxUsageWindow 单次统计的最大时间窗口，历史较长时分多轮追平
+	maxUsageWindow = 24 * time.Hour
+	// airborneGap 两个离地时间桶间隔超过该值视为新的一次飞行
+	airborneGap = 2 * time.Minute
+)
+
+// MaintenanceConfig 维护管理配置
+type MaintenanceConfig struct {
+	CheckInterval    time.Duration `yaml:"check_interval" json:"check_interval"`       // 统计和检查间隔
+	UsageLag         time.Duration `yaml:"usage_lag" json:"usage_lag"`                 // 统计延迟，等待遥测降采样完成
+	AirborneAltitude float64       `yaml:"airborne_altitude" json:"airborne_altitude"` // 高于该高度（米）视为飞行中
+	LockTTL          time.Duration `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultMaintenanceConfig 默认维护管理配置
+func DefaultMaintenanceConfig() *MaintenanceConfig {
+	return &MaintenanceConfig{
+		CheckInterval:    5 * time.Minute,
+		UsageLag:         10 * time.Minute,
+		AirborneAltitude: 2,
+		LockTTL:          2 * time.Minute,
+	}
+}
+
+// CreateMaintenanceParams 创建维护工单参数
+type CreateMaintenanceParams struct {
+	DroneID     uint                   `json:"drone_id"`
+	IntervalID  *uint                  `json:"interval_id"`
+	Type        models.MaintenanceType `json:"type"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Reason      string                 `json:"reason"`
+	Technician  string                 `json:"technician"`
+	Ground      bool                   `json:"ground"` // 是否立即将无人机置为维护状态
+	OpenedBy    uint                   `json:"opened_by"`
+}
+
+// CompleteMaintenanceParams 维护完成签字参数
+type CompleteMaintenanceParams struct {
+	Parts       []models.MaintenancePart `json:"parts"`
+	Technician  string                   `json:"technician"`
+	Notes       string                   `json:"notes"`
+	SignedOffBy uint                     `json:"signed_off_by"`
+}
+
+// ListMaintenanceParams 维护工单列表参数
+type ListMaintenanceParams struct {
+	Offset  int                      `json:"offset"`
+	Limit   int                      `json:"limit"`
+	DroneID uint                     `json:"drone_id"`
+	Status  models.MaintenanceStatus `json:"status"`
+	Scope   *FleetScope              `json:"scope"` // 调用者可访问的机队范围，为nil时不限制
+}
+
+// ServiceIntervalParams 保养周期参数
+type ServiceIntervalParams struct {
+	Model        string  `json:"model"`
+	Name         string  `json:"name"`
+	Description  string  `json:"description"`
+	FlightHours  float64 `json:"flight_hours"`
+	FlightCycles int     `json:"flight_cycles"`
+	CalendarDays int     `json:"calendar_days"`
+}
+
+// MaintenanceDue 单个保养周期的到期情况
+type MaintenanceDue struct {
+	IntervalID         uint       `json:"interval_id"`
+	Name               string     `json:"name"`
+	HoursSinceService  float64    `json:"hours_since_service"`
+	CyclesSinceService int        `json:"cycles_since_service"`
+	DaysSinceService   int        `json:"days_since_service"`
+	HoursRemaining     *float64   `json:"hours_remaining,omitempty"`
+	CyclesRemaining    *int       `json:"cycles_remaining,omitempty"`
+	DaysRemaining      *int       `json:"days_remaining,omitempty"`
+	Overdue            bool       `json:"overdue"`
+	Reasons            []string   `json:"reasons,omitempty"`
+	EstimatedDueAt     *time.Time `json:"estimated_due_at,omitempty"` // 按平均使用强度估算
+	UsedFraction       float64    `json:"used_fraction"`              // 已消耗周期比例，超过1表示逾期
+}
+
+// DroneMaintenanceStatus 无人机维护状态
+type DroneMaintenanceStatus struct {
+	DroneID     uint                        `json:"drone_id"`
+	Model       string                      `json:"model"`
+	Status      models.DroneStatus          `json:"status"`
+	Usage       *models.DroneUsage          `json:"usage"`
+	Due         []*MaintenanceDue           `json:"due"`
+	Overdue     bool                        `json:"overdue"`
+	OpenRecords []*models.MaintenanceRecord `json:"open_records"`
+}
+
+// MaintenanceService 维护管理服务接口
+type MaintenanceService interface {
+	// 维护工单
+	CreateRecord(ctx context.Context, params *CreateMaintenanceParams) (*models.MaintenanceRecord, error)
+	GetRecord(ctx context.Context, id uint) (*models.MaintenanceRecord, error)
+	ListRecords(ctx context.Context, params *ListMaintenanceParams) ([]*models.MaintenanceRecord, int64, error)
+	StartRecord(ctx context.Context, id uint, technician string) error
+	// CompleteRecord 签字完成工单，无其他未完成工单时无人机恢复在线
+	CompleteRecord(ctx context.Context, id uint, params *CompleteMaintenanceParams) (*models.MaintenanceRecord, error)
+	CancelRecord(ctx context.Context, id uint, reason string) error
+
+	// 机型保养周期
+	CreateInterval(ctx context.Context, params *ServiceIntervalParams) (*models.ServiceInterval, error)
+	UpdateInterval(ctx context.Context, id uint, params *ServiceIntervalParams) (*models.ServiceInterval, error)
+	DeleteInterval(ctx context.Context, id uint) error
+	ListIntervals(ctx context.Context, model string) ([]*models.ServiceInterval, error)
+
+	// GetMaintenanceStatus 获取无人机累计使用量和各保养周期的到期情况
+	GetMaintenanceStatus(ctx context.Context, droneID uint) (*DroneMaintenanceStatus, error)
+
+	// 服务管理（使用量统计和逾期自动停飞）
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// MaintenanceServiceImpl 维护管理服务实现
+type MaintenanceServiceImpl struct {
+	config           *MaintenanceConfig
+	db               *gorm.DB
+	election         *database.LeaderElection
+	heartbeatMonitor HeartbeatMonitor
+	alertService     AlertService
+	kafkaService     KafkaService
+	logger           *logger.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewMaintenanceService 创建维护管理服务
+// heartbeatMonitor 用于维护结束后重新监控无人机心跳，为nil时不处理
+func NewMaintenanceService(
+	config *MaintenanceConfig,
+	db *gorm.DB,
+	lockService *database.LockService,
+	heartbeatMonitor HeartbeatMonitor,
+	alertService AlertService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) MaintenanceService {
+	if config == nil {
+		config = DefaultMaintenanceConfig()
+	}
+
+	return &MaintenanceServiceImpl{
+		config:           config,
+		db:               db,
+		election:         database.NewLeaderElection(lockService, maintenanceLeaderKey, config.LockTTL),
+		heartbeatMonitor: heartbeatMonitor,
+		alertService:     alertService,
+		kafkaService:     kafkaService,
+		logger:           logger,
+	}
+}
+
+// CreateRecord 创建维护工单
+func (s *MaintenanceServiceImpl) CreateRecord(ctx context.Context, params *CreateMaintenanceParams) (*models.MaintenanceRecord, error) {
+	if params == nil || params.DroneID == 0 || strings.TrimSpace(params.Title) == "" {
+		return nil, fmt.Errorf("%w: drone_id and title are required", ErrInvalidData)
+	}
+	if params.Type == "" {
+		params.Type = models.MaintenanceTypeRepair
+	}
+
+	drone, err := s.loadDrone(ctx, params.DroneID)
+	if err != nil {
+		return nil, err
+	}
+	if params.IntervalID != nil {
+		interval, err := s.getInterval(ctx, *params.IntervalID)
+		if err != nil {
+			return nil, err
+		}
+		if interval.Model != drone.Model {
+			return nil, fmt.Errorf("%w: service interval %d does not apply to model %s", ErrInvalidData, interval.ID, drone.Model)
+		}
+	}
+	if params.Ground && drone.Status == models.DroneStatusFlying {
+		return nil, ErrDroneInUse
+	}
+
+	record := &models.MaintenanceRecord{
+		DroneID:     drone.ID,
+		IntervalID:  params.IntervalID,
+		Type:        params.Type,
+		Status:      models.MaintenanceStatusOpen,
+		Title:       strings.TrimSpace(params.Title),
+		Description: params.Description,
+		Reason:      params.Reason,
+		Technician:  params.Technician,
+		OpenedBy:    params.OpenedBy,
+	}
+
+	grounded, err := s.openRecord(ctx, drone, record, params.Ground)
+	if err != nil {
+		return nil, err
+	}
+	if grounded {
+		s.publishStatusChange(ctx, drone, models.DroneStatusMaintenance, "maintenance: "+record.Title)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"record_id": record.ID,
+		"drone_id":  drone.ID,
+		"type":      record.Type,
+		"grounded":  grounded,
+	}).Info("Maintenance record created")
+	return record, nil
+}
+
+// GetRecord 获取维护工单
+func (s *MaintenanceServiceImpl) GetRecord(ctx context.Context, id uint) (*models.MaintenanceRecord, error) {
+	var record models.MaintenanceRecord
+	if err := s.db.WithContext(ctx).First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMaintenanceNotFound
+		}
+		return nil, fmt.Errorf("failed to get maintenance record: %w", err)
+	}
+	return &record, nil
+}
+
+// ListRecords 获取维护工单列表
+func (s *MaintenanceServiceImpl) ListRecords(ctx context.Context, params *ListMaintenanceParams) ([]*models.MaintenanceRecord, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.MaintenanceRecord{})
+	if params.DroneID != 0 {
+		query = query.Where("drone_id = ?", params.DroneID)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+	if params.Scope != nil {
+		droneIDs, ok := scopedDroneIDs(s.db.WithContext(ctx), params.Scope)
+		if !ok {
+			return []*models.MaintenanceRecord{}, 0, nil
+		}
+		query = query.Where("drone_id IN (?)", droneIDs)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count maintenance records: %w", err)
+	}
+
+	var records []*models.MaintenanceRecord
+	if err := query.Order("id DESC").Offset(params.Offset).Limit(params.Limit).Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list maintenance records: %w", err)
+	}
+	return records, total, nil
+}
+
+// StartRecord 开始维护
+func (s *MaintenanceServiceImpl) StartRecord(ctx context.Context, id uint, technician string) error {
+	updates := map[string]interface{}{
+		"status":     models.MaintenanceStatusInProgress,
+		"started_at": time.Now(),
+	}
+	if technician != "" {
+		updates["technician"] = technician
+	}
+
+	_, err := s.transitionRecord(ctx, id, []models.MaintenanceStatus{models.MaintenanceStatusOpen}, updates)
+	return err
+}
+
+// CompleteRecord 签字完成维护，记录当前累计使用量作为下次保养起点
+func (s *MaintenanceServiceImpl) CompleteRecord(ctx context.Context, id uint, params *CompleteMaintenanceParams) (*models.MaintenanceRecord, error) {
+	if params == nil || params.SignedOffBy == 0 {
+		return nil, fmt.Errorf("%w: sign-off user is required", ErrInvalidData)
+	}
+
+	record, err := s.GetRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !record.IsOpen() {
+		return nil, ErrMaintenanceInvalidState
+	}
+
+	usage, err := s.loadUsage(ctx, record.DroneID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := record.SetParts(params.Parts); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":        models.MaintenanceStatusCompleted,
+		"parts":         record.Parts,
+		"notes":         params.Notes,
+		"flight_hours":  usage.FlightHours,
+		"flight_cycles": usage.FlightCycles,
+		"signed_off_by": params.SignedOffBy,
+		"signed_off_at": now,
+	}
+	if params.Technician != "" {
+		updates["technician"] = params.Technician
+	}
+	if record.StartedAt == nil {
+		updates["started_at"] = now
+	}
+
+	record, err = s.transitionRecord(ctx, id, []models.MaintenanceStatus{
+		models.MaintenanceStatusOpen, models.MaintenanceStatusInProgress,
+	}, updates)
+	if err != nil {
+		return nil, err
+	}
+
+	s.releaseDrone(ctx, record.DroneID, "maintenance completed: "+record.Title)
+
+	s.logger.WithFields(map[string]interface{}{
+		"record_id":     record.ID,
+		"drone_id":      record.DroneID,
+		"signed_off_by": params.SignedOffBy,
+	}).Info("Maintenance record completed")
+	return record, nil
+}
+
+// CancelRecord 取消维护工单
+func (s *MaintenanceServiceImpl) CancelRecord(ctx context.Context, id uint, reason string) error {
+	record, err := s.transitionRecord(ctx, id, []models.MaintenanceStatus{
+		models.MaintenanceStatusOpen, models.MaintenanceStatusInProgress,
+	}, map[string]interface{}{
+		"status": models.MaintenanceStatusCancelled,
+		"notes":  reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.releaseDrone(ctx, record.DroneID, "maintenance cancelled: "+record.Title)
+	return nil
+}
+
+// CreateInterval 创建保养周期
+func (s *MaintenanceServiceImpl) CreateInterval(ctx context.Context, params *ServiceIntervalParams) (*models.ServiceInterval, error) {
+	interval := &models.ServiceInterval{}
+	if err := applyIntervalParams(interval, params); err != nil {
+		return nil, err
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.ServiceInterval{}).
+		Where("model = ? AND name = ?", interval.Model, interval.Name).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check service interval: %w", err)
+	}
+	if count > 0 {
+		return nil, ErrServiceIntervalExists
+	}
+
+	if err := s.db.WithContext(ctx).Create(interval).Error; err != nil {
+		return nil, fmt.Errorf("failed to create service interval: %w", err)
+	}
+	return interval, nil
+}
+
+// UpdateInterval 更新保养周期
+func (s *MaintenanceServiceImpl) UpdateInterval(ctx context.Context, id uint, params *ServiceIntervalParams) (*models.ServiceInterval, error) {
+	interval, err := s.getInterval(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyIntervalParams(interval, params); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Save(interval).Error; err != nil {
+		return nil, fmt.Errorf("failed to update service interval: %w", err)
+	}
+	return interval, nil
+}
+
+// DeleteInterval 删除保养周期
+func (s *MaintenanceServiceImpl) DeleteInterval(ctx context.Context, id uint) error {
+	res := s.db.WithContext(ctx).Delete(&models.ServiceInterval{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete service interval: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrServiceIntervalNotFound
+	}
+	return nil
+}
+
+// ListIntervals 获取保养周期列表
+func (s *MaintenanceServiceImpl) ListIntervals(ctx context.Context, model string) ([]*models.ServiceInterval, error) {
+	query := s.db.WithContext(ctx).Order("model, name")
+	if model != "" {
+		query = query.Where("model = ?", model)
+	}
+
+	var intervals []*models.ServiceInterval
+	if err := query.Find(&intervals).Error; err != nil {
+		return nil, fmt.Errorf("failed to list service intervals: %w", err)
+	}
+	return intervals, nil
+}
+
+// GetMaintenanceStatus 获取无人机维护状态
+func (s *MaintenanceServiceImpl) GetMaintenanceStatus(ctx context.Context, droneID uint) (*DroneMaintenanceStatus, error) {
+	drone, err := s.loadDrone(ctx, droneID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.loadUsage(ctx, droneID)
+	if err != nil {
+		return nil, err
+	}
+
+	dues, err := s.evaluateDues(ctx, drone, usage)
+	if err != nil {
+		return nil, err
+	}
+
+	var open []*models.MaintenanceRecord
+	if err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND status IN ?", droneID, openMaintenanceStatuses()).
+		Order("id").Find(&open).Error; err != nil {
+		return nil, fmt.Errorf("failed to list open maintenance records: %w", err)
+	}
+
+	status := &DroneMaintenanceStatus{
+		DroneID:     drone.ID,
+		Model:       drone.Model,
+		Status:      drone.Status,
+		Usage:       usage,
+		Due:         dues,
+		OpenRecords: open,
+	}
+	for _, due := range dues {
+		if due.Overdue {
+			status.Overdue = true
+		}
+	}
+	return status, nil
+}
+
+// Start 启动使用量统计和保养到期检查
+func (s *MaintenanceServiceImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.checkLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"check_interval": s.config.CheckInterval.String(),
+		"instance":       s.election.Identity(),
+	}).Info("Maintenance service started")
+	return nil
+}
+
+// Stop 停止后台任务并释放主节点锁
+func (s *MaintenanceServiceImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release maintenance leader lock")
+	}
+
+	s.logger.Info("Maintenance service stopped")
+	return nil
+}
+
+// checkLoop 周期统计使用量并检查逾期
+func (s *MaintenanceServiceImpl) checkLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Maintenance leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+			s.checkFleet(s.ctx)
+		}
+	}
+}
+
+// checkFleet 对所有无人机统计使用量并处理逾期
+func (s *MaintenanceServiceImpl) checkFleet(ctx context.Context) {
+	var drones []*models.Drone
+	if err := s.db.WithContext(ctx).Find(&drones).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to load drones for maintenance check")
+		return
+	}
+
+	until := time.Now().Add(-s.config.UsageLag)
+	for _, drone := range drones {
+		if ctx.Err() != nil {
+			return
+		}
+
+		usage, err := s.accountUsage(ctx, drone, until)
+		if err != nil {
+			s.logger.WithError(err).WithField("drone_id", drone.ID).Error("Failed to account drone usage")
+			continue
+		}
+
+		s.groundIfOverdue(ctx, drone, usage)
+	}
+}
+
+// accountUsage 增量累加飞行时长和架次
+// 已完成任务按起止时间计入；任务之外的飞行由1分钟遥测聚合中离地的时间桶推算
+func (s *MaintenanceServiceImpl) accountUsage(ctx context.Context, drone *models.Drone, until time.Time) (*models.DroneUsage, error) {
+	usage, err := s.loadUsage(ctx, drone.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	from := usage.AccountedUntil
+	if !until.After(from) {
+		return usage, nil
+	}
+	to := until
+	if to.Sub(from) > maxUsageWindow {
+		to = from.Add(maxUsageWindow)
+	}
+
+	var finished []*models.Task
+	if err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND started_at IS NOT NULL AND completed_at > ? AND completed_at <= ?", drone.ID, from, to).
+		Find(&finished).Error; err != nil {
+		return nil, fmt.Errorf("failed to load finished tasks: %w", err)
+	}
+	for _, task := range finished {
+		if task.CompletedAt.After(*task.StartedAt) {
+			usage.FlightHours += task.CompletedAt.Sub(*task.StartedAt).Hours()
+		}
+		usage.FlightCycles++
+		usage.LastFlightAt = latestTime(usage.LastFlightAt, task.CompletedAt)
+	}
+
+	// 与窗口重叠的任务（含执行中的任务），其时段内的遥测已由任务计入或将在任务结束时计入
+	var overlapping []*models.Task
+	if err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND started_at IS NOT NULL AND started_at < ? AND (completed_at IS NULL OR completed_at > ?)", drone.ID, to, from).
+		Find(&overlapping).Error; err != nil {
+		return nil, fmt.Errorf("failed to load overlapping tasks: %w", err)
+	}
+
+	var points []*models.DroneTelemetry
+	if err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND resolution = ? AND recorded_at > ? AND recorded_at <= ? AND altitude >= ?",
+			drone.ID, models.TelemetryResolutionMinute, from, to, s.config.AirborneAltitude).
+		Order("recorded_at").
+		Find(&points).Error; err != nil {
+		return nil, fmt.Errorf("failed to load telemetry: %w", err)
+	}
+
+	bucket := models.TelemetryResolutionMinute.BucketSize()
+	for _, point := range points {
+		at := point.RecordedAt
+		newFlight := usage.LastAirborneAt == nil || at.Sub(*usage.LastAirborneAt) > airborneGap
+		usage.LastAirborneAt = &at
+
+		if withinTasks(overlapping, at) {
+			continue
+		}
+		usage.FlightHours += bucket.Hours()
+		if newFlight {
+			usage.FlightCycles++
+		}
+		usage.LastFlightAt = latestTime(usage.LastFlightAt, &at)
+	}
+
+	usage.AccountedUntil = to
+	if err := s.db.WithContext(ctx).Save(usage).Error; err != nil {
+		return nil, fmt.Errorf("failed to save drone usage: %w", err)
+	}
+	return usage, nil
+}
+
+// groundIfOverdue 保养逾期的无人机自动进入维护状态并创建工单
+// 飞行中的无人机等待降落后的下一轮检查
+func (s *MaintenanceServiceImpl) groundIfOverdue(ctx context.Context, drone *models.Drone, usage *models.DroneUsage) {
+	switch drone.Status {
+	case models.DroneStatusOnline, models.DroneStatusOffline, models.DroneStatusCharging, models.DroneStatusMaintenance:
+	default:
+		return
+	}
+
+	dues, err := s.evaluateDues(ctx, drone, usage)
+	if err != nil {
+		s.logger.WithError(err).WithField("drone_id", drone.ID).Error("Failed to evaluate maintenance dues")
+		return
+	}
+
+	for _, due := range dues {
+		if !due.Overdue {
+			continue
+		}
+
+		var open int64
+		if err := s.db.WithContext(ctx).Model(&models.MaintenanceRecord{}).
+			Where("drone_id = ? AND interval_id = ? AND status IN ?", drone.ID, due.IntervalID, openMaintenanceStatuses()).
+			Count(&open).Error; err != nil || open > 0 {
+			continue
+		}
+
+		intervalID := due.IntervalID
+		reason := "overdue: " + strings.Join(due.Reasons, "; ")
+		record := &models.MaintenanceRecord{
+			DroneID:      drone.ID,
+			IntervalID:   &intervalID,
+			Type:         models.MaintenanceTypeScheduled,
+			Status:       models.MaintenanceStatusOpen,
+			Title:        due.Name,
+			Reason:       reason,
+			FlightHours:  usage.FlightHours,
+			FlightCycles: usage.FlightCycles,
+		}
+
+		grounded, err := s.openRecord(ctx, drone, record, true)
+		if err != nil {
+			s.logger.WithError(err).WithField("drone_id", drone.ID).Error("Failed to open scheduled maintenance")
+			continue
+		}
+
+		s.logger.WithFields(map[string]interface{}{
+			"drone_id":  drone.ID,
+			"record_id": record.ID,
+			"interval":  due.Name,
+			"reason":    reason,
+			"grounded":  grounded,
+		}).Warn("Drone maintenance overdue")
+
+		if grounded {
+			s.publishStatusChange(ctx, drone, models.DroneStatusMaintenance, "maintenance "+reason)
+			drone.Status = models.DroneStatusMaintenance
+		}
+
+		droneID := drone.ID
+		raiseAlert(ctx, s.alertService, s.kafkaService, s.logger, &CreateAlertParams{
+			Title:   "无人机保养逾期",
+			Message: fmt.Sprintf("无人机 %s 的保养项目「%s」已逾期（%s），已转入维护状态", drone.SerialNo, due.Name, strings.Join(due.Reasons, "；")),
+			Type:    models.AlertTypeDrone,
+			Level:   models.AlertLevelWarning,
+			Source:  "maintenance-service",
+			Code:    "DRONE_MAINTENANCE_OVERDUE",
+			DroneID: &droneID,
+		})
+	}
+}
+
+// evaluateDues 计算该机型各保养周期的到期情况
+func (s *MaintenanceServiceImpl) evaluateDues(ctx context.Context, drone *models.Drone, usage *models.DroneUsage) ([]*MaintenanceDue, error) {
+	intervals, err := s.ListIntervals(ctx, drone.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	trackedDays := now.Sub(usage.TrackingSince).Hours() / 24
+	if trackedDays < 1 {
+		trackedDays = 1
+	}
+	hoursPerDay := usage.FlightHours / trackedDays
+	cyclesPerDay := float64(usage.FlightCycles) / trackedDays
+
+	dues := make([]*MaintenanceDue, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.IsEmpty() {
+			continue
+		}
+
+		// 上次完成该项保养时的使用量为起点，从未保养过则从开始统计时算起
+		baseHours, baseCycles, baseTime := 0.0, 0, usage.TrackingSince
+		var last models.MaintenanceRecord
+		err := s.db.WithContext(ctx).
+			Where("drone_id = ? AND interval_id = ? AND status = ?", drone.ID, interval.ID, models.MaintenanceStatusCompleted).
+			Order("signed_off_at DESC").
+			First(&last).Error
+		switch {
+		case err == nil:
+			baseHours, baseCycles = last.FlightHours, last.FlightCycles
+			if last.SignedOffAt != nil {
+				baseTime = *last.SignedOffAt
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, fmt.Errorf("failed to load last maintenance: %w", err)
+		}
+
+		due := &MaintenanceDue{
+			IntervalID:         interval.ID,
+			Name:               interval.Name,
+			HoursSinceService:  math.Round((usage.FlightHours-baseHours)*100) / 100,
+			CyclesSinceService: usage.FlightCycles - baseCycles,
+			DaysSinceService:   int(now.Sub(baseTime).Hours() / 24),
+		}
+
+		// 按平均使用强度估算到期时间，取最早到达的阈值
+		var daysToDue []float64
+		if interval.FlightHours > 0 {
+			remaining := interval.FlightHours - due.HoursSinceService
+			due.HoursRemaining = &remaining
+			due.UsedFraction = math.Max(due.UsedFraction, due.HoursSinceService/interval.FlightHours)
+			if remaining <= 0 {
+				due.Reasons = append(due.Reasons, fmt.Sprintf("%.1f flight hours since service (limit %.1f)", due.HoursSinceService, interval.FlightHours))
+			} else if hoursPerDay > 0 {
+				daysToDue = append(daysToDue, remaining/hoursPerDay)
+			}
+		}
+		if interval.FlightCycles > 0 {
+			remaining := interval.FlightCycles - due.CyclesSinceService
+			due.CyclesRemaining = &remaining
+			due.UsedFraction = math.Max(due.UsedFraction, float64(due.CyclesSinceService)/float64(interval.FlightCycles))
+			if remaining <= 0 {
+				due.Reasons = append(due.Reasons, fmt.Sprintf("%d flights since service (limit %d)", due.CyclesSinceService, interval.FlightCycles))
+			} else if cyclesPerDay > 0 {
+				daysToDue = append(daysToDue, float64(remaining)/cyclesPerDay)
+			}
+		}
+		if interval.CalendarDays > 0 {
+			remaining := interval.CalendarDays - due.DaysSinceService
+			due.DaysRemaining = &remaining
+			due.UsedFraction = math.Max(due.UsedFraction, float64(due.DaysSinceService)/float64(interval.CalendarDays))
+			if remaining <= 0 {
+				due.Reasons = append(due.Reasons, fmt.Sprintf("%d days since service (limit %d)", due.DaysSinceService, interval.CalendarDays))
+			} else {
+				daysToDue = append(daysToDue, float64(remaining))
+			}
+		}
+
+		due.Overdue = len(due.Reasons) > 0
+		if due.Overdue {
+			due.EstimatedDueAt = &now
+		} else if len(daysToDue) > 0 {
+			earliest := daysToDue[0]
+			for _, days := range daysToDue[1:] {
+				earliest = math.Min(earliest, days)
+			}
+			at := now.Add(time.Duration(earliest * 24 * float64(time.Hour)))
+			due.EstimatedDueAt = &at
+		}
+
+		dues = append(dues, due)
+	}
+
+	return dues, nil
+}
+
+// openRecord 创建工单，ground 为true时同时将未飞行的无人机置为维护状态，返回是否发生了状态变更
+func (s *MaintenanceServiceImpl) openRecord(ctx context.Context, drone *models.Drone, record *models.MaintenanceRecord, ground bool) (bool, error) {
+	grounded := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if !ground || drone.Status == models.DroneStatusMaintenance {
+			return nil
+		}
+
+		res := tx.Model(&models.Drone{}).
+			Where("id = ? AND status <> ?", drone.ID, models.DroneStatusFlying).
+			Update("status", models.DroneStatusMaintenance)
+		if res.Error != nil {
+			return res.Error
+		}
+		grounded = res.RowsAffected > 0
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to open maintenance record: %w", err)
+	}
+	return grounded, nil
+}
+
+// releaseDrone 无其他未完成工单时将维护中的无人机恢复在线
+// 维护期间失联的无人机已移出心跳集合，需要重新纳入监控，之后仍无心跳时由心跳监控判定离线
+func (s *MaintenanceServiceImpl) releaseDrone(ctx context.Context, droneID uint, reason string) {
+	var open int64
+	if err := s.db.WithContext(ctx).Model(&models.MaintenanceRecord{}).
+		Where("drone_id = ? AND status IN ?", droneID, openMaintenanceStatuses()).
+		Count(&open).Error; err != nil || open > 0 {
+		return
+	}
+
+	drone, err := s.loadDrone(ctx, droneID)
+	if err != nil || drone.Status != models.DroneStatusMaintenance {
+		return
+	}
+
+	res := s.db.WithContext(ctx).Model(&models.Drone{}).
+		Where("id = ? AND status = ?", droneID, models.DroneStatusMaintenance).
+		Update("status", models.DroneStatusOnline)
+	if res.Error != nil {
+		s.logger.WithError(res.Error).WithField("drone_id", droneID).Error("Failed to release drone from maintenance")
+		return
+	}
+	if res.RowsAffected == 0 {
+		return
+	}
+
+	if s.heartbeatMonitor != nil {
+		if err := s.heartbeatMonitor.Watch(ctx, droneID); err != nil {
+			s.logger.WithError(err).WithField("drone_id", droneID).Warn("Failed to resume heartbeat monitoring")
+		}
+	}
+	s.publishStatusChange(ctx, drone, models.DroneStatusOnline, reason)
+}
+
+// transitionRecord 在允许的状态下更新工单
+func (s *MaintenanceServiceImpl) transitionRecord(ctx context.Context, id uint, from []models.MaintenanceStatus, updates map[string]interface{}) (*models.MaintenanceRecord, error) {
+	res := s.db.WithContext(ctx).Model(&models.MaintenanceRecord{}).
+		Where("id = ? AND status IN ?", id, from).
+		Updates(updates)
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to update maintenance record: %w", res.Error)
+	}
+
+	record, err := s.GetRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrMaintenanceInvalidState
+	}
+	return record, nil
+}
+
+// publishStatusChange 发布无人机状态变更事件
+func (s *MaintenanceServiceImpl) publishStatusChange(ctx context.Context, drone *models.Drone, newStatus models.DroneStatus, reason string) {
+	if s.kafkaService == nil {
+		return
+	}
+
+	eventData := kafka.DroneStatusChangedEventData{
+		DroneID:   drone.ID,
+		DroneName: drone.SerialNo,
+		OldStatus: string(drone.Status),
+		NewStatus: string(newStatus),
+		Reason:    reason,
+		Battery:   drone.Battery,
+		Timestamp: time.Now(),
+	}
+
+	if err := s.kafkaService.PublishDroneEvent(ctx, kafka.DroneStatusChangedEvent, eventData); err != nil {
+		s.logger.WithFields(map[string]interface{}{
+			"drone_id": drone.ID,
+			"error":    err.Error(),
+		}).Error("Failed to publish drone status event")
+	}
+}
+
+// loadDrone 读取无人机
+func (s *MaintenanceServiceImpl) loadDrone(ctx context.Context, droneID uint) (*models.Drone, error) {
+	var drone models.Drone
+	if err := s.db.WithContext(ctx).First(&drone, droneID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDroneNotFound
+		}
+		return nil, fmt.Errorf("failed to get drone: %w", err)
+	}
+	return &drone, nil
+}
+
+// loadUsage 读取累计使用量，不存在时从无人机登记时间开始统计
+func (s *MaintenanceServiceImpl) loadUsage(ctx context.Context, droneID uint) (*models.DroneUsage, error) {
+	var usage models.DroneUsage
+	err := s.db.WithContext(ctx).First(&usage, "drone_id = ?", droneID).Error
+	if err == nil {
+		return &usage, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get drone usage: %w", err)
+	}
+
+	drone, err := s.loadDrone(ctx, droneID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DroneUsage{
+		DroneID:        droneID,
+		TrackingSince:  drone.CreatedAt,
+		AccountedUntil: drone.CreatedAt,
+	}, nil
+}
+
+// getInterval 读取保养周期
+func (s *MaintenanceServiceImpl) getInterval(ctx context.Context, id uint) (*models.ServiceInterval, error) {
+	var interval models.ServiceInterval
+	if err := s.db.WithContext(ctx).First(&interval, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrServiceIntervalNotFound
+		}
+		return nil, fmt.Errorf("failed to get service interval: %w", err)
+	}
+	return &interval, nil
+}
+
+// applyIntervalParams 校验并写入保养周期参数
+func applyIntervalParams(interval *models.ServiceInterval, params *ServiceIntervalParams) error {
+	if params == nil || strings.TrimSpace(params.Model) == "" || strings.TrimSpace(params.Name) == "" {
+		return fmt.Errorf("%w: model and name are required", ErrInvalidData)
+	}
+	if params.FlightHours < 0 || params.FlightCycles < 0 || params.CalendarDays < 0 {
+		return fmt.Errorf("%w: service interval limits must not be negative", ErrInvalidData)
+	}
+
+	interval.Model = strings.TrimSpace(params.Model)
+	interval.Name = strings.TrimSpace(params.Name)
+	interval.Description = params.Description
+	interval.FlightHours = params.FlightHours
+	interval.FlightCycles = params.FlightCycles
+	interval.CalendarDays = params.CalendarDays
+
+	if interval.IsEmpty() {
+		return fmt.Errorf("%w: at least one of flight_hours, flight_cycles or calendar_days is required", ErrInvalidData)
+	}
+	return nil
+}
+
+// openMaintenanceStatuses 未结束的工单状态
+func openMaintenanceStatuses() []models.MaintenanceStatus {
+	return []models.MaintenanceStatus{models.MaintenanceStatusOpen, models.MaintenanceStatusInProgress}
+}
+
+// withinTasks 时间点是否落在任一任务的执行时段内
+func withinTasks(tasks []*models.Task, at time.Time) bool {
+	for _, task := range tasks {
+		if task.StartedAt == nil || at.Before(*task.StartedAt) {
+			continue
+		}
+		if task.CompletedAt == nil || !at.After(*task.CompletedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// latestTime 返回较晚的时间
+func latestTime(current, candidate *time.Time) *time.Time {
+	if current == nil || candidate.After(*current) {
+		t := *candidate
+		return &t
+	}
+	return current
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"drone-control-system/internal/mvc/models"
+)
+
+// MissionValidationError 任务计划未通过校验，包含逐航点的问题列表
+type MissionValidationError struct {
+	DroneID uint                  `json:"drone_id,omitempty"`
+	Issues  []models.MissionIssue `json:"issues"`
+}
+
+func (e *MissionValidationError) Error() string {
+	messages := make([]string, 0, len(e.Issues))
+	for _, issue := range e.Issues {
+		messages = append(messages, issue.String())
+	}
+	return fmt.Sprintf("invalid mission plan: %s", strings.Join(messages, "; "))
+}
+
+// Unwrap 支持 errors.Is(err, ErrInvalidMission)
+func (e *MissionValidationError) Unwrap() error {
+	return ErrInvalidMission
+}
+
+// CheckMissionPlan 校验任务计划，drone 不为空时同时校验机型限制
+func CheckMissionPlan(plan *models.TaskPlan, drone *models.Drone) error {
+	var caps *models.DroneCapabilities
+	var droneID uint
+	if drone != nil {
+		parsed, err := drone.ParseCapabilities()
+		if err != nil {
+			return fmt.Errorf("%w: drone %d: %v", ErrInvalidCapabilities, drone.ID, err)
+		}
+		caps = parsed
+		droneID = drone.ID
+	}
+
+	if issues := plan.Validate(caps); len(issues) > 0 {
+		return &MissionValidationError{DroneID: droneID, Issues: issues}
+	}
+	return nil
+}
+
+// MissionTaskService 在创建和更新任务时校验计划航线的任务服务装饰器
+type MissionTaskService struct {
+	TaskService
+	droneService DroneService
+}
+
+// NewMissionTaskService 创建航线校验任务服务
+func NewMissionTaskService(next TaskService, droneService DroneService) TaskService {
+	return &MissionTaskService{
+		TaskService:  next,
+		droneService: droneService,
+	}
+}
+
+// CreateTask 创建任务前校验计划，已指派无人机时同时校验机型限制
+func (s *MissionTaskService) CreateTask(ctx context.Context, params *CreateTaskParams) (*models.Task, error) {
+	if err := s.checkPlan(ctx, &params.Plan, params.DroneID); err != nil {
+		return nil, err
+	}
+	return s.TaskService.CreateTask(ctx, params)
+}
+
+// UpdateTask 修改计划或更换无人机时重新校验
+func (s *MissionTaskService) UpdateTask(ctx context.Context, id uint, params *UpdateTaskParams) (*models.Task, error) {
+	if params.Plan == nil && params.DroneID == nil {
+		return s.TaskService.UpdateTask(ctx, id, params)
+	}
+
+	task, err := s.TaskService.GetTaskByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &task.Plan
+	if params.Plan != nil {
+		plan = params.Plan
+	}
+	droneID := task.DroneID
+	if params.DroneID != nil {
+		droneID = *params.DroneID
+	}
+
+	if err := s.checkPlan(ctx, plan, droneID); err != nil {
+		return nil, err
+	}
+	return s.TaskService.UpdateTask(ctx, id, params)
+}
+
+// checkPlan 加载无人机并校验计划
+func (s *MissionTaskService) checkPlan(ctx context.Context, plan *models.TaskPlan, droneID uint) error {
+	if droneID == 0 {
+		return CheckMissionPlan(plan, nil)
+	}
+
+	drone, err := s.droneService.GetDroneByID(ctx, droneID)
+	if err != nil {
+		return err
+	}
+	return CheckMissionPlan(plan, drone)
+}
@@ -0,0 +1,501 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// 起飞前检查项
+const (
+	PreflightCheckDroneStatus = "drone_status"
+	PreflightCheckMaintenance = "maintenance"
+	PreflightCheckBattery     = "battery"
+	PreflightCheckFirmware    = "firmware"
+	PreflightCheckGeofence    = "geofence"
+	PreflightCheckWeather     = "weather"
+	PreflightCheckConflict    = "conflict"
+)
+
+// PreflightConfig 起飞前检查配置
+type PreflightConfig struct {
+	BatteryWarnMargin int           `yaml:"battery_warn_margin" json:"battery_warn_margin"` // 电量余量低于该值（%）时警告
+	MaxWindSpeed      float64       `yaml:"max_wind_speed" json:"max_wind_speed"`           // 未登记抗风能力的无人机使用的风速上限（m/s）
+	WindWarnRatio     float64       `yaml:"wind_warn_ratio" json:"wind_warn_ratio"`         // 风速超过上限的该比例时警告
+	MinVisibility     float64       `yaml:"min_visibility" json:"min_visibility"`           // 能见度下限（km）
+	WarnVisibility    float64       `yaml:"warn_visibility" json:"warn_visibility"`         // 能见度低于该值（km）时警告
+	RequireWeather    bool          `yaml:"require_weather" json:"require_weather"`         // 无法获取天气时是否警告
+	MaintenanceWarn   float64       `yaml:"maintenance_warn" json:"maintenance_warn"`       // 保养周期消耗比例超过该值时警告
+	OverrideTTL       time.Duration `yaml:"override_ttl" json:"override_ttl"`               // 放行记录有效期
+}
+
+// DefaultPreflightConfig 默认起飞前检查配置
+func DefaultPreflightConfig() *PreflightConfig {
+	return &PreflightConfig{
+		BatteryWarnMargin: 10,
+		MaxWindSpeed:      12,
+		WindWarnRatio:     0.8,
+		MinVisibility:     1,
+		WarnVisibility:    3,
+		MaintenanceWarn:   0.9,
+		OverrideTTL:       30 * time.Minute,
+	}
+}
+
+// WeatherConditions 任务区域的气象条件
+type WeatherConditions struct {
+	WindSpeed     float64   `json:"wind_speed"`     // 风速（m/s）
+	WindDirection float64   `json:"wind_direction"` // 风向（度，风的来向）
+	Visibility    float64   `json:"visibility"`     // 能见度（km）
+	ObservedAt    time.Time `json:"observed_at"`
+}
+
+// WeatherProvider 气象数据来源
+type WeatherProvider interface {
+	CurrentWeather(ctx context.Context, latitude, longitude float64) (*WeatherConditions, error)
+}
+
+// PreflightFailedError 起飞前检查未通过，Overridable 表示只有警告、可由管理员放行
+type PreflightFailedError struct {
+	Report      *models.PreflightReport `json:"report"`
+	Overridable bool                    `json:"overridable"`
+}
+
+func (e *PreflightFailedError) Error() string {
+	status := models.PreflightFail
+	if e.Overridable {
+		status = models.PreflightWarn
+	}
+	return fmt.Sprintf("pre-flight checks did not pass: %s", strings.Join(e.Report.Codes(status), ", "))
+}
+
+// Unwrap 支持 errors.Is(err, ErrPreflightFailed)
+func (e *PreflightFailedError) Unwrap() error {
+	return ErrPreflightFailed
+}
+
+// PreflightService 起飞前检查服务接口
+type PreflightService interface {
+	// RunChecks 执行起飞前检查，返回结构化报告
+	RunChecks(ctx context.Context, taskID uint) (*models.PreflightReport, error)
+	// OverrideWarnings 管理员放行当前检查报告中的警告，失败项不能放行
+	OverrideWarnings(ctx context.Context, taskID, userID uint, reason string) (*models.PreflightOverride, error)
+	ListOverrides(ctx context.Context, taskID uint) ([]*models.PreflightOverride, error)
+	// Authorize 启动前执行检查，有警告时使用未过期的放行记录
+	Authorize(ctx context.Context, taskID uint) (*models.PreflightReport, error)
+}
+
+// PreflightServiceImpl 起飞前检查服务实现
+type PreflightServiceImpl struct {
+	config             *PreflightConfig
+	db                 *gorm.DB
+	taskService        TaskService
+	maintenanceService MaintenanceService
+	firmwareService    FirmwareService
+	geofenceService    GeofenceService
+	energyService      EnergyService
+	weatherProvider    WeatherProvider
+	logger             *logger.Logger
+}
+
+// NewPreflightService 创建起飞前检查服务，依赖的服务为nil时跳过对应检查
+func NewPreflightService(
+	config *PreflightConfig,
+	db *gorm.DB,
+	taskService TaskService,
+	maintenanceService MaintenanceService,
+	firmwareService FirmwareService,
+	geofenceService GeofenceService,
+	energyService EnergyService,
+	weatherProvider WeatherProvider,
+	logger *logger.Logger,
+) PreflightService {
+	if config == nil {
+		config = DefaultPreflightConfig()
+	}
+
+	return &PreflightServiceImpl{
+		config:             config,
+		db:                 db,
+		taskService:        taskService,
+		maintenanceService: maintenanceService,
+		firmwareService:    firmwareService,
+		geofenceService:    geofenceService,
+		energyService:      energyService,
+		weatherProvider:    weatherProvider,
+		logger:             logger,
+	}
+}
+
+// RunChecks 执行起飞前检查
+func (s *PreflightServiceImpl) RunChecks(ctx context.Context, taskID uint) (*models.PreflightReport, error) {
+	task, err := s.taskService.GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.PreflightReport{
+		TaskID:    task.ID,
+		DroneID:   task.DroneID,
+		Status:    models.PreflightPass,
+		CheckedAt: time.Now(),
+	}
+
+	var drone models.Drone
+	if err := s.db.WithContext(ctx).First(&drone, task.DroneID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			report.Add(PreflightCheckDroneStatus, models.PreflightFail, "no drone assigned to task")
+			return report, nil
+		}
+		return nil, fmt.Errorf("failed to load drone: %w", err)
+	}
+	caps, err := drone.ParseCapabilities()
+	if err != nil {
+		caps = &models.DroneCapabilities{}
+	}
+
+	s.checkDroneStatus(report, &drone)
+	s.checkMaintenance(ctx, report, &drone)
+	remaining := task.RemainingPlan()
+	s.checkBattery(ctx, report, &drone, &remaining)
+	s.checkFirmware(ctx, report, &drone)
+	s.checkGeofence(ctx, report, &task.Plan)
+	s.checkWeather(ctx, report, &drone, caps, &task.Plan)
+	s.checkConflict(ctx, report, task)
+	return report, nil
+}
+
+// OverrideWarnings 记录管理员放行
+func (s *PreflightServiceImpl) OverrideWarnings(ctx context.Context, taskID, userID uint, reason string) (*models.PreflightOverride, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: override reason is required", ErrInvalidData)
+	}
+
+	report, err := s.RunChecks(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	switch report.Status {
+	case models.PreflightFail:
+		return nil, &PreflightFailedError{Report: report}
+	case models.PreflightPass:
+		return nil, ErrNothingToOverride
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pre-flight report: %w", err)
+	}
+	override := &models.PreflightOverride{
+		TaskID:    taskID,
+		DroneID:   report.DroneID,
+		UserID:    userID,
+		Reason:    reason,
+		Warnings:  strings.Join(report.Codes(models.PreflightWarn), ","),
+		Report:    string(data),
+		ExpiresAt: time.Now().Add(s.config.OverrideTTL),
+	}
+	if err := s.db.WithContext(ctx).Create(override).Error; err != nil {
+		return nil, fmt.Errorf("failed to record pre-flight override: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"task_id":     taskID,
+		"user_id":     userID,
+		"override_id": override.ID,
+		"warnings":    override.Warnings,
+		"reason":      reason,
+	}).Warn("Pre-flight warnings overridden")
+	return override, nil
+}
+
+// ListOverrides 获取任务的放行记录
+func (s *PreflightServiceImpl) ListOverrides(ctx context.Context, taskID uint) ([]*models.PreflightOverride, error) {
+	var overrides []*models.PreflightOverride
+	if err := s.db.WithContext(ctx).Where("task_id = ?", taskID).Order("id DESC").Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("failed to list pre-flight overrides: %w", err)
+	}
+	return overrides, nil
+}
+
+// Authorize 启动前检查，有警告时消耗一条覆盖当前全部警告项的放行记录
+func (s *PreflightServiceImpl) Authorize(ctx context.Context, taskID uint) (*models.PreflightReport, error) {
+	report, err := s.RunChecks(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch report.Status {
+	case models.PreflightPass:
+		return report, nil
+	case models.PreflightFail:
+		return report, &PreflightFailedError{Report: report}
+	}
+
+	var overrides []*models.PreflightOverride
+	if err := s.db.WithContext(ctx).
+		Where("task_id = ? AND used_at IS NULL AND expires_at > ?", taskID, time.Now()).
+		Order("id DESC").
+		Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("failed to load pre-flight overrides: %w", err)
+	}
+
+	warnings := report.Codes(models.PreflightWarn)
+	for _, override := range overrides {
+		if !coversWarnings(override, warnings) {
+			continue
+		}
+
+		// 条件更新保证每条放行记录只使用一次
+		res := s.db.WithContext(ctx).Model(&models.PreflightOverride{}).
+			Where("id = ? AND used_at IS NULL", override.ID).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return nil, fmt.Errorf("failed to use pre-flight override: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+
+		report.OverrideID = &override.ID
+		return report, nil
+	}
+
+	return report, &PreflightFailedError{Report: report, Overridable: true}
+}
+
+// coversWarnings 放行记录是否覆盖当前所有警告项，出现新的警告时需要重新放行
+func coversWarnings(override *models.PreflightOverride, warnings []string) bool {
+	approved := make(map[string]bool)
+	for _, code := range strings.Split(override.Warnings, ",") {
+		approved[code] = true
+	}
+	for _, code := range warnings {
+		if !approved[code] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDroneStatus 无人机在线且未在维护
+func (s *PreflightServiceImpl) checkDroneStatus(report *models.PreflightReport, drone *models.Drone) {
+	switch drone.Status {
+	case models.DroneStatusOnline:
+		report.Add(PreflightCheckDroneStatus, models.PreflightPass, "drone is online")
+	case models.DroneStatusMaintenance:
+		report.Add(PreflightCheckDroneStatus, models.PreflightFail, "drone is in maintenance")
+	default:
+		report.Add(PreflightCheckDroneStatus, models.PreflightFail, fmt.Sprintf("drone status is %s", drone.Status))
+	}
+}
+
+// checkMaintenance 无逾期保养和未完成的维护工单
+func (s *PreflightServiceImpl) checkMaintenance(ctx context.Context, report *models.PreflightReport, drone *models.Drone) {
+	if s.maintenanceService == nil {
+		return
+	}
+
+	status, err := s.maintenanceService.GetMaintenanceStatus(ctx, drone.ID)
+	if err != nil {
+		report.Add(PreflightCheckMaintenance, models.PreflightWarn, fmt.Sprintf("could not verify maintenance status: %v", err))
+		return
+	}
+
+	switch {
+	case len(status.OpenRecords) > 0:
+		report.Add(PreflightCheckMaintenance, models.PreflightFail, fmt.Sprintf("%d maintenance record(s) still open", len(status.OpenRecords)))
+	case status.Overdue:
+		report.Add(PreflightCheckMaintenance, models.PreflightFail, "scheduled maintenance is overdue")
+	default:
+		var dueSoon []string
+		for _, due := range status.Due {
+			if due.UsedFraction >= s.config.MaintenanceWarn {
+				dueSoon = append(dueSoon, due.Name)
+			}
+		}
+		if len(dueSoon) > 0 {
+			report.Add(PreflightCheckMaintenance, models.PreflightWarn, "maintenance due soon: "+strings.Join(dueSoon, ", "))
+		} else {
+			report.Add(PreflightCheckMaintenance, models.PreflightPass, "no maintenance due")
+		}
+	}
+}
+
+// checkBattery 按能耗估算，扣除置信余量后的结束电量不低于保留电量
+func (s *PreflightServiceImpl) checkBattery(ctx context.Context, report *models.PreflightReport, drone *models.Drone, plan *models.TaskPlan) {
+	if s.energyService == nil {
+		return
+	}
+
+	estimate, err := s.energyService.Estimate(ctx, &EnergyEstimateParams{Drone: drone, Plan: plan})
+	if err != nil {
+		report.Add(PreflightCheckBattery, models.PreflightWarn, fmt.Sprintf("could not estimate energy: %v", err))
+		return
+	}
+
+	lowest := estimate.LowerBound()
+	reserve := float64(estimate.MinBattery)
+	summary := fmt.Sprintf("battery %d%%, estimated use %.0f%% (±%.0f%%), ending at %.0f%%",
+		drone.Battery, estimate.BatteryUsed, estimate.Margin, estimate.PredictedEndBattery)
+	switch {
+	case lowest < reserve:
+		report.Add(PreflightCheckBattery, models.PreflightFail,
+			fmt.Sprintf("%s, below %d%% reserve", summary, estimate.MinBattery))
+	case lowest < reserve+float64(s.config.BatteryWarnMargin):
+		report.Add(PreflightCheckBattery, models.PreflightWarn,
+			fmt.Sprintf("%s, less than %d%% over %d%% reserve", summary, s.config.BatteryWarnMargin, estimate.MinBattery))
+	default:
+		report.Add(PreflightCheckBattery, models.PreflightPass, summary)
+	}
+}
+
+// checkFirmware 固件满足合规要求
+func (s *PreflightServiceImpl) checkFirmware(ctx context.Context, report *models.PreflightReport, drone *models.Drone) {
+	if s.firmwareService == nil {
+		return
+	}
+
+	compliance, err := s.firmwareService.GetComplianceReport(ctx, drone.Model, false)
+	if err != nil {
+		report.Add(PreflightCheckFirmware, models.PreflightWarn, fmt.Sprintf("could not verify firmware compliance: %v", err))
+		return
+	}
+
+	for _, item := range compliance.Drones {
+		if item.DroneID != drone.ID {
+			continue
+		}
+		switch item.Compliance {
+		case FirmwareBelowMinimum:
+			report.Add(PreflightCheckFirmware, models.PreflightFail,
+				fmt.Sprintf("firmware %s is below the minimum supported version for %s", item.Firmware, item.LatestVersion))
+		case FirmwareOutdated:
+			report.Add(PreflightCheckFirmware, models.PreflightWarn,
+				fmt.Sprintf("firmware %s is older than latest %s", item.Firmware, item.LatestVersion))
+		default:
+			report.Add(PreflightCheckFirmware, models.PreflightPass, fmt.Sprintf("firmware %s is compliant", item.Firmware))
+		}
+		return
+	}
+}
+
+// checkGeofence 航线不穿越当前生效的禁飞区和限飞区
+func (s *PreflightServiceImpl) checkGeofence(ctx context.Context, report *models.PreflightReport, plan *models.TaskPlan) {
+	if s.geofenceService == nil {
+		return
+	}
+
+	err := s.geofenceService.CheckPlan(ctx, plan, time.Now())
+	var violation *GeofenceViolationError
+	switch {
+	case err == nil:
+		report.Add(PreflightCheckGeofence, models.PreflightPass, "plan stays clear of restricted zones")
+	case errors.As(err, &violation):
+		report.Add(PreflightCheckGeofence, models.PreflightFail, violation.Error())
+	default:
+		report.Add(PreflightCheckGeofence, models.PreflightWarn, fmt.Sprintf("could not verify geofences: %v", err))
+	}
+}
+
+// checkWeather 风速和能见度在限制范围内
+func (s *PreflightServiceImpl) checkWeather(ctx context.Context, report *models.PreflightReport, drone *models.Drone, caps *models.DroneCapabilities, plan *models.TaskPlan) {
+	if s.weatherProvider == nil {
+		if s.config.RequireWeather {
+			report.Add(PreflightCheckWeather, models.PreflightWarn, "no weather source configured")
+		}
+		return
+	}
+
+	latitude, longitude := drone.Position.Latitude, drone.Position.Longitude
+	if len(plan.Waypoints) > 0 {
+		latitude, longitude = plan.Waypoints[0].Latitude, plan.Waypoints[0].Longitude
+	}
+	weather, err := s.weatherProvider.CurrentWeather(ctx, latitude, longitude)
+	if err != nil {
+		report.Add(PreflightCheckWeather, models.PreflightWarn, fmt.Sprintf("could not get weather: %v", err))
+		return
+	}
+
+	windLimit := caps.MaxWindSpeed
+	if windLimit <= 0 {
+		windLimit = s.config.MaxWindSpeed
+	}
+
+	var problems []string
+	status := models.PreflightPass
+	raise := func(to models.PreflightStatus, problem string) {
+		problems = append(problems, problem)
+		if to == models.PreflightFail || status == models.PreflightPass {
+			status = to
+		}
+	}
+
+	switch {
+	case weather.WindSpeed > windLimit:
+		raise(models.PreflightFail, fmt.Sprintf("wind %.1fm/s exceeds limit %.1fm/s", weather.WindSpeed, windLimit))
+	case weather.WindSpeed > windLimit*s.config.WindWarnRatio:
+		raise(models.PreflightWarn, fmt.Sprintf("wind %.1fm/s is close to limit %.1fm/s", weather.WindSpeed, windLimit))
+	}
+	switch {
+	case weather.Visibility < s.config.MinVisibility:
+		raise(models.PreflightFail, fmt.Sprintf("visibility %.1fkm below minimum %.1fkm", weather.Visibility, s.config.MinVisibility))
+	case weather.Visibility < s.config.WarnVisibility:
+		raise(models.PreflightWarn, fmt.Sprintf("visibility %.1fkm is reduced", weather.Visibility))
+	}
+
+	if len(problems) == 0 {
+		report.Add(PreflightCheckWeather, models.PreflightPass,
+			fmt.Sprintf("wind %.1fm/s, visibility %.1fkm", weather.WindSpeed, weather.Visibility))
+		return
+	}
+	report.Add(PreflightCheckWeather, status, strings.Join(problems, "; "))
+}
+
+// checkConflict 无人机上没有其他执行中的任务
+func (s *PreflightServiceImpl) checkConflict(ctx context.Context, report *models.PreflightReport, task *models.Task) {
+	var running []uint
+	if err := s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("drone_id = ? AND status = ? AND id <> ?", task.DroneID, models.TaskStatusRunning, task.ID).
+		Pluck("id", &running).Error; err != nil {
+		report.Add(PreflightCheckConflict, models.PreflightWarn, fmt.Sprintf("could not check active tasks: %v", err))
+		return
+	}
+
+	if len(running) > 0 {
+		report.Add(PreflightCheckConflict, models.PreflightFail, fmt.Sprintf("drone is already running task %d", running[0]))
+		return
+	}
+	report.Add(PreflightCheckConflict, models.PreflightPass, "no conflicting active task")
+}
+
+// PreflightTaskService 启动任务前执行起飞前检查的任务服务装饰器
+type PreflightTaskService struct {
+	TaskService
+	preflightService PreflightService
+}
+
+// NewPreflightTaskService 创建起飞前检查任务服务
+func NewPreflightTaskService(next TaskService, preflightService PreflightService) TaskService {
+	return &PreflightTaskService{
+		TaskService:      next,
+		preflightService: preflightService,
+	}
+}
+
+// StartTask 检查未通过时拒绝启动
+func (s *PreflightTaskService) StartTask(ctx context.Context, id uint) error {
+	if _, err := s.preflightService.Authorize(ctx, id); err != nil {
+		return err
+	}
+	return s.TaskService.StartTask(ctx, id)
+}
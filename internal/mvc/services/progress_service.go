@@ -0,0 +1,523 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// progressLeaderKey 任务停滞巡检主节点锁
+const progressLeaderKey = "task:progress:leader"
+
+// ProgressConfig 任务进度推算配置
+type ProgressConfig struct {
+	ArrivalRadius       float64       `yaml:"arrival_radius" json:"arrival_radius"`               // 距航点小于该距离（米）视为到达
+	MinProgressDistance float64       `yaml:"min_progress_distance" json:"min_progress_distance"` // 剩余航程减少超过该距离（米）才视为有推进
+	DefaultSpeed        float64       `yaml:"default_speed" json:"default_speed"`                 // 无法得到地速时估算ETA使用的速度（m/s）
+	StallTimeout        time.Duration `yaml:"stall_timeout" json:"stall_timeout"`                 // 超过该时间没有推进视为停滞（航点停留时间另计）
+	CheckInterval       time.Duration `yaml:"check_interval" json:"check_interval"`               // 停滞巡检间隔
+	LockTTL             time.Duration `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultProgressConfig 默认任务进度推算配置
+func DefaultProgressConfig() *ProgressConfig {
+	return &ProgressConfig{
+		ArrivalRadius:       10,
+		MinProgressDistance: 10,
+		DefaultSpeed:        10,
+		StallTimeout:        3 * time.Minute,
+		CheckInterval:       30 * time.Second,
+		LockTTL:             time.Minute,
+	}
+}
+
+// ProgressService 任务进度推算服务接口
+// 将无人机位置与执行中任务的航点逐个匹配，记录到达和完成时间，推算进度和预计完成时间，
+// 并发布 TaskProgressEvent。多实例部署时只有主节点执行停滞巡检。
+type ProgressService interface {
+	// HandlePosition 处理无人机位置上报，无人机没有执行中的任务时忽略
+	HandlePosition(ctx context.Context, droneID uint, position models.Position, at time.Time) error
+
+	// 服务管理（停滞巡检）
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// ProgressServiceImpl 任务进度推算服务实现
+type ProgressServiceImpl struct {
+	config       *ProgressConfig
+	db           *gorm.DB
+	election     *database.LeaderElection
+	alertService AlertService
+	kafkaService KafkaService
+	logger       *logger.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewProgressService 创建任务进度推算服务
+func NewProgressService(
+	config *ProgressConfig,
+	db *gorm.DB,
+	lockService *database.LockService,
+	alertService AlertService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) ProgressService {
+	if config == nil {
+		config = DefaultProgressConfig()
+	}
+
+	return &ProgressServiceImpl{
+		config:       config,
+		db:           db,
+		election:     database.NewLeaderElection(lockService, progressLeaderKey, config.LockTTL),
+		alertService: alertService,
+		kafkaService: kafkaService,
+		logger:       logger,
+	}
+}
+
+// HandlePosition 处理无人机位置上报
+func (s *ProgressServiceImpl) HandlePosition(ctx context.Context, droneID uint, position models.Position, at time.Time) error {
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	var task models.Task
+	err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND status = ?", droneID, models.TaskStatusRunning).
+		Order("started_at DESC").First(&task).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to load running task: %w", err)
+	}
+
+	waypoints := task.Plan.Waypoints
+	if len(waypoints) == 0 {
+		return nil
+	}
+
+	tracking := task.Tracking
+	initialized := tracking.Attempt != task.Attempt || tracking.LastPositionAt.IsZero()
+	if initialized {
+		tracking = s.initTracking(&task, position, at)
+	} else if !at.After(tracking.LastPositionAt) {
+		// 乱序到达的旧位置不参与推算
+		return nil
+	}
+
+	previous := tracking
+	speed := s.groundSpeed(&tracking, position, at)
+	reached := s.advanceWaypoints(&task, &tracking, position, at)
+
+	tracking.LastLatitude = position.Latitude
+	tracking.LastLongitude = position.Longitude
+	tracking.LastAltitude = position.Altitude
+	tracking.LastPositionAt = at
+
+	tracking.RemainingDistance = s.remainingDistance(waypoints, &tracking, position)
+	if reached || tracking.RemainingDistance <= previous.RemainingDistance-s.config.MinProgressDistance {
+		tracking.LastProgressAt = at
+	}
+	resumed := tracking.Stalled && tracking.LastProgressAt.After(previous.LastProgressAt)
+	if resumed {
+		tracking.Stalled = false
+		tracking.StalledAt = nil
+	}
+	eta := at.Add(s.remainingTime(&task, &tracking, speed, at))
+	tracking.ETA = &eta
+
+	progress := trackingProgress(&tracking)
+	changed := initialized || progress != task.Progress || reached || resumed
+	if !changed && !tracking.LastProgressAt.After(previous.LastProgressAt) {
+		// 没有推进时不写库，下一个位置仍与已保存的位置比较
+		return nil
+	}
+
+	res := s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("id = ? AND status = ? AND attempt = ?", task.ID, models.TaskStatusRunning, task.Attempt).
+		Updates(map[string]interface{}{
+			"progress": progress,
+			"tracking": tracking,
+		})
+	if res.Error != nil {
+		return fmt.Errorf("failed to update task progress: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+
+	if resumed {
+		s.logger.WithFields(map[string]interface{}{
+			"task_id":  task.ID,
+			"drone_id": droneID,
+		}).Info("Stalled task resumed progress")
+	}
+	if changed {
+		task.Progress = progress
+		s.publishProgress(ctx, &task, &tracking, string(task.Status), &position)
+	}
+	return nil
+}
+
+// initTracking 任务（或重试）开始后的第一个位置，从 ResumeWaypoint 开始推算
+func (s *ProgressServiceImpl) initTracking(task *models.Task, position models.Position, at time.Time) models.TaskTracking {
+	start := task.ResumeWaypoint
+	if start < 0 || start >= len(task.Plan.Waypoints) {
+		start = 0
+	}
+
+	tracking := models.TaskTracking{
+		Attempt:        task.Attempt,
+		NextWaypoint:   start,
+		LastLatitude:   position.Latitude,
+		LastLongitude:  position.Longitude,
+		LastAltitude:   position.Altitude,
+		LastPositionAt: at,
+		LastProgressAt: at,
+	}
+	tracking.TotalDistance = s.remainingDistance(task.Plan.Waypoints, &tracking, position)
+	tracking.RemainingDistance = tracking.TotalDistance
+	return tracking
+}
+
+// advanceWaypoints 按顺序匹配航点：进入到达半径记为到达，停留时间结束或离开半径记为完成
+// 返回是否有航点到达或完成
+func (s *ProgressServiceImpl) advanceWaypoints(task *models.Task, tracking *models.TaskTracking, position models.Position, at time.Time) bool {
+	waypoints := task.Plan.Waypoints
+	changed := false
+	for tracking.NextWaypoint < len(waypoints) {
+		wp := waypoints[tracking.NextWaypoint]
+		within := geo.Distance(position.Latitude, position.Longitude, wp.Latitude, wp.Longitude) <= s.config.ArrivalRadius
+
+		holding := tracking.Holding()
+		if holding == nil {
+			if !within {
+				return changed
+			}
+			tracking.Arrivals = append(tracking.Arrivals, models.WaypointArrival{
+				Index:     tracking.NextWaypoint,
+				ArrivedAt: at,
+			})
+			holding = &tracking.Arrivals[len(tracking.Arrivals)-1]
+			changed = true
+		}
+
+		hold := time.Duration(wp.HoldTime * float64(time.Second))
+		if within && at.Sub(holding.ArrivedAt) < hold {
+			return changed
+		}
+
+		completedAt := at
+		holding.CompletedAt = &completedAt
+		tracking.NextWaypoint++
+		changed = true
+	}
+	return changed
+}
+
+// remainingDistance 当前位置经未完成航点到最后一个航点的航程
+func (s *ProgressServiceImpl) remainingDistance(waypoints models.WaypointList, tracking *models.TaskTracking, position models.Position) float64 {
+	next := tracking.NextWaypoint
+	if next >= len(waypoints) {
+		return 0
+	}
+
+	distance := 0.0
+	if tracking.Holding() == nil {
+		distance = geo.Distance(position.Latitude, position.Longitude, waypoints[next].Latitude, waypoints[next].Longitude)
+	}
+	for i := next + 1; i < len(waypoints); i++ {
+		distance += geo.Distance(waypoints[i-1].Latitude, waypoints[i-1].Longitude, waypoints[i].Latitude, waypoints[i].Longitude)
+	}
+	return distance
+}
+
+// remainingTime 按地速（或计划速度）和剩余停留时间估算完成全部航点需要的时间
+func (s *ProgressServiceImpl) remainingTime(task *models.Task, tracking *models.TaskTracking, speed float64, at time.Time) time.Duration {
+	waypoints := task.Plan.Waypoints
+	if tracking.NextWaypoint >= len(waypoints) {
+		return 0
+	}
+
+	if speed < 1 {
+		speed = waypoints[tracking.NextWaypoint].Speed
+	}
+	if speed <= 0 {
+		speed = task.Plan.MaxSpeed
+	}
+	if speed <= 0 {
+		speed = s.config.DefaultSpeed
+	}
+
+	seconds := tracking.RemainingDistance / speed
+	for i := tracking.NextWaypoint; i < len(waypoints); i++ {
+		seconds += waypoints[i].HoldTime
+	}
+	if holding := tracking.Holding(); holding != nil {
+		seconds -= math.Min(at.Sub(holding.ArrivedAt).Seconds(), waypoints[holding.Index].HoldTime)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// groundSpeed 根据上一个位置计算地速（m/s），间隔过长时返回0
+func (s *ProgressServiceImpl) groundSpeed(tracking *models.TaskTracking, position models.Position, at time.Time) float64 {
+	elapsed := at.Sub(tracking.LastPositionAt).Seconds()
+	if elapsed <= 0 || elapsed > 60 {
+		return 0
+	}
+	return geo.Distance(tracking.LastLatitude, tracking.LastLongitude, position.Latitude, position.Longitude) / elapsed
+}
+
+// trackingProgress 按已飞航程计算进度，任务结束前最多为99
+func trackingProgress(tracking *models.TaskTracking) int {
+	if tracking.TotalDistance <= 0 {
+		if tracking.RemainingDistance > 0 {
+			return 0
+		}
+		return 99
+	}
+
+	progress := int((tracking.TotalDistance - tracking.RemainingDistance) / tracking.TotalDistance * 100)
+	if progress < 0 {
+		return 0
+	}
+	if progress > 99 {
+		return 99
+	}
+	return progress
+}
+
+// currentStep 进度事件中的当前步骤说明
+func currentStep(task *models.Task, tracking *models.TaskTracking) string {
+	waypoints := task.Plan.Waypoints
+	if tracking.Stalled {
+		return fmt.Sprintf("stalled before waypoint %d/%d", tracking.NextWaypoint+1, len(waypoints))
+	}
+	if tracking.NextWaypoint >= len(waypoints) {
+		return "all waypoints completed"
+	}
+
+	label := fmt.Sprintf("waypoint %d/%d", tracking.NextWaypoint+1, len(waypoints))
+	if name := waypoints[tracking.NextWaypoint].Name; name != "" {
+		label = fmt.Sprintf("%s (%s)", label, name)
+	}
+	if tracking.Holding() != nil {
+		return "holding at " + label
+	}
+	return "flying to " + label
+}
+
+// publishProgress 发布任务进度事件
+func (s *ProgressServiceImpl) publishProgress(ctx context.Context, task *models.Task, tracking *models.TaskTracking, status string, position *models.Position) {
+	if s.kafkaService == nil {
+		return
+	}
+
+	eventData := kafka.TaskProgressEventData{
+		TaskID:      task.ID,
+		TaskName:    task.Name,
+		DroneID:     task.DroneID,
+		Progress:    task.Progress,
+		Status:      status,
+		CurrentStep: currentStep(task, tracking),
+		Timestamp:   time.Now(),
+	}
+	if position != nil {
+		eventData.Location = &kafka.Location{
+			Latitude:  position.Latitude,
+			Longitude: position.Longitude,
+			Altitude:  position.Altitude,
+			Heading:   position.Heading,
+		}
+	}
+
+	if err := s.kafkaService.PublishTaskEvent(ctx, kafka.TaskProgressEvent, eventData); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to publish task progress event")
+	}
+}
+
+// Start 启动停滞巡检
+func (s *ProgressServiceImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.checkLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"check_interval": s.config.CheckInterval.String(),
+		"stall_timeout":  s.config.StallTimeout.String(),
+		"instance":       s.election.Identity(),
+	}).Info("Progress service started")
+	return nil
+}
+
+// Stop 停止巡检并释放主节点锁
+func (s *ProgressServiceImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release progress leader lock")
+	}
+
+	s.logger.Info("Progress service stopped")
+	return nil
+}
+
+// checkLoop 周期检查执行中的任务是否停滞
+func (s *ProgressServiceImpl) checkLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Progress leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+			s.checkStalled(s.ctx)
+		}
+	}
+}
+
+// checkStalled 标记超过时限没有推进的任务并产生告警
+// 还没有收到位置的任务从启动时间开始计算，航点停留期间按停留时间顺延
+func (s *ProgressServiceImpl) checkStalled(ctx context.Context) {
+	var tasks []*models.Task
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND started_at IS NOT NULL", models.TaskStatusRunning).
+		Find(&tasks).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to load running tasks")
+		return
+	}
+
+	now := time.Now()
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			return
+		}
+		if len(task.Plan.Waypoints) == 0 {
+			continue
+		}
+
+		tracking := task.Tracking
+		if tracking.Attempt != task.Attempt {
+			tracking = models.TaskTracking{Attempt: task.Attempt, NextWaypoint: task.ResumeWaypoint}
+		}
+		if tracking.Stalled {
+			continue
+		}
+
+		last := tracking.LastProgressAt
+		if last.IsZero() || last.Before(*task.StartedAt) {
+			last = *task.StartedAt
+		}
+		deadline := last.Add(s.config.StallTimeout)
+		if holding := tracking.Holding(); holding != nil && holding.Index < len(task.Plan.Waypoints) {
+			deadline = deadline.Add(time.Duration(task.Plan.Waypoints[holding.Index].HoldTime * float64(time.Second)))
+		}
+		if now.Before(deadline) {
+			continue
+		}
+
+		s.markStalled(ctx, task, tracking, now)
+	}
+}
+
+// markStalled 标记任务停滞，只有状态实际变化时才告警
+func (s *ProgressServiceImpl) markStalled(ctx context.Context, task *models.Task, tracking models.TaskTracking, now time.Time) {
+	tracking.Stalled = true
+	tracking.StalledAt = &now
+	if tracking.LastProgressAt.IsZero() {
+		tracking.LastProgressAt = *task.StartedAt
+	}
+
+	res := s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("id = ? AND status = ? AND attempt = ?", task.ID, models.TaskStatusRunning, task.Attempt).
+		Update("tracking", tracking)
+	if res.Error != nil {
+		s.logger.WithError(res.Error).WithField("task_id", task.ID).Error("Failed to mark task stalled")
+		return
+	}
+	if res.RowsAffected == 0 {
+		return
+	}
+
+	idle := now.Sub(tracking.LastProgressAt).Round(time.Second)
+	s.logger.WithFields(map[string]interface{}{
+		"task_id":       task.ID,
+		"drone_id":      task.DroneID,
+		"next_waypoint": tracking.NextWaypoint,
+		"idle":          idle.String(),
+	}).Warn("Task stalled")
+
+	droneID := task.DroneID
+	taskID := task.ID
+	raiseAlert(ctx, s.alertService, s.kafkaService, s.logger, &CreateAlertParams{
+		Title:   "任务执行停滞",
+		Message: fmt.Sprintf("任务 %s 已 %s 没有推进（%s）", task.Name, idle, currentStep(task, &tracking)),
+		Type:    models.AlertTypeTask,
+		Level:   models.AlertLevelWarning,
+		Source:  "progress-service",
+		Code:    "TASK_STALLED",
+		Data:    fmt.Sprintf(`{"next_waypoint":%d,"idle_seconds":%.0f}`, tracking.NextWaypoint, idle.Seconds()),
+		DroneID: &droneID,
+		TaskID:  &taskID,
+	})
+
+	var position *models.Position
+	if !tracking.LastPositionAt.IsZero() {
+		position = &models.Position{
+			Latitude:  tracking.LastLatitude,
+			Longitude: tracking.LastLongitude,
+			Altitude:  tracking.LastAltitude,
+		}
+	}
+	s.publishProgress(ctx, task, &tracking, "stalled", position)
+}
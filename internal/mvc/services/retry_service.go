@@ -0,0 +1,676 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// retryLeaderKey 失败任务重试巡检主节点锁
+	retryLeaderKey = "task:retry:leader"
+
+	// maxRetryAttempts 重试策略允许的最大执行次数
+	maxRetryAttempts = 10
+)
+
+// RetryConfig 任务失败重试配置
+type RetryConfig struct {
+	CheckInterval  time.Duration `yaml:"check_interval" json:"check_interval"`   // 补充处理漏掉的失败任务的巡检间隔
+	LookBack       time.Duration `yaml:"look_back" json:"look_back"`             // 巡检只处理该时间内失败的任务
+	WaypointRadius float64       `yaml:"waypoint_radius" json:"waypoint_radius"` // 轨迹点距航点小于该距离（米）视为到达
+	LockTTL        time.Duration `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultRetryConfig 默认任务失败重试配置
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		CheckInterval:  30 * time.Second,
+		LookBack:       24 * time.Hour,
+		WaypointRadius: 15,
+		LockTTL:        time.Minute,
+	}
+}
+
+// RetryPolicyParams 设置重试策略参数
+type RetryPolicyParams struct {
+	TaskType            models.TaskType      `json:"task_type"`
+	MaxAttempts         int                  `json:"max_attempts"`
+	BackoffSeconds      int                  `json:"backoff_seconds"`
+	BackoffMultiplier   float64              `json:"backoff_multiplier"`
+	MaxBackoffSeconds   int                  `json:"max_backoff_seconds"`
+	RetryableErrorCodes []string             `json:"retryable_error_codes"`
+	Strategy            models.RetryStrategy `json:"strategy"` // 为空时为 auto
+	ReassignErrorCodes  []string             `json:"reassign_error_codes"`
+	ResumeFromWaypoint  bool                 `json:"resume_from_waypoint"`
+}
+
+// RetryService 任务失败重试服务接口
+// 任务执行结束后记录执行历史；失败的任务按任务类型的策略决定是否重试、在原无人机上重试还是重新分配，
+// 重试复用原任务，按退避时间置为已调度后由调度器启动。多实例部署时只有主节点执行巡检。
+type RetryService interface {
+	// 重试策略管理
+	ListPolicies(ctx context.Context) ([]*models.TaskRetryPolicy, error)
+	GetPolicy(ctx context.Context, taskType models.TaskType) (*models.TaskRetryPolicy, error)
+	// SetPolicy 创建或替换任务类型的重试策略
+	SetPolicy(ctx context.Context, params *RetryPolicyParams) (*models.TaskRetryPolicy, error)
+	DeletePolicy(ctx context.Context, taskType models.TaskType) error
+
+	// ListAttempts 获取任务的执行历史，按执行次数排列
+	ListAttempts(ctx context.Context, taskID uint) ([]*models.TaskAttempt, error)
+	// HandleTaskFinished 记录任务本次执行的结果，失败时按策略安排重试，重复调用不会重复处理
+	HandleTaskFinished(ctx context.Context, taskID uint) error
+
+	// 服务管理
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// RetryServiceImpl 任务失败重试服务实现
+type RetryServiceImpl struct {
+	config            *RetryConfig
+	db                *gorm.DB
+	election          *database.LeaderElection
+	assignmentService AssignmentService
+	telemetryService  TelemetryService
+	kafkaService      KafkaService
+	logger            *logger.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewRetryService 创建任务失败重试服务
+// telemetryService 用于根据轨迹判断已完成的航点，为nil时总是从头执行
+func NewRetryService(
+	config *RetryConfig,
+	db *gorm.DB,
+	lockService *database.LockService,
+	assignmentService AssignmentService,
+	telemetryService TelemetryService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) RetryService {
+	if config == nil {
+		config = DefaultRetryConfig()
+	}
+
+	return &RetryServiceImpl{
+		config:            config,
+		db:                db,
+		election:          database.NewLeaderElection(lockService, retryLeaderKey, config.LockTTL),
+		assignmentService: assignmentService,
+		telemetryService:  telemetryService,
+		kafkaService:      kafkaService,
+		logger:            logger,
+	}
+}
+
+// ListPolicies 获取全部重试策略
+func (s *RetryServiceImpl) ListPolicies(ctx context.Context) ([]*models.TaskRetryPolicy, error) {
+	var policies []*models.TaskRetryPolicy
+	if err := s.db.WithContext(ctx).Order("task_type").Find(&policies).Error; err != nil {
+		return nil, fmt.Errorf("failed to list retry policies: %w", err)
+	}
+	return policies, nil
+}
+
+// GetPolicy 获取任务类型的重试策略
+func (s *RetryServiceImpl) GetPolicy(ctx context.Context, taskType models.TaskType) (*models.TaskRetryPolicy, error) {
+	var policy models.TaskRetryPolicy
+	if err := s.db.WithContext(ctx).Where("task_type = ?", taskType).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRetryPolicyNotFound
+		}
+		return nil, fmt.Errorf("failed to get retry policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// SetPolicy 创建或替换任务类型的重试策略
+func (s *RetryServiceImpl) SetPolicy(ctx context.Context, params *RetryPolicyParams) (*models.TaskRetryPolicy, error) {
+	if params.Strategy == "" {
+		params.Strategy = models.RetryAuto
+	}
+	if err := validateRetryPolicy(params); err != nil {
+		return nil, err
+	}
+
+	policy, err := s.GetPolicy(ctx, params.TaskType)
+	if err == ErrRetryPolicyNotFound {
+		policy = &models.TaskRetryPolicy{TaskType: params.TaskType}
+	} else if err != nil {
+		return nil, err
+	}
+
+	policy.MaxAttempts = params.MaxAttempts
+	policy.BackoffSeconds = params.BackoffSeconds
+	policy.BackoffMultiplier = params.BackoffMultiplier
+	policy.MaxBackoffSeconds = params.MaxBackoffSeconds
+	policy.RetryableErrorCodes = params.RetryableErrorCodes
+	policy.Strategy = params.Strategy
+	policy.ReassignErrorCodes = params.ReassignErrorCodes
+	policy.ResumeFromWaypoint = params.ResumeFromWaypoint
+
+	if err := s.db.WithContext(ctx).Save(policy).Error; err != nil {
+		return nil, fmt.Errorf("failed to save retry policy: %w", err)
+	}
+	return policy, nil
+}
+
+// DeletePolicy 删除任务类型的重试策略，之后该类型的任务失败后不再重试
+func (s *RetryServiceImpl) DeletePolicy(ctx context.Context, taskType models.TaskType) error {
+	res := s.db.WithContext(ctx).Where("task_type = ?", taskType).Delete(&models.TaskRetryPolicy{})
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete retry policy: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrRetryPolicyNotFound
+	}
+	return nil
+}
+
+// ListAttempts 获取任务的执行历史
+func (s *RetryServiceImpl) ListAttempts(ctx context.Context, taskID uint) ([]*models.TaskAttempt, error) {
+	var attempts []*models.TaskAttempt
+	if err := s.db.WithContext(ctx).Where("task_id = ?", taskID).
+		Order("attempt").Find(&attempts).Error; err != nil {
+		return nil, fmt.Errorf("failed to list task attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// retryPlan 失败任务的重试安排
+type retryPlan struct {
+	droneID        uint
+	reassigned     bool
+	note           string
+	resumeWaypoint int
+	retryAt        time.Time
+}
+
+// HandleTaskFinished 记录任务本次执行的结果，失败时按策略安排重试
+func (s *RetryServiceImpl) HandleTaskFinished(ctx context.Context, taskID uint) error {
+	var task models.Task
+	if err := s.db.WithContext(ctx).First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTaskNotFound
+		}
+		return fmt.Errorf("failed to load task: %w", err)
+	}
+	if !task.IsCompleted() {
+		return nil
+	}
+
+	recorded, err := s.attemptRecorded(s.db.WithContext(ctx), &task)
+	if err != nil || recorded {
+		return err
+	}
+
+	attempt := &models.TaskAttempt{
+		TaskID:        task.ID,
+		Attempt:       task.Attempt,
+		DroneID:       task.DroneID,
+		Status:        task.Status,
+		ErrorCode:     task.Result.ErrorCode,
+		ErrorDetail:   taskFailureMessage(&task),
+		StartWaypoint: task.ResumeWaypoint,
+		LastWaypoint:  s.lastCompletedWaypoint(ctx, &task),
+		Decision:      models.RetryDecisionNone,
+		StartedAt:     task.StartedAt,
+		CompletedAt:   task.CompletedAt,
+	}
+	if task.Status != models.TaskStatusFailed {
+		attempt.ErrorDetail = ""
+		return s.recordAttempt(ctx, &task, attempt, nil)
+	}
+
+	plan := s.planRetry(ctx, &task, attempt)
+	return s.recordAttempt(ctx, &task, attempt, plan)
+}
+
+// planRetry 按策略决定失败任务是否重试以及使用的无人机，不重试时返回nil并在执行记录中写明原因
+func (s *RetryServiceImpl) planRetry(ctx context.Context, task *models.Task, attempt *models.TaskAttempt) *retryPlan {
+	policy, err := s.GetPolicy(ctx, task.Type)
+	if err != nil {
+		if err != ErrRetryPolicyNotFound {
+			s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to load retry policy")
+		}
+		attempt.Decision = models.RetryDecisionNoPolicy
+		attempt.Reason = fmt.Sprintf("no retry policy for task type %s", task.Type)
+		return nil
+	}
+
+	code := task.Result.ErrorCode
+	switch {
+	case task.Attempt >= policy.MaxAttempts:
+		attempt.Decision = models.RetryDecisionExhausted
+		attempt.Reason = fmt.Sprintf("reached max attempts (%d)", policy.MaxAttempts)
+		return nil
+	case !policy.Retryable(code):
+		attempt.Decision = models.RetryDecisionNotRetryable
+		attempt.Reason = fmt.Sprintf("error code %q is not retryable", code)
+		return nil
+	}
+
+	plan := &retryPlan{
+		droneID: task.DroneID,
+		retryAt: time.Now().Add(policy.Backoff(task.Attempt)),
+	}
+
+	// 从最后完成的航点之后继续，全部航点都已到达时重飞最后一段
+	if policy.ResumeFromWaypoint && attempt.LastWaypoint >= task.ResumeWaypoint {
+		plan.resumeWaypoint = attempt.LastWaypoint + 1
+		if plan.resumeWaypoint >= len(task.Plan.Waypoints) {
+			plan.resumeWaypoint = len(task.Plan.Waypoints) - 1
+		}
+	} else if policy.ResumeFromWaypoint {
+		plan.resumeWaypoint = task.ResumeWaypoint
+	}
+
+	previous, err := s.loadDrone(ctx, task.DroneID)
+	if err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to load task drone for retry")
+	}
+
+	reassign := policy.Strategy == models.RetryReassign
+	if policy.Strategy == models.RetryAuto {
+		reassign = previous == nil || !previous.IsAvailable() || policy.ReassignErrorCodes.Contains(code)
+	}
+
+	if !reassign {
+		attempt.Decision = models.RetryDecisionRetry
+		attempt.Reason = fmt.Sprintf("retrying on drone %d in %s", task.DroneID, policy.Backoff(task.Attempt))
+		return s.finishPlan(attempt, plan)
+	}
+
+	candidate, err := s.selectReplacement(ctx, task, previous, plan.resumeWaypoint)
+	if err != nil {
+		attempt.Decision = models.RetryDecisionNoDrone
+		attempt.Reason = fmt.Sprintf("no replacement drone available: %v", err)
+		return nil
+	}
+
+	plan.droneID = candidate.DroneID
+	plan.reassigned = true
+	plan.note = fmt.Sprintf("reassigned from drone %d after failed attempt %d: %s", task.DroneID, task.Attempt, candidate.Explanation)
+	attempt.Decision = models.RetryDecisionReassign
+	attempt.Reason = plan.note
+	return s.finishPlan(attempt, plan)
+}
+
+// finishPlan 把重试安排写入执行记录
+func (s *RetryServiceImpl) finishPlan(attempt *models.TaskAttempt, plan *retryPlan) *retryPlan {
+	attempt.NextDroneID = &plan.droneID
+	attempt.NextAttemptAt = &plan.retryAt
+	return plan
+}
+
+// selectReplacement 为失败任务选择其他无人机，排除之前执行失败的无人机
+func (s *RetryServiceImpl) selectReplacement(ctx context.Context, task *models.Task, previous *models.Drone, resumeWaypoint int) (*AssignmentCandidate, error) {
+	if s.assignmentService == nil {
+		return nil, ErrNoDroneAvailable
+	}
+
+	req, err := task.ParseRequiredCapabilities()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCapabilities, err)
+	}
+
+	var excluded []uint
+	if err := s.db.WithContext(ctx).Model(&models.TaskAttempt{}).
+		Where("task_id = ? AND status = ?", task.ID, models.TaskStatusFailed).
+		Distinct().Pluck("drone_id", &excluded).Error; err != nil {
+		return nil, fmt.Errorf("failed to load previous attempts: %w", err)
+	}
+	excluded = append(excluded, task.DroneID)
+
+	// 按重试时的剩余航点评估距离和电量
+	resumed := *task
+	resumed.ResumeWaypoint = resumeWaypoint
+
+	var scope *FleetScope
+	if previous != nil {
+		scope = droneScope(previous)
+	}
+
+	return s.assignmentService.SelectDrone(ctx, &AssignmentRequest{
+		Plan:                 resumed.RemainingPlan(),
+		RequiredCapabilities: req,
+		ExcludeDroneIDs:      excluded,
+		Scope:                scope,
+	})
+}
+
+// recordAttempt 写入执行记录；plan 不为nil时把任务置为已调度等待重试
+// 锁定任务行后再检查，多实例同时处理同一任务时只有一个生效
+func (s *RetryServiceImpl) recordAttempt(ctx context.Context, task *models.Task, attempt *models.TaskAttempt, plan *retryPlan) error {
+	handled := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Task
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, task.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock task: %w", err)
+		}
+		if current.Status != task.Status || current.Attempt != task.Attempt {
+			return nil
+		}
+		if recorded, err := s.attemptRecorded(tx, task); err != nil || recorded {
+			return err
+		}
+
+		if err := tx.Create(attempt).Error; err != nil {
+			return fmt.Errorf("failed to record task attempt: %w", err)
+		}
+		handled = true
+		if plan == nil {
+			return nil
+		}
+
+		updates := map[string]interface{}{
+			"status":              models.TaskStatusScheduled,
+			"scheduled_at":        plan.retryAt,
+			"drone_id":            plan.droneID,
+			"attempt":             task.Attempt + 1,
+			"resume_waypoint":     plan.resumeWaypoint,
+			"progress":            0,
+			"started_at":          nil,
+			"completed_at":        nil,
+			"result_success":      false,
+			"result_message":      "",
+			"result_error_code":   "",
+			"result_error_detail": "",
+		}
+		if plan.reassigned {
+			updates["auto_assigned"] = true
+			updates["assignment_note"] = plan.note
+		}
+		if err := tx.Model(&models.Task{}).Where("id = ?", task.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to schedule task retry: %w", err)
+		}
+		return nil
+	})
+	if err != nil || !handled {
+		return err
+	}
+
+	fields := map[string]interface{}{
+		"task_id":  task.ID,
+		"attempt":  attempt.Attempt,
+		"decision": attempt.Decision,
+	}
+	if plan == nil {
+		if task.Status == models.TaskStatusFailed {
+			s.logger.WithFields(fields).WithField("reason", attempt.Reason).Info("Failed task will not be retried")
+		}
+		return nil
+	}
+
+	fields["drone_id"] = plan.droneID
+	fields["retry_at"] = plan.retryAt
+	fields["resume_waypoint"] = plan.resumeWaypoint
+	s.logger.WithFields(fields).Info("Task retry scheduled")
+	s.publishRetry(ctx, task, attempt, plan)
+	return nil
+}
+
+// attemptRecorded 任务本次执行是否已经记录
+func (s *RetryServiceImpl) attemptRecorded(db *gorm.DB, task *models.Task) (bool, error) {
+	var count int64
+	if err := db.Model(&models.TaskAttempt{}).
+		Where("task_id = ? AND attempt = ?", task.ID, task.Attempt).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check task attempt: %w", err)
+	}
+	return count > 0, nil
+}
+
+// lastCompletedWaypoint 根据本次执行的轨迹判断按顺序到达的最后一个航点，没有时返回 ResumeWaypoint-1
+// 成功完成的任务视为全部航点已完成，轨迹按页读取完整，不受单次查询点数上限影响
+func (s *RetryServiceImpl) lastCompletedWaypoint(ctx context.Context, task *models.Task) int {
+	waypoints := task.Plan.Waypoints
+	last := task.ResumeWaypoint - 1
+	if task.Status == models.TaskStatusCompleted {
+		return len(waypoints) - 1
+	}
+	if s.telemetryService == nil || task.StartedAt == nil || last+1 >= len(waypoints) {
+		return last
+	}
+
+	params := &TrackQueryParams{
+		DroneID: task.DroneID,
+		From:    *task.StartedAt,
+		To:      time.Now(),
+		Limit:   maxTrackLimit,
+	}
+	if task.CompletedAt != nil {
+		params.To = *task.CompletedAt
+	}
+
+	next := last + 1
+	for next < len(waypoints) {
+		track, err := s.telemetryService.QueryTrack(ctx, params)
+		if err != nil {
+			s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to load task track for retry")
+			return last
+		}
+
+		for _, point := range track.Points {
+			for next < len(waypoints) &&
+				geo.Distance(point.Latitude, point.Longitude, waypoints[next].Latitude, waypoints[next].Longitude) <= s.config.WaypointRadius {
+				last = next
+				next++
+			}
+			if next >= len(waypoints) {
+				break
+			}
+		}
+
+		if !track.Truncated {
+			break
+		}
+		// 后续页沿用首页选定的精度
+		params.From = *track.Next
+		params.Resolution = track.Resolution
+	}
+	return last
+}
+
+// loadDrone 获取无人机
+func (s *RetryServiceImpl) loadDrone(ctx context.Context, id uint) (*models.Drone, error) {
+	var drone models.Drone
+	if err := s.db.WithContext(ctx).First(&drone, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDroneNotFound
+		}
+		return nil, fmt.Errorf("failed to get drone: %w", err)
+	}
+	return &drone, nil
+}
+
+// publishRetry 发布任务重试事件
+func (s *RetryServiceImpl) publishRetry(ctx context.Context, task *models.Task, attempt *models.TaskAttempt, plan *retryPlan) {
+	if s.kafkaService == nil {
+		return
+	}
+
+	if err := s.kafkaService.PublishTaskEvent(ctx, kafka.TaskRetryScheduledEvent, kafka.TaskRetryEventData{
+		TaskID:          task.ID,
+		TaskName:        task.Name,
+		Attempt:         task.Attempt + 1,
+		PreviousDroneID: task.DroneID,
+		DroneID:         plan.droneID,
+		Reassigned:      plan.reassigned,
+		ErrorCode:       attempt.ErrorCode,
+		ResumeWaypoint:  plan.resumeWaypoint,
+		RetryAt:         plan.retryAt,
+		Timestamp:       time.Now(),
+	}); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to publish task retry event")
+	}
+}
+
+// Start 启动失败任务巡检
+func (s *RetryServiceImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.checkLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"check_interval": s.config.CheckInterval.String(),
+		"instance":       s.election.Identity(),
+	}).Info("Retry service started")
+	return nil
+}
+
+// Stop 停止巡检并释放主节点锁
+func (s *RetryServiceImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release retry leader lock")
+	}
+
+	s.logger.Info("Retry service stopped")
+	return nil
+}
+
+// checkLoop 周期处理尚未记录执行结果的失败任务
+// 任务结束回调和失败事件会即时处理，巡检用于兜底实例重启或事件丢失的情况
+func (s *RetryServiceImpl) checkLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Retry leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+			s.checkFailedTasks(s.ctx)
+		}
+	}
+}
+
+// checkFailedTasks 处理最近失败且本次执行尚未记录的任务
+func (s *RetryServiceImpl) checkFailedTasks(ctx context.Context) {
+	var taskIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.Task{}).
+		Joins("LEFT JOIN task_attempts ON task_attempts.task_id = tasks.id AND task_attempts.attempt = tasks.attempt").
+		Where("tasks.status = ? AND tasks.completed_at >= ? AND task_attempts.id IS NULL",
+			models.TaskStatusFailed, time.Now().Add(-s.config.LookBack)).
+		Pluck("tasks.id", &taskIDs).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to load failed tasks")
+		return
+	}
+
+	for _, id := range taskIDs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.HandleTaskFinished(ctx, id); err != nil {
+			s.logger.WithError(err).WithField("task_id", id).Error("Failed to handle failed task")
+		}
+	}
+}
+
+// validateRetryPolicy 校验重试策略参数
+func validateRetryPolicy(params *RetryPolicyParams) error {
+	switch {
+	case !validTaskType(params.TaskType):
+		return fmt.Errorf("%w: unknown task type %q", ErrInvalidRetryPolicy, params.TaskType)
+	case params.MaxAttempts < 1 || params.MaxAttempts > maxRetryAttempts:
+		return fmt.Errorf("%w: max_attempts must be between 1 and %d", ErrInvalidRetryPolicy, maxRetryAttempts)
+	case params.BackoffSeconds < 0 || params.MaxBackoffSeconds < 0:
+		return fmt.Errorf("%w: backoff must not be negative", ErrInvalidRetryPolicy)
+	case params.BackoffMultiplier < 0:
+		return fmt.Errorf("%w: backoff_multiplier must not be negative", ErrInvalidRetryPolicy)
+	case !params.Strategy.IsValid():
+		return fmt.Errorf("%w: unknown strategy %q", ErrInvalidRetryPolicy, params.Strategy)
+	}
+	return nil
+}
+
+// RetryTaskService 失败重试任务服务装饰器
+// 需要位于流程装饰器之内，流程推进时看到的是已安排重试的任务
+type RetryTaskService struct {
+	TaskService
+	retryService RetryService
+	logger       *logger.Logger
+}
+
+// NewRetryTaskService 创建失败重试任务服务
+func NewRetryTaskService(next TaskService, retryService RetryService, logger *logger.Logger) TaskService {
+	return &RetryTaskService{
+		TaskService:  next,
+		retryService: retryService,
+		logger:       logger,
+	}
+}
+
+// CompleteTask 完成后记录执行结果，失败时安排重试
+func (s *RetryTaskService) CompleteTask(ctx context.Context, id uint, success bool, message string) error {
+	if err := s.TaskService.CompleteTask(ctx, id, success, message); err != nil {
+		return err
+	}
+	s.record(ctx, id)
+	return nil
+}
+
+// StopTask 停止后记录执行结果
+func (s *RetryTaskService) StopTask(ctx context.Context, id uint) error {
+	if err := s.TaskService.StopTask(ctx, id); err != nil {
+		return err
+	}
+	s.record(ctx, id)
+	return nil
+}
+
+// record 记录执行结果，失败只记录日志，失败任务由巡检补充处理
+func (s *RetryTaskService) record(ctx context.Context, id uint) {
+	if err := s.retryService.HandleTaskFinished(ctx, id); err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Warn("Failed to record task attempt")
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/llm"
+	"drone-control-system/pkg/logger"
+	"drone-control-system/pkg/planner"
+)
+
+// RouteConfig 航线规划配置
+type RouteConfig struct {
+	SafetyBuffer   float64 // 与禁飞区和障碍物的水平安全距离（米）
+	VerticalBuffer float64 // 与区域高度范围的垂直安全距离（米）
+	MaxAltitude    float64 // 最大飞行高度（米）
+	CircleSegments int     // 圆形围栏转换为多边形的边数
+	MaxNodes       int     // 可见图节点上限
+}
+
+// DefaultRouteConfig 默认航线规划配置
+func DefaultRouteConfig() *RouteConfig {
+	return &RouteConfig{
+		SafetyBuffer:   30,
+		VerticalBuffer: 10,
+		MaxAltitude:    120,
+		CircleSegments: planner.DefaultCircleSegments,
+		MaxNodes:       2000,
+	}
+}
+
+// RouteParams 航线规划或校验参数
+type RouteParams struct {
+	Waypoints models.WaypointList `json:"waypoints"`
+	At        *time.Time          `json:"at"` // 按该时间生效的围栏规划，为空或已过去时使用当前时间
+
+	// 围栏之外的额外限制，通常来自环境感知或LLM规划上下文
+	Obstacles []llm.Obstacle `json:"obstacles"`
+	Zones     []llm.Zone     `json:"zones"`
+}
+
+// RouteResult 航线规划或校验结果
+type RouteResult struct {
+	Waypoints  models.WaypointList `json:"waypoints"`
+	Inserted   int                 `json:"inserted"` // 插入的绕行航点数
+	Distance   float64             `json:"distance"` // 水平航程（米）
+	Safe       bool                `json:"safe"`
+	Violations []planner.Violation `json:"violations,omitempty"`
+}
+
+// RouteService 航线规划服务接口
+type RouteService interface {
+	// PlanRoute 在航点之间插入绕行点，返回与所有限制区域保持安全距离的航线
+	PlanRoute(ctx context.Context, params *RouteParams) (*RouteResult, error)
+	// VerifyRoute 校验航线（包括LLM给出的航线）是否与限制区域保持安全距离，不修改航线
+	VerifyRoute(ctx context.Context, params *RouteParams) (*RouteResult, error)
+}
+
+// RouteServiceImpl 航线规划服务实现
+type RouteServiceImpl struct {
+	config          *RouteConfig
+	geofenceService GeofenceService
+	logger          *logger.Logger
+}
+
+// NewRouteService 创建航线规划服务
+func NewRouteService(config *RouteConfig, geofenceService GeofenceService, logger *logger.Logger) RouteService {
+	if config == nil {
+		config = DefaultRouteConfig()
+	}
+
+	return &RouteServiceImpl{
+		config:          config,
+		geofenceService: geofenceService,
+		logger:          logger,
+	}
+}
+
+// PlanRoute 规划避障航线
+func (s *RouteServiceImpl) PlanRoute(ctx context.Context, params *RouteParams) (*RouteResult, error) {
+	p, err := s.planner(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	route, err := p.Plan(toPlannerWaypoints(params.Waypoints))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNoRoute, err)
+	}
+
+	waypoints := fromPlannerRoute(route, params.Waypoints)
+	result := &RouteResult{
+		Waypoints: waypoints,
+		Inserted:  len(waypoints) - len(params.Waypoints),
+		Distance:  routeDistance(waypoints),
+		Safe:      true,
+	}
+	if result.Inserted > 0 {
+		s.logger.WithFields(map[string]interface{}{
+			"waypoints": len(params.Waypoints),
+			"inserted":  result.Inserted,
+			"distance":  result.Distance,
+		}).Info("Route planned around restricted regions")
+	}
+	return result, nil
+}
+
+// VerifyRoute 校验航线
+func (s *RouteServiceImpl) VerifyRoute(ctx context.Context, params *RouteParams) (*RouteResult, error) {
+	p, err := s.planner(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	violations := p.Verify(toPlannerWaypoints(params.Waypoints))
+	return &RouteResult{
+		Waypoints:  params.Waypoints,
+		Distance:   routeDistance(params.Waypoints),
+		Safe:       len(violations) == 0,
+		Violations: violations,
+	}, nil
+}
+
+// planner 按生效的禁飞区、限飞区和额外障碍物构建规划器
+func (s *RouteServiceImpl) planner(ctx context.Context, params *RouteParams) (*planner.Planner, error) {
+	if params == nil || len(params.Waypoints) == 0 {
+		return nil, fmt.Errorf("%w: waypoints are required", ErrInvalidData)
+	}
+	if len(params.Waypoints) > models.MaxMissionWaypoints {
+		return nil, fmt.Errorf("%w: too many waypoints (%d > %d)", ErrInvalidData, len(params.Waypoints), models.MaxMissionWaypoints)
+	}
+
+	fences, err := s.geofenceService.ActiveGeofences(ctx, plannedAt(params.At))
+	if err != nil {
+		return nil, err
+	}
+
+	regions := make([]planner.Region, 0, len(fences)+len(params.Obstacles)+len(params.Zones))
+	for _, fence := range fences {
+		if fence.Type.BlocksPlans() {
+			regions = append(regions, s.geofenceRegion(fence))
+		}
+	}
+	regions = append(regions, planner.FromEnvironment(llm.EnvironmentState{
+		Obstacles:  params.Obstacles,
+		NoFlyZones: params.Zones,
+	})...)
+
+	return planner.New(planner.Config{
+		SafetyBuffer:   s.config.SafetyBuffer,
+		VerticalBuffer: s.config.VerticalBuffer,
+		MaxAltitude:    s.config.MaxAltitude,
+		MaxNodes:       s.config.MaxNodes,
+	}, regions), nil
+}
+
+// geofenceRegion 将围栏转换为规划区域，圆形围栏使用外接多边形
+func (s *RouteServiceImpl) geofenceRegion(fence *models.Geofence) planner.Region {
+	region := planner.Region{
+		Name:        fmt.Sprintf("geofence %d (%s)", fence.ID, fence.Name),
+		MinAltitude: fence.MinAltitude,
+		MaxAltitude: fence.MaxAltitude,
+	}
+	if fence.Shape == models.GeofenceShapeCircle {
+		center := geo.Point{Latitude: fence.CenterLatitude, Longitude: fence.CenterLongitude}
+		region.Polygon = planner.Circle(center, fence.Radius, s.config.CircleSegments)
+	} else {
+		region.Polygon = fence.Vertices
+	}
+	return region
+}
+
+// toPlannerWaypoints 转换为规划器航点
+func toPlannerWaypoints(waypoints models.WaypointList) []planner.Waypoint {
+	result := make([]planner.Waypoint, len(waypoints))
+	for i, wp := range waypoints {
+		result[i] = planner.Waypoint{
+			Latitude:  wp.Latitude,
+			Longitude: wp.Longitude,
+			Altitude:  wp.Altitude,
+			Source:    i,
+		}
+	}
+	return result
+}
+
+// fromPlannerRoute 还原任务航点：原航点保留动作等属性，绕行点使用下一个原航点的速度
+func fromPlannerRoute(route []planner.Waypoint, original models.WaypointList) models.WaypointList {
+	result := make(models.WaypointList, len(route))
+	detour := 0
+	for i, wp := range route {
+		if wp.Source >= 0 {
+			result[i] = original[wp.Source]
+			continue
+		}
+		detour++
+		result[i] = models.Waypoint{
+			Latitude:  wp.Latitude,
+			Longitude: wp.Longitude,
+			Altitude:  wp.Altitude,
+			Name:      fmt.Sprintf("detour-%d", detour),
+		}
+	}
+
+	speed := 0.0
+	for i := len(route) - 1; i >= 0; i-- {
+		if route[i].Source >= 0 {
+			speed = result[i].Speed
+		} else {
+			result[i].Speed = speed
+		}
+	}
+	return result
+}
+
+// routeDistance 航线水平航程
+func routeDistance(waypoints models.WaypointList) float64 {
+	points := make([]geo.Point, len(waypoints))
+	for i, wp := range waypoints {
+		points[i] = geo.Point{Latitude: wp.Latitude, Longitude: wp.Longitude}
+	}
+	return geo.PathLength(points)
+}
+
+// RouteTaskService 在创建或修改任务计划时按需自动规划绕行航线的任务服务装饰器
+type RouteTaskService struct {
+	TaskService
+	routeService RouteService
+}
+
+// NewRouteTaskService 创建自动航线规划任务服务
+func NewRouteTaskService(next TaskService, routeService RouteService) TaskService {
+	return &RouteTaskService{
+		TaskService:  next,
+		routeService: routeService,
+	}
+}
+
+// CreateTask 设置 AutoRoute 时先规划航线
+func (s *RouteTaskService) CreateTask(ctx context.Context, params *CreateTaskParams) (*models.Task, error) {
+	if params.AutoRoute && len(params.Plan.Waypoints) > 0 {
+		result, err := s.routeService.PlanRoute(ctx, &RouteParams{
+			Waypoints: params.Plan.Waypoints,
+			At:        params.ScheduledAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		params.Plan.Waypoints = result.Waypoints
+	}
+	return s.TaskService.CreateTask(ctx, params)
+}
+
+// UpdateTask 修改计划且设置 AutoRoute 时先规划航线
+func (s *RouteTaskService) UpdateTask(ctx context.Context, id uint, params *UpdateTaskParams) (*models.Task, error) {
+	if params.AutoRoute && params.Plan != nil && len(params.Plan.Waypoints) > 0 {
+		scheduledAt := params.ScheduledAt
+		if scheduledAt == nil {
+			task, err := s.TaskService.GetTaskByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			scheduledAt = task.ScheduledAt
+		}
+
+		result, err := s.routeService.PlanRoute(ctx, &RouteParams{
+			Waypoints: params.Plan.Waypoints,
+			At:        scheduledAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		params.Plan.Waypoints = result.Waypoints
+	}
+	return s.TaskService.UpdateTask(ctx, id, params)
+}
@@ -0,0 +1,832 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/cron"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// schedulerLeaderKey 任务调度主节点锁
+	schedulerLeaderKey = "task:scheduler:leader"
+
+	// maxMissedEnumeration 计算错过执行次数时的最大枚举数
+	maxMissedEnumeration = 10000
+)
+
+// SchedulerConfig 任务调度配置
+type SchedulerConfig struct {
+	CheckInterval      time.Duration          `yaml:"check_interval" json:"check_interval"`             // 调度检查间隔
+	MissedRunTolerance time.Duration          `yaml:"missed_run_tolerance" json:"missed_run_tolerance"` // 晚于计划时间超过该值视为错过执行
+	StartRetryWindow   time.Duration          `yaml:"start_retry_window" json:"start_retry_window"`     // 启动失败后的重试时间窗口，超出后任务标记为失败
+	MaxCatchUpRuns     int                    `yaml:"max_catch_up_runs" json:"max_catch_up_runs"`       // run_all 策略单次最多补执行次数
+	DefaultPolicy      models.MissedRunPolicy `yaml:"default_policy" json:"default_policy"`             // 单次定时任务错过执行时的策略
+	LockTTL            time.Duration          `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultSchedulerConfig 默认任务调度配置
+func DefaultSchedulerConfig() *SchedulerConfig {
+	return &SchedulerConfig{
+		CheckInterval:      30 * time.Second,
+		MissedRunTolerance: 5 * time.Minute,
+		StartRetryWindow:   15 * time.Minute,
+		MaxCatchUpRuns:     10,
+		DefaultPolicy:      models.MissedRunOnce,
+		LockTTL:            time.Minute,
+	}
+}
+
+// TaskScheduleParams 创建或更新周期计划参数
+type TaskScheduleParams struct {
+	Name      string                 `json:"name"`
+	CronExpr  string                 `json:"cron_expr"`
+	Timezone  string                 `json:"timezone"`
+	Enabled   *bool                  `json:"enabled"`
+	Policy    models.MissedRunPolicy `json:"missed_run_policy"`
+	StartAt   *time.Time             `json:"start_at"`
+	EndAt     *time.Time             `json:"end_at"`
+	CreatedBy uint                   `json:"created_by"`
+
+	TaskName             string                         `json:"task_name"`
+	TaskDescription      string                         `json:"task_description"`
+	TaskType             models.TaskType                `json:"task_type"`
+	TaskPriority         models.TaskPriority            `json:"task_priority"`
+	DroneID              uint                           `json:"drone_id"`
+	Plan                 *models.TaskPlan               `json:"plan"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities"`
+	Delivery             *models.TaskDelivery           `json:"delivery"` // 配送任务必填
+}
+
+// ListTaskSchedulesParams 周期计划列表参数
+type ListTaskSchedulesParams struct {
+	Offset  int         `json:"offset"`
+	Limit   int         `json:"limit"`
+	DroneID uint        `json:"drone_id"`
+	Enabled *bool       `json:"enabled"`
+	Scope   *FleetScope `json:"scope"` // 调用者可访问的机队范围，为nil时不限制
+}
+
+// TaskScheduler 任务调度服务接口
+// 调度器将带计划时间的待处理任务置为已调度，到点通过 TaskService.StartTask 启动，
+// 并按周期计划生成任务实例。多实例部署时只有主节点执行调度。
+type TaskScheduler interface {
+	// 周期计划管理
+	CreateSchedule(ctx context.Context, params *TaskScheduleParams) (*models.TaskSchedule, error)
+	GetSchedule(ctx context.Context, id uint) (*models.TaskSchedule, error)
+	ListSchedules(ctx context.Context, params *ListTaskSchedulesParams) ([]*models.TaskSchedule, int64, error)
+	// UpdateSchedule 更新计划，零值字段保持不变；修改cron规则或重新启用时重新计算下次执行时间
+	UpdateSchedule(ctx context.Context, id uint, params *TaskScheduleParams) (*models.TaskSchedule, error)
+	DeleteSchedule(ctx context.Context, id uint) error
+
+	// PreviewRuns 预览cron规则接下来的执行时间
+	PreviewRuns(cronExpr, timezone string, count int) ([]time.Time, error)
+
+	// 服务管理
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// TaskSchedulerImpl 任务调度服务实现
+type TaskSchedulerImpl struct {
+	config       *SchedulerConfig
+	db           *gorm.DB
+	election     *database.LeaderElection
+	taskService  TaskService
+	kafkaService KafkaService
+	logger       *logger.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewTaskScheduler 创建任务调度服务
+func NewTaskScheduler(
+	config *SchedulerConfig,
+	db *gorm.DB,
+	lockService *database.LockService,
+	taskService TaskService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) TaskScheduler {
+	if config == nil {
+		config = DefaultSchedulerConfig()
+	}
+
+	return &TaskSchedulerImpl{
+		config:       config,
+		db:           db,
+		election:     database.NewLeaderElection(lockService, schedulerLeaderKey, config.LockTTL),
+		taskService:  taskService,
+		kafkaService: kafkaService,
+		logger:       logger,
+	}
+}
+
+// CreateSchedule 创建周期计划
+func (s *TaskSchedulerImpl) CreateSchedule(ctx context.Context, params *TaskScheduleParams) (*models.TaskSchedule, error) {
+	if params == nil || strings.TrimSpace(params.Name) == "" || strings.TrimSpace(params.TaskName) == "" {
+		return nil, fmt.Errorf("%w: name and task_name are required", ErrInvalidData)
+	}
+	if params.TaskType == "" || params.DroneID == 0 || params.CreatedBy == 0 {
+		return nil, fmt.Errorf("%w: task_type, drone_id and created_by are required", ErrInvalidData)
+	}
+
+	schedule := &models.TaskSchedule{
+		Name:            params.Name,
+		CronExpr:        strings.TrimSpace(params.CronExpr),
+		Timezone:        params.Timezone,
+		Enabled:         true,
+		Policy:          params.Policy,
+		StartAt:         params.StartAt,
+		EndAt:           params.EndAt,
+		TaskName:        params.TaskName,
+		TaskDescription: params.TaskDescription,
+		TaskType:        params.TaskType,
+		TaskPriority:    params.TaskPriority,
+		DroneID:         params.DroneID,
+		CreatedBy:       params.CreatedBy,
+	}
+	if params.Enabled != nil {
+		schedule.Enabled = *params.Enabled
+	}
+	if schedule.Policy == "" {
+		schedule.Policy = models.MissedRunOnce
+	}
+	if schedule.TaskPriority == "" {
+		schedule.TaskPriority = models.TaskPriorityNormal
+	}
+	if params.Plan != nil {
+		schedule.Plan = *params.Plan
+	}
+	if err := setScheduleCapabilities(schedule, params.RequiredCapabilities); err != nil {
+		return nil, err
+	}
+	if err := setScheduleDelivery(schedule, params.Delivery); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkMission(ctx, schedule); err != nil {
+		return nil, err
+	}
+	if err := s.prepareSchedule(schedule, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Create(schedule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create task schedule: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"schedule_id": schedule.ID,
+		"cron":        schedule.CronExpr,
+		"next_run_at": schedule.NextRunAt,
+	}).Info("Task schedule created")
+	return schedule, nil
+}
+
+// GetSchedule 获取周期计划
+func (s *TaskSchedulerImpl) GetSchedule(ctx context.Context, id uint) (*models.TaskSchedule, error) {
+	var schedule models.TaskSchedule
+	if err := s.db.WithContext(ctx).First(&schedule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScheduleNotFound
+		}
+		return nil, fmt.Errorf("failed to get task schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+// ListSchedules 获取周期计划列表
+func (s *TaskSchedulerImpl) ListSchedules(ctx context.Context, params *ListTaskSchedulesParams) ([]*models.TaskSchedule, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.TaskSchedule{})
+	if params.DroneID != 0 {
+		query = query.Where("task_schedules.drone_id = ?", params.DroneID)
+	}
+	if params.Enabled != nil {
+		query = query.Where("task_schedules.enabled = ?", *params.Enabled)
+	}
+	if scope := params.Scope; scope != nil {
+		query = query.Joins("JOIN drones ON drones.id = task_schedules.drone_id")
+		switch {
+		case len(scope.FleetIDs) > 0 && scope.IncludeUnassigned:
+			query = query.Where("drones.fleet_id IN ? OR drones.fleet_id IS NULL", scope.FleetIDs)
+		case len(scope.FleetIDs) > 0:
+			query = query.Where("drones.fleet_id IN ?", scope.FleetIDs)
+		case scope.IncludeUnassigned:
+			query = query.Where("drones.fleet_id IS NULL")
+		default:
+			return []*models.TaskSchedule{}, 0, nil
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count task schedules: %w", err)
+	}
+
+	var schedules []*models.TaskSchedule
+	if err := query.Order("task_schedules.id DESC").Offset(params.Offset).Limit(params.Limit).Find(&schedules).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list task schedules: %w", err)
+	}
+	return schedules, total, nil
+}
+
+// UpdateSchedule 更新周期计划
+func (s *TaskSchedulerImpl) UpdateSchedule(ctx context.Context, id uint, params *TaskScheduleParams) (*models.TaskSchedule, error) {
+	schedule, err := s.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	reschedule := false
+	if params.Name != "" {
+		schedule.Name = params.Name
+	}
+	if params.CronExpr != "" && params.CronExpr != schedule.CronExpr {
+		schedule.CronExpr = strings.TrimSpace(params.CronExpr)
+		reschedule = true
+	}
+	if params.Timezone != "" && params.Timezone != schedule.Timezone {
+		schedule.Timezone = params.Timezone
+		reschedule = true
+	}
+	if params.Enabled != nil && *params.Enabled != schedule.Enabled {
+		schedule.Enabled = *params.Enabled
+		// 重新启用时从当前时间开始，不补执行停用期间的计划
+		reschedule = reschedule || schedule.Enabled
+	}
+	if params.Policy != "" {
+		schedule.Policy = params.Policy
+	}
+	if params.StartAt != nil {
+		schedule.StartAt = params.StartAt
+		reschedule = true
+	}
+	if params.EndAt != nil {
+		schedule.EndAt = params.EndAt
+		reschedule = true
+	}
+	if params.TaskName != "" {
+		schedule.TaskName = params.TaskName
+	}
+	if params.TaskDescription != "" {
+		schedule.TaskDescription = params.TaskDescription
+	}
+	if params.TaskType != "" {
+		schedule.TaskType = params.TaskType
+	}
+	if params.TaskPriority != "" {
+		schedule.TaskPriority = params.TaskPriority
+	}
+	missionChanged := params.Plan != nil
+	if params.DroneID != 0 && params.DroneID != schedule.DroneID {
+		schedule.DroneID = params.DroneID
+		missionChanged = true
+	}
+	if params.Plan != nil {
+		schedule.Plan = *params.Plan
+	}
+	if missionChanged {
+		if err := s.checkMission(ctx, schedule); err != nil {
+			return nil, err
+		}
+	}
+	if params.RequiredCapabilities != nil {
+		if err := setScheduleCapabilities(schedule, params.RequiredCapabilities); err != nil {
+			return nil, err
+		}
+	}
+	if err := setScheduleDelivery(schedule, params.Delivery); err != nil {
+		return nil, err
+	}
+
+	if reschedule {
+		schedule.NextRunAt = nil
+	}
+	if err := s.prepareSchedule(schedule, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Save(schedule).Error; err != nil {
+		return nil, fmt.Errorf("failed to update task schedule: %w", err)
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule 删除周期计划，已生成的任务实例保留
+func (s *TaskSchedulerImpl) DeleteSchedule(ctx context.Context, id uint) error {
+	res := s.db.WithContext(ctx).Delete(&models.TaskSchedule{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete task schedule: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+// PreviewRuns 预览cron规则接下来的执行时间
+func (s *TaskSchedulerImpl) PreviewRuns(cronExpr, timezone string, count int) ([]time.Time, error) {
+	if count <= 0 || count > 50 {
+		count = 5
+	}
+
+	preview := &models.TaskSchedule{CronExpr: cronExpr, Timezone: timezone}
+	spec, loc, err := parseSchedule(preview)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]time.Time, 0, count)
+	for t := spec.Next(time.Now().In(loc)); !t.IsZero() && len(runs) < count; t = spec.Next(t) {
+		runs = append(runs, t)
+	}
+	return runs, nil
+}
+
+// Start 启动调度
+func (s *TaskSchedulerImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.scheduleLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"check_interval": s.config.CheckInterval.String(),
+		"instance":       s.election.Identity(),
+	}).Info("Task scheduler started")
+	return nil
+}
+
+// Stop 停止调度并释放主节点锁
+func (s *TaskSchedulerImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release task scheduler leader lock")
+	}
+
+	s.logger.Info("Task scheduler stopped")
+	return nil
+}
+
+// scheduleLoop 周期执行调度
+func (s *TaskSchedulerImpl) scheduleLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Task scheduler leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+
+			now := time.Now()
+			s.spawnRecurring(s.ctx, now)
+			s.promotePending(s.ctx)
+			s.startDue(s.ctx, now)
+		}
+	}
+}
+
+// spawnRecurring 为到期的周期计划生成任务实例
+func (s *TaskSchedulerImpl) spawnRecurring(ctx context.Context, now time.Time) {
+	var schedules []*models.TaskSchedule
+	if err := s.db.WithContext(ctx).
+		Where("enabled = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Find(&schedules).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to load due task schedules")
+		return
+	}
+
+	for _, schedule := range schedules {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.runSchedule(ctx, schedule, now); err != nil {
+			s.logger.WithError(err).WithField("schedule_id", schedule.ID).Error("Failed to run task schedule")
+		}
+	}
+}
+
+// runSchedule 按错过执行策略生成实例并推进下次执行时间
+func (s *TaskSchedulerImpl) runSchedule(ctx context.Context, schedule *models.TaskSchedule, now time.Time) error {
+	spec, loc, err := parseSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	// 计划时间在 [next_run_at, now] 内的所有执行
+	due := append([]time.Time{*schedule.NextRunAt}, spec.Between(schedule.NextRunAt.In(loc), now.In(loc), maxMissedEnumeration)...)
+	if schedule.EndAt != nil {
+		for len(due) > 0 && due[len(due)-1].After(*schedule.EndAt) {
+			due = due[:len(due)-1]
+		}
+	}
+
+	runs, skipped := s.selectRuns(schedule.Policy, due, now)
+
+	// 先推进下次执行时间再生成实例，主节点切换时不会重复生成
+	next := s.nextRun(schedule, spec, loc, now)
+	updates := map[string]interface{}{
+		"next_run_at": next,
+		"run_count":   gorm.Expr("run_count + ?", len(runs)),
+		"skip_count":  gorm.Expr("skip_count + ?", skipped),
+	}
+	if len(runs) > 0 {
+		updates["last_run_at"] = now
+	}
+	res := s.db.WithContext(ctx).Model(&models.TaskSchedule{}).
+		Where("id = ? AND next_run_at = ?", schedule.ID, *schedule.NextRunAt).
+		Updates(updates)
+	if res.Error != nil {
+		return fmt.Errorf("failed to advance task schedule: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+
+	if skipped > 0 {
+		s.logger.WithFields(map[string]interface{}{
+			"schedule_id": schedule.ID,
+			"policy":      schedule.Policy,
+			"skipped":     skipped,
+		}).Warn("Skipped missed task schedule runs")
+	}
+
+	var lastTaskID uint
+	for _, runAt := range runs {
+		task, err := s.spawnTask(ctx, schedule, runAt)
+		if err != nil {
+			s.logger.WithError(err).WithFields(map[string]interface{}{
+				"schedule_id": schedule.ID,
+				"run_at":      runAt,
+			}).Error("Failed to create scheduled task instance")
+			continue
+		}
+		lastTaskID = task.ID
+	}
+	if lastTaskID != 0 {
+		if err := s.db.WithContext(ctx).Model(&models.TaskSchedule{}).Where("id = ?", schedule.ID).
+			Update("last_task_id", lastTaskID).Error; err != nil {
+			s.logger.WithError(err).WithField("schedule_id", schedule.ID).Warn("Failed to record last scheduled task")
+		}
+	}
+	return nil
+}
+
+// selectRuns 根据错过执行策略选出需要生成的实例计划时间，返回跳过的次数
+// 准时的执行按原计划时间生成；补执行的实例计划时间为当前时间
+func (s *TaskSchedulerImpl) selectRuns(policy models.MissedRunPolicy, due []time.Time, now time.Time) ([]time.Time, int) {
+	var onTime, missed []time.Time
+	for _, t := range due {
+		if now.Sub(t) <= s.config.MissedRunTolerance {
+			onTime = append(onTime, t)
+		} else {
+			missed = append(missed, t)
+		}
+	}
+
+	var runs []time.Time
+	if len(onTime) > 0 {
+		// 检查间隔内有多次准时执行时只取最近一次
+		runs = append(runs, onTime[len(onTime)-1])
+	}
+
+	skipped := len(onTime) - len(runs)
+	switch policy {
+	case models.MissedRunCatchUp:
+		n := len(missed)
+		if n > s.config.MaxCatchUpRuns {
+			n = s.config.MaxCatchUpRuns
+		}
+		for i := 0; i < n; i++ {
+			runs = append(runs, now)
+		}
+		skipped += len(missed) - n
+	case models.MissedRunOnce:
+		if len(missed) > 0 && len(runs) == 0 {
+			runs = append(runs, now)
+			skipped += len(missed) - 1
+		} else {
+			skipped += len(missed)
+		}
+	default:
+		skipped += len(missed)
+	}
+	return runs, skipped
+}
+
+// nextRun 计算下次执行时间，超出生效区间时返回nil
+func (s *TaskSchedulerImpl) nextRun(schedule *models.TaskSchedule, spec *cron.Schedule, loc *time.Location, now time.Time) *time.Time {
+	from := now
+	if schedule.StartAt != nil && schedule.StartAt.After(from) {
+		// Next 返回严格晚于from的时间，回退一分钟使StartAt本身可以命中
+		from = schedule.StartAt.Add(-time.Minute)
+	}
+
+	next := spec.Next(from.In(loc))
+	if next.IsZero() || (schedule.EndAt != nil && next.After(*schedule.EndAt)) {
+		return nil
+	}
+	return &next
+}
+
+// spawnTask 按计划的任务定义生成任务实例
+func (s *TaskSchedulerImpl) spawnTask(ctx context.Context, schedule *models.TaskSchedule, runAt time.Time) (*models.Task, error) {
+	params := &CreateTaskParams{
+		Name:        fmt.Sprintf("%s %s", schedule.TaskName, runAt.Format("2006-01-02 15:04")),
+		Description: schedule.TaskDescription,
+		Type:        schedule.TaskType,
+		Priority:    schedule.TaskPriority,
+		UserID:      schedule.CreatedBy,
+		DroneID:     schedule.DroneID,
+		Plan:        schedule.Plan,
+		ScheduledAt: &runAt,
+		ScheduleID:  &schedule.ID,
+	}
+	if schedule.RequiredCapabilities != "" {
+		var req models.CapabilityRequirements
+		if err := json.Unmarshal([]byte(schedule.RequiredCapabilities), &req); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCapabilities, err)
+		}
+		params.RequiredCapabilities = &req
+	}
+	if !schedule.TaskDelivery.IsZero() {
+		delivery := schedule.TaskDelivery
+		params.Delivery = &delivery
+	}
+
+	return s.taskService.CreateTask(ctx, params)
+}
+
+// promotePending 将设置了计划时间的待处理任务置为已调度
+func (s *TaskSchedulerImpl) promotePending(ctx context.Context) {
+	var tasks []*models.Task
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND scheduled_at IS NOT NULL", models.TaskStatusPending).
+		Find(&tasks).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to load pending scheduled tasks")
+		return
+	}
+
+	for _, task := range tasks {
+		res := s.db.WithContext(ctx).Model(&models.Task{}).
+			Where("id = ? AND status = ?", task.ID, models.TaskStatusPending).
+			Update("status", models.TaskStatusScheduled)
+		if res.Error != nil {
+			s.logger.WithError(res.Error).WithField("task_id", task.ID).Error("Failed to mark task scheduled")
+			continue
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+
+		s.publishScheduled(ctx, task)
+	}
+}
+
+// startDue 启动到达计划时间的任务
+func (s *TaskSchedulerImpl) startDue(ctx context.Context, now time.Time) {
+	var tasks []*models.Task
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND scheduled_at <= ?", models.TaskStatusScheduled, now).
+		Order("scheduled_at").Find(&tasks).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to load due scheduled tasks")
+		return
+	}
+
+	policies := make(map[uint]models.MissedRunPolicy)
+	for _, task := range tasks {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 停机期间错过的任务按策略跳过
+		if now.Sub(*task.ScheduledAt) > s.config.MissedRunTolerance && s.missedPolicy(ctx, task, policies) == models.MissedRunSkip {
+			s.finishUnstarted(ctx, task, models.TaskStatusCancelled, "SCHEDULE_MISSED", "scheduled run was missed and skipped by policy")
+			continue
+		}
+
+		if err := s.taskService.StartTask(ctx, task.ID); err != nil {
+			fields := map[string]interface{}{
+				"task_id":      task.ID,
+				"drone_id":     task.DroneID,
+				"scheduled_at": task.ScheduledAt,
+			}
+			if now.Sub(*task.ScheduledAt) > s.config.StartRetryWindow {
+				s.logger.WithError(err).WithFields(fields).Error("Scheduled task could not be started, giving up")
+				s.finishUnstarted(ctx, task, models.TaskStatusFailed, "SCHEDULE_START_FAILED", err.Error())
+				continue
+			}
+			// 无人机忙碌等情况下一轮重试
+			s.logger.WithError(err).WithFields(fields).Warn("Failed to start scheduled task, will retry")
+			continue
+		}
+
+		s.logger.WithFields(map[string]interface{}{
+			"task_id":      task.ID,
+			"schedule_id":  task.ScheduleID,
+			"scheduled_at": task.ScheduledAt,
+		}).Info("Scheduled task started")
+	}
+}
+
+// missedPolicy 任务错过执行时的策略：周期实例使用计划的策略，单次任务使用默认策略
+func (s *TaskSchedulerImpl) missedPolicy(ctx context.Context, task *models.Task, cache map[uint]models.MissedRunPolicy) models.MissedRunPolicy {
+	if task.ScheduleID == nil {
+		return s.config.DefaultPolicy
+	}
+	if policy, ok := cache[*task.ScheduleID]; ok {
+		return policy
+	}
+
+	policy := s.config.DefaultPolicy
+	if schedule, err := s.GetSchedule(ctx, *task.ScheduleID); err == nil {
+		policy = schedule.Policy
+	}
+	cache[*task.ScheduleID] = policy
+	return policy
+}
+
+// finishUnstarted 结束未能启动的已调度任务
+func (s *TaskSchedulerImpl) finishUnstarted(ctx context.Context, task *models.Task, status models.TaskStatus, code, message string) {
+	res := s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("id = ? AND status = ?", task.ID, models.TaskStatusScheduled).
+		Updates(map[string]interface{}{
+			"status":              status,
+			"completed_at":        time.Now(),
+			"result_success":      false,
+			"result_error_code":   code,
+			"result_error_detail": message,
+		})
+	if res.Error != nil {
+		s.logger.WithError(res.Error).WithField("task_id", task.ID).Error("Failed to finish unstarted task")
+		return
+	}
+	if res.RowsAffected == 0 || s.kafkaService == nil {
+		return
+	}
+
+	eventType := kafka.TaskFailedEvent
+	if status == models.TaskStatusCancelled {
+		eventType = kafka.TaskCancelledEvent
+	}
+	if err := s.kafkaService.PublishTaskEvent(ctx, eventType, map[string]interface{}{
+		"task_id":    task.ID,
+		"drone_id":   task.DroneID,
+		"status":     status,
+		"error_code": code,
+		"message":    message,
+		"timestamp":  time.Now(),
+	}); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to publish task event")
+	}
+}
+
+// publishScheduled 发布任务进入调度事件
+func (s *TaskSchedulerImpl) publishScheduled(ctx context.Context, task *models.Task) {
+	if s.kafkaService == nil {
+		return
+	}
+
+	if err := s.kafkaService.PublishTaskEvent(ctx, kafka.TaskScheduledEvent, kafka.TaskScheduledEventData{
+		TaskID:      task.ID,
+		TaskName:    task.Name,
+		DroneID:     task.DroneID,
+		ScheduleID:  task.ScheduleID,
+		ScheduledAt: *task.ScheduledAt,
+		Timestamp:   time.Now(),
+	}); err != nil {
+		s.logger.WithError(err).WithField("task_id", task.ID).Warn("Failed to publish task scheduled event")
+	}
+}
+
+// prepareSchedule 校验计划并计算下次执行时间（已有下次执行时间时保持不变）
+func (s *TaskSchedulerImpl) prepareSchedule(schedule *models.TaskSchedule, now time.Time) error {
+	if !schedule.Policy.IsValid() {
+		return fmt.Errorf("%w: unknown missed run policy: %s", ErrInvalidData, schedule.Policy)
+	}
+	if schedule.StartAt != nil && schedule.EndAt != nil && !schedule.EndAt.After(*schedule.StartAt) {
+		return fmt.Errorf("%w: end_at must be after start_at", ErrInvalidData)
+	}
+
+	spec, loc, err := parseSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	if !schedule.Enabled {
+		schedule.NextRunAt = nil
+		return nil
+	}
+	if schedule.NextRunAt == nil {
+		schedule.NextRunAt = s.nextRun(schedule, spec, loc, now)
+	}
+	return nil
+}
+
+// checkMission 检查无人机是否存在，并按机型限制校验计划航线
+func (s *TaskSchedulerImpl) checkMission(ctx context.Context, schedule *models.TaskSchedule) error {
+	var drone models.Drone
+	if err := s.db.WithContext(ctx).First(&drone, schedule.DroneID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrDroneNotFound
+		}
+		return fmt.Errorf("failed to check drone: %w", err)
+	}
+	return CheckMissionPlan(&schedule.Plan, &drone)
+}
+
+// parseSchedule 解析计划的cron规则和时区
+func parseSchedule(schedule *models.TaskSchedule) (*cron.Schedule, *time.Location, error) {
+	spec, err := cron.Parse(schedule.CronExpr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	loc, err := schedule.Location()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: unknown timezone: %s", ErrInvalidSchedule, schedule.Timezone)
+	}
+	return spec, loc, nil
+}
+
+// setScheduleDelivery 设置计划任务的配送信息，配送任务必须有配送信息，其他类型不允许设置
+// 详细校验在生成任务实例时由配送任务服务完成
+func setScheduleDelivery(schedule *models.TaskSchedule, delivery *models.TaskDelivery) error {
+	if delivery != nil {
+		schedule.TaskDelivery = *delivery
+	}
+	if schedule.TaskType != models.TaskTypeDelivery {
+		if !schedule.TaskDelivery.IsZero() {
+			return fmt.Errorf("%w: delivery details are only allowed for delivery tasks", ErrInvalidSchedule)
+		}
+		return nil
+	}
+	if schedule.TaskDelivery.IsZero() {
+		return fmt.Errorf("%w: delivery tasks require delivery details", ErrInvalidSchedule)
+	}
+	return nil
+}
+
+// setScheduleCapabilities 校验并保存计划任务的能力要求
+func setScheduleCapabilities(schedule *models.TaskSchedule, req *models.CapabilityRequirements) error {
+	if req == nil || req.IsEmpty() {
+		schedule.RequiredCapabilities = ""
+		return nil
+	}
+	if err := req.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCapabilities, err)
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCapabilities, err)
+	}
+	schedule.RequiredCapabilities = string(data)
+	return nil
+}
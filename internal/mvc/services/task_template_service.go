@@ -0,0 +1,728 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxTemplateParameters 单个模板允许的最大参数数
+const maxTemplateParameters = 30
+
+var (
+	// templatePlaceholder 参数占位符 ${name} 或 ${name.path}
+	templatePlaceholder = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)((?:\.[A-Za-z0-9_]+)*)\}`)
+	// templateParameterName 合法的参数名
+	templateParameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// TaskTemplateParams 创建或克隆任务模板参数，克隆时零值字段沿用来源版本
+type TaskTemplateParams struct {
+	Name                 string                       `json:"name"`
+	Description          string                       `json:"description"`
+	TaskName             string                       `json:"task_name"`
+	Type                 models.TaskType              `json:"type"`
+	Priority             models.TaskPriority          `json:"priority"`
+	Plan                 models.TemplateBody          `json:"plan"`
+	RequiredCapabilities models.TemplateBody          `json:"required_capabilities"`
+	Delivery             models.TemplateBody          `json:"delivery"`
+	Parameters           models.TemplateParameterList `json:"parameters"`
+	CreatedBy            uint                         `json:"created_by"`
+}
+
+// ListTaskTemplatesParams 任务模板列表参数
+type ListTaskTemplatesParams struct {
+	Offset      int             `json:"offset"`
+	Limit       int             `json:"limit"`
+	Name        string          `json:"name"` // 按模板名称精确过滤
+	Type        models.TaskType `json:"type"`
+	Search      string          `json:"search"`
+	AllVersions bool            `json:"all_versions"` // 为false时每个模板只返回最新版本
+}
+
+// InstantiateTemplateParams 由模板创建任务的参数
+type InstantiateTemplateParams struct {
+	TemplateID  uint                       `json:"template_id"`
+	Values      map[string]json.RawMessage `json:"values"`      // 参数值，未提供的参数使用默认值
+	Name        string                     `json:"name"`        // 为空时使用模板的任务名称
+	Description string                     `json:"description"` // 为空时使用模板描述
+	Priority    models.TaskPriority        `json:"priority"`    // 为空时使用模板优先级
+	UserID      uint                       `json:"user_id"`
+	DroneID     uint                       `json:"drone_id"` // 为0时由分配引擎自动选择
+	ScheduledAt *time.Time                 `json:"scheduled_at"`
+	AutoRoute   bool                       `json:"auto_route"`
+
+	AssignmentScope *FleetScope `json:"assignment_scope"` // 自动分配时可选择的机队范围
+}
+
+// TaskTemplateService 任务模板服务接口
+// 模板版本保存后不再修改，克隆同名模板生成新版本；实例化时替换参数占位符并通过 TaskService 创建任务
+type TaskTemplateService interface {
+	CreateTemplate(ctx context.Context, params *TaskTemplateParams) (*models.TaskTemplate, error)
+	GetTemplate(ctx context.Context, id uint) (*models.TaskTemplate, error)
+	ListTemplates(ctx context.Context, params *ListTaskTemplatesParams) ([]*models.TaskTemplate, int64, error)
+	// CloneTemplate 复制模板版本并应用修改，名称为空或与来源相同时生成新版本，否则生成新模板的第1版
+	CloneTemplate(ctx context.Context, id uint, params *TaskTemplateParams) (*models.TaskTemplate, error)
+	// DeleteTemplate 删除模板版本，已实例化的任务保留模板记录
+	DeleteTemplate(ctx context.Context, id uint) error
+	// Instantiate 按参数值生成任务
+	Instantiate(ctx context.Context, params *InstantiateTemplateParams) (*models.Task, error)
+}
+
+// TaskTemplateServiceImpl 任务模板服务实现
+type TaskTemplateServiceImpl struct {
+	db          *gorm.DB
+	taskService TaskService
+	logger      *logger.Logger
+}
+
+// NewTaskTemplateService 创建任务模板服务
+// taskService 用于创建实例化的任务，需要包含自动分配装饰器以支持未指定无人机的实例化
+func NewTaskTemplateService(db *gorm.DB, taskService TaskService, logger *logger.Logger) TaskTemplateService {
+	return &TaskTemplateServiceImpl{
+		db:          db,
+		taskService: taskService,
+		logger:      logger,
+	}
+}
+
+// CreateTemplate 创建模板的第1版，同名模板已存在时需克隆生成新版本
+func (s *TaskTemplateServiceImpl) CreateTemplate(ctx context.Context, params *TaskTemplateParams) (*models.TaskTemplate, error) {
+	if params == nil || params.CreatedBy == 0 {
+		return nil, fmt.Errorf("%w: created_by is required", ErrInvalidData)
+	}
+
+	template := &models.TaskTemplate{
+		Name:                 strings.TrimSpace(params.Name),
+		Description:          params.Description,
+		TaskName:             params.TaskName,
+		Type:                 params.Type,
+		Priority:             params.Priority,
+		Plan:                 params.Plan,
+		RequiredCapabilities: params.RequiredCapabilities,
+		Delivery:             params.Delivery,
+		Parameters:           params.Parameters,
+		CreatedBy:            params.CreatedBy,
+	}
+	if err := s.save(ctx, template, true); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"template_id": template.ID,
+		"name":        template.Name,
+		"version":     template.Version,
+	}).Info("Task template created")
+	return template, nil
+}
+
+// GetTemplate 获取模板版本
+func (s *TaskTemplateServiceImpl) GetTemplate(ctx context.Context, id uint) (*models.TaskTemplate, error) {
+	var template models.TaskTemplate
+	if err := s.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get task template: %w", err)
+	}
+	return &template, nil
+}
+
+// ListTemplates 获取模板列表
+func (s *TaskTemplateServiceImpl) ListTemplates(ctx context.Context, params *ListTaskTemplatesParams) ([]*models.TaskTemplate, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.TaskTemplate{})
+	if params.Name != "" {
+		query = query.Where("name = ?", params.Name)
+	}
+	if params.Type != "" {
+		query = query.Where("type = ?", params.Type)
+	}
+	if params.Search != "" {
+		like := "%" + params.Search + "%"
+		query = query.Where("name LIKE ? OR description LIKE ?", like, like)
+	}
+	if !params.AllVersions {
+		latest := s.db.Table("task_templates AS latest").
+			Select("MAX(latest.version)").
+			Where("latest.name = task_templates.name AND latest.deleted_at IS NULL")
+		query = query.Where("task_templates.version = (?)", latest)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count task templates: %w", err)
+	}
+
+	var templates []*models.TaskTemplate
+	if err := query.Order("name, version DESC").Offset(params.Offset).Limit(params.Limit).Find(&templates).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list task templates: %w", err)
+	}
+	return templates, total, nil
+}
+
+// CloneTemplate 克隆模板版本
+func (s *TaskTemplateServiceImpl) CloneTemplate(ctx context.Context, id uint, params *TaskTemplateParams) (*models.TaskTemplate, error) {
+	if params == nil || params.CreatedBy == 0 {
+		return nil, fmt.Errorf("%w: created_by is required", ErrInvalidData)
+	}
+	source, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &models.TaskTemplate{
+		Name:                 source.Name,
+		Description:          source.Description,
+		TaskName:             source.TaskName,
+		Type:                 source.Type,
+		Priority:             source.Priority,
+		Plan:                 source.Plan,
+		RequiredCapabilities: source.RequiredCapabilities,
+		Delivery:             source.Delivery,
+		Parameters:           source.Parameters,
+		SourceID:             &source.ID,
+		CreatedBy:            params.CreatedBy,
+	}
+	if name := strings.TrimSpace(params.Name); name != "" {
+		template.Name = name
+	}
+	if params.Description != "" {
+		template.Description = params.Description
+	}
+	if params.TaskName != "" {
+		template.TaskName = params.TaskName
+	}
+	if params.Type != "" {
+		template.Type = params.Type
+		// 改为非配送类型时不再沿用来源的配送模板
+		if params.Type != models.TaskTypeDelivery && params.Delivery == nil {
+			template.Delivery = nil
+		}
+	}
+	if params.Priority != "" {
+		template.Priority = params.Priority
+	}
+	if params.Plan != nil {
+		template.Plan = params.Plan
+	}
+	if params.RequiredCapabilities != nil {
+		template.RequiredCapabilities = params.RequiredCapabilities
+	}
+	if params.Delivery != nil {
+		template.Delivery = params.Delivery
+	}
+	if params.Parameters != nil {
+		template.Parameters = params.Parameters
+	}
+
+	if err := s.save(ctx, template, template.Name != source.Name); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"template_id": template.ID,
+		"source_id":   source.ID,
+		"name":        template.Name,
+		"version":     template.Version,
+	}).Info("Task template cloned")
+	return template, nil
+}
+
+// DeleteTemplate 删除模板版本
+func (s *TaskTemplateServiceImpl) DeleteTemplate(ctx context.Context, id uint) error {
+	res := s.db.WithContext(ctx).Delete(&models.TaskTemplate{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete task template: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// Instantiate 替换参数后创建任务，任务记录模板ID和版本
+func (s *TaskTemplateServiceImpl) Instantiate(ctx context.Context, params *InstantiateTemplateParams) (*models.Task, error) {
+	if params == nil || params.TemplateID == 0 || params.UserID == 0 {
+		return nil, fmt.Errorf("%w: template_id and user_id are required", ErrInvalidData)
+	}
+	template, err := s.GetTemplate(ctx, params.TemplateID)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := templateValues(template.Parameters, params.Values)
+	if err != nil {
+		return nil, err
+	}
+	create, err := renderTaskTemplate(template, values)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Name != "" {
+		create.Name = params.Name
+	}
+	if params.Description != "" {
+		create.Description = params.Description
+	}
+	if params.Priority != "" {
+		create.Priority = params.Priority
+	}
+	create.UserID = params.UserID
+	create.DroneID = params.DroneID
+	create.ScheduledAt = params.ScheduledAt
+	create.AutoRoute = params.AutoRoute
+	create.AssignmentScope = params.AssignmentScope
+
+	task, err := s.taskService.CreateTask(ctx, create)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"task_id":          task.ID,
+		"template_id":      template.ID,
+		"template_version": template.Version,
+	}).Info("Task created from template")
+	return task, nil
+}
+
+// save 校验并以下一个版本号保存模板，requireNewName 为true时要求名称未被使用
+func (s *TaskTemplateServiceImpl) save(ctx context.Context, template *models.TaskTemplate, requireNewName bool) error {
+	if template.Priority == "" {
+		template.Priority = models.TaskPriorityNormal
+	}
+	if err := validateTaskTemplate(template); err != nil {
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁定同名的所有版本（包括已删除的），并发克隆时版本号不重复，删除的版本号也不复用
+		var versions []models.TaskTemplate
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "version", "deleted_at").
+			Where("name = ?", template.Name).Find(&versions).Error; err != nil {
+			return err
+		}
+
+		template.Version = 1
+		for _, v := range versions {
+			if requireNewName && !v.DeletedAt.Valid {
+				return fmt.Errorf("%w: template %q already exists, clone it to create a new version", ErrInvalidTemplate, template.Name)
+			}
+			if v.Version >= template.Version {
+				template.Version = v.Version + 1
+			}
+		}
+		return tx.Create(template).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidTemplate) {
+			return err
+		}
+		return fmt.Errorf("failed to save task template: %w", err)
+	}
+	return nil
+}
+
+// validateTaskTemplate 校验模板字段和参数定义，并用默认值或示例值试渲染一次
+func validateTaskTemplate(template *models.TaskTemplate) error {
+	switch {
+	case template.Name == "" || len(template.Name) > 100:
+		return fmt.Errorf("%w: name must be 1-100 characters", ErrInvalidTemplate)
+	case len(template.TaskName) > 100:
+		return fmt.Errorf("%w: task_name must be at most 100 characters", ErrInvalidTemplate)
+	case !validTaskType(template.Type):
+		return fmt.Errorf("%w: unknown task type: %s", ErrInvalidTemplate, template.Type)
+	case !validTaskPriority(template.Priority):
+		return fmt.Errorf("%w: unknown task priority: %s", ErrInvalidTemplate, template.Priority)
+	case template.Plan.IsEmpty():
+		return fmt.Errorf("%w: plan is required", ErrInvalidTemplate)
+	case (template.Type == models.TaskTypeDelivery) != !template.Delivery.IsEmpty():
+		return fmt.Errorf("%w: delivery is required for and only allowed on delivery templates", ErrInvalidTemplate)
+	case len(template.Parameters) > maxTemplateParameters:
+		return fmt.Errorf("%w: at most %d parameters are allowed", ErrInvalidTemplate, maxTemplateParameters)
+	}
+
+	used := make(map[string]bool)
+	for _, text := range []string{string(template.Plan), string(template.RequiredCapabilities), string(template.Delivery), template.TaskName} {
+		for _, match := range templatePlaceholder.FindAllStringSubmatch(text, -1) {
+			used[match[1]] = true
+		}
+	}
+
+	samples := make(map[string]json.RawMessage, len(template.Parameters))
+	seen := make(map[string]bool, len(template.Parameters))
+	for i := range template.Parameters {
+		param := &template.Parameters[i]
+		if err := validateTemplateParameter(param); err != nil {
+			return fmt.Errorf("%w: parameter %q: %v", ErrInvalidTemplate, param.Name, err)
+		}
+		if seen[param.Name] {
+			return fmt.Errorf("%w: duplicate parameter: %s", ErrInvalidTemplate, param.Name)
+		}
+		seen[param.Name] = true
+		if !used[param.Name] {
+			return fmt.Errorf("%w: parameter %q is not referenced", ErrInvalidTemplate, param.Name)
+		}
+		if param.Default == nil {
+			samples[param.Name] = sampleTemplateValue(param)
+		}
+	}
+	for name := range used {
+		if !seen[name] {
+			return fmt.Errorf("%w: placeholder references undefined parameter: %s", ErrInvalidTemplate, name)
+		}
+	}
+
+	values, err := templateValues(template.Parameters, samples)
+	if err != nil {
+		return err
+	}
+	_, err = renderTaskTemplate(template, values)
+	return err
+}
+
+// validateTemplateParameter 校验单个参数定义
+func validateTemplateParameter(param *models.TemplateParameter) error {
+	if !templateParameterName.MatchString(param.Name) {
+		return fmt.Errorf("name must start with a letter or underscore and contain only letters, digits and underscores")
+	}
+	if !param.Type.IsValid() {
+		return fmt.Errorf("unknown type: %s", param.Type)
+	}
+
+	numeric := param.Type == models.TemplateParamNumber || param.Type == models.TemplateParamInteger
+	if (param.Min != nil || param.Max != nil) && !numeric {
+		return fmt.Errorf("min and max are only allowed on numeric parameters")
+	}
+	if param.Min != nil && param.Max != nil && *param.Min > *param.Max {
+		return fmt.Errorf("min must not exceed max")
+	}
+	if len(param.Enum) > 0 && param.Type != models.TemplateParamString {
+		return fmt.Errorf("enum is only allowed on string parameters")
+	}
+
+	if param.Default != nil {
+		if param.Required {
+			return fmt.Errorf("required parameters cannot have a default")
+		}
+		if _, err := decodeTemplateValue(param, param.Default); err != nil {
+			return fmt.Errorf("invalid default: %v", err)
+		}
+	}
+	return nil
+}
+
+// sampleTemplateValue 没有默认值的参数用于试渲染的示例值
+func sampleTemplateValue(param *models.TemplateParameter) json.RawMessage {
+	switch param.Type {
+	case models.TemplateParamNumber, models.TemplateParamInteger:
+		n := 0.0
+		if param.Min != nil && n < *param.Min {
+			n = math.Ceil(*param.Min)
+		}
+		if param.Max != nil && n > *param.Max {
+			n = math.Floor(*param.Max)
+		}
+		data, _ := json.Marshal(n)
+		return data
+	case models.TemplateParamString:
+		if len(param.Enum) > 0 {
+			data, _ := json.Marshal(param.Enum[0])
+			return data
+		}
+		return json.RawMessage(`""`)
+	case models.TemplateParamBoolean:
+		return json.RawMessage(`false`)
+	case models.TemplateParamPoint:
+		return json.RawMessage(`{"latitude":0,"longitude":0}`)
+	case models.TemplateParamArea:
+		return json.RawMessage(`[{"latitude":0,"longitude":0},{"latitude":0,"longitude":0.001},{"latitude":0.001,"longitude":0}]`)
+	default:
+		return json.RawMessage(`[{"latitude":0,"longitude":0,"altitude":0}]`)
+	}
+}
+
+// templateValues 合并参数值和默认值并校验类型，返回用于替换占位符的通用JSON值
+func templateValues(params models.TemplateParameterList, provided map[string]json.RawMessage) (map[string]interface{}, error) {
+	for name := range provided {
+		if params.Find(name) == nil {
+			return nil, fmt.Errorf("%w: unknown parameter: %s", ErrInvalidTemplate, name)
+		}
+	}
+
+	values := make(map[string]interface{}, len(params))
+	for i := range params {
+		param := &params[i]
+		raw, ok := provided[param.Name]
+		if !ok || isJSONNull(raw) {
+			if param.Default == nil {
+				return nil, fmt.Errorf("%w: parameter %q is required", ErrInvalidTemplate, param.Name)
+			}
+			raw = param.Default
+		}
+		value, err := decodeTemplateValue(param, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parameter %q: %v", ErrInvalidTemplate, param.Name, err)
+		}
+		values[param.Name] = value
+	}
+	return values, nil
+}
+
+// decodeTemplateValue 按参数类型校验值
+func decodeTemplateValue(param *models.TemplateParameter, raw json.RawMessage) (interface{}, error) {
+	switch param.Type {
+	case models.TemplateParamNumber, models.TemplateParamInteger:
+		var n float64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		if param.Type == models.TemplateParamInteger && n != math.Trunc(n) {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		if param.Min != nil && n < *param.Min {
+			return nil, fmt.Errorf("must be at least %g", *param.Min)
+		}
+		if param.Max != nil && n > *param.Max {
+			return nil, fmt.Errorf("must be at most %g", *param.Max)
+		}
+
+	case models.TemplateParamString:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("must be a string")
+		}
+		if len(param.Enum) > 0 && !containsString(param.Enum, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(param.Enum, ", "))
+		}
+
+	case models.TemplateParamBoolean:
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+
+	case models.TemplateParamPoint:
+		var p models.TemplatePoint
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("must be a point object")
+		}
+		if !validCoordinate(p.Latitude, p.Longitude) {
+			return nil, fmt.Errorf("invalid coordinates")
+		}
+		normalized, err := json.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		raw = normalized
+
+	case models.TemplateParamArea:
+		var points []geo.Point
+		if err := json.Unmarshal(raw, &points); err != nil {
+			return nil, fmt.Errorf("must be a list of points")
+		}
+		if len(points) < 3 {
+			return nil, fmt.Errorf("area needs at least 3 points")
+		}
+		for i, p := range points {
+			if !validCoordinate(p.Latitude, p.Longitude) {
+				return nil, fmt.Errorf("point %d has invalid coordinates", i)
+			}
+		}
+
+	case models.TemplateParamWaypoints:
+		var waypoints models.WaypointList
+		if err := json.Unmarshal(raw, &waypoints); err != nil {
+			return nil, fmt.Errorf("must be a list of waypoints")
+		}
+		if len(waypoints) == 0 {
+			return nil, fmt.Errorf("at least one waypoint is required")
+		}
+		for i, wp := range waypoints {
+			if !validCoordinate(wp.Latitude, wp.Longitude) {
+				return nil, fmt.Errorf("waypoint %d has invalid coordinates", i)
+			}
+		}
+	}
+
+	return decodeJSONValue(raw)
+}
+
+// renderTaskTemplate 替换占位符，生成创建任务的参数；计划、能力要求和配送信息的取值由任务装饰器在创建时校验
+func renderTaskTemplate(template *models.TaskTemplate, values map[string]interface{}) (*CreateTaskParams, error) {
+	create := &CreateTaskParams{
+		Name:            template.Name,
+		Description:     template.Description,
+		Type:            template.Type,
+		Priority:        template.Priority,
+		TemplateID:      &template.ID,
+		TemplateVersion: template.Version,
+	}
+
+	if template.TaskName != "" {
+		name, err := interpolateTemplateString(template.TaskName, values)
+		if err != nil {
+			return nil, fmt.Errorf("%w: task_name: %v", ErrInvalidTemplate, err)
+		}
+		if name = strings.TrimSpace(name); name == "" || len(name) > 100 {
+			return nil, fmt.Errorf("%w: rendered task name must be 1-100 characters", ErrInvalidTemplate)
+		}
+		create.Name = name
+	}
+
+	if err := renderTemplateBody(template.Plan, values, &create.Plan); err != nil {
+		return nil, fmt.Errorf("%w: plan: %v", ErrInvalidTemplate, err)
+	}
+	if !template.RequiredCapabilities.IsEmpty() {
+		var req models.CapabilityRequirements
+		if err := renderTemplateBody(template.RequiredCapabilities, values, &req); err != nil {
+			return nil, fmt.Errorf("%w: required_capabilities: %v", ErrInvalidTemplate, err)
+		}
+		if !req.IsEmpty() {
+			create.RequiredCapabilities = &req
+		}
+	}
+	if !template.Delivery.IsEmpty() {
+		var delivery models.TaskDelivery
+		if err := renderTemplateBody(template.Delivery, values, &delivery); err != nil {
+			return nil, fmt.Errorf("%w: delivery: %v", ErrInvalidTemplate, err)
+		}
+		create.Delivery = &delivery
+	}
+	return create, nil
+}
+
+// renderTemplateBody 替换JSON内容中的占位符后解析到 out
+func renderTemplateBody(body models.TemplateBody, values map[string]interface{}, out interface{}) error {
+	node, err := decodeJSONValue(json.RawMessage(body))
+	if err != nil {
+		return err
+	}
+	if node, err = renderTemplateNode(node, values); err != nil {
+		return err
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// renderTemplateNode 递归替换字符串值中的占位符
+// 整个字符串是一个占位符时替换为参数值本身（保留数字、对象等类型），否则按文本插入
+func renderTemplateNode(node interface{}, values map[string]interface{}) (interface{}, error) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			rendered, err := renderTemplateNode(child, values)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = rendered
+		}
+		return v, nil
+
+	case []interface{}:
+		for i, child := range v {
+			rendered, err := renderTemplateNode(child, values)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rendered
+		}
+		return v, nil
+
+	case string:
+		if loc := templatePlaceholder.FindStringSubmatchIndex(v); loc != nil && loc[0] == 0 && loc[1] == len(v) {
+			return lookupTemplateValue(values, v[loc[2]:loc[3]], v[loc[4]:loc[5]])
+		}
+		return interpolateTemplateString(v, values)
+	}
+	return node, nil
+}
+
+// interpolateTemplateString 按文本替换字符串中的所有占位符
+func interpolateTemplateString(s string, values map[string]interface{}) (string, error) {
+	var firstErr error
+	result := templatePlaceholder.ReplaceAllStringFunc(s, func(placeholder string) string {
+		match := templatePlaceholder.FindStringSubmatch(placeholder)
+		value, err := lookupTemplateValue(values, match[1], match[2])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return placeholder
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case json.Number:
+			return v.String()
+		case bool:
+			return fmt.Sprint(v)
+		default:
+			data, _ := json.Marshal(v)
+			return string(data)
+		}
+	})
+	return result, firstErr
+}
+
+// lookupTemplateValue 读取参数值，path 为 ".field" 形式的点分路径
+func lookupTemplateValue(values map[string]interface{}, name, path string) (interface{}, error) {
+	value, ok := values[name]
+	if !ok {
+		return nil, fmt.Errorf("undefined parameter: %s", name)
+	}
+	if value, ok = lookupResultPath(value, strings.TrimPrefix(path, ".")); !ok {
+		return nil, fmt.Errorf("parameter %s has no value at %s", name, strings.TrimPrefix(path, "."))
+	}
+	return value, nil
+}
+
+// decodeJSONValue 解析为通用JSON值，数字保留原始文本
+func decodeJSONValue(raw json.RawMessage) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// isJSONNull 是否为空值或JSON null
+func isJSONNull(raw json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(raw)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// validCoordinate 检查经纬度范围
+func validCoordinate(lat, lon float64) bool {
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180
+}
+
+// containsString 检查字符串是否在列表中
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
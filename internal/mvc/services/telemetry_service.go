@@ -0,0 +1,852 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/logger"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const (
+	// telemetryLeaderKey 降采样/清理任务主节点锁
+	telemetryLeaderKey = "telemetry:maintenance:leader"
+	// telemetryWatermarkKey 各聚合档位已处理到的时间点
+	telemetryWatermarkKey = "telemetry:downsample:%s:watermark"
+
+	// telemetryTable 遥测表名
+	telemetryTable = "drone_telemetry"
+
+	defaultTrackLimit = 5000
+	maxTrackLimit     = 20000
+
+	// telemetryStateTTL 缓存的最近遥测超过该时间未更新时重新从数据库加载，并在维护周期中清理
+	telemetryStateTTL = 5 * time.Minute
+)
+
+// telemetryPartitionOrder 分区按 (resolution, recorded_at) 排列，档位顺序需与字符串排序一致
+var telemetryPartitionOrder = []models.TelemetryResolution{
+	models.TelemetryResolutionHour,
+	models.TelemetryResolutionMinute,
+	models.TelemetryResolutionRaw,
+}
+
+// TelemetryConfig 遥测存储配置
+type TelemetryConfig struct {
+	RawRetention       time.Duration `yaml:"raw_retention" json:"raw_retention"`             // 原始点保留时长
+	MinuteRetention    time.Duration `yaml:"minute_retention" json:"minute_retention"`       // 1分钟聚合保留时长
+	HourRetention      time.Duration `yaml:"hour_retention" json:"hour_retention"`           // 1小时聚合保留时长
+	DownsampleInterval time.Duration `yaml:"downsample_interval" json:"downsample_interval"` // 降采样执行间隔
+	DownsampleLag      time.Duration `yaml:"downsample_lag" json:"downsample_lag"`           // 聚合延迟，等待迟到数据
+	PartitionAhead     time.Duration `yaml:"partition_ahead" json:"partition_ahead"`         // 提前创建分区的时长
+	LockTTL            time.Duration `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultTelemetryConfig 默认遥测存储配置
+func DefaultTelemetryConfig() *TelemetryConfig {
+	return &TelemetryConfig{
+		RawRetention:       7 * 24 * time.Hour,
+		MinuteRetention:    90 * 24 * time.Hour,
+		HourRetention:      2 * 365 * 24 * time.Hour,
+		DownsampleInterval: time.Minute,
+		DownsampleLag:      2 * time.Minute,
+		PartitionAhead:     3 * 24 * time.Hour,
+		LockTTL:            2 * time.Minute,
+	}
+}
+
+// TelemetrySample 遥测样本，位置和电量可以分别上报
+type TelemetrySample struct {
+	DroneID   uint             `json:"drone_id"`
+	Position  *models.Position `json:"position,omitempty"`
+	Battery   *int             `json:"battery,omitempty"`
+	Speed     *float64         `json:"speed,omitempty"` // 未提供时根据相邻位置计算
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// TrackQueryParams 轨迹查询参数
+type TrackQueryParams struct {
+	DroneID    uint                       `json:"drone_id"`
+	From       time.Time                  `json:"from"`
+	To         time.Time                  `json:"to"`
+	Resolution models.TelemetryResolution `json:"resolution"` // 为空时根据时间跨度自动选择
+	Limit      int                        `json:"limit"`
+}
+
+// Track 轨迹
+type Track struct {
+	DroneID    uint                       `json:"drone_id"`
+	TaskID     *uint                      `json:"task_id,omitempty"`
+	Resolution models.TelemetryResolution `json:"resolution"`
+	From       time.Time                  `json:"from"`
+	To         time.Time                  `json:"to"`
+	Points     []*models.DroneTelemetry   `json:"points"`
+	Truncated  bool                       `json:"truncated"`      // 超过点数上限，只返回了前面的部分
+	Next       *time.Time                 `json:"next,omitempty"` // 截断时下一页的起始时间，作为 from 继续查询
+}
+
+// TelemetryService 遥测时序存储服务接口
+type TelemetryService interface {
+	RecordTelemetry(ctx context.Context, sample *TelemetrySample) error
+	QueryTrack(ctx context.Context, params *TrackQueryParams) (*Track, error)
+	GetTaskTrack(ctx context.Context, taskID uint, resolution models.TelemetryResolution) (*Track, error)
+	// ForgetDrone 丢弃无人机缓存的最近遥测，离线后再次上报时从数据库重新加载
+	ForgetDrone(droneID uint)
+
+	// 服务管理（降采样与过期清理）
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// TelemetryServiceImpl 遥测时序存储实现
+type TelemetryServiceImpl struct {
+	config   *TelemetryConfig
+	db       *gorm.DB
+	redis    *redis.Client
+	election *database.LeaderElection
+	logger   *logger.Logger
+
+	// 每架无人机最近一次遥测，用于补全部分上报的字段和计算地速
+	lastState map[uint]*telemetryState
+	stateMu   sync.Mutex
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// telemetryState 缓存的无人机最近遥测
+type telemetryState struct {
+	last      models.DroneTelemetry
+	updatedAt time.Time // 本实例最后一次更新缓存的时间
+}
+
+// NewTelemetryService 创建遥测存储服务
+func NewTelemetryService(
+	config *TelemetryConfig,
+	db *gorm.DB,
+	redisClient *redis.Client,
+	lockService *database.LockService,
+	logger *logger.Logger,
+) TelemetryService {
+	if config == nil {
+		config = DefaultTelemetryConfig()
+	}
+
+	return &TelemetryServiceImpl{
+		config:    config,
+		db:        db,
+		redis:     redisClient,
+		election:  database.NewLeaderElection(lockService, telemetryLeaderKey, config.LockTTL),
+		logger:    logger,
+		lastState: make(map[uint]*telemetryState),
+	}
+}
+
+// RecordTelemetry 写入一条遥测
+func (s *TelemetryServiceImpl) RecordTelemetry(ctx context.Context, sample *TelemetrySample) error {
+	if sample == nil || sample.DroneID == 0 {
+		return ErrInvalidData
+	}
+
+	recordedAt := sample.Timestamp
+	if recordedAt.IsZero() {
+		recordedAt = time.Now()
+	}
+
+	if err := s.seedState(ctx, sample.DroneID); err != nil {
+		return err
+	}
+
+	s.stateMu.Lock()
+	state := s.lastState[sample.DroneID]
+	if state == nil {
+		// 加载后被 ForgetDrone 丢弃
+		state = &telemetryState{last: models.DroneTelemetry{DroneID: sample.DroneID, Battery: -1}}
+		s.lastState[sample.DroneID] = state
+	}
+	state.updatedAt = time.Now()
+	last := &state.last
+
+	// 仅电量上报：更新状态，等待下一个位置点写入
+	if sample.Position == nil {
+		if sample.Battery != nil {
+			last.Battery = *sample.Battery
+		}
+		s.stateMu.Unlock()
+		return nil
+	}
+
+	point := &models.DroneTelemetry{
+		DroneID:     sample.DroneID,
+		Resolution:  models.TelemetryResolutionRaw,
+		RecordedAt:  recordedAt.UTC(),
+		Latitude:    sample.Position.Latitude,
+		Longitude:   sample.Position.Longitude,
+		Altitude:    sample.Position.Altitude,
+		Heading:     sample.Position.Heading,
+		Battery:     -1,
+		SampleCount: 1,
+	}
+
+	if sample.Battery != nil {
+		point.Battery = *sample.Battery
+	} else {
+		point.Battery = last.Battery
+	}
+
+	if sample.Speed != nil {
+		point.Speed = *sample.Speed
+	} else if !last.RecordedAt.IsZero() {
+		elapsed := point.RecordedAt.Sub(last.RecordedAt).Seconds()
+		// 间隔过长时不推算速度，避免离线前后两点连线造成误差
+		if elapsed > 0 && elapsed <= 300 {
+			point.Speed = geo.Distance(last.Latitude, last.Longitude, point.Latitude, point.Longitude) / elapsed
+		}
+	}
+
+	state.last = *point
+	s.stateMu.Unlock()
+
+	if err := s.db.WithContext(ctx).Create(point).Error; err != nil {
+		return fmt.Errorf("failed to store telemetry: %w", err)
+	}
+
+	return nil
+}
+
+// seedState 缓存中没有或已过期时从最近存储的原始点加载，重启或由其他实例接收过遥测后电量和地速可以延续
+func (s *TelemetryServiceImpl) seedState(ctx context.Context, droneID uint) error {
+	s.stateMu.Lock()
+	state := s.lastState[droneID]
+	fresh := state != nil && time.Since(state.updatedAt) <= telemetryStateTTL
+	s.stateMu.Unlock()
+	if fresh {
+		return nil
+	}
+
+	var rows []*models.DroneTelemetry
+	err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND resolution = ? AND recorded_at >= ?",
+			droneID, models.TelemetryResolutionRaw, time.Now().Add(-s.config.RawRetention).UTC()).
+		Order("recorded_at DESC").
+		Limit(1).
+		Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to load latest telemetry: %w", err)
+	}
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	// 加载期间已有其他上报更新了缓存
+	if s.lastState[droneID] != state {
+		return nil
+	}
+
+	seeded := &telemetryState{
+		last:      models.DroneTelemetry{DroneID: droneID, Battery: -1},
+		updatedAt: time.Now(),
+	}
+	if state != nil {
+		seeded.last = state.last
+	}
+	if len(rows) > 0 && rows[0].RecordedAt.After(seeded.last.RecordedAt) {
+		seeded.last = *rows[0]
+	}
+	s.lastState[droneID] = seeded
+	return nil
+}
+
+// ForgetDrone 丢弃缓存的最近遥测
+func (s *TelemetryServiceImpl) ForgetDrone(droneID uint) {
+	s.stateMu.Lock()
+	delete(s.lastState, droneID)
+	s.stateMu.Unlock()
+}
+
+// pruneState 清理长时间未更新的缓存
+func (s *TelemetryServiceImpl) pruneState() {
+	staleBefore := time.Now().Add(-telemetryStateTTL)
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	for droneID, state := range s.lastState {
+		if state.updatedAt.Before(staleBefore) {
+			delete(s.lastState, droneID)
+		}
+	}
+}
+
+// QueryTrack 查询无人机在时间范围内的轨迹
+func (s *TelemetryServiceImpl) QueryTrack(ctx context.Context, params *TrackQueryParams) (*Track, error) {
+	if params == nil || params.DroneID == 0 {
+		return nil, ErrInvalidData
+	}
+	if params.To.IsZero() {
+		params.To = time.Now()
+	}
+	if params.From.IsZero() {
+		params.From = params.To.Add(-time.Hour)
+	}
+	if !params.From.Before(params.To) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	resolution := params.Resolution
+	if resolution == "" {
+		resolution = s.selectResolution(params.From, params.To)
+	} else if !resolution.IsValid() {
+		return nil, ErrInvalidData
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultTrackLimit
+	}
+	if limit > maxTrackLimit {
+		limit = maxTrackLimit
+	}
+
+	// 多取一个点判断是否截断，(drone_id, resolution, recorded_at) 唯一，下一页从该点开始不会重复
+	var points []*models.DroneTelemetry
+	err := s.db.WithContext(ctx).
+		Where("drone_id = ? AND resolution = ? AND recorded_at >= ? AND recorded_at <= ?",
+			params.DroneID, resolution, params.From.UTC(), params.To.UTC()).
+		Order("recorded_at ASC").
+		Limit(limit + 1).
+		Find(&points).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to query track: %w", err)
+	}
+
+	track := &Track{
+		DroneID:    params.DroneID,
+		Resolution: resolution,
+		From:       params.From,
+		To:         params.To,
+		Points:     points,
+	}
+	if len(points) > limit {
+		next := points[limit].RecordedAt
+		track.Points = points[:limit]
+		track.Truncated = true
+		track.Next = &next
+	}
+	return track, nil
+}
+
+// GetTaskTrack 查询任务执行期间的实际飞行轨迹
+// 未指定精度时按时间跨度选择，点数超过上限则逐级改用更粗的档位
+func (s *TelemetryServiceImpl) GetTaskTrack(ctx context.Context, taskID uint, resolution models.TelemetryResolution) (*Track, error) {
+	var task models.Task
+	if err := s.db.WithContext(ctx).First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to load task: %w", err)
+	}
+
+	if task.StartedAt == nil {
+		return nil, ErrTaskNotStarted
+	}
+
+	to := time.Now()
+	if task.CompletedAt != nil {
+		to = *task.CompletedAt
+	}
+
+	auto := resolution == ""
+	if auto {
+		resolution = s.selectResolution(*task.StartedAt, to)
+	}
+
+	for {
+		track, err := s.QueryTrack(ctx, &TrackQueryParams{
+			DroneID:    task.DroneID,
+			From:       *task.StartedAt,
+			To:         to,
+			Resolution: resolution,
+			Limit:      maxTrackLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if auto && track.Truncated && resolution.Coarser() != "" {
+			resolution = resolution.Coarser()
+			continue
+		}
+
+		track.TaskID = &task.ID
+		return track, nil
+	}
+}
+
+// selectResolution 根据时间跨度和数据保留期选择查询精度
+func (s *TelemetryServiceImpl) selectResolution(from, to time.Time) models.TelemetryResolution {
+	span := to.Sub(from)
+	resolution := models.TelemetryResolutionRaw
+	switch {
+	case span > 7*24*time.Hour:
+		resolution = models.TelemetryResolutionHour
+	case span > 6*time.Hour:
+		resolution = models.TelemetryResolutionMinute
+	}
+
+	// 起点超出保留期时降级到更粗的档位
+	age := time.Since(from)
+	if resolution == models.TelemetryResolutionRaw && age > s.config.RawRetention {
+		resolution = models.TelemetryResolutionMinute
+	}
+	if resolution == models.TelemetryResolutionMinute && age > s.config.MinuteRetention {
+		resolution = models.TelemetryResolutionHour
+	}
+
+	return resolution
+}
+
+// Start 启动降采样和过期清理
+func (s *TelemetryServiceImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.maintenanceLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"raw_retention":    s.config.RawRetention.String(),
+		"minute_retention": s.config.MinuteRetention.String(),
+		"hour_retention":   s.config.HourRetention.String(),
+	}).Info("Telemetry service started")
+	return nil
+}
+
+// Stop 停止后台任务
+func (s *TelemetryServiceImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release telemetry leader lock")
+	}
+
+	s.logger.Info("Telemetry service stopped")
+	return nil
+}
+
+// maintenanceLoop 周期执行降采样和分区维护，并清理本实例的遥测缓存
+func (s *TelemetryServiceImpl) maintenanceLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.DownsampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			// 缓存在每个实例各自维护
+			s.pruneState()
+
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Telemetry leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+
+			if err := s.downsample(s.ctx, models.TelemetryResolutionRaw, models.TelemetryResolutionMinute); err != nil {
+				s.logger.WithError(err).Error("Failed to downsample telemetry to 1m")
+			}
+			if err := s.downsample(s.ctx, models.TelemetryResolutionMinute, models.TelemetryResolutionHour); err != nil {
+				s.logger.WithError(err).Error("Failed to downsample telemetry to 1h")
+			}
+			if err := s.managePartitions(s.ctx); err != nil {
+				s.logger.WithError(err).Error("Failed to manage telemetry partitions")
+			}
+		}
+	}
+}
+
+// downsample 将源档位数据聚合到目标档位，每次最多处理60个时间桶
+func (s *TelemetryServiceImpl) downsample(ctx context.Context, source, target models.TelemetryResolution) error {
+	bucket := target.BucketSize()
+	cutoff := time.Now().UTC().Add(-s.config.DownsampleLag).Truncate(bucket)
+
+	// 源档位本身是聚合结果时，只处理源档位已完成的时间段，避免用不完整的数据生成目标桶
+	if source != models.TelemetryResolutionRaw {
+		sourceMark, err := s.loadWatermark(ctx, source)
+		if err != nil {
+			return err
+		}
+		if sourceMark.Truncate(bucket).Before(cutoff) {
+			cutoff = sourceMark.Truncate(bucket)
+		}
+	}
+
+	start, err := s.loadWatermark(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	if start.IsZero() {
+		var first models.DroneTelemetry
+		err := s.db.WithContext(ctx).
+			Where("resolution = ?", source).
+			Order("recorded_at ASC").
+			First(&first).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find first %s telemetry: %w", source, err)
+		}
+		start = first.RecordedAt.UTC().Truncate(bucket)
+	}
+
+	end := start.Add(60 * bucket)
+	if end.After(cutoff) {
+		end = cutoff
+	}
+	if !end.After(start) {
+		return nil
+	}
+
+	var points []*models.DroneTelemetry
+	err = s.db.WithContext(ctx).
+		Where("resolution = ? AND recorded_at >= ? AND recorded_at < ?", source, start, end).
+		Order("drone_id ASC, recorded_at ASC").
+		Find(&points).Error
+	if err != nil {
+		return fmt.Errorf("failed to load %s telemetry: %w", source, err)
+	}
+
+	aggregated := aggregateTelemetry(points, target)
+
+	// 先删除窗口内已有的聚合结果，保证重复执行幂等
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("resolution = ? AND recorded_at >= ? AND recorded_at < ?", target, start, end).
+			Delete(&models.DroneTelemetry{}).Error; err != nil {
+			return err
+		}
+		if len(aggregated) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(aggregated, 500).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store %s telemetry: %w", target, err)
+	}
+
+	return s.saveWatermark(ctx, target, end)
+}
+
+// managePartitions 预建后续分区并删除整体超出保留期的分区
+// 原始点和1分钟聚合按天分区，1小时聚合按月分区；各档位另有一个 MAXVALUE 分区承接预建范围之后的数据
+func (s *TelemetryServiceImpl) managePartitions(ctx context.Context) error {
+	var names []string
+	err := s.db.WithContext(ctx).Raw(
+		"SELECT PARTITION_NAME FROM information_schema.PARTITIONS "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL "+
+			"ORDER BY PARTITION_ORDINAL_POSITION", telemetryTable).
+		Scan(&names).Error
+	if err != nil {
+		return fmt.Errorf("failed to list partitions: %w", err)
+	}
+	if len(names) == 0 {
+		return s.partitionTable(ctx)
+	}
+
+	now := time.Now()
+	horizon := now.Add(s.config.PartitionAhead)
+
+	latest := make(map[models.TelemetryResolution]time.Time)
+	var expired []string
+	for _, name := range names {
+		resolution, start, ok := parseTelemetryPartition(name)
+		if !ok {
+			continue
+		}
+		if start.After(latest[resolution]) {
+			latest[resolution] = start
+		}
+		retention := s.retention(resolution)
+		if retention > 0 && !nextTelemetryPartition(resolution, start).After(now.Add(-retention)) {
+			expired = append(expired, name)
+		}
+	}
+
+	for _, resolution := range telemetryPartitionOrder {
+		start := telemetryPartitionStart(resolution, now)
+		if last, exists := latest[resolution]; exists {
+			start = nextTelemetryPartition(resolution, last)
+		}
+
+		var definitions []string
+		for ; !start.After(horizon); start = nextTelemetryPartition(resolution, start) {
+			definitions = append(definitions, telemetryPartitionDefinition(resolution, start))
+		}
+		if len(definitions) == 0 {
+			continue
+		}
+
+		// MAXVALUE 分区中只有尚未到来的数据，拆分代价很小
+		maxName := telemetryMaxPartitionName(resolution)
+		definitions = append(definitions, telemetryMaxPartitionDefinition(resolution))
+		err := s.db.WithContext(ctx).Exec(fmt.Sprintf("ALTER TABLE %s REORGANIZE PARTITION %s INTO (%s)",
+			telemetryTable, maxName, strings.Join(definitions, ", "))).Error
+		if err != nil {
+			return fmt.Errorf("failed to create %s partitions: %w", resolution, err)
+		}
+		s.logger.WithFields(map[string]interface{}{
+			"resolution": resolution,
+			"created":    len(definitions) - 1,
+		}).Info("Telemetry partitions created")
+	}
+
+	if len(expired) > 0 {
+		err := s.db.WithContext(ctx).Exec(fmt.Sprintf("ALTER TABLE %s DROP PARTITION %s",
+			telemetryTable, strings.Join(expired, ", "))).Error
+		if err != nil {
+			return fmt.Errorf("failed to drop expired partitions: %w", err)
+		}
+		s.logger.WithField("partitions", expired).Info("Expired telemetry partitions dropped")
+	}
+
+	return nil
+}
+
+// partitionTable 将未分区的遥测表转换为分区表
+// 各档位第一个分区从保留期起点开始，更早的数据落入该分区，随其过期一并删除
+func (s *TelemetryServiceImpl) partitionTable(ctx context.Context) error {
+	var keyColumns int64
+	err := s.db.WithContext(ctx).Raw(
+		"SELECT COUNT(*) FROM information_schema.KEY_COLUMN_USAGE "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'", telemetryTable).
+		Scan(&keyColumns).Error
+	if err != nil {
+		return fmt.Errorf("failed to inspect primary key: %w", err)
+	}
+
+	// 旧表主键只有 id，分区前需要补充分区列
+	if keyColumns < 3 {
+		err := s.db.WithContext(ctx).Exec(fmt.Sprintf(
+			"ALTER TABLE %s DROP PRIMARY KEY, ADD PRIMARY KEY (id, resolution, recorded_at)", telemetryTable)).Error
+		if err != nil {
+			return fmt.Errorf("failed to extend primary key: %w", err)
+		}
+	}
+
+	now := time.Now()
+	horizon := now.Add(s.config.PartitionAhead)
+
+	var definitions []string
+	for _, resolution := range telemetryPartitionOrder {
+		start := telemetryPartitionStart(resolution, now)
+		if retention := s.retention(resolution); retention > 0 {
+			start = telemetryPartitionStart(resolution, now.Add(-retention))
+		}
+		for ; !start.After(horizon); start = nextTelemetryPartition(resolution, start) {
+			definitions = append(definitions, telemetryPartitionDefinition(resolution, start))
+		}
+		definitions = append(definitions, telemetryMaxPartitionDefinition(resolution))
+	}
+
+	err = s.db.WithContext(ctx).Exec(fmt.Sprintf(
+		"ALTER TABLE %s PARTITION BY RANGE COLUMNS(resolution, recorded_at) (%s)",
+		telemetryTable, strings.Join(definitions, ", "))).Error
+	if err != nil {
+		return fmt.Errorf("failed to partition telemetry table: %w", err)
+	}
+
+	s.logger.WithField("partitions", len(definitions)).Info("Telemetry table partitioned")
+	return nil
+}
+
+// retention 档位的数据保留时长
+func (s *TelemetryServiceImpl) retention(resolution models.TelemetryResolution) time.Duration {
+	switch resolution {
+	case models.TelemetryResolutionRaw:
+		return s.config.RawRetention
+	case models.TelemetryResolutionMinute:
+		return s.config.MinuteRetention
+	case models.TelemetryResolutionHour:
+		return s.config.HourRetention
+	default:
+		return 0
+	}
+}
+
+// telemetryPartitionStart 时间所在分区的起点，按连接时区（loc）划分
+func telemetryPartitionStart(resolution models.TelemetryResolution, t time.Time) time.Time {
+	t = t.In(time.Local)
+	if resolution == models.TelemetryResolutionHour {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// nextTelemetryPartition 下一个分区的起点，即当前分区的上界
+func nextTelemetryPartition(resolution models.TelemetryResolution, start time.Time) time.Time {
+	if resolution == models.TelemetryResolutionHour {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// telemetryPartitionDefinition 分区定义，分区名为 p<档位>_<起始日期>
+func telemetryPartitionDefinition(resolution models.TelemetryResolution, start time.Time) string {
+	return fmt.Sprintf("PARTITION p%s_%s VALUES LESS THAN ('%s', '%s')",
+		resolution, start.Format("20060102"), resolution,
+		nextTelemetryPartition(resolution, start).Format("2006-01-02 15:04:05"))
+}
+
+func telemetryMaxPartitionName(resolution models.TelemetryResolution) string {
+	return fmt.Sprintf("p%s_max", resolution)
+}
+
+func telemetryMaxPartitionDefinition(resolution models.TelemetryResolution) string {
+	return fmt.Sprintf("PARTITION %s VALUES LESS THAN ('%s', MAXVALUE)", telemetryMaxPartitionName(resolution), resolution)
+}
+
+// parseTelemetryPartition 从分区名解析档位和起始时间，MAXVALUE 分区和无法识别的分区返回 false
+func parseTelemetryPartition(name string) (models.TelemetryResolution, time.Time, bool) {
+	sep := strings.LastIndex(name, "_")
+	if !strings.HasPrefix(name, "p") || sep < 0 {
+		return "", time.Time{}, false
+	}
+	resolution := models.TelemetryResolution(name[1:sep])
+	if !resolution.IsValid() {
+		return "", time.Time{}, false
+	}
+	start, err := time.ParseInLocation("20060102", name[sep+1:], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return resolution, start, true
+}
+
+func (s *TelemetryServiceImpl) loadWatermark(ctx context.Context, resolution models.TelemetryResolution) (time.Time, error) {
+	value, err := s.redis.Get(ctx, fmt.Sprintf(telemetryWatermarkKey, resolution)).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load watermark: %w", err)
+	}
+
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return time.Unix(unix, 0).UTC(), nil
+}
+
+func (s *TelemetryServiceImpl) saveWatermark(ctx context.Context, resolution models.TelemetryResolution, at time.Time) error {
+	key := fmt.Sprintf(telemetryWatermarkKey, resolution)
+	if err := s.redis.Set(ctx, key, at.Unix(), 0).Err(); err != nil {
+		return fmt.Errorf("failed to save watermark: %w", err)
+	}
+	return nil
+}
+
+// aggregateTelemetry 按无人机和时间桶聚合遥测点
+// 位置、高度、速度按样本数加权平均；航向和电量取桶内最后一个有效值
+func aggregateTelemetry(points []*models.DroneTelemetry, target models.TelemetryResolution) []*models.DroneTelemetry {
+	bucket := target.BucketSize()
+
+	type bucketKey struct {
+		droneID uint
+		start   int64
+	}
+	type accumulator struct {
+		lat, lon, alt, speed float64
+		weight               int
+		heading              float64
+		battery              int
+	}
+
+	groups := make(map[bucketKey]*accumulator)
+	for _, p := range points {
+		key := bucketKey{droneID: p.DroneID, start: p.RecordedAt.UTC().Truncate(bucket).Unix()}
+		acc, exists := groups[key]
+		if !exists {
+			acc = &accumulator{battery: -1}
+			groups[key] = acc
+		}
+
+		weight := p.SampleCount
+		if weight <= 0 {
+			weight = 1
+		}
+		acc.lat += p.Latitude * float64(weight)
+		acc.lon += p.Longitude * float64(weight)
+		acc.alt += p.Altitude * float64(weight)
+		acc.speed += p.Speed * float64(weight)
+		acc.weight += weight
+		acc.heading = p.Heading
+		if p.HasBattery() {
+			acc.battery = p.Battery
+		}
+	}
+
+	result := make([]*models.DroneTelemetry, 0, len(groups))
+	for key, acc := range groups {
+		w := float64(acc.weight)
+		result = append(result, &models.DroneTelemetry{
+			DroneID:     key.droneID,
+			Resolution:  target,
+			RecordedAt:  time.Unix(key.start, 0).UTC(),
+			Latitude:    acc.lat / w,
+			Longitude:   acc.lon / w,
+			Altitude:    acc.alt / w,
+			Heading:     acc.heading,
+			Speed:       acc.speed / w,
+			Battery:     acc.battery,
+			SampleCount: acc.weight,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].DroneID != result[j].DroneID {
+			return result[i].DroneID < result[j].DroneID
+		}
+		return result[i].RecordedAt.Before(result[j].RecordedAt)
+	})
+
+	return result
+}
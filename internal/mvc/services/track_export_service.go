@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/trackexport"
+
+	"gorm.io/gorm"
+)
+
+// TrackExport 导出结果
+type TrackExport struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Truncated   bool   `json:"truncated"` // 实际轨迹超过点数上限，只导出了前面的部分
+	Data        []byte `json:"-"`
+}
+
+// TrackExportService 飞行轨迹导出服务接口
+type TrackExportService interface {
+	// ExportDroneTrack 导出无人机在时间窗口内的实际轨迹
+	ExportDroneTrack(ctx context.Context, params *TrackQueryParams, format trackexport.Format) (*TrackExport, error)
+	// ExportTaskTrack 导出任务的计划航线和实际飞行轨迹
+	ExportTaskTrack(ctx context.Context, taskID uint, resolution models.TelemetryResolution, format trackexport.Format) (*TrackExport, error)
+}
+
+// TrackExportServiceImpl 轨迹导出实现
+type TrackExportServiceImpl struct {
+	db               *gorm.DB
+	telemetryService TelemetryService
+}
+
+// NewTrackExportService 创建轨迹导出服务
+func NewTrackExportService(db *gorm.DB, telemetryService TelemetryService) TrackExportService {
+	return &TrackExportServiceImpl{
+		db:               db,
+		telemetryService: telemetryService,
+	}
+}
+
+// ExportDroneTrack 导出无人机轨迹
+func (s *TrackExportServiceImpl) ExportDroneTrack(ctx context.Context, params *TrackQueryParams, format trackexport.Format) (*TrackExport, error) {
+	track, err := s.telemetryService.QueryTrack(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &trackexport.Document{
+		Name: fmt.Sprintf("drone-%d", track.DroneID),
+		Description: fmt.Sprintf("%s ~ %s (%s)",
+			track.From.UTC().Format(time.RFC3339), track.To.UTC().Format(time.RFC3339), track.Resolution),
+		Actual: toTrackPoints(track.Points),
+	}
+
+	filename := fmt.Sprintf("drone-%d-%s", track.DroneID, track.From.UTC().Format("20060102T150405Z"))
+	export, err := renderTrackExport(doc, filename, format)
+	if err != nil {
+		return nil, err
+	}
+	export.Truncated = track.Truncated
+	return export, nil
+}
+
+// ExportTaskTrack 导出任务轨迹，任务未开始时只包含计划航线
+func (s *TrackExportServiceImpl) ExportTaskTrack(ctx context.Context, taskID uint, resolution models.TelemetryResolution, format trackexport.Format) (*TrackExport, error) {
+	var task models.Task
+	if err := s.db.WithContext(ctx).First(&task, taskID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to load task: %w", err)
+	}
+
+	waypoints := task.Plan.Waypoints
+
+	doc := &trackexport.Document{
+		Name:        task.Name,
+		Description: fmt.Sprintf("task %d, drone %d, status %s", task.ID, task.DroneID, task.Status),
+		Planned:     make([]trackexport.PlannedPoint, 0, len(waypoints)),
+	}
+	for i, wp := range waypoints {
+		name := wp.Name
+		if name == "" {
+			name = fmt.Sprintf("WP%d", i+1)
+		}
+		doc.Planned = append(doc.Planned, trackexport.PlannedPoint{
+			Latitude:  wp.Latitude,
+			Longitude: wp.Longitude,
+			Altitude:  wp.Altitude,
+			Name:      name,
+		})
+	}
+
+	truncated := false
+	if task.StartedAt != nil {
+		track, err := s.telemetryService.GetTaskTrack(ctx, taskID, resolution)
+		if err != nil {
+			return nil, err
+		}
+		doc.Actual = toTrackPoints(track.Points)
+		truncated = track.Truncated
+	}
+
+	export, err := renderTrackExport(doc, fmt.Sprintf("task-%d", task.ID), format)
+	if err != nil {
+		return nil, err
+	}
+	export.Truncated = truncated
+	return export, nil
+}
+
+// renderTrackExport 按格式渲染导出文档
+func renderTrackExport(doc *trackexport.Document, filename string, format trackexport.Format) (*TrackExport, error) {
+	var buf bytes.Buffer
+	if err := trackexport.Write(&buf, format, doc); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", format, err)
+	}
+
+	return &TrackExport{
+		Filename:    filename + format.Extension(),
+		ContentType: format.ContentType(),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// toTrackPoints 遥测点转换为导出轨迹点
+func toTrackPoints(points []*models.DroneTelemetry) []trackexport.TrackPoint {
+	result := make([]trackexport.TrackPoint, 0, len(points))
+	for _, p := range points {
+		result = append(result, trackexport.TrackPoint{
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Altitude:  p.Altitude,
+			Time:      p.RecordedAt,
+			Speed:     p.Speed,
+			Battery:   p.Battery,
+		})
+	}
+	return result
+}
@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"sync"
 	"time"
 
+	"drone-control-system/internal/mvc/models"
 	"drone-control-system/pkg/kafka"
 	"drone-control-system/pkg/logger"
 
@@ -24,7 +26,8 @@ type WebSocketClient struct {
 	ID     string
 	Conn   *websocket.Conn
 	Send   chan WebSocketMessage
-	UserID *uint // 可选，用于权限控制
+	UserID *uint           // 可选，用于权限控制
+	Role   models.UserRole // 全局角色，匿名连接为空
 }
 
 // WebSocketService WebSocket服务接口
@@ -32,11 +35,15 @@ type WebSocketService interface {
 	// 连接管理
 	RegisterClient(client *WebSocketClient)
 	UnregisterClient(clientID string)
-	HandleWebSocketConnection(w http.ResponseWriter, r *http.Request, userID *uint) error
+	HandleWebSocketConnection(w http.ResponseWriter, r *http.Request, userID *uint, role models.UserRole) error
 
 	// 消息广播
 	BroadcastToAll(message WebSocketMessage)
 	BroadcastToUser(userID uint, message WebSocketMessage)
+	// BroadcastToRole 发送给全局角色不低于指定角色的客户端
+	BroadcastToRole(role models.UserRole, message WebSocketMessage)
+	// BroadcastForDrone 只发送给有权查看该无人机所属机队的客户端
+	BroadcastForDrone(droneID uint, message WebSocketMessage)
 	SendToClient(clientID string, message WebSocketMessage)
 
 	// Kafka事件处理
@@ -57,16 +64,19 @@ type WebSocketServiceImpl struct {
 	upgrader   websocket.Upgrader
 	mu         sync.RWMutex
 	running    bool
+
+	fleetService FleetService // 为nil时不按机队过滤
 }
 
 // NewWebSocketService 创建WebSocket服务
-func NewWebSocketService(logger *logger.Logger) WebSocketService {
+func NewWebSocketService(logger *logger.Logger, fleetService FleetService) WebSocketService {
 	return &WebSocketServiceImpl{
-		clients:    make(map[string]*WebSocketClient),
-		register:   make(chan *WebSocketClient),
-		unregister: make(chan string),
-		broadcast:  make(chan WebSocketMessage),
-		logger:     logger,
+		clients:      make(map[string]*WebSocketClient),
+		register:     make(chan *WebSocketClient),
+		unregister:   make(chan string),
+		broadcast:    make(chan WebSocketMessage),
+		logger:       logger,
+		fleetService: fleetService,
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				// 生产环境应该检查Origin
@@ -205,6 +215,87 @@ func (ws *WebSocketServiceImpl) BroadcastToUser(userID uint, message WebSocketMe
 	}
 }
 
+// BroadcastToRole 广播消息给全局角色不低于指定角色的客户端
+func (ws *WebSocketServiceImpl) BroadcastToRole(role models.UserRole, message WebSocketMessage) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	for _, client := range ws.clients {
+		if client.Role.Level() < role.Level() {
+			continue
+		}
+		select {
+		case client.Send <- message:
+			// 成功发送
+		default:
+			// 发送失败
+			ws.logger.Warning("Failed to send message to role", map[string]interface{}{
+				"role":      role,
+				"client_id": client.ID,
+			})
+		}
+	}
+}
+
+// BroadcastForDrone 按机队权限广播无人机相关消息
+// 未分配机队的无人机消息发送给所有客户端；机队内无人机的消息只发送给全局管理员和该机队成员
+func (ws *WebSocketServiceImpl) BroadcastForDrone(droneID uint, message WebSocketMessage) {
+	if ws.fleetService == nil || droneID == 0 {
+		ws.BroadcastToAll(message)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fleetID, err := ws.fleetService.GetDroneFleet(ctx, droneID)
+	if err != nil && err != ErrDroneNotFound {
+		// 无法确定归属时只发送给管理员
+		ws.logger.WithError(err).WithField("drone_id", droneID).Warn("Failed to resolve drone fleet for websocket delivery")
+	}
+	if err == nil && fleetID == nil {
+		ws.BroadcastToAll(message)
+		return
+	}
+
+	// 在锁外查询权限，避免阻塞注册和注销
+	type recipient struct {
+		id     string
+		userID *uint
+		role   models.UserRole
+	}
+	ws.mu.RLock()
+	recipients := make([]recipient, 0, len(ws.clients))
+	for id, client := range ws.clients {
+		recipients = append(recipients, recipient{id: id, userID: client.UserID, role: client.Role})
+	}
+	ws.mu.RUnlock()
+
+	allowed := make([]string, 0, len(recipients))
+	for _, r := range recipients {
+		if r.role == models.RoleAdmin {
+			allowed = append(allowed, r.id)
+			continue
+		}
+		if err != nil || r.userID == nil {
+			continue
+		}
+
+		access, accessErr := ws.fleetService.GetAccess(ctx, *r.userID, r.role)
+		if accessErr != nil {
+			ws.logger.WithError(accessErr).WithField("user_id", *r.userID).Warn("Failed to load fleet access for websocket client")
+			continue
+		}
+		if access.Allows(fleetID, models.RoleViewer) {
+			allowed = append(allowed, r.id)
+		}
+	}
+
+	for _, clientID := range allowed {
+		ws.SendToClient(clientID, message)
+	}
+}
+
 // SendToClient 发送消息给指定客户端
 func (ws *WebSocketServiceImpl) SendToClient(clientID string, message WebSocketMessage) {
 	ws.mu.RLock()
@@ -231,19 +322,31 @@ func (ws *WebSocketServiceImpl) HandleKafkaEvent(event *kafka.Event) {
 		Timestamp: event.Timestamp,
 	}
 
-	// 根据事件类型决定广播策略
+	// 根据事件类型决定广播策略，涉及无人机的事件按机队权限过滤
 	switch event.Type {
-	case kafka.DroneLocationUpdatedEvent, kafka.DroneStatusChangedEvent, kafka.DroneBatteryLowEvent:
-		// 无人机相关事件广播给所有连接的客户端
-		ws.BroadcastToAll(message)
+	case kafka.DroneLocationUpdatedEvent, kafka.DroneStatusChangedEvent, kafka.DroneBatteryLowEvent,
+		kafka.DroneConnectedEvent, kafka.DroneDisconnectedEvent:
+		// 无人机相关事件
+		ws.BroadcastForDrone(eventDataDroneID(event.Data), message)
 
 	case kafka.AlertCreatedEvent:
-		// 告警事件广播给所有客户端
-		ws.BroadcastToAll(message)
+		// 告警事件，系统告警（无无人机）广播给所有客户端
+		ws.BroadcastForDrone(eventDataDroneID(event.Data), message)
 
 	case kafka.TaskProgressEvent, kafka.TaskCompletedEvent, kafka.TaskFailedEvent:
-		// 任务相关事件广播给所有客户端
-		ws.BroadcastToAll(message)
+		// 任务相关事件
+		ws.BroadcastForDrone(eventDataDroneID(event.Data), message)
+
+	case kafka.TaskApprovalRequestedEvent:
+		// 待审批任务通知管理员
+		ws.BroadcastToRole(models.RoleAdmin, message)
+
+	case kafka.TaskApprovalDecidedEvent:
+		// 审批结果通知提交人和其他管理员
+		if requestedBy := eventDataUint(event.Data, "requested_by"); requestedBy != 0 {
+			ws.BroadcastToUser(requestedBy, message)
+		}
+		ws.BroadcastToRole(models.RoleAdmin, message)
 
 	default:
 		// 其他事件只记录日志
@@ -255,7 +358,7 @@ func (ws *WebSocketServiceImpl) HandleKafkaEvent(event *kafka.Event) {
 }
 
 // HandleWebSocketConnection 处理WebSocket连接升级
-func (ws *WebSocketServiceImpl) HandleWebSocketConnection(w http.ResponseWriter, r *http.Request, userID *uint) error {
+func (ws *WebSocketServiceImpl) HandleWebSocketConnection(w http.ResponseWriter, r *http.Request, userID *uint, role models.UserRole) error {
 	conn, err := ws.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		return err
@@ -269,6 +372,7 @@ func (ws *WebSocketServiceImpl) HandleWebSocketConnection(w http.ResponseWriter,
 		Conn:   conn,
 		Send:   make(chan WebSocketMessage, 256),
 		UserID: userID,
+		Role:   role,
 	}
 
 	// 注册客户端
@@ -355,6 +459,25 @@ func (ws *WebSocketServiceImpl) handleClientWrites(client *WebSocketClient) {
 	}
 }
 
+// eventDataDroneID 从事件数据中取无人机ID，不存在时为0
+func eventDataDroneID(data map[string]interface{}) uint {
+	return eventDataUint(data, "drone_id")
+}
+
+// eventDataUint 从事件数据中取ID字段，不存在时为0
+func eventDataUint(data map[string]interface{}, key string) uint {
+	switch v := data[key].(type) {
+	case float64:
+		return uint(v)
+	case uint:
+		return v
+	case int:
+		return uint(v)
+	default:
+		return 0
+	}
+}
+
 // generateClientID 生成客户端ID
 func generateClientID() string {
 	return "client_" + time.Now().Format("20060102150405") + "_" + randomString(6)
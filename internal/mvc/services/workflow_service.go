@@ -0,0 +1,1201 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"drone-control-system/internal/mvc/models"
+	"drone-control-system/pkg/database"
+	"drone-control-system/pkg/kafka"
+	"drone-control-system/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// workflowLeaderKey 流程推进主节点锁
+	workflowLeaderKey = "task:workflow:leader"
+
+	// maxWorkflowSteps 单个流程允许的最大步骤数
+	maxWorkflowSteps = 50
+)
+
+// WorkflowConfig 流程执行配置
+type WorkflowConfig struct {
+	CheckInterval    time.Duration `yaml:"check_interval" json:"check_interval"`         // 推进运行中流程的巡检间隔
+	StartRetryWindow time.Duration `yaml:"start_retry_window" json:"start_retry_window"` // 步骤任务启动失败后的重试时间窗口，超出后步骤失败
+	LockTTL          time.Duration `yaml:"lock_ttl" json:"lock_ttl"`
+}
+
+// DefaultWorkflowConfig 默认流程执行配置
+func DefaultWorkflowConfig() *WorkflowConfig {
+	return &WorkflowConfig{
+		CheckInterval:    15 * time.Second,
+		StartRetryWindow: 15 * time.Minute,
+		LockTTL:          time.Minute,
+	}
+}
+
+// WorkflowDefinitionParams 创建或更新流程定义参数
+type WorkflowDefinitionParams struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Steps       models.WorkflowStepList `json:"steps"`
+	CreatedBy   uint                    `json:"created_by"`
+}
+
+// StartWorkflowParams 启动流程参数
+type StartWorkflowParams struct {
+	DefinitionID    uint        `json:"definition_id"`
+	Name            string      `json:"name"` // 为空时使用流程定义名称
+	CreatedBy       uint        `json:"created_by"`
+	AssignmentScope *FleetScope `json:"assignment_scope"` // 自动分配步骤可选择的机队范围，为nil时不限制
+}
+
+// ListWorkflowRunsParams 流程实例列表参数
+type ListWorkflowRunsParams struct {
+	Offset       int                      `json:"offset"`
+	Limit        int                      `json:"limit"`
+	DefinitionID uint                     `json:"definition_id"`
+	Status       models.WorkflowRunStatus `json:"status"`
+}
+
+// WorkflowService 多步骤流程服务接口
+// 流程定义是任务模板组成的有向无环图；执行器按依赖和条件通过 TaskService 创建并启动各步骤任务，
+// 把上游任务 Result.Data 中的值写入下游计划，并汇总流程状态和进度。多实例部署时只有主节点执行巡检。
+type WorkflowService interface {
+	// 流程定义管理
+	CreateDefinition(ctx context.Context, params *WorkflowDefinitionParams) (*models.WorkflowDefinition, error)
+	GetDefinition(ctx context.Context, id uint) (*models.WorkflowDefinition, error)
+	ListDefinitions(ctx context.Context, offset, limit int) ([]*models.WorkflowDefinition, int64, error)
+	// UpdateDefinition 更新定义，零值字段保持不变；已启动的实例使用启动时的步骤快照
+	UpdateDefinition(ctx context.Context, id uint, params *WorkflowDefinitionParams) (*models.WorkflowDefinition, error)
+	DeleteDefinition(ctx context.Context, id uint) error
+
+	// 流程执行
+	StartWorkflow(ctx context.Context, params *StartWorkflowParams) (*models.WorkflowRun, error)
+	GetRun(ctx context.Context, id uint) (*models.WorkflowRun, error)
+	ListRuns(ctx context.Context, params *ListWorkflowRunsParams) ([]*models.WorkflowRun, int64, error)
+	// GetRunTasks 获取流程实例已创建的步骤任务
+	GetRunTasks(ctx context.Context, id uint) ([]*models.Task, error)
+	// CancelRun 取消流程，停止运行中的步骤任务，未执行的步骤不再执行
+	CancelRun(ctx context.Context, id uint, reason string) (*models.WorkflowRun, error)
+	// HandleTaskFinished 步骤任务结束后立即推进所属流程，不属于流程的任务忽略
+	HandleTaskFinished(ctx context.Context, taskID uint) error
+
+	// 服务管理
+	Start(ctx context.Context) error
+	Stop() error
+}
+
+// WorkflowServiceImpl 多步骤流程服务实现
+type WorkflowServiceImpl struct {
+	config       *WorkflowConfig
+	db           *gorm.DB
+	election     *database.LeaderElection
+	taskService  TaskService
+	kafkaService KafkaService
+	logger       *logger.Logger
+
+	// advanceMu 串行化本实例内的流程推进（巡检和任务结束回调可能同时触发）
+	advanceMu sync.Mutex
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewWorkflowService 创建多步骤流程服务
+// taskService 用于创建和启动步骤任务，需要包含自动分配装饰器以支持未指定无人机的步骤
+func NewWorkflowService(
+	config *WorkflowConfig,
+	db *gorm.DB,
+	lockService *database.LockService,
+	taskService TaskService,
+	kafkaService KafkaService,
+	logger *logger.Logger,
+) WorkflowService {
+	if config == nil {
+		config = DefaultWorkflowConfig()
+	}
+
+	return &WorkflowServiceImpl{
+		config:       config,
+		db:           db,
+		election:     database.NewLeaderElection(lockService, workflowLeaderKey, config.LockTTL),
+		taskService:  taskService,
+		kafkaService: kafkaService,
+		logger:       logger,
+	}
+}
+
+// CreateDefinition 创建流程定义
+func (s *WorkflowServiceImpl) CreateDefinition(ctx context.Context, params *WorkflowDefinitionParams) (*models.WorkflowDefinition, error) {
+	if params == nil || strings.TrimSpace(params.Name) == "" || params.CreatedBy == 0 {
+		return nil, fmt.Errorf("%w: name and created_by are required", ErrInvalidData)
+	}
+	if err := validateWorkflowSteps(params.Steps); err != nil {
+		return nil, err
+	}
+
+	definition := &models.WorkflowDefinition{
+		Name:        params.Name,
+		Description: params.Description,
+		Steps:       params.Steps,
+		CreatedBy:   params.CreatedBy,
+	}
+	if err := s.db.WithContext(ctx).Create(definition).Error; err != nil {
+		return nil, fmt.Errorf("failed to create workflow definition: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"definition_id": definition.ID,
+		"steps":         len(definition.Steps),
+	}).Info("Workflow definition created")
+	return definition, nil
+}
+
+// GetDefinition 获取流程定义
+func (s *WorkflowServiceImpl) GetDefinition(ctx context.Context, id uint) (*models.WorkflowDefinition, error) {
+	var definition models.WorkflowDefinition
+	if err := s.db.WithContext(ctx).First(&definition, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkflowNotFound
+		}
+		return nil, fmt.Errorf("failed to get workflow definition: %w", err)
+	}
+	return &definition, nil
+}
+
+// ListDefinitions 获取流程定义列表
+func (s *WorkflowServiceImpl) ListDefinitions(ctx context.Context, offset, limit int) ([]*models.WorkflowDefinition, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.WorkflowDefinition{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count workflow definitions: %w", err)
+	}
+
+	var definitions []*models.WorkflowDefinition
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&definitions).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list workflow definitions: %w", err)
+	}
+	return definitions, total, nil
+}
+
+// UpdateDefinition 更新流程定义
+func (s *WorkflowServiceImpl) UpdateDefinition(ctx context.Context, id uint, params *WorkflowDefinitionParams) (*models.WorkflowDefinition, error) {
+	definition, err := s.GetDefinition(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Name != "" {
+		definition.Name = params.Name
+	}
+	if params.Description != "" {
+		definition.Description = params.Description
+	}
+	if params.Steps != nil {
+		if err := validateWorkflowSteps(params.Steps); err != nil {
+			return nil, err
+		}
+		definition.Steps = params.Steps
+	}
+
+	if err := s.db.WithContext(ctx).Save(definition).Error; err != nil {
+		return nil, fmt.Errorf("failed to update workflow definition: %w", err)
+	}
+	return definition, nil
+}
+
+// DeleteDefinition 删除流程定义，已启动的实例继续执行
+func (s *WorkflowServiceImpl) DeleteDefinition(ctx context.Context, id uint) error {
+	res := s.db.WithContext(ctx).Delete(&models.WorkflowDefinition{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete workflow definition: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrWorkflowNotFound
+	}
+	return nil
+}
+
+// StartWorkflow 按流程定义创建实例并立即启动没有依赖的步骤
+func (s *WorkflowServiceImpl) StartWorkflow(ctx context.Context, params *StartWorkflowParams) (*models.WorkflowRun, error) {
+	if params == nil || params.DefinitionID == 0 || params.CreatedBy == 0 {
+		return nil, fmt.Errorf("%w: definition_id and created_by are required", ErrInvalidData)
+	}
+
+	definition, err := s.GetDefinition(ctx, params.DefinitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	run := &models.WorkflowRun{
+		DefinitionID: definition.ID,
+		Name:         definition.Name,
+		Status:       models.WorkflowRunRunning,
+		Steps:        definition.Steps,
+		CreatedBy:    params.CreatedBy,
+		StartedAt:    &now,
+	}
+	if params.Name != "" {
+		run.Name = params.Name
+	}
+	if params.AssignmentScope != nil {
+		data, err := json.Marshal(params.AssignmentScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode assignment scope: %w", err)
+		}
+		run.AssignmentScope = string(data)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		stepRuns := make([]*models.WorkflowRunStep, 0, len(run.Steps))
+		for _, step := range run.Steps {
+			stepRuns = append(stepRuns, &models.WorkflowRunStep{
+				RunID:   run.ID,
+				StepKey: step.Key,
+				Status:  models.WorkflowStepPending,
+			})
+		}
+		return tx.Create(&stepRuns).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workflow run: %w", err)
+	}
+
+	s.logger.WithFields(map[string]interface{}{
+		"run_id":        run.ID,
+		"definition_id": definition.ID,
+		"steps":         len(run.Steps),
+	}).Info("Workflow run started")
+	s.publishRunEvent(ctx, run, "started", nil, "")
+
+	s.advance(ctx, run.ID)
+	return s.GetRun(ctx, run.ID)
+}
+
+// GetRun 获取流程实例及步骤状态
+func (s *WorkflowServiceImpl) GetRun(ctx context.Context, id uint) (*models.WorkflowRun, error) {
+	var run models.WorkflowRun
+	err := s.db.WithContext(ctx).
+		Preload("StepRuns", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&run, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorkflowRunNotFound
+		}
+		return nil, fmt.Errorf("failed to get workflow run: %w", err)
+	}
+	return &run, nil
+}
+
+// ListRuns 获取流程实例列表
+func (s *WorkflowServiceImpl) ListRuns(ctx context.Context, params *ListWorkflowRunsParams) ([]*models.WorkflowRun, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.WorkflowRun{})
+	if params.DefinitionID != 0 {
+		query = query.Where("definition_id = ?", params.DefinitionID)
+	}
+	if params.Status != "" {
+		query = query.Where("status = ?", params.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count workflow runs: %w", err)
+	}
+
+	var runs []*models.WorkflowRun
+	if err := query.Preload("StepRuns", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Order("id DESC").Offset(params.Offset).Limit(params.Limit).Find(&runs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list workflow runs: %w", err)
+	}
+	return runs, total, nil
+}
+
+// GetRunTasks 获取流程实例已创建的步骤任务
+func (s *WorkflowServiceImpl) GetRunTasks(ctx context.Context, id uint) ([]*models.Task, error) {
+	run, err := s.GetRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*models.Task, 0, len(run.StepRuns))
+	for _, step := range run.StepRuns {
+		if step.TaskID == nil {
+			continue
+		}
+		task, err := s.taskService.GetTaskByID(ctx, *step.TaskID)
+		if err != nil {
+			if err == ErrTaskNotFound {
+				continue
+			}
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// CancelRun 取消流程实例
+func (s *WorkflowServiceImpl) CancelRun(ctx context.Context, id uint, reason string) (*models.WorkflowRun, error) {
+	s.advanceMu.Lock()
+	defer s.advanceMu.Unlock()
+
+	run, err := s.GetRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.IsFinished() {
+		return nil, ErrWorkflowRunFinished
+	}
+	if reason == "" {
+		reason = "workflow cancelled"
+	}
+
+	res := s.db.WithContext(ctx).Model(&models.WorkflowRun{}).
+		Where("id = ? AND status = ?", run.ID, models.WorkflowRunRunning).
+		Updates(map[string]interface{}{
+			"status":       models.WorkflowRunCancelled,
+			"error":        reason,
+			"completed_at": time.Now(),
+		})
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to cancel workflow run: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrWorkflowRunFinished
+	}
+
+	for _, step := range run.StepRuns {
+		switch step.Status {
+		case models.WorkflowStepPending:
+			s.finishStep(ctx, step, models.WorkflowStepCancelled, reason)
+		case models.WorkflowStepRunning:
+			if step.TaskID != nil {
+				s.cancelStepTask(ctx, *step.TaskID, reason)
+			}
+			s.finishStep(ctx, step, models.WorkflowStepCancelled, reason)
+		}
+	}
+
+	run, err = s.GetRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.WithFields(map[string]interface{}{
+		"run_id": run.ID,
+		"reason": reason,
+	}).Info("Workflow run cancelled")
+	s.publishRunEvent(ctx, run, "cancelled", nil, reason)
+	return run, nil
+}
+
+// HandleTaskFinished 推进任务所属的流程实例
+func (s *WorkflowServiceImpl) HandleTaskFinished(ctx context.Context, taskID uint) error {
+	var step models.WorkflowRunStep
+	err := s.db.WithContext(ctx).
+		Joins("JOIN workflow_runs ON workflow_runs.id = workflow_run_steps.run_id").
+		Where("workflow_run_steps.task_id = ? AND workflow_runs.status = ?", taskID, models.WorkflowRunRunning).
+		First(&step).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to find workflow step: %w", err)
+	}
+
+	s.advance(ctx, step.RunID)
+	return nil
+}
+
+// Start 启动流程巡检
+func (s *WorkflowServiceImpl) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+
+	s.wg.Add(1)
+	go s.advanceLoop()
+
+	s.logger.WithFields(map[string]interface{}{
+		"check_interval": s.config.CheckInterval.String(),
+		"instance":       s.election.Identity(),
+	}).Info("Workflow executor started")
+	return nil
+}
+
+// Stop 停止流程巡检并释放主节点锁
+func (s *WorkflowServiceImpl) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return nil
+	}
+
+	s.cancel()
+	s.wg.Wait()
+	s.running = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := s.election.Resign(ctx); err != nil {
+		s.logger.WithError(err).Warn("Failed to release workflow leader lock")
+	}
+
+	s.logger.Info("Workflow executor stopped")
+	return nil
+}
+
+// advanceLoop 周期推进运行中的流程，补充处理未经过任务服务回调结束的任务
+func (s *WorkflowServiceImpl) advanceLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			leader, err := s.election.TryAcquire(s.ctx)
+			if err != nil {
+				s.logger.WithError(err).Warn("Workflow leader election failed")
+				continue
+			}
+			if !leader {
+				continue
+			}
+
+			var ids []uint
+			if err := s.db.WithContext(s.ctx).Model(&models.WorkflowRun{}).
+				Where("status = ?", models.WorkflowRunRunning).
+				Order("id").Pluck("id", &ids).Error; err != nil {
+				s.logger.WithError(err).Error("Failed to load running workflow runs")
+				continue
+			}
+			for _, id := range ids {
+				if s.ctx.Err() != nil {
+					return
+				}
+				s.advance(s.ctx, id)
+			}
+		}
+	}
+}
+
+// advance 推进流程实例：同步运行中步骤的任务状态，启动满足依赖的步骤，跳过条件不满足的步骤，全部结束后汇总结果
+func (s *WorkflowServiceImpl) advance(ctx context.Context, runID uint) {
+	s.advanceMu.Lock()
+	defer s.advanceMu.Unlock()
+
+	log := s.logger.WithField("run_id", runID)
+	run, err := s.GetRun(ctx, runID)
+	if err != nil {
+		log.WithError(err).Error("Failed to load workflow run")
+		return
+	}
+	if run.Status != models.WorkflowRunRunning {
+		return
+	}
+
+	steps := make(map[string]*models.WorkflowRunStep, len(run.StepRuns))
+	for _, step := range run.StepRuns {
+		steps[step.StepKey] = step
+		if step.Status == models.WorkflowStepRunning {
+			s.syncStep(ctx, run, step)
+		}
+	}
+
+	// 步骤结束可能使下游步骤就绪或被跳过，重复直到没有变化
+	for changed := true; changed; {
+		changed = false
+		for i := range run.Steps {
+			def := &run.Steps[i]
+			step := steps[def.Key]
+			if step == nil || step.Status != models.WorkflowStepPending {
+				continue
+			}
+
+			ready, satisfied := dependenciesMet(def, steps)
+			if !ready {
+				continue
+			}
+			if !satisfied {
+				s.finishStep(ctx, step, models.WorkflowStepSkipped, "dependency condition not met")
+				s.publishRunEvent(ctx, run, "step_finished", step, "")
+				changed = true
+				continue
+			}
+
+			s.launchStep(ctx, run, def, step, steps)
+			changed = changed || step.IsFinished()
+		}
+	}
+
+	s.finalize(ctx, run, steps)
+}
+
+// syncStep 按步骤任务的当前状态更新步骤，未启动的任务在重试窗口内重新启动
+func (s *WorkflowServiceImpl) syncStep(ctx context.Context, run *models.WorkflowRun, step *models.WorkflowRunStep) {
+	log := s.logger.WithFields(map[string]interface{}{"run_id": run.ID, "step": step.StepKey})
+
+	if step.TaskID == nil {
+		// 其他实例正在创建任务，超过重试窗口仍未创建视为失败
+		if step.StartedAt != nil && time.Since(*step.StartedAt) > s.config.StartRetryWindow {
+			s.finishStep(ctx, step, models.WorkflowStepFailed, "step task was not created")
+			s.publishRunEvent(ctx, run, "step_finished", step, "")
+		}
+		return
+	}
+
+	task, err := s.taskService.GetTaskByID(ctx, *step.TaskID)
+	if err != nil {
+		if err == ErrTaskNotFound {
+			s.finishStep(ctx, step, models.WorkflowStepFailed, "step task was deleted")
+			s.publishRunEvent(ctx, run, "step_finished", step, "")
+			return
+		}
+		log.WithError(err).Warn("Failed to load workflow step task")
+		return
+	}
+
+	switch {
+	case task.Status == models.TaskStatusCancelled:
+		s.finishStep(ctx, step, models.WorkflowStepCancelled, taskFailureMessage(task))
+		s.publishRunEvent(ctx, run, "step_finished", step, "")
+	case task.IsCompleted():
+		// 按任务结果分支，成功的任务进度记为100
+		if task.Result.Success {
+			s.finishStep(ctx, step, models.WorkflowStepCompleted, "")
+		} else {
+			s.finishStep(ctx, step, models.WorkflowStepFailed, taskFailureMessage(task))
+		}
+		s.publishRunEvent(ctx, run, "step_finished", step, "")
+	case task.IsRunning():
+		if task.Progress != step.Progress {
+			step.Progress = task.Progress
+			if err := s.db.WithContext(ctx).Model(step).Update("progress", task.Progress).Error; err != nil {
+				log.WithError(err).Warn("Failed to update workflow step progress")
+			}
+		}
+	case task.Status == models.TaskStatusScheduled && task.ScheduledAt != nil && task.ScheduledAt.After(time.Now()):
+		// 失败后等待重试的任务由调度器按退避时间启动
+	case task.CanStart():
+		s.startStepTask(ctx, run, step)
+	}
+}
+
+// launchStep 认领就绪步骤，写入上游结果后创建并启动步骤任务
+func (s *WorkflowServiceImpl) launchStep(ctx context.Context, run *models.WorkflowRun, def *models.WorkflowStep, step *models.WorkflowRunStep, steps map[string]*models.WorkflowRunStep) {
+	log := s.logger.WithFields(map[string]interface{}{"run_id": run.ID, "step": step.StepKey})
+
+	// 先认领步骤再创建任务，其他实例同时推进时不会重复创建
+	now := time.Now()
+	res := s.db.WithContext(ctx).Model(&models.WorkflowRunStep{}).
+		Where("id = ? AND status = ?", step.ID, models.WorkflowStepPending).
+		Updates(map[string]interface{}{
+			"status":     models.WorkflowStepRunning,
+			"started_at": now,
+		})
+	if res.Error != nil {
+		log.WithError(res.Error).Error("Failed to claim workflow step")
+		return
+	}
+	if res.RowsAffected == 0 {
+		step.Status = models.WorkflowStepRunning
+		return
+	}
+	step.Status = models.WorkflowStepRunning
+	step.StartedAt = &now
+
+	plan := def.Plan
+	if err := s.resolveInputs(ctx, def, steps, &plan); err != nil {
+		s.finishStep(ctx, step, models.WorkflowStepFailed, err.Error())
+		s.publishRunEvent(ctx, run, "step_finished", step, "")
+		return
+	}
+
+	params := &CreateTaskParams{
+		Name:                 def.TaskName,
+		Description:          def.TaskDescription,
+		Type:                 def.TaskType,
+		Priority:             def.TaskPriority,
+		UserID:               run.CreatedBy,
+		DroneID:              def.DroneID,
+		Plan:                 plan,
+		WorkflowRunID:        &run.ID,
+		RequiredCapabilities: def.RequiredCapabilities,
+		Delivery:             def.Delivery,
+	}
+	if params.Priority == "" {
+		params.Priority = models.TaskPriorityNormal
+	}
+	if params.DroneID == 0 && run.AssignmentScope != "" {
+		var scope FleetScope
+		if err := json.Unmarshal([]byte(run.AssignmentScope), &scope); err != nil {
+			s.finishStep(ctx, step, models.WorkflowStepFailed, fmt.Sprintf("invalid assignment scope: %v", err))
+			s.publishRunEvent(ctx, run, "step_finished", step, "")
+			return
+		}
+		params.AssignmentScope = &scope
+	}
+
+	task, err := s.taskService.CreateTask(ctx, params)
+	if err != nil {
+		log.WithError(err).Warn("Failed to create workflow step task")
+		s.finishStep(ctx, step, models.WorkflowStepFailed, err.Error())
+		s.publishRunEvent(ctx, run, "step_finished", step, "")
+		return
+	}
+
+	step.TaskID = &task.ID
+	if err := s.db.WithContext(ctx).Model(step).Update("task_id", task.ID).Error; err != nil {
+		log.WithError(err).Error("Failed to record workflow step task")
+		return
+	}
+	s.publishRunEvent(ctx, run, "step_started", step, "")
+
+	s.startStepTask(ctx, run, step)
+}
+
+// startStepTask 启动步骤任务，失败时在重试窗口内等待下一轮巡检
+func (s *WorkflowServiceImpl) startStepTask(ctx context.Context, run *models.WorkflowRun, step *models.WorkflowRunStep) {
+	err := s.taskService.StartTask(ctx, *step.TaskID)
+	if err == nil {
+		return
+	}
+
+	fields := map[string]interface{}{
+		"run_id":  run.ID,
+		"step":    step.StepKey,
+		"task_id": *step.TaskID,
+	}
+	if step.StartedAt != nil && time.Since(*step.StartedAt) > s.config.StartRetryWindow {
+		s.logger.WithError(err).WithFields(fields).Error("Workflow step task could not be started, giving up")
+		message := fmt.Sprintf("task could not be started: %v", err)
+		s.cancelStepTask(ctx, *step.TaskID, message)
+		s.finishStep(ctx, step, models.WorkflowStepFailed, message)
+		s.publishRunEvent(ctx, run, "step_finished", step, "")
+		return
+	}
+
+	// 无人机忙碌、起飞前检查未通过等情况下一轮重试
+	s.logger.WithError(err).WithFields(fields).Warn("Failed to start workflow step task, will retry")
+	if err := s.db.WithContext(ctx).Model(step).Update("error", err.Error()).Error; err != nil {
+		s.logger.WithError(err).WithFields(fields).Warn("Failed to record workflow step start error")
+	}
+}
+
+// resolveInputs 将上游任务 Result.Data 中的值写入步骤计划
+func (s *WorkflowServiceImpl) resolveInputs(ctx context.Context, def *models.WorkflowStep, steps map[string]*models.WorkflowRunStep, plan *models.TaskPlan) error {
+	results := make(map[string]interface{})
+	for _, input := range def.Inputs {
+		data, ok := results[input.Step]
+		if !ok {
+			var err error
+			data, err = s.resultData(ctx, steps[input.Step])
+			if err != nil {
+				return fmt.Errorf("failed to read result of step %s: %w", input.Step, err)
+			}
+			results[input.Step] = data
+		}
+
+		value, found := lookupResultPath(data, input.Path)
+		if !found {
+			if input.Optional {
+				continue
+			}
+			return fmt.Errorf("result of step %s has no value at %q", input.Step, input.Path)
+		}
+		if err := setPlanField(plan, input.Target, value); err != nil {
+			return fmt.Errorf("cannot use result of step %s as %s: %w", input.Step, input.Target, err)
+		}
+	}
+	return nil
+}
+
+// resultData 解析上游步骤任务的结果数据，步骤未执行或没有结果时返回nil
+func (s *WorkflowServiceImpl) resultData(ctx context.Context, step *models.WorkflowRunStep) (interface{}, error) {
+	if step == nil || step.TaskID == nil {
+		return nil, nil
+	}
+	task, err := s.taskService.GetTaskByID(ctx, *step.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(task.Result.Data) == "" {
+		return nil, nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(task.Result.Data), &data); err != nil {
+		return nil, fmt.Errorf("result data is not valid JSON: %w", err)
+	}
+	return data, nil
+}
+
+// finalize 更新流程进度，所有步骤结束后汇总流程结果
+func (s *WorkflowServiceImpl) finalize(ctx context.Context, run *models.WorkflowRun, steps map[string]*models.WorkflowRunStep) {
+	progress, finished := 0, true
+	for _, step := range steps {
+		if step.IsFinished() {
+			progress += 100
+		} else {
+			progress += step.Progress
+			finished = false
+		}
+	}
+	if len(steps) > 0 {
+		progress /= len(steps)
+	}
+
+	if !finished {
+		if progress != run.Progress {
+			if err := s.db.WithContext(ctx).Model(&models.WorkflowRun{}).Where("id = ?", run.ID).
+				Update("progress", progress).Error; err != nil {
+				s.logger.WithError(err).WithField("run_id", run.ID).Warn("Failed to update workflow progress")
+			}
+		}
+		return
+	}
+
+	status, reason := models.WorkflowRunCompleted, ""
+	if failed := unhandledFailures(run.Steps, steps); len(failed) > 0 {
+		status = models.WorkflowRunFailed
+		reason = fmt.Sprintf("steps failed: %s", strings.Join(failed, ", "))
+	}
+
+	res := s.db.WithContext(ctx).Model(&models.WorkflowRun{}).
+		Where("id = ? AND status = ?", run.ID, models.WorkflowRunRunning).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"progress":     100,
+			"error":        reason,
+			"completed_at": time.Now(),
+		})
+	if res.Error != nil {
+		s.logger.WithError(res.Error).WithField("run_id", run.ID).Error("Failed to finish workflow run")
+		return
+	}
+	if res.RowsAffected == 0 {
+		return
+	}
+
+	run.Status = status
+	run.Progress = 100
+	s.logger.WithFields(map[string]interface{}{
+		"run_id": run.ID,
+		"status": status,
+		"reason": reason,
+	}).Info("Workflow run finished")
+	s.publishRunEvent(ctx, run, string(status), nil, reason)
+}
+
+// finishStep 结束步骤
+func (s *WorkflowServiceImpl) finishStep(ctx context.Context, step *models.WorkflowRunStep, status models.WorkflowStepStatus, message string) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":       status,
+		"error":        message,
+		"completed_at": now,
+	}
+	if status == models.WorkflowStepCompleted {
+		updates["progress"] = 100
+	}
+
+	res := s.db.WithContext(ctx).Model(&models.WorkflowRunStep{}).
+		Where("id = ? AND status IN ?", step.ID, []models.WorkflowStepStatus{models.WorkflowStepPending, models.WorkflowStepRunning}).
+		Updates(updates)
+	if res.Error != nil {
+		s.logger.WithError(res.Error).WithField("step_id", step.ID).Error("Failed to finish workflow step")
+		return
+	}
+
+	step.Status = status
+	step.Error = message
+	step.CompletedAt = &now
+	if status == models.WorkflowStepCompleted {
+		step.Progress = 100
+	}
+}
+
+// cancelStepTask 停止步骤任务，未启动的任务直接标记为已取消
+func (s *WorkflowServiceImpl) cancelStepTask(ctx context.Context, taskID uint, reason string) {
+	res := s.db.WithContext(ctx).Model(&models.Task{}).
+		Where("id = ? AND status IN ?", taskID, []models.TaskStatus{models.TaskStatusPending, models.TaskStatusPendingApproval, models.TaskStatusScheduled}).
+		Updates(map[string]interface{}{
+			"status":              models.TaskStatusCancelled,
+			"completed_at":        time.Now(),
+			"result_success":      false,
+			"result_error_code":   "WORKFLOW_CANCELLED",
+			"result_error_detail": reason,
+		})
+	if res.Error != nil {
+		s.logger.WithError(res.Error).WithField("task_id", taskID).Warn("Failed to cancel workflow step task")
+		return
+	}
+	if res.RowsAffected > 0 {
+		return
+	}
+
+	if err := s.taskService.StopTask(ctx, taskID); err != nil && err != ErrTaskNotRunning {
+		s.logger.WithError(err).WithField("task_id", taskID).Warn("Failed to stop workflow step task")
+	}
+}
+
+// publishRunEvent 发布流程实例事件
+func (s *WorkflowServiceImpl) publishRunEvent(ctx context.Context, run *models.WorkflowRun, action string, step *models.WorkflowRunStep, reason string) {
+	if s.kafkaService == nil {
+		return
+	}
+
+	eventData := kafka.WorkflowRunEventData{
+		RunID:        run.ID,
+		DefinitionID: run.DefinitionID,
+		Name:         run.Name,
+		Status:       string(run.Status),
+		Progress:     run.Progress,
+		Action:       action,
+		Reason:       reason,
+		Timestamp:    time.Now(),
+	}
+	if step != nil {
+		eventData.Step = step.StepKey
+		eventData.StepStatus = string(step.Status)
+		eventData.TaskID = step.TaskID
+		if eventData.Reason == "" {
+			eventData.Reason = step.Error
+		}
+	}
+
+	if err := s.kafkaService.PublishTaskEvent(ctx, kafka.WorkflowRunStatusEvent, eventData); err != nil {
+		s.logger.WithError(err).WithField("run_id", run.ID).Warn("Failed to publish workflow event")
+	}
+}
+
+// validateWorkflowSteps 校验流程步骤：标识唯一、依赖存在且无环、数据来源是上游步骤
+func validateWorkflowSteps(steps models.WorkflowStepList) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("%w: at least one step is required", ErrInvalidWorkflow)
+	}
+	if len(steps) > maxWorkflowSteps {
+		return fmt.Errorf("%w: at most %d steps are allowed", ErrInvalidWorkflow, maxWorkflowSteps)
+	}
+
+	keys := make(map[string]bool, len(steps))
+	for i := range steps {
+		step := &steps[i]
+		if step.Key == "" || len(step.Key) > 64 {
+			return fmt.Errorf("%w: step %d: key is required and must be at most 64 characters", ErrInvalidWorkflow, i)
+		}
+		if keys[step.Key] {
+			return fmt.Errorf("%w: duplicate step key %q", ErrInvalidWorkflow, step.Key)
+		}
+		keys[step.Key] = true
+
+		if strings.TrimSpace(step.TaskName) == "" {
+			return fmt.Errorf("%w: step %s: task_name is required", ErrInvalidWorkflow, step.Key)
+		}
+		if !validTaskType(step.TaskType) {
+			return fmt.Errorf("%w: step %s: unknown task_type %q", ErrInvalidWorkflow, step.Key, step.TaskType)
+		}
+		if step.TaskPriority != "" && !validTaskPriority(step.TaskPriority) {
+			return fmt.Errorf("%w: step %s: unknown task_priority %q", ErrInvalidWorkflow, step.Key, step.TaskPriority)
+		}
+		if step.RequiredCapabilities != nil {
+			if err := step.RequiredCapabilities.Validate(); err != nil {
+				return fmt.Errorf("%w: step %s: %v", ErrInvalidCapabilities, step.Key, err)
+			}
+		}
+		if (step.TaskType == models.TaskTypeDelivery) != (step.Delivery != nil) {
+			return fmt.Errorf("%w: step %s: delivery details are required for and only allowed on delivery steps", ErrInvalidWorkflow, step.Key)
+		}
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		seen := make(map[string]bool, len(step.DependsOn))
+		for _, dep := range step.DependsOn {
+			if !keys[dep.Step] {
+				return fmt.Errorf("%w: step %s depends on unknown step %q", ErrInvalidWorkflow, step.Key, dep.Step)
+			}
+			if dep.Step == step.Key || seen[dep.Step] {
+				return fmt.Errorf("%w: step %s has an invalid dependency on %q", ErrInvalidWorkflow, step.Key, dep.Step)
+			}
+			if !dep.Condition.IsValid() {
+				return fmt.Errorf("%w: step %s: unknown condition %q", ErrInvalidWorkflow, step.Key, dep.Condition)
+			}
+			seen[dep.Step] = true
+		}
+	}
+
+	if cycle := findWorkflowCycle(steps); cycle != "" {
+		return fmt.Errorf("%w: dependency cycle through step %q", ErrInvalidWorkflow, cycle)
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		ancestors := workflowAncestors(steps, step.Key)
+		for _, input := range step.Inputs {
+			if !ancestors[input.Step] {
+				return fmt.Errorf("%w: step %s reads from %q which is not one of its dependencies", ErrInvalidWorkflow, step.Key, input.Step)
+			}
+			if !models.WorkflowInputTargets[input.Target] {
+				return fmt.Errorf("%w: step %s: unknown input target %q", ErrInvalidWorkflow, step.Key, input.Target)
+			}
+		}
+	}
+	return nil
+}
+
+// findWorkflowCycle 检查依赖是否有环，返回环上的一个步骤
+func findWorkflowCycle(steps models.WorkflowStepList) string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(steps))
+
+	var visit func(key string) string
+	visit = func(key string) string {
+		switch state[key] {
+		case visiting:
+			return key
+		case done:
+			return ""
+		}
+		state[key] = visiting
+		if step := steps.Find(key); step != nil {
+			for _, dep := range step.DependsOn {
+				if cycle := visit(dep.Step); cycle != "" {
+					return cycle
+				}
+			}
+		}
+		state[key] = done
+		return ""
+	}
+
+	for i := range steps {
+		if cycle := visit(steps[i].Key); cycle != "" {
+			return cycle
+		}
+	}
+	return ""
+}
+
+// workflowAncestors 步骤的所有直接和间接依赖
+func workflowAncestors(steps models.WorkflowStepList, key string) map[string]bool {
+	ancestors := make(map[string]bool)
+	queue := []string{key}
+	for len(queue) > 0 {
+		step := steps.Find(queue[0])
+		queue = queue[1:]
+		if step == nil {
+			continue
+		}
+		for _, dep := range step.DependsOn {
+			if !ancestors[dep.Step] {
+				ancestors[dep.Step] = true
+				queue = append(queue, dep.Step)
+			}
+		}
+	}
+	return ancestors
+}
+
+// dependenciesMet 返回依赖是否都已结束，以及是否都满足条件
+func dependenciesMet(def *models.WorkflowStep, steps map[string]*models.WorkflowRunStep) (ready, satisfied bool) {
+	satisfied = true
+	for _, dep := range def.DependsOn {
+		upstream := steps[dep.Step]
+		if upstream == nil {
+			return true, false
+		}
+		if !upstream.IsFinished() {
+			return false, false
+		}
+		if !upstream.Satisfies(dep.EffectiveCondition()) {
+			satisfied = false
+		}
+	}
+	return true, satisfied
+}
+
+// unhandledFailures 失败且没有下游步骤处理（on_failure 或 always）的步骤
+func unhandledFailures(defs models.WorkflowStepList, steps map[string]*models.WorkflowRunStep) []string {
+	handled := make(map[string]bool)
+	for _, def := range defs {
+		for _, dep := range def.DependsOn {
+			if c := dep.EffectiveCondition(); c == models.WorkflowOnFailure || c == models.WorkflowAlways {
+				handled[dep.Step] = true
+			}
+		}
+	}
+
+	var failed []string
+	for _, def := range defs {
+		step := steps[def.Key]
+		if step == nil || handled[def.Key] {
+			continue
+		}
+		if step.Status == models.WorkflowStepFailed || step.Status == models.WorkflowStepCancelled {
+			failed = append(failed, def.Key)
+		}
+	}
+	return failed
+}
+
+// lookupResultPath 按点分路径读取结果数据，数字段用作数组下标，空路径返回整个结果
+func lookupResultPath(data interface{}, path string) (interface{}, bool) {
+	if data == nil {
+		return nil, false
+	}
+	if path == "" {
+		return data, true
+	}
+
+	current := data
+	for _, part := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[part]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
+
+// setPlanField 按JSON字段名替换计划中的字段
+func setPlanField(plan *models.TaskPlan, target string, value interface{}) error {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	fields[target] = encoded
+
+	if data, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	var updated models.TaskPlan
+	if err := json.Unmarshal(data, &updated); err != nil {
+		return err
+	}
+	*plan = updated
+	return nil
+}
+
+// taskFailureMessage 任务失败原因
+func taskFailureMessage(task *models.Task) string {
+	switch {
+	case task.Result.ErrorDetail != "":
+		return task.Result.ErrorDetail
+	case task.Result.Message != "":
+		return task.Result.Message
+	default:
+		return fmt.Sprintf("task %s", task.Status)
+	}
+}
+
+// validTaskType 检查任务类型是否合法
+func validTaskType(t models.TaskType) bool {
+	switch t {
+	case models.TaskTypeInspection, models.TaskTypeDelivery, models.TaskTypeMapping,
+		models.TaskTypePatrol, models.TaskTypeEmergency:
+		return true
+	}
+	return false
+}
+
+// validTaskPriority 检查任务优先级是否合法
+func validTaskPriority(p models.TaskPriority) bool {
+	switch p {
+	case models.TaskPriorityLow, models.TaskPriorityNormal, models.TaskPriorityHigh, models.TaskPriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// WorkflowTaskService 步骤任务结束后立即推进所属流程的任务服务装饰器
+type WorkflowTaskService struct {
+	TaskService
+	workflowService WorkflowService
+	logger          *logger.Logger
+}
+
+// NewWorkflowTaskService 创建流程推进任务服务
+func NewWorkflowTaskService(next TaskService, workflowService WorkflowService, logger *logger.Logger) TaskService {
+	return &WorkflowTaskService{
+		TaskService:     next,
+		workflowService: workflowService,
+		logger:          logger,
+	}
+}
+
+// CompleteTask 完成后推进流程
+func (s *WorkflowTaskService) CompleteTask(ctx context.Context, id uint, success bool, message string) error {
+	if err := s.TaskService.CompleteTask(ctx, id, success, message); err != nil {
+		return err
+	}
+	s.notify(ctx, id)
+	return nil
+}
+
+// StopTask 停止后推进流程
+func (s *WorkflowTaskService) StopTask(ctx context.Context, id uint) error {
+	if err := s.TaskService.StopTask(ctx, id); err != nil {
+		return err
+	}
+	s.notify(ctx, id)
+	return nil
+}
+
+// notify 推进任务所属的流程，失败只记录日志，由巡检补充处理
+func (s *WorkflowTaskService) notify(ctx context.Context, id uint) {
+	if err := s.workflowService.HandleTaskFinished(ctx, id); err != nil {
+		s.logger.WithError(err).WithField("task_id", id).Warn("Failed to advance workflow after task finished")
+	}
+}
@@ -32,11 +32,8 @@ func ModelToDroneView(drone *models.Drone) *DroneView {
 		return nil
 	}
 
-	// 解析capabilities JSON字符串
-	var capabilities []string
-	if drone.Capabilities != "" {
-		json.Unmarshal([]byte(drone.Capabilities), &capabilities)
-	}
+	// 解析能力数据，格式错误时忽略
+	capabilities, _ := drone.ParseCapabilities()
 
 	return &DroneView{
 		ID:           drone.ID,
@@ -62,23 +59,32 @@ func ModelToTaskView(task *models.Task) *TaskView {
 		return nil
 	}
 
+	requirements, _ := task.ParseRequiredCapabilities()
+
+	var energy *models.EnergyEstimate
+	if !task.Energy.IsZero() {
+		energy = &task.Energy
+	}
+
 	return &TaskView{
-		ID:          task.ID,
-		Name:        task.Name,
-		Description: task.Description,
-		Type:        task.Type,
-		Status:      task.Status,
-		Priority:    task.Priority,
-		Progress:    task.Progress,
-		User:        ModelToUserView(&task.User),
-		Drone:       ModelToDroneView(&task.Drone),
-		Plan:        task.Plan,
-		Result:      task.Result,
-		ScheduledAt: task.ScheduledAt,
-		StartedAt:   task.StartedAt,
-		CompletedAt: task.CompletedAt,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
+		ID:                   task.ID,
+		Name:                 task.Name,
+		Description:          task.Description,
+		Type:                 task.Type,
+		Status:               task.Status,
+		Priority:             task.Priority,
+		Progress:             task.Progress,
+		User:                 ModelToUserView(&task.User),
+		Drone:                ModelToDroneView(&task.Drone),
+		Plan:                 task.Plan,
+		RequiredCapabilities: requirements,
+		Result:               task.Result,
+		Energy:               energy,
+		ScheduledAt:          task.ScheduledAt,
+		StartedAt:            task.StartedAt,
+		CompletedAt:          task.CompletedAt,
+		CreatedAt:            task.CreatedAt,
+		UpdatedAt:            task.UpdatedAt,
 	}
 }
 
@@ -206,6 +212,8 @@ func FormatTaskStatus(status models.TaskStatus) string {
 	switch status {
 	case models.TaskStatusPending:
 		return "待处理"
+	case models.TaskStatusPendingApproval:
+		return "待审批"
 	case models.TaskStatusScheduled:
 		return "已调度"
 	case models.TaskStatusRunning:
@@ -44,40 +44,42 @@ type UserView struct {
 
 // DroneView 无人机视图
 type DroneView struct {
-	ID           uint               `json:"id"`
-	SerialNo     string             `json:"serial_no"`
-	Model        string             `json:"model"`
-	Status       models.DroneStatus `json:"status"`
-	Battery      int                `json:"battery"`
-	Position     models.Position    `json:"position"`
-	LastSeen     *time.Time         `json:"last_seen,omitempty"`
-	Capabilities []string           `json:"capabilities,omitempty"`
-	Firmware     string             `json:"firmware,omitempty"`
-	Version      string             `json:"version,omitempty"`
-	IsOnline     bool               `json:"is_online"`
-	IsAvailable  bool               `json:"is_available"`
-	CreatedAt    time.Time          `json:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at"`
+	ID           uint                      `json:"id"`
+	SerialNo     string                    `json:"serial_no"`
+	Model        string                    `json:"model"`
+	Status       models.DroneStatus        `json:"status"`
+	Battery      int                       `json:"battery"`
+	Position     models.Position           `json:"position"`
+	LastSeen     *time.Time                `json:"last_seen,omitempty"`
+	Capabilities *models.DroneCapabilities `json:"capabilities,omitempty"`
+	Firmware     string                    `json:"firmware,omitempty"`
+	Version      string                    `json:"version,omitempty"`
+	IsOnline     bool                      `json:"is_online"`
+	IsAvailable  bool                      `json:"is_available"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
 }
 
 // TaskView 任务视图
 type TaskView struct {
-	ID          uint                `json:"id"`
-	Name        string              `json:"name"`
-	Description string              `json:"description,omitempty"`
-	Type        models.TaskType     `json:"type"`
-	Status      models.TaskStatus   `json:"status"`
-	Priority    models.TaskPriority `json:"priority"`
-	Progress    int                 `json:"progress"`
-	User        *UserView           `json:"user,omitempty"`
-	Drone       *DroneView          `json:"drone,omitempty"`
-	Plan        models.TaskPlan     `json:"plan,omitempty"`
-	Result      models.TaskResult   `json:"result,omitempty"`
-	ScheduledAt *time.Time          `json:"scheduled_at,omitempty"`
-	StartedAt   *time.Time          `json:"started_at,omitempty"`
-	CompletedAt *time.Time          `json:"completed_at,omitempty"`
-	CreatedAt   time.Time           `json:"created_at"`
-	UpdatedAt   time.Time           `json:"updated_at"`
+	ID                   uint                           `json:"id"`
+	Name                 string                         `json:"name"`
+	Description          string                         `json:"description,omitempty"`
+	Type                 models.TaskType                `json:"type"`
+	Status               models.TaskStatus              `json:"status"`
+	Priority             models.TaskPriority            `json:"priority"`
+	Progress             int                            `json:"progress"`
+	User                 *UserView                      `json:"user,omitempty"`
+	Drone                *DroneView                     `json:"drone,omitempty"`
+	Plan                 models.TaskPlan                `json:"plan,omitempty"`
+	RequiredCapabilities *models.CapabilityRequirements `json:"required_capabilities,omitempty"`
+	Result               models.TaskResult              `json:"result,omitempty"`
+	Energy               *models.EnergyEstimate         `json:"energy,omitempty"`
+	ScheduledAt          *time.Time                     `json:"scheduled_at,omitempty"`
+	StartedAt            *time.Time                     `json:"started_at,omitempty"`
+	CompletedAt          *time.Time                     `json:"completed_at,omitempty"`
+	CreatedAt            time.Time                      `json:"created_at"`
+	UpdatedAt            time.Time                      `json:"updated_at"`
 }
 
 // AlertView 告警视图
@@ -0,0 +1,47 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrNotFound 对象不存在
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey 对象键不合法（为空、绝对路径或包含 ..）
+	ErrInvalidKey = errors.New("invalid blob key")
+	// ErrOffsetMismatch 续写位置与对象当前大小不一致
+	ErrOffsetMismatch = errors.New("append offset does not match blob size")
+)
+
+// Info 对象信息
+type Info struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Object 打开的对象，支持随机读取（提取元数据和分段下载）
+type Object interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// Store 二进制对象存储
+// 键是以 / 分隔的相对路径，由调用方生成，实现需要拒绝越出存储根目录的键
+type Store interface {
+	// Put 写入完整对象，已存在时覆盖，返回写入的字节数
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Append 从 offset 处续写对象，offset 必须等于当前大小（不存在时为0），返回续写后的大小
+	Append(ctx context.Context, key string, offset int64, r io.Reader) (int64, error)
+	// Open 打开对象用于读取
+	Open(ctx context.Context, key string) (Object, error)
+	// Stat 获取对象信息
+	Stat(ctx context.Context, key string) (*Info, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,482 @@
+// Package coverage 生成测绘区域的往返式（割草机）覆盖航线
+//
+// 区域多边形投影到局部平面并旋转到扫描方向，按相机地面覆盖宽度和旁向重叠计算扫描线间距，
+// 每条扫描线与多边形求交得到航段，航段内按航向重叠等距布置拍照点。航段两端各外延转弯余量，
+// 掉头在测区外完成，进入航段时已恢复直线稳定飞行。未指定扫描方向时依次尝试多边形各边的方向，
+// 选择估算飞行时间最短的方向。多机并行时按扫描顺序把扫描线切分为连续的若干组，
+// 使各组估算飞行时间的最大值最小。
+package coverage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"drone-control-system/pkg/geo"
+)
+
+var (
+	// ErrInvalidArea 测区多边形无效
+	ErrInvalidArea = errors.New("invalid coverage area")
+	// ErrInvalidParams 相机、重叠率或飞行参数无效
+	ErrInvalidParams = errors.New("invalid coverage parameters")
+	// ErrTooManyParts 扫描线数量少于切分份数
+	ErrTooManyParts = errors.New("not enough scan lines to split area")
+)
+
+// 单个测区允许的最大扫描线数，防止参数错误时计算量失控
+const maxLines = 5000
+
+// MaxOverlap 允许的最大重叠率（%）
+const MaxOverlap = 95
+
+// Camera 相机视场角
+type Camera struct {
+	HorizontalFOV float64 `json:"horizontal_fov"` // 垂直于航线方向的视场角（度）
+	VerticalFOV   float64 `json:"vertical_fov"`   // 沿航线方向的视场角（度）
+}
+
+// Params 覆盖规划参数
+type Params struct {
+	Polygon      []geo.Point `json:"polygon"`
+	Altitude     float64     `json:"altitude"` // 相对地面飞行高度（米）
+	Camera       Camera      `json:"camera"`
+	FrontOverlap float64     `json:"front_overlap"` // 航向重叠率（%）
+	SideOverlap  float64     `json:"side_overlap"`  // 旁向重叠率（%）
+	Angle        *float64    `json:"angle"`         // 扫描线方位角（度，正北为0），为空时自动选择
+	Speed        float64     `json:"speed"`         // 航段飞行速度（m/s）
+	TurnMargin   float64     `json:"turn_margin"`   // 航段两端外延距离（米）
+	TurnTime     float64     `json:"turn_time"`     // 每次掉头的额外耗时（秒）
+	Parts        int         `json:"parts"`         // 切分份数，即参与的无人机数
+}
+
+// Point 覆盖航线点
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Line      int     `json:"line"`  // 所属扫描线序号（从0开始）
+	Photo     bool    `json:"photo"` // 是否为拍照点，否则为航段外延的进入或退出点
+}
+
+// Part 分配给一架无人机的连续扫描线
+type Part struct {
+	Index     int     `json:"index"`
+	FirstLine int     `json:"first_line"`
+	LastLine  int     `json:"last_line"`
+	Points    []Point `json:"points"`
+	Photos    int     `json:"photos"`
+	Distance  float64 `json:"distance"` // 首点到末点的航程（米）
+	Duration  float64 `json:"duration"` // 估算飞行时间（秒），不含往返测区
+}
+
+// Plan 覆盖规划结果
+type Plan struct {
+	Angle           float64 `json:"angle"`            // 扫描线方位角（度）
+	LineSpacing     float64 `json:"line_spacing"`     // 扫描线间距（米）
+	TriggerDistance float64 `json:"trigger_distance"` // 拍照间距（米）
+	FootprintWidth  float64 `json:"footprint_width"`  // 单张照片垂直于航线方向的地面宽度（米）
+	FootprintLength float64 `json:"footprint_length"` // 单张照片沿航线方向的地面长度（米）
+	Area            float64 `json:"area"`             // 测区面积（平方米）
+	Lines           int     `json:"lines"`
+	Photos          int     `json:"photos"`
+	Distance        float64 `json:"distance"`
+	Duration        float64 `json:"duration"` // 各部分估算飞行时间之和（秒）
+	Parts           []Part  `json:"parts"`
+}
+
+// vec 局部平面坐标（米）
+type vec struct{ x, y float64 }
+
+// segment 扫描线与测区相交的航段，坐标位于旋转后的平面
+type segment struct {
+	x0, x1 float64
+}
+
+// line 一条扫描线上的全部航段，按 x 升序
+type line struct {
+	y        float64
+	segments []segment
+}
+
+// Generate 生成覆盖航线
+func Generate(params Params) (*Plan, error) {
+	if err := validate(&params); err != nil {
+		return nil, err
+	}
+
+	polygon := params.Polygon
+	if len(polygon) > 3 && polygon[0] == polygon[len(polygon)-1] {
+		polygon = polygon[:len(polygon)-1]
+	}
+
+	proj := geo.NewProjection(polygon[0])
+	points := make([]vec, len(polygon))
+	for i, p := range polygon {
+		x, y := proj.ToXY(p)
+		points[i] = vec{x, y}
+	}
+	area := math.Abs(signedArea(points))
+	if area < 1 {
+		return nil, fmt.Errorf("%w: polygon area is zero", ErrInvalidArea)
+	}
+
+	width := 2 * params.Altitude * math.Tan(toRadians(params.Camera.HorizontalFOV)/2)
+	length := 2 * params.Altitude * math.Tan(toRadians(params.Camera.VerticalFOV)/2)
+	g := &generator{
+		params:  params,
+		proj:    proj,
+		spacing: width * (1 - params.SideOverlap/100),
+		trigger: length * (1 - params.FrontOverlap/100),
+	}
+
+	var angle float64
+	var lines []line
+	if params.Angle != nil {
+		angle = math.Mod(math.Mod(*params.Angle, 180)+180, 180)
+		var err error
+		if lines, err = g.scan(points, angle); err != nil {
+			return nil, err
+		}
+	} else {
+		best := math.Inf(1)
+		for _, candidate := range edgeAngles(points) {
+			candidateLines, err := g.scan(points, candidate)
+			if err != nil {
+				continue
+			}
+			if cost := g.totalCost(candidateLines); cost < best {
+				best, angle, lines = cost, candidate, candidateLines
+			}
+		}
+		if lines == nil {
+			return nil, fmt.Errorf("%w: too many scan lines, increase altitude or reduce side overlap", ErrInvalidParams)
+		}
+	}
+
+	if len(lines) < params.Parts {
+		return nil, fmt.Errorf("%w: area has %d scan lines, cannot split across %d drones", ErrTooManyParts, len(lines), params.Parts)
+	}
+
+	plan := &Plan{
+		Angle:           angle,
+		LineSpacing:     g.spacing,
+		TriggerDistance: g.trigger,
+		FootprintWidth:  width,
+		FootprintLength: length,
+		Area:            area,
+		Lines:           len(lines),
+	}
+	for i, bounds := range g.partition(lines, params.Parts) {
+		part := g.buildPart(lines, angle, bounds[0], bounds[1])
+		part.Index = i
+		plan.Parts = append(plan.Parts, part)
+		plan.Photos += part.Photos
+		plan.Distance += part.Distance
+		plan.Duration += part.Duration
+	}
+	return plan, nil
+}
+
+// validate 校验参数
+func validate(p *Params) error {
+	if len(p.Polygon) < 3 {
+		return fmt.Errorf("%w: polygon requires at least 3 vertices", ErrInvalidArea)
+	}
+	for i, pt := range p.Polygon {
+		if pt.Latitude < -90 || pt.Latitude > 90 || pt.Longitude < -180 || pt.Longitude > 180 {
+			return fmt.Errorf("%w: vertex %d out of range", ErrInvalidArea, i)
+		}
+	}
+
+	switch {
+	case p.Altitude <= 0:
+		return fmt.Errorf("%w: altitude must be positive", ErrInvalidParams)
+	case p.Camera.HorizontalFOV <= 0 || p.Camera.HorizontalFOV >= 180:
+		return fmt.Errorf("%w: horizontal_fov must be in (0, 180)", ErrInvalidParams)
+	case p.Camera.VerticalFOV <= 0 || p.Camera.VerticalFOV >= 180:
+		return fmt.Errorf("%w: vertical_fov must be in (0, 180)", ErrInvalidParams)
+	case p.FrontOverlap < 0 || p.FrontOverlap > MaxOverlap:
+		return fmt.Errorf("%w: front_overlap must be between 0 and %d", ErrInvalidParams, MaxOverlap)
+	case p.SideOverlap < 0 || p.SideOverlap > MaxOverlap:
+		return fmt.Errorf("%w: side_overlap must be between 0 and %d", ErrInvalidParams, MaxOverlap)
+	case p.Speed <= 0:
+		return fmt.Errorf("%w: speed must be positive", ErrInvalidParams)
+	case p.TurnMargin < 0 || p.TurnTime < 0:
+		return fmt.Errorf("%w: turn_margin and turn_time must not be negative", ErrInvalidParams)
+	case p.Parts < 1:
+		return fmt.Errorf("%w: parts must be at least 1", ErrInvalidParams)
+	}
+	return nil
+}
+
+// generator 保存一次规划的派生参数
+type generator struct {
+	params  Params
+	proj    *geo.Projection
+	spacing float64
+	trigger float64
+}
+
+// scan 按方位角生成扫描线：旋转后扫描线平行于 x 轴，在 y 方向上居中等距排列
+func (g *generator) scan(points []vec, angle float64) ([]line, error) {
+	rotated := make([]vec, len(points))
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for i, p := range points {
+		rotated[i] = rotate(p, angle)
+		minY = math.Min(minY, rotated[i].y)
+		maxY = math.Max(maxY, rotated[i].y)
+	}
+
+	height := maxY - minY
+	count := int(math.Ceil(height/g.spacing - 1e-9))
+	if count < 1 {
+		count = 1
+	}
+	if count > maxLines {
+		return nil, fmt.Errorf("%w: %d scan lines exceed limit %d", ErrInvalidParams, count, maxLines)
+	}
+
+	start := minY + (height-float64(count-1)*g.spacing)/2
+	lines := make([]line, 0, count)
+	for i := 0; i < count; i++ {
+		y := start + float64(i)*g.spacing
+		if segments := intersect(rotated, y); len(segments) > 0 {
+			lines = append(lines, line{y: y, segments: segments})
+		}
+	}
+	return lines, nil
+}
+
+// intersect 水平线 y 与多边形的相交航段（偶奇规则），按 x 升序
+func intersect(polygon []vec, y float64) []segment {
+	var xs []float64
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%len(polygon)]
+		if (a.y <= y && b.y > y) || (b.y <= y && a.y > y) {
+			xs = append(xs, a.x+(y-a.y)*(b.x-a.x)/(b.y-a.y))
+		}
+	}
+	sort.Float64s(xs)
+
+	var segments []segment
+	for i := 0; i+1 < len(xs); i += 2 {
+		if xs[i+1]-xs[i] > 0.01 {
+			segments = append(segments, segment{x0: xs[i], x1: xs[i+1]})
+		}
+	}
+	return segments
+}
+
+// lineCost 扫描线上各航段（含两端外延）的飞行时间
+func (g *generator) lineCost(l line) float64 {
+	distance := 0.0
+	for _, s := range l.segments {
+		distance += s.x1 - s.x0 + 2*g.params.TurnMargin
+	}
+	return distance / g.params.Speed
+}
+
+// transitCost 从扫描线 a 的末端掉头进入扫描线 b 的时间，forward 表示 a 沿 x 增大方向飞行
+func (g *generator) transitCost(a, b line, forward bool) float64 {
+	var from, to float64
+	if forward {
+		from = a.segments[len(a.segments)-1].x1
+		to = b.segments[len(b.segments)-1].x1
+	} else {
+		from = a.segments[0].x0
+		to = b.segments[0].x0
+	}
+	return math.Hypot(to-from, b.y-a.y)/g.params.Speed + g.params.TurnTime
+}
+
+// totalCost 单机完成全部扫描线的估算时间，用于选择扫描方向
+func (g *generator) totalCost(lines []line) float64 {
+	cost := 0.0
+	for i, l := range lines {
+		cost += g.lineCost(l)
+		if i+1 < len(lines) {
+			cost += g.transitCost(l, lines[i+1], i%2 == 0)
+		}
+	}
+	return cost
+}
+
+// partition 将扫描线切分为 parts 组连续区间 [first, last]，使各组估算时间的最大值最小
+func (g *generator) partition(lines []line, parts int) [][2]int {
+	n := len(lines)
+	// prefix[i] 为前 i 条扫描线的航段时间，transit[i] 为前 i 次相邻扫描线间掉头时间
+	prefix := make([]float64, n+1)
+	transit := make([]float64, n)
+	for i, l := range lines {
+		prefix[i+1] = prefix[i] + g.lineCost(l)
+		if i+1 < n {
+			transit[i+1] = transit[i] + g.transitCost(l, lines[i+1], i%2 == 0)
+		}
+	}
+	cost := func(first, last int) float64 {
+		return prefix[last+1] - prefix[first] + transit[last] - transit[first]
+	}
+
+	// best[k][j] 为前 j 条扫描线分成 k 组时的最小最大组时间，cut[k][j] 为最后一组的起始扫描线
+	best := make([][]float64, parts+1)
+	cut := make([][]int, parts+1)
+	for k := range best {
+		best[k] = make([]float64, n+1)
+		cut[k] = make([]int, n+1)
+		for j := range best[k] {
+			best[k][j] = math.Inf(1)
+		}
+	}
+	best[0][0] = 0
+	for k := 1; k <= parts; k++ {
+		for j := k; j <= n; j++ {
+			for i := k - 1; i < j; i++ {
+				value := math.Max(best[k-1][i], cost(i, j-1))
+				if value < best[k][j] {
+					best[k][j] = value
+					cut[k][j] = i
+				}
+			}
+		}
+	}
+
+	bounds := make([][2]int, parts)
+	j := n
+	for k := parts; k >= 1; k-- {
+		i := cut[k][j]
+		bounds[k-1] = [2]int{i, j - 1}
+		j = i
+	}
+	return bounds
+}
+
+// buildPart 生成扫描线 [first, last] 的往返航线：与切分估算一致，奇数序号的扫描线反向飞行，
+// 同一扫描线上相邻航段的间隔不足两倍外延距离时直接穿过，不再单独进入和退出
+func (g *generator) buildPart(lines []line, angle float64, first, last int) Part {
+	part := Part{FirstLine: first, LastLine: last}
+	margin := g.params.TurnMargin
+
+	var path []vec
+	add := func(x, y float64, lineIndex int, photo bool) {
+		path = append(path, vec{x, y})
+		local := unrotate(vec{x, y}, angle)
+		p := g.proj.FromXY(local.x, local.y)
+		part.Points = append(part.Points, Point{
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Line:      lineIndex,
+			Photo:     photo,
+		})
+		if photo {
+			part.Photos++
+		}
+	}
+
+	for li := first; li <= last; li++ {
+		l := lines[li]
+		forward := li%2 == 0
+		segments := make([]segment, len(l.segments))
+		copy(segments, l.segments)
+		if !forward {
+			for i, j := 0, len(segments)-1; i < j; i, j = i+1, j-1 {
+				segments[i], segments[j] = segments[j], segments[i]
+			}
+			for i := range segments {
+				segments[i].x0, segments[i].x1 = segments[i].x1, segments[i].x0
+			}
+		}
+		direction := 1.0
+		if !forward {
+			direction = -1
+		}
+
+		for si, s := range segments {
+			joined := si > 0 && math.Abs(s.x0-segments[si-1].x1) <= 2*margin
+			if !joined && margin > 0 {
+				add(s.x0-direction*margin, l.y, li, false)
+			}
+			for _, x := range g.triggers(s) {
+				add(x, l.y, li, true)
+			}
+			next := si+1 < len(segments) && math.Abs(segments[si+1].x0-s.x1) <= 2*margin
+			if !next && margin > 0 {
+				add(s.x1+direction*margin, l.y, li, false)
+			}
+		}
+	}
+
+	turns := last - first
+	for i := 1; i < len(path); i++ {
+		part.Distance += math.Hypot(path[i].x-path[i-1].x, path[i].y-path[i-1].y)
+	}
+	part.Duration = part.Distance/g.params.Speed + float64(turns)*g.params.TurnTime
+	return part
+}
+
+// triggers 航段内等距的拍照位置，包含两端，间距不超过拍照间距
+func (g *generator) triggers(s segment) []float64 {
+	length := math.Abs(s.x1 - s.x0)
+	count := int(math.Ceil(length/g.trigger-1e-9)) + 1
+	if count < 2 {
+		return []float64{(s.x0 + s.x1) / 2}
+	}
+	step := (s.x1 - s.x0) / float64(count-1)
+	xs := make([]float64, count)
+	for i := range xs {
+		xs[i] = s.x0 + float64(i)*step
+	}
+	return xs
+}
+
+// edgeAngles 多边形各边的方位角（度，[0, 180)），去重后升序
+func edgeAngles(points []vec) []float64 {
+	seen := make(map[int]bool)
+	var angles []float64
+	for i := range points {
+		a, b := points[i], points[(i+1)%len(points)]
+		if a == b {
+			continue
+		}
+		bearing := math.Mod(90-toDegrees(math.Atan2(b.y-a.y, b.x-a.x))+360, 180)
+		key := int(math.Round(bearing*10)) % 1800
+		if !seen[key] {
+			seen[key] = true
+			angles = append(angles, float64(key)/10)
+		}
+	}
+	sort.Float64s(angles)
+	return angles
+}
+
+// rotate 将局部坐标旋转到扫描方位角为 x 轴正方向的平面
+func rotate(p vec, bearing float64) vec {
+	alpha := toRadians(90 - bearing)
+	sin, cos := math.Sin(alpha), math.Cos(alpha)
+	return vec{p.x*cos + p.y*sin, -p.x*sin + p.y*cos}
+}
+
+// unrotate rotate 的逆变换
+func unrotate(p vec, bearing float64) vec {
+	alpha := toRadians(90 - bearing)
+	sin, cos := math.Sin(alpha), math.Cos(alpha)
+	return vec{p.x*cos - p.y*sin, p.x*sin + p.y*cos}
+}
+
+// signedArea 多边形有向面积（鞋带公式）
+func signedArea(points []vec) float64 {
+	sum := 0.0
+	for i := range points {
+		a, b := points[i], points[(i+1)%len(points)]
+		sum += a.x*b.y - b.x*a.y
+	}
+	return sum / 2
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
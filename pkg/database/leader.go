@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// LeaderElection 基于分布式锁的主节点选举
+// 多个服务实例竞争同一把锁，持有锁的实例负责运行后台任务（心跳扫描、调度等）
+type LeaderElection struct {
+	lock     *LockService
+	key      string
+	identity string
+	ttl      time.Duration
+	isLeader bool
+	mu       sync.Mutex
+}
+
+// NewLeaderElection 创建主节点选举器
+func NewLeaderElection(lock *LockService, key string, ttl time.Duration) *LeaderElection {
+	hostname, _ := os.Hostname()
+	return &LeaderElection{
+		lock:     lock,
+		key:      key,
+		identity: fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		ttl:      ttl,
+	}
+}
+
+// TryAcquire 尝试获取或续约领导权，返回当前实例是否为主节点
+func (le *LeaderElection) TryAcquire(ctx context.Context) (bool, error) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	// 已是主节点时续约，续约失败说明锁已过期或被抢占
+	if le.isLeader {
+		extended, err := le.lock.ExtendLock(ctx, le.key, le.identity, le.ttl)
+		if err == nil && extended {
+			return true, nil
+		}
+		le.isLeader = false
+	}
+
+	acquired, err := le.lock.AcquireLock(ctx, le.key, le.identity, le.ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire leader lock %s: %w", le.key, err)
+	}
+
+	le.isLeader = acquired
+	return acquired, nil
+}
+
+// IsLeader 当前实例是否为主节点
+func (le *LeaderElection) IsLeader() bool {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.isLeader
+}
+
+// Resign 主动放弃领导权（服务关闭时调用）
+func (le *LeaderElection) Resign(ctx context.Context) error {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	if !le.isLeader {
+		return nil
+	}
+
+	le.isLeader = false
+	return le.lock.ReleaseLock(ctx, le.key, le.identity)
+}
+
+// Identity 当前实例标识
+func (le *LeaderElection) Identity() string {
+	return le.identity
+}
@@ -99,6 +99,33 @@ func Migrate(db *gorm.DB) error {
 		&models.Drone{},
 		&models.Task{},
 		&models.Alert{},
+		&models.DroneTelemetry{},
+		&models.FirmwareRelease{},
+		&models.FirmwareRollout{},
+		&models.FirmwareRolloutTarget{},
+		&models.MaintenanceRecord{},
+		&models.ServiceInterval{},
+		&models.DroneUsage{},
+		&models.BatteryPack{},
+		&models.BatteryInstallation{},
+		&models.Fleet{},
+		&models.DroneGroup{},
+		&models.FleetMember{},
+		&models.DeviceCredential{},
+		&models.DroneCommandRecord{},
+		&models.TaskSchedule{},
+		&models.Geofence{},
+		&models.PreflightOverride{},
+		&models.EnergyCalibration{},
+		&models.WorkflowDefinition{},
+		&models.WorkflowRun{},
+		&models.WorkflowRunStep{},
+		&models.TaskRetryPolicy{},
+		&models.TaskAttempt{},
+		&models.TaskTemplate{},
+		&models.TaskArtifact{},
+		&models.ArtifactUpload{},
+		&models.ApprovalRule{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
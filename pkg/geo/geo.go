@@ -0,0 +1,76 @@
+package geo
+
+import (
+	"math"
+)
+
+// EarthRadius 地球平均半径（米）
+const EarthRadius = 6371000.0
+
+// Point 经纬度坐标点
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Distance 计算两点间的大圆距离（Haversine公式，单位米）
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	return EarthRadius * c
+}
+
+// DistanceBetween 计算两个坐标点间距离（米）
+func DistanceBetween(a, b Point) float64 {
+	return Distance(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+}
+
+// Bearing 计算从起点到终点的初始方位角（度，0-360，正北为0）
+func Bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := toRadians(lat1)
+	phi2 := toRadians(lat2)
+	dLon := toRadians(lon2 - lon1)
+
+	y := math.Sin(dLon) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLon)
+
+	return math.Mod(toDegrees(math.Atan2(y, x))+360, 360)
+}
+
+// Destination 根据起点、方位角和距离计算终点坐标
+func Destination(lat, lon, bearing, distance float64) (float64, float64) {
+	delta := distance / EarthRadius
+	theta := toRadians(bearing)
+	phi1 := toRadians(lat)
+	lambda1 := toRadians(lon)
+
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda1 + math.Atan2(
+		math.Sin(theta)*math.Sin(delta)*math.Cos(phi1),
+		math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2),
+	)
+
+	return toDegrees(phi2), math.Mod(toDegrees(lambda2)+540, 360) - 180
+}
+
+// PathLength 计算折线总长度（米）
+func PathLength(points []Point) float64 {
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += DistanceBetween(points[i-1], points[i])
+	}
+	return total
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,164 @@
+package geo
+
+import (
+	"math"
+)
+
+// 多边形和线段计算使用以参考点为原点的等距投影，适用于几十公里范围内的区域
+
+// Vec 局部平面坐标（米）
+type Vec struct {
+	X, Y float64
+}
+
+// 向量运算：加、减、数乘、点积、叉积（z 分量）、长度和逆时针旋转
+func (a Vec) Add(b Vec) Vec       { return Vec{a.X + b.X, a.Y + b.Y} }
+func (a Vec) Sub(b Vec) Vec       { return Vec{a.X - b.X, a.Y - b.Y} }
+func (a Vec) Scale(k float64) Vec { return Vec{a.X * k, a.Y * k} }
+func (a Vec) Dot(b Vec) float64   { return a.X*b.X + a.Y*b.Y }
+func (a Vec) Cross(b Vec) float64 { return a.X*b.Y - a.Y*b.X }
+func (a Vec) Length() float64     { return math.Hypot(a.X, a.Y) }
+func (a Vec) Rotate(angle float64) Vec {
+	s, c := math.Sincos(angle)
+	return Vec{a.X*c - a.Y*s, a.X*s + a.Y*c}
+}
+
+// project 将坐标点投影到以 ref 为原点的局部平面
+func project(ref, p Point) Vec {
+	return Vec{
+		X: toRadians(p.Longitude-ref.Longitude) * EarthRadius * math.Cos(toRadians(ref.Latitude)),
+		Y: toRadians(p.Latitude-ref.Latitude) * EarthRadius,
+	}
+}
+
+// PointInPolygon 判断点是否在多边形内（射线法），多边形顶点按顺序排列，首尾无需重复
+func PointInPolygon(p Point, polygon []Point) bool {
+	if len(polygon) < 3 {
+		return false
+	}
+
+	inside := false
+	j := len(polygon) - 1
+	for i := 0; i < len(polygon); i++ {
+		a, b := polygon[i], polygon[j]
+		if (a.Latitude > p.Latitude) != (b.Latitude > p.Latitude) {
+			lon := (b.Longitude-a.Longitude)*(p.Latitude-a.Latitude)/(b.Latitude-a.Latitude) + a.Longitude
+			if p.Longitude < lon {
+				inside = !inside
+			}
+		}
+		j = i
+	}
+	return inside
+}
+
+// DistanceToSegment 计算点到线段的最短距离（米）
+func DistanceToSegment(p, a, b Point) float64 {
+	va := project(p, a)
+	vb := project(p, b)
+	return PointSegmentDistance(Vec{}, va, vb)
+}
+
+// DistanceToPolygon 计算点到多边形边界的最短距离（米），点在多边形内时返回0
+func DistanceToPolygon(p Point, polygon []Point) float64 {
+	if len(polygon) == 0 {
+		return math.Inf(1)
+	}
+	if PointInPolygon(p, polygon) {
+		return 0
+	}
+
+	min := math.Inf(1)
+	for i := range polygon {
+		d := DistanceToSegment(p, polygon[i], polygon[(i+1)%len(polygon)])
+		if d < min {
+			min = d
+		}
+	}
+	return min
+}
+
+// SegmentIntersectsPolygon 判断线段是否穿过或进入多边形
+func SegmentIntersectsPolygon(a, b Point, polygon []Point) bool {
+	if len(polygon) < 3 {
+		return false
+	}
+	if PointInPolygon(a, polygon) || PointInPolygon(b, polygon) {
+		return true
+	}
+
+	va := project(a, a)
+	vb := project(a, b)
+	for i := range polygon {
+		pa := project(a, polygon[i])
+		pb := project(a, polygon[(i+1)%len(polygon)])
+		if SegmentsIntersect(va, vb, pa, pb) {
+			return true
+		}
+	}
+	return false
+}
+
+// PointSegmentDistance 平面上点到线段的距离
+func PointSegmentDistance(p, a, b Vec) float64 {
+	ab := b.Sub(a)
+	lengthSq := ab.Dot(ab)
+	if lengthSq == 0 {
+		return p.Sub(a).Length()
+	}
+
+	t := math.Max(0, math.Min(1, p.Sub(a).Dot(ab)/lengthSq))
+	return p.Sub(a.Add(ab.Scale(t))).Length()
+}
+
+// SegmentsIntersect 判断平面上两条线段是否相交（含端点接触）
+func SegmentsIntersect(p1, p2, q1, q2 Vec) bool {
+	d1 := q2.Sub(q1).Cross(p1.Sub(q1))
+	d2 := q2.Sub(q1).Cross(p2.Sub(q1))
+	d3 := p2.Sub(p1).Cross(q1.Sub(p1))
+	d4 := p2.Sub(p1).Cross(q2.Sub(p1))
+
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+
+	return (d1 == 0 && OnSegment(q1, q2, p1)) ||
+		(d2 == 0 && OnSegment(q1, q2, p2)) ||
+		(d3 == 0 && OnSegment(p1, p2, q1)) ||
+		(d4 == 0 && OnSegment(p1, p2, q2))
+}
+
+// OnSegment 已知 c 与 ab 共线时判断 c 是否在线段 ab 上
+func OnSegment(a, b, c Vec) bool {
+	return math.Min(a.X, b.X) <= c.X && c.X <= math.Max(a.X, b.X) &&
+		math.Min(a.Y, b.Y) <= c.Y && c.Y <= math.Max(a.Y, b.Y)
+}
+
+// Projection 以原点为中心的局部等距投影，x 指向正东、y 指向正北（米）
+type Projection struct {
+	origin Point
+	cosLat float64
+}
+
+// NewProjection 创建以 origin 为原点的局部投影
+func NewProjection(origin Point) *Projection {
+	return &Projection{origin: origin, cosLat: math.Cos(toRadians(origin.Latitude))}
+}
+
+// ToXY 将坐标点投影到局部平面
+func (p *Projection) ToXY(point Point) (float64, float64) {
+	return toRadians(point.Longitude-p.origin.Longitude) * EarthRadius * p.cosLat,
+		toRadians(point.Latitude-p.origin.Latitude) * EarthRadius
+}
+
+// FromXY 将局部平面坐标还原为经纬度
+func (p *Projection) FromXY(x, y float64) Point {
+	lon := p.origin.Longitude
+	if p.cosLat != 0 {
+		lon += toDegrees(x / (EarthRadius * p.cosLat))
+	}
+	return Point{
+		Latitude:  p.origin.Latitude + toDegrees(y/EarthRadius),
+		Longitude: lon,
+	}
+}
@@ -17,15 +17,25 @@ const (
 	DroneStatusChangedEvent   EventType = "drone.status.changed"
 	DroneBatteryLowEvent      EventType = "drone.battery.low"
 	DroneLocationUpdatedEvent EventType = "drone.location.updated"
+	DroneBatteryUpdatedEvent  EventType = "drone.battery.updated"
+	DroneCommandIssuedEvent   EventType = "drone.command.issued"
 
 	// 任务事件
-	TaskCreatedEvent   EventType = "task.created"
-	TaskScheduledEvent EventType = "task.scheduled"
-	TaskStartedEvent   EventType = "task.started"
-	TaskProgressEvent  EventType = "task.progress"
-	TaskCompletedEvent EventType = "task.completed"
-	TaskFailedEvent    EventType = "task.failed"
-	TaskCancelledEvent EventType = "task.cancelled"
+	TaskCreatedEvent           EventType = "task.created"
+	TaskScheduledEvent         EventType = "task.scheduled"
+	TaskStartedEvent           EventType = "task.started"
+	TaskProgressEvent          EventType = "task.progress"
+	TaskCompletedEvent         EventType = "task.completed"
+	TaskFailedEvent            EventType = "task.failed"
+	TaskCancelledEvent         EventType = "task.cancelled"
+	TaskReassignedEvent        EventType = "task.reassigned"
+	TaskRetryScheduledEvent    EventType = "task.retry_scheduled"
+	TaskDeliveryMilestoneEvent EventType = "task.delivery_milestone"
+	TaskApprovalRequestedEvent EventType = "task.approval_requested"
+	TaskApprovalDecidedEvent   EventType = "task.approval_decided"
+
+	// 流程事件
+	WorkflowRunStatusEvent EventType = "workflow.run.status"
 
 	// 用户事件
 	UserLoggedInEvent  EventType = "user.logged.in"
@@ -39,6 +49,11 @@ const (
 	AlertAcknowledgedEvent EventType = "alert.acknowledged"
 	AlertResolvedEvent     EventType = "alert.resolved"
 
+	// 固件事件
+	FirmwareUpdateRequestedEvent EventType = "firmware.update.requested"
+	FirmwareRolloutStepEvent     EventType = "firmware.rollout.step"
+	FirmwareRolloutStatusEvent   EventType = "firmware.rollout.status"
+
 	// 系统事件
 	SystemHealthCheckEvent EventType = "system.health.check"
 	SystemMetricsEvent     EventType = "system.metrics"
@@ -78,6 +93,17 @@ type DroneStatusChangedEventData struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// DroneCommandEventData 无人机控制指令事件数据
+type DroneCommandEventData struct {
+	CommandID string                 `json:"command_id"`
+	DroneID   uint                   `json:"drone_id"`
+	Command   string                 `json:"command"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+	FleetID   *uint                  `json:"fleet_id,omitempty"` // 机队批量下发时设置
+	IssuedBy  uint                   `json:"issued_by"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
 // Location 位置信息
 type Location struct {
 	Latitude  float64 `json:"latitude"`
@@ -98,6 +124,83 @@ type TaskProgressEventData struct {
 	Timestamp   time.Time `json:"timestamp"`
 }
 
+// TaskScheduledEventData 任务进入调度事件数据
+type TaskScheduledEventData struct {
+	TaskID      uint      `json:"task_id"`
+	TaskName    string    `json:"task_name"`
+	DroneID     uint      `json:"drone_id"`
+	ScheduleID  *uint     `json:"schedule_id,omitempty"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// TaskReassignedEventData 任务重新分配事件数据
+type TaskReassignedEventData struct {
+	TaskID          uint      `json:"task_id"`
+	TaskName        string    `json:"task_name"`
+	PreviousDroneID uint      `json:"previous_drone_id"`
+	DroneID         uint      `json:"drone_id"`
+	Reason          string    `json:"reason"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// TaskRetryEventData 失败任务安排重试事件数据
+type TaskRetryEventData struct {
+	TaskID          uint      `json:"task_id"`
+	TaskName        string    `json:"task_name"`
+	Attempt         int       `json:"attempt"` // 即将开始的执行次数
+	PreviousDroneID uint      `json:"previous_drone_id"`
+	DroneID         uint      `json:"drone_id"`
+	Reassigned      bool      `json:"reassigned"`
+	ErrorCode       string    `json:"error_code"`
+	ResumeWaypoint  int       `json:"resume_waypoint"` // 重试的起始航点下标
+	RetryAt         time.Time `json:"retry_at"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// TaskDeliveryEventData 配送状态节点事件数据
+type TaskDeliveryEventData struct {
+	TaskID         uint        `json:"task_id"`
+	TaskName       string      `json:"task_name"`
+	DroneID        uint        `json:"drone_id"`
+	Status         string      `json:"status"`
+	PreviousStatus string      `json:"previous_status"`
+	Latitude       float64     `json:"latitude"`
+	Longitude      float64     `json:"longitude"`
+	Note           string      `json:"note,omitempty"`
+	Proof          interface{} `json:"proof,omitempty"` // 交付时的交付证明
+	Timestamp      time.Time   `json:"timestamp"`
+}
+
+// TaskApprovalEventData 任务审批事件数据
+type TaskApprovalEventData struct {
+	TaskID      uint        `json:"task_id"`
+	TaskName    string      `json:"task_name"`
+	DroneID     uint        `json:"drone_id"`
+	Priority    string      `json:"priority"`
+	Status      string      `json:"status"` // pending, approved, rejected
+	RequestedBy uint        `json:"requested_by"`
+	DecidedBy   uint        `json:"decided_by,omitempty"`
+	Comment     string      `json:"comment,omitempty"`
+	Reasons     interface{} `json:"reasons,omitempty"` // 命中的审批规则
+	Timestamp   time.Time   `json:"timestamp"`
+}
+
+// WorkflowRunEventData 流程实例状态事件数据
+type WorkflowRunEventData struct {
+	RunID        uint      `json:"run_id"`
+	DefinitionID uint      `json:"definition_id"`
+	Name         string    `json:"name"`
+	Status       string    `json:"status"`
+	Progress     int       `json:"progress"`
+	Action       string    `json:"action"` // started, step_started, step_finished, completed, failed, cancelled
+	Step         string    `json:"step,omitempty"`
+	StepStatus   string    `json:"step_status,omitempty"`
+	TaskID       *uint     `json:"task_id,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
 // AlertCreatedEventData 告警创建事件数据
 type AlertCreatedEventData struct {
 	AlertID   uint      `json:"alert_id"`
@@ -110,6 +213,35 @@ type AlertCreatedEventData struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// FirmwareUpdateRequestedEventData 单机固件升级指令事件数据
+type FirmwareUpdateRequestedEventData struct {
+	RolloutID   uint      `json:"rollout_id"`
+	DroneID     uint      `json:"drone_id"`
+	FromVersion string    `json:"from_version"`
+	Version     string    `json:"version"`
+	DownloadURL string    `json:"download_url"`
+	Checksum    string    `json:"checksum"`
+	Size        int64     `json:"size"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// FirmwareRolloutEventData 固件发布活动事件数据
+type FirmwareRolloutEventData struct {
+	RolloutID  uint      `json:"rollout_id"`
+	ReleaseID  uint      `json:"release_id"`
+	Model      string    `json:"model"`
+	Version    string    `json:"version"`
+	Status     string    `json:"status"`
+	Step       int       `json:"step"`
+	TotalSteps int       `json:"total_steps"`
+	Action     string    `json:"action"` // started, completed, paused, resumed, aborted
+	DroneIDs   []uint    `json:"drone_ids,omitempty"`
+	Succeeded  int       `json:"succeeded"`
+	Failed     int       `json:"failed"`
+	Reason     string    `json:"reason,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
 // UserActionEventData 用户操作事件数据
 type UserActionEventData struct {
 	UserID    uint      `json:"user_id"`
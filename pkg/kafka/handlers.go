@@ -144,6 +144,8 @@ func (h *TaskEventHandler) HandleMessage(ctx context.Context, message *Message)
 		return h.handleTaskFailed(ctx, &event)
 	case TaskCancelledEvent:
 		return h.handleTaskCancelled(ctx, &event)
+	case TaskDeliveryMilestoneEvent:
+		return h.handleDeliveryMilestone(ctx, &event)
 	default:
 		h.logger.WithField("event_type", event.Type).Warn("Unknown task event type")
 		return nil
@@ -162,6 +164,20 @@ func (h *TaskEventHandler) handleTaskCreated(ctx context.Context, event *Event)
 	return nil
 }
 
+// handleDeliveryMilestone 处理配送状态节点事件
+func (h *TaskEventHandler) handleDeliveryMilestone(ctx context.Context, event *Event) error {
+	var data TaskDeliveryEventData
+	dataBytes, _ := json.Marshal(event.Data)
+	if err := json.Unmarshal(dataBytes, &data); err != nil {
+		return fmt.Errorf("failed to parse delivery milestone data: %w", err)
+	}
+
+	h.logger.WithField("task_id", data.TaskID).
+		WithField("status", data.Status).
+		Info("Delivery milestone reached")
+	return nil
+}
+
 // handleTaskScheduled 处理任务调度事件
 func (h *TaskEventHandler) handleTaskScheduled(ctx context.Context, event *Event) error {
 	h.logger.WithField("event_id", event.ID).Info("Task scheduled")
@@ -209,7 +225,7 @@ func (h *TaskEventHandler) handleTaskFailed(ctx context.Context, event *Event) e
 	// 业务逻辑：
 	// 1. 创建故障告警
 	// 2. 分析失败原因
-	// 3. 触发重试机制
+	// 失败重试由任务服务按任务类型的重试策略处理
 
 	return nil
 }
@@ -217,6 +217,7 @@ func (c *Client) AnalyzeCommand(ctx context.Context, command string) (map[string
 }
 
 // OptimizePath 路径优化
+// LLM给出的路径不保证避开障碍物，调用方应使用 planner.Verify 校验后再使用
 func (c *Client) OptimizePath(ctx context.Context, waypoints []Position, constraints PlanningConstraints) ([]Position, error) {
 	waypointsJSON, _ := json.Marshal(waypoints)
 	constraintsJSON, _ := json.Marshal(constraints)
@@ -258,14 +259,8 @@ func (c *Client) OptimizePath(ctx context.Context, waypoints []Position, constra
 		return nil, fmt.Errorf("no response from LLM")
 	}
 
-	// 解析优化后的路径
-	content := resp.Choices[0].Message.Content
-	var optimizedPath []Position
-	if err := json.Unmarshal([]byte(content), &optimizedPath); err != nil {
-		return waypoints, nil // 返回原始路径
-	}
-
-	return optimizedPath, nil
+	// 解析优化后的路径，无法解析时返回错误而不是静默返回原始路径
+	return c.parsePathResponse(resp.Choices[0].Message.Content)
 }
 
 // 私有方法
@@ -326,6 +321,25 @@ func (c *Client) parsePlanResponse(content string) (*TaskPlan, error) {
 	return &plan, nil
 }
 
+func (c *Client) parsePathResponse(content string) ([]Position, error) {
+	// 提取JSON数组部分，兼容模型在数组前后附加说明文字或代码块
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]") + 1
+	if start == -1 || end <= start {
+		return nil, fmt.Errorf("no JSON array found in path response")
+	}
+
+	var path []Position
+	if err := json.Unmarshal([]byte(content[start:end]), &path); err != nil {
+		return nil, fmt.Errorf("failed to parse optimized path: %w", err)
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("optimized path is empty")
+	}
+
+	return path, nil
+}
+
 func (c *Client) validatePlan(plan *TaskPlan, constraints PlanningConstraints) error {
 	if len(plan.Steps) == 0 {
 		return fmt.Errorf("plan contains no steps")
@@ -0,0 +1,299 @@
+package mediameta
+
+import (
+	"encoding/binary"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	tagMake        = 0x010F
+	tagModel       = 0x0110
+	tagDateTime    = 0x0132
+	tagExifIFD     = 0x8769
+	tagGPSIFD      = 0x8825
+	tagDateTimeOrg = 0x9003
+	tagOffsetOrg   = 0x9011
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+	tagGPSTimeStamp    = 0x0007
+	tagGPSDateStamp    = 0x001D
+
+	// maxIFDEntries 单个IFD允许的最大条目数，超过视为损坏
+	maxIFDEntries = 1000
+	// maxJPEGScan JPEG中查找EXIF段的最大扫描长度
+	maxJPEGScan = 1 << 20
+)
+
+// typeSizes TIFF字段类型的单个值字节数
+var typeSizes = map[uint16]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// extractJPEG 在JPEG段中查找 APP1 Exif 段
+func extractJPEG(r io.ReaderAt, size int64) (*Metadata, error) {
+	offset := int64(2)
+	header := make([]byte, 4)
+	for offset+4 <= size && offset < maxJPEGScan {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil, ErrMalformed
+		}
+		if header[0] != 0xFF {
+			return nil, ErrMalformed
+		}
+		marker := header[1]
+		if marker == 0xD8 || (marker >= 0xD0 && marker <= 0xD7) || marker == 0x01 || marker == 0xFF {
+			offset += 2
+			continue
+		}
+		// 到达图像数据后不再有元数据段
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+
+		length := int64(binary.BigEndian.Uint16(header[2:]))
+		if length < 2 || offset+2+length > size {
+			return nil, ErrMalformed
+		}
+		if marker == 0xE1 && length >= 8 {
+			ident := make([]byte, 6)
+			if _, err := r.ReadAt(ident, offset+4); err != nil {
+				return nil, ErrMalformed
+			}
+			if string(ident) == "Exif\x00\x00" {
+				return parseTIFF(io.NewSectionReader(r, offset+10, length-8))
+			}
+		}
+		offset += 2 + length
+	}
+	return &Metadata{}, nil
+}
+
+// tiffReader 按TIFF字节序读取IFD
+type tiffReader struct {
+	r     *io.SectionReader
+	order binary.ByteOrder
+}
+
+// ifdEntry IFD条目
+type ifdEntry struct {
+	typ   uint16
+	value []byte // 原始值（已按偏移读取）
+}
+
+// parseTIFF 解析TIFF结构中的 IFD0、Exif IFD 和 GPS IFD
+func parseTIFF(r *io.SectionReader) (*Metadata, error) {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, ErrMalformed
+	}
+
+	t := &tiffReader{r: r}
+	switch string(header[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, ErrMalformed
+	}
+	if t.order.Uint16(header[2:]) != 42 {
+		return nil, ErrMalformed
+	}
+
+	ifd0, err := t.readIFD(int64(t.order.Uint32(header[4:])))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{
+		Make:  t.ascii(ifd0[tagMake]),
+		Model: t.ascii(ifd0[tagModel]),
+	}
+
+	var localTime, zone string
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		if exif, err := t.readIFD(int64(t.uint(entry, 0))); err == nil {
+			localTime = t.ascii(exif[tagDateTimeOrg])
+			zone = t.ascii(exif[tagOffsetOrg])
+		}
+	}
+	if localTime == "" {
+		localTime = t.ascii(ifd0[tagDateTime])
+	}
+
+	var gpsTime *time.Time
+	if entry, ok := ifd0[tagGPSIFD]; ok {
+		if gps, err := t.readIFD(int64(t.uint(entry, 0))); err == nil {
+			t.applyGPS(meta, gps)
+			gpsTime = t.gpsTime(gps)
+		}
+	}
+
+	// GPS时间是UTC，优先使用；否则使用拍摄时间和时区偏移，没有偏移时按UTC解析
+	if gpsTime != nil {
+		meta.CapturedAt = gpsTime
+	} else if at, ok := parseExifTime(localTime, zone); ok {
+		meta.CapturedAt = &at
+	}
+	return meta, nil
+}
+
+// readIFD 读取IFD条目
+func (t *tiffReader) readIFD(offset int64) (map[uint16]ifdEntry, error) {
+	countBuf := make([]byte, 2)
+	if offset <= 0 || offset >= t.r.Size() {
+		return nil, ErrMalformed
+	}
+	if _, err := t.r.ReadAt(countBuf, offset); err != nil {
+		return nil, ErrMalformed
+	}
+	count := int(t.order.Uint16(countBuf))
+	if count > maxIFDEntries {
+		return nil, ErrMalformed
+	}
+
+	data := make([]byte, count*12)
+	if _, err := t.r.ReadAt(data, offset+2); err != nil {
+		return nil, ErrMalformed
+	}
+
+	entries := make(map[uint16]ifdEntry, count)
+	for i := 0; i < count; i++ {
+		raw := data[i*12 : i*12+12]
+		tag := t.order.Uint16(raw)
+		typ := t.order.Uint16(raw[2:])
+		n := t.order.Uint32(raw[4:])
+		unit, ok := typeSizes[typ]
+		if !ok || n == 0 || int64(n)*int64(unit) > 1<<16 {
+			continue
+		}
+
+		total := int(n) * unit
+		value := make([]byte, total)
+		if total <= 4 {
+			copy(value, raw[8:8+total])
+		} else if _, err := t.r.ReadAt(value, int64(t.order.Uint32(raw[8:]))); err != nil {
+			continue
+		}
+		entries[tag] = ifdEntry{typ: typ, value: value}
+	}
+	return entries, nil
+}
+
+// ascii 读取ASCII字段
+func (t *tiffReader) ascii(entry ifdEntry) string {
+	if entry.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(entry.value), "\x00"))
+}
+
+// uint 读取SHORT或LONG字段的第i个值
+func (t *tiffReader) uint(entry ifdEntry, i int) uint32 {
+	switch entry.typ {
+	case 1, 7:
+		if i < len(entry.value) {
+			return uint32(entry.value[i])
+		}
+	case 3:
+		if (i+1)*2 <= len(entry.value) {
+			return uint32(t.order.Uint16(entry.value[i*2:]))
+		}
+	case 4:
+		if (i+1)*4 <= len(entry.value) {
+			return t.order.Uint32(entry.value[i*4:])
+		}
+	}
+	return 0
+}
+
+// rational 读取RATIONAL字段的第i个值
+func (t *tiffReader) rational(entry ifdEntry, i int) (float64, bool) {
+	if entry.typ != 5 || (i+1)*8 > len(entry.value) {
+		return 0, false
+	}
+	num := t.order.Uint32(entry.value[i*8:])
+	den := t.order.Uint32(entry.value[i*8+4:])
+	if den == 0 {
+		return 0, false
+	}
+	return float64(num) / float64(den), true
+}
+
+// degrees 读取度、分、秒三个RATIONAL值
+func (t *tiffReader) degrees(entry ifdEntry) (float64, bool) {
+	d, ok1 := t.rational(entry, 0)
+	m, ok2 := t.rational(entry, 1)
+	s, ok3 := t.rational(entry, 2)
+	if !ok1 || !ok2 || !ok3 {
+		return 0, false
+	}
+	return d + m/60 + s/3600, true
+}
+
+// applyGPS 读取GPS IFD中的经纬度和海拔
+func (t *tiffReader) applyGPS(meta *Metadata, gps map[uint16]ifdEntry) {
+	lat, okLat := t.degrees(gps[tagGPSLatitude])
+	lon, okLon := t.degrees(gps[tagGPSLongitude])
+	if okLat && okLon && lat <= 90 && lon <= 180 {
+		if strings.EqualFold(t.ascii(gps[tagGPSLatitudeRef]), "S") {
+			lat = -lat
+		}
+		if strings.EqualFold(t.ascii(gps[tagGPSLongitudeRef]), "W") {
+			lon = -lon
+		}
+		meta.Latitude = &lat
+		meta.Longitude = &lon
+	}
+
+	if alt, ok := t.rational(gps[tagGPSAltitude], 0); ok {
+		// AltitudeRef 为1时表示海平面以下
+		if entry, ok := gps[tagGPSAltitudeRef]; ok && t.uint(entry, 0) == 1 {
+			alt = -alt
+		}
+		meta.Altitude = &alt
+	}
+}
+
+// gpsTime 读取GPS日期和UTC时间
+func (t *tiffReader) gpsTime(gps map[uint16]ifdEntry) *time.Time {
+	date, err := time.Parse("2006:01:02", t.ascii(gps[tagGPSDateStamp]))
+	if err != nil {
+		return nil
+	}
+	entry := gps[tagGPSTimeStamp]
+	h, ok1 := t.rational(entry, 0)
+	m, ok2 := t.rational(entry, 1)
+	s, ok3 := t.rational(entry, 2)
+	if !ok1 || !ok2 || !ok3 {
+		return nil
+	}
+	at := date.Add(time.Duration((h*3600 + m*60 + s) * float64(time.Second))).UTC()
+	return &at
+}
+
+// parseExifTime 解析 "2006:01:02 15:04:05" 格式的拍摄时间，zone 为 "+08:00" 形式的时区偏移
+func parseExifTime(value, zone string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	loc := time.UTC
+	if zone != "" {
+		if offset, err := time.Parse("-07:00", zone); err == nil {
+			_, seconds := offset.Zone()
+			loc = time.FixedZone(zone, seconds)
+		}
+	}
+	at, err := time.ParseInLocation("2006:01:02 15:04:05", value, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return at.UTC(), true
+}
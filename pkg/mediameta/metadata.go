@@ -0,0 +1,47 @@
+package mediameta
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrMalformed 文件头可识别但元数据结构损坏
+var ErrMalformed = errors.New("malformed media metadata")
+
+// Metadata 从照片或视频中提取的拍摄时间、地理标签和设备信息，零值字段表示文件中没有该信息
+type Metadata struct {
+	CapturedAt *time.Time `json:"captured_at,omitempty"`
+	Latitude   *float64   `json:"latitude,omitempty"`
+	Longitude  *float64   `json:"longitude,omitempty"`
+	Altitude   *float64   `json:"altitude,omitempty"` // 海拔（米）
+	Make       string     `json:"make,omitempty"`
+	Model      string     `json:"model,omitempty"`
+}
+
+// HasLocation 是否包含地理标签
+func (m *Metadata) HasLocation() bool {
+	return m.Latitude != nil && m.Longitude != nil
+}
+
+// Extract 按文件头识别格式并提取元数据
+// 支持 JPEG（EXIF）、TIFF/DNG 和 MP4/MOV，其他格式返回空元数据
+func Extract(r io.ReaderAt, size int64) (*Metadata, error) {
+	head := make([]byte, 12)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8}):
+		return extractJPEG(r, size)
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return parseTIFF(io.NewSectionReader(r, 0, size))
+	case len(head) >= 8 && string(head[4:8]) == "ftyp":
+		return extractMP4(r, size)
+	}
+	return &Metadata{}, nil
+}
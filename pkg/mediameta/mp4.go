@@ -0,0 +1,148 @@
+package mediameta
+
+import (
+	"encoding/binary"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// mp4Epoch MP4时间字段的起点
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// iso6709 ISO 6709 十进制度坐标，如 "+37.7749-122.4194+010.000/"
+var iso6709 = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)?`)
+
+// mp4Box box位置
+type mp4Box struct {
+	typ    string
+	offset int64 // 内容起始位置（不含头）
+	size   int64 // 内容长度
+}
+
+// extractMP4 读取 moov/mvhd 的创建时间和 moov/udta/©xyz 的位置
+func extractMP4(r io.ReaderAt, size int64) (*Metadata, error) {
+	meta := &Metadata{}
+
+	moov, ok, err := findBox(r, 0, size, "moov")
+	if err != nil || !ok {
+		return meta, err
+	}
+
+	if mvhd, ok, err := findBox(r, moov.offset, moov.size, "mvhd"); err != nil {
+		return nil, err
+	} else if ok {
+		if at, ok := readMVHDTime(r, mvhd); ok {
+			meta.CapturedAt = &at
+		}
+	}
+
+	if udta, ok, err := findBox(r, moov.offset, moov.size, "udta"); err != nil {
+		return nil, err
+	} else if ok {
+		if xyz, ok, err := findBox(r, udta.offset, udta.size, "\xA9xyz"); err == nil && ok {
+			readXYZ(r, xyz, meta)
+		}
+	}
+	return meta, nil
+}
+
+// findBox 在 [offset, offset+size) 范围内查找指定类型的box
+func findBox(r io.ReaderAt, offset, size int64, typ string) (mp4Box, bool, error) {
+	end := offset + size
+	header := make([]byte, 16)
+	for offset+8 <= end {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return mp4Box{}, false, ErrMalformed
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header))
+		boxType := string(header[4:8])
+		headerSize := int64(8)
+
+		switch boxSize {
+		case 0:
+			// 延伸到文件末尾
+			boxSize = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return mp4Box{}, false, ErrMalformed
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+		if boxSize < headerSize || offset+boxSize > end {
+			return mp4Box{}, false, ErrMalformed
+		}
+
+		if boxType == typ {
+			return mp4Box{typ: boxType, offset: offset + headerSize, size: boxSize - headerSize}, true, nil
+		}
+		offset += boxSize
+	}
+	return mp4Box{}, false, nil
+}
+
+// readMVHDTime 读取影片创建时间（UTC）
+func readMVHDTime(r io.ReaderAt, box mp4Box) (time.Time, bool) {
+	buf := make([]byte, 12)
+	if box.size < 8 {
+		return time.Time{}, false
+	}
+	if _, err := r.ReadAt(buf[:4], box.offset); err != nil {
+		return time.Time{}, false
+	}
+
+	var seconds uint64
+	if buf[0] == 1 {
+		if box.size < 12 {
+			return time.Time{}, false
+		}
+		if _, err := r.ReadAt(buf[4:12], box.offset+4); err != nil {
+			return time.Time{}, false
+		}
+		seconds = binary.BigEndian.Uint64(buf[4:12])
+	} else {
+		if _, err := r.ReadAt(buf[4:8], box.offset+4); err != nil {
+			return time.Time{}, false
+		}
+		seconds = uint64(binary.BigEndian.Uint32(buf[4:8]))
+	}
+	// 未设置时间的文件该字段为0
+	if seconds == 0 {
+		return time.Time{}, false
+	}
+	return mp4Epoch.Add(time.Duration(seconds) * time.Second), true
+}
+
+// readXYZ 读取 ©xyz 中的 ISO 6709 坐标（2字节长度、2字节语言，随后是字符串）
+func readXYZ(r io.ReaderAt, box mp4Box, meta *Metadata) {
+	if box.size < 4 || box.size > 256 {
+		return
+	}
+	data := make([]byte, box.size)
+	if _, err := r.ReadAt(data, box.offset); err != nil {
+		return
+	}
+	length := int(binary.BigEndian.Uint16(data))
+	if length > len(data)-4 {
+		length = len(data) - 4
+	}
+
+	match := iso6709.FindStringSubmatch(string(data[4 : 4+length]))
+	if match == nil {
+		return
+	}
+	lat, err1 := strconv.ParseFloat(match[1], 64)
+	lon, err2 := strconv.ParseFloat(match[2], 64)
+	if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return
+	}
+	meta.Latitude = &lat
+	meta.Longitude = &lon
+	if match[3] != "" {
+		if alt, err := strconv.ParseFloat(match[3], 64); err == nil {
+			meta.Altitude = &alt
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package planner
+
+import (
+	"math"
+
+	"drone-control-system/pkg/geo"
+)
+
+// maxArcStep 凸顶点外扩圆弧的最大分段角度
+const maxArcStep = math.Pi / 6
+
+// obstacle 投影到局部平面的区域，顶点按逆时针排列
+type obstacle struct {
+	name     string
+	vertices []geo.Vec
+}
+
+// newObstacle 投影区域多边形
+func newObstacle(proj *geo.Projection, r *Region) *obstacle {
+	vertices := make([]geo.Vec, len(r.Polygon))
+	for i, p := range r.Polygon {
+		x, y := proj.ToXY(p)
+		vertices[i] = geo.Vec{X: x, Y: y}
+	}
+
+	area := 0.0
+	for i := range vertices {
+		area += vertices[i].Cross(vertices[(i+1)%len(vertices)])
+	}
+	if area < 0 {
+		for i, j := 0, len(vertices)-1; i < j; i, j = i+1, j-1 {
+			vertices[i], vertices[j] = vertices[j], vertices[i]
+		}
+	}
+	return &obstacle{name: r.Name, vertices: vertices}
+}
+
+// contains 判断点是否在多边形内（射线法）
+func (o *obstacle) contains(p geo.Vec) bool {
+	inside := false
+	n := len(o.vertices)
+	for i, j := 0, n-1; i < n; j, i = i, i+1 {
+		a, b := o.vertices[i], o.vertices[j]
+		if (a.Y > p.Y) != (b.Y > p.Y) && p.X < (b.X-a.X)*(p.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// segmentClearance 航段到多边形的最小距离，相交或位于内部时返回0
+func (o *obstacle) segmentClearance(a, b geo.Vec) float64 {
+	if o.contains(a) || o.contains(b) {
+		return 0
+	}
+
+	min := math.Inf(1)
+	n := len(o.vertices)
+	for i := 0; i < n; i++ {
+		p, q := o.vertices[i], o.vertices[(i+1)%n]
+		if geo.SegmentsIntersect(a, b, p, q) {
+			return 0
+		}
+		min = math.Min(min, geo.PointSegmentDistance(a, p, q))
+		min = math.Min(min, geo.PointSegmentDistance(b, p, q))
+		min = math.Min(min, geo.PointSegmentDistance(p, a, b))
+	}
+	return min
+}
+
+// inflate 在每个凸顶点外侧生成绕行候选点
+// 转角较小时取斜接点；转角较大时沿圆弧分段取点，使相邻候选点之间的连线与顶点保持 offset 距离
+func (o *obstacle) inflate(offset float64) []geo.Vec {
+	var points []geo.Vec
+	n := len(o.vertices)
+	for i := 0; i < n; i++ {
+		prev, v, next := o.vertices[(i+n-1)%n], o.vertices[i], o.vertices[(i+1)%n]
+		in, out := v.Sub(prev), next.Sub(v)
+		if in.Length() == 0 || out.Length() == 0 || in.Cross(out) <= 0 {
+			continue // 凹顶点或重复顶点，最短路径不会经过
+		}
+
+		n1 := outwardNormal(in)
+		n2 := outwardNormal(out)
+		turn := math.Acos(math.Max(-1, math.Min(1, n1.Dot(n2))))
+
+		if turn <= maxArcStep {
+			bisector := n1.Add(n2)
+			bisector = bisector.Scale(1 / bisector.Length())
+			points = append(points, v.Add(bisector.Scale(offset/math.Cos(turn/2))))
+			continue
+		}
+
+		steps := int(math.Ceil(turn / maxArcStep))
+		step := turn / float64(steps)
+		radius := offset / math.Cos(step/2)
+		for k := 0; k <= steps; k++ {
+			points = append(points, v.Add(n1.Rotate(step*float64(k)).Scale(radius)))
+		}
+	}
+	return points
+}
+
+// outwardNormal 逆时针多边形边的外法向单位向量
+func outwardNormal(edge geo.Vec) geo.Vec {
+	l := edge.Length()
+	return geo.Vec{X: edge.Y / l, Y: -edge.X / l}
+}
@@ -0,0 +1,362 @@
+// Package planner 提供确定性的避障航线规划和航线安全校验
+//
+// 限制区域（禁飞区多边形、障碍物体积）在局部平面内按安全距离外扩，
+// 以外扩顶点构建可见图并用A*搜索最短路径。规划结果在返回前经过 Verify 校验，
+// 因此返回的航线保证与所有生效区域保持安全距离；同一校验也用于检查LLM给出的航线。
+package planner
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+
+	"drone-control-system/pkg/geo"
+)
+
+var (
+	// ErrNoPath 在安全距离约束下无法找到可行航线
+	ErrNoPath = errors.New("no collision-free path found")
+	// ErrEndpointBlocked 航点本身位于限制区域或其安全距离内
+	ErrEndpointBlocked = errors.New("waypoint lies inside a restricted region")
+	// ErrAltitude 航点高度超出限制
+	ErrAltitude = errors.New("waypoint altitude out of range")
+)
+
+// Config 规划参数
+type Config struct {
+	SafetyBuffer   float64 `json:"safety_buffer"`   // 与区域的水平安全距离（米）
+	VerticalBuffer float64 `json:"vertical_buffer"` // 与区域高度范围的垂直安全距离（米）
+	MaxAltitude    float64 `json:"max_altitude"`    // 最大飞行高度（米），0表示不限制
+	MaxNodes       int     `json:"max_nodes"`       // 可见图节点上限，防止区域过多时计算量失控
+}
+
+// DefaultConfig 默认规划参数
+func DefaultConfig() Config {
+	return Config{
+		SafetyBuffer:   30,
+		VerticalBuffer: 10,
+		MaxAltitude:    120,
+		MaxNodes:       2000,
+	}
+}
+
+// Region 限制区域：水平多边形加高度范围
+type Region struct {
+	Name        string      `json:"name"`
+	Polygon     []geo.Point `json:"polygon"`
+	MinAltitude float64     `json:"min_altitude"`
+	MaxAltitude float64     `json:"max_altitude"` // 0表示不限上限
+}
+
+// Waypoint 航线点
+type Waypoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+	Source    int     `json:"source"` // 对应输入航点的序号，规划插入的绕行点为-1
+}
+
+// Violation 航线违反安全约束的航段
+type Violation struct {
+	Leg       int     `json:"leg"`    // 航段终点序号，航点本身违规时为该航点序号
+	Region    string  `json:"region"` // 违反的区域，高度违规时为空
+	Clearance float64 `json:"clearance"`
+	Message   string  `json:"message"`
+}
+
+// Planner 确定性航线规划器
+type Planner struct {
+	config  Config
+	regions []Region
+}
+
+// New 创建规划器
+func New(config Config, regions []Region) *Planner {
+	if config.MaxNodes <= 0 {
+		config.MaxNodes = DefaultConfig().MaxNodes
+	}
+
+	valid := make([]Region, 0, len(regions))
+	for _, r := range regions {
+		if len(r.Polygon) >= 3 {
+			valid = append(valid, r)
+		}
+	}
+	return &Planner{config: config, regions: valid}
+}
+
+// Plan 依次规划相邻航点之间的航段，必要时插入绕行点
+// 插入点的高度按沿航段的距离在两端航点高度之间线性插值
+func (p *Planner) Plan(waypoints []Waypoint) ([]Waypoint, error) {
+	if len(waypoints) == 0 {
+		return nil, nil
+	}
+
+	proj := geo.NewProjection(geo.Point{Latitude: waypoints[0].Latitude, Longitude: waypoints[0].Longitude})
+	for i, wp := range waypoints {
+		if err := p.checkAltitude(wp.Altitude); err != nil {
+			return nil, fmt.Errorf("waypoint %d: %w", i, err)
+		}
+	}
+
+	first := waypoints[0]
+	first.Source = 0
+	route := []Waypoint{first}
+	for i := 1; i < len(waypoints); i++ {
+		from, to := waypoints[i-1], waypoints[i]
+		obstacles := p.activeObstacles(proj, from.Altitude, to.Altitude)
+
+		start := toVec(proj, from)
+		goal := toVec(proj, to)
+		if c, name := clearance(start, obstacles); c < p.config.SafetyBuffer {
+			return nil, fmt.Errorf("%w: waypoint %d is %.1fm from %s", ErrEndpointBlocked, i-1, c, name)
+		}
+		if c, name := clearance(goal, obstacles); c < p.config.SafetyBuffer {
+			return nil, fmt.Errorf("%w: waypoint %d is %.1fm from %s", ErrEndpointBlocked, i, c, name)
+		}
+
+		path, err := p.search(start, goal, obstacles)
+		if err != nil {
+			return nil, fmt.Errorf("leg %d: %w", i, err)
+		}
+
+		total := pathLength(path)
+		walked := 0.0
+		for j := 1; j < len(path)-1; j++ {
+			walked += path[j].Sub(path[j-1]).Length()
+			ratio := 0.0
+			if total > 0 {
+				ratio = walked / total
+			}
+			point := proj.FromXY(path[j].X, path[j].Y)
+			route = append(route, Waypoint{
+				Latitude:  point.Latitude,
+				Longitude: point.Longitude,
+				Altitude:  from.Altitude + (to.Altitude-from.Altitude)*ratio,
+				Source:    -1,
+			})
+		}
+
+		end := to
+		end.Source = i
+		route = append(route, end)
+	}
+
+	if violations := p.Verify(route); len(violations) > 0 {
+		// 外扩顶点与校验使用同一安全距离，理论上不会发生；出现时宁可拒绝也不返回不安全的航线
+		return nil, fmt.Errorf("%w: planned route failed verification: %s", ErrNoPath, violations[0].Message)
+	}
+	return route, nil
+}
+
+// Verify 校验航线每个航段与生效区域的安全距离和高度限制，返回所有违规
+func (p *Planner) Verify(path []Waypoint) []Violation {
+	var violations []Violation
+	if len(path) == 0 {
+		return nil
+	}
+
+	proj := geo.NewProjection(geo.Point{Latitude: path[0].Latitude, Longitude: path[0].Longitude})
+	for i, wp := range path {
+		if err := p.checkAltitude(wp.Altitude); err != nil {
+			violations = append(violations, Violation{
+				Leg:     i,
+				Message: fmt.Sprintf("waypoint %d: %v", i, err),
+			})
+		}
+	}
+
+	if len(path) == 1 {
+		obstacles := p.activeObstacles(proj, path[0].Altitude, path[0].Altitude)
+		if c, name := clearance(toVec(proj, path[0]), obstacles); c < p.config.SafetyBuffer {
+			violations = append(violations, Violation{
+				Leg:       0,
+				Region:    name,
+				Clearance: c,
+				Message:   fmt.Sprintf("waypoint 0 is %.1fm from %s, %.1fm required", c, name, p.config.SafetyBuffer),
+			})
+		}
+		return violations
+	}
+
+	for i := 1; i < len(path); i++ {
+		obstacles := p.activeObstacles(proj, path[i-1].Altitude, path[i].Altitude)
+		a, b := toVec(proj, path[i-1]), toVec(proj, path[i])
+		for _, o := range obstacles {
+			if c := o.segmentClearance(a, b); c < p.config.SafetyBuffer {
+				violations = append(violations, Violation{
+					Leg:       i,
+					Region:    o.name,
+					Clearance: c,
+					Message: fmt.Sprintf("leg %d-%d passes %.1fm from %s, %.1fm required",
+						i-1, i, c, o.name, p.config.SafetyBuffer),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// VerifyOrPlan 校验候选航线（如LLM给出的航线），不安全时基于原始航点重新规划
+func (p *Planner) VerifyOrPlan(proposed, original []Waypoint) ([]Waypoint, []Violation, error) {
+	violations := p.Verify(proposed)
+	if len(proposed) > 0 && len(violations) == 0 {
+		return proposed, nil, nil
+	}
+
+	route, err := p.Plan(original)
+	return route, violations, err
+}
+
+// checkAltitude 检查航点高度
+func (p *Planner) checkAltitude(altitude float64) error {
+	if altitude < 0 {
+		return fmt.Errorf("%w: %.1fm is negative", ErrAltitude, altitude)
+	}
+	if p.config.MaxAltitude > 0 && altitude > p.config.MaxAltitude {
+		return fmt.Errorf("%w: %.1fm exceeds %.1fm", ErrAltitude, altitude, p.config.MaxAltitude)
+	}
+	return nil
+}
+
+// activeObstacles 返回与航段高度区间（含垂直安全距离）重叠的区域
+func (p *Planner) activeObstacles(proj *geo.Projection, altA, altB float64) []*obstacle {
+	low, high := math.Min(altA, altB)-p.config.VerticalBuffer, math.Max(altA, altB)+p.config.VerticalBuffer
+
+	var result []*obstacle
+	for i := range p.regions {
+		r := &p.regions[i]
+		if high < r.MinAltitude || (r.MaxAltitude > 0 && low > r.MaxAltitude) {
+			continue
+		}
+		result = append(result, newObstacle(proj, r))
+	}
+	return result
+}
+
+// search 在外扩顶点构成的可见图上执行A*搜索
+func (p *Planner) search(start, goal geo.Vec, obstacles []*obstacle) ([]geo.Vec, error) {
+	if visible(start, goal, obstacles, p.config.SafetyBuffer) {
+		return []geo.Vec{start, goal}, nil
+	}
+
+	nodes := []geo.Vec{start, goal}
+	// 外扩距离略大于安全距离，避免浮点误差导致相邻外扩点之间的航段校验失败
+	offset := p.config.SafetyBuffer*1.01 + 0.5
+	for _, o := range obstacles {
+		for _, n := range o.inflate(offset) {
+			if c, _ := clearance(n, obstacles); c >= p.config.SafetyBuffer {
+				nodes = append(nodes, n)
+			}
+		}
+		if len(nodes) > p.config.MaxNodes {
+			return nil, fmt.Errorf("%w: too many region vertices (%d)", ErrNoPath, len(nodes))
+		}
+	}
+
+	const startIdx, goalIdx = 0, 1
+	dist := make([]float64, len(nodes))
+	prev := make([]int, len(nodes))
+	closed := make([]bool, len(nodes))
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	dist[startIdx] = 0
+
+	open := &nodeQueue{{index: startIdx, priority: nodes[startIdx].Sub(goal).Length()}}
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*queueItem).index
+		if closed[current] {
+			continue
+		}
+		if current == goalIdx {
+			break
+		}
+		closed[current] = true
+
+		for next := range nodes {
+			if closed[next] || next == current {
+				continue
+			}
+			step := nodes[next].Sub(nodes[current]).Length()
+			candidate := dist[current] + step
+			if candidate >= dist[next] {
+				continue
+			}
+			if !visible(nodes[current], nodes[next], obstacles, p.config.SafetyBuffer) {
+				continue
+			}
+			dist[next] = candidate
+			prev[next] = current
+			heap.Push(open, &queueItem{index: next, priority: candidate + nodes[next].Sub(goal).Length()})
+		}
+	}
+
+	if prev[goalIdx] == -1 {
+		return nil, ErrNoPath
+	}
+
+	var path []geo.Vec
+	for at := goalIdx; at != -1; at = prev[at] {
+		path = append([]geo.Vec{nodes[at]}, path...)
+	}
+	return path, nil
+}
+
+// visible 判断两点之间的直线航段是否与所有区域保持安全距离
+func visible(a, b geo.Vec, obstacles []*obstacle, buffer float64) bool {
+	for _, o := range obstacles {
+		if o.segmentClearance(a, b) < buffer {
+			return false
+		}
+	}
+	return true
+}
+
+// clearance 返回点到最近区域的距离和该区域名称
+func clearance(point geo.Vec, obstacles []*obstacle) (float64, string) {
+	min, name := math.Inf(1), ""
+	for _, o := range obstacles {
+		if c := o.segmentClearance(point, point); c < min {
+			min, name = c, o.name
+		}
+	}
+	return min, name
+}
+
+// pathLength 平面折线长度
+func pathLength(path []geo.Vec) float64 {
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		total += path[i].Sub(path[i-1]).Length()
+	}
+	return total
+}
+
+// toVec 将航点投影到局部平面
+func toVec(proj *geo.Projection, wp Waypoint) geo.Vec {
+	x, y := proj.ToXY(geo.Point{Latitude: wp.Latitude, Longitude: wp.Longitude})
+	return geo.Vec{X: x, Y: y}
+}
+
+// queueItem A*开放列表项
+type queueItem struct {
+	index    int
+	priority float64
+}
+
+// nodeQueue 按优先级排序的最小堆
+type nodeQueue []*queueItem
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].priority < q[j].priority }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(*queueItem)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
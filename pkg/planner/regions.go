@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"math"
+	"strings"
+
+	"drone-control-system/pkg/geo"
+	"drone-control-system/pkg/llm"
+)
+
+// DefaultCircleSegments 圆形区域转换为多边形时的默认边数
+const DefaultCircleSegments = 32
+
+// Circle 将圆形区域转换为外接正多边形，保证多边形完整覆盖圆
+func Circle(center geo.Point, radius float64, segments int) []geo.Point {
+	if segments < 3 {
+		segments = DefaultCircleSegments
+	}
+
+	// 外接多边形的顶点距圆心 r/cos(π/n)
+	r := radius / math.Cos(math.Pi/float64(segments))
+	points := make([]geo.Point, segments)
+	for i := 0; i < segments; i++ {
+		bearing := 360 * float64(i) / float64(segments)
+		lat, lon := geo.Destination(center.Latitude, center.Longitude, bearing, r)
+		points[i] = geo.Point{Latitude: lat, Longitude: lon}
+	}
+	return points
+}
+
+// FromObstacle 将障碍物转换为区域：以位置为中心、沿航向放置的 Length×Width 矩形，
+// 高度范围从障碍物位置高度到其顶部，未给出高度时不限上限
+func FromObstacle(o llm.Obstacle) Region {
+	halfLength := math.Max(o.Size.Length, 1) / 2
+	halfWidth := math.Max(o.Size.Width, 1) / 2
+	diagonal := math.Hypot(halfLength, halfWidth)
+	angle := math.Atan2(halfWidth, halfLength) * 180 / math.Pi
+
+	corners := []float64{o.Position.Heading + angle, o.Position.Heading + 180 - angle,
+		o.Position.Heading + 180 + angle, o.Position.Heading - angle}
+	polygon := make([]geo.Point, len(corners))
+	for i, bearing := range corners {
+		lat, lon := geo.Destination(o.Position.Latitude, o.Position.Longitude, bearing, diagonal)
+		polygon[i] = geo.Point{Latitude: lat, Longitude: lon}
+	}
+
+	region := Region{
+		Name:        "obstacle " + o.ID,
+		Polygon:     polygon,
+		MinAltitude: math.Max(o.Position.Altitude, 0),
+	}
+	if o.Size.Height > 0 {
+		region.MaxAltitude = region.MinAltitude + o.Size.Height
+	}
+	return region
+}
+
+// FromZone 将禁飞区或限飞区转换为区域，其他类型返回 false
+func FromZone(z llm.Zone) (Region, bool) {
+	switch strings.ToLower(strings.ReplaceAll(z.Type, "_", "-")) {
+	case "no-fly", "restricted":
+	default:
+		return Region{}, false
+	}
+	if len(z.Boundary) < 3 {
+		return Region{}, false
+	}
+
+	polygon := make([]geo.Point, len(z.Boundary))
+	for i, p := range z.Boundary {
+		polygon[i] = geo.Point{Latitude: p.Latitude, Longitude: p.Longitude}
+	}
+	name := z.Name
+	if name == "" {
+		name = "zone " + z.ID
+	}
+	return Region{
+		Name:        name,
+		Polygon:     polygon,
+		MinAltitude: z.MinAlt,
+		MaxAltitude: z.MaxAlt,
+	}, true
+}
+
+// FromEnvironment 收集环境中的障碍物和禁飞区
+func FromEnvironment(env llm.EnvironmentState) []Region {
+	regions := make([]Region, 0, len(env.Obstacles)+len(env.NoFlyZones))
+	for _, o := range env.Obstacles {
+		regions = append(regions, FromObstacle(o))
+	}
+	for _, z := range env.NoFlyZones {
+		if r, ok := FromZone(z); ok {
+			regions = append(regions, r)
+		}
+	}
+	return regions
+}
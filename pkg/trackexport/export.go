@@ -0,0 +1,92 @@
+package trackexport
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	FormatGPX     Format = "gpx"
+	FormatKML     Format = "kml"
+	FormatGeoJSON Format = "geojson"
+)
+
+// TrackPoint 实际飞行轨迹点
+type TrackPoint struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	Time      time.Time
+	Speed     float64 // 地速 m/s
+	Battery   int     // 电量百分比，-1表示未知
+}
+
+// PlannedPoint 计划航点
+type PlannedPoint struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+	Name      string
+}
+
+// Document 导出文档，实际轨迹和计划航线可同时包含以便叠加对比
+type Document struct {
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	Actual      []TrackPoint
+	Planned     []PlannedPoint
+}
+
+// ParseFormat 解析导出格式
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case FormatGPX, FormatKML, FormatGeoJSON:
+		return Format(value), nil
+	case "json":
+		return FormatGeoJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", value)
+	}
+}
+
+// ContentType 格式对应的MIME类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatGPX:
+		return "application/gpx+xml"
+	case FormatKML:
+		return "application/vnd.google-earth.kml+xml"
+	case FormatGeoJSON:
+		return "application/geo+json"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Extension 格式对应的文件扩展名
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Write 按指定格式输出文档
+func Write(w io.Writer, format Format, doc *Document) error {
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = time.Now()
+	}
+
+	switch format {
+	case FormatGPX:
+		return WriteGPX(w, doc)
+	case FormatKML:
+		return WriteKML(w, doc)
+	case FormatGeoJSON:
+		return WriteGeoJSON(w, doc)
+	default:
+		return fmt.Errorf("unsupported export format: %s", format)
+	}
+}
@@ -0,0 +1,101 @@
+package trackexport
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+)
+
+type featureCollection struct {
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	Features   []feature              `json:"features"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Geometry   geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geometry struct {
+	Type        string      `json:"type"`
+	Coordinates [][]float64 `json:"coordinates"`
+}
+
+// WriteGeoJSON 输出GeoJSON FeatureCollection
+// 每条路径为一个LineString Feature，逐点时间、速度、电量以与坐标等长的数组放在properties中
+func WriteGeoJSON(w io.Writer, doc *Document) error {
+	collection := featureCollection{
+		Type: "FeatureCollection",
+		Properties: map[string]interface{}{
+			"name":        doc.Name,
+			"description": doc.Description,
+			"created_at":  formatTime(doc.CreatedAt),
+		},
+		Features: make([]feature, 0, 2),
+	}
+
+	if len(doc.Planned) > 0 {
+		coords := make([][]float64, 0, len(doc.Planned))
+		names := make([]string, 0, len(doc.Planned))
+		for _, p := range doc.Planned {
+			coords = append(coords, []float64{p.Longitude, p.Latitude, p.Altitude})
+			names = append(names, p.Name)
+		}
+		collection.Features = append(collection.Features, feature{
+			Type:     "Feature",
+			Geometry: geometry{Type: "LineString", Coordinates: coords},
+			Properties: map[string]interface{}{
+				"kind":           "planned",
+				"waypoint_names": names,
+			},
+		})
+	}
+
+	if len(doc.Actual) > 0 {
+		coords := make([][]float64, 0, len(doc.Actual))
+		times := make([]string, 0, len(doc.Actual))
+		speeds := make([]float64, 0, len(doc.Actual))
+		batteries := make([]*int, 0, len(doc.Actual))
+		for _, p := range doc.Actual {
+			coords = append(coords, []float64{p.Longitude, p.Latitude, p.Altitude})
+			times = append(times, formatTime(p.Time))
+			speeds = append(speeds, round(p.Speed, 2))
+			if p.Battery >= 0 {
+				battery := p.Battery
+				batteries = append(batteries, &battery)
+			} else {
+				batteries = append(batteries, nil)
+			}
+		}
+		collection.Features = append(collection.Features, feature{
+			Type:     "Feature",
+			Geometry: geometry{Type: "LineString", Coordinates: coords},
+			Properties: map[string]interface{}{
+				"kind":       "actual",
+				"start_time": times[0],
+				"end_time":   times[len(times)-1],
+				"timestamps": times,
+				"speeds":     speeds,
+				"batteries":  batteries,
+			},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(collection)
+}
+
+// round 保留指定位小数
+func round(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
+
+// formatFloat 输出不带多余零的小数
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
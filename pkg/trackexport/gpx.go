@@ -0,0 +1,126 @@
+package trackexport
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const (
+	gpxNamespace = "http://www.topografix.com/GPX/1/1"
+	gpxSchema    = "http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd"
+	// extNamespace 轨迹点扩展数据（速度、电量）命名空间
+	extNamespace = "https://github.com/whkp/Drone-control-system/track/1"
+)
+
+type gpxRoot struct {
+	XMLName        xml.Name    `xml:"gpx"`
+	Version        string      `xml:"version,attr"`
+	Creator        string      `xml:"creator,attr"`
+	Xmlns          string      `xml:"xmlns,attr"`
+	XmlnsXSI       string      `xml:"xmlns:xsi,attr"`
+	XmlnsExt       string      `xml:"xmlns:dcs,attr"`
+	SchemaLocation string      `xml:"xsi:schemaLocation,attr"`
+	Metadata       gpxMetadata `xml:"metadata"`
+	Routes         []gpxRoute  `xml:"rte,omitempty"`
+	Tracks         []gpxTrack  `xml:"trk,omitempty"`
+}
+
+type gpxMetadata struct {
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+	Time string `xml:"time"`
+}
+
+type gpxRoute struct {
+	Name   string     `xml:"name"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name    string      `xml:"name"`
+	Segment gpxTrackSeg `xml:"trkseg"`
+}
+
+type gpxTrackSeg struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat        float64        `xml:"lat,attr"`
+	Lon        float64        `xml:"lon,attr"`
+	Ele        float64        `xml:"ele"`
+	Time       string         `xml:"time,omitempty"`
+	Name       string         `xml:"name,omitempty"`
+	Extensions *gpxExtensions `xml:"extensions,omitempty"`
+}
+
+type gpxExtensions struct {
+	Speed   float64 `xml:"dcs:speed"`
+	Battery *int    `xml:"dcs:battery,omitempty"`
+}
+
+// WriteGPX 输出GPX 1.1文档：计划航点为rte，实际轨迹为trk
+func WriteGPX(w io.Writer, doc *Document) error {
+	root := gpxRoot{
+		Version:        "1.1",
+		Creator:        "drone-control-system",
+		Xmlns:          gpxNamespace,
+		XmlnsXSI:       "http://www.w3.org/2001/XMLSchema-instance",
+		XmlnsExt:       extNamespace,
+		SchemaLocation: gpxSchema,
+		Metadata: gpxMetadata{
+			Name: doc.Name,
+			Desc: doc.Description,
+			Time: formatTime(doc.CreatedAt),
+		},
+	}
+
+	if len(doc.Planned) > 0 {
+		route := gpxRoute{Name: "planned", Points: make([]gpxPoint, 0, len(doc.Planned))}
+		for _, p := range doc.Planned {
+			route.Points = append(route.Points, gpxPoint{
+				Lat:  p.Latitude,
+				Lon:  p.Longitude,
+				Ele:  p.Altitude,
+				Name: p.Name,
+			})
+		}
+		root.Routes = append(root.Routes, route)
+	}
+
+	if len(doc.Actual) > 0 {
+		track := gpxTrack{Name: "actual"}
+		track.Segment.Points = make([]gpxPoint, 0, len(doc.Actual))
+		for _, p := range doc.Actual {
+			ext := &gpxExtensions{Speed: round(p.Speed, 2)}
+			if p.Battery >= 0 {
+				battery := p.Battery
+				ext.Battery = &battery
+			}
+			track.Segment.Points = append(track.Segment.Points, gpxPoint{
+				Lat:        p.Latitude,
+				Lon:        p.Longitude,
+				Ele:        p.Altitude,
+				Time:       formatTime(p.Time),
+				Extensions: ext,
+			})
+		}
+		root.Tracks = append(root.Tracks, track)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(root)
+}
+
+// formatTime 统一输出UTC时间
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
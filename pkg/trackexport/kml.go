@@ -0,0 +1,148 @@
+package trackexport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	kmlNamespace   = "http://www.opengis.net/kml/2.2"
+	kmlGxNamespace = "http://www.google.com/kml/ext/2.2"
+)
+
+type kmlRoot struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	XmlnsGx  string      `xml:"xmlns:gx,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name        string         `xml:"name,omitempty"`
+	Description string         `xml:"description,omitempty"`
+	Schema      *kmlSchema     `xml:"Schema,omitempty"`
+	Placemarks  []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlSchema struct {
+	ID     string           `xml:"id,attr"`
+	Fields []kmlSimpleField `xml:"gx:SimpleArrayField"`
+}
+
+type kmlSimpleField struct {
+	Name        string `xml:"name,attr"`
+	Type        string `xml:"type,attr"`
+	DisplayName string `xml:"displayName"`
+}
+
+type kmlPlacemark struct {
+	Name       string         `xml:"name"`
+	LineString *kmlLineString `xml:"LineString,omitempty"`
+	Track      *kmlTrack      `xml:"gx:Track,omitempty"`
+}
+
+type kmlLineString struct {
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+type kmlTrack struct {
+	AltitudeMode string          `xml:"altitudeMode"`
+	When         []string        `xml:"when"`
+	Coords       []string        `xml:"gx:coord"`
+	ExtendedData kmlExtendedData `xml:"ExtendedData"`
+}
+
+type kmlExtendedData struct {
+	SchemaData kmlSchemaData `xml:"SchemaData"`
+}
+
+type kmlSchemaData struct {
+	SchemaURL string         `xml:"schemaUrl,attr"`
+	Arrays    []kmlArrayData `xml:"gx:SimpleArrayData"`
+}
+
+type kmlArrayData struct {
+	Name   string   `xml:"name,attr"`
+	Values []string `xml:"gx:value"`
+}
+
+// WriteKML 输出KML 2.2文档
+// 计划航线为LineString；实际轨迹使用gx:Track携带逐点时间，速度和电量写入ExtendedData
+func WriteKML(w io.Writer, doc *Document) error {
+	root := kmlRoot{
+		Xmlns:   kmlNamespace,
+		XmlnsGx: kmlGxNamespace,
+		Document: kmlDocument{
+			Name:        doc.Name,
+			Description: doc.Description,
+		},
+	}
+
+	if len(doc.Planned) > 0 {
+		coords := make([]string, 0, len(doc.Planned))
+		for _, p := range doc.Planned {
+			coords = append(coords, fmt.Sprintf("%s,%s,%s",
+				formatFloat(p.Longitude), formatFloat(p.Latitude), formatFloat(p.Altitude)))
+		}
+		root.Document.Placemarks = append(root.Document.Placemarks, kmlPlacemark{
+			Name: "planned",
+			LineString: &kmlLineString{
+				AltitudeMode: "relativeToGround",
+				Coordinates:  strings.Join(coords, " "),
+			},
+		})
+	}
+
+	if len(doc.Actual) > 0 {
+		root.Document.Schema = &kmlSchema{
+			ID: "telemetry",
+			Fields: []kmlSimpleField{
+				{Name: "speed", Type: "float", DisplayName: "Speed (m/s)"},
+				{Name: "battery", Type: "int", DisplayName: "Battery (%)"},
+			},
+		}
+
+		track := &kmlTrack{
+			AltitudeMode: "relativeToGround",
+			When:         make([]string, 0, len(doc.Actual)),
+			Coords:       make([]string, 0, len(doc.Actual)),
+		}
+		speeds := kmlArrayData{Name: "speed", Values: make([]string, 0, len(doc.Actual))}
+		batteries := kmlArrayData{Name: "battery", Values: make([]string, 0, len(doc.Actual))}
+
+		for _, p := range doc.Actual {
+			track.When = append(track.When, formatTime(p.Time))
+			track.Coords = append(track.Coords, fmt.Sprintf("%s %s %s",
+				formatFloat(p.Longitude), formatFloat(p.Latitude), formatFloat(p.Altitude)))
+			speeds.Values = append(speeds.Values, formatFloat(round(p.Speed, 2)))
+
+			// gx:value 数量必须与点数一致，未知电量输出空值
+			battery := ""
+			if p.Battery >= 0 {
+				battery = strconv.Itoa(p.Battery)
+			}
+			batteries.Values = append(batteries.Values, battery)
+		}
+
+		track.ExtendedData.SchemaData = kmlSchemaData{
+			SchemaURL: "#telemetry",
+			Arrays:    []kmlArrayData{speeds, batteries},
+		}
+
+		root.Document.Placemarks = append(root.Document.Placemarks, kmlPlacemark{
+			Name:  "actual",
+			Track: track,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(root)
+}